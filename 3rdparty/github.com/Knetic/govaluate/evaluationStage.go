@@ -297,6 +297,15 @@ func makeAccessorStage(pair []string) evaluationOperator {
 
 		var params []reflect.Value
 
+		// parameters resolving accessors by themselves, e.g. the ones of nested maps
+		if pathParameters, ok := unwrapParameters(parameters).(PathParameters); ok {
+			value, err := pathParameters.GetPath(pair)
+			if err != nil {
+				return nil, err
+			}
+			return castToFloat64(value), nil
+		}
+
 		value, err := parameters.Get(pair[0])
 		if err != nil {
 			return nil, err
@@ -307,7 +316,7 @@ func makeAccessorStage(pair []string) evaluationOperator {
 		// therefore every call to an accessor sets up a defer that tries to recover from panics, converting them to errors.
 		defer func() {
 			if r := recover(); r != nil {
-				errorMsg := fmt.Sprintf("Failed to access '%s': %v", reconstructed, r.(string))
+				errorMsg := fmt.Sprintf("Failed to access '%s': %v", reconstructed, r)
 				err = errors.New(errorMsg)
 				ret = nil
 			}
@@ -331,6 +340,9 @@ func makeAccessorStage(pair []string) evaluationOperator {
 
 			field := coreValue.FieldByName(pair[i])
 			if field != (reflect.Value{}) {
+				if !field.CanInterface() {
+					return nil, errors.New("Unable to access unexported field '" + pair[i] + "' in '" + reconstructed + "'")
+				}
 				value = field.Interface()
 				continue
 			}
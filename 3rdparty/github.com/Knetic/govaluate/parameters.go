@@ -18,6 +18,19 @@ type Parameters interface {
 	Get(name string) (interface{}, error)
 }
 
+/*
+	PathParameters are Parameters which resolve accessors like "resource.labels.env" by themselves,
+	instead of accessing the fields and methods of structs by reflection.
+*/
+type PathParameters interface {
+	Parameters
+
+	/*
+		GetPath gets the value at the given path, whose first element is the name of a parameter.
+	*/
+	GetPath(path []string) (interface{}, error)
+}
+
 type MapParameters map[string]interface{}
 
 func (p MapParameters) Get(name string) (interface{}, error) {
@@ -15,6 +15,17 @@ func (p sanitizedParameters) Get(key string) (interface{}, error) {
 	return castToFloat64(value), nil
 }
 
+// unwrapParameters returns the original Parameters of sanitized ones
+func unwrapParameters(parameters Parameters) Parameters {
+	switch p := parameters.(type) {
+	case *sanitizedParameters:
+		return p.orig
+	case sanitizedParameters:
+		return p.orig
+	}
+	return parameters
+}
+
 func castToFloat64(value interface{}) interface{} {
 	switch value.(type) {
 	case uint8:
@@ -97,12 +97,18 @@ const (
 	BuiltIn_Attr_RequestAction   = "request_action"
 	BuiltIn_Attr_RequestEntity   = "request_entity"
 
-	BuiltIn_Attr_RequestTime    = "request_time"
-	BuiltIn_Attr_RequestYear    = "request_year"
-	BuiltIn_Attr_RequestMonth   = "request_month"
-	BuiltIn_Attr_RequestDay     = "request_day"
-	BuiltIn_Attr_RequestHour    = "request_hour"
-	BuiltIn_Attr_RequestWeekday = "request_weekday"
+	// The request time attributes are in the time zone of request_timezone, which callers can pass to override
+	// the time zone of the service. Callers can pass request_time to override the time of the request as well.
+	BuiltIn_Attr_RequestTime      = "request_time"
+	BuiltIn_Attr_RequestYear      = "request_year"
+	BuiltIn_Attr_RequestMonth     = "request_month"
+	BuiltIn_Attr_RequestDay       = "request_day"
+	BuiltIn_Attr_RequestHour      = "request_hour"
+	BuiltIn_Attr_RequestMinute    = "request_minute"
+	BuiltIn_Attr_RequestWeekday   = "request_weekday"
+	BuiltIn_Attr_RequestDate      = "request_date"
+	BuiltIn_Attr_RequestTimestamp = "request_timestamp"
+	BuiltIn_Attr_RequestTimezone  = "request_timezone"
 )
 
 var reason = []string{
@@ -0,0 +1,185 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: function.proto
+
+/*
+Package pb is a generated protocol buffer package.
+
+It is generated from these files:
+	function.proto
+
+It has these top-level messages:
+	FunctionRequest
+	FunctionResponse
+*/
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "github.com/golang/protobuf/ptypes/struct"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type FunctionRequest struct {
+	// Name of the function, it's NAME in the function URL, or the name of the function definition if the URL
+	// has no path, so that a service can serve more than one function
+	Function string                   `protobuf:"bytes,1,opt,name=function" json:"function,omitempty"`
+	Params   []*google_protobuf.Value `protobuf:"bytes,2,rep,name=params" json:"params,omitempty"`
+}
+
+func (m *FunctionRequest) Reset()                    { *m = FunctionRequest{} }
+func (m *FunctionRequest) String() string            { return proto.CompactTextString(m) }
+func (*FunctionRequest) ProtoMessage()               {}
+func (*FunctionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *FunctionRequest) GetFunction() string {
+	if m != nil {
+		return m.Function
+	}
+	return ""
+}
+
+func (m *FunctionRequest) GetParams() []*google_protobuf.Value {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+type FunctionResponse struct {
+	Result *google_protobuf.Value `protobuf:"bytes,1,opt,name=result" json:"result,omitempty"`
+	// Error is the reason why the function fails, result is ignored if it's not empty
+	Error string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *FunctionResponse) Reset()                    { *m = FunctionResponse{} }
+func (m *FunctionResponse) String() string            { return proto.CompactTextString(m) }
+func (*FunctionResponse) ProtoMessage()               {}
+func (*FunctionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *FunctionResponse) GetResult() *google_protobuf.Value {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+func (m *FunctionResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*FunctionRequest)(nil), "speedle.ext.FunctionRequest")
+	proto.RegisterType((*FunctionResponse)(nil), "speedle.ext.FunctionResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for CustomFunction service
+
+type CustomFunctionClient interface {
+	// Call evaluates a custom function with the parameters passed in a condition
+	Call(ctx context.Context, in *FunctionRequest, opts ...grpc.CallOption) (*FunctionResponse, error)
+}
+
+type customFunctionClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCustomFunctionClient(cc *grpc.ClientConn) CustomFunctionClient {
+	return &customFunctionClient{cc}
+}
+
+func (c *customFunctionClient) Call(ctx context.Context, in *FunctionRequest, opts ...grpc.CallOption) (*FunctionResponse, error) {
+	out := new(FunctionResponse)
+	err := grpc.Invoke(ctx, "/speedle.ext.CustomFunction/Call", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for CustomFunction service
+
+type CustomFunctionServer interface {
+	// Call evaluates a custom function with the parameters passed in a condition
+	Call(context.Context, *FunctionRequest) (*FunctionResponse, error)
+}
+
+func RegisterCustomFunctionServer(s *grpc.Server, srv CustomFunctionServer) {
+	s.RegisterService(&_CustomFunction_serviceDesc, srv)
+}
+
+func _CustomFunction_Call_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FunctionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CustomFunctionServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/speedle.ext.CustomFunction/Call",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CustomFunctionServer).Call(ctx, req.(*FunctionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CustomFunction_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "speedle.ext.CustomFunction",
+	HandlerType: (*CustomFunctionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    _CustomFunction_Call_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "function.proto",
+}
+
+func init() { proto.RegisterFile("function.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 222 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0xcf, 0xc1, 0x4a, 0x03, 0x41,
+	0x0c, 0x06, 0x60, 0xbb, 0xd6, 0xa2, 0x29, 0x54, 0x19, 0x44, 0x96, 0xa5, 0x42, 0xd9, 0x53, 0x4f,
+	0x29, 0xd4, 0x37, 0xb0, 0xe8, 0x03, 0xec, 0x41, 0x45, 0xf0, 0x30, 0x5b, 0xd3, 0x22, 0x4c, 0x37,
+	0xe3, 0x24, 0x03, 0x3e, 0xbe, 0x38, 0xd3, 0x55, 0x11, 0xec, 0x31, 0xe4, 0xcf, 0xc7, 0x1f, 0x98,
+	0x6c, 0x62, 0xb7, 0xd6, 0x37, 0xee, 0xd0, 0x07, 0x56, 0x36, 0x63, 0xf1, 0x44, 0xaf, 0x8e, 0x90,
+	0x3e, 0xb4, 0x9a, 0x6e, 0x99, 0xb7, 0x8e, 0x16, 0x69, 0xd5, 0xc6, 0xcd, 0x42, 0x34, 0xc4, 0xb5,
+	0xe6, 0x68, 0xfd, 0x02, 0xe7, 0xf7, 0xfb, 0xe3, 0x86, 0xde, 0x23, 0x89, 0x9a, 0x0a, 0x4e, 0x7b,
+	0xaf, 0x1c, 0xcc, 0x06, 0xf3, 0xb3, 0xe6, 0x7b, 0x36, 0x08, 0x23, 0x6f, 0x83, 0xdd, 0x49, 0x59,
+	0xcc, 0x8e, 0xe7, 0xe3, 0xe5, 0x15, 0x66, 0x1d, 0x7b, 0x1d, 0x1f, 0xac, 0x8b, 0xd4, 0xec, 0x53,
+	0xf5, 0x13, 0x5c, 0xfc, 0xf0, 0xe2, 0xb9, 0x13, 0xfa, 0x32, 0x02, 0x49, 0x74, 0x9a, 0xf4, 0x03,
+	0x46, 0x4e, 0x99, 0x4b, 0x38, 0xa1, 0x10, 0x38, 0x94, 0x45, 0x2a, 0x93, 0x87, 0xe5, 0x23, 0x4c,
+	0x56, 0x51, 0x94, 0x77, 0xbd, 0x6f, 0xee, 0x60, 0xb8, 0xb2, 0xce, 0x99, 0x29, 0xfe, 0x7a, 0x1f,
+	0xff, 0x7c, 0x57, 0x5d, 0xff, 0xb3, 0xcd, 0xe5, 0xea, 0xa3, 0xdb, 0xe1, 0x73, 0xe1, 0xdb, 0x76,
+	0x94, 0xca, 0xdc, 0x7c, 0x0e, 0x00, 0xef, 0x44, 0xe3, 0x2b, 0x5b, 0x01, 0x00, 0x00,
+}
@@ -61,6 +61,19 @@ type PolicyStoreManager interface {
 	PolicyStoreWatcher
 }
 
+// TenantManager manages tenants, each tenant has its own policy store isolated from others
+type TenantManager interface {
+	CreateTenant(tenant *Tenant) error
+	DeleteTenant(tenantName string) error
+	GetTenant(tenantName string) (*Tenant, error)
+	// UpdateTenant updates the quota and metadata of an existing tenant
+	UpdateTenant(tenant *Tenant) error
+	ListAllTenants() ([]*Tenant, error)
+	// TenantStore returns the policy store of a tenant, the store of DefaultTenant is the one
+	// policies are stored in when multi-tenancy is not used
+	TenantStore(tenantName string) (PolicyStoreManager, error)
+}
+
 type PolicyStoreManagerADS interface {
 	Type() string
 	ReadPolicyStore() (*PolicyStore, error)
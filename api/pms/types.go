@@ -18,6 +18,62 @@ type Function struct {
 	ResultCachable bool              `json:"resultCachable,omitempty"` //false by default
 	ResultTTL      int64             `json:"resultTTL,omitempty"`      // TTL of function result in second
 	Metadata       map[string]string `json:"metadata,omitempty"`
+	// Kind is "http" (default) for the functions served at FuncURL, or "wasm" for the WebAssembly modules run in ADS
+	Kind string `json:"kind,omitempty"`
+	// Module is the WebAssembly binary of a wasm function, it's base64 encoded in JSON
+	Module []byte `json:"module,omitempty"`
+	// Limits are the resource limits of a function call
+	Limits *FunctionLimits `json:"limits,omitempty"`
+	// Retry is the retry policy of failed calls to an http function, calls are not retried by default
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// CircuitBreaker stops calling an http function which keeps failing, it's disabled by default
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+const (
+	// FunctionKindHTTP is the kind of the functions called over http(s)
+	FunctionKindHTTP = "http"
+	// FunctionKindWasm is the kind of the functions compiled to WebAssembly and run in process
+	FunctionKindWasm = "wasm"
+)
+
+// FunctionLimits are the limits of a function call, zero value means the default limit applies
+type FunctionLimits struct {
+	MaxMemoryMB int `json:"maxMemoryMB,omitempty"` // Maximum memory of the module of a wasm function in MiB, 16 by default
+	// Timeout of a call in milliseconds, 1000 by default for wasm functions, and 5000 for http functions. The timeout
+	// applies to each attempt of an http function call if it's retried.
+	TimeoutMS int `json:"timeoutMS,omitempty"`
+	// Maximum number of concurrent calls of a wasm function in an ADS, the number of CPUs by default. The calls beyond
+	// it wait for a running one to complete, and fail if they can't start within the timeout.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+}
+
+// RetryPolicy retries the calls to an http function which fail by network errors, timeouts, or http status 429 or
+// 5xx. Errors returned by the function itself are never retried. A call is not retried once it has taken as long
+// as the timeout of the function, so a call with its retries ends within twice the timeout.
+type RetryPolicy struct {
+	MaxRetries int `json:"maxRetries,omitempty"` // Maximum number of retries of a call, at most MaxFunctionRetries
+	BackoffMS  int `json:"backoffMS,omitempty"`  // Milliseconds before the first retry, doubled for every next retry up to MaxFunctionRetryBackoffMS, 100 by default
+}
+
+const (
+	// MaxFunctionRetries is the maximum number of retries of a call to a function
+	MaxFunctionRetries = 5
+	// MaxFunctionRetryBackoffMS is the maximum milliseconds between two retries of a call to a function
+	MaxFunctionRetryBackoffMS = 5000
+)
+
+// CircuitBreaker opens the circuit of an http function after FailureThreshold consecutive calls fail, calls are
+// not sent to the function while the circuit is open. One trial call is sent after OpenSeconds, the circuit is
+// closed if it succeeds, or open again if it fails.
+type CircuitBreaker struct {
+	FailureThreshold int `json:"failureThreshold,omitempty"` // 5 by default
+	OpenSeconds      int `json:"openSeconds,omitempty"`      // 30 by default
+	// FailOpen makes failed calls and the calls rejected by an open circuit return FallbackResult, the fallback
+	// result is never cached. Those calls return errors by default, so that the conditions calling the function
+	// fail (fail closed).
+	FailOpen       bool        `json:"failOpen,omitempty"`
+	FallbackResult interface{} `json:"fallbackResult,omitempty"`
 }
 
 type Policy struct {
@@ -62,11 +118,54 @@ type Service struct {
 
 const GlobalService = "global"
 
+// ServiceTimezoneKey is the key of the service metadata specifying the IANA time zone like "Europe/Paris",
+// which the request time attributes of the service are in. It's the local time zone of the server by default.
+const ServiceTimezoneKey = "timezone"
+
 type PolicyStore struct {
 	Functions []*Function `json:"functions,omitempty"`
 	Services  []*Service  `json:"services,omitempty"`
 }
 
+// DefaultTenant is the tenant which requests without tenant information are scoped to
+const DefaultTenant = "default"
+
+// Quota is the limits applied to the entities of a tenant, negative value means no limit,
+// and zero value means the default limit of the server applies
+type Quota struct {
+	MaxServiceNum  int64 `json:"maxServiceNum,omitempty"`  // Maximum number of service
+	MaxPolicyNum   int64 `json:"maxPolicyNum,omitempty"`   // Maximum number of Policy + RolePolicy
+	MaxFunctionNum int64 `json:"maxFunctionNum,omitempty"` // Maximum number of function
+	MaxPolicySize  int64 `json:"maxPolicySize,omitempty"`  // Maximum size in bytes for a Policy or RolePolicy
+	// Services are the limits applied to individual services, keyed by service name
+	Services map[string]*ServiceQuota `json:"services,omitempty"`
+}
+
+// ServiceQuota is the limits applied to the policies of a service, negative value means no limit,
+// and zero value means the limit of the tenant applies
+type ServiceQuota struct {
+	MaxPolicyNum  int64 `json:"maxPolicyNum,omitempty"`  // Maximum number of Policy + RolePolicy in the service
+	MaxPolicySize int64 `json:"maxPolicySize,omitempty"` // Maximum size in bytes for a Policy or RolePolicy in the service
+}
+
+// QuotaUsage reports the usage of a tenant against its quota
+type QuotaUsage struct {
+	Tenant      string `json:"tenant"`
+	Quota       *Quota `json:"quota"` // The limits in effect
+	ServiceNum  int64  `json:"serviceNum"`
+	PolicyNum   int64  `json:"policyNum"` // Number of Policy + RolePolicy
+	FunctionNum int64  `json:"functionNum"`
+	// Services is the number of Policy + RolePolicy in each service
+	Services map[string]int64 `json:"services,omitempty"`
+}
+
+// Tenant is an isolated namespace of services, policies and functions
+type Tenant struct {
+	Name     string            `json:"name"`
+	Quota    *Quota            `json:"quota,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
 type PolicyAndRolePolicyCount struct {
 	PolicyCount     int64 `json:"policycount,omitempty"`
 	RolePolicyCount int64 `json:"rolePolicycount,omitempty"`
@@ -3,5 +3,7 @@
 homeDir=$(cd $(dirname ${BASH_SOURCE[0]})/.. > /dev/null; pwd -P)
 
 export GOPATH=$homeDir
+# Dependencies are vendored by dep, so the build runs in GOPATH mode
+export GO111MODULE=off
 mkdir -p $GOPATH/src/github.com/oracle
 ln -s $homeDir $GOPATH/src/github.com/oracle/speedle
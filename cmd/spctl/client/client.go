@@ -12,9 +12,11 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/svcs"
 )
 
 type Client struct {
@@ -69,7 +71,8 @@ func (c *Client) Delete(paths []string, token string) error {
 	return c.delete(u, token)
 }
 
-func (c *Client) get(u *url.URL, paths []string, params url.Values, token string) ([]byte, error) {
+// get sends a GET request, and returns the response body and the token to get the next page of a list
+func (c *Client) get(u *url.URL, paths []string, params url.Values, token string) ([]byte, string, error) {
 	if params != nil {
 		q := u.Query()
 		for name, val := range params {
@@ -80,7 +83,7 @@ func (c *Client) get(u *url.URL, paths []string, params url.Values, token string
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	setAuthorizationHeader(req, token)
@@ -88,21 +91,21 @@ func (c *Client) get(u *url.URL, paths []string, params url.Values, token string
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		fmt.Printf("resp is : %v\n err is : %v\n", resp, err)
-		return nil, err
+		return nil, "", err
 	}
 	switch resp.StatusCode {
 	case http.StatusNotFound:
-		return nil, fmt.Errorf("%s not found", strings.Join(paths, " "))
+		return nil, "", fmt.Errorf("%s not found", strings.Join(paths, " "))
 	case http.StatusOK:
 		defer resp.Body.Close()
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
-		return body, nil
+		return body, resp.Header.Get(svcs.ContinueHeader), nil
 	case http.StatusUnauthorized, http.StatusForbidden:
 		fmt.Println("Authentication or authorization failed. Please specify correct token using '--token' flag.")
-		return nil, errors.New(resp.Status)
+		return nil, "", errors.New(resp.Status)
 	case http.StatusBadRequest:
 		defer resp.Body.Close()
 		body, err := ioutil.ReadAll(resp.Body)
@@ -110,12 +113,12 @@ func (c *Client) get(u *url.URL, paths []string, params url.Values, token string
 			var errorDetail httputils.ErrorResponse
 			err = json.Unmarshal(body, &errorDetail)
 			if err == nil {
-				return nil, errors.New(fmt.Sprintf("%s: %s", resp.Status, errorDetail.Error))
+				return nil, "", errors.New(fmt.Sprintf("%s: %s", resp.Status, errorDetail.Error))
 			}
 		}
-		return nil, errors.New(resp.Status)
+		return nil, "", errors.New(resp.Status)
 	default:
-		return nil, errors.New(resp.Status)
+		return nil, "", errors.New(resp.Status)
 	}
 }
 func (c *Client) Get(paths []string, params url.Values, token string) ([]byte, error) {
@@ -123,7 +126,40 @@ func (c *Client) Get(paths []string, params url.Values, token string) ([]byte, e
 	if err != nil {
 		return nil, err
 	}
-	return c.get(u, paths, params, token)
+	body, _, err := c.get(u, paths, params, token)
+	return body, err
+}
+
+// GetAll gets a list page by page, pageSize is the max number of elements in a page, and returns all elements in
+// one JSON array
+func (c *Client) GetAll(paths []string, params url.Values, pageSize int, token string) ([]byte, error) {
+	u, err := c.pmsURL(paths)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	for name, val := range params {
+		query[name] = val
+	}
+	if pageSize > 0 {
+		query.Set("limit", strconv.Itoa(pageSize))
+	}
+	elements := []json.RawMessage{}
+	for {
+		body, next, err := c.get(u, paths, query, token)
+		if err != nil {
+			return nil, err
+		}
+		page := []json.RawMessage{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		elements = append(elements, page...)
+		if len(next) == 0 {
+			return json.Marshal(elements)
+		}
+		query.Set("continue", next)
+	}
 }
 
 func (c *Client) post(u *url.URL, paths []string, payload io.Reader, token string) (string, error) {
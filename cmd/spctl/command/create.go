@@ -19,6 +19,7 @@ import (
 	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/cmd/spctl/client"
 	"github.com/oracle/speedle/cmd/spctl/pdl"
+	"github.com/oracle/speedle/pkg/store/file"
 )
 
 var (
@@ -29,6 +30,11 @@ var (
 	funcURL            string
 	funcResultCachable bool
 	funcResultTTL      int64
+	wasmFileName       string
+	funcMaxMemoryMB    int
+	funcTimeoutMS      int
+	funcMaxConcurrency int
+	funcMaxRetries     int
 )
 
 var (
@@ -54,6 +60,22 @@ var (
 		grant user User1 GET /service/service1
 		---------------------------------------------------------
 
+		# Create a service and the functions its policies use with a file in SPDL format, whose name ends with .spdl
+		spctl create service service1 --pdl-file service1.spdl
+		sample spdl file:
+		--------------------------------------------------------
+		[functions]
+		isWorkday https://funcs.example.com/isWorkday cachable ttl=3600
+		[service.service1]
+		type = k8s
+		[policy]
+		@name administrators can manage service on workdays
+		p1: grant group Administrators GET,POST,DELETE expr:/service/* \
+		        if isWorkday()
+		[rolepolicy]
+		grant user User1 Role1 on res1
+		---------------------------------------------------------
+
 		# Create a policy with name "p01" using pdl
 		spctl create policy p01 --pdl-command "grant group Administrators list,watch,get expr:c1/default/core/pods/*" --service-name=service1
 
@@ -69,6 +91,12 @@ var (
 		# Create a function "foo", funcUrl , cacheResult, cacheTTL 
 		spctl create function foo --func-url=https://a.b.c:3456/funcs/foo --cachable=true --cache-ttl=3600
 
+		# Create a function "bar" running the WebAssembly module bar.wasm in ADS, with 8 MiB memory and 200 ms timeout
+		spctl create function bar --wasm-file=bar.wasm --max-memory-mb=8 --timeout-ms=200
+
+		# Create a function "baz" which is retried twice if it fails, the client certificate presented to it is configured in ADS
+		spctl create function baz --func-url=https://a.b.c:3456/funcs/baz --max-retries=2
+
 		# Create a function using function definition json file
 		spctl create function --json-file=function.json`
 )
@@ -89,6 +117,11 @@ func NewCreateCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&funcURL, "func-url", "", "", "URL for the function")
 	cmd.Flags().BoolVarP(&funcResultCachable, "cachable", "", false, "whether the function result is cachable")
 	cmd.Flags().Int64VarP(&funcResultTTL, "cache-ttl", "", 0, "How many seconds could the function result be kept in cache, 0 means the result could be kept in cache forever")
+	cmd.Flags().StringVarP(&wasmFileName, "wasm-file", "", "", "WebAssembly module of the function, which runs in ADS instead of calling the function URL")
+	cmd.Flags().IntVarP(&funcMaxMemoryMB, "max-memory-mb", "", 0, "Maximum memory in MiB of the WebAssembly function, 0 means 16")
+	cmd.Flags().IntVarP(&funcTimeoutMS, "timeout-ms", "", 0, "Timeout in milliseconds of calling the function, 0 means 1000 for WebAssembly functions and 5000 for the others")
+	cmd.Flags().IntVarP(&funcMaxConcurrency, "max-concurrency", "", 0, "Maximum number of concurrent calls of the WebAssembly function in an ADS, 0 means the number of CPUs")
+	cmd.Flags().IntVarP(&funcMaxRetries, "max-retries", "", 0, "Maximum number of retries of the calls failed by network errors, timeouts or http status 429 and 5xx, at most 5")
 	return cmd
 }
 
@@ -142,6 +175,37 @@ func parsePdlFile(pdlFileName string, serviceName, serviceType string) (*pms.Ser
 	return &service, err
 }
 
+// parseSPDLFile reads service serviceName and the functions defined in a file in SPDL format. If the file doesn't define
+// service serviceName, its only service is used. The service type in the file is overridden by flag --service-type if set.
+func parseSPDLFile(fileName string, serviceName, serviceType string, typeChanged bool) (*pms.Service, []*pms.Function, error) {
+	ps, err := file.ParseSPDLFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var service *pms.Service
+	for _, svc := range ps.Services {
+		if svc.Name == serviceName {
+			service = svc
+		}
+	}
+	if service == nil {
+		switch len(ps.Services) {
+		case 0:
+			service = &pms.Service{}
+		case 1:
+			service = ps.Services[0]
+		default:
+			return nil, nil, fmt.Errorf("service %s is not defined in %s", serviceName, fileName)
+		}
+		service.Name = serviceName
+	}
+	if typeChanged || len(service.Type) == 0 {
+		service.Type = serviceType
+	}
+	return service, ps.Functions, nil
+}
+
 func createCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) < 1 {
 		cmd.Help()
@@ -180,6 +244,19 @@ func createCommandFunc(cmd *cobra.Command, args []string) {
 			if pdlFileName == "" {
 				service := pms.Service{Name: serviceName, Type: serviceType}
 				buf, err = json.Marshal(service)
+			} else if strings.HasSuffix(pdlFileName, ".spdl") {
+				var service *pms.Service
+				var functions []*pms.Function
+				service, functions, err = parseSPDLFile(pdlFileName, serviceName, serviceType, cmd.Flags().Changed("service-type"))
+				for i := 0; err == nil && i < len(functions); i++ {
+					var funcBuf []byte
+					if funcBuf, err = json.Marshal(functions[i]); err == nil {
+						_, err = cli.Post([]string{"function"}, bytes.NewBuffer(funcBuf), "")
+					}
+				}
+				if err == nil {
+					buf, err = json.Marshal(service)
+				}
 			} else {
 				var service *pms.Service
 				service, err = parsePdlFile(pdlFileName, serviceName, serviceType)
@@ -247,7 +324,18 @@ func createCommandFunc(cmd *cobra.Command, args []string) {
 				ResultCachable: funcResultCachable,
 				ResultTTL:      funcResultTTL,
 			}
-			buf, err = json.Marshal(function)
+			if funcMaxMemoryMB != 0 || funcTimeoutMS != 0 || funcMaxConcurrency != 0 {
+				function.Limits = &pms.FunctionLimits{MaxMemoryMB: funcMaxMemoryMB, TimeoutMS: funcTimeoutMS, MaxConcurrency: funcMaxConcurrency}
+			}
+			if wasmFileName != "" {
+				function.Kind = pms.FunctionKindWasm
+				function.Module, err = ioutil.ReadFile(wasmFileName)
+			} else if funcMaxRetries != 0 {
+				function.Retry = &pms.RetryPolicy{MaxRetries: funcMaxRetries}
+			}
+			if err == nil {
+				buf, err = json.Marshal(function)
+			}
 
 		}
 		if err == nil {
@@ -6,6 +6,7 @@ package command
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 
@@ -19,6 +20,8 @@ import (
 var (
 	all         bool
 	serviceName string
+	filter      string
+	pageSize    int
 )
 
 var (
@@ -31,6 +34,9 @@ var (
 		
 		# List all policies in service "foo"
 		spctl get policy --all --service-name=foo
+
+		# List the policies granting to group "finance" in service "foo"
+		spctl get policy --all --service-name=foo --filter='effect eq grant and principal eq "group:finance"'
 		
 		# List the policy with id "1" in service "foo"
 		spctl get policy 1 --service-name=foo
@@ -52,6 +58,8 @@ func NewGetCommand() *cobra.Command {
 
 	cmd.Flags().BoolVarP(&all, "all", "a", false, "Get all elements")
 	cmd.Flags().StringVar(&serviceName, "service-name", "", "Service name")
+	cmd.Flags().StringVar(&filter, "filter", "", "Filter expression of elements got with --all, e.g. 'name sw read and created ge 2019-01-01'")
+	cmd.Flags().IntVar(&pageSize, "page-size", 500, "Max number of elements got in a request with --all, 0 means no limit")
 	return cmd
 }
 
@@ -72,11 +80,15 @@ func getCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	var res []byte
 	var output = []byte{}
+	var params url.Values
+	if len(filter) > 0 {
+		params = url.Values{"filter": {filter}}
+	}
 
 	switch strings.ToLower(args[0]) {
 	case "service":
 		if all {
-			res, err = cli.Get([]string{"service"}, nil, "")
+			res, err = cli.GetAll([]string{"service"}, params, pageSize, "")
 			if err == nil {
 				services := []pms.Service{}
 				if json.Unmarshal(res, &services) == nil {
@@ -113,7 +125,7 @@ func getCommandFunc(cmd *cobra.Command, args []string) {
 			kind = "role-policy"
 		}
 		if all {
-			res, err = cli.Get([]string{"service", serviceName, kind}, nil, "")
+			res, err = cli.GetAll([]string{"service", serviceName, kind}, params, pageSize, "")
 
 			if err == nil {
 				var policies interface{}
@@ -153,7 +165,7 @@ func getCommandFunc(cmd *cobra.Command, args []string) {
 		}
 	case "function":
 		if all {
-			res, err = cli.Get([]string{"function"}, nil, "")
+			res, err = cli.GetAll([]string{"function"}, params, pageSize, "")
 			if err == nil {
 				functions := []pms.Function{}
 				if json.Unmarshal(res, &functions) == nil {
@@ -16,6 +16,8 @@ import (
 	"path"
 	"strings"
 	"time"
+
+	"github.com/oracle/speedle/pkg/svcs"
 )
 
 type GlobalFlags struct {
@@ -25,6 +27,7 @@ type GlobalFlags struct {
 	KeyFile            string
 	CAFile             string
 	InsecureSkipVerify bool
+	Tenant             string
 }
 
 const (
@@ -45,7 +48,7 @@ func httpClient() (*http.Client, error) {
 	if strings.HasPrefix(strings.ToLower(globalFlags.PMSEndpoint), "http:") {
 		return &http.Client{
 			Timeout:   globalFlags.Timeout,
-			Transport: tr,
+			Transport: tenantTransport(tr),
 		}, nil
 	}
 
@@ -79,12 +82,31 @@ func httpClient() (*http.Client, error) {
 
 	return &http.Client{
 		Timeout: globalFlags.Timeout,
-		Transport: &http.Transport{
+		Transport: tenantTransport(&http.Transport{
 			TLSClientConfig: tlsConfig,
-		},
+		}),
 	}, nil
 }
 
+// tenantRoundTripper scopes each request to the tenant specified by '--tenant' flag
+type tenantRoundTripper struct {
+	tenant string
+	next   http.RoundTripper
+}
+
+func (t *tenantRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(svcs.TenantHeader, t.tenant)
+	return t.next.RoundTrip(req)
+}
+
+func tenantTransport(tr http.RoundTripper) http.RoundTripper {
+	if globalFlags.Tenant == "" {
+		return tr
+	}
+	return &tenantRoundTripper{tenant: globalFlags.Tenant, next: tr}
+}
+
 func readConfigFile() (map[string]string, error) {
 	flags := make(map[string]string)
 	u, err := user.Current()
@@ -0,0 +1,88 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/oracle/speedle/cmd/spctl/client"
+	"github.com/oracle/speedle/pkg/store"
+
+	"github.com/spf13/cobra"
+)
+
+var searchQuery store.SearchQuery
+
+var (
+	searchExample = `
+		# Search the policies and role policies of all services granting to user "alice"
+		spctl search --principal=user:alice
+
+		# Search the policies and role policies of all services about resource "/books"
+		spctl search --resource=/books
+
+		# Search the policies of all services allowing action "read" on resource "/books"
+		spctl search --resource=/books --action=read
+
+		# Search the policies and role policies of all services about role "auditor"
+		spctl search --role=auditor`
+)
+
+func NewSearchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "search [--principal=PRINCIPAL] [--resource=RESOURCE] [--role=ROLE] [--action=ACTION]",
+		Short:   "Search policies and role-policies of all services",
+		Example: searchExample,
+		Run:     searchCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&searchQuery.Principal, "principal", "", "Principal in policies and role policies, e.g. user:alice")
+	cmd.Flags().StringVar(&searchQuery.Resource, "resource", "", "Resource in policies and role policies, matched by resource expressions as well")
+	cmd.Flags().StringVar(&searchQuery.Role, "role", "", "Role granted by role policies, or granted to by policies")
+	cmd.Flags().StringVar(&searchQuery.Action, "action", "", "Action in the permissions of policies")
+	return cmd
+}
+
+func searchCommandFunc(cmd *cobra.Command, args []string) {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"principal": searchQuery.Principal,
+		"resource":  searchQuery.Resource,
+		"role":      searchQuery.Role,
+		"action":    searchQuery.Action,
+	} {
+		if len(value) > 0 {
+			params.Set(name, value)
+		}
+	}
+	if len(args) > 0 || len(params) == 0 {
+		cmd.Help()
+		return
+	}
+
+	hc, err := httpClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	cli := &client.Client{
+		PMSEndpoint: globalFlags.PMSEndpoint,
+		HTTPClient:  hc,
+	}
+	res, err := cli.Get([]string{"search"}, params, "")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	results := []*store.SearchResult{}
+	var output []byte
+	if json.Unmarshal(res, &results) == nil {
+		output, _ = json.MarshalIndent(&results, "", strings.Repeat(" ", 4))
+	}
+	fmt.Println(string(output))
+}
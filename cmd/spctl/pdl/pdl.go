@@ -478,6 +478,17 @@ func getErrorIndicator(cmd string, pos int) string {
 	return fmt.Sprintf("%s\n%s", cmd, buffer.String())
 }
 
+// SyntaxError is returned when a PDL command is malformed, Pos is the offset in Cmd where the error is found
+type SyntaxError struct {
+	Msg string
+	Cmd string
+	Pos int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s\n%s", e.Msg, getErrorIndicator(e.Cmd, e.Pos))
+}
+
 func getError(msg, cmd string, pos int) error {
-	return fmt.Errorf("%s\n%s", msg, getErrorIndicator(cmd, pos))
+	return &SyntaxError{Msg: msg, Cmd: cmd, Pos: pos}
 }
@@ -6,16 +6,14 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 
-	adsapi "github.com/oracle/speedle/api/ads"
-	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/pkg/cfg"
 	"github.com/oracle/speedle/pkg/cmd/flags"
-	"github.com/oracle/speedle/pkg/errors"
 	"github.com/oracle/speedle/pkg/eval"
 	"github.com/oracle/speedle/pkg/logging"
 	"github.com/oracle/speedle/pkg/store"
@@ -45,7 +43,7 @@ func main() {
 	storeParamsMap := store.GetAllStoreParams()
 
 	var params flags.Parameters
-	params.ParseFlags(flags.DefaultAuthzCheckEndPoint, printVersionInfo, storeParamsMap)
+	params.ParseFlags(flags.DefaultAuthzCheckEndPoint, flags.DefaultAuthzCheckGRPCEndPoint, printVersionInfo, storeParamsMap)
 	params.ValidateFlags()
 
 	conf, _ := params.Param2Config(storeParamsMap)
@@ -70,34 +68,65 @@ func main() {
 		log.Error("No any audit log configurations for authorization service.\n")
 	}
 
-	evaluator, err := newEvaluator(conf)
+	evaluators, err := newTenantEvaluators(conf)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	httpServer, err := newHTTPServer(&params, evaluator)
+	httpServer, err := newHTTPServer(&params, evaluators)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	grpcServer, err := newGRPCServer(evaluator)
+	grpcServer, err := newGRPCServer(&params, evaluators)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	adminServer, err := params.NewAdminServer(adsrest.NewAdminRouter(evaluators))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	reloader := params.NewConfigReloader(conf, storeParamsMap)
+	reloader.OnChange(func(oldConf, newConf *cfg.Config) {
+		applyConfigChange(evaluators, oldConf, newConf)
+	})
+	if err := reloader.Watch(stopChan); err != nil {
+		log.Warningf("Configuration hot reload is disabled, err: %v.", err)
+	}
+
 	intChan := make(chan os.Signal, 1)
 	signal.Notify(intChan, os.Interrupt)
 
-	errChan := make(chan error, 2)
-	go func() {
-		log.Info("Starting the gRPC server for authorization service...")
-		errChan <- listenGRPCServer(grpcServer)
-	}()
-
-	go func() {
-		log.Info("Starting the REST server for authorization service...")
-		errChan <- params.ListenAndServe(httpServer)
-	}()
+	errChan := make(chan error, 3)
+	if params.SharedPort() {
+		go func() {
+			log.Info("Starting the REST and gRPC server on one port for authorization service...")
+			errChan <- params.ListenAndServeShared(httpServer, grpcServer)
+		}()
+	} else {
+		if grpcServer != nil {
+			go func() {
+				log.Info("Starting the gRPC server for authorization service...")
+				errChan <- params.ListenAndServeGRPC(grpcServer)
+			}()
+		}
+		if httpServer != nil {
+			go func() {
+				log.Info("Starting the REST server for authorization service...")
+				errChan <- params.ListenAndServe(httpServer)
+			}()
+		}
+	}
+	if adminServer != nil {
+		go func() {
+			log.Info("Starting the admin REST server for authorization service...")
+			errChan <- params.ListenAndServe(adminServer)
+		}()
+	}
 
 	err = nil
 	select {
@@ -115,6 +144,10 @@ func main() {
 		log.Info("Stopping HTTP Server.")
 		httpServer.Shutdown(context.Background())
 	}
+	if adminServer != nil {
+		log.Info("Stopping admin HTTP Server.")
+		adminServer.Shutdown(context.Background())
+	}
 	if grpcServer != nil {
 		log.Info("Stopping GRPC Server.")
 		grpcServer.Stop()
@@ -125,69 +158,108 @@ func main() {
 	}
 }
 
-func newEvaluator(conf *cfg.Config) (eval.InternalEvaluator, error) {
-	evaluator, err := eval.NewFromConfig(conf)
+// newTenantEvaluators creates the evaluators of the default tenant and, if the policy store supports multi-tenancy,
+// of the other tenants on demand
+func newTenantEvaluators(conf *cfg.Config) (*eval.TenantEvaluators, error) {
+	if err := eval.SetFunctionClientCerts(conf.FunctionClientCerts); err != nil {
+		return nil, err
+	}
+	s, err := store.NewStore(conf.StoreConfig.StoreType, conf.StoreConfig.StoreProps)
 	if err != nil {
 		return nil, err
 	}
+	// The asserter is loaded below and set to the evaluators of all the tenants, together with its cache
+	evalConf := *conf
+	evalConf.AsserterWebhookConfig, evalConf.JWTAsserterConfig = nil, nil
+	evaluator, err := eval.NewWithStore(&evalConf, s)
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := store.NewTenantManager(s)
+	if err != nil {
+		log.Warningf("Multi-tenancy is disabled, err: %v.", err)
+		tenants = nil
+	}
+	evaluators := eval.NewTenantEvaluators(conf, evaluator, tenants)
 
 	log.Info("Loading asserters.")
-	as, errLoadAsserter := assertion.NewAsserter(conf.AsserterWebhookConfig, nil)
+	f, cache, errLoadAsserter := eval.NewAsserterFuncFromConfig(conf)
 	if errLoadAsserter != nil {
 		log.Warningf("load asserter error: %v", errLoadAsserter)
 	} else {
-		f := func(ctx *adsapi.RequestContext) error {
-			if ctx.Subject != nil &&
-				len(ctx.Subject.TokenType) != 0 &&
-				len(ctx.Subject.Token) != 0 {
-				tokenType := ctx.Subject.TokenType
-				token := ctx.Subject.Token
-				log.Debugf("Asserting token %s with token type %s.", token, tokenType)
-				s, err := as.AssertToken(token, tokenType, "", nil)
-				if err == nil {
-					for _, p := range s.Principals {
-						ctx.Subject.Principals = append(ctx.Subject.Principals, p)
-					}
-				} else {
-					log.Errorf("Failed to assert due to error %v.", err)
-					return err
-				}
-			}
-			return nil
+		evaluators.SetAsserterFunc(f)
+		evaluators.SetAssertionCache(cache)
+	}
+
+	return evaluators, nil
+}
+
+// applyConfigChange applies the reloaded asserter and function service configurations to the evaluators
+func applyConfigChange(evaluators *eval.TenantEvaluators, oldConf, newConf *cfg.Config) {
+	if oldConf.AsserterType != newConf.AsserterType ||
+		!reflect.DeepEqual(oldConf.AsserterWebhookConfig, newConf.AsserterWebhookConfig) ||
+		!reflect.DeepEqual(oldConf.JWTAsserterConfig, newConf.JWTAsserterConfig) ||
+		!reflect.DeepEqual(oldConf.AssertionCacheConfig, newConf.AssertionCacheConfig) {
+		if newConf.AsserterWebhookConfig == nil && newConf.JWTAsserterConfig == nil {
+			log.Info("Asserter is removed from configuration.")
+			evaluators.SetAsserterFunc(nil)
+			evaluators.SetAssertionCache(nil)
+		} else if f, cache, err := eval.NewAsserterFuncFromConfig(newConf); err != nil {
+			log.Errorf("Failed to load the new asserter, keep using the previous one, err: %v.", err)
+		} else {
+			// The results cached by the previous asserter are dropped with it
+			log.Infof("Asserter is changed, asserter type: %q.", newConf.AsserterType)
+			evaluators.SetAsserterFunc(f)
+			evaluators.SetAssertionCache(cache)
 		}
-		evaluator.SetAsserterFunc(f)
 	}
 
-	return evaluator, nil
+	if oldConf.FuncsvcEndpoint != newConf.FuncsvcEndpoint {
+		log.Infof("Function service endpoint is changed to %q.", newConf.FuncsvcEndpoint)
+		evaluators.SetFuncSvcEndpoint(newConf.FuncsvcEndpoint)
+	}
+
+	if !reflect.DeepEqual(oldConf.FunctionClientCerts, newConf.FunctionClientCerts) {
+		// New connections to the functions present the new certificates, the pooled ones are kept
+		if err := eval.SetFunctionClientCerts(newConf.FunctionClientCerts); err != nil {
+			log.Errorf("Failed to load the new function client certificates, keep using the previous ones, err: %v.", err)
+		} else {
+			log.Info("Function client certificates are changed.")
+		}
+	}
 }
 
-func newGRPCServer(evaluator eval.InternalEvaluator) (*grpc.Server, error) {
+func newGRPCServer(params *flags.Parameters, evaluators *eval.TenantEvaluators) (*grpc.Server, error) {
+	if params.GRPCDisabled() {
+		log.Info("gRPC server is disabled.")
+		return nil, nil
+	}
 
+	evaluator, err := evaluators.Evaluator(pms.DefaultTenant)
+	if err != nil {
+		return nil, err
+	}
 	serviceImpl, err := adsgrpc.NewGRPCService(evaluator)
 	if err != nil {
 		return nil, err
 	}
 
-	server := grpc.NewServer()
+	server, err := params.NewGRPCServer(grpc.UnaryInterceptor(adsgrpc.NewTenantInterceptor(evaluators)))
+	if err != nil {
+		return nil, err
+	}
 	pb.RegisterEvaluatorServer(server, serviceImpl)
 	// Register reflection service on gRPC server.
 	reflection.Register(server)
 	return server, nil
 }
 
-func listenGRPCServer(server *grpc.Server) error {
-	lis, err := net.Listen("tcp", ":50002")
-	if err != nil {
-		return errors.Wrap(err, errors.ServerError, "failed to listen on endpoint :50002")
-	}
-	if err := server.Serve(lis); err != nil {
-		return errors.Wrap(err, errors.ServerError, "failed to serve for endpoint :50002")
+func newHTTPServer(params *flags.Parameters, evaluators *eval.TenantEvaluators) (*http.Server, error) {
+	if params.RESTDisabled() {
+		log.Info("REST server is disabled.")
+		return nil, nil
 	}
-	return nil
-}
-
-func newHTTPServer(params *flags.Parameters, evaluator eval.InternalEvaluator) (*http.Server, error) {
-	routers, err := adsrest.NewRouter(evaluator)
+	routers, err := adsrest.NewTenantRouter(evaluators)
 	if err != nil {
 		return nil, err
 	}
@@ -7,6 +7,8 @@
 package main
 
 import (
+	_ "github.com/oracle/speedle/pkg/store/bolt"
 	_ "github.com/oracle/speedle/pkg/store/etcd"
 	_ "github.com/oracle/speedle/pkg/store/file"
+	_ "github.com/oracle/speedle/pkg/store/sqlstore"
 )
@@ -6,18 +6,21 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"strconv"
 
 	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
 	"github.com/oracle/speedle/pkg/cmd/flags"
-	"github.com/oracle/speedle/pkg/errors"
 	"github.com/oracle/speedle/pkg/logging"
 	"github.com/oracle/speedle/pkg/store"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
 	"github.com/oracle/speedle/pkg/svcs/pmsgrpc"
 	"github.com/oracle/speedle/pkg/svcs/pmsgrpc/pb"
+	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
 	"github.com/oracle/speedle/pkg/svcs/pmsrest"
 
 	log "github.com/sirupsen/logrus"
@@ -42,7 +45,7 @@ func main() {
 	storeParamsMap := store.GetAllStoreParams()
 
 	var params flags.Parameters
-	params.ParseFlags(flags.DefaultPolicyMgmtEndPoint, printVersionInfo, storeParamsMap)
+	params.ParseFlags(flags.DefaultPolicyMgmtEndPoint, flags.DefaultPolicyMgmtGRPCEndPoint, printVersionInfo, storeParamsMap)
 	params.ValidateFlags()
 
 	conf, _ := params.Param2Config(storeParamsMap)
@@ -72,29 +75,84 @@ func main() {
 		log.Fatal(err)
 	}
 
-	httpServer, err := newHTTPServer(&params, ps)
+	tenants, err := store.NewTenantManager(ps)
+	if err != nil {
+		log.Warningf("Multi-tenancy is disabled, err: %v.", err)
+		tenants = nil
+	}
+
+	quotas, err := pmsimpl.NewQuotasWithTenants(conf.QuotaConfig, tenants)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	grpcServer, err := newGRPCServer(ps)
+	authorizer, err := newAuthorizer(&params, conf, ps)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	httpServer, err := newHTTPServer(&params, ps, tenants, quotas, authorizer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	grpcServer, err := newGRPCServer(&params, ps, tenants, quotas, authorizer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	reloader := params.NewConfigReloader(conf, storeParamsMap)
+	reloader.OnChange(func(oldConf, newConf *cfg.Config) {
+		if authorizer != nil && !reflect.DeepEqual(oldConf.PMSAuthConfig, newConf.PMSAuthConfig) {
+			log.Info("Applied the new policy management authorization configuration.")
+			authorizer.SetConfig(newConf.PMSAuthConfig)
+		}
+		if authorizer != nil && (oldConf.AsserterType != newConf.AsserterType ||
+			!reflect.DeepEqual(oldConf.AsserterWebhookConfig, newConf.AsserterWebhookConfig) ||
+			!reflect.DeepEqual(oldConf.JWTAsserterConfig, newConf.JWTAsserterConfig)) {
+			if err := authorizer.SetAsserterConfig(newConf); err != nil {
+				log.Errorf("Failed to load the new asserter of bearer token authentication, keep using the previous one, err: %v.", err)
+			} else {
+				log.Infof("Asserter of bearer token authentication is changed, asserter type: %q.", newConf.AsserterType)
+			}
+		}
+		if !reflect.DeepEqual(oldConf.QuotaConfig, newConf.QuotaConfig) {
+			if err := quotas.SetGlobalQuota(newConf.QuotaConfig); err != nil {
+				log.Errorf("Failed to apply the new quota configuration, keep using the previous one, err: %v.", err)
+			} else {
+				log.Info("Applied the new quota configuration.")
+			}
+		}
+	})
+	if err := reloader.Watch(stopChan); err != nil {
+		log.Warningf("Configuration hot reload is disabled, err: %v.", err)
+	}
+
 	intChan := make(chan os.Signal, 1)
 	signal.Notify(intChan, os.Interrupt)
 
 	errChan := make(chan error, 2)
-	go func() {
-		log.Info("Starting the gRPC server for policy management service...")
-		errChan <- listenGRPCServer(grpcServer)
-	}()
-
-	go func() {
-		log.Info("Starting the REST server for policy management service...")
-		errChan <- params.ListenAndServe(httpServer)
-	}()
+	if params.SharedPort() {
+		go func() {
+			log.Info("Starting the REST and gRPC server on one port for policy management service...")
+			errChan <- params.ListenAndServeShared(httpServer, grpcServer)
+		}()
+	} else {
+		if grpcServer != nil {
+			go func() {
+				log.Info("Starting the gRPC server for policy management service...")
+				errChan <- params.ListenAndServeGRPC(grpcServer)
+			}()
+		}
+		if httpServer != nil {
+			go func() {
+				log.Info("Starting the REST server for policy management service...")
+				errChan <- params.ListenAndServe(httpServer)
+			}()
+		}
+	}
 
 	err = nil
 	select {
@@ -122,27 +180,45 @@ func main() {
 	}
 }
 
-func newGRPCServer(ps pms.PolicyStoreManager) (*grpc.Server, error) {
-	server := grpc.NewServer()
-	pb.RegisterPolicyManagerServer(server, pmsgrpc.NewServiceImpl(ps))
-	reflection.Register(server)
-	return server, nil
+// newAuthorizer creates the authorizer of policy management APIs if authorization check is enabled
+func newAuthorizer(params *flags.Parameters, conf *cfg.Config, ps pms.PolicyStoreManager) (*pmsauth.Authorizer, error) {
+	enableAuthz, _ := strconv.ParseBool(params.EnableAuthz.Value)
+	if !enableAuthz {
+		return nil, nil
+	}
+	authorizer, err := pmsauth.New(conf, ps)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Policy management APIs are authorized against the policies in service %q.", authorizer.AdminService())
+	return authorizer, nil
 }
 
-func listenGRPCServer(server *grpc.Server) error {
-	// Register reflection service on gRPC server.
-	lis, err := net.Listen("tcp", ":50001")
-	if err != nil {
-		return errors.Wrap(err, errors.ServerError, "failed to listen on endpoint :50001")
+func newGRPCServer(params *flags.Parameters, ps pms.PolicyStoreManager, tenants pms.TenantManager, quotas *pmsimpl.Quotas, authorizer *pmsauth.Authorizer) (*grpc.Server, error) {
+	if params.GRPCDisabled() {
+		log.Info("gRPC server is disabled.")
+		return nil, nil
 	}
-	if err := server.Serve(lis); err != nil {
-		return errors.Wrap(err, errors.ServerError, "failed to serve for endpoint :50001")
+	var interceptors []grpc.UnaryServerInterceptor
+	if authorizer != nil {
+		interceptors = append(interceptors, pmsgrpc.NewAuthzInterceptor(authorizer))
+	}
+	interceptors = append(interceptors, pmsgrpc.NewTenantInterceptor(tenants, ps))
+	server, err := params.NewGRPCServer(grpc.UnaryInterceptor(pmsgrpc.ChainUnaryInterceptors(interceptors...)))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	pb.RegisterPolicyManagerServer(server, pmsgrpc.NewServiceImplWithQuotas(ps, quotas))
+	reflection.Register(server)
+	return server, nil
 }
 
-func newHTTPServer(params *flags.Parameters, ps pms.PolicyStoreManager) (*http.Server, error) {
-	routers, err := pmsrest.NewRouter(ps)
+func newHTTPServer(params *flags.Parameters, ps pms.PolicyStoreManager, tenants pms.TenantManager, quotas *pmsimpl.Quotas, authorizer *pmsauth.Authorizer) (*http.Server, error) {
+	if params.RESTDisabled() {
+		log.Info("REST server is disabled.")
+		return nil, nil
+	}
+	routers, err := pmsrest.NewTenantRouter(ps, tenants, quotas, authorizer)
 	if err != nil {
 		log.Error("Fail to create handler...")
 		return nil, err
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -49,8 +50,30 @@ type AsserterConfig struct {
 	ClientCert  string `json:"clientCert"`
 	ClientKey   string `json:"clientKey"`
 	HTTPTimeout int    `json:"httpTimeout"`
+	AttributesConfig
 }
 
+// AttributesConfig configures how the asserted attributes are merged into the attributes of requests
+type AttributesConfig struct {
+	// AttributesNamespace is the object attribute the asserted attributes are merged into, e.g. department is
+	// accessed by subject.department in conditions with the default namespace "subject"
+	AttributesNamespace string `json:"attributesNamespace,omitempty"`
+	// AttributesPrecedence resolves the conflicts of the asserted attributes and the ones passed by callers,
+	// it's "asserted" (default), "request" or "reject"
+	AttributesPrecedence string `json:"attributesPrecedence,omitempty"`
+}
+
+const (
+	// DefaultAttributesNamespace is the object attribute of the asserted attributes by default
+	DefaultAttributesNamespace = "subject"
+	// AssertedAttributesFirst overrides the attributes passed by callers with the asserted ones
+	AssertedAttributesFirst = "asserted"
+	// RequestAttributesFirst keeps the attributes passed by callers over the asserted ones
+	RequestAttributesFirst = "request"
+	// RejectConflictAttributes rejects the requests passing the attributes which are asserted as well
+	RejectConflictAttributes = "reject"
+)
+
 // WebHookAsserter implements asserter client interface
 type WebHookAsserter struct {
 	ServerEndpoint string
@@ -116,6 +139,26 @@ func NewAsserter(conf *AsserterConfig, tenant *string) (TokenAsserter, error) {
 	return &a, nil
 }
 
+// NewTokenAsserter creates the token asserter of asserterType, which is the webhook asserter of webhookConf by
+// default, or the JWT asserter of jwtConf. The attributes configuration of the asserter is returned as well.
+func NewTokenAsserter(asserterType string, webhookConf *AsserterConfig, jwtConf *JWTAsserterConfig) (TokenAsserter, *AttributesConfig, error) {
+	switch asserterType {
+	case "", WebhookAsserterType:
+		a, err := NewAsserter(webhookConf, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a, &webhookConf.AttributesConfig, nil
+	case JWTAsserterType:
+		a, err := NewJWTAsserter(jwtConf)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a, &jwtConf.AttributesConfig, nil
+	}
+	return nil, nil, fmt.Errorf("unknown asserter type %q", asserterType)
+}
+
 // AssertToken assert token via webhook
 func (a *WebHookAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*AssertResponse, error) {
 	log.Debugf("token: %s, idpType: %s, allowedIDD: %s, requestHeaders: %v", token, idpType, allowedIDD, requestHeaders)
@@ -162,6 +205,11 @@ func (a *WebHookAsserter) AssertToken(token string, idpType string, allowedIDD s
 
 	if resp.StatusCode != http.StatusOK {
 		log.Errorf("assertion error, status code: %d", resp.StatusCode)
+		// Only these statuses mean the token is rejected, others like 408, 429 and server errors may be transient
+		switch resp.StatusCode {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return nil, errors.Errorf(errors.Unauthorized, "asserter error, status code: %d", resp.StatusCode)
+		}
 		return nil, fmt.Errorf("asserter error, status code: %d", resp.StatusCode)
 	}
 
@@ -182,7 +230,7 @@ func (a *WebHookAsserter) AssertToken(token string, idpType string, allowedIDD s
 	// flag error if asserter indicates failure
 	if ar.ErrCode != 0 {
 		log.Errorf("assertion failure: %v", ar)
-		return nil, fmt.Errorf("ErrCode: %d, ErrMsg: %s", ar.ErrCode, ar.ErrMessage)
+		return nil, errors.Errorf(errors.Unauthorized, "ErrCode: %d, ErrMsg: %s", ar.ErrCode, ar.ErrMessage)
 	}
 
 	log.Debugf("asserted: %v", ar)
@@ -4,11 +4,13 @@ package assertion
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
 
 	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/errors"
 )
 
 func TestAssertion(t *testing.T) {
@@ -62,6 +64,30 @@ func TestAssertion(t *testing.T) {
 
 }
 
+func TestAsserterStatus(t *testing.T) {
+	for status, rejected := range map[int]bool{
+		http.StatusBadRequest:          true,
+		http.StatusUnauthorized:        true,
+		http.StatusForbidden:           true,
+		http.StatusRequestTimeout:      false,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		asserter, err := getAsserter(server.URL, t)
+		if err != nil {
+			t.Fatal("failed to create asserter:", err)
+		}
+		_, err = asserter.AssertToken("token", "", "", nil)
+		if err == nil || (errors.Code(err) == errors.Unauthorized) != rejected {
+			t.Errorf("status %d: expected the token rejected %v, got %v", status, rejected, err)
+		}
+		server.Close()
+	}
+}
+
 func getAsserter(endpoint string, t *testing.T) (TokenAsserter, error) {
 	conf := &AsserterConfig{
 		Endpoint: endpoint,
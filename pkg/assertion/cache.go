@@ -0,0 +1,227 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package assertion
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultCacheMaxEntries = 10000
+
+// CacheConfig configures the cache of token assertion results
+type CacheConfig struct {
+	// TTL is the seconds an asserted token is cached, it's bounded by the expiry of the token if the token is a JWT
+	// or the exp attribute is asserted. The cache is disabled if it's 0.
+	TTL int `json:"ttl"`
+	// NegativeTTL is the seconds a token rejected by the asserter is cached, rejected tokens are asserted again
+	// every time if it's 0. Failures of reaching the asserter are never cached.
+	NegativeTTL int `json:"negativeTTL,omitempty"`
+	// MaxEntries is the maximum number of cached tokens, 10000 by default. The least recently used tokens are
+	// evicted when it's exceeded.
+	MaxEntries int `json:"maxEntries,omitempty"`
+}
+
+// CacheStats are the statistics of an assertion cache, the counters are accumulated since the cache is created
+type CacheStats struct {
+	Entries      int    `json:"entries"`
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negativeHits"`
+	Misses       uint64 `json:"misses"`
+	Evictions    uint64 `json:"evictions"`
+	Flushes      uint64 `json:"flushes"`
+}
+
+type cacheEntry struct {
+	key       string
+	resp      *AssertResponse
+	err       error
+	expiresAt time.Time
+}
+
+// CachingAsserter caches the assertion results of a token asserter keyed by the hash of the token, the IDP type
+// and the allowed identity domain. Tokens rejected by the asserter are cached as well if NegativeTTL is set.
+// Request headers are passed to the asserter when a token is not cached, they are not part of the key.
+// Cached responses are shared by callers, they must not be modified.
+type CachingAsserter struct {
+	sync.Mutex
+	asserter    TokenAsserter
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	entries     map[string]*list.Element
+	// lru keeps the entries from the most recently used to the least recently used
+	lru   *list.List
+	stats CacheStats
+	// now returns the current time, it's replaced in tests
+	now func() time.Time
+}
+
+// NewCachingAsserter wraps asserter with the cache configured by conf
+func NewCachingAsserter(asserter TokenAsserter, conf *CacheConfig) (*CachingAsserter, error) {
+	if asserter == nil || conf == nil || conf.TTL <= 0 {
+		return nil, errors.New(errors.ConfigError, "asserter is nil or TTL of assertion cache is not positive")
+	}
+	maxEntries := conf.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	negativeTTL := conf.NegativeTTL
+	if negativeTTL < 0 {
+		negativeTTL = 0
+	}
+	return &CachingAsserter{
+		asserter:    asserter,
+		ttl:         time.Duration(conf.TTL) * time.Second,
+		negativeTTL: time.Duration(negativeTTL) * time.Second,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		now:         time.Now,
+	}, nil
+}
+
+// AssertToken returns the cached assertion result of token, or asserts it with the wrapped asserter and caches
+// the result
+func (c *CachingAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*AssertResponse, error) {
+	if len(token) == 0 {
+		return c.asserter.AssertToken(token, idpType, allowedIDD, requestHeaders)
+	}
+	key := cacheKey(token, idpType, allowedIDD)
+	if entry, ok := c.get(key); ok {
+		return entry.resp, entry.err
+	}
+
+	resp, err := c.asserter.AssertToken(token, idpType, allowedIDD, requestHeaders)
+	now := c.now()
+	if err != nil {
+		// Only the tokens rejected by the asserter are cached, other failures may be transient
+		if errors.Code(err) == errors.Unauthorized && c.negativeTTL > 0 {
+			c.put(&cacheEntry{key: key, err: err, expiresAt: now.Add(c.negativeTTL)})
+		}
+		return nil, err
+	}
+	expiresAt := now.Add(c.ttl)
+	if exp, ok := tokenExpiry(token, resp); ok && exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	if expiresAt.After(now) {
+		c.put(&cacheEntry{key: key, resp: resp, expiresAt: expiresAt})
+	}
+	return resp, nil
+}
+
+func (c *CachingAsserter) get(key string) (*cacheEntry, bool) {
+	c.Lock()
+	defer c.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if c.now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(elem)
+			if entry.err != nil {
+				c.stats.NegativeHits++
+			} else {
+				c.stats.Hits++
+			}
+			return entry, true
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *CachingAsserter) put(entry *cacheEntry) {
+	c.Lock()
+	defer c.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// Flush removes all the cached tokens, and returns the number of them
+func (c *CachingAsserter) Flush() int {
+	c.Lock()
+	defer c.Unlock()
+	n := c.lru.Len()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.stats.Flushes++
+	log.Infof("Assertion cache is flushed, %d tokens are removed.", n)
+	return n
+}
+
+// Stats returns the statistics of the cache
+func (c *CachingAsserter) Stats() CacheStats {
+	c.Lock()
+	defer c.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// cacheKey hashes the token, so that tokens are not kept in memory in clear text
+func cacheKey(token, idpType, allowedIDD string) string {
+	h := sha256.New()
+	for _, s := range []string{idpType, allowedIDD, token} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// tokenExpiry returns the expiry of a token from the asserted exp attribute, or the exp claim if the token is a
+// JWT. The claims of JWTs are read without validation, as the token has been asserted.
+func tokenExpiry(token string, resp *AssertResponse) (time.Time, bool) {
+	if resp != nil {
+		if exp, ok := numericDate(resp.Attributes["exp"]); ok {
+			return exp, true
+		}
+	}
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return time.Time{}, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return time.Time{}, false
+	}
+	return numericDate(claims["exp"])
+}
+
+// numericDate converts the seconds since the epoch of JWT claims to time
+func numericDate(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return time.Unix(n, 0), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,139 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package assertion
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// countingAsserter asserts the tokens prefixed by "valid", rejects the ones prefixed by "invalid", and fails
+// the others as if the asserter is down
+type countingAsserter struct {
+	calls int
+}
+
+func (a *countingAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*AssertResponse, error) {
+	a.calls++
+	switch {
+	case len(token) >= 5 && token[:5] == "valid":
+		return &AssertResponse{Principals: []*adsapi.Principal{{Type: adsapi.PRINCIPAL_TYPE_USER, Name: token}}}, nil
+	case len(token) >= 7 && token[:7] == "invalid":
+		return nil, errors.New(errors.Unauthorized, "invalid token")
+	}
+	return nil, fmt.Errorf("asserter is down")
+}
+
+func TestCachingAsserter(t *testing.T) {
+	asserter := &countingAsserter{}
+	cache, err := NewCachingAsserter(asserter, &CacheConfig{TTL: 60, NegativeTTL: 10, MaxEntries: 2})
+	if err != nil {
+		t.Fatal("failed to create caching asserter:", err)
+	}
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	assert := func(token, idpType string) error {
+		_, err := cache.AssertToken(token, idpType, "", nil)
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := assert("valid1", "github"); err != nil {
+			t.Fatal("failed to assert token:", err)
+		}
+	}
+	if asserter.calls != 1 {
+		t.Errorf("token should be asserted once, asserted %d times", asserter.calls)
+	}
+	// Tokens are cached by IDP types
+	assert("valid1", "google")
+	if asserter.calls != 2 {
+		t.Errorf("token of another IDP type should be asserted, asserted %d times", asserter.calls)
+	}
+
+	// Rejected tokens are cached for the negative TTL, failures are never cached
+	for i := 0; i < 2; i++ {
+		if err := assert("invalid1", "github"); errors.Code(err) != errors.Unauthorized {
+			t.Errorf("expected unauthorized error, got %v", err)
+		}
+		assert("down", "github")
+	}
+	if asserter.calls != 5 {
+		t.Errorf("expected 5 assertions, got %d", asserter.calls)
+	}
+	now = now.Add(11 * time.Second)
+	assert("invalid1", "github")
+	if asserter.calls != 6 {
+		t.Errorf("rejected token should be asserted again after the negative TTL, asserted %d times", asserter.calls)
+	}
+
+	// The least recently used tokens are evicted
+	stats := cache.Stats()
+	want := CacheStats{Entries: 2, Hits: 2, NegativeHits: 1, Misses: 6, Evictions: 1}
+	if stats != want {
+		t.Errorf("expected stats %+v, got %+v", want, stats)
+	}
+
+	now = now.Add(50 * time.Second)
+	assert("valid1", "google")
+	if asserter.calls != 7 {
+		t.Errorf("token should be asserted again after the TTL, asserted %d times", asserter.calls)
+	}
+
+	if n := cache.Flush(); n != 2 {
+		t.Errorf("expected 2 tokens flushed, got %d", n)
+	}
+	assert("valid1", "google")
+	if asserter.calls != 8 {
+		t.Errorf("token should be asserted again after flushed, asserted %d times", asserter.calls)
+	}
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Flushes != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := NewCachingAsserter(asserter, &CacheConfig{}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of zero TTL, got %v", err)
+	}
+}
+
+func TestCacheTTLBoundedByTokenExpiry(t *testing.T) {
+	asserter := &countingAsserter{}
+	cache, err := NewCachingAsserter(asserter, &CacheConfig{TTL: 3600})
+	if err != nil {
+		t.Fatal("failed to create caching asserter:", err)
+	}
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	// The token looks like a JWT expiring in 10 seconds, the countingAsserter asserts it by the prefix
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": now.Add(10 * time.Second).Unix()}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal("failed to sign token:", err)
+	}
+	token = "valid" + token
+	cache.AssertToken(token, "jwt", "", nil)
+	cache.AssertToken(token, "jwt", "", nil)
+	if asserter.calls != 1 {
+		t.Errorf("token should be cached before it expires, asserted %d times", asserter.calls)
+	}
+	now = now.Add(10 * time.Second)
+	cache.AssertToken(token, "jwt", "", nil)
+	if asserter.calls != 2 {
+		t.Errorf("token should not be cached after it expires, asserted %d times", asserter.calls)
+	}
+
+	// The asserted exp attribute bounds the TTL as well
+	exp, ok := tokenExpiry("opaque", &AssertResponse{Attributes: map[string]interface{}{"exp": float64(now.Unix())}})
+	if !ok || !exp.Equal(time.Unix(now.Unix(), 0)) {
+		t.Errorf("expected expiry %v, got %v, %v", now, exp, ok)
+	}
+	if _, ok := tokenExpiry("opaque", nil); ok {
+		t.Error("opaque token should have no expiry")
+	}
+}
@@ -0,0 +1,236 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package assertion
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// minJWKSRefreshInterval limits how often the JWKS is reloaded for the tokens signed by unknown keys, it's also
+	// the backoff after the first failed load
+	minJWKSRefreshInterval = 30 * time.Second
+	// maxJWKSRetryBackoff is the maximum backoff after failed loads, the backoff is doubled by each failed load
+	maxJWKSRetryBackoff = 5 * time.Minute
+)
+
+// jwk is a JSON Web Key, see RFC 7517. Only the public RSA and EC keys for signature are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// publicKey returns the *rsa.PublicKey or *ecdsa.PublicKey of the key
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of RSA key %q: %v", k.Kid, err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid exponent of RSA key %q", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q of EC key %q", k.Crv, k.Kid)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate of EC key %q: %v", k.Kid, err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate of EC key %q: %v", k.Kid, err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC key %q is not on curve %s", k.Kid, k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q of key %q", k.Kty, k.Kid)
+}
+
+// parseJWKS parses the signature keys in a JWKS document keyed by key ID. The keys which are not supported
+// are skipped, so that the other keys of the set still work.
+func parseJWKS(raw []byte) (map[string]interface{}, error) {
+	var set jwkSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	for i := range set.Keys {
+		k := &set.Keys[i]
+		if len(k.Use) != 0 && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Warningf("Key is skipped: %v.", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no supported signature key is found")
+	}
+	return keys, nil
+}
+
+// jwksCache caches the keys of a JWKS loaded from a file or a URL. The keys are reloaded after ttl, or when a
+// token is signed by an unknown key. The JWKS is loaded without holding the lock, and only by one request at a
+// time, the other requests wait for it. Once a load fails, the JWKS is not loaded again until a backoff has passed,
+// meanwhile the keys loaded before are used, or the error of the failed load is returned.
+type jwksCache struct {
+	sync.Mutex
+	location   string
+	ttl        time.Duration
+	httpClient *http.Client
+	keys       map[string]interface{}
+	loadedAt   time.Time
+	// loadErr is the error of the last load if no keys are loaded
+	loadErr error
+	// failures is the number of consecutive failed loads, the JWKS is not loaded again before retryAt
+	failures int
+	retryAt  time.Time
+	// loading is closed once the load in flight is done, it's nil if no load is in flight
+	loading chan struct{}
+}
+
+func (c *jwksCache) load() (map[string]interface{}, error) {
+	var raw []byte
+	var err error
+	if strings.HasPrefix(c.location, "http://") || strings.HasPrefix(c.location, "https://") {
+		raw, err = c.fetch()
+	} else {
+		raw, err = ioutil.ReadFile(c.location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(raw)
+}
+
+func (c *jwksCache) fetch() ([]byte, error) {
+	resp, err := c.httpClient.Get(c.location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// key returns the key of kid, which can be empty if the JWKS has only one key
+func (c *jwksCache) key(kid string, now time.Time) (interface{}, error) {
+	c.Lock()
+	expired := c.keys == nil || now.Sub(c.loadedAt) >= c.ttl
+	_, found := c.lookup(kid)
+	if !now.Before(c.retryAt) && (expired || (!found && now.Sub(c.loadedAt) >= minJWKSRefreshInterval)) {
+		if loading := c.loading; loading != nil {
+			c.Unlock()
+			<-loading
+			c.Lock()
+		} else {
+			loading = make(chan struct{})
+			c.loading = loading
+			c.Unlock()
+			keys, err := c.load()
+			c.Lock()
+			c.loaded(keys, err, now)
+			c.loading = nil
+			close(loading)
+		}
+	}
+	defer c.Unlock()
+
+	if c.keys == nil {
+		return nil, errors.Wrapf(c.loadErr, errors.ServerError, "failed to load JWKS from %s", c.location)
+	}
+	key, found := c.lookup(kid)
+	if !found {
+		return nil, errors.Errorf(errors.Unauthorized, "signing key %q is not found", kid)
+	}
+	return key, nil
+}
+
+// loaded keeps the keys loaded at now, it must be called with the lock held
+func (c *jwksCache) loaded(keys map[string]interface{}, err error, now time.Time) {
+	c.loadedAt = now
+	if err == nil {
+		c.keys, c.loadErr = keys, nil
+		c.failures, c.retryAt = 0, time.Time{}
+		return
+	}
+	backoff := minJWKSRefreshInterval
+	for i := 0; i < c.failures && backoff < maxJWKSRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxJWKSRetryBackoff {
+		backoff = maxJWKSRetryBackoff
+	}
+	c.failures++
+	c.retryAt = now.Add(backoff)
+	if c.keys == nil {
+		c.loadErr = err
+		log.Errorf("Failed to load JWKS from %s, retry in %v: %v.", c.location, backoff, err)
+		return
+	}
+	// Keep the keys loaded before, and retry later
+	log.Errorf("Failed to reload JWKS from %s, keep using the keys loaded before and retry in %v: %v.", c.location, backoff, err)
+}
+
+func (c *jwksCache) lookup(kid string) (interface{}, bool) {
+	if len(kid) == 0 && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
@@ -0,0 +1,260 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package assertion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookAsserterType is the type of the asserter calling the asserter webhook
+	WebhookAsserterType = "webhook"
+	// JWTAsserterType is the type of the asserter validating JWTs locally
+	JWTAsserterType = "jwt"
+
+	defaultJWKSCacheTTL = 3600
+)
+
+// defaultPrincipalClaims maps the subject to the user, and the groups claim to the groups
+var defaultPrincipalClaims = map[string]string{
+	"sub":    adsapi.PRINCIPAL_TYPE_USER,
+	"groups": adsapi.PRINCIPAL_TYPE_GROUP,
+}
+
+// jwtSigningMethods are the asymmetric signing methods accepted, HMAC and none are never accepted as the
+// keys in JWKS are public
+var jwtSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+// JWTAsserterConfig is the configuration of the asserter validating JWTs with the keys of a JWKS
+type JWTAsserterConfig struct {
+	// JWKS is the path of a JSON Web Key Set file, or the http(s) URL of it like the jwks_uri of an OpenID provider
+	JWKS string `json:"jwks"`
+	// JWKSCacheTTL is the seconds the JWKS is cached, 3600 by default. It's reloaded earlier if a token is
+	// signed by an unknown key.
+	JWKSCacheTTL int `json:"jwksCacheTTL,omitempty"`
+	// CACert is the CA certificate file of the https JWKS URL
+	CACert string `json:"caCert,omitempty"`
+	// HTTPTimeout is the seconds of fetching the JWKS, 10 by default
+	HTTPTimeout int `json:"httpTimeout,omitempty"`
+	// Issuer is the expected iss claim, it's not checked if empty
+	Issuer string `json:"issuer,omitempty"`
+	// Audience is expected in the aud claim, it's not checked if empty
+	Audience string `json:"audience,omitempty"`
+	// ClockSkew is the seconds of clock skew tolerated in checking the exp and nbf claims
+	ClockSkew int `json:"clockSkew,omitempty"`
+	// PrincipalClaims maps the claims to the principal types user, group or entity, {"sub": "user", "groups":
+	// "group"} by default. A claim is a string or an array of strings, nested claims are named by dot paths
+	// like realm_access.roles.
+	PrincipalClaims map[string]string `json:"principalClaims,omitempty"`
+	// IDDClaim is the claim of the identity domain of the asserted principals
+	IDDClaim string `json:"iddClaim,omitempty"`
+	AttributesConfig
+}
+
+// JWTAsserter asserts JWTs locally, the signatures are validated against the keys of a JWKS, and the
+// principals are mapped from the claims. All the claims are returned as the asserted attributes.
+type JWTAsserter struct {
+	issuer          string
+	audience        string
+	clockSkew       time.Duration
+	principalClaims map[string]string
+	// claims are the claims in principalClaims in order, so that the principals are always in the same order
+	claims   []string
+	iddClaim string
+	jwks     *jwksCache
+	parser   *jwt.Parser
+	// now returns the current time, it's replaced in tests
+	now func() time.Time
+}
+
+// NewJWTAsserter creates the JWT asserter, the JWKS is loaded when the first token is asserted
+func NewJWTAsserter(conf *JWTAsserterConfig) (*JWTAsserter, error) {
+	if conf == nil || len(conf.JWKS) == 0 {
+		return nil, errors.New(errors.ConfigError, "JWT asserter configuration is nil or JWKS is empty")
+	}
+	principalClaims := conf.PrincipalClaims
+	if len(principalClaims) == 0 {
+		principalClaims = defaultPrincipalClaims
+	}
+	var claims []string
+	for claim, principalType := range principalClaims {
+		switch principalType {
+		case adsapi.PRINCIPAL_TYPE_USER, adsapi.PRINCIPAL_TYPE_GROUP, adsapi.PRINCIPAL_TYPE_ENTITY:
+		default:
+			return nil, errors.Errorf(errors.ConfigError, "claim %q is mapped to unknown principal type %q", claim, principalType)
+		}
+		claims = append(claims, claim)
+	}
+	sort.Strings(claims)
+
+	ttl := conf.JWKSCacheTTL
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+	timeout := conf.HTTPTimeout
+	if timeout <= 0 {
+		timeout = 10
+	}
+	tr := http.Transport{
+		IdleConnTimeout: 60 * time.Second,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	if len(conf.CACert) > 0 {
+		caCert, err := ioutil.ReadFile(conf.CACert)
+		if err != nil {
+			return nil, errors.Wrapf(err, errors.ConfigError, "failed to read CA certificate %s", conf.CACert)
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tr.TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+	}
+
+	return &JWTAsserter{
+		issuer:          conf.Issuer,
+		audience:        conf.Audience,
+		clockSkew:       time.Duration(conf.ClockSkew) * time.Second,
+		principalClaims: principalClaims,
+		claims:          claims,
+		iddClaim:        conf.IDDClaim,
+		jwks: &jwksCache{
+			location: conf.JWKS,
+			ttl:      time.Duration(ttl) * time.Second,
+			httpClient: &http.Client{
+				Transport: &tr,
+				Timeout:   time.Duration(timeout) * time.Second,
+			},
+		},
+		parser: &jwt.Parser{
+			ValidMethods: jwtSigningMethods,
+			// The claims are validated by validateClaims, with clock skew and audience arrays
+			SkipClaimsValidation: true,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// AssertToken validates JWT token, and returns the principals mapped from its claims
+func (a *JWTAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*AssertResponse, error) {
+	if len(token) == 0 {
+		return nil, errors.New(errors.Unauthorized, "token is empty")
+	}
+	now := a.now()
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.jwks.key(kid, now)
+	})
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Inner != nil {
+			if errors.Code(ve.Inner) == errors.ServerError {
+				return nil, ve.Inner
+			}
+		}
+		return nil, errors.Wrap(err, errors.Unauthorized, "invalid JWT")
+	}
+	if err := a.validateClaims(claims, now); err != nil {
+		return nil, err
+	}
+
+	idd, _ := claimValue(claims, a.iddClaim).(string)
+	if len(a.iddClaim) != 0 && len(allowedIDD) != 0 && idd != allowedIDD {
+		return nil, errors.Errorf(errors.Unauthorized, "identity domain %q is not allowed", idd)
+	}
+	var principals []*adsapi.Principal
+	for _, claim := range a.claims {
+		for _, name := range claimStrings(claimValue(claims, claim)) {
+			principals = append(principals, &adsapi.Principal{Type: a.principalClaims[claim], Name: name, IDD: idd})
+		}
+	}
+	if len(principals) == 0 {
+		return nil, errors.New(errors.Unauthorized, "no principal is found in the claims of JWT")
+	}
+	log.Debugf("asserted principals: %v", principals)
+	return &AssertResponse{Principals: principals, Attributes: claims}, nil
+}
+
+// validateClaims checks the time, issuer and audience claims
+func (a *JWTAsserter) validateClaims(claims jwt.MapClaims, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New(errors.Unauthorized, "exp claim is missing in JWT")
+	}
+	if now.Add(-a.clockSkew).After(time.Unix(int64(exp), 0)) {
+		return errors.New(errors.Unauthorized, "JWT is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New(errors.Unauthorized, "JWT is not valid yet")
+	}
+	if len(a.issuer) != 0 {
+		if iss, _ := claims["iss"].(string); iss != a.issuer {
+			return errors.Errorf(errors.Unauthorized, "unexpected issuer %q of JWT", iss)
+		}
+	}
+	if len(a.audience) != 0 {
+		found := false
+		for _, aud := range claimStrings(claims["aud"]) {
+			if aud == a.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(errors.Unauthorized, "audience %q is not found in JWT", a.audience)
+		}
+	}
+	return nil
+}
+
+// claimValue returns the value of claim, nested claims are named by dot paths like realm_access.roles
+func claimValue(claims map[string]interface{}, claim string) interface{} {
+	if len(claim) == 0 {
+		return nil
+	}
+	if value, ok := claims[claim]; ok {
+		return value
+	}
+	var value interface{} = claims
+	for _, key := range strings.Split(claim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// claimStrings returns the strings of a string claim, or an array claim of strings
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if len(v) != 0 {
+			return []string{v}
+		}
+	case []interface{}:
+		var ret []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && len(s) != 0 {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package assertion
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// testKeys generates an RSA key "rsa1" and an EC key "ec1", and returns their JWKS
+func testKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey, []byte) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("failed to generate RSA key:", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate EC key:", err)
+	}
+	raw, err := json.Marshal(jwkSet{Keys: []jwk{
+		{Kty: "RSA", Kid: "rsa1", Use: "sig", N: encodeBigInt(rsaKey.N), E: encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		{Kty: "EC", Kid: "ec1", Crv: "P-256", X: encodeBigInt(ecKey.X), Y: encodeBigInt(ecKey.Y)},
+		{Kty: "oct", Kid: "hmac1"},
+	}})
+	if err != nil {
+		t.Fatal("failed to marshal JWKS:", err)
+	}
+	return rsaKey, ecKey, raw
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if len(kid) != 0 {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal("failed to sign token:", err)
+	}
+	return s
+}
+
+func TestJWTAsserter(t *testing.T) {
+	rsaKey, ecKey, jwks := testKeys(t)
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, jwks, 0600); err != nil {
+		t.Fatal("failed to write JWKS:", err)
+	}
+
+	asserter, err := NewJWTAsserter(&JWTAsserterConfig{
+		JWKS:      jwksFile,
+		Issuer:    "https://idp.example.com",
+		Audience:  "speedle",
+		ClockSkew: 60,
+		PrincipalClaims: map[string]string{
+			"sub":               adsapi.PRINCIPAL_TYPE_USER,
+			"groups":            adsapi.PRINCIPAL_TYPE_GROUP,
+			"realm_access.apps": adsapi.PRINCIPAL_TYPE_ENTITY,
+		},
+		IDDClaim: "tenant",
+	})
+	if err != nil {
+		t.Fatal("failed to create JWT asserter:", err)
+	}
+	now := time.Date(2019, 3, 4, 12, 0, 0, 0, time.UTC)
+	asserter.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]interface{}) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":          "https://idp.example.com",
+			"aud":          []string{"speedle", "other"},
+			"sub":          "alice",
+			"groups":       []string{"finance", "audit"},
+			"realm_access": map[string]interface{}{"apps": "crm"},
+			"tenant":       "acme",
+			"department":   "finance",
+			"exp":          now.Add(time.Hour).Unix(),
+			"nbf":          now.Add(-time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	resp, err := asserter.AssertToken(signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(nil)), "jwt", "", nil)
+	if err != nil {
+		t.Fatal("failed to assert RS256 token:", err)
+	}
+	want := []adsapi.Principal{
+		{Type: adsapi.PRINCIPAL_TYPE_GROUP, Name: "finance", IDD: "acme"},
+		{Type: adsapi.PRINCIPAL_TYPE_GROUP, Name: "audit", IDD: "acme"},
+		{Type: adsapi.PRINCIPAL_TYPE_ENTITY, Name: "crm", IDD: "acme"},
+		{Type: adsapi.PRINCIPAL_TYPE_USER, Name: "alice", IDD: "acme"},
+	}
+	if len(resp.Principals) != len(want) {
+		t.Fatalf("expected principals %v, got %v", want, resp.Principals)
+	}
+	for i, p := range resp.Principals {
+		if *p != want[i] {
+			t.Errorf("expected principal %v, got %v", want[i], *p)
+		}
+	}
+	if resp.Attributes["department"] != "finance" {
+		t.Errorf("claims should be asserted as attributes, got %v", resp.Attributes)
+	}
+
+	// EC key, single audience, and the clock skew
+	token := signToken(t, jwt.SigningMethodES256, "ec1", ecKey, claims(map[string]interface{}{
+		"aud": "speedle", "exp": now.Add(-30 * time.Second).Unix()}))
+	if _, err := asserter.AssertToken(token, "jwt", "acme", nil); err != nil {
+		t.Error("failed to assert ES256 token:", err)
+	}
+
+	_, otherKey, _ := testKeys(t)
+	hmacToken := signToken(t, jwt.SigningMethodHS256, "hmac1", []byte("secret"), claims(nil))
+	invalidTokens := map[string]string{
+		"expired":         signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()})),
+		"no exp":          signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"exp": nil})),
+		"not valid yet":   signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()})),
+		"wrong issuer":    signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"wrong audience":  signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"aud": "other"})),
+		"no principal":    signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(map[string]interface{}{"sub": nil, "groups": nil, "realm_access": nil})),
+		"wrong signature": signToken(t, jwt.SigningMethodES256, "ec1", otherKey, claims(nil)),
+		"unknown key":     signToken(t, jwt.SigningMethodES256, "ec2", ecKey, claims(nil)),
+		"HMAC":            hmacToken,
+		"garbage":         "not.a.jwt",
+	}
+	for name, token := range invalidTokens {
+		if _, err := asserter.AssertToken(token, "jwt", "", nil); errors.Code(err) != errors.Unauthorized {
+			t.Errorf("%s token: expected unauthorized error, got %v", name, err)
+		}
+	}
+	if _, err := asserter.AssertToken(signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(nil)), "jwt", "other", nil); errors.Code(err) != errors.Unauthorized {
+		t.Errorf("expected unauthorized error of identity domain, got %v", err)
+	}
+}
+
+func TestJWKSCache(t *testing.T) {
+	rsaKey, _, jwks := testKeys(t)
+	var fetches int32
+	var current atomic.Value
+	current.Store(jwks)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(current.Load().([]byte))
+	}))
+	defer server.Close()
+
+	asserter, err := NewJWTAsserter(&JWTAsserterConfig{JWKS: server.URL, JWKSCacheTTL: 600})
+	if err != nil {
+		t.Fatal("failed to create JWT asserter:", err)
+	}
+	now := time.Now()
+	asserter.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix()}
+
+	token := signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims)
+	for i := 0; i < 3; i++ {
+		if _, err := asserter.AssertToken(token, "jwt", "", nil); err != nil {
+			t.Fatal("failed to assert token:", err)
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("JWKS should be fetched once, fetched %d times", n)
+	}
+
+	// Keys are rotated, they are reloaded for the tokens signed by unknown keys after the minimum refresh interval
+	newKey, _, newJWKS := testKeys(t)
+	current.Store(newJWKS)
+	newToken := signToken(t, jwt.SigningMethodRS256, "rsa1", newKey, claims)
+	if _, err := asserter.AssertToken(newToken, "jwt", "", nil); err == nil {
+		t.Error("the JWKS should not be fetched again within the minimum refresh interval")
+	}
+	now = now.Add(minJWKSRefreshInterval)
+	if _, err := asserter.AssertToken(signToken(t, jwt.SigningMethodRS256, "rsa2", newKey, claims), "jwt", "", nil); err == nil ||
+		!strings.Contains(err.Error(), "signing key \"rsa2\" is not found") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+	now = now.Add(601 * time.Second)
+	if _, err := asserter.AssertToken(newToken, "jwt", "", nil); err != nil {
+		t.Error("failed to assert token signed by the rotated key:", err)
+	}
+
+	// The keys loaded before are kept if the JWKS can't be reloaded
+	current.Store([]byte("{"))
+	now = now.Add(601 * time.Second)
+	if _, err := asserter.AssertToken(newToken, "jwt", "", nil); err != nil {
+		t.Error("the keys loaded before should be kept:", err)
+	}
+}
+
+func TestJWKSRetryBackoff(t *testing.T) {
+	rsaKey, _, jwks := testKeys(t)
+	var fetches int32
+	var current atomic.Value
+	current.Store([]byte("{"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(current.Load().([]byte))
+	}))
+	defer server.Close()
+
+	asserter, err := NewJWTAsserter(&JWTAsserterConfig{JWKS: server.URL, JWKSCacheTTL: 600})
+	if err != nil {
+		t.Fatal("failed to create JWT asserter:", err)
+	}
+	now := time.Now()
+	asserter.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix()}
+	token := signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims)
+
+	// The failed load is not retried by the next tokens until the backoff has passed
+	for i := 0; i < 3; i++ {
+		if _, err := asserter.AssertToken(token, "jwt", "", nil); errors.Code(err) != errors.ServerError {
+			t.Errorf("expected server error of loading JWKS, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("JWKS should be fetched once, fetched %d times", n)
+	}
+
+	// The backoff is doubled by the next failure
+	now = now.Add(minJWKSRefreshInterval)
+	asserter.AssertToken(token, "jwt", "", nil)
+	now = now.Add(minJWKSRefreshInterval)
+	asserter.AssertToken(token, "jwt", "", nil)
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("JWKS should be fetched twice, fetched %d times", n)
+	}
+	current.Store(jwks)
+	now = now.Add(minJWKSRefreshInterval)
+	if _, err := asserter.AssertToken(token, "jwt", "", nil); err != nil {
+		t.Error("failed to assert token once JWKS is loaded:", err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 3 {
+		t.Errorf("JWKS should be fetched 3 times, fetched %d times", n)
+	}
+}
+
+func TestJWKSConcurrentLoad(t *testing.T) {
+	rsaKey, _, jwks := testKeys(t)
+	var fetches int32
+	var blocked atomic.Value
+	blocked.Store(false)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if blocked.Load().(bool) {
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	asserter, err := NewJWTAsserter(&JWTAsserterConfig{JWKS: server.URL, JWKSCacheTTL: 600})
+	if err != nil {
+		t.Fatal("failed to create JWT asserter:", err)
+	}
+	now := time.Now()
+	asserter.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix()}
+	token := signToken(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims)
+	if _, err := asserter.AssertToken(token, "jwt", "", nil); err != nil {
+		t.Fatal("failed to assert token:", err)
+	}
+
+	// Tokens signed by an unknown key reload the JWKS once, and the reload doesn't block other tokens
+	blocked.Store(true)
+	now = now.Add(minJWKSRefreshInterval)
+	unknownToken := signToken(t, jwt.SigningMethodRS256, "rsa2", rsaKey, claims)
+	errs := make(chan error)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := asserter.AssertToken(unknownToken, "jwt", "", nil)
+			errs <- err
+		}()
+	}
+	for atomic.LoadInt32(&fetches) != 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := asserter.AssertToken(token, "jwt", "", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("failed to assert token:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("the token signed by a loaded key should not wait for the JWKS being reloaded")
+	}
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err == nil {
+			t.Error("the token signed by an unknown key should be rejected")
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("JWKS should be fetched twice, fetched %d times", n)
+	}
+}
+
+func TestNewTokenAsserter(t *testing.T) {
+	if _, _, err := NewTokenAsserter("saml", nil, nil); err == nil {
+		t.Error("unknown asserter type should be rejected")
+	}
+	if _, _, err := NewTokenAsserter(JWTAsserterType, nil, nil); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of missing JWKS, got %v", err)
+	}
+	if _, _, err := NewTokenAsserter(JWTAsserterType, nil, &JWTAsserterConfig{JWKS: "jwks.json",
+		PrincipalClaims: map[string]string{"sub": "role"}}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of unknown principal type, got %v", err)
+	}
+	conf := &JWTAsserterConfig{JWKS: "jwks.json", AttributesConfig: AttributesConfig{AttributesNamespace: "claims"}}
+	a, attributesConf, err := NewTokenAsserter(JWTAsserterType, nil, conf)
+	if err != nil || a == nil || attributesConf.AttributesNamespace != "claims" {
+		t.Errorf("unexpected JWT asserter %v, %v, %v", a, attributesConf, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 
+	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/pkg/assertion"
 	"github.com/oracle/speedle/pkg/errors"
 	"github.com/oracle/speedle/pkg/logging"
@@ -29,6 +30,30 @@ type ServerConfig struct {
 	CertPath        string `json:"certPath,omitempty"`
 	ClientCertPath  string `json:"clientCertPath,omitempty"`
 	ForceClientCert bool   `json:"forceClientCert,omitempty"`
+	DisableREST     bool   `json:"disableREST,omitempty"`
+	AdminEndpoint   string `json:"adminEndpoint,omitempty"`
+
+	GRPCEndpoint        string `json:"grpcEndpoint,omitempty"`
+	GRPCInsecure        string `json:"grpcInsecure,omitempty"`
+	GRPCKeyPath         string `json:"grpcKeyPath,omitempty"`
+	GRPCCertPath        string `json:"grpcCertPath,omitempty"`
+	GRPCClientCertPath  string `json:"grpcClientCertPath,omitempty"`
+	GRPCForceClientCert bool   `json:"grpcForceClientCert,omitempty"`
+	DisableGRPC         bool   `json:"disableGRPC,omitempty"`
+}
+
+// PMSAuthConfig is the authentication and authorization configuration of policy management service.
+// It takes effect when authorization check is enabled in server configuration.
+type PMSAuthConfig struct {
+	// AdminService is the Speedle service whose policies govern the access to policy management APIs
+	AdminService string `json:"adminService,omitempty"`
+	// AdminPrincipals are always allowed to call policy management APIs, e.g. "user:admin", which
+	// bootstraps the policies in admin service.
+	AdminPrincipals []string `json:"adminPrincipals,omitempty"`
+	// APIKeys maps static API keys to the user names they authenticate
+	APIKeys map[string]string `json:"apiKeys,omitempty"`
+	// TokenType is the identity provider type used to assert bearer tokens
+	TokenType string `json:"tokenType,omitempty"`
 }
 
 type Config struct {
@@ -39,6 +64,47 @@ type Config struct {
 	ServerConfig          *ServerConfig             `json:"serverConfig,omitempty"`
 	LogConfig             *logging.LogConfig        `json:"logConfig,omitempty"`
 	AuditLogConfig        *logging.LogConfig        `json:"auditLogConfig,omitempty"`
+	PMSAuthConfig         *PMSAuthConfig            `json:"pmsAuthConfig,omitempty"`
+	// AsserterType selects the token asserter, "webhook" (default) configured by AsserterWebhookConfig, or "jwt"
+	// configured by JWTAsserterConfig
+	AsserterType      string                       `json:"asserterType,omitempty"`
+	JWTAsserterConfig *assertion.JWTAsserterConfig `json:"jwtAsserterConfig,omitempty"`
+	// AssertionCacheConfig caches the results of the token asserter, tokens are asserted every time if it's nil
+	AssertionCacheConfig *assertion.CacheConfig `json:"assertionCacheConfig,omitempty"`
+	// QuotaConfig is the quota applied to all the tenants, unless a tenant has its own
+	QuotaConfig *pms.Quota `json:"quotaConfig,omitempty"`
+	// TypeCheckConditions fails the conditions accessing non-object paths of object attributes,
+	// instead of evaluating them to null
+	TypeCheckConditions bool `json:"typeCheckConditions,omitempty"`
+	// AllowRequestTimeOverride lets callers pass request_time and request_timezone in request attributes to
+	// evaluate the policies at another time, for testing or replay. It must not be enabled in production, as
+	// callers can bypass time-window conditions with it.
+	AllowRequestTimeOverride bool `json:"allowRequestTimeOverride,omitempty"`
+	// FunctionResultCacheConfig configures the cache of the results of cachable custom functions, results are
+	// kept in memory with the default limits if it's nil
+	FunctionResultCacheConfig *FunctionResultCacheConfig `json:"functionResultCacheConfig,omitempty"`
+	// FunctionClientCerts are the client certificates presented to the custom functions which verify clients,
+	// keyed by function name or by the host of function URL, the function name is looked up first
+	FunctionClientCerts map[string]*FunctionClientCert `json:"functionClientCerts,omitempty"`
+}
+
+// FunctionClientCert is a client certificate presented to custom functions, the PEM files are on the ADS hosts.
+// They are reloaded once they change, so that the certificate can be rotated without changing the functions.
+type FunctionClientCert struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// FunctionResultCacheConfig configures the cache of custom function results
+type FunctionResultCacheConfig struct {
+	// Backend is the name of the backend storing the results, "memory" (default) keeps them in the memory of the
+	// ADS. Backends shared by ADS replicas are registered by the packages implementing them.
+	Backend      string                 `json:"backend,omitempty"`
+	BackendProps map[string]interface{} `json:"backendProps,omitempty"`
+	// MaxEntries and MaxSizeMB limit the number of results and their size in the memory backend, 10000 results
+	// and 64 MiB by default. The least recently used results are evicted when either is exceeded.
+	MaxEntries int `json:"maxEntries,omitempty"`
+	MaxSizeMB  int `json:"maxSizeMB,omitempty"`
 }
 
 func ReadConfig(configFileLocation string) (*Config, error) {
@@ -0,0 +1,111 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// CheckReloadable verifies that newConf only changes settings which can be applied to a running server.
+// Log configurations, token asserters, function service endpoint and TLS certificate/key paths can
+// be changed live; store configuration, watch flag, condition type checking, function result cache, listen
+// endpoints, enabled transports and transport security mode require a restart.
+func CheckReloadable(oldConf, newConf *Config) error {
+	if oldConf == nil || newConf == nil {
+		return nil
+	}
+
+	var changed []string
+	if !reflect.DeepEqual(storeType(oldConf.StoreConfig), storeType(newConf.StoreConfig)) {
+		changed = append(changed, "storeConfig.storeType")
+	}
+	if !reflect.DeepEqual(storeProps(oldConf.StoreConfig), storeProps(newConf.StoreConfig)) {
+		changed = append(changed, "storeConfig.storeProps")
+	}
+	if oldConf.EnableWatch != newConf.EnableWatch {
+		changed = append(changed, "enableWatch")
+	}
+	if oldConf.TypeCheckConditions != newConf.TypeCheckConditions {
+		changed = append(changed, "typeCheckConditions")
+	}
+	if oldConf.AllowRequestTimeOverride != newConf.AllowRequestTimeOverride {
+		changed = append(changed, "allowRequestTimeOverride")
+	}
+	if !reflect.DeepEqual(oldConf.FunctionResultCacheConfig, newConf.FunctionResultCacheConfig) {
+		changed = append(changed, "functionResultCacheConfig")
+	}
+
+	oldServer, newServer := serverConfig(oldConf), serverConfig(newConf)
+	if oldServer.Endpoint != newServer.Endpoint {
+		changed = append(changed, "serverConfig.endpoint")
+	}
+	if oldServer.Insecure != newServer.Insecure {
+		changed = append(changed, "serverConfig.insecure")
+	}
+	if oldServer.EnableAuthz != newServer.EnableAuthz {
+		changed = append(changed, "serverConfig.enableAuthz")
+	}
+	if oldServer.ClientCertPath != newServer.ClientCertPath {
+		changed = append(changed, "serverConfig.clientCertPath")
+	}
+	if oldServer.ForceClientCert != newServer.ForceClientCert {
+		changed = append(changed, "serverConfig.forceClientCert")
+	}
+	if oldServer.DisableREST != newServer.DisableREST {
+		changed = append(changed, "serverConfig.disableREST")
+	}
+	if oldServer.AdminEndpoint != newServer.AdminEndpoint {
+		changed = append(changed, "serverConfig.adminEndpoint")
+	}
+	if oldServer.GRPCEndpoint != newServer.GRPCEndpoint {
+		changed = append(changed, "serverConfig.grpcEndpoint")
+	}
+	if oldServer.GRPCInsecure != newServer.GRPCInsecure {
+		changed = append(changed, "serverConfig.grpcInsecure")
+	}
+	if oldServer.GRPCClientCertPath != newServer.GRPCClientCertPath {
+		changed = append(changed, "serverConfig.grpcClientCertPath")
+	}
+	if oldServer.GRPCForceClientCert != newServer.GRPCForceClientCert {
+		changed = append(changed, "serverConfig.grpcForceClientCert")
+	}
+	if oldServer.DisableGRPC != newServer.DisableGRPC {
+		changed = append(changed, "serverConfig.disableGRPC")
+	}
+
+	if len(changed) != 0 {
+		return errors.Errorf(errors.ConfigError, "%s can not be changed without restarting the server", strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func storeType(storeConfig *StoreConfig) string {
+	if storeConfig == nil {
+		return ""
+	}
+	return storeConfig.StoreType
+}
+
+func storeProps(storeConfig *StoreConfig) map[string]string {
+	props := map[string]string{}
+	if storeConfig == nil {
+		return props
+	}
+	// Compare in string form, because values parsed from flags are strings while values read from JSON are typed
+	for key, value := range storeConfig.StoreProps {
+		props[key] = fmt.Sprintf("%v", value)
+	}
+	return props
+}
+
+func serverConfig(conf *Config) ServerConfig {
+	if conf.ServerConfig == nil {
+		return ServerConfig{}
+	}
+	return *conf.ServerConfig
+}
@@ -0,0 +1,51 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package cfg
+
+import (
+	"testing"
+
+	"github.com/oracle/speedle/pkg/logging"
+)
+
+func TestCheckReloadable(t *testing.T) {
+	oldConf, err := ReadConfig("./config_file.json")
+	if err != nil {
+		t.Fatal("Fail to read file store config")
+	}
+
+	newConf, _ := ReadConfig("./config_file.json")
+	newConf.LogConfig = &logging.LogConfig{Level: "debug"}
+	newConf.FuncsvcEndpoint = "http://localhost:8080/funcsvc"
+	newConf.ServerConfig = &ServerConfig{CertPath: "/tmp/new.crt", KeyPath: "/tmp/new.key"}
+	if err := CheckReloadable(oldConf, newConf); err != nil {
+		t.Errorf("live changes should be accepted, err: %v", err)
+	}
+
+	etcdConf, err := ReadConfig("./config_etcd.json")
+	if err != nil {
+		t.Fatal("Fail to read etcd store config")
+	}
+	if err := CheckReloadable(oldConf, etcdConf); err == nil {
+		t.Error("changing store type should be rejected")
+	}
+
+	newConf, _ = ReadConfig("./config_file.json")
+	newConf.ServerConfig = &ServerConfig{Endpoint: "0.0.0.0:1234"}
+	if err := CheckReloadable(oldConf, newConf); err == nil {
+		t.Error("changing endpoint should be rejected")
+	}
+
+	newConf, _ = ReadConfig("./config_file.json")
+	newConf.TypeCheckConditions = !oldConf.TypeCheckConditions
+	if err := CheckReloadable(oldConf, newConf); err == nil {
+		t.Error("changing condition type checking should be rejected")
+	}
+
+	newConf, _ = ReadConfig("./config_file.json")
+	newConf.FunctionResultCacheConfig = &FunctionResultCacheConfig{MaxEntries: 100}
+	if err := CheckReloadable(oldConf, newConf); err == nil {
+		t.Error("changing function result cache should be rejected")
+	}
+}
@@ -0,0 +1,208 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package flags
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/soheilhy/cmux"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// RESTDisabled returns true if the REST server is disabled
+func (k *Parameters) RESTDisabled() bool {
+	disabled, _ := strconv.ParseBool(k.DisableREST.Value)
+	return disabled
+}
+
+// GRPCDisabled returns true if the gRPC server is disabled
+func (k *Parameters) GRPCDisabled() bool {
+	disabled, _ := strconv.ParseBool(k.DisableGRPC.Value)
+	return disabled
+}
+
+// SharedPort returns true if REST and gRPC are served on one port
+func (k *Parameters) SharedPort() bool {
+	return !k.RESTDisabled() && !k.GRPCDisabled() && k.GRPCEndpoint.Value == k.Endpoint.Value
+}
+
+// grpcInsecure returns the transport security mode of gRPC server, which defaults to the one of REST server
+func (k *Parameters) grpcInsecure() bool {
+	value := k.GRPCInsecure.Value
+	if value == "" || k.SharedPort() {
+		value = k.Insecure.Value
+	}
+	insecure, _ := strconv.ParseBool(value)
+	return insecure
+}
+
+func (k *Parameters) grpcCertPath() string {
+	if k.GRPCCertPath.Value != "" {
+		return k.GRPCCertPath.Value
+	}
+	return k.CertPath.Value
+}
+
+func (k *Parameters) grpcKeyPath() string {
+	if k.GRPCKeyPath.Value != "" {
+		return k.GRPCKeyPath.Value
+	}
+	return k.KeyPath.Value
+}
+
+func (k *Parameters) grpcClientCertPath() string {
+	if k.GRPCClientCertPath.Value != "" {
+		return k.GRPCClientCertPath.Value
+	}
+	return k.ClientCertPath.Value
+}
+
+func (k *Parameters) grpcForceClientCert() string {
+	if k.GRPCForceClientCert.Value != "" {
+		return k.GRPCForceClientCert.Value
+	}
+	return k.ForceClientCert.Value
+}
+
+func (k *Parameters) validateGRPCFlags() {
+	for _, param := range []StrParamDetail{k.DisableREST, k.DisableGRPC, k.GRPCInsecure, k.GRPCForceClientCert} {
+		if param.Value == "" {
+			continue
+		}
+		if _, err := strconv.ParseBool(param.Value); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid value for '%s' parameter: %s", param.Name, param.Value)
+			k.usage()
+		}
+	}
+
+	if k.RESTDisabled() && k.GRPCDisabled() {
+		fmt.Fprintln(os.Stderr, "REST server and gRPC server can not be both disabled.")
+		k.usage()
+	}
+
+	if k.GRPCDisabled() || k.SharedPort() || k.grpcInsecure() {
+		return
+	}
+	if k.grpcCertPath() == "" || k.grpcKeyPath() == "" {
+		fmt.Fprintln(os.Stderr, "In secure mode, "+k.GRPCKeyPath.Name+", "+k.GRPCCertPath.Name+" or "+k.KeyPath.Name+", "+k.CertPath.Name+" should be passed.")
+		k.usage()
+	}
+	forceClientCert, _ := strconv.ParseBool(k.grpcForceClientCert())
+	if forceClientCert && k.grpcClientCertPath() == "" {
+		fmt.Fprintln(os.Stderr, "In secure mode and force client certification is enabled, "+k.GRPCClientCertPath.Name+" or "+k.ClientCertPath.Name+" should be passed.")
+		k.usage()
+	}
+}
+
+// NewGRPCServer creates a gRPC server with the transport credentials configured by gRPC server parameters.
+// When REST and gRPC share one port, TLS is terminated by the REST server instead.
+func (k *Parameters) NewGRPCServer(opt ...grpc.ServerOption) (*grpc.Server, error) {
+	if !k.grpcInsecure() && !k.SharedPort() {
+		k.grpcCertLoader = &certificateLoader{}
+		if err := k.grpcCertLoader.load(k.grpcCertPath(), k.grpcKeyPath()); err != nil {
+			return nil, err
+		}
+		tlsConfig, err := newServerTLSConfig(k.grpcCertLoader, k.grpcClientCertPath(), k.grpcForceClientCert())
+		if err != nil {
+			return nil, err
+		}
+		opt = append(opt, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return grpc.NewServer(opt...), nil
+}
+
+// ListenAndServeGRPC listens on gRPC endpoint and serves gRPC requests
+func (k *Parameters) ListenAndServeGRPC(s *grpc.Server) error {
+	endpoint := k.GRPCEndpoint.Value
+	lis, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return errors.Wrapf(err, errors.ServerError, "failed to listen on endpoint %s", endpoint)
+	}
+	if err := s.Serve(lis); err != nil {
+		return errors.Wrapf(err, errors.ServerError, "failed to serve for endpoint %s", endpoint)
+	}
+	return nil
+}
+
+// ListenAndServeShared listens on the endpoint, and serves both gRPC and REST requests on it.
+// gRPC requests are recognized by their HTTP/2 content type.
+func (k *Parameters) ListenAndServeShared(httpServer *http.Server, grpcServer *grpc.Server) error {
+	endpoint := k.Endpoint.Value
+	lis, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return errors.Wrapf(err, errors.ServerError, "failed to listen on endpoint %s", endpoint)
+	}
+	if err := k.serveShared(lis, httpServer, grpcServer); err != nil {
+		return errors.Wrapf(err, errors.ServerError, "failed to serve for endpoint %s", endpoint)
+	}
+	return nil
+}
+
+func (k *Parameters) serveShared(lis net.Listener, httpServer *http.Server, grpcServer *grpc.Server) error {
+	insecure, _ := strconv.ParseBool(k.Insecure.Value)
+	if !insecure {
+		// TLS is terminated by the REST server, so that both REST handlers and gRPC services see the
+		// client certificates, gRPC requests are handed over to the gRPC server by the REST server.
+		httpServer.Handler = grpcHandler(grpcServer, httpServer.Handler)
+		if err := http2.ConfigureServer(httpServer, nil); err != nil {
+			return err
+		}
+		return httpServer.ServeTLS(lis, "", "")
+	}
+
+	m := cmux.New(lis)
+	httpListener := m.Match(cmux.HTTP1Fast())
+	grpcListener := m.Match(cmux.HTTP2HeaderField("content-type", "application/grpc"))
+	http2Listener := m.Match(cmux.Any())
+
+	errChan := make(chan error, 4)
+	go func() {
+		errChan <- grpcServer.Serve(grpcListener)
+	}()
+	go func() {
+		errChan <- httpServer.Serve(httpListener)
+	}()
+	go func() {
+		errChan <- serveHTTP2(http2Listener, httpServer)
+	}()
+	go func() {
+		errChan <- m.Serve()
+	}()
+	return <-errChan
+}
+
+// grpcHandler dispatches gRPC requests to grpcServer, and the other requests to handler
+func grpcHandler(grpcServer *grpc.Server, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// serveHTTP2 serves REST requests over HTTP/2, which http.Server does not negotiate for connections
+// accepted from a multiplexed listener
+func serveHTTP2(lis net.Listener, httpServer *http.Server) error {
+	h2Server := &http2.Server{}
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			return err
+		}
+		go h2Server.ServeConn(conn, &http2.ServeConnOpts{
+			BaseConfig: httpServer,
+			Handler:    httpServer.Handler,
+		})
+	}
+}
@@ -0,0 +1,145 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package flags
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// writeTestCertificate writes a self signed certificate for 127.0.0.1, which is used by both server and client
+func writeTestCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "speedle"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("failed to create certificate:", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("failed to marshal key:", err)
+	}
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile, cert
+}
+
+func TestServeSharedTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-flags")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, cert := writeTestCertificate(t, dir)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen:", err)
+	}
+	endpoint := lis.Addr().String()
+	k := &Parameters{}
+	k.Endpoint.Value, k.GRPCEndpoint.Value = endpoint, endpoint
+	k.Insecure.Value = "false"
+	k.CertPath.Value, k.KeyPath.Value = certFile, keyFile
+	k.ClientCertPath.Value, k.ForceClientCert.Value = certFile, "true"
+	if !k.SharedPort() {
+		t.Fatal("REST and gRPC should share the port")
+	}
+
+	// Both REST handlers and gRPC services see the client certificate
+	httpServer, err := k.NewHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	if err != nil {
+		t.Fatal("failed to create REST server:", err)
+	}
+	var grpcPeer string
+	grpcServer, err := k.NewGRPCServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) != 0 {
+				grpcPeer = tlsInfo.State.PeerCertificates[0].Subject.CommonName
+			}
+		}
+		return handler(ctx, req)
+	}))
+	if err != nil {
+		t.Fatal("failed to create gRPC server:", err)
+	}
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	go k.serveShared(lis, httpServer, grpcServer)
+	defer httpServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal("failed to load client certificate:", err)
+	}
+	tlsConfig := &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}
+
+	// An empty TLSNextProto disables HTTP/2, so that the request is sent over HTTP/1.1
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: tlsConfig,
+		TLSNextProto:    map[string]func(string, *tls.Conn) http.RoundTripper{},
+	}}
+	resp, err := client.Get("https://" + endpoint + "/")
+	if err != nil {
+		t.Fatal("failed to send REST request:", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Proto != "HTTP/1.1" || resp.StatusCode != http.StatusOK || string(body) != "speedle" {
+		t.Errorf("expected client certificate in %s REST request, got status %d, body %q", resp.Proto, resp.StatusCode, body)
+	}
+
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		t.Fatal("failed to connect to gRPC server:", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal("failed to send gRPC request:", err)
+	}
+	if grpcPeer != "speedle" {
+		t.Errorf("expected client certificate in gRPC request, got %q", grpcPeer)
+	}
+}
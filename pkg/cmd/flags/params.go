@@ -37,16 +37,38 @@ type Parameters struct {
 	CertPath        StrParamDetail
 	ClientCertPath  StrParamDetail
 	ForceClientCert StrParamDetail
+	DisableREST     StrParamDetail
+	AdminEndpoint   StrParamDetail
+	/////////gRPC server config//////////
+	GRPCEndpoint        StrParamDetail
+	GRPCInsecure        StrParamDetail
+	GRPCKeyPath         StrParamDetail
+	GRPCCertPath        StrParamDetail
+	GRPCClientCertPath  StrParamDetail
+	GRPCForceClientCert StrParamDetail
+	DisableGRPC         StrParamDetail
 	/////////Store config////////////////
 	StoreType         StrParamDetail
 	StoreWatchEnabled StrParamDetail
 
+	////////Evaluator config////////////////
+	FuncsvcEndpoint     StrParamDetail
+	TypeCheckConditions StrParamDetail
+	RequestTimeOverride StrParamDetail
+
 	////////Log config/////////////////////
 	LogConf      LogParameters // normal log configuration
 	AuditLogConf LogParameters // audit log configuration
 
 	// AsserterParameters asserter webhook configuration
 	AsserterConf AsserterParameters
+
+	// certLoader holds the server certificate of the TLS server
+	certLoader *certificateLoader
+	// grpcCertLoader holds the server certificate of the gRPC server
+	grpcCertLoader *certificateLoader
+	// fileConfig is the configuration read from configuration file, which keeps the settings without flags
+	fileConfig *cfg.Config
 }
 
 // LogParameters is the parameters for log configuration
@@ -69,13 +91,17 @@ type AsserterParameters struct {
 	AsserterCaPath         StrParamDetail
 	AsserterClientCertPath StrParamDetail
 	AsserterClientTimeout  StrParamDetail
+	AttributesNamespace    StrParamDetail
+	AttributesPrecedence   StrParamDetail
 }
 
 const (
-	DefaultPolicyMgmtEndPoint = "0.0.0.0:6733"
-	DefaultAuthzCheckEndPoint = "0.0.0.0:6734"
-	DefaultInsecure           = true
-	DefaultEnableAuthz        = false
+	DefaultPolicyMgmtEndPoint     = "0.0.0.0:6733"
+	DefaultAuthzCheckEndPoint     = "0.0.0.0:6734"
+	DefaultPolicyMgmtGRPCEndPoint = "0.0.0.0:50001"
+	DefaultAuthzCheckGRPCEndPoint = "0.0.0.0:50002"
+	DefaultInsecure               = true
+	DefaultEnableAuthz            = false
 
 	DefaultStoreType = cfg.StorageTypeFile //file
 
@@ -113,23 +139,62 @@ func (k *Parameters) NewHTTPServer(handler http.Handler) (*http.Server, error) {
 	return k.newTLSServer(handler)
 }
 
+// NewAdminServer creates the server of the admin REST APIs, it has the transport security of the REST server
+// but listens on the admin endpoint. It returns nil if the admin endpoint is not set.
+func (k *Parameters) NewAdminServer(handler http.Handler) (*http.Server, error) {
+	if len(k.AdminEndpoint.Value) == 0 {
+		return nil, nil
+	}
+	server, err := k.NewHTTPServer(handler)
+	if err != nil {
+		return nil, err
+	}
+	server.Addr = k.AdminEndpoint.Value
+	return server, nil
+}
+
 func (k *Parameters) newTLSServer(handler http.Handler) (*http.Server, error) {
-	// Set HTTPS client
-	tlsConfig := &tls.Config{}
+	// Server certificate is loaded through GetCertificate, so that it can be rotated without restarting the server,
+	// the loader is shared by the REST and admin servers
+	if k.certLoader == nil {
+		k.certLoader = &certificateLoader{}
+		if err := k.certLoader.load(k.CertPath.Value, k.KeyPath.Value); err != nil {
+			return nil, err
+		}
+	}
+	tlsConfig, err := newServerTLSConfig(k.certLoader, k.ClientCertPath.Value, k.ForceClientCert.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	server := http.Server{
+		Addr:      k.Endpoint.Value,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+	return &server, nil
+}
 
-	if k.ClientCertPath.Value != "" {
-		caCert, err := ioutil.ReadFile(k.ClientCertPath.Value)
+// newServerTLSConfig creates a server side TLS configuration, client certificates are verified
+// against the CA in clientCertPath if it is given.
+func newServerTLSConfig(certLoader *certificateLoader, clientCertPath string, forceClientCertValue string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: certLoader.GetCertificate,
+	}
+
+	if clientCertPath != "" {
+		caCert, err := ioutil.ReadFile(clientCertPath)
 		if err != nil {
-			return nil, errors.Wrapf(err, errors.ConfigError, "unable to read client CA certification from file %s", k.ClientCertPath.Value)
+			return nil, errors.Wrapf(err, errors.ConfigError, "unable to read client CA certification from file %s", clientCertPath)
 		}
 
 		caCertPool := x509.NewCertPool()
 		if !caCertPool.AppendCertsFromPEM(caCert) {
-			return nil, errors.Wrap(err, errors.ConfigError, "failed to append certificates to pool")
+			return nil, errors.Errorf(errors.ConfigError, "failed to append certificates in %s to pool", clientCertPath)
 		}
 
 		tlsConfig.ClientCAs = caCertPool
-		forceClientCert, _ := strconv.ParseBool(k.ForceClientCert.Value)
+		forceClientCert, _ := strconv.ParseBool(forceClientCertValue)
 		if forceClientCert {
 			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 		} else {
@@ -138,15 +203,7 @@ func (k *Parameters) newTLSServer(handler http.Handler) (*http.Server, error) {
 	} else {
 		tlsConfig.ClientAuth = tls.NoClientCert
 	}
-
-	tlsConfig.BuildNameToCertificate()
-
-	server := http.Server{
-		Addr:      k.Endpoint.Value,
-		Handler:   handler,
-		TLSConfig: tlsConfig,
-	}
-	return &server, nil
+	return tlsConfig, nil
 }
 
 func (k *Parameters) ListenAndServe(s *http.Server) error {
@@ -158,11 +215,14 @@ func (k *Parameters) ListenAndServe(s *http.Server) error {
 }
 
 func (k *Parameters) listenAndServeTLS(s *http.Server) error {
+	if s.TLSConfig != nil && s.TLSConfig.GetCertificate != nil {
+		return s.ListenAndServeTLS("", "")
+	}
 	return s.ListenAndServeTLS(k.CertPath.Value, k.KeyPath.Value)
 }
 
 // ParseFlags parses command line arguments
-func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func(), storeParamsMap map[string]string) {
+func (k *Parameters) ParseFlags(defaultEndpoint string, defaultGRPCEndpoint string, printVersionInfoFun func(), storeParamsMap map[string]string) {
 	var params []*StrParamDetail
 	k.ConfigFile = StrParamDetail{Name: "config-file", ShortName: "k", Usage: "Configuration file."}
 	params = append(params, &k.ConfigFile)
@@ -172,19 +232,44 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 	params = append(params, &k.Insecure)
 	k.EnableAuthz = StrParamDetail{Name: "enable-authz", DefaultValue: strconv.FormatBool(DefaultEnableAuthz), Usage: "Server config: Enable authorization check."}
 	params = append(params, &k.EnableAuthz)
-	k.CertPath = StrParamDetail{Name: "cert", Usage: "Server config: Server certifice file path."}
+	k.CertPath = StrParamDetail{Name: "cert", Usage: "Server config: Server certificate file path."}
 	params = append(params, &k.CertPath)
 	k.KeyPath = StrParamDetail{Name: "key", Usage: "Server config: Server key file path."}
 	params = append(params, &k.KeyPath)
-	k.ClientCertPath = StrParamDetail{Name: "client-cert", ShortName: "c", Usage: "Server config: Client certifice file path."}
+	k.ClientCertPath = StrParamDetail{Name: "client-cert", ShortName: "c", Usage: "Server config: Client certificate file path."}
 	params = append(params, &k.ClientCertPath)
 	k.ForceClientCert = StrParamDetail{Name: "force-client-cert", ShortName: "f", Usage: "Server config: Force Client certification."}
 	params = append(params, &k.ForceClientCert)
+	k.DisableREST = StrParamDetail{Name: "disable-rest", DefaultValue: strconv.FormatBool(false), Usage: "Server config: Disable the REST server."}
+	params = append(params, &k.DisableREST)
+	k.AdminEndpoint = StrParamDetail{Name: "admin-endpoint", Usage: "Server config: Endpoint of the admin REST APIs, e.g. the cache APIs of authorization decision service. They are disabled if it is empty, and it should only be reachable by administrators."}
+	params = append(params, &k.AdminEndpoint)
+
+	k.GRPCEndpoint = StrParamDetail{Name: "grpc-endpoint", DefaultValue: defaultGRPCEndpoint, Usage: "gRPC server config: Endpoint the gRPC server listen and serve. gRPC and REST are served on one port if it is the same as endpoint."}
+	params = append(params, &k.GRPCEndpoint)
+	k.GRPCInsecure = StrParamDetail{Name: "grpc-insecure", Usage: "gRPC server config: Disable transport security, defaults to the value of insecure."}
+	params = append(params, &k.GRPCInsecure)
+	k.GRPCCertPath = StrParamDetail{Name: "grpc-cert", Usage: "gRPC server config: Server certificate file path, defaults to the value of cert."}
+	params = append(params, &k.GRPCCertPath)
+	k.GRPCKeyPath = StrParamDetail{Name: "grpc-key", Usage: "gRPC server config: Server key file path, defaults to the value of key."}
+	params = append(params, &k.GRPCKeyPath)
+	k.GRPCClientCertPath = StrParamDetail{Name: "grpc-client-cert", Usage: "gRPC server config: Client certificate file path, defaults to the value of client-cert."}
+	params = append(params, &k.GRPCClientCertPath)
+	k.GRPCForceClientCert = StrParamDetail{Name: "grpc-force-client-cert", Usage: "gRPC server config: Force Client certification, defaults to the value of force-client-cert."}
+	params = append(params, &k.GRPCForceClientCert)
+	k.DisableGRPC = StrParamDetail{Name: "disable-grpc", DefaultValue: strconv.FormatBool(false), Usage: "gRPC server config: Disable the gRPC server."}
+	params = append(params, &k.DisableGRPC)
 
 	k.StoreType = StrParamDetail{Name: "store-type", DefaultValue: DefaultStoreType, Usage: "Store config: Policy store type, etcd or file."}
 	params = append(params, &k.StoreType)
 	k.StoreWatchEnabled = StrParamDetail{Name: "enable-watch", DefaultValue: strconv.FormatBool(DefaultStoreWatchEnabled), Usage: "Evaluator config: Whether enable watch store changes."}
 	params = append(params, &k.StoreWatchEnabled)
+	k.FuncsvcEndpoint = StrParamDetail{Name: "funcsvc-endpoint", Usage: "Evaluator config: Endpoint of the delegator calling customer functions."}
+	params = append(params, &k.FuncsvcEndpoint)
+	k.TypeCheckConditions = StrParamDetail{Name: "type-check-conditions", DefaultValue: strconv.FormatBool(false), Usage: "Evaluator config: Fail the conditions accessing non-object paths of object attributes, instead of evaluating them to null."}
+	params = append(params, &k.TypeCheckConditions)
+	k.RequestTimeOverride = StrParamDetail{Name: "allow-request-time-override", DefaultValue: strconv.FormatBool(false), Usage: "Evaluator config: Allow callers to override request_time and request_timezone for testing or replay, don't enable it in production."}
+	params = append(params, &k.RequestTimeOverride)
 
 	// Log configurations
 	k.LogConf.LogLevel = StrParamDetail{Name: "log-level", Usage: "Log config: log level, available levels are panic, fatal, error, warn, info and debug."}
@@ -236,6 +321,10 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 	params = append(params, &k.AsserterConf.AsserterCaPath)
 	k.AsserterConf.AsserterClientTimeout = StrParamDetail{Name: "asserter-client-timeout", DefaultValue: DefaultAsserterClientTimeout, Usage: "Assertion service client http timeout value."}
 	params = append(params, &k.AsserterConf.AsserterClientTimeout)
+	k.AsserterConf.AttributesNamespace = StrParamDetail{Name: "asserter-attributes-namespace", Usage: "Object attribute the asserted attributes are merged into, defaults to subject."}
+	params = append(params, &k.AsserterConf.AttributesNamespace)
+	k.AsserterConf.AttributesPrecedence = StrParamDetail{Name: "asserter-attributes-precedence", Usage: "Precedence of the asserted attributes over the ones in requests, available values are asserted (default), request and reject."}
+	params = append(params, &k.AsserterConf.AttributesPrecedence)
 
 	pflag.BoolVarP(&k.Version, "version", "", false, "print version information")
 
@@ -269,6 +358,16 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 		conf = nil
 	}
 
+	k.applyConfig(conf, storeParamsMap, false)
+
+	fmt.Printf("parameters:%v\n", k)
+}
+
+// applyConfig sets the flags which are set neither from command line nor from environment variables
+// to the values in conf. If resetToDefault is true, such flags are reset to their default values first,
+// so that settings removed from the configuration file take effect when reloading it.
+func (k *Parameters) applyConfig(conf *cfg.Config, storeParamsMap map[string]string, resetToDefault bool) {
+	k.fileConfig = conf
 	pflag.VisitAll(func(f *pflag.Flag) {
 		key := FlagToEnv(f.Name)
 		if !f.Changed {
@@ -278,6 +377,9 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 				f.Value.Set(val)
 			} else {
 				//if not set from environment variable, search it from config file
+				if resetToDefault {
+					f.Value.Set(f.DefValue)
+				}
 				switch f.Name {
 				case k.Endpoint.Name:
 					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.Endpoint) != 0 {
@@ -307,6 +409,42 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.ClientCertPath) != 0 {
 						f.Value.Set(conf.ServerConfig.ClientCertPath)
 					}
+				case k.DisableREST.Name:
+					if conf != nil && conf.ServerConfig != nil {
+						f.Value.Set(strconv.FormatBool(conf.ServerConfig.DisableREST))
+					}
+				case k.AdminEndpoint.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.AdminEndpoint) != 0 {
+						f.Value.Set(conf.ServerConfig.AdminEndpoint)
+					}
+				case k.GRPCEndpoint.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.GRPCEndpoint) != 0 {
+						f.Value.Set(conf.ServerConfig.GRPCEndpoint)
+					}
+				case k.GRPCInsecure.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.GRPCInsecure) != 0 {
+						f.Value.Set(conf.ServerConfig.GRPCInsecure)
+					}
+				case k.GRPCKeyPath.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.GRPCKeyPath) != 0 {
+						f.Value.Set(conf.ServerConfig.GRPCKeyPath)
+					}
+				case k.GRPCCertPath.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.GRPCCertPath) != 0 {
+						f.Value.Set(conf.ServerConfig.GRPCCertPath)
+					}
+				case k.GRPCClientCertPath.Name:
+					if conf != nil && conf.ServerConfig != nil && len(conf.ServerConfig.GRPCClientCertPath) != 0 {
+						f.Value.Set(conf.ServerConfig.GRPCClientCertPath)
+					}
+				case k.GRPCForceClientCert.Name:
+					if conf != nil && conf.ServerConfig != nil && conf.ServerConfig.GRPCForceClientCert {
+						f.Value.Set(strconv.FormatBool(conf.ServerConfig.GRPCForceClientCert))
+					}
+				case k.DisableGRPC.Name:
+					if conf != nil && conf.ServerConfig != nil {
+						f.Value.Set(strconv.FormatBool(conf.ServerConfig.DisableGRPC))
+					}
 				case k.StoreType.Name:
 					if conf != nil && conf.StoreConfig != nil && len(conf.StoreConfig.StoreType) != 0 {
 						f.Value.Set(conf.StoreConfig.StoreType)
@@ -315,6 +453,18 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 					if conf != nil {
 						f.Value.Set(strconv.FormatBool(conf.EnableWatch))
 					}
+				case k.FuncsvcEndpoint.Name:
+					if conf != nil && len(conf.FuncsvcEndpoint) != 0 {
+						f.Value.Set(conf.FuncsvcEndpoint)
+					}
+				case k.TypeCheckConditions.Name:
+					if conf != nil {
+						f.Value.Set(strconv.FormatBool(conf.TypeCheckConditions))
+					}
+				case k.RequestTimeOverride.Name:
+					if conf != nil {
+						f.Value.Set(strconv.FormatBool(conf.AllowRequestTimeOverride))
+					}
 				// Log configurations
 				case k.LogConf.LogLevel.Name:
 					if conf != nil && conf.LogConfig != nil {
@@ -408,7 +558,15 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 					}
 				case k.AsserterConf.AsserterClientTimeout.Name:
 					if conf != nil && conf.AsserterWebhookConfig != nil {
-						f.Value.Set(string(conf.AsserterWebhookConfig.HTTPTimeout))
+						f.Value.Set(strconv.Itoa(conf.AsserterWebhookConfig.HTTPTimeout))
+					}
+				case k.AsserterConf.AttributesNamespace.Name:
+					if conf != nil && conf.AsserterWebhookConfig != nil && len(conf.AsserterWebhookConfig.AttributesNamespace) != 0 {
+						f.Value.Set(conf.AsserterWebhookConfig.AttributesNamespace)
+					}
+				case k.AsserterConf.AttributesPrecedence.Name:
+					if conf != nil && conf.AsserterWebhookConfig != nil && len(conf.AsserterWebhookConfig.AttributesPrecedence) != 0 {
+						f.Value.Set(conf.AsserterWebhookConfig.AttributesPrecedence)
 					}
 				default:
 					//
@@ -435,8 +593,6 @@ func (k *Parameters) ParseFlags(defaultEndpoint string, printVersionInfoFun func
 
 		}
 	})
-
-	fmt.Printf("parameters:%v\n", k)
 }
 
 // FlagToEnv converts flag string to upper-case environment variable key string.
@@ -451,6 +607,8 @@ func (k *Parameters) ValidateFlags() {
 		k.usage()
 	}
 
+	k.validateGRPCFlags()
+
 	if len(k.EnableAuthz.Value) != 0 {
 		_, err = strconv.ParseBool(k.EnableAuthz.Value)
 		if err != nil {
@@ -459,7 +617,7 @@ func (k *Parameters) ValidateFlags() {
 		}
 	}
 
-	if !insecure {
+	if !insecure && !k.RESTDisabled() {
 		if k.CertPath.Value == "" || k.KeyPath.Value == "" {
 			fmt.Fprintln(os.Stderr, "In secure mode, "+k.KeyPath.Name+", "+k.CertPath.Name+" should be passed.")
 			k.usage()
@@ -496,8 +654,36 @@ func (k *Parameters) Param2Config(storeParamsMap map[string]string) (*cfg.Config
 
 	conf.StoreConfig = &storeConf
 
+	forceClientCert, _ := strconv.ParseBool(k.ForceClientCert.Value)
+	grpcForceClientCert, _ := strconv.ParseBool(k.GRPCForceClientCert.Value)
+	disableREST, _ := strconv.ParseBool(k.DisableREST.Value)
+	disableGRPC, _ := strconv.ParseBool(k.DisableGRPC.Value)
+	conf.ServerConfig = &cfg.ServerConfig{
+		Endpoint:            k.Endpoint.Value,
+		Insecure:            k.Insecure.Value,
+		EnableAuthz:         k.EnableAuthz.Value,
+		KeyPath:             k.KeyPath.Value,
+		CertPath:            k.CertPath.Value,
+		ClientCertPath:      k.ClientCertPath.Value,
+		ForceClientCert:     forceClientCert,
+		DisableREST:         disableREST,
+		AdminEndpoint:       k.AdminEndpoint.Value,
+		GRPCEndpoint:        k.GRPCEndpoint.Value,
+		GRPCInsecure:        k.GRPCInsecure.Value,
+		GRPCKeyPath:         k.GRPCKeyPath.Value,
+		GRPCCertPath:        k.GRPCCertPath.Value,
+		GRPCClientCertPath:  k.GRPCClientCertPath.Value,
+		GRPCForceClientCert: grpcForceClientCert,
+		DisableGRPC:         disableGRPC,
+	}
+
 	watchEnabled, _ := strconv.ParseBool(k.StoreWatchEnabled.Value)
 	conf.EnableWatch = watchEnabled
+	conf.FuncsvcEndpoint = k.FuncsvcEndpoint.Value
+	typeCheckConditions, _ := strconv.ParseBool(k.TypeCheckConditions.Value)
+	conf.TypeCheckConditions = typeCheckConditions
+	requestTimeOverride, _ := strconv.ParseBool(k.RequestTimeOverride.Value)
+	conf.AllowRequestTimeOverride = requestTimeOverride
 
 	// Log Configuration
 	if len(k.LogConf.LogLevel.Value) != 0 ||
@@ -621,11 +807,24 @@ func (k *Parameters) Param2Config(storeParamsMap map[string]string) (*cfg.Config
 				asserterConf.HTTPTimeout = timeout
 			}
 		}
+		asserterConf.AttributesNamespace = k.AsserterConf.AttributesNamespace.Value
+		asserterConf.AttributesPrecedence = k.AsserterConf.AttributesPrecedence.Value
 		conf.AsserterWebhookConfig = &asserterConf
 	}
 
 	fmt.Printf("%v\n", conf.AsserterWebhookConfig)
 
+	// Settings can only be set in configuration file
+	if k.fileConfig != nil {
+		conf.PMSAuthConfig = k.fileConfig.PMSAuthConfig
+		conf.QuotaConfig = k.fileConfig.QuotaConfig
+		conf.AsserterType = k.fileConfig.AsserterType
+		conf.JWTAsserterConfig = k.fileConfig.JWTAsserterConfig
+		conf.AssertionCacheConfig = k.fileConfig.AssertionCacheConfig
+		conf.FunctionResultCacheConfig = k.fileConfig.FunctionResultCacheConfig
+		conf.FunctionClientCerts = k.fileConfig.FunctionClientCerts
+	}
+
 	return &conf, nil
 }
 
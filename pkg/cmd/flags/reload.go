@@ -0,0 +1,252 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package flags
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/logging"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// configReloadDelay is the time to wait for more file system events before reloading configuration file,
+	// editors and config management tools usually generate several events for one change.
+	configReloadDelay = 500 * time.Millisecond
+
+	// kubernetesDataDir is the symlink swapped by Kubernetes when a mounted ConfigMap or Secret is updated
+	kubernetesDataDir = "..data"
+)
+
+// certificateLoader keeps the server certificate and serves it to TLS handshakes
+type certificateLoader struct {
+	sync.RWMutex
+	certPath string
+	keyPath  string
+	cert     *tls.Certificate
+}
+
+func (c *certificateLoader) load(certPath, keyPath string) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return errors.Wrapf(err, errors.ConfigError, "unable to load server certificate %q and key %q", certPath, keyPath)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.certPath = certPath
+	c.keyPath = keyPath
+	c.cert = &cert
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (c *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.cert, nil
+}
+
+// ConfigChangeHandler is called with the previous and the new configuration after the configuration is reloaded
+type ConfigChangeHandler func(oldConf, newConf *cfg.Config)
+
+// ConfigReloader reloads the configuration file when it is changed or SIGHUP is received, and applies
+// the changes to the running server
+type ConfigReloader struct {
+	sync.Mutex
+	params         *Parameters
+	storeParamsMap map[string]string
+	current        *cfg.Config
+	handlers       []ConfigChangeHandler
+}
+
+// NewConfigReloader creates a configuration reloader, conf is the configuration the server is running with
+func (k *Parameters) NewConfigReloader(conf *cfg.Config, storeParamsMap map[string]string) *ConfigReloader {
+	return &ConfigReloader{
+		params:         k,
+		storeParamsMap: storeParamsMap,
+		current:        conf,
+	}
+}
+
+// OnChange registers a handler which applies server specific settings once the configuration is reloaded.
+// Log configurations and server certificate are applied by the reloader itself.
+func (r *ConfigReloader) OnChange(handler ConfigChangeHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// Reload re-reads the configuration file and server certificate, and applies the changes.
+// The changes are rejected as a whole if any setting which can not be changed live is modified.
+func (r *ConfigReloader) Reload() error {
+	r.Lock()
+	defer r.Unlock()
+
+	k := r.params
+	if k.ConfigFile.Value != "" {
+		fileConf, err := cfg.ReadConfig(k.ConfigFile.Value)
+		if err != nil {
+			return err
+		}
+
+		saved, savedFileConfig := saveFlagValues(), k.fileConfig
+		k.applyConfig(fileConf, r.storeParamsMap, true)
+		newConf, err := k.Param2Config(r.storeParamsMap)
+		if err == nil {
+			err = cfg.CheckReloadable(r.current, newConf)
+		}
+		if err != nil {
+			restoreFlagValues(saved)
+			k.fileConfig = savedFileConfig
+			return err
+		}
+
+		r.applyLogConfig(newConf)
+		for _, handler := range r.handlers {
+			handler(r.current, newConf)
+		}
+		r.current = newConf
+	}
+
+	if k.certLoader != nil {
+		if err := k.certLoader.load(k.CertPath.Value, k.KeyPath.Value); err != nil {
+			return err
+		}
+		log.Infof("Reloaded server certificate %q.", k.CertPath.Value)
+	}
+	if k.grpcCertLoader != nil {
+		if err := k.grpcCertLoader.load(k.grpcCertPath(), k.grpcKeyPath()); err != nil {
+			return err
+		}
+		log.Infof("Reloaded gRPC server certificate %q.", k.grpcCertPath())
+	}
+	return nil
+}
+
+func (r *ConfigReloader) applyLogConfig(newConf *cfg.Config) {
+	if newConf.LogConfig != nil && !reflect.DeepEqual(r.current.LogConfig, newConf.LogConfig) {
+		if err := logging.InitLog(newConf.LogConfig); err != nil {
+			log.Errorf("Failed to apply the new log configuration, err: %v.", err)
+		} else {
+			log.Info("Applied the new log configuration.")
+		}
+	}
+	if newConf.AuditLogConfig != nil && !reflect.DeepEqual(r.current.AuditLogConfig, newConf.AuditLogConfig) {
+		if err := logging.InitAuditLog(newConf.AuditLogConfig); err != nil {
+			log.Errorf("Failed to apply the new audit log configuration, err: %v.", err)
+		} else {
+			log.Info("Applied the new audit log configuration.")
+		}
+	}
+}
+
+// Watch reloads the configuration whenever the configuration file is changed or SIGHUP is received,
+// until stop is closed
+func (r *ConfigReloader) Watch(stop <-chan struct{}) error {
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	var watcher *fsnotify.Watcher
+	configFile := r.params.ConfigFile.Value
+	if configFile != "" {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			signal.Stop(hupChan)
+			return errors.Wrap(err, errors.ConfigError, "fsnotify new watcher failed")
+		}
+		// Watch the directory rather than the file, so that the file can be replaced by rename
+		if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+			watcher.Close()
+			signal.Stop(hupChan)
+			return errors.Wrapf(err, errors.ConfigError, "failed to watch the directory of configuration file %q", configFile)
+		}
+		events = watcher.Events
+		watchErrors = watcher.Errors
+	}
+
+	go func() {
+		defer func() {
+			signal.Stop(hupChan)
+			if watcher != nil {
+				watcher.Close()
+			}
+		}()
+
+		var timer *time.Timer
+		var timerChan <-chan time.Time
+		for {
+			select {
+			case event := <-events:
+				if !isConfigFileEvent(configFile, event) {
+					continue
+				}
+				if timer == nil {
+					timer = time.NewTimer(configReloadDelay)
+				} else {
+					timer.Reset(configReloadDelay)
+				}
+				timerChan = timer.C
+			case <-timerChan:
+				timerChan = nil
+				log.Infof("Configuration file %q is changed, reloading...", configFile)
+				r.reloadAndLog()
+			case <-hupChan:
+				log.Info("Received SIGHUP, reloading configuration...")
+				r.reloadAndLog()
+			case err := <-watchErrors:
+				log.Warningf("Error happened when watching the configuration file, error: %v", err)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *ConfigReloader) reloadAndLog() {
+	if err := r.Reload(); err != nil {
+		log.Errorf("Failed to reload configuration, keep running with the previous configuration, err: %v.", err)
+		return
+	}
+	log.Info("Configuration reloaded.")
+}
+
+func isConfigFileEvent(configFile string, event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(configFile) || filepath.Base(name) == kubernetesDataDir
+}
+
+func saveFlagValues() map[string]string {
+	values := make(map[string]string)
+	pflag.VisitAll(func(f *pflag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+func restoreFlagValues(values map[string]string) {
+	pflag.VisitAll(func(f *pflag.Flag) {
+		if value, ok := values[f.Name]; ok {
+			f.Value.Set(value)
+		}
+	})
+}
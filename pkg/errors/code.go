@@ -15,6 +15,8 @@ const (
 	ServerError    ErrorCode = "SPDL-0002"
 	LoggingError   ErrorCode = "SPDL-0003"
 	InvalidRequest ErrorCode = "SPDL-0004"
+	Unauthorized   ErrorCode = "SPDL-0005"
+	Forbidden      ErrorCode = "SPDL-0006"
 )
 
 // For policy management errors
@@ -0,0 +1,117 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NewAsserterFuncFromConfig creates the function asserting the tokens of requests with the asserter of conf, and
+// the cache of the asserter if it's configured. Nil function is returned if no asserter is configured.
+func NewAsserterFuncFromConfig(conf *cfg.Config) (func(ctx *adsapi.RequestContext) error, *assertion.CachingAsserter, error) {
+	if conf.AsserterWebhookConfig == nil && conf.JWTAsserterConfig == nil {
+		return nil, nil, nil
+	}
+	as, attributesConf, err := assertion.NewTokenAsserter(conf.AsserterType, conf.AsserterWebhookConfig, conf.JWTAsserterConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ConfigError, "failed to load asserter")
+	}
+
+	var cache *assertion.CachingAsserter
+	if conf.AssertionCacheConfig != nil && conf.AssertionCacheConfig.TTL > 0 {
+		if cache, err = assertion.NewCachingAsserter(as, conf.AssertionCacheConfig); err != nil {
+			return nil, nil, err
+		}
+		as = cache
+	}
+	f, err := NewAsserterFunc(as, attributesConf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, cache, nil
+}
+
+// NewAsserterFunc returns the function asserting the tokens of requests with asserter, it's set to evaluators by
+// SetAsserterFunc. The asserted principals are added to the subjects of requests, and the asserted attributes are
+// merged into the attributes of requests as configured by conf.
+func NewAsserterFunc(asserter assertion.TokenAsserter, conf *assertion.AttributesConfig) (func(ctx *adsapi.RequestContext) error, error) {
+	namespace := assertion.DefaultAttributesNamespace
+	precedence := assertion.AssertedAttributesFirst
+	if conf != nil {
+		if len(conf.AttributesNamespace) != 0 {
+			namespace = conf.AttributesNamespace
+		}
+		if len(conf.AttributesPrecedence) != 0 {
+			precedence = conf.AttributesPrecedence
+		}
+	}
+	switch precedence {
+	case assertion.AssertedAttributesFirst, assertion.RequestAttributesFirst, assertion.RejectConflictAttributes:
+	default:
+		return nil, errors.Errorf(errors.ConfigError, "unknown precedence of asserted attributes %q", precedence)
+	}
+
+	return func(ctx *adsapi.RequestContext) error {
+		if ctx.Subject == nil || len(ctx.Subject.TokenType) == 0 || len(ctx.Subject.Token) == 0 {
+			return nil
+		}
+		log.Debugf("Asserting token %s with token type %s.", ctx.Subject.Token, ctx.Subject.TokenType)
+		resp, err := asserter.AssertToken(ctx.Subject.Token, ctx.Subject.TokenType, "", nil)
+		if err != nil {
+			log.Errorf("Failed to assert due to error %v.", err)
+			return err
+		}
+		ctx.Subject.Principals = append(ctx.Subject.Principals, resp.Principals...)
+		return mergeAssertedAttributes(ctx, resp.Attributes, namespace, precedence)
+	}, nil
+}
+
+// mergeAssertedAttributes merges the asserted attributes into object attribute namespace of the request. The
+// attributes of the request are copied rather than modified, as they may be shared by callers. The precedence
+// applies to the namespace passed by callers even if nothing is asserted.
+func mergeAssertedAttributes(ctx *adsapi.RequestContext, asserted map[string]interface{}, namespace, precedence string) error {
+	passed, ok := ctx.Attributes[namespace]
+	if len(asserted) == 0 && !ok {
+		return nil
+	}
+	attributes := make(map[string]interface{}, len(ctx.Attributes)+1)
+	for key, value := range ctx.Attributes {
+		attributes[key] = value
+	}
+
+	object := make(map[string]interface{}, len(asserted))
+	if ok {
+		passedObject, isObject := passed.(map[string]interface{})
+		switch {
+		case isObject:
+			for key, value := range passedObject {
+				object[key] = value
+			}
+		case precedence == assertion.RejectConflictAttributes:
+			return errors.Errorf(errors.InvalidRequest, "attribute %q is reserved for the asserted attributes", namespace)
+		case precedence == assertion.RequestAttributesFirst:
+			log.Debugf("Asserted attributes are dropped, as attribute %q is passed.", namespace)
+			return nil
+		}
+	}
+	for key, value := range asserted {
+		if _, ok := object[key]; ok {
+			switch precedence {
+			case assertion.RejectConflictAttributes:
+				return errors.Errorf(errors.InvalidRequest, "attribute %s.%s is asserted, it can't be passed in the request", namespace, key)
+			case assertion.RequestAttributesFirst:
+				continue
+			}
+		}
+		object[key] = value
+	}
+	attributes[namespace] = object
+	ctx.Attributes = attributes
+	return nil
+}
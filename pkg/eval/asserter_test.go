@@ -0,0 +1,179 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// fakeAsserter asserts any token as user alice in group finance
+type fakeAsserter struct{}
+
+func (fakeAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*assertion.AssertResponse, error) {
+	return &assertion.AssertResponse{
+		Principals: []*adsapi.Principal{
+			{Type: adsapi.PRINCIPAL_TYPE_USER, Name: "alice"},
+			{Type: adsapi.PRINCIPAL_TYPE_GROUP, Name: "finance"},
+		},
+		Attributes: map[string]interface{}{"department": "finance", "clearance": 3.0},
+	}, nil
+}
+
+func TestAssertedAttributes(t *testing.T) {
+	testCases := []struct {
+		conf       *assertion.AttributesConfig
+		attributes map[string]interface{}
+		want       map[string]interface{}
+		err        bool
+	}{
+		{nil, nil, map[string]interface{}{
+			"subject": map[string]interface{}{"department": "finance", "clearance": 3.0}}, false},
+		{&assertion.AttributesConfig{AttributesNamespace: "claims"}, map[string]interface{}{"env": "prod"}, map[string]interface{}{
+			"env": "prod", "claims": map[string]interface{}{"department": "finance", "clearance": 3.0}}, false},
+		{nil, map[string]interface{}{"subject": map[string]interface{}{"department": "hr", "site": "BJ"}}, map[string]interface{}{
+			"subject": map[string]interface{}{"department": "finance", "clearance": 3.0, "site": "BJ"}}, false},
+		{&assertion.AttributesConfig{AttributesPrecedence: "request"},
+			map[string]interface{}{"subject": map[string]interface{}{"department": "hr"}}, map[string]interface{}{
+				"subject": map[string]interface{}{"department": "hr", "clearance": 3.0}}, false},
+		{&assertion.AttributesConfig{AttributesPrecedence: "request"}, map[string]interface{}{"subject": "bob"},
+			map[string]interface{}{"subject": "bob"}, false},
+		{nil, map[string]interface{}{"subject": "bob"}, map[string]interface{}{
+			"subject": map[string]interface{}{"department": "finance", "clearance": 3.0}}, false},
+		{&assertion.AttributesConfig{AttributesPrecedence: "reject"},
+			map[string]interface{}{"subject": map[string]interface{}{"site": "BJ"}}, map[string]interface{}{
+				"subject": map[string]interface{}{"department": "finance", "clearance": 3.0, "site": "BJ"}}, false},
+		{&assertion.AttributesConfig{AttributesPrecedence: "reject"},
+			map[string]interface{}{"subject": map[string]interface{}{"department": "hr"}}, nil, true},
+		{&assertion.AttributesConfig{AttributesPrecedence: "reject"}, map[string]interface{}{"subject": "bob"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		f, err := NewAsserterFunc(fakeAsserter{}, tc.conf)
+		if err != nil {
+			t.Fatalf("failed to create asserter function: %v", err)
+		}
+		ctx := &adsapi.RequestContext{
+			Subject:    &adsapi.Subject{TokenType: "fake", Token: "token"},
+			Attributes: tc.attributes,
+		}
+		err = f(ctx)
+		if tc.err {
+			if errors.Code(err) != errors.InvalidRequest {
+				t.Errorf("attributes %v: expected invalid request, got %v", tc.attributes, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ctx.Attributes, tc.want) {
+			t.Errorf("attributes %v: expected %v, got %v, %v", tc.attributes, tc.want, ctx.Attributes, err)
+		}
+		if len(ctx.Subject.Principals) != 2 {
+			t.Errorf("unexpected principals %v", ctx.Subject.Principals)
+		}
+	}
+
+	if _, err := NewAsserterFunc(fakeAsserter{}, &assertion.AttributesConfig{AttributesPrecedence: "caller"}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of unknown precedence, got %v", err)
+	}
+}
+
+func TestNoAssertedAttributes(t *testing.T) {
+	testCases := []struct {
+		precedence string
+		attributes map[string]interface{}
+		want       map[string]interface{}
+		err        bool
+	}{
+		{assertion.AssertedAttributesFirst, map[string]interface{}{"env": "prod"}, map[string]interface{}{"env": "prod"}, false},
+		{assertion.AssertedAttributesFirst, map[string]interface{}{"subject": "bob"}, map[string]interface{}{
+			"subject": map[string]interface{}{}}, false},
+		{assertion.RequestAttributesFirst, map[string]interface{}{"subject": "bob"}, map[string]interface{}{"subject": "bob"}, false},
+		{assertion.RejectConflictAttributes, map[string]interface{}{"subject": "bob"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		ctx := &adsapi.RequestContext{Attributes: tc.attributes}
+		err := mergeAssertedAttributes(ctx, nil, assertion.DefaultAttributesNamespace, tc.precedence)
+		if tc.err {
+			if errors.Code(err) != errors.InvalidRequest {
+				t.Errorf("%s, attributes %v: expected invalid request, got %v", tc.precedence, tc.attributes, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ctx.Attributes, tc.want) {
+			t.Errorf("%s, attributes %v: expected %v, got %v, %v", tc.precedence, tc.attributes, tc.want, ctx.Attributes, err)
+		}
+	}
+}
+
+func TestAssertedAttributesInConditions(t *testing.T) {
+	stream := `{"services": [{"name": "crm", "policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/reports","actions": ["get"]}], "principals": [["group:finance"]], "condition": "subject.department == 'finance' && subject.clearance >= 3"}]}]}`
+	preparePolicyDataInStore([]byte(stream), t)
+	eval, err := NewWithStore(conf, testPS)
+	if err != nil {
+		t.Fatalf("error creating evaluator : %v", err)
+	}
+	f, err := NewAsserterFunc(fakeAsserter{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create asserter function: %v", err)
+	}
+	eval.SetAsserterFunc(f)
+
+	attributes := map[string]interface{}{"subject": map[string]interface{}{"department": "hr"}}
+	ctx := adsapi.RequestContext{
+		Subject:     &adsapi.Subject{TokenType: "fake", Token: "token"},
+		ServiceName: "crm",
+		Resource:    "/reports",
+		Action:      "get",
+		Attributes:  attributes,
+	}
+	allowed, _, err := eval.IsAllowed(ctx)
+	if err != nil || !allowed {
+		t.Errorf("expected allowed by the asserted attributes, got %v, %v", allowed, err)
+	}
+	if attributes["subject"].(map[string]interface{})["department"] != "hr" {
+		t.Errorf("the attributes of callers should not be modified, got %v", attributes)
+	}
+}
+
+func TestAsserterFromConfig(t *testing.T) {
+	stream := `{"services": [{"name": "crm", "policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/reports","actions": ["get"]}], "principals": [["group:finance"]], "condition": "claims.department == 'finance'"}]}]}`
+	preparePolicyDataInStore([]byte(stream), t)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, _ := fakeAsserter{}.AssertToken(r.Header.Get(assertion.TokenKey), "", "", nil)
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer webhook.Close()
+
+	// Embedded evaluators assert tokens with the asserter of the configuration, like ADS does
+	asserterConf := *conf
+	asserterConf.AsserterWebhookConfig = &assertion.AsserterConfig{Endpoint: webhook.URL,
+		AttributesConfig: assertion.AttributesConfig{AttributesNamespace: "claims"}}
+	eval, err := NewWithStore(&asserterConf, testPS)
+	if err != nil {
+		t.Fatalf("error creating evaluator : %v", err)
+	}
+	ctx := adsapi.RequestContext{
+		Subject:     &adsapi.Subject{TokenType: "fake", Token: "token"},
+		ServiceName: "crm",
+		Resource:    "/reports",
+		Action:      "get",
+	}
+	allowed, _, err := eval.IsAllowed(ctx)
+	if err != nil || !allowed {
+		t.Errorf("expected allowed by the asserted principals and attributes, got %v, %v", allowed, err)
+	}
+
+	asserterConf.AsserterType = "unknown"
+	if _, err := NewWithStore(&asserterConf, testPS); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of unknown asserter type, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/oracle/speedle/3rdparty/github.com/Knetic/govaluate"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// attributeParameters are the attributes of a request accessed by conditions. Object attributes are nested maps,
+// the values in them are accessed by dot paths like resource.labels.env.
+type attributeParameters struct {
+	attributes map[string]interface{}
+	// typeCheck fails accessing the keys of non-objects, they are null otherwise. Missing keys of objects always
+	// fail like missing attributes.
+	typeCheck bool
+}
+
+func (p attributeParameters) Get(name string) (interface{}, error) {
+	return govaluate.MapParameters(p.attributes).Get(name)
+}
+
+// GetPath returns the value at path in the attributes, e.g. the value of resource.labels.env is at path
+// ["resource", "labels", "env"]
+func (p attributeParameters) GetPath(path []string) (interface{}, error) {
+	value, err := p.Get(path[0])
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(path); i++ {
+		var isObject, found bool
+		parent := value
+		value, isObject, found = objectValue(parent, path[i])
+		if found {
+			continue
+		}
+		parentPath := strings.Join(path[:i], ".")
+		if isObject {
+			return nil, errors.Errorf(errors.EvalEngineError, "attribute %q is not found in object %q", path[i], parentPath)
+		}
+		if !p.typeCheck {
+			return nil, nil
+		}
+		return nil, errors.Errorf(errors.EvalEngineError, "unable to access %q, %q is %s instead of an object",
+			strings.Join(path[:i+1], "."), parentPath, typeName(parent))
+	}
+	return arrayValue(value), nil
+}
+
+// objectValue returns the value of key in object, isObject is false if object is not a map with string keys
+func objectValue(object interface{}, key string) (value interface{}, isObject bool, found bool) {
+	if m, ok := object.(map[string]interface{}); ok {
+		value, found = m[key]
+		return value, true, found
+	}
+	rv := reflect.ValueOf(object)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false, false
+	}
+	elem := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !elem.IsValid() {
+		return nil, true, false
+	}
+	return elem.Interface(), true, true
+}
+
+// arrayValue converts arrays to []interface{} like the top level attributes, so that they can be passed to the
+// functions like IsSubSet
+func arrayValue(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return value
+	}
+	ret := make([]interface{}, rv.Len())
+	for i := range ret {
+		ret[i] = rv.Index(i).Interface()
+	}
+	return ret
+}
+
+func typeName(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return reflect.TypeOf(value).String()
+}
@@ -5,15 +5,15 @@ package eval
 
 import (
 	"fmt"
-	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oracle/speedle/3rdparty/github.com/Knetic/govaluate"
 	adsapi "github.com/oracle/speedle/api/ads"
 	"github.com/oracle/speedle/pkg/errors"
 	"github.com/oracle/speedle/pkg/eval/function"
+	"github.com/oracle/speedle/pkg/store"
 	"github.com/oracle/speedle/pkg/subjectutils"
 
 	"github.com/oracle/speedle/api/pms"
@@ -28,6 +28,26 @@ var builtinFunctions = map[string]govaluate.ExpressionFunction{
 	"Sum":      function.Sum,
 	"Avg":      function.Avg,
 	"IsSubSet": function.IsSubSet,
+
+	"StartsWith": function.StartsWith,
+	"EndsWith":   function.EndsWith,
+	"Contains":   function.Contains,
+	"Lower":      function.Lower,
+	"Matches":    function.Matches,
+
+	"In":         function.In,
+	"Intersects": function.Intersects,
+	"Count":      function.Count,
+
+	"CIDRMatch": function.CIDRMatch,
+
+	"Now":         function.Now,
+	"ParseTime":   function.ParseTime,
+	"TimeBetween": function.TimeBetween,
+	"DayOfWeek":   function.DayOfWeek,
+
+	"Semver":   function.Semver,
+	"JSONPath": function.JSONPath,
 }
 
 type TokenAsserter interface {
@@ -37,9 +57,20 @@ type TokenAsserter interface {
 	AssertToken(ctx *adsapi.RequestContext) error
 }
 
+type FunctionDelegator interface {
+	// SetFuncSvcEndpoint sets the endpoint of customer function delegator, customer functions are called directly if it is empty
+	SetFuncSvcEndpoint(endpoint string)
+}
+
+// FunctionResultCacheReporter is implemented by the evaluators caching customer function results
+type FunctionResultCacheReporter interface {
+	FunctionResultCacheStats() FunctionResultCacheStats
+}
+
 type InternalEvaluator interface {
 	adsapi.PolicyEvaluator
 	TokenAsserter
+	FunctionDelegator
 }
 
 type internalRequestContext struct {
@@ -49,6 +80,13 @@ type internalRequestContext struct {
 	Resource      string
 	Action        string
 	Attributes    map[string]interface{}
+	// TypeCheck fails the conditions accessing non-object paths of object attributes
+	TypeCheck bool
+}
+
+// parameters returns the attributes accessed by conditions
+func (ctx *internalRequestContext) parameters() govaluate.Parameters {
+	return attributeParameters{attributes: ctx.Attributes, typeCheck: ctx.TypeCheck}
 }
 
 type subject struct {
@@ -64,6 +102,14 @@ type PolicyEvalImpl struct {
 	RuntimePolicyStore *RuntimePolicyStore //This is runtime policy store
 	Store              pms.PolicyStoreManagerADS
 	AsserterFunc       func(ctx *adsapi.RequestContext) error
+	asserterLock       sync.RWMutex
+	healthLock         sync.RWMutex
+	// reloadErr is the error of the last reload, the runtime cache loaded before is kept on failure
+	reloadErr error
+	// typeCheckConditions fails the conditions accessing non-object paths of object attributes
+	typeCheckConditions bool
+	// requestTimeOverride allows callers to override the request time attributes
+	requestTimeOverride bool
 }
 
 func (p *PolicyEvalImpl) deleteService(serviceName string) {
@@ -80,6 +126,40 @@ func (p *PolicyEvalImpl) fullReloadRuntimeCache() {
 	p.RuntimePolicyStore.reloadPolicyStore(ps)
 }
 
+// reloadRuntimeCache reads the whole policy store, and updates the runtime cache incrementally
+func (p *PolicyEvalImpl) reloadRuntimeCache() {
+	ps, err := p.Store.ReadPolicyStore()
+	if err == nil {
+		err = p.syncRuntimeCache(ps)
+	}
+	if err != nil {
+		log.Errorf("Fail to reload runtime cache, err:%v", err)
+	}
+	p.healthLock.Lock()
+	p.reloadErr = err
+	p.healthLock.Unlock()
+}
+
+// Health returns the problem of the policies being evaluated, it is nil if they are up to date with the policy store
+func (p *PolicyEvalImpl) Health() error {
+	if reporter, ok := p.Store.(store.HealthReporter); ok {
+		if err := reporter.Health(); err != nil {
+			return err
+		}
+	}
+	p.healthLock.RLock()
+	defer p.healthLock.RUnlock()
+	return p.reloadErr
+}
+
+// Revision returns the revision of the policies being evaluated, if the policy store tells it
+func (p *PolicyEvalImpl) Revision() (int64, bool) {
+	if reporter, ok := p.Store.(store.RevisionReporter); ok {
+		return reporter.Revision()
+	}
+	return 0, false
+}
+
 func (p *PolicyEvalImpl) Refresh() error {
 	p.fullReloadRuntimeCache()
 	return nil
@@ -116,17 +196,34 @@ func (p *PolicyEvalImpl) CleanExpiredFunctionResult() {
 	p.RuntimePolicyStore.expireFunctionResultCache()
 }
 
+// FunctionResultCacheStats returns the statistics of the cache of customer function results
+func (p *PolicyEvalImpl) FunctionResultCacheStats() FunctionResultCacheStats {
+	p.RuntimePolicyStore.RLock()
+	defer p.RuntimePolicyStore.RUnlock()
+	return p.RuntimePolicyStore.FunctionResultCache.Stats()
+}
+
 func (p *PolicyEvalImpl) SetAsserterFunc(f func(ctx *adsapi.RequestContext) error) {
+	p.asserterLock.Lock()
+	defer p.asserterLock.Unlock()
 	p.AsserterFunc = f
 }
 
+func (p *PolicyEvalImpl) SetFuncSvcEndpoint(endpoint string) {
+	p.RuntimePolicyStore.setFuncSvcEndpoint(endpoint)
+}
+
 func (p *PolicyEvalImpl) AssertToken(ctx *adsapi.RequestContext) error {
+	p.asserterLock.RLock()
+	asserterFunc := p.AsserterFunc
+	p.asserterLock.RUnlock()
+
 	// Assert identity token
 	if ctx.Subject != nil &&
-		p.AsserterFunc != nil &&
+		asserterFunc != nil &&
 		len(ctx.Subject.TokenType) != 0 &&
 		len(ctx.Subject.Token) != 0 && !ctx.Subject.Asserted {
-		err := p.AsserterFunc(ctx)
+		err := asserterFunc(ctx)
 		if err == nil {
 			ctx.Subject.Asserted = true
 		}
@@ -159,16 +256,15 @@ func (p *PolicyEvalImpl) populateContext(ctx *adsapi.RequestContext) (*internalR
 		Service:       service,
 		GlobalService: globalService,
 		Attributes:    make(map[string]interface{}),
+		TypeCheck:     p.typeCheckConditions,
 	}
 
-	now := time.Now()
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestTime] = now.Unix()
-	year, month, day := now.Date()
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestYear] = year
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestMonth] = int(month)
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestDay] = day
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestWeekday] = now.Weekday().String()
-	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestHour] = now.Hour()
+	service.RLock()
+	loc := service.Location
+	service.RUnlock()
+	if err := setRequestTimeAttributes(newCtx.Attributes, ctx.Attributes, loc, p.requestTimeOverride); err != nil {
+		return nil, err
+	}
 
 	newCtx.Subject = &subject{
 		Users:    []string{},
@@ -226,6 +322,10 @@ func (p *PolicyEvalImpl) populateContext(ctx *adsapi.RequestContext) (*internalR
 	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestResource] = ctx.Resource
 	newCtx.Attributes[adsapi.BuiltIn_Attr_RequestAction] = ctx.Action
 	for key, value := range ctx.Attributes {
+		if key == adsapi.BuiltIn_Attr_RequestTime || key == adsapi.BuiltIn_Attr_RequestTimezone {
+			// They are normalized by setRequestTimeAttributes
+			continue
+		}
 		newCtx.Attributes[key] = value
 	}
 
@@ -234,6 +334,58 @@ func (p *PolicyEvalImpl) populateContext(ctx *adsapi.RequestContext) (*internalR
 	return &newCtx, nil
 }
 
+// setRequestTimeAttributes sets the built-in request time attributes. The time of the request is the current time,
+// unless allowOverride is set and request_time is passed in the request attributes for testing or replay, e.g. as
+// seconds since the Unix epoch or an RFC 3339 string. The time zone is the one passed in request_timezone if
+// allowOverride is set, or loc of the service, or the local time zone of the server.
+func setRequestTimeAttributes(attributes, requestAttributes map[string]interface{}, loc *time.Location, allowOverride bool) error {
+	if !allowOverride {
+		for _, name := range []string{adsapi.BuiltIn_Attr_RequestTime, adsapi.BuiltIn_Attr_RequestTimezone} {
+			if _, ok := requestAttributes[name]; ok {
+				return errors.Errorf(errors.InvalidRequest, "%s can't be passed in request attributes, overriding the request time is not allowed", name)
+			}
+		}
+	}
+	now := time.Now()
+	if value, ok := requestAttributes[adsapi.BuiltIn_Attr_RequestTime]; ok {
+		t, ok := function.TimeValue(value)
+		if !ok {
+			return errors.Errorf(errors.InvalidRequest, "%s %v is not a valid date time", adsapi.BuiltIn_Attr_RequestTime, value)
+		}
+		now = t
+	}
+	if value, ok := requestAttributes[adsapi.BuiltIn_Attr_RequestTimezone]; ok {
+		name, _ := value.(string)
+		var err error
+		if loc, err = function.LoadLocation(name); err != nil || len(name) == 0 {
+			return errors.Errorf(errors.InvalidRequest, "%s %v is not a valid time zone", adsapi.BuiltIn_Attr_RequestTimezone, value)
+		}
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	now = now.In(loc)
+
+	attributes[adsapi.BuiltIn_Attr_RequestTime] = now.Unix()
+	year, month, day := now.Date()
+	attributes[adsapi.BuiltIn_Attr_RequestYear] = year
+	attributes[adsapi.BuiltIn_Attr_RequestMonth] = int(month)
+	attributes[adsapi.BuiltIn_Attr_RequestDay] = day
+	attributes[adsapi.BuiltIn_Attr_RequestWeekday] = now.Weekday().String()
+	attributes[adsapi.BuiltIn_Attr_RequestHour] = now.Hour()
+	attributes[adsapi.BuiltIn_Attr_RequestMinute] = now.Minute()
+	// Date literals like '2019-03-04' in conditions are the midnights in the local time zone of the server, so is
+	// the date of the request, in order to compare them
+	attributes[adsapi.BuiltIn_Attr_RequestDate] = time.Date(year, month, day, 0, 0, 0, 0, time.Local).Unix()
+	attributes[adsapi.BuiltIn_Attr_RequestTimestamp] = now.Format(time.RFC3339)
+	zone := loc.String()
+	if loc == time.Local {
+		zone, _ = now.Zone()
+	}
+	attributes[adsapi.BuiltIn_Attr_RequestTimezone] = zone
+	return nil
+}
+
 func (p *PolicyEvalImpl) IsAllowed(ctx adsapi.RequestContext) (bool, adsapi.Reason, error) {
 	//IsAllowed don't need return EvaluationResult, so pass nil
 	return p.InternalIsAllowed(&ctx, nil)
@@ -390,12 +542,12 @@ func (p *PolicyEvalImpl) getDirectRolePolices(principals []string,
 
 	grantedRolePolicies := make([]*pms.RolePolicy, 0)
 	deniedRolePolicies := make([]*pms.RolePolicy, 0)
-	grantedRolePolicies, deniedRolePolicies, err := p.getDirectRolePolicesInService(principals, ctx.Service, ctx.Resource, ctx.Attributes, policyIDMap, evaluationResult, grantedRolePolicies, deniedRolePolicies)
+	grantedRolePolicies, deniedRolePolicies, err := p.getDirectRolePolicesInService(principals, ctx.Service, ctx.Resource, ctx.parameters(), policyIDMap, evaluationResult, grantedRolePolicies, deniedRolePolicies)
 	if err != nil {
 		return nil, nil, err
 	}
 	if ctx.GlobalService != nil {
-		grantedRolePolicies, deniedRolePolicies, err = p.getDirectRolePolicesInService(principals, ctx.GlobalService, ctx.Resource, ctx.Attributes, policyIDMap, evaluationResult, grantedRolePolicies, deniedRolePolicies)
+		grantedRolePolicies, deniedRolePolicies, err = p.getDirectRolePolicesInService(principals, ctx.GlobalService, ctx.Resource, ctx.parameters(), policyIDMap, evaluationResult, grantedRolePolicies, deniedRolePolicies)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -404,7 +556,7 @@ func (p *PolicyEvalImpl) getDirectRolePolices(principals []string,
 }
 
 func (p *PolicyEvalImpl) getDirectRolePolicesInService(principals []string,
-	service *RuntimeService, resource string, attributes map[string]interface{}, policyIDMap map[string]bool, evaluationResult *adsapi.EvaluationResult, grantedRolePolicies []*pms.RolePolicy, deniedRolePolicies []*pms.RolePolicy) ([]*pms.RolePolicy, []*pms.RolePolicy, error) {
+	service *RuntimeService, resource string, parameters govaluate.Parameters, policyIDMap map[string]bool, evaluationResult *adsapi.EvaluationResult, grantedRolePolicies []*pms.RolePolicy, deniedRolePolicies []*pms.RolePolicy) ([]*pms.RolePolicy, []*pms.RolePolicy, error) {
 	for _, policy := range service.GetRelatedRolePolicyMap(principals, resource) {
 
 		if policyIDMap[policy.ID] {
@@ -425,7 +577,7 @@ func (p *PolicyEvalImpl) getDirectRolePolicesInService(principals []string,
 				}
 			}
 			if condition != nil {
-				result, _ = evaluateCondition(condition, attributes)
+				result, _ = evaluateCondition(condition, parameters)
 			}
 
 			if evaluationResult != nil {
@@ -884,7 +1036,7 @@ func (p *PolicyEvalImpl) getPolicyList(ctx *internalRequestContext, matchResourc
 					}
 				}
 				if condition != nil {
-					result, _ = evaluateCondition(condition, ctx.Attributes)
+					result, _ = evaluateCondition(condition, ctx.parameters())
 				}
 
 				if result {
@@ -908,176 +1060,18 @@ func (p *PolicyEvalImpl) getPolicyList(ctx *internalRequestContext, matchResourc
 	return grantedPolicyList, deniedPolicyList, nil
 }
 
-//dataSet should be this:
-// {
-//   [] string, //service name slice
-//   map[int]string, //map of policy ID to it's serviceName
-//   map[int]string, //map of rolePolicy ID to it's serviceName
-//   [] string, //custom function slice
-// }
-func (p *PolicyEvalImpl) syncRuntimeCache(dataSet []interface{}) error {
-	log.Info("start to sync runtime cache data.")
-	if len(dataSet) != 4 {
-		return errors.New(errors.EvalCacheError, "invalid data set for cache reloading")
-	}
-	policyStore := p.Store
-	log.Info("start to sync service data.")
-	if serviceArr, ok := dataSet[0].([]string); ok {
-		for _, serviceInDB := range serviceArr { //add new services
-			if p.RuntimePolicyStore.RuntimeServices[serviceInDB] == nil {
-				service, err := policyStore.GetService(serviceInDB)
-				if err != nil {
-					return err
-				}
-				p.AddServiceInRuntimeCache(service)
-			}
-		}
-
-		for serviceInCache := range p.RuntimePolicyStore.RuntimeServices {
-			isInDB := false
-			for _, serviceInDB := range serviceArr {
-				if serviceInDB == serviceInCache {
-					isInDB = true
-					break
-				}
-			}
-			if !isInDB {
-				p.deleteService(serviceInCache)
-			}
-		}
-	} else {
-		return errors.New(errors.EvalCacheError, "invalid service data set for cache reloading")
+// syncRuntimeCache updates the runtime cache to the policies in ps incrementally, only the services, policies
+// and role policies which are added, removed or changed are updated
+func (p *PolicyEvalImpl) syncRuntimeCache(ps *pms.PolicyStore) error {
+	if ps == nil {
+		return errors.New(errors.EvalCacheError, "invalid policy store for cache reloading")
 	}
-
-	log.Info("start to sync policy data.")
-	if policySetInDB, ok := dataSet[1].(map[int]string); ok {
-		idInDB := getSortedIndexOfMap(policySetInDB)
-		policySetInCache, idInCache, err := p.getPolicySetInCache()
-		if err != nil {
-			return err
-		}
-		missed, removed := difPolicySets(idInCache, idInDB)
-		for _, id := range missed {
-			serviceName := policySetInDB[id]
-			policy, err := policyStore.GetPolicy(serviceName, strconv.Itoa(id))
-			if err != nil {
-				return err
-			} else {
-				p.AddPolicyInRuntimeCache(serviceName, policy)
-			}
-		}
-		for _, id := range removed {
-			serviceName := policySetInCache[id]
-			p.DeletePolicyInRuntimeCache(serviceName, strconv.Itoa(id))
-		}
-	} else {
-		return errors.New(errors.EvalCacheError, "invalid policy data set for cache reloading")
-	}
-
-	log.Info("start to sync rolePolicy data.")
-	if rolePolicySetInDB, ok := dataSet[2].(map[int]string); ok {
-		idInDB := getSortedIndexOfMap(rolePolicySetInDB)
-		rolePolicySetInCache, idInCache, err := p.getRolePolicySetInCache()
-		if err != nil {
-			return err
-		}
-		missed, removed := difPolicySets(idInCache, idInDB)
-		for _, id := range missed {
-			serviceName := rolePolicySetInDB[id]
-			policy, err := policyStore.GetRolePolicy(serviceName, strconv.Itoa(id))
-			if err != nil {
-				return err
-			} else {
-				p.AddRolePolicyInRuntimeCache(serviceName, policy)
-			}
-		}
-		for _, id := range removed {
-			serviceName := rolePolicySetInCache[id]
-			p.DeleteRolePolicyInRuntimeCache(serviceName, strconv.Itoa(id))
-		}
-	} else {
-		return errors.New(errors.EvalCacheError, "invalid rolePolicy data set for cache reloading")
-	}
-
-	log.Info("start to sync custom functions.")
-	if CustFuncSetInDB, ok := dataSet[3].([]string); ok {
-		custFuncSetInCache, err := p.getCustFunctionSetInCache()
-		if err != nil {
-			return err
-		}
-		missed, removed := difFuncSets(custFuncSetInCache, CustFuncSetInDB)
-		for _, name := range missed {
-			function, err := policyStore.GetFunction(name)
-			if err != nil {
-				return err
-			} else {
-				p.AddFunctionInRuntimeCache(function)
-			}
-		}
-		for _, name := range removed {
-			p.DeleteFunctionInRuntimeCache(name)
-		}
-	} else {
-		return errors.New(errors.EvalCacheError, "invalid custom function data set for cache reloading")
-	}
-
+	log.Info("start to sync runtime cache data.")
+	p.RuntimePolicyStore.syncPolicyStore(ps)
 	log.Info("finished sync of runtime cache.")
 	return nil
 }
 
-func getSortedIndexOfMap(m map[int]string) []int {
-	var idx []int
-	for i := range m {
-		idx = append(idx, i)
-	}
-	sort.Ints(idx)
-	return idx
-}
-
-func (p *PolicyEvalImpl) getPolicySetInCache() (map[int]string, []int, error) {
-	resultSet := make(map[int]string)
-	var idx []int
-	for _, rService := range p.RuntimePolicyStore.RuntimeServices {
-		for pId := range rService.PoliciesCache.PolicyMap {
-			id, err := strconv.Atoi(pId)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, errors.EvalCacheError, "unable to convert policy ID %q", pId)
-			}
-			idx = append(idx, id)
-			resultSet[id] = rService.Name
-		}
-	}
-	sort.Ints(idx)
-	return resultSet, idx, nil
-}
-
-func (p *PolicyEvalImpl) getRolePolicySetInCache() (map[int]string, []int, error) {
-	resultSet := make(map[int]string)
-	var idx []int
-	for _, rService := range p.RuntimePolicyStore.RuntimeServices {
-		for pId := range rService.RolePoliciesCache.PolicyMap {
-			id, err := strconv.Atoi(pId)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, errors.EvalCacheError, "unable to convert policy ID %q", pId)
-			}
-			idx = append(idx, id)
-			resultSet[id] = rService.Name
-		}
-	}
-	sort.Ints(idx)
-	return resultSet, idx, nil
-}
-
-func (p *PolicyEvalImpl) getCustFunctionSetInCache() ([]string, error) {
-	resultSet := []string{}
-	for funcName := range p.RuntimePolicyStore.Functions {
-		if _, ok := builtinFunctions[funcName]; !ok {
-			resultSet = append(resultSet, funcName)
-		}
-	}
-	return resultSet, nil
-}
-
 func (p *PolicyEvalImpl) updateRuntimeCacheWithStoreChange(updateChan pms.StorageChangeChannel) {
 	for e := range updateChan {
 		switch e.Type {
@@ -1113,9 +1107,9 @@ func (p *PolicyEvalImpl) updateRuntimeCacheWithStoreChange(updateChan pms.Storag
 				rolePolicy := s.Data.(*pms.RolePolicy)
 				p.DeleteRolePolicyInRuntimeCache(s.ServiceName, rolePolicy.ID)
 			}
-		case pms.SYNC_RELOAD:
-			data := e.Content.([]interface{})
-			err := p.syncRuntimeCache(data)
+		case pms.SYNC_RELOAD: //Event content: *pms.PolicyStore
+			ps, _ := e.Content.(*pms.PolicyStore)
+			err := p.syncRuntimeCache(ps)
 			if err != nil {
 				log.Error("failed to reload cache data. ", err)
 			}
@@ -1128,7 +1122,7 @@ func (p *PolicyEvalImpl) updateRuntimeCacheWithStoreChange(updateChan pms.Storag
 				p.DeleteFunctionInRuntimeCache(f)
 			}
 		case pms.FULL_RELOAD:
-			p.fullReloadRuntimeCache()
+			p.reloadRuntimeCache()
 		}
 	}
 }
@@ -1150,60 +1144,3 @@ func (p *PolicyEvalImpl) cleanExpiredFunctionResultPeriodically() {
 func (p *PolicyEvalImpl) StopWatch() {
 	p.Store.StopWatch()
 }
-
-func difPolicySets(oldSet, newSet []int) (missed, removed []int) {
-	var missedData, removedData []int
-	i := 0
-	j := 0
-	for i <= len(oldSet) && j <= len(newSet) {
-
-		if i == len(oldSet) { //add all data in newSet to missed
-			missedData = append(missedData, newSet[j:]...)
-			break
-		}
-		if j == len(newSet) { //add all data in oldSet to removed
-			removedData = append(removedData, oldSet[i:]...)
-			break
-		}
-
-		if oldSet[i] == newSet[j] {
-			i++
-			j++
-		} else {
-			removedData = append(removedData, oldSet[i])
-			i++
-		}
-	}
-	return missedData, removedData
-}
-
-func difFuncSets(oldSet, newSet []string) (missed, removed []string) {
-	if len(oldSet) == 0 {
-		return newSet, nil
-	}
-	if len(newSet) == 0 {
-		return nil, oldSet
-	}
-	missed = []string{}
-	removed = []string{}
-	newMap := make(map[string]bool)
-	for _, v := range newSet {
-		newMap[v] = true
-	}
-	oldMap := make(map[string]bool)
-	for _, v := range newSet {
-		oldMap[v] = true
-	}
-	for _, v := range newSet {
-		if !oldMap[v] {
-			missed = append(missed, v)
-		}
-	}
-	for _, v := range oldSet {
-		if !newMap[v] {
-			removed = append(removed, v)
-		}
-	}
-	return missed, removed
-
-}
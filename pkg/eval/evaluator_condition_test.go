@@ -9,6 +9,8 @@ import (
 	"time"
 
 	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
 )
 
 func TestConditions(t *testing.T) {
@@ -718,3 +720,115 @@ func TestComplexConditionsPostive(t *testing.T) {
 	}
 
 }
+
+func TestRequestTimeAttributes(t *testing.T) {
+	// 2019-03-04T23:30:00Z is Tuesday 07:30 in Shanghai, and Monday 18:30 in New York
+	requestTime := "2019-03-04T23:30:00Z"
+	testCases := []struct {
+		condition  string
+		attributes map[string]interface{}
+		want       bool
+		err        bool
+	}{
+		{"request_hour == 7 && request_minute == 30 && request_weekday == 'Tuesday'",
+			map[string]interface{}{"request_time": requestTime}, true, false},
+		{"request_date == '2019-03-05' && request_year == 2019 && request_month == 3 && request_day == 5",
+			map[string]interface{}{"request_time": requestTime}, true, false},
+		{"Contains(request_timestamp, 'T07:30:00+08:00') && request_timezone == 'Asia/Shanghai'",
+			map[string]interface{}{"request_time": requestTime}, true, false},
+		{"request_time == 1551742200", map[string]interface{}{"request_time": float64(1551742200)}, true, false},
+		{"request_hour == 18 && request_weekday == 'Monday' && request_timezone == 'America/New_York'",
+			map[string]interface{}{"request_time": requestTime, "request_timezone": "America/New_York"}, true, false},
+		{"request_hour >= 0", map[string]interface{}{"request_timezone": "Mars/Olympus"}, false, true},
+		{"request_hour >= 0", map[string]interface{}{"request_time": "yesterday"}, false, true},
+		{"request_hour >= 0", nil, true, false},
+	}
+	overrideConf := *conf
+	overrideConf.AllowRequestTimeOverride = true
+
+	for _, tc := range testCases {
+		stream := fmt.Sprintf(`{"services": [{"name": "crm", "metadata": {"timezone": "Asia/Shanghai"}, "policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/node1","actions": ["get"]}],"condition": %q}]}]}`, tc.condition)
+		preparePolicyDataInStore([]byte(stream), t)
+		eval, err := NewWithStore(&overrideConf, testPS)
+		if err != nil {
+			t.Errorf("error creating evaluator : %v", err)
+			continue
+		}
+		ctx := adsapi.RequestContext{ServiceName: "crm", Resource: "/node1", Action: "get", Attributes: tc.attributes}
+		got, _, err := eval.IsAllowed(ctx)
+		if (err != nil) != tc.err {
+			t.Errorf("condition: %s, context: %v, unexpected error: %v", tc.condition, tc.attributes, err)
+		}
+		if got != tc.want {
+			t.Errorf("condition: %s, context: %v, got %v, want %v", tc.condition, tc.attributes, got, tc.want)
+		}
+	}
+}
+
+func TestRequestTimeOverrideNotAllowed(t *testing.T) {
+	stream := `{"services": [{"name": "crm", "policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/node1","actions": ["get"]}],"condition": "request_hour >= 0"}]}]}`
+	preparePolicyDataInStore([]byte(stream), t)
+	eval, err := NewWithStore(conf, testPS)
+	if err != nil {
+		t.Fatalf("error creating evaluator : %v", err)
+	}
+	for _, attributes := range []map[string]interface{}{
+		{"request_time": "2019-03-04T23:30:00Z"},
+		{"request_timezone": "America/New_York"},
+	} {
+		ctx := adsapi.RequestContext{ServiceName: "crm", Resource: "/node1", Action: "get", Attributes: attributes}
+		if got, _, err := eval.IsAllowed(ctx); got || errors.Code(err) != errors.InvalidRequest {
+			t.Errorf("context: %v, expected the override to be rejected, got %v, err: %v", attributes, got, err)
+		}
+	}
+}
+
+func TestObjectAttributes(t *testing.T) {
+	attributes := map[string]interface{}{
+		"resource": map[string]interface{}{
+			"labels": map[string]interface{}{"env": "prod"},
+			"owner":  map[string]string{"name": "alice"},
+			"size":   3,
+			"tags":   []string{"finance", "audit"},
+		},
+	}
+	typeCheckConf := *conf
+	typeCheckConf.TypeCheckConditions = true
+
+	testCases := []struct {
+		condition string
+		want      bool
+		// wantTypeCheck is the result in type checking mode
+		wantTypeCheck bool
+	}{
+		{"resource.labels.env == 'prod'", true, true},
+		{"resource.owner.name == 'alice' && resource.size > 2", true, true},
+		{"'audit' in resource.tags && IsSubSet(resource.tags, ('finance', 'audit', 'hr'))", true, true},
+		// missing keys fail like missing attributes, so that negated conditions don't match either
+		{"resource.labels.team != 'hr'", false, false},
+		{"!(resource.labels.team == 'hr')", false, false},
+		{"!(resource.parent.labels.env == 'prod')", false, false},
+		{"!(resource.labels.env.name == 'prod')", true, false},
+		{"!(principal.labels.env == 'prod')", false, false},
+	}
+
+	for _, tc := range testCases {
+		stream := fmt.Sprintf(`{"services": [{"name": "crm", "policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/node1","actions": ["get"]}],"condition": %q}]}]}`, tc.condition)
+		preparePolicyDataInStore([]byte(stream), t)
+		for _, c := range []struct {
+			conf *cfg.Config
+			want bool
+		}{{conf, tc.want}, {&typeCheckConf, tc.wantTypeCheck}} {
+			eval, err := NewWithStore(c.conf, testPS)
+			if err != nil {
+				t.Errorf("error creating evaluator : %v", err)
+				continue
+			}
+			ctx := adsapi.RequestContext{ServiceName: "crm", Resource: "/node1", Action: "get", Attributes: attributes}
+			got, _, err := eval.IsAllowed(ctx)
+			if err != nil || got != c.want {
+				t.Errorf("condition: %s, type check: %v, got %v, %v, want %v", tc.condition, c.conf.TypeCheckConditions, got, err, c.want)
+			}
+		}
+	}
+}
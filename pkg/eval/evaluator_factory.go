@@ -48,8 +48,13 @@ func NewFromConfig(conf *cfg.Config) (InternalEvaluator, error) {
 	return NewWithStore(conf, s)
 }
 
-// NewWithStore creates a policy evaluator with policy store
+// NewWithStore creates a policy evaluator with policy store, the tokens of requests are asserted by the asserter
+// of conf if it's configured
 func NewWithStore(conf *cfg.Config, s pms.PolicyStoreManagerADS) (InternalEvaluator, error) {
+	asserterFunc, _, err := NewAsserterFuncFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
 	ps, err := s.ReadPolicyStore()
 	if err != nil {
 		return nil, err
@@ -64,12 +69,20 @@ func NewWithStore(conf *cfg.Config, s pms.PolicyStoreManagerADS) (InternalEvalua
 		}
 	}
 
+	resultCache, err := NewFuncResultCache(conf.FunctionResultCacheConfig)
+	if err != nil {
+		return nil, err
+	}
 	runtimePolicyStore := NewRuntimePolicyStore()
+	runtimePolicyStore.FunctionResultCache = resultCache
 	runtimePolicyStore.init(ps, conf.FuncsvcEndpoint)
 
 	p := &PolicyEvalImpl{
-		RuntimePolicyStore: runtimePolicyStore,
-		Store:              s,
+		RuntimePolicyStore:  runtimePolicyStore,
+		Store:               s,
+		typeCheckConditions: conf.TypeCheckConditions,
+		requestTimeOverride: conf.AllowRequestTimeOverride,
+		AsserterFunc:        asserterFunc,
 	}
 
 	// start a goroutine watching to the channel for update events and
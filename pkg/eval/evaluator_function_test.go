@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	adsapi "github.com/oracle/speedle/api/ads"
 	"github.com/oracle/speedle/api/ext"
@@ -203,3 +204,44 @@ func TestFunctions(t *testing.T) {
 		}
 	}
 }
+
+func TestBuiltinFunctionConditions(t *testing.T) {
+	claims := map[string]interface{}{"address": map[string]interface{}{"country": "CN"}}
+	testCases := []struct {
+		condition  string
+		attributes map[string]interface{}
+		want       bool
+	}{
+		{"StartsWith(path, '/books/') && !EndsWith(path, '.pdf')", map[string]interface{}{"path": "/books/1.txt"}, true},
+		{"Contains(Lower(email), '@example.com')", map[string]interface{}{"email": "Alice@Example.COM"}, true},
+		{"Matches(id, '^emp-[0-9]+$')", map[string]interface{}{"id": "mgr-1"}, false},
+		{"In(city, ('BJ', 'SH'))", map[string]interface{}{"city": "SH"}, true},
+		{"In(city, cities)", map[string]interface{}{"city": "GZ", "cities": []interface{}{"BJ", "SH"}}, false},
+		{"Intersects(groups, ('finance', 'audit'))", map[string]interface{}{"groups": []interface{}{"hr", "audit"}}, true},
+		{"Count(groups) == 2", map[string]interface{}{"groups": []interface{}{"hr", "audit"}}, true},
+		{"CIDRMatch(ip, '10.0.0.0/8', 'fd00::/8')", map[string]interface{}{"ip": "fd00::1"}, true},
+		{"TimeBetween(t, '09:00', '17:00', 'Asia/Shanghai') && DayOfWeek(t) == 'Monday'",
+			map[string]interface{}{"t": float64(time.Date(2019, 3, 4, 2, 0, 0, 0, time.UTC).Unix())}, true},
+		{"ParseTime('2019-03-04T00:00:00Z') < Now()", nil, true},
+		{"Semver(version, '2.1.0') >= 0", map[string]interface{}{"version": "2.0.9"}, false},
+		{"JSONPath(claims, '$.address.country') == 'CN'", map[string]interface{}{"claims": claims}, true},
+	}
+
+	for _, tc := range testCases {
+		stream := fmt.Sprintf(`{"services": [{"name": "crm","policies": [{"id": "p1", "effect": "grant", "permissions": [{"resource": "/node1","actions": ["get"]}],"condition": %q}]}]}`, tc.condition)
+		preparePolicyDataInStore([]byte(stream), t)
+		eval, err := NewWithStore(conf, testPS)
+		if err != nil {
+			t.Errorf("error creating evaluator : %v", err)
+			continue
+		}
+		ctx := adsapi.RequestContext{ServiceName: "crm", Resource: "/node1", Action: "get", Attributes: tc.attributes}
+		got, _, err := eval.IsAllowed(ctx)
+		if err != nil {
+			t.Errorf("condition: %s, context: %v, error: %v", tc.condition, tc.attributes, err)
+		}
+		if got != tc.want {
+			t.Errorf("condition: %s, context: %v, got %v, want %v", tc.condition, tc.attributes, got, tc.want)
+		}
+	}
+}
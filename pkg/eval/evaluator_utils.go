@@ -171,8 +171,8 @@ func calculatePermissions(grantedPermissions, deniedPermissions []pms.Permission
 
 }
 
-func evaluateCondition(condition *govaluate.EvaluableExpression, attributes map[string]interface{}) (bool, error) {
-	res, err := condition.Evaluate(attributes)
+func evaluateCondition(condition *govaluate.EvaluableExpression, parameters govaluate.Parameters) (bool, error) {
+	res, err := condition.Eval(parameters)
 	if err != nil || res != true {
 		if err != nil {
 			log.Errorf("Error happens in evaluating condition (%s): %v", condition.String(), err)
@@ -0,0 +1,103 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"reflect"
+)
+
+// Conditions pass an array attribute or literal like ('a', 'b') as a []interface{}. Note that the elements of
+// an array are passed as separate arguments if it's the only argument, or if it's the first one of []interface{},
+// so arrays are put at the end of the arguments, see IsSubSet as well.
+
+// sliceValues returns the elements of v if it is a slice or an array
+func sliceValues(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// toFloat64 converts numeric values to float64, as numeric attributes are float64 in conditions
+func toFloat64(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
+
+func equalValues(a, b interface{}) bool {
+	return reflect.DeepEqual(toFloat64(a), toFloat64(b))
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if equalValues(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// In(x, S) tests if x is an element of array S
+func In(args ...interface{}) (interface{}, error) {
+	const usage = "In(x, S) - S is an array"
+	if err := checkArgCount(usage, args, 2, 2); err != nil {
+		return nil, err
+	}
+	values, ok := sliceValues(args[1])
+	if !ok {
+		return nil, usageError(usage, "argument 2 should be an array, got %T", args[1])
+	}
+	return containsValue(values, args[0]), nil
+}
+
+// Intersects(S1, S2) tests if arrays S1 and S2 have any element in common
+func Intersects(args ...interface{}) (interface{}, error) {
+	const usage = "Intersects(S1, S2) - S1 and S2 are arrays"
+	n := len(args)
+	if n < 2 {
+		return nil, usageError(usage, "2 arrays are expected, got %d arguments", n)
+	}
+	// The elements of S1 are passed as separate arguments if it is a []interface{}
+	values1, ok := sliceValues(args[0])
+	if n > 2 || !ok {
+		values1 = args[:n-1]
+	}
+	values2, ok := sliceValues(args[n-1])
+	if !ok {
+		return nil, usageError(usage, "argument %d should be an array, got %T", n, args[n-1])
+	}
+	for _, v := range values1 {
+		if containsValue(values2, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Count(S) returns the number of the elements in array S
+func Count(args ...interface{}) (interface{}, error) {
+	// The elements of S are passed as separate arguments if it is a []interface{}
+	if len(args) == 1 {
+		if values, ok := sliceValues(args[0]); ok {
+			return float64(len(values)), nil
+		}
+	}
+	return float64(len(args)), nil
+}
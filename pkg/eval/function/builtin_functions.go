@@ -4,13 +4,39 @@
 package function
 
 import (
+	"fmt"
 	"math"
 	"reflect"
 
 	"github.com/oracle/speedle/pkg/errors"
 )
 
-// Add all built-in functions in this file
+// Add the numeric functions in this file, and the others in builtin_*.go files by category
+
+// usageError returns the error of calling a built-in function with invalid arguments
+func usageError(usage string, format string, args ...interface{}) error {
+	return errors.Errorf(errors.BuiltInFuncError, "%s. Usage: %s", fmt.Sprintf(format, args...), usage)
+}
+
+// checkArgCount checks that the number of arguments is between min and max
+func checkArgCount(usage string, args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return usageError(usage, "%d arguments are expected, got %d", min, len(args))
+		}
+		return usageError(usage, "%d to %d arguments are expected, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+// stringArg returns the i-th argument, which must be a string
+func stringArg(usage string, args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", usageError(usage, "argument %d should be a string, got %T", i+1, args[i])
+	}
+	return s, nil
+}
 
 func Sqrt(args ...interface{}) (interface{}, error) {
 	err := errors.New(errors.BuiltInFuncError, "Usage: Sqrt(x)")
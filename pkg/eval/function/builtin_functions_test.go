@@ -0,0 +1,199 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+type funcTest struct {
+	args []interface{}
+	want interface{}
+	// err is a part of the expected error message, no error is expected if it's empty
+	err string
+}
+
+func args(values ...interface{}) []interface{} {
+	return values
+}
+
+func testFunction(t *testing.T, name string, f func(...interface{}) (interface{}, error), tests []funcTest) {
+	for _, test := range tests {
+		got, err := f(test.args...)
+		if len(test.err) > 0 {
+			if err == nil || errors.Code(err) != errors.BuiltInFuncError || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s%v: expected error %q, got %v, %v", name, test.args, test.err, got, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s%v: expected %v, got %v, %v", name, test.args, test.want, got, err)
+		}
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	testFunction(t, "StartsWith", StartsWith, []funcTest{
+		{args: args("/books/1", "/books/"), want: true},
+		{args: args("/books/1", "/reports/"), want: false},
+		{args: args("/books/1"), err: "2 arguments are expected, got 1"},
+		{args: args("/books/1", 1.0), err: "argument 2 should be a string, got float64"},
+	})
+	testFunction(t, "EndsWith", EndsWith, []funcTest{
+		{args: args("report.pdf", ".pdf"), want: true},
+		{args: args("report.pdf", ".doc"), want: false},
+		{args: args(true, ".doc"), err: "argument 1 should be a string, got bool"},
+	})
+	testFunction(t, "Contains", Contains, []funcTest{
+		{args: args("finance-east", "east"), want: true},
+		{args: args("finance-east", "west"), want: false},
+		{args: args("finance-east", "east", "west"), err: "2 arguments are expected, got 3"},
+	})
+	testFunction(t, "Lower", Lower, []funcTest{
+		{args: args("Alice@Example.COM"), want: "alice@example.com"},
+		{args: args(), err: "1 arguments are expected, got 0"},
+	})
+	testFunction(t, "Matches", Matches, []funcTest{
+		{args: args("emp-1024", "^emp-[0-9]+$"), want: true},
+		{args: args("mgr-1024", "^emp-[0-9]+$"), want: false},
+		{args: args("emp-1024", "[0-9"), err: "invalid pattern"},
+	})
+}
+
+func TestCollectionFunctions(t *testing.T) {
+	testFunction(t, "In", In, []funcTest{
+		{args: args("BJ", []interface{}{"BJ", "SH"}), want: true},
+		{args: args("GZ", []interface{}{"BJ", "SH"}), want: false},
+		{args: args(2.0, []int{1, 2, 3}), want: true},
+		{args: args("BJ", []string{"BJ"}), want: true},
+		{args: args("BJ", "BJ"), err: "argument 2 should be an array, got string"},
+		{args: args("BJ"), err: "2 arguments are expected, got 1"},
+	})
+	testFunction(t, "Intersects", Intersects, []funcTest{
+		{args: args([]string{"a", "b"}, []string{"b", "c"}), want: true},
+		{args: args([]string{"a", "b"}, []interface{}{"c"}), want: false},
+		// The elements of a []interface{} S1 are passed as separate arguments
+		{args: args("a", "b", []interface{}{"b", "c"}), want: true},
+		{args: args("a", []interface{}{"b", "c"}), want: false},
+		{args: args([]interface{}{}), err: "2 arrays are expected, got 1 arguments"},
+		{args: args([]string{"a"}, "a"), err: "argument 2 should be an array, got string"},
+	})
+	testFunction(t, "Count", Count, []funcTest{
+		{args: args([]string{"a", "b"}), want: 2.0},
+		// The elements of a []interface{} are passed as separate arguments
+		{args: args("a", "b", "c"), want: 3.0},
+		{args: args(), want: 0.0},
+	})
+}
+
+func TestCIDRMatch(t *testing.T) {
+	testFunction(t, "CIDRMatch", CIDRMatch, []funcTest{
+		{args: args("10.1.2.3", "10.0.0.0/8"), want: true},
+		{args: args("192.168.1.1", "10.0.0.0/8", "192.168.0.0/16"), want: true},
+		{args: args("192.168.1.1", []interface{}{"10.0.0.0/8", "172.16.0.0/12"}), want: false},
+		{args: args("2001:db8::1", "2001:db8::/32"), want: true},
+		{args: args("2001:db9::1", "2001:db8::/32"), want: false},
+		{args: args("::ffff:10.1.2.3", "10.0.0.0/8"), want: true},
+		{args: args("10.1.2.3"), err: "at least 2 arguments are expected"},
+		{args: args("10.1.2", "10.0.0.0/8"), err: "invalid IP address"},
+		{args: args("10.1.2.3", "10.0.0.0/33"), err: "invalid CIDR"},
+		{args: args("10.1.2.3", 10.0), err: "CIDR should be a string, got float64"},
+	})
+}
+
+func TestTimeFunctions(t *testing.T) {
+	// 2019-03-04 is a Monday
+	monday := float64(time.Date(2019, 3, 4, 23, 30, 0, 0, time.UTC).Unix())
+
+	testFunction(t, "ParseTime", ParseTime, []funcTest{
+		{args: args("2019-03-04T23:30:00Z"), want: monday},
+		{args: args(monday), want: monday},
+		{args: args("2019-03-05T07:30:00+08:00"), want: monday},
+		{args: args("2019-03-04 23:30", "2006-01-02 15:04"), want: monday},
+		{args: args("2019-03-05 00:30", "2006-01-02 15:04", "Europe/Paris"), want: monday},
+		{args: args("yesterday"), err: "is not a supported date time"},
+		{args: args("2019-03-04", "2006-01-02 15:04"), err: "is not a date time in layout"},
+		{args: args("2019-03-04 23:30", "2006-01-02 15:04", "Mars/Olympus"), err: "unknown time zone"},
+	})
+	testFunction(t, "TimeBetween", TimeBetween, []funcTest{
+		{args: args(monday, "23:00", "23:59"), want: true},
+		{args: args(monday, "09:00", "17:00"), want: false},
+		{args: args(monday, "07:00", "08:00", "Asia/Shanghai"), want: true},
+		{args: args(monday, "22:00", "06:00"), want: true},
+		{args: args(monday, "00:00", "23:30:00"), want: false},
+		{args: args(monday, "2019-03-04T00:00:00Z", "2019-03-05T00:00:00Z"), want: true},
+		{args: args("2019-03-05T00:00:00Z", monday, monday+3600), want: true},
+		{args: args(monday, monday+1, monday+3600), want: false},
+		{args: args(monday, "09:00"), err: "3 to 4 arguments are expected"},
+		{args: args(monday, "9am", "5pm"), err: "argument 2 is not a supported date time"},
+		{args: args(true, "09:00", "17:00"), err: "argument 1 should be a time, got bool"},
+	})
+	testFunction(t, "DayOfWeek", DayOfWeek, []funcTest{
+		{args: args(monday), want: "Monday"},
+		{args: args(monday, "Asia/Tokyo"), want: "Tuesday"},
+		{args: args("2019-03-04T23:30:00Z", "America/New_York"), want: "Monday"},
+		{args: args(monday, 8.0), err: "argument 2 should be a string"},
+	})
+	testFunction(t, "Now", Now, []funcTest{
+		{args: args(1.0), err: "0 arguments are expected, got 1"},
+	})
+
+	now, err := Now()
+	if err != nil || now.(float64) < monday {
+		t.Errorf("unexpected Now() %v, %v", now, err)
+	}
+}
+
+func TestSemver(t *testing.T) {
+	testFunction(t, "Semver", Semver, []funcTest{
+		{args: args("1.2.3", "1.2.3"), want: 0.0},
+		{args: args("v1.10.0", "1.9.9"), want: 1.0},
+		{args: args("1.2", "1.2.1"), want: -1.0},
+		{args: args("2.0.0-rc.1", "2.0.0"), want: -1.0},
+		{args: args("2.0.0-alpha", "2.0.0-alpha.1"), want: -1.0},
+		{args: args("2.0.0-alpha.2", "2.0.0-alpha.10"), want: -1.0},
+		{args: args("2.0.0-beta", "2.0.0-alpha.10"), want: 1.0},
+		{args: args("2.0.0-1", "2.0.0-alpha"), want: -1.0},
+		{args: args("1.0.0+build.1", "1.0.0+build.2"), want: 0.0},
+		{args: args("1.x", "1.0.0"), err: "invalid semantic version \"1.x\""},
+		{args: args("1.0.0", "1.0.0.0"), err: "invalid semantic version \"1.0.0.0\""},
+		{args: args("1.0.0-", "1.0.0"), err: "invalid semantic version"},
+		{args: args("1.0.0", 1.0), err: "argument 2 should be a string"},
+	})
+}
+
+func TestJSONPath(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":        "alice",
+		"age":        30,
+		"address":    map[string]interface{}{"country": "CN", "city": "Beijing"},
+		"groups":     []interface{}{"finance", "audit"},
+		"first name": "Alice",
+	}
+	testFunction(t, "JSONPath", JSONPath, []funcTest{
+		{args: args(claims, "$.sub"), want: "alice"},
+		{args: args(claims, "sub"), want: "alice"},
+		{args: args(claims, "$.age"), want: 30.0},
+		{args: args(claims, "$.address.country"), want: "CN"},
+		{args: args(claims, "address.city"), want: "Beijing"},
+		{args: args(claims, "$.groups[1]"), want: "audit"},
+		{args: args(claims, "$['first name']"), want: "Alice"},
+		{args: args(claims, "$.groups[2]"), want: nil},
+		{args: args(claims, "$.missing.key"), want: nil},
+		{args: args(claims, "$.sub.key"), want: nil},
+		{args: args(`{"a": {"b": [1, {"c": true}]}}`, "$.a.b[1].c"), want: true},
+		{args: args(`{"a": 1}`, "$"), want: map[string]interface{}{"a": 1.0}},
+		{args: args(claims, "$.groups[x]"), err: "invalid index"},
+		{args: args(claims, "$.groups[0"), err: "missing ]"},
+		{args: args(claims, "$..sub"), err: "empty key"},
+		{args: args(claims, "$sub"), err: "unexpected"},
+		{args: args("{", "$.a"), err: "not a valid JSON string"},
+		{args: args(claims, 1.0), err: "argument 2 should be a string"},
+	})
+}
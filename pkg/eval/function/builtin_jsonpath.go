@@ -0,0 +1,101 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parseJSONPath parses path like '$.address.city', 'groups[0]' or "$['first name']" into the keys of objects
+// and the indexes of arrays
+func parseJSONPath(path string) ([]interface{}, error) {
+	var segments []interface{}
+	rest := strings.TrimPrefix(path, "$")
+	for first := true; len(rest) > 0; first = false {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in path %q", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, inner[1:len(inner)-1])
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				segments = append(segments, index)
+			} else {
+				return nil, fmt.Errorf("invalid index %q in path %q", inner, path)
+			}
+			rest = rest[end+1:]
+		default:
+			if rest[0] == '.' {
+				rest = rest[1:]
+			} else if !first || strings.HasPrefix(path, "$") {
+				return nil, fmt.Errorf("unexpected %q in path %q", rest[0], path)
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path %q", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		}
+	}
+	return segments, nil
+}
+
+// JSONPath(obj, path) returns the value at path in obj, which is an object attribute or a JSON string, e.g.
+// JSONPath(claims, '$.address.country') == 'CN'. Null is returned if there is no value at path.
+func JSONPath(args ...interface{}) (interface{}, error) {
+	const usage = "JSONPath(obj, path) - obj is an object or a JSON string"
+	if err := checkArgCount(usage, args, 2, 2); err != nil {
+		return nil, err
+	}
+	path, err := stringArg(usage, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, usageError(usage, "%v", err)
+	}
+
+	value := args[0]
+	if s, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, usageError(usage, "argument 1 is not a valid JSON string, %v", err)
+		}
+	}
+	for _, segment := range segments {
+		if value == nil {
+			return nil, nil
+		}
+		switch key := segment.(type) {
+		case string:
+			rv := reflect.ValueOf(value)
+			if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+				return nil, nil
+			}
+			elem := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+			if !elem.IsValid() {
+				return nil, nil
+			}
+			value = elem.Interface()
+		case int:
+			values, ok := sliceValues(value)
+			if !ok || key >= len(values) {
+				return nil, nil
+			}
+			value = values[key]
+		}
+	}
+	return toFloat64(value), nil
+}
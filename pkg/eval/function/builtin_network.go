@@ -0,0 +1,48 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"net"
+)
+
+// CIDRMatch(ip, cidr1, cidr2, ...) tests if IPv4 or IPv6 address ip is in any of the CIDR blocks, which are
+// strings like '10.0.0.0/8' or 'fd00::/8', or arrays of them
+func CIDRMatch(args ...interface{}) (interface{}, error) {
+	const usage = "CIDRMatch(ip, cidr1, cidr2, ...) - cidri is a CIDR string or an array of them"
+	if len(args) < 2 {
+		return nil, usageError(usage, "at least 2 arguments are expected, got %d", len(args))
+	}
+	s, err := stringArg(usage, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, usageError(usage, "invalid IP address %q", s)
+	}
+
+	var cidrs []interface{}
+	for _, arg := range args[1:] {
+		if values, ok := sliceValues(arg); ok {
+			cidrs = append(cidrs, values...)
+		} else {
+			cidrs = append(cidrs, arg)
+		}
+	}
+	for _, c := range cidrs {
+		cidr, ok := c.(string)
+		if !ok {
+			return nil, usageError(usage, "CIDR should be a string, got %T", c)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, usageError(usage, "invalid CIDR %q", cidr)
+		}
+		if network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
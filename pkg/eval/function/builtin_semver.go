@@ -0,0 +1,114 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver is a semantic version, see https://semver.org
+type semver struct {
+	numbers    [3]uint64
+	prerelease []string
+}
+
+// parseSemver parses versions like '1.2.3', 'v1.2.3-rc.1+build.5' and '1.2', the missing minor and patch
+// numbers are 0, and build metadata is ignored
+func parseSemver(s string) (*semver, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v semver
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.prerelease = strings.Split(s[i+1:], ".")
+		for _, id := range v.prerelease {
+			if len(id) == 0 {
+				return nil, false
+			}
+		}
+		s = s[:i]
+	}
+	numbers := strings.Split(s, ".")
+	if len(numbers) > 3 {
+		return nil, false
+	}
+	for i, number := range numbers {
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		v.numbers[i] = n
+	}
+	return &v, true
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare returns -1, 0 or 1 if v precedes, equals or follows o
+func (v *semver) compare(o *semver) int {
+	for i := range v.numbers {
+		if c := compareUint(v.numbers[i], o.numbers[i]); c != 0 {
+			return c
+		}
+	}
+	// A pre-release version precedes the normal version
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		if c := comparePrerelease(v.prerelease[i], o.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.prerelease)), uint64(len(o.prerelease)))
+}
+
+// comparePrerelease compares numeric identifiers numerically, which precede alphanumeric ones compared in ASCII order
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Semver(v1, v2) compares semantic versions v1 and v2, and returns -1, 0 or 1 if v1 is lower than, equal to
+// or higher than v2, e.g. Semver(client_version, '2.1.0') >= 0
+func Semver(args ...interface{}) (interface{}, error) {
+	const usage = "Semver(v1, v2)"
+	s, err := stringArgs(usage, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	v1, ok := parseSemver(s[0])
+	if !ok {
+		return nil, usageError(usage, "invalid semantic version %q", s[0])
+	}
+	v2, ok := parseSemver(s[1])
+	if !ok {
+		return nil, usageError(usage, "invalid semantic version %q", s[1])
+	}
+	return float64(v1.compare(v2)), nil
+}
@@ -0,0 +1,96 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// stringArgs returns the arguments of a function taking n strings
+func stringArgs(usage string, args []interface{}, n int) ([]string, error) {
+	if err := checkArgCount(usage, args, n, n); err != nil {
+		return nil, err
+	}
+	ret := make([]string, n)
+	for i := range args {
+		s, err := stringArg(usage, args, i)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = s
+	}
+	return ret, nil
+}
+
+// StartsWith(s, prefix) tests if string s begins with prefix
+func StartsWith(args ...interface{}) (interface{}, error) {
+	s, err := stringArgs("StartsWith(s, prefix)", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s[0], s[1]), nil
+}
+
+// EndsWith(s, suffix) tests if string s ends with suffix
+func EndsWith(args ...interface{}) (interface{}, error) {
+	s, err := stringArgs("EndsWith(s, suffix)", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s[0], s[1]), nil
+}
+
+// Contains(s, substr) tests if substr is within string s
+func Contains(args ...interface{}) (interface{}, error) {
+	s, err := stringArgs("Contains(s, substr)", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(s[0], s[1]), nil
+}
+
+// Lower(s) returns string s with all letters mapped to their lower case
+func Lower(args ...interface{}) (interface{}, error) {
+	s, err := stringArgs("Lower(s)", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s[0]), nil
+}
+
+// maxPatterns bounds the number of the regular expressions cached by Matches
+const maxPatterns = 1024
+
+// patterns caches the compiled regular expressions of Matches, as the same patterns are used by every evaluation
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+// Matches(s, pattern) tests if string s contains any match of regular expression pattern,
+// use ^ and $ to match the whole string
+func Matches(args ...interface{}) (interface{}, error) {
+	const usage = "Matches(s, pattern)"
+	s, err := stringArgs(usage, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Lock()
+	re, ok := patterns.compiled[s[1]]
+	patterns.Unlock()
+	if !ok {
+		if re, err = regexp.Compile(s[1]); err != nil {
+			return nil, usageError(usage, "invalid pattern %q, %v", s[1], err)
+		}
+		patterns.Lock()
+		if len(patterns.compiled) >= maxPatterns {
+			patterns.compiled = make(map[string]*regexp.Regexp)
+		}
+		patterns.compiled[s[1]] = re
+		patterns.Unlock()
+	}
+	return re.MatchString(s[0]), nil
+}
@@ -0,0 +1,213 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package function
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Times are numeric seconds since the Unix epoch in conditions, like request_time and datetime attributes.
+// Time arguments can be date time strings in the layouts of timeLayouts as well.
+
+// timeLayouts are the layouts of date time strings, the same as the ones of datetime attributes
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RubyDate,
+	time.UnixDate,
+}
+
+// clockLayouts are the layouts of the times of day in TimeBetween
+var clockLayouts = []string{"15:04", "15:04:05"}
+
+// locations caches the loaded time zones, as loading reads the time zone database
+var locations sync.Map
+
+// LoadLocation returns the time zone of IANA name like 'America/New_York', or UTC if name is empty or "UTC".
+// The loaded time zones are cached.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// location returns the time zone of IANA name like 'America/New_York', UTC if name is empty
+func location(usage string, name string) (*time.Location, error) {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		return nil, usageError(usage, "unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// locationArg returns the time zone named by the i-th argument, or UTC if there is no such argument
+func locationArg(usage string, args []interface{}, i int) (*time.Location, error) {
+	if len(args) <= i {
+		return time.UTC, nil
+	}
+	name, err := stringArg(usage, args, i)
+	if err != nil {
+		return nil, err
+	}
+	return location(usage, name)
+}
+
+func parseTime(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TimeValue converts v to a time, v is either seconds since the Unix epoch or a date time string
+func TimeValue(v interface{}) (time.Time, bool) {
+	switch v := toFloat64(v).(type) {
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	case string:
+		return parseTime(v, time.UTC)
+	}
+	return time.Time{}, false
+}
+
+// timeArg returns the i-th argument as a time
+func timeArg(usage string, args []interface{}, i int) (time.Time, error) {
+	t, ok := TimeValue(args[i])
+	if ok {
+		return t, nil
+	}
+	if s, isString := args[i].(string); isString {
+		return time.Time{}, usageError(usage, "argument %d is not a supported date time %q", i+1, s)
+	}
+	return time.Time{}, usageError(usage, "argument %d should be a time, got %T", i+1, args[i])
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// Now() returns the current time
+func Now(args ...interface{}) (interface{}, error) {
+	if err := checkArgCount("Now()", args, 0, 0); err != nil {
+		return nil, err
+	}
+	return float64(time.Now().Unix()), nil
+}
+
+// ParseTime(s, layout, tz) parses date time string s into a time. layout is a Go time layout like
+// '2006-01-02 15:04', s is parsed in the default layouts if it's not specified. tz is the time zone of the
+// date time strings without one, which is UTC by default. Note that string literals like '2019-03-04' in
+// conditions are converted to times in the local time zone of the server, they are returned as is.
+func ParseTime(args ...interface{}) (interface{}, error) {
+	const usage = "ParseTime(s[, layout[, tz]])"
+	if err := checkArgCount(usage, args, 1, 3); err != nil {
+		return nil, err
+	}
+	if t, ok := toFloat64(args[0]).(float64); ok {
+		return t, nil
+	}
+	s, err := stringArg(usage, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locationArg(usage, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		if t, ok := parseTime(s, loc); ok {
+			return unixSeconds(t), nil
+		}
+		return nil, usageError(usage, "%q is not a supported date time", s)
+	}
+	layout, err := stringArg(usage, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return nil, usageError(usage, "%q is not a date time in layout %q", s, layout)
+	}
+	return unixSeconds(t), nil
+}
+
+// clockSeconds returns the seconds since midnight of a time of day like '09:30' or '17:00:00'
+func clockSeconds(value string) (int, bool) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Hour()*3600 + t.Minute()*60 + t.Second(), true
+		}
+	}
+	return 0, false
+}
+
+// TimeBetween(t, start, end, tz) tests if time t is in [start, end). start and end are either times, or times
+// of day like '09:00' and '17:30:00' in time zone tz, which is UTC by default. Times of day wrap around midnight
+// if end is earlier than start, e.g. TimeBetween(request_time, '22:00', '06:00', 'Europe/Paris').
+func TimeBetween(args ...interface{}) (interface{}, error) {
+	const usage = "TimeBetween(t, start, end[, tz])"
+	if err := checkArgCount(usage, args, 3, 4); err != nil {
+		return nil, err
+	}
+	t, err := timeArg(usage, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locationArg(usage, args, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	startClock, isStartClock := args[1].(string)
+	endClock, isEndClock := args[2].(string)
+	if isStartClock && isEndClock {
+		start, isStartClock := clockSeconds(startClock)
+		end, isEndClock := clockSeconds(endClock)
+		if isStartClock && isEndClock {
+			local := t.In(loc)
+			now := local.Hour()*3600 + local.Minute()*60 + local.Second()
+			if start <= end {
+				return start <= now && now < end, nil
+			}
+			return start <= now || now < end, nil
+		}
+	}
+
+	start, err := timeArg(usage, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timeArg(usage, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return !t.Before(start) && t.Before(end), nil
+}
+
+// DayOfWeek(t, tz) returns the day of the week of time t in time zone tz like 'Monday', tz is UTC by default
+func DayOfWeek(args ...interface{}) (interface{}, error) {
+	const usage = "DayOfWeek(t[, tz])"
+	if err := checkArgCount(usage, args, 1, 2); err != nil {
+		return nil, err
+	}
+	t, err := timeArg(usage, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locationArg(usage, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return t.In(loc).Weekday().String(), nil
+}
@@ -0,0 +1,358 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MemoryResultBackend keeps function results in the memory of the ADS
+	MemoryResultBackend = "memory"
+
+	defaultResultCacheMaxEntries = 10000
+	defaultResultCacheMaxSizeMB  = 64
+	// memoryResultOverhead is the estimated bytes used by a cached result besides its namespace, key and value
+	memoryResultOverhead = 128
+)
+
+// FunctionResultBackend stores the JSON encoded results of custom functions. Results are grouped by namespaces,
+// every version of a function definition has its own namespace, so a backend may be shared by ADS replicas and
+// tenants as long as the namespaces are not changed.
+type FunctionResultBackend interface {
+	// Get returns the result of key in namespace, it returns false if the result is not found or expired
+	Get(namespace, key string) ([]byte, bool)
+	// Set stores the result of key in namespace, the result expires after ttl, or never expires if ttl is 0
+	Set(namespace, key string, value []byte, ttl time.Duration)
+	// DeleteNamespace removes all the results in namespace
+	DeleteNamespace(namespace string)
+	// CleanExpired removes the expired results, it does nothing if the backend expires results by itself
+	CleanExpired()
+	// Stats returns the usage of the backend, the counters which are unknown to the backend are 0
+	Stats() FunctionResultBackendStats
+}
+
+// FunctionResultBackendBuilder creates a backend configured by conf
+type FunctionResultBackendBuilder func(conf *cfg.FunctionResultCacheConfig) (FunctionResultBackend, error)
+
+var (
+	resultBackendBuildersMu sync.RWMutex
+	resultBackendBuilders   = map[string]FunctionResultBackendBuilder{
+		MemoryResultBackend: func(conf *cfg.FunctionResultCacheConfig) (FunctionResultBackend, error) {
+			return newMemoryResultBackend(conf), nil
+		},
+	}
+)
+
+// RegisterFunctionResultBackend makes a type of function result backend available by the provided name.
+// If RegisterFunctionResultBackend is called twice with the same name or if builder is nil, it panics.
+func RegisterFunctionResultBackend(name string, builder FunctionResultBackendBuilder) {
+	resultBackendBuildersMu.Lock()
+	defer resultBackendBuildersMu.Unlock()
+	if builder == nil {
+		panic("speedle: RegisterFunctionResultBackend builder is nil")
+	}
+	if _, dup := resultBackendBuilders[name]; dup {
+		panic("speedle: RegisterFunctionResultBackend called twice for backend " + name)
+	}
+	resultBackendBuilders[name] = builder
+}
+
+// FunctionResultBackends returns a sorted list of the names of the registered backends
+func FunctionResultBackends() []string {
+	resultBackendBuildersMu.RLock()
+	defer resultBackendBuildersMu.RUnlock()
+	var names []string
+	for name := range resultBackendBuilders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FunctionResultBackendStats is the usage of a function result backend
+type FunctionResultBackendStats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// FunctionResultCacheStats are the statistics of a function result cache, the counters are accumulated since the
+// cache is created. SharedCalls counts the calls which share the result of an identical call in progress.
+type FunctionResultCacheStats struct {
+	FunctionResultBackendStats
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	SharedCalls uint64 `json:"sharedCalls"`
+}
+
+// FuncResultCache caches the results of cachable custom functions by the canonical hashes of their arguments.
+// Concurrent calls with the same arguments to a function are sent only once, and share the result.
+type FuncResultCache struct {
+	backend FunctionResultBackend
+	callsMu sync.Mutex
+	// calls are the calls in progress keyed by namespaces and keys
+	calls       map[string]*resultCall
+	hits        uint64
+	misses      uint64
+	sharedCalls uint64
+}
+
+type resultCall struct {
+	wg     sync.WaitGroup
+	result interface{}
+	err    error
+}
+
+// NewFuncResultCache creates the cache configured by conf, results are kept in memory with the default limits if
+// conf is nil
+func NewFuncResultCache(conf *cfg.FunctionResultCacheConfig) (*FuncResultCache, error) {
+	if conf == nil {
+		conf = &cfg.FunctionResultCacheConfig{}
+	}
+	name := conf.Backend
+	if name == "" {
+		name = MemoryResultBackend
+	}
+	resultBackendBuildersMu.RLock()
+	builder, ok := resultBackendBuilders[name]
+	resultBackendBuildersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf(errors.ConfigError, "unknown function result backend %q (forgotten import?)", name)
+	}
+	backend, err := builder(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newFuncResultCacheWithBackend(backend), nil
+}
+
+func newFuncResultCacheWithBackend(backend FunctionResultBackend) *FuncResultCache {
+	return &FuncResultCache{
+		backend: backend,
+		calls:   make(map[string]*resultCall),
+	}
+}
+
+// resultNamespace returns the namespace of the results of function cf, which changes with the definition of cf
+func resultNamespace(cf *pms.Function) string {
+	buf, err := json.Marshal(cf)
+	if err != nil {
+		return cf.Name
+	}
+	sum := sha256.Sum256(buf)
+	return cf.Name + "@" + hex.EncodeToString(sum[:8])
+}
+
+// resultKey hashes the canonical JSON encoding of arguments, in which the keys of objects are sorted
+func resultKey(arguments []interface{}) string {
+	buf, err := json.Marshal(arguments)
+	if err != nil {
+		buf = []byte(fmt.Sprintf("%#v", arguments))
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// call returns the cached result of function cf with arguments in namespace, or calls fn and caches its result
+// if cf is cachable
+func (frc *FuncResultCache) call(namespace string, cf *pms.Function, arguments []interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	if !cf.ResultCachable {
+		return fn()
+	}
+	key := resultKey(arguments)
+	if buf, ok := frc.backend.Get(namespace, key); ok {
+		var result interface{}
+		if err := json.Unmarshal(buf, &result); err == nil {
+			atomic.AddUint64(&frc.hits, 1)
+			return result, nil
+		}
+	}
+	atomic.AddUint64(&frc.misses, 1)
+
+	callKey := namespace + "\x00" + key
+	frc.callsMu.Lock()
+	if c, ok := frc.calls[callKey]; ok {
+		frc.callsMu.Unlock()
+		atomic.AddUint64(&frc.sharedCalls, 1)
+		c.wg.Wait()
+		return c.result, c.err
+	}
+	c := &resultCall{}
+	c.wg.Add(1)
+	frc.calls[callKey] = c
+	frc.callsMu.Unlock()
+
+	defer func() {
+		frc.callsMu.Lock()
+		delete(frc.calls, callKey)
+		frc.callsMu.Unlock()
+		c.wg.Done()
+	}()
+	c.result, c.err = fn()
+	if c.err == nil {
+		if buf, err := json.Marshal(c.result); err == nil {
+			frc.backend.Set(namespace, key, buf, time.Duration(cf.ResultTTL)*time.Second)
+		} else {
+			log.Warningf("result of customer function %s is not cached, err is: %v\n", cf.Name, err)
+		}
+	}
+	return c.result, c.err
+}
+
+// DeleteFromCache removes the cached results of function cf
+func (frc *FuncResultCache) DeleteFromCache(cf *pms.Function) {
+	frc.backend.DeleteNamespace(resultNamespace(cf))
+}
+
+// CleanExpiredResult removes the expired results
+func (frc *FuncResultCache) CleanExpiredResult() {
+	frc.backend.CleanExpired()
+}
+
+// Stats returns the statistics of the cache
+func (frc *FuncResultCache) Stats() FunctionResultCacheStats {
+	return FunctionResultCacheStats{
+		FunctionResultBackendStats: frc.backend.Stats(),
+		Hits:                       atomic.LoadUint64(&frc.hits),
+		Misses:                     atomic.LoadUint64(&frc.misses),
+		SharedCalls:                atomic.LoadUint64(&frc.sharedCalls),
+	}
+}
+
+// memoryResultBackend keeps the results in an LRU list bounded by the number and the size of the results
+type memoryResultBackend struct {
+	sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	namespaces map[string]map[string]*list.Element
+	// lru keeps the results from the most recently used to the least recently used
+	lru       *list.List
+	bytes     int64
+	evictions uint64
+	// now returns the current time, it's replaced in tests
+	now func() time.Time
+}
+
+type memoryResult struct {
+	namespace string
+	key       string
+	value     []byte
+	// expiresAt is zero if the result never expires
+	expiresAt time.Time
+}
+
+func (r *memoryResult) size() int64 {
+	return int64(len(r.namespace) + len(r.key) + len(r.value) + memoryResultOverhead)
+}
+
+func newMemoryResultBackend(conf *cfg.FunctionResultCacheConfig) *memoryResultBackend {
+	maxEntries, maxSizeMB := conf.MaxEntries, conf.MaxSizeMB
+	if maxEntries <= 0 {
+		maxEntries = defaultResultCacheMaxEntries
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultResultCacheMaxSizeMB
+	}
+	return &memoryResultBackend{
+		maxEntries: maxEntries,
+		maxBytes:   int64(maxSizeMB) * 1024 * 1024,
+		namespaces: make(map[string]map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+func (b *memoryResultBackend) Get(namespace, key string) ([]byte, bool) {
+	b.Lock()
+	defer b.Unlock()
+	elem, ok := b.namespaces[namespace][key]
+	if !ok {
+		return nil, false
+	}
+	result := elem.Value.(*memoryResult)
+	if !result.expiresAt.IsZero() && !b.now().Before(result.expiresAt) {
+		b.remove(elem)
+		return nil, false
+	}
+	b.lru.MoveToFront(elem)
+	return result.value, true
+}
+
+func (b *memoryResultBackend) Set(namespace, key string, value []byte, ttl time.Duration) {
+	result := &memoryResult{namespace: namespace, key: key, value: value}
+	if ttl > 0 {
+		result.expiresAt = b.now().Add(ttl)
+	}
+	if result.size() > b.maxBytes {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	if elem, ok := b.namespaces[namespace][key]; ok {
+		b.remove(elem)
+	}
+	results, ok := b.namespaces[namespace]
+	if !ok {
+		results = make(map[string]*list.Element)
+		b.namespaces[namespace] = results
+	}
+	results[key] = b.lru.PushFront(result)
+	b.bytes += result.size()
+	for b.lru.Len() > b.maxEntries || b.bytes > b.maxBytes {
+		b.remove(b.lru.Back())
+		b.evictions++
+	}
+}
+
+func (b *memoryResultBackend) remove(elem *list.Element) {
+	result := elem.Value.(*memoryResult)
+	b.lru.Remove(elem)
+	b.bytes -= result.size()
+	results := b.namespaces[result.namespace]
+	delete(results, result.key)
+	if len(results) == 0 {
+		delete(b.namespaces, result.namespace)
+	}
+}
+
+func (b *memoryResultBackend) DeleteNamespace(namespace string) {
+	b.Lock()
+	defer b.Unlock()
+	for _, elem := range b.namespaces[namespace] {
+		b.remove(elem)
+	}
+}
+
+func (b *memoryResultBackend) CleanExpired() {
+	b.Lock()
+	defer b.Unlock()
+	now := b.now()
+	for elem := b.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if expiresAt := elem.Value.(*memoryResult).expiresAt; !expiresAt.IsZero() && !now.Before(expiresAt) {
+			b.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+func (b *memoryResultBackend) Stats() FunctionResultBackendStats {
+	b.Lock()
+	defer b.Unlock()
+	return FunctionResultBackendStats{Entries: b.lru.Len(), Bytes: b.bytes, Evictions: b.evictions}
+}
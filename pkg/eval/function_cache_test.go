@@ -0,0 +1,169 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+func TestMemoryResultBackend(t *testing.T) {
+	b := newMemoryResultBackend(&cfg.FunctionResultCacheConfig{MaxEntries: 3})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.Set("f@1", "k1", []byte("1"), 0)
+	b.Set("f@1", "k2", []byte("2"), 10*time.Second)
+	b.Set("g@1", "k1", []byte("3"), 0)
+	if v, ok := b.Get("f@1", "k1"); !ok || string(v) != "1" {
+		t.Errorf("expected result 1, got %s, %v", v, ok)
+	}
+	// f@1/k2 is the least recently used
+	b.Set("g@1", "k2", []byte("4"), 0)
+	if _, ok := b.Get("f@1", "k2"); ok {
+		t.Error("least recently used result should be evicted")
+	}
+	if stats := b.Stats(); stats.Entries != 3 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	b.Set("f@1", "k2", []byte("2"), 10*time.Second)
+	now = now.Add(10 * time.Second)
+	if _, ok := b.Get("f@1", "k2"); ok {
+		t.Error("result should expire after TTL")
+	}
+	b.DeleteNamespace("g@1")
+	if stats := b.Stats(); stats.Entries != 1 || stats.Bytes != (&memoryResult{namespace: "f@1", key: "k1", value: []byte("1")}).size() {
+		t.Errorf("only result f@1/k1 should be left, got stats %+v", stats)
+	}
+
+	b.Set("f@1", "k3", []byte("3"), time.Second)
+	now = now.Add(time.Second)
+	b.CleanExpired()
+	if stats := b.Stats(); stats.Entries != 1 {
+		t.Errorf("expired results should be cleaned, got stats %+v", stats)
+	}
+
+	// Results are evicted once their size exceeds the limit
+	b = newMemoryResultBackend(&cfg.FunctionResultCacheConfig{MaxSizeMB: 1})
+	big := make([]byte, 400*1024)
+	for i := 0; i < 3; i++ {
+		b.Set("f@1", fmt.Sprint(i), big, 0)
+	}
+	if stats := b.Stats(); stats.Entries != 2 || stats.Evictions != 1 || stats.Bytes > 1024*1024 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	b.Set("f@1", "huge", make([]byte, 2*1024*1024), 0)
+	if _, ok := b.Get("f@1", "huge"); ok {
+		t.Error("result larger than the cache should not be cached")
+	}
+}
+
+func TestFuncResultCache(t *testing.T) {
+	frc, err := NewFuncResultCache(nil)
+	if err != nil {
+		t.Fatal("failed to create cache:", err)
+	}
+	cf := &pms.Function{Name: "f", FuncURL: "http://localhost/f", ResultCachable: true}
+	ns := resultNamespace(cf)
+	var calls int32
+	call := func(arguments ...interface{}) (interface{}, error) {
+		return frc.call(ns, cf, arguments, func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return map[string]interface{}{"n": float64(len(arguments))}, nil
+		})
+	}
+
+	// Keys are canonical, the order of object keys doesn't matter, and arguments are not joined ambiguously
+	call(map[string]interface{}{"a": 1.0, "b": "x"})
+	call(map[string]interface{}{"b": "x", "a": 1.0})
+	call("a b")
+	call("a", "b")
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if result, err := call("a", "b"); err != nil || result.(map[string]interface{})["n"] != 2.0 {
+		t.Errorf("expected cached result, got %v, %v", result, err)
+	}
+
+	// Concurrent identical calls share one call
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	calls = 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			frc.call(ns, cf, []interface{}{"slow"}, func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return true, nil
+			})
+		}()
+	}
+	for atomic.LoadUint64(&frc.sharedCalls) < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("concurrent identical calls should be sent once, sent %d times", calls)
+	}
+
+	// Errors and the results of functions which are not cachable are never cached
+	failing := func() (interface{}, error) {
+		return nil, errors.New(errors.CustomerFuncError, "failed")
+	}
+	frc.call(ns, cf, []interface{}{"error"}, failing)
+	if _, err := frc.call(ns, cf, []interface{}{"error"}, failing); err == nil {
+		t.Error("errors should not be cached")
+	}
+	notCachable := &pms.Function{Name: "g", FuncURL: "http://localhost/g"}
+	frc.call(resultNamespace(notCachable), notCachable, nil, func() (interface{}, error) { return true, nil })
+
+	stats := frc.Stats()
+	if stats.Entries != 4 || stats.Hits != 2 || stats.SharedCalls != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	frc.DeleteFromCache(cf)
+	if stats := frc.Stats(); stats.Entries != 0 {
+		t.Errorf("results of f should be deleted, got stats %+v", stats)
+	}
+
+	if _, err := NewFuncResultCache(&cfg.FunctionResultCacheConfig{Backend: "unknown"}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected config error of unknown backend, got %v", err)
+	}
+}
+
+func TestFunctionResultsInvalidatedOnChange(t *testing.T) {
+	server, calls := flakyFunctionServer(0, 0)
+	defer server.Close()
+	fs := NewRuntimePolicyStore()
+	fs.init(&pms.PolicyStore{Functions: []*pms.Function{{Name: "f", FuncURL: server.URL, ResultCachable: true}}}, "")
+	fs.Functions["f"]("alice")
+	fs.Functions["f"]("alice")
+	if *calls != 1 {
+		t.Errorf("result should be cached, function is called %d times", *calls)
+	}
+
+	fs.addFunction(&pms.Function{Name: "f", FuncURL: server.URL, ResultCachable: true, ResultTTL: 60})
+	if stats := fs.FunctionResultCache.Stats(); stats.Entries != 0 {
+		t.Errorf("results of the old definition should be deleted, got stats %+v", stats)
+	}
+	fs.Functions["f"]("alice")
+	if *calls != 2 {
+		t.Errorf("changed function should be called again, function is called %d times", *calls)
+	}
+	fs.deleteFunction("f")
+	if stats := fs.FunctionResultCache.Stats(); stats.Entries != 0 {
+		t.Errorf("results of the deleted function should be deleted, got stats %+v", stats)
+	}
+}
@@ -0,0 +1,133 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"crypto/tls"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// functionClientCerts are the client certificates presented to the custom functions which verify clients
+var functionClientCerts = &clientCertStore{}
+
+// clientCertStore loads the client certificates configured in the ADS, keyed by function name or by the host
+// of function URL. Certificates are loaded when connections are made, and reloaded once their files change,
+// so that they can be rotated without changing the functions.
+type clientCertStore struct {
+	sync.RWMutex
+	configs map[string]*cfg.FunctionClientCert
+	loaded  map[string]*loadedClientCert
+}
+
+type loadedClientCert struct {
+	cert              *tls.Certificate
+	certFile, keyFile string
+	certMod, keyMod   time.Time
+}
+
+// SetFunctionClientCerts sets the client certificates presented to custom functions, keyed by function name or by
+// the host of function URL. The certificates are loaded to check them, the previous ones are kept if any fails.
+func SetFunctionClientCerts(configs map[string]*cfg.FunctionClientCert) error {
+	loaded := make(map[string]*loadedClientCert, len(configs))
+	for key, conf := range configs {
+		if conf == nil || conf.CertFile == "" || conf.KeyFile == "" {
+			return errors.Errorf(errors.ConfigError, "client certificate and key of %q must be set together", key)
+		}
+		cert, err := loadClientCert(conf)
+		if err != nil {
+			return err
+		}
+		loaded[key] = cert
+	}
+
+	functionClientCerts.Lock()
+	defer functionClientCerts.Unlock()
+	functionClientCerts.configs = configs
+	functionClientCerts.loaded = loaded
+	return nil
+}
+
+func loadClientCert(conf *cfg.FunctionClientCert) (*loadedClientCert, error) {
+	certInfo, err := os.Stat(conf.CertFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "unable to load function client certificate %q", conf.CertFile)
+	}
+	keyInfo, err := os.Stat(conf.KeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "unable to load function client key %q", conf.KeyFile)
+	}
+	cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "unable to load function client certificate %q and key %q", conf.CertFile, conf.KeyFile)
+	}
+	return &loadedClientCert{cert: &cert, certMod: certInfo.ModTime(), keyMod: keyInfo.ModTime(),
+		certFile: conf.CertFile, keyFile: conf.KeyFile}, nil
+}
+
+// configKey returns the key of the client certificate of function name called at funcURL, the function name is
+// looked up before the host
+func (s *clientCertStore) configKey(name, funcURL string) (string, bool) {
+	if _, ok := s.configs[name]; ok {
+		return name, true
+	}
+	if u, err := url.Parse(funcURL); err == nil {
+		if _, ok := s.configs[u.Hostname()]; ok {
+			return u.Hostname(), true
+		}
+	}
+	return "", false
+}
+
+// clientCertificate returns the function of tls.Config.GetClientCertificate, which presents the client
+// certificate of function name called at funcURL, or no certificate if none is configured
+func (s *clientCertStore) clientCertificate(name, funcURL string) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		s.RLock()
+		key, ok := s.configKey(name, funcURL)
+		if !ok {
+			s.RUnlock()
+			return &tls.Certificate{}, nil
+		}
+		conf, current := s.configs[key], s.loaded[key]
+		s.RUnlock()
+
+		if current != nil && !clientCertChanged(current) {
+			return current.cert, nil
+		}
+		cert, err := loadClientCert(conf)
+		if err != nil {
+			// The paths are only logged in the ADS, they're never returned to the callers
+			log.Errorf("Failed to reload the client certificate of customer function %s, err: %v.", name, err)
+			if current != nil {
+				return current.cert, nil
+			}
+			return nil, errors.Errorf(errors.CustomerFuncError, "client certificate of customer function %q is not available", name)
+		}
+		s.Lock()
+		if s.configs[key] == conf {
+			s.loaded[key] = cert
+		}
+		s.Unlock()
+		return cert.cert, nil
+	}
+}
+
+func clientCertChanged(c *loadedClientCert) bool {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return true
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return true
+	}
+	return !certInfo.ModTime().Equal(c.certMod) || !keyInfo.ModTime().Equal(c.keyMod)
+}
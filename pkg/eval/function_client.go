@@ -0,0 +1,335 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRetryBackoff            = 100 * time.Millisecond
+	maxRetryBackoff                = pms.MaxFunctionRetryBackoffMS * time.Millisecond
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenDuration     = 30 * time.Second
+	functionIdleConnTimeout        = 90 * time.Second
+	functionMaxIdleConnsPerHost    = 100
+)
+
+// remoteFunction calls a custom function served over http(s), directly or via the delegator, or served over gRPC.
+// It's created when the function is loaded, so that its connections are pooled for all the calls to the function,
+// and closed once the definition of the function changes or the function is deleted.
+type remoteFunction struct {
+	cf     *pms.Function
+	client *http.Client
+	// grpc is the client of the function if it's served over gRPC, and client is nil
+	grpc       *grpcFunction
+	maxRetries int
+	backoff    time.Duration
+	// timeout is the timeout of one attempt, retries are not started once a call has taken as long
+	timeout time.Duration
+	// breaker is nil if the circuit breaker is disabled
+	breaker *circuitBreaker
+
+	// calls is the number of calls in progress, the connections are closed by the last one once the function is closed
+	sync.Mutex
+	calls  int
+	closed bool
+}
+
+// newRemoteFunction creates the client of function cf with its timeout, CA and the client certificate configured for it
+func newRemoteFunction(cf *pms.Function) (*remoteFunction, error) {
+	timeout := defaultCustomerFunctionCallTimeout
+	if cf.Limits != nil && cf.Limits.TimeoutMS > 0 {
+		timeout = time.Duration(cf.Limits.TimeoutMS) * time.Millisecond
+	}
+	tlsConfig := &tls.Config{}
+	if len(cf.CA) > 0 { //this is only required if func server use certificate which is signed by unknown CA
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM([]byte(cf.CA)) {
+			return nil, errors.Errorf(errors.CustomerFuncError, "no certificate is found in CA of customer function %q", cf.Name)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	// The client certificate is configured in the ADS, and picked when a connection is made
+	tlsConfig.GetClientCertificate = functionClientCerts.clientCertificate(cf.Name, cf.FuncURL)
+
+	f := &remoteFunction{
+		cf:      cf,
+		backoff: defaultRetryBackoff,
+		timeout: timeout,
+	}
+	if isGRPCURL(cf.FuncURL) {
+		var err error
+		if f.grpc, err = newGRPCFunction(cf, tlsConfig, timeout); err != nil {
+			return nil, err
+		}
+	} else {
+		transport := &http.Transport{
+			TLSClientConfig:     tlsConfig,
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost: functionMaxIdleConnsPerHost,
+			IdleConnTimeout:     functionIdleConnTimeout,
+		}
+		f.client = &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		}
+	}
+	if cf.Retry != nil {
+		// Limits are also applied to the definitions stored before they were validated
+		f.maxRetries = cf.Retry.MaxRetries
+		if f.maxRetries > pms.MaxFunctionRetries {
+			f.maxRetries = pms.MaxFunctionRetries
+		}
+		if cf.Retry.BackoffMS > 0 {
+			f.backoff = time.Duration(cf.Retry.BackoffMS) * time.Millisecond
+		}
+		if f.backoff > maxRetryBackoff {
+			f.backoff = maxRetryBackoff
+		}
+	}
+	if cf.CircuitBreaker != nil {
+		f.breaker = newCircuitBreaker(cf.Name, cf.CircuitBreaker)
+	}
+	return f, nil
+}
+
+// close closes the pooled connections to the function once the calls in progress complete, it's called when the
+// function is replaced or deleted
+func (f *remoteFunction) close() {
+	f.Lock()
+	defer f.Unlock()
+	f.closed = true
+	if f.calls == 0 {
+		f.closeConnections()
+	}
+}
+
+// acquire starts a call, calls over gRPC are rejected once the function is closed, as its connection is closed
+func (f *remoteFunction) acquire() bool {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed && f.grpc != nil {
+		return false
+	}
+	f.calls++
+	return true
+}
+
+func (f *remoteFunction) release() {
+	f.Lock()
+	defer f.Unlock()
+	f.calls--
+	if f.closed && f.calls == 0 {
+		f.closeConnections()
+	}
+}
+
+func (f *remoteFunction) closeConnections() {
+	if f.grpc != nil {
+		f.grpc.close()
+	} else {
+		f.client.Transport.(*http.Transport).CloseIdleConnections()
+	}
+}
+
+// call sends request to the function, or to the delegator at cfdURL if it's not empty. Functions served over gRPC
+// are always called directly. Failed calls are retried by the retry policy of the function.
+func (f *remoteFunction) call(cfdURL string, request *ext.CustomerFunctionRequest) (interface{}, error) {
+	if f.breaker != nil && !f.breaker.allow() {
+		return nil, errors.Errorf(errors.CustomerFuncError, "circuit of customer function %q is open", f.cf.Name)
+	}
+	if !f.acquire() {
+		return nil, errors.Errorf(errors.CustomerFuncError, "customer function %q is unloaded", f.cf.Name)
+	}
+	defer f.release()
+	var send func(ctx context.Context) (interface{}, bool, error)
+	if f.grpc != nil {
+		send = func(ctx context.Context) (interface{}, bool, error) {
+			return f.grpc.call(ctx, request)
+		}
+	} else {
+		url, buf, err := f.requestBody(cfdURL, request)
+		if err != nil {
+			return nil, err
+		}
+		send = func(ctx context.Context) (interface{}, bool, error) {
+			return f.post(ctx, url, buf)
+		}
+	}
+
+	// Retries are started within the timeout, and every attempt ends by the deadline of the whole call
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(2*f.timeout))
+	defer cancel()
+	var result interface{}
+	var retryable bool
+	var err error
+	backoff := f.backoff
+	for attempt := 0; ; attempt++ {
+		result, retryable, err = send(ctx)
+		if err == nil || !retryable || attempt >= f.maxRetries {
+			break
+		}
+		if time.Since(start)+backoff >= f.timeout {
+			log.Warningf("customer function %s is not retried after %v, err is: %v\n", f.cf.Name, time.Since(start), err)
+			break
+		}
+		log.Warningf("retrying customer function %s in %v, err is: %v\n", f.cf.Name, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+	if f.breaker != nil {
+		// Errors returned by the function itself mean it's up, they never open the circuit
+		f.breaker.record(err == nil || !retryable)
+	}
+	return result, err
+}
+
+// fallback returns the result of the calls which fail or are rejected by the open circuit if the function fails open
+func (f *remoteFunction) fallback() (interface{}, bool) {
+	if f.breaker == nil || !f.breaker.failOpen {
+		return nil, false
+	}
+	return f.breaker.fallbackResult, true
+}
+
+func (f *remoteFunction) requestBody(cfdURL string, request *ext.CustomerFunctionRequest) (string, []byte, error) {
+	if cfdURL != "" {
+		//assume that http is used when communicate with delegator.
+		buf, err := json.Marshal(Request2Delegator{
+			Function: f.cf,
+			Request:  request,
+		})
+		return cfdURL, buf, err
+	}
+	lowerURL := strings.ToLower(f.cf.FuncURL)
+	if !strings.HasPrefix(lowerURL, "https:") && !strings.HasPrefix(lowerURL, "http:") {
+		return "", nil, errors.Errorf(errors.CustomerFuncError, "URL of customer function %q is not supported", f.cf.FuncURL)
+	}
+	buf, err := json.Marshal(request)
+	return f.cf.FuncURL, buf, err
+}
+
+// post sends one request to url, and reports whether the error is worth retrying
+func (f *remoteFunction) post(ctx context.Context, url string, buf []byte) (interface{}, bool, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+	if err != nil {
+		return nil, false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		log.Errorf("error happens when calling customer function %s, err is: %v\n", f.cf.Name, err)
+		return nil, true, errors.Wrapf(err, errors.CustomerFuncError, "failed to do customer function request for customer function %q", f.cf.Name)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		//TODO: We might need to limit the larget size we want to receive
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			log.Errorf("error reading response from customer function %s, err is: %v\n", f.cf.Name, err)
+			return nil, true, errors.Wrapf(err, errors.CustomerFuncError, "fail to read response for customer function %q", f.cf.Name)
+		}
+		result, err := parseFunctionResponse(body, f.cf)
+		return result, false, err
+	default:
+		// The body is drained, so that the connection is reused
+		io.Copy(ioutil.Discard, resp.Body)
+		log.Errorf("Invalid status code returns when calling customer function %s, status code is : %v\n", f.cf.Name, resp.StatusCode)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return nil, retryable, errors.Errorf(errors.CustomerFuncError, "unexpected http status %d returned when calling customer function %s", resp.StatusCode, f.cf.Name)
+	}
+}
+
+// circuitBreaker counts the consecutive failed calls to a function, the circuit is open when the failures reach
+// the threshold, and half open after the open duration, when only one trial call is allowed
+type circuitBreaker struct {
+	sync.Mutex
+	name           string
+	threshold      int
+	openDuration   time.Duration
+	failOpen       bool
+	fallbackResult interface{}
+	failures       int
+	// openedAt is zero while the circuit is closed
+	openedAt time.Time
+	trial    bool
+	// now returns the current time, it's replaced in tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(name string, conf *pms.CircuitBreaker) *circuitBreaker {
+	b := &circuitBreaker{
+		name:           name,
+		threshold:      defaultBreakerFailureThreshold,
+		openDuration:   defaultBreakerOpenDuration,
+		failOpen:       conf.FailOpen,
+		fallbackResult: conf.FallbackResult,
+		now:            time.Now,
+	}
+	if conf.FailureThreshold > 0 {
+		b.threshold = conf.FailureThreshold
+	}
+	if conf.OpenSeconds > 0 {
+		b.openDuration = time.Duration(conf.OpenSeconds) * time.Second
+	}
+	return b
+}
+
+// allow reports whether a call can be sent to the function
+func (b *circuitBreaker) allow() bool {
+	b.Lock()
+	defer b.Unlock()
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.openDuration {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record counts the result of an allowed call
+func (b *circuitBreaker) record(success bool) {
+	b.Lock()
+	defer b.Unlock()
+	b.trial = false
+	if success {
+		if !b.openedAt.IsZero() {
+			log.Infof("circuit of customer function %s is closed\n", b.name)
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.openedAt.IsZero() {
+			log.Warningf("circuit of customer function %s is open after %d consecutive failures\n", b.name, b.failures)
+		}
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,288 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// flakyFunctionServer fails the first failures calls with status, and returns true afterwards
+func flakyFunctionServer(failures int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"result":true}`))
+	})), &calls
+}
+
+func TestHTTPFunctionRetry(t *testing.T) {
+	server, calls := flakyFunctionServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+	f, err := newRemoteFunction(&pms.Function{Name: "flaky", FuncURL: server.URL, Retry: &pms.RetryPolicy{MaxRetries: 2, BackoffMS: 1}})
+	if err != nil {
+		t.Fatal("failed to create http function:", err)
+	}
+	if result, err := f.call("", &ext.CustomerFunctionRequest{}); err != nil || result != true {
+		t.Errorf("expected true after retries, got %v, %v", result, err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+
+	// Errors returned by the function are not retried
+	server, calls = flakyFunctionServer(1, http.StatusBadRequest)
+	defer server.Close()
+	f, _ = newRemoteFunction(&pms.Function{Name: "bad", FuncURL: server.URL, Retry: &pms.RetryPolicy{MaxRetries: 2, BackoffMS: 1}})
+	if _, err := f.call("", &ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError || *calls != 1 {
+		t.Errorf("expected customer function error without retries, got %v after %d calls", err, *calls)
+	}
+
+	// Calls are not retried once the next retry would start after the timeout
+	server, calls = flakyFunctionServer(10, http.StatusServiceUnavailable)
+	defer server.Close()
+	f, _ = newRemoteFunction(&pms.Function{Name: "down", FuncURL: server.URL, Retry: &pms.RetryPolicy{MaxRetries: 5, BackoffMS: 40},
+		Limits: &pms.FunctionLimits{TimeoutMS: 100}})
+	if _, err := f.call("", &ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError || *calls != 2 {
+		t.Errorf("expected customer function error after 2 calls, got %v after %d calls", err, *calls)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"result":true}`))
+	}))
+	defer slow.Close()
+	f, _ = newRemoteFunction(&pms.Function{Name: "slow", FuncURL: slow.URL, Limits: &pms.FunctionLimits{TimeoutMS: 50}})
+	if _, err := f.call("", &ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestHTTPFunctionCircuitBreaker(t *testing.T) {
+	server, calls := flakyFunctionServer(3, http.StatusInternalServerError)
+	defer server.Close()
+	f, err := newRemoteFunction(&pms.Function{Name: "down", FuncURL: server.URL,
+		CircuitBreaker: &pms.CircuitBreaker{FailureThreshold: 2, OpenSeconds: 10}})
+	if err != nil {
+		t.Fatal("failed to create http function:", err)
+	}
+	now := time.Now()
+	f.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.call("", &ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError {
+			t.Errorf("expected customer function error, got %v", err)
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("calls should be rejected once the circuit is open, got %d calls", *calls)
+	}
+	// The failed trial call opens the circuit again
+	now = now.Add(10 * time.Second)
+	f.call("", &ext.CustomerFunctionRequest{})
+	f.call("", &ext.CustomerFunctionRequest{})
+	if *calls != 3 {
+		t.Errorf("one trial call should be sent after the circuit is open, got %d calls", *calls)
+	}
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		if result, err := f.call("", &ext.CustomerFunctionRequest{}); err != nil || result != true {
+			t.Errorf("circuit should be closed after the trial call succeeds, got %v, %v", result, err)
+		}
+	}
+}
+
+func TestHTTPFunctionFailOpen(t *testing.T) {
+	server, calls := flakyFunctionServer(1, http.StatusInternalServerError)
+	defer server.Close()
+	cf := &pms.Function{Name: "down", FuncURL: server.URL, ResultCachable: true,
+		CircuitBreaker: &pms.CircuitBreaker{FailOpen: true, FallbackResult: false}}
+	fs := NewRuntimePolicyStore()
+	function, _, err := fs.FunctionResultCache.generateCustomerExpressionFunction(fs.FuncSvcEndpoint, cf)
+	if err != nil {
+		t.Fatal("failed to generate function:", err)
+	}
+	if result, err := function("alice"); err != nil || result != false {
+		t.Errorf("expected fallback result false, got %v, %v", result, err)
+	}
+	// The fallback result is not cached
+	if result, err := function("alice"); err != nil || result != true || *calls != 2 {
+		t.Errorf("expected result true of the second call, got %v, %v after %d calls", result, err, *calls)
+	}
+}
+
+func TestFuncSvcEndpointChanged(t *testing.T) {
+	server, calls := flakyFunctionServer(0, http.StatusOK)
+	defer server.Close()
+	delegator, delegatorCalls := flakyFunctionServer(0, http.StatusOK)
+	defer delegator.Close()
+	fs := NewRuntimePolicyStore()
+	function, _, err := fs.FunctionResultCache.generateCustomerExpressionFunction(fs.FuncSvcEndpoint, &pms.Function{Name: "f", FuncURL: server.URL})
+	if err != nil {
+		t.Fatal("failed to generate function:", err)
+	}
+	if result, err := function("alice"); err != nil || result != true || *calls != 1 {
+		t.Errorf("function should be called directly, got %v, %v after %d calls", result, err, *calls)
+	}
+
+	// The endpoint is changed by config reload while conditions call the function
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			function("alice")
+		}()
+	}
+	fs.setFuncSvcEndpoint(delegator.URL)
+	wg.Wait()
+	if result, err := function("bob"); err != nil || result != true || *delegatorCalls == 0 {
+		t.Errorf("function should be called via the delegator, got %v, %v after %d calls", result, err, *delegatorCalls)
+	}
+}
+
+func TestReplacedHTTPFunctionIsClosed(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":true}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+	fs := NewRuntimePolicyStore()
+	fs.init(&pms.PolicyStore{Functions: []*pms.Function{{Name: "f", FuncURL: server.URL}}}, "")
+	if result, err := fs.Functions["f"]("alice"); err != nil || result != true {
+		t.Fatalf("expected result true, got %v, %v", result, err)
+	}
+
+	// The pooled connection to the function is closed once the function is replaced
+	fs.addFunction(&pms.Function{Name: "f", FuncURL: server.URL, ResultCachable: true})
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("the connection to the replaced function should be closed")
+	}
+}
+
+// writeClientCert writes a self signed client certificate with common name cn to certFile and keyFile
+func writeClientCert(t *testing.T, cn, certFile, keyFile string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("failed to create certificate:", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("failed to marshal key:", err)
+	}
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestHTTPFunctionClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-function")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	rotatedCertFile, rotatedKeyFile := filepath.Join(dir, "rotated.crt"), filepath.Join(dir, "rotated.key")
+	rotatedCert := writeClientCert(t, "speedle-ads-rotated", rotatedCertFile, rotatedKeyFile)
+	clientCert := writeClientCert(t, "speedle-ads", certFile, keyFile)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"` + r.TLS.PeerCertificates[0].Subject.CommonName + `"}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	clientCAs.AddCert(rotatedCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	call := func(name string) (interface{}, error) {
+		f, err := newRemoteFunction(&pms.Function{Name: name, FuncURL: server.URL, CA: ca})
+		if err != nil {
+			t.Fatal("failed to create http function:", err)
+		}
+		return f.call("", &ext.CustomerFunctionRequest{})
+	}
+
+	// Certificates are looked up by function name, and then by host
+	if err := SetFunctionClientCerts(map[string]*cfg.FunctionClientCert{"mtls": {CertFile: certFile, KeyFile: keyFile}}); err != nil {
+		t.Fatal("failed to set client certificates:", err)
+	}
+	defer SetFunctionClientCerts(nil)
+	if result, err := call("mtls"); err != nil || result != "speedle-ads" {
+		t.Errorf("expected result speedle-ads, got %v, %v", result, err)
+	}
+	if _, err := call("tls"); err == nil {
+		t.Error("call without client certificate should fail")
+	}
+	SetFunctionClientCerts(map[string]*cfg.FunctionClientCert{"127.0.0.1": {CertFile: certFile, KeyFile: keyFile}})
+	if result, err := call("tls"); err != nil || result != "speedle-ads" {
+		t.Errorf("expected result speedle-ads by host, got %v, %v", result, err)
+	}
+
+	// Rotated certificates are presented to new connections without changing the function
+	for from, to := range map[string]string{rotatedCertFile: certFile, rotatedKeyFile: keyFile} {
+		buf, _ := ioutil.ReadFile(from)
+		ioutil.WriteFile(to, buf, 0600)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if result, err := call("tls"); err != nil || result != "speedle-ads-rotated" {
+		t.Errorf("expected result speedle-ads-rotated, got %v, %v", result, err)
+	}
+
+	missing := map[string]*cfg.FunctionClientCert{"missing": {CertFile: certFile + ".missing", KeyFile: keyFile}}
+	if err := SetFunctionClientCerts(missing); errors.Code(err) != errors.ConfigError || !strings.Contains(err.Error(), "client certificate") {
+		t.Errorf("expected error loading client certificate, got %v", err)
+	}
+	if err := SetFunctionClientCerts(map[string]*cfg.FunctionClientCert{"nokey": {CertFile: certFile}}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expected error of client certificate without key, got %v", err)
+	}
+}
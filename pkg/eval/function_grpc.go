@@ -0,0 +1,181 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/ext/pb"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grpcScheme    = "grpc"
+	grpcTLSScheme = "grpcs"
+)
+
+// isGRPCURL tells whether a function is served over gRPC by the scheme of its URL
+func isGRPCURL(funcURL string) bool {
+	lowerURL := strings.ToLower(funcURL)
+	return strings.HasPrefix(lowerURL, grpcScheme+"://") || strings.HasPrefix(lowerURL, grpcTLSScheme+"://")
+}
+
+// grpcFunction calls a custom function with the CustomFunction service defined in api/ext/pb/function.proto. The
+// function URL is grpc://HOST:PORT[/NAME] in plain text, or grpcs://HOST:PORT[/NAME] over TLS, NAME is sent to the
+// service as the name of the function, and it's the name of the function definition by default.
+type grpcFunction struct {
+	name    string
+	conn    *grpc.ClientConn
+	client  pb.CustomFunctionClient
+	timeout time.Duration
+}
+
+// newGRPCFunction creates the connection to the service of function cf, tlsConfig applies if the URL scheme is
+// grpcs. The connection is established in background, and it's reconnected if it's broken.
+func newGRPCFunction(cf *pms.Function, tlsConfig *tls.Config, timeout time.Duration) (*grpcFunction, error) {
+	u, err := url.Parse(cf.FuncURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.CustomerFuncError, "invalid URL of customer function %q", cf.Name)
+	}
+	if u.Port() == "" {
+		return nil, errors.Errorf(errors.CustomerFuncError, "port is required in gRPC URL %q of customer function %q", cf.FuncURL, cf.Name)
+	}
+	name := strings.Trim(u.Path, "/")
+	if name == "" {
+		name = cf.Name
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if strings.ToLower(u.Scheme) == grpcTLSScheme {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	}
+	conn, err := grpc.Dial(u.Host, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.CustomerFuncError, "failed to connect to customer function %q", cf.Name)
+	}
+	return &grpcFunction{
+		name:    name,
+		conn:    conn,
+		client:  pb.NewCustomFunctionClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// call sends request to the function, and reports whether the error is worth retrying
+func (g *grpcFunction) call(ctx context.Context, request *ext.CustomerFunctionRequest) (interface{}, bool, error) {
+	params := make([]*structpb.Value, 0, len(request.Params))
+	for _, param := range request.Params {
+		value, err := toProtoValue(param)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, errors.CustomerFuncError, "unsupported parameter %v of customer function %q", param, g.name)
+		}
+		params = append(params, value)
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	resp, err := g.client.Call(ctx, &pb.FunctionRequest{Function: g.name, Params: params})
+	if err != nil {
+		log.Errorf("error happens when calling customer function %s over gRPC, err is: %v\n", g.name, err)
+		code := status.Code(err)
+		retryable := code == codes.Unavailable || code == codes.DeadlineExceeded || code == codes.ResourceExhausted
+		return nil, retryable, errors.Wrapf(err, errors.CustomerFuncError, "failed to do customer function request for customer function %q", g.name)
+	}
+	if resp.Error != "" {
+		log.Errorf("error in response from customer function %s, err is: %v\n", g.name, resp.Error)
+		return nil, false, errors.Errorf(errors.CustomerFuncError, "customer function %q returns error %q", g.name, resp.Error)
+	}
+	return fromProtoValue(resp.Result), false, nil
+}
+
+// close closes the connection, it's called by the remoteFunction once the function is closed and no call is in
+// progress
+func (g *grpcFunction) close() {
+	g.conn.Close()
+}
+
+// toProtoValue converts the value of a parameter to protobuf, values of other types than the ones decoded from
+// JSON are converted by their JSON encoding
+func toProtoValue(v interface{}) (*structpb.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}, nil
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}, nil
+	case float64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}, nil
+	case []interface{}:
+		list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(v))}
+		for _, elem := range v {
+			value, err := toProtoValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list.Values = append(list.Values, value)
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}, nil
+	case map[string]interface{}:
+		fields := make(map[string]*structpb.Value, len(v))
+		for key, elem := range v {
+			value, err := toProtoValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = value
+		}
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: fields}}}, nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		return nil, err
+	}
+	return toProtoValue(decoded)
+}
+
+// fromProtoValue converts a protobuf value to the same types as the result decoded from JSON
+func fromProtoValue(v *structpb.Value) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch kind := v.Kind.(type) {
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_NumberValue:
+		return kind.NumberValue
+	case *structpb.Value_ListValue:
+		list := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, elem := range kind.ListValue.GetValues() {
+			list = append(list, fromProtoValue(elem))
+		}
+		return list
+	case *structpb.Value_StructValue:
+		object := make(map[string]interface{}, len(kind.StructValue.GetFields()))
+		for key, elem := range kind.StructValue.GetFields() {
+			object[key] = fromProtoValue(elem)
+		}
+		return object
+	}
+	return nil
+}
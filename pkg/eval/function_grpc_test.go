@@ -0,0 +1,160 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/ext/pb"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+)
+
+// echoFunctionServer returns the name of the function and the parameters, function "fail" returns an error
+type echoFunctionServer struct {
+	calls int32
+}
+
+func (s *echoFunctionServer) Call(ctx context.Context, req *pb.FunctionRequest) (*pb.FunctionResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	if req.Function == "fail" {
+		return &pb.FunctionResponse{Error: "failed"}, nil
+	}
+	result := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"function": {Kind: &structpb.Value_StringValue{StringValue: req.Function}},
+		"params":   {Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{Values: req.Params}}},
+	}}
+	return &pb.FunctionResponse{Result: &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: result}}}, nil
+}
+
+func startFunctionServer(t *testing.T, opts ...grpc.ServerOption) (*grpc.Server, string, *echoFunctionServer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen:", err)
+	}
+	server := grpc.NewServer(opts...)
+	echo := &echoFunctionServer{}
+	pb.RegisterCustomFunctionServer(server, echo)
+	go server.Serve(listener)
+	return server, listener.Addr().String(), echo
+}
+
+func TestGRPCFunction(t *testing.T) {
+	server, addr, echo := startFunctionServer(t)
+	defer server.Stop()
+
+	f, err := newRemoteFunction(&pms.Function{Name: "isValid", FuncURL: "grpc://" + addr + "/echo"})
+	if err != nil {
+		t.Fatal("failed to create gRPC function:", err)
+	}
+	params := []interface{}{"alice", 3.0, true, nil, map[string]interface{}{"env": []interface{}{"prod"}}}
+	result, err := f.call("", &ext.CustomerFunctionRequest{Params: params})
+	want := map[string]interface{}{"function": "echo", "params": params}
+	if err != nil || !reflect.DeepEqual(result, want) {
+		t.Errorf("expected result %v, got %v, %v", want, result, err)
+	}
+
+	// The name of the function definition is sent if the URL has no path, and the delegator is never used
+	f, _ = newRemoteFunction(&pms.Function{Name: "fail", FuncURL: "grpc://" + addr, Retry: &pms.RetryPolicy{MaxRetries: 2, BackoffMS: 1}})
+	echo.calls = 0
+	if _, err := f.call("http://localhost:1/delegator", &ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError || echo.calls != 1 {
+		t.Errorf("expected customer function error without retries, got %v after %d calls", err, echo.calls)
+	}
+
+	if _, err := newRemoteFunction(&pms.Function{Name: "noport", FuncURL: "grpc://localhost/echo"}); errors.Code(err) != errors.CustomerFuncError {
+		t.Errorf("expected error of gRPC URL without port, got %v", err)
+	}
+}
+
+func TestReplacedGRPCFunctionIsClosed(t *testing.T) {
+	server, addr, _ := startFunctionServer(t)
+	defer server.Stop()
+
+	f, err := newRemoteFunction(&pms.Function{Name: "echo", FuncURL: "grpc://" + addr})
+	if err != nil {
+		t.Fatal("failed to create gRPC function:", err)
+	}
+	f.close()
+	if state := f.grpc.conn.GetState(); state != connectivity.Shutdown {
+		t.Errorf("connection of the closed function should be shut down, got %v", state)
+	}
+
+	fs := NewRuntimePolicyStore()
+	fs.init(&pms.PolicyStore{Functions: []*pms.Function{{Name: "echo", FuncURL: "grpc://" + addr}}}, "")
+	old := fs.Functions["echo"]
+	if _, err := old("alice"); err != nil {
+		t.Fatal("failed to call gRPC function:", err)
+	}
+	fs.addFunction(&pms.Function{Name: "echo", FuncURL: "grpc://" + addr + "/echo2"})
+	if _, err := old("alice"); errors.Code(err) != errors.CustomerFuncError || !strings.Contains(err.Error(), "unloaded") {
+		t.Errorf("replaced gRPC function should be closed, got %v", err)
+	}
+	if _, err := fs.Functions["echo"]("alice"); err != nil {
+		t.Errorf("failed to call the new gRPC function: %v", err)
+	}
+}
+
+func TestGRPCFunctionTLS(t *testing.T) {
+	// The certificate of httptest servers is valid for 127.0.0.1
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	cert, ca := ts.TLS.Certificates[0], pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	ts.Close()
+
+	server, addr, _ := startFunctionServer(t, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}})))
+	defer server.Stop()
+
+	f, err := newRemoteFunction(&pms.Function{Name: "echo", FuncURL: "grpcs://" + addr, CA: string(ca)})
+	if err != nil {
+		t.Fatal("failed to create gRPC function:", err)
+	}
+	if result, err := f.call("", &ext.CustomerFunctionRequest{Params: []interface{}{"alice"}}); err != nil ||
+		!reflect.DeepEqual(result.(map[string]interface{})["params"], []interface{}{"alice"}) {
+		t.Errorf("unexpected result %v, %v", result, err)
+	}
+
+	// Plain text calls fail, and are retried as the service is unavailable
+	f, _ = newRemoteFunction(&pms.Function{Name: "echo", FuncURL: "grpc://" + addr})
+	if _, retryable, err := f.grpc.call(context.Background(), &ext.CustomerFunctionRequest{}); err == nil || !retryable {
+		t.Errorf("expected retryable error of plain text call, got %v, %v", retryable, err)
+	}
+}
+
+func TestProtoValues(t *testing.T) {
+	type point struct {
+		X int `json:"x"`
+	}
+	values := map[interface{}]interface{}{
+		"s":         "s",
+		1.5:         1.5,
+		false:       false,
+		point{X: 1}: map[string]interface{}{"x": 1.0},
+	}
+	for value, want := range values {
+		v, err := toProtoValue(value)
+		if err != nil {
+			t.Errorf("failed to convert %v: %v", value, err)
+			continue
+		}
+		if got := fromProtoValue(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+	if _, err := toProtoValue(func() {}); err == nil {
+		t.Error("functions should not be converted")
+	}
+}
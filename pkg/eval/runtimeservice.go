@@ -5,10 +5,15 @@ package eval
 
 import (
 	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/oracle/speedle/3rdparty/github.com/Knetic/govaluate"
 	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/eval/function"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,15 +22,22 @@ type RuntimePolicyStore struct {
 	Functions           map[string]govaluate.ExpressionFunction
 	RuntimeServices     map[string]*RuntimeService
 	FunctionResultCache *FuncResultCache
-	FuncSvcEndpoint     string //endpoint in sphinx side to call external customer function
+	// funcSvcEndpoint is the endpoint in sphinx side to call external customer function, it's read by customer
+	// functions on every call without the lock of the store
+	funcSvcEndpoint atomic.Value
+	// functionDefs are the definitions of customer functions, to tell whether functions are changed on sync
+	functionDefs map[string]*pms.Function
+	// functionClosers release the resources of customer functions once they're replaced or deleted
+	functionClosers map[string]func()
 }
 
 func NewRuntimePolicyStore() *RuntimePolicyStore {
 	return &RuntimePolicyStore{
 		RuntimeServices: make(map[string]*RuntimeService),
-		FunctionResultCache: &FuncResultCache{
-			Results: make(map[string]FuncResult),
-		},
+		functionDefs:    make(map[string]*pms.Function),
+		functionClosers: make(map[string]func()),
+		FunctionResultCache: newFuncResultCacheWithBackend(
+			newMemoryResultBackend(&cfg.FunctionResultCacheConfig{})),
 	}
 }
 
@@ -36,6 +48,8 @@ type RuntimeService struct {
 	PoliciesCache     *PolicyCacheData
 	RolePoliciesCache *RolePolicyCacheData
 	Functions         map[string]govaluate.ExpressionFunction
+	// Location is the time zone of the request time attributes, the local time zone is used if it's nil
+	Location *time.Location
 }
 
 func NewRuntimeService() *RuntimeService {
@@ -47,21 +61,34 @@ func NewRuntimeService() *RuntimeService {
 
 func (rtps *RuntimePolicyStore) init(ps *pms.PolicyStore, funcSvcEndpoint string) {
 	if funcSvcEndpoint != "" {
-		rtps.FuncSvcEndpoint = funcSvcEndpoint
+		rtps.funcSvcEndpoint.Store(funcSvcEndpoint)
 	}
 	// No need to lock, because this is a init method, evaluator should not be ready at this point
-	rtps.Functions = convertFunctions(ps.Functions, rtps.FunctionResultCache, &rtps.FuncSvcEndpoint)
+	rtps.Functions, rtps.functionClosers = convertFunctions(ps.Functions, rtps.FunctionResultCache, rtps.FuncSvcEndpoint)
+	rtps.functionDefs = functionDefs(ps.Functions)
 	for _, service := range ps.Services {
 		rtps.RuntimeServices[service.Name] = convertService(service, rtps.Functions)
 	}
 }
 
+// FuncSvcEndpoint returns the endpoint of customer function delegator, it's empty if customer functions are called
+// directly
+func (rtps *RuntimePolicyStore) FuncSvcEndpoint() string {
+	endpoint, _ := rtps.funcSvcEndpoint.Load().(string)
+	return endpoint
+}
+
+func (rtps *RuntimePolicyStore) setFuncSvcEndpoint(funcSvcEndpoint string) {
+	// Customer functions get the endpoint on every call, so the new value takes effect for the next call
+	rtps.funcSvcEndpoint.Store(funcSvcEndpoint)
+}
+
 func (rtps *RuntimePolicyStore) reloadPolicyStore(ps *pms.PolicyStore) {
-	// Clear all cached data first
-	fncsResultCache := FuncResultCache{
-		Results: make(map[string]FuncResult),
-	}
-	functions := convertFunctions(ps.Functions, &fncsResultCache, &rtps.FuncSvcEndpoint)
+	rtps.RLock()
+	oldDefs := rtps.functionDefs
+	rtps.RUnlock()
+	functions, closers := convertFunctions(ps.Functions, rtps.FunctionResultCache, rtps.FuncSvcEndpoint)
+	newDefs := functionDefs(ps.Functions)
 	services := make(map[string]*RuntimeService)
 
 	for _, service := range ps.Services {
@@ -71,9 +98,61 @@ func (rtps *RuntimePolicyStore) reloadPolicyStore(ps *pms.PolicyStore) {
 	// New cache items are ready here, replace all the caches.
 	rtps.Lock()
 	defer rtps.Unlock()
+	oldClosers := rtps.functionClosers
 	rtps.Functions = functions
+	rtps.functionDefs = newDefs
+	rtps.functionClosers = closers
 	rtps.RuntimeServices = services
-	rtps.FunctionResultCache = &fncsResultCache
+	// All the functions are loaded again, the old ones are not referred by the new conditions
+	for _, closeFunc := range oldClosers {
+		closeFunc()
+	}
+	// The results of unchanged functions are kept, as their namespaces are not changed
+	for name, def := range oldDefs {
+		if newDef, ok := newDefs[name]; !ok || !reflect.DeepEqual(def, newDef) {
+			rtps.FunctionResultCache.DeleteFromCache(def)
+		}
+	}
+}
+
+// syncPolicyStore updates the runtime cache to the policies in ps. Policies are identified by their IDs,
+// only the ones which are added, removed or changed are updated, and the compiled conditions of the others are kept.
+// All the conditions are compiled again if customer functions are changed.
+func (rtps *RuntimePolicyStore) syncPolicyStore(ps *pms.PolicyStore) {
+	rtps.RLock()
+	functionsChanged := !reflect.DeepEqual(rtps.functionDefs, functionDefs(ps.Functions))
+	rtps.RUnlock()
+	if functionsChanged {
+		log.Info("Customer functions are changed, reload all the policies.")
+		rtps.reloadPolicyStore(ps)
+		return
+	}
+
+	rtps.Lock()
+	defer rtps.Unlock()
+	serviceNames := make(map[string]bool, len(ps.Services))
+	for _, service := range ps.Services {
+		serviceNames[service.Name] = true
+		rtService, ok := rtps.RuntimeServices[service.Name]
+		if !ok || rtService.Type != service.Type {
+			rtps.RuntimeServices[service.Name] = convertService(service, rtps.Functions)
+			continue
+		}
+		rtService.sync(service, rtps.Functions)
+	}
+	for serviceName := range rtps.RuntimeServices {
+		if !serviceNames[serviceName] {
+			delete(rtps.RuntimeServices, serviceName)
+		}
+	}
+}
+
+func functionDefs(functions []*pms.Function) map[string]*pms.Function {
+	defs := make(map[string]*pms.Function, len(functions))
+	for _, function := range functions {
+		defs[function.Name] = function
+	}
+	return defs
 }
 
 func (rtps *RuntimePolicyStore) addService(service *pms.Service) {
@@ -212,9 +291,21 @@ func (rtps *RuntimePolicyStore) addFunction(function *pms.Function) {
 	rtps.Lock()
 	defer rtps.Unlock()
 
-	ef, err := rtps.FunctionResultCache.generateCustomerExpressionFunction(&rtps.FuncSvcEndpoint, function)
+	ef, closeFunc, err := rtps.FunctionResultCache.generateCustomerExpressionFunction(rtps.FuncSvcEndpoint, function)
 	if err == nil {
+		if old, ok := rtps.functionDefs[function.Name]; ok && !reflect.DeepEqual(old, function) {
+			rtps.FunctionResultCache.DeleteFromCache(old)
+		}
+		if oldClose, ok := rtps.functionClosers[function.Name]; ok {
+			// The conditions referring to the replaced function are compiled again with the new one
+			for _, svc := range rtps.RuntimeServices {
+				svc.clearConditionsCache()
+			}
+			oldClose()
+		}
 		rtps.Functions[function.Name] = ef
+		rtps.functionDefs[function.Name] = function
+		rtps.functionClosers[function.Name] = closeFunc
 		log.Infof("loaded customer function %q.\n", function.Name)
 	} else {
 		log.Errorf("fail to load customer function %q, err is %v. \n", function.Name, err)
@@ -230,8 +321,15 @@ func (rtps *RuntimePolicyStore) delFunc_rtps(name string) {
 	rtps.Lock()
 	defer rtps.Unlock()
 
+	if def, ok := rtps.functionDefs[name]; ok {
+		rtps.FunctionResultCache.DeleteFromCache(def)
+	}
+	if closeFunc, ok := rtps.functionClosers[name]; ok {
+		closeFunc()
+	}
 	delete(rtps.Functions, name)
-	rtps.FunctionResultCache.DeleteFromCache(name)
+	delete(rtps.functionDefs, name)
+	delete(rtps.functionClosers, name)
 }
 
 func (rtps *RuntimePolicyStore) delFunc_rtsvc() {
@@ -278,6 +376,7 @@ func convertService(service *pms.Service,
 		PoliciesCache:     NewPolicyCacheData(),
 		RolePoliciesCache: NewRolePolicyCacheData(),
 		Functions:         functions,
+		Location:          serviceLocation(service),
 	}
 	for _, policy := range service.Policies {
 		condition, _ := compileCondition(policy.Condition, functions)
@@ -291,8 +390,23 @@ func convertService(service *pms.Service,
 	return &rtService
 }
 
-func convertFunctions(functions []*pms.Function, resultCache *FuncResultCache, funcSvcEndpoint *string) map[string]govaluate.ExpressionFunction {
+// serviceLocation returns the time zone specified in the metadata of service, or nil if it's not specified or unknown
+func serviceLocation(service *pms.Service) *time.Location {
+	name, ok := service.Metadata[pms.ServiceTimezoneKey]
+	if !ok {
+		return nil
+	}
+	loc, err := function.LoadLocation(name)
+	if err != nil {
+		log.Errorf("Unknown time zone %q of service %q, the local time zone is used.", name, service.Name)
+		return nil
+	}
+	return loc
+}
+
+func convertFunctions(functions []*pms.Function, resultCache *FuncResultCache, funcSvcEndpoint func() string) (map[string]govaluate.ExpressionFunction, map[string]func()) {
 	funcs := map[string]govaluate.ExpressionFunction{}
+	closers := map[string]func(){}
 
 	//loading builtin functions
 	for key, value := range builtinFunctions {
@@ -301,15 +415,55 @@ func convertFunctions(functions []*pms.Function, resultCache *FuncResultCache, f
 
 	//loading customer functions
 	for _, function := range functions {
-		ef, err := resultCache.generateCustomerExpressionFunction(funcSvcEndpoint, function)
+		ef, closeFunc, err := resultCache.generateCustomerExpressionFunction(funcSvcEndpoint, function)
 		if err == nil {
 			funcs[function.Name] = ef
+			closers[function.Name] = closeFunc
 			log.Infof("loaded customer function %q.\n", function.Name)
 		} else {
 			log.Errorf("fail to load customer function %q, err is %v. \n", function.Name, err)
 		}
 	}
-	return funcs
+	return funcs, closers
+}
+
+// sync updates the cached policies and role policies of the service to the ones in service, the caller holds the lock of runtime policy store
+func (svc *RuntimeService) sync(service *pms.Service, functions map[string]govaluate.ExpressionFunction) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.Location = serviceLocation(service)
+	policies := make(map[string]*pms.Policy, len(service.Policies))
+	for _, policy := range service.Policies {
+		policies[policy.ID] = policy
+	}
+	for id, cached := range svc.PoliciesCache.PolicyMap {
+		if policy, ok := policies[id]; !ok || !reflect.DeepEqual(cached, policy) {
+			svc.PoliciesCache.DeletePolicyFromCache(id)
+		}
+	}
+	for id, policy := range policies {
+		if _, ok := svc.PoliciesCache.PolicyMap[id]; !ok {
+			condition, _ := compileCondition(policy.Condition, functions)
+			svc.PoliciesCache.AddPolicyToCache(policy, condition)
+		}
+	}
+
+	rolePolicies := make(map[string]*pms.RolePolicy, len(service.RolePolicies))
+	for _, rolePolicy := range service.RolePolicies {
+		rolePolicies[rolePolicy.ID] = rolePolicy
+	}
+	for id, cached := range svc.RolePoliciesCache.PolicyMap {
+		if rolePolicy, ok := rolePolicies[id]; !ok || !reflect.DeepEqual(cached, rolePolicy) {
+			svc.RolePoliciesCache.DeleteRolePolicyFromCache(id)
+		}
+	}
+	for id, rolePolicy := range rolePolicies {
+		if _, ok := svc.RolePoliciesCache.PolicyMap[id]; !ok {
+			condition, _ := compileCondition(rolePolicy.Condition, functions)
+			svc.RolePoliciesCache.AddRolePolicyToCache(rolePolicy, condition)
+		}
+	}
 }
 
 func (svc *RuntimeService) clearConditionsCache() {
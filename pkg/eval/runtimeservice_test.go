@@ -0,0 +1,58 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+)
+
+func TestSyncPolicyStore(t *testing.T) {
+	newPolicy := func(id, user string) *pms.Policy {
+		return &pms.Policy{
+			ID:          id,
+			Effect:      "grant",
+			Principals:  [][]string{{"user:" + user}},
+			Permissions: []*pms.Permission{{Resource: "/books", Actions: []string{"read"}}},
+		}
+	}
+	kept := newPolicy("p1", "alice")
+	ps := &pms.PolicyStore{Services: []*pms.Service{
+		{Name: "app1", Policies: []*pms.Policy{kept, newPolicy("p2", "bob")}},
+		{Name: "app2", Policies: []*pms.Policy{newPolicy("p1", "carl")}},
+	}}
+	rtps := NewRuntimePolicyStore()
+	rtps.init(ps, "")
+
+	changed := newPolicy("p2", "dave")
+	rtps.syncPolicyStore(&pms.PolicyStore{Services: []*pms.Service{
+		{Name: "app1", Policies: []*pms.Policy{newPolicy("p1", "alice"), changed, newPolicy("p3", "eve")},
+			RolePolicies: []*pms.RolePolicy{{ID: "rp1", Effect: "grant", Roles: []string{"reader"}, Principals: []string{"user:eve"}}}},
+	}})
+
+	if _, ok := rtps.RuntimeServices["app2"]; ok {
+		t.Error("service app2 should be removed")
+	}
+	app1, ok := rtps.RuntimeServices["app1"]
+	if !ok {
+		t.Fatal("service app1 should be kept")
+	}
+	policies := app1.PoliciesCache.PolicyMap
+	if len(policies) != 3 {
+		t.Fatalf("expect 3 policies, but got %d", len(policies))
+	}
+	if policies["p1"] != kept {
+		t.Error("unchanged policy p1 should be kept in cache")
+	}
+	if policies["p2"] != changed {
+		t.Error("changed policy p2 should be updated in cache")
+	}
+	if _, ok := policies["p3"]; !ok {
+		t.Error("policy p3 should be added to cache")
+	}
+	if _, ok := app1.RolePoliciesCache.PolicyMap["rp1"]; !ok {
+		t.Error("role policy rp1 should be added to cache")
+	}
+}
@@ -0,0 +1,296 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// MissingTenantRecheckInterval is how often a tenant is looked up again while its policies are missing
+var MissingTenantRecheckInterval = 10 * time.Second
+
+// TenantEvaluators creates and caches the evaluators of tenants, each evaluator evaluates requests against
+// the policies of its own tenant. Asserter and function service settings are shared by all the evaluators.
+// Tenants are looked up and loaded from the backend without holding the lock, so a slow backend only delays
+// the requests of the tenants being looked up.
+type TenantEvaluators struct {
+	sync.RWMutex
+	enableWatch      bool
+	tenants          pms.TenantManager
+	defaultEvaluator InternalEvaluator
+	evaluators       map[string]InternalEvaluator
+	// missing are the tenants whose policies are missing from the backend while the tenants still exist, keyed
+	// by when they are last looked up
+	missing map[string]time.Time
+	// loading are the tenants being loaded, the channel is closed once the load is done
+	loading         map[string]chan struct{}
+	asserterFunc    func(ctx *adsapi.RequestContext) error
+	assertionCache  *assertion.CachingAsserter
+	funcSvcEndpoint string
+	typeCheck       bool
+	timeOverride    bool
+	resultCache     *cfg.FunctionResultCacheConfig
+}
+
+// NewTenantEvaluators creates the evaluators of the tenants managed by tenants, defaultEvaluator evaluates
+// the requests of the default tenant. tenants is nil if the policy store doesn't support multi-tenancy.
+func NewTenantEvaluators(conf *cfg.Config, defaultEvaluator InternalEvaluator, tenants pms.TenantManager) *TenantEvaluators {
+	return &TenantEvaluators{
+		enableWatch:      conf.EnableWatch,
+		tenants:          tenants,
+		defaultEvaluator: defaultEvaluator,
+		evaluators:       make(map[string]InternalEvaluator),
+		missing:          make(map[string]time.Time),
+		loading:          make(map[string]chan struct{}),
+		funcSvcEndpoint:  conf.FuncsvcEndpoint,
+		typeCheck:        conf.TypeCheckConditions,
+		timeOverride:     conf.AllowRequestTimeOverride,
+		resultCache:      conf.FunctionResultCacheConfig,
+	}
+}
+
+// Evaluator returns the evaluator of a tenant, the evaluator of the default tenant is returned if tenantName is empty
+func (t *TenantEvaluators) Evaluator(tenantName string) (InternalEvaluator, error) {
+	if len(tenantName) == 0 || tenantName == pms.DefaultTenant {
+		return t.defaultEvaluator, nil
+	}
+	if t.tenants == nil {
+		return nil, errors.Errorf(errors.InvalidRequest, "the policy store doesn't support multi-tenancy, tenant %q is not found", tenantName)
+	}
+
+	t.RLock()
+	evaluator, ok := t.evaluators[tenantName]
+	checkedAt, missing := t.missing[tenantName]
+	t.RUnlock()
+	if ok {
+		if !store.IsRemoved(evaluator) {
+			if missing {
+				t.found(tenantName, evaluator)
+			}
+			return evaluator, nil
+		}
+		// Policies may be missing for a while, e.g. when the policy file is replaced, so the last good
+		// policies are served until the tenant is deleted
+		if missing && time.Since(checkedAt) < MissingTenantRecheckInterval {
+			return evaluator, nil
+		}
+		if t.tenantExists(tenantName, evaluator) {
+			return evaluator, nil
+		}
+	}
+	return t.load(tenantName)
+}
+
+// found forgets that the policies of a tenant are missing once they are loaded again
+func (t *TenantEvaluators) found(tenantName string, evaluator InternalEvaluator) {
+	t.Lock()
+	defer t.Unlock()
+	if t.evaluators[tenantName] == evaluator {
+		delete(t.missing, tenantName)
+	}
+}
+
+// tenantExists looks up a tenant whose policies are missing, the evaluator of the tenant is unloaded if the tenant
+// is deleted. The lookup is recorded before it's done, so the other requests keep using the evaluator meanwhile.
+func (t *TenantEvaluators) tenantExists(tenantName string, evaluator InternalEvaluator) bool {
+	t.Lock()
+	if t.evaluators[tenantName] != evaluator {
+		t.Unlock()
+		return true
+	}
+	_, reported := t.missing[tenantName]
+	t.missing[tenantName] = time.Now()
+	t.Unlock()
+
+	if _, err := t.tenants.GetTenant(tenantName); errors.Code(err) != errors.EntityNotFound {
+		if !reported {
+			log.Warningf("Policies of tenant %q are missing, serving the last loaded ones, err: %v.", tenantName, evaluatorHealth(evaluator))
+		}
+		return true
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.evaluators[tenantName] == evaluator {
+		// The tenant is deleted, its policies are unloaded, and the tenant is looked up again
+		log.Infof("Policies of tenant %q are removed, unloading them.", tenantName)
+		delete(t.missing, tenantName)
+		if watcher, ok := evaluator.(interface{ StopWatch() }); ok {
+			watcher.StopWatch()
+		}
+		delete(t.evaluators, tenantName)
+	}
+	return false
+}
+
+// load loads the evaluator of a tenant, a tenant is loaded by one request at a time, the other requests of the
+// tenant wait for it
+func (t *TenantEvaluators) load(tenantName string) (InternalEvaluator, error) {
+	t.Lock()
+	for {
+		if evaluator, ok := t.evaluators[tenantName]; ok {
+			t.Unlock()
+			return evaluator, nil
+		}
+		loading, ok := t.loading[tenantName]
+		if !ok {
+			break
+		}
+		t.Unlock()
+		<-loading
+		t.Lock()
+	}
+	loading := make(chan struct{})
+	t.loading[tenantName] = loading
+	conf := &cfg.Config{EnableWatch: t.enableWatch, FuncsvcEndpoint: t.funcSvcEndpoint, TypeCheckConditions: t.typeCheck,
+		AllowRequestTimeOverride: t.timeOverride, FunctionResultCacheConfig: t.resultCache}
+	t.Unlock()
+
+	evaluator, err := t.newEvaluator(tenantName, conf)
+
+	t.Lock()
+	defer t.Unlock()
+	delete(t.loading, tenantName)
+	close(loading)
+	if err != nil {
+		return nil, err
+	}
+	// The settings may be changed while the tenant is loaded
+	evaluator.SetAsserterFunc(t.asserterFunc)
+	evaluator.SetFuncSvcEndpoint(t.funcSvcEndpoint)
+	t.evaluators[tenantName] = evaluator
+	return evaluator, nil
+}
+
+func (t *TenantEvaluators) newEvaluator(tenantName string, conf *cfg.Config) (InternalEvaluator, error) {
+	ps, err := t.tenants.TenantStore(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Loading policies of tenant %q.", tenantName)
+	return NewWithStore(conf, ps)
+}
+
+// SetAsserterFunc sets the token asserter of all the evaluators
+func (t *TenantEvaluators) SetAsserterFunc(f func(ctx *adsapi.RequestContext) error) {
+	t.Lock()
+	defer t.Unlock()
+	t.asserterFunc = f
+	t.defaultEvaluator.SetAsserterFunc(f)
+	for _, evaluator := range t.evaluators {
+		evaluator.SetAsserterFunc(f)
+	}
+}
+
+// SetAssertionCache sets the cache of the token asserter, it's nil if assertion results are not cached
+func (t *TenantEvaluators) SetAssertionCache(cache *assertion.CachingAsserter) {
+	t.Lock()
+	defer t.Unlock()
+	t.assertionCache = cache
+}
+
+// AssertionCache returns the cache of the token asserter, or nil if assertion results are not cached
+func (t *TenantEvaluators) AssertionCache() *assertion.CachingAsserter {
+	t.RLock()
+	defer t.RUnlock()
+	return t.assertionCache
+}
+
+// SetFuncSvcEndpoint sets the function service endpoint of all the evaluators
+func (t *TenantEvaluators) SetFuncSvcEndpoint(endpoint string) {
+	t.Lock()
+	defer t.Unlock()
+	t.funcSvcEndpoint = endpoint
+	t.defaultEvaluator.SetFuncSvcEndpoint(endpoint)
+	for _, evaluator := range t.evaluators {
+		evaluator.SetFuncSvcEndpoint(endpoint)
+	}
+}
+
+// Health returns the problems of the evaluators keyed by tenant name, it is empty if all the evaluators are healthy
+func (t *TenantEvaluators) Health() map[string]error {
+	problems := make(map[string]error)
+	if err := evaluatorHealth(t.defaultEvaluator); err != nil {
+		problems[pms.DefaultTenant] = err
+	}
+	t.RLock()
+	defer t.RUnlock()
+	for tenantName, evaluator := range t.evaluators {
+		if err := evaluatorHealth(evaluator); err != nil {
+			problems[tenantName] = err
+		}
+	}
+	return problems
+}
+
+func evaluatorHealth(evaluator InternalEvaluator) error {
+	if reporter, ok := evaluator.(store.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
+
+// Revisions returns the revisions of the policies being evaluated keyed by tenant name, tenants whose policy stores
+// can't tell the revision are omitted
+func (t *TenantEvaluators) Revisions() map[string]int64 {
+	revisions := make(map[string]int64)
+	if revision, ok := evaluatorRevision(t.defaultEvaluator); ok {
+		revisions[pms.DefaultTenant] = revision
+	}
+	t.RLock()
+	defer t.RUnlock()
+	for tenantName, evaluator := range t.evaluators {
+		if revision, ok := evaluatorRevision(evaluator); ok {
+			revisions[tenantName] = revision
+		}
+	}
+	return revisions
+}
+
+func evaluatorRevision(evaluator InternalEvaluator) (int64, bool) {
+	if reporter, ok := evaluator.(store.RevisionReporter); ok {
+		return reporter.Revision()
+	}
+	return 0, false
+}
+
+// FunctionResultCacheStats returns the statistics of the function result caches keyed by tenant name, every
+// tenant has its own cache
+func (t *TenantEvaluators) FunctionResultCacheStats() map[string]FunctionResultCacheStats {
+	stats := make(map[string]FunctionResultCacheStats)
+	if reporter, ok := t.defaultEvaluator.(FunctionResultCacheReporter); ok {
+		stats[pms.DefaultTenant] = reporter.FunctionResultCacheStats()
+	}
+	t.RLock()
+	defer t.RUnlock()
+	for tenantName, evaluator := range t.evaluators {
+		if reporter, ok := evaluator.(FunctionResultCacheReporter); ok {
+			stats[tenantName] = reporter.FunctionResultCacheStats()
+		}
+	}
+	return stats
+}
+
+type evaluatorKey struct{}
+
+// WithEvaluator returns a copy of ctx carrying the evaluator of the tenant a request is scoped to
+func WithEvaluator(ctx context.Context, evaluator InternalEvaluator) context.Context {
+	return context.WithValue(ctx, evaluatorKey{}, evaluator)
+}
+
+// EvaluatorFrom returns the evaluator carried by ctx
+func EvaluatorFrom(ctx context.Context) (InternalEvaluator, bool) {
+	evaluator, ok := ctx.Value(evaluatorKey{}).(InternalEvaluator)
+	return evaluator, ok
+}
@@ -0,0 +1,146 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+func TestRemovedTenantIsUnloaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-tenant")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ps, err := store.NewStore("file", map[string]interface{}{"FileLocation": filepath.Join(dir, "ps.json")})
+	if err != nil {
+		t.Fatal("fail to new file store:", err)
+	}
+	tenants, err := store.NewTenantManager(ps)
+	if err != nil {
+		t.Fatal("fail to new tenant manager:", err)
+	}
+	if err := tenants.CreateTenant(&pms.Tenant{Name: "t1"}); err != nil {
+		t.Fatal("fail to create tenant:", err)
+	}
+	conf := &cfg.Config{EnableWatch: true}
+	defaultEvaluator, err := NewWithStore(conf, ps)
+	if err != nil {
+		t.Fatal("fail to new evaluator:", err)
+	}
+	defer defaultEvaluator.(*PolicyEvalImpl).StopWatch()
+	evaluators := NewTenantEvaluators(conf, defaultEvaluator, tenants)
+	if _, err := evaluators.Evaluator("t1"); err != nil {
+		t.Fatal("fail to get evaluator of tenant:", err)
+	}
+
+	// Tenant is deleted by another process, e.g. PMS
+	other, _ := store.NewStore("file", map[string]interface{}{"FileLocation": filepath.Join(dir, "ps.json")})
+	otherTenants, _ := store.NewTenantManager(other)
+	if err := otherTenants.DeleteTenant("t1"); err != nil {
+		t.Fatal("fail to delete tenant:", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if len(evaluators.Health()) != 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if problems := evaluators.Health(); errors.Code(problems["t1"]) != errors.EntityNotFound {
+		t.Fatalf("removed policies of tenant should be reported, but got %v", problems)
+	}
+	if _, err := evaluators.Evaluator("t1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect %s error for deleted tenant, but got %v", errors.EntityNotFound, err)
+	}
+	if problems := evaluators.Health(); len(problems) != 0 {
+		t.Errorf("evaluator of deleted tenant should be unloaded, but got %v", problems)
+	}
+}
+
+func TestMissingTenantPoliciesAreKept(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-tenant")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ps, err := store.NewStore("file", map[string]interface{}{"FileLocation": filepath.Join(dir, "ps.json")})
+	if err != nil {
+		t.Fatal("fail to new file store:", err)
+	}
+	manager, err := store.NewTenantManager(ps)
+	if err != nil {
+		t.Fatal("fail to new tenant manager:", err)
+	}
+	tenants := &countingTenants{TenantManager: manager}
+	if err := tenants.CreateTenant(&pms.Tenant{Name: "t1"}); err != nil {
+		t.Fatal("fail to create tenant:", err)
+	}
+	conf := &cfg.Config{EnableWatch: true}
+	defaultEvaluator, err := NewWithStore(conf, ps)
+	if err != nil {
+		t.Fatal("fail to new evaluator:", err)
+	}
+	defer defaultEvaluator.(*PolicyEvalImpl).StopWatch()
+	evaluators := NewTenantEvaluators(conf, defaultEvaluator, tenants)
+	evaluator, err := evaluators.Evaluator("t1")
+	if err != nil {
+		t.Fatal("fail to get evaluator of tenant:", err)
+	}
+
+	// The policy file of the tenant is missing, while the tenant still exists
+	if err := os.Remove(filepath.Join(dir, "speedle_tenants", "t1.json")); err != nil {
+		t.Fatal("fail to remove policy file of tenant:", err)
+	}
+	for i := 0; i < 50; i++ {
+		if len(evaluators.Health()) != 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if problems := evaluators.Health(); errors.Code(problems["t1"]) != errors.EntityNotFound {
+		t.Fatalf("missing policies of tenant should be reported, but got %v", problems)
+	}
+	for i := 0; i < 3; i++ {
+		if kept, err := evaluators.Evaluator("t1"); err != nil || kept != evaluator {
+			t.Errorf("the last loaded policies of tenant should be kept, but got %v, err: %v", kept, err)
+		}
+	}
+	// The tenant is looked up once within the recheck interval
+	if n := atomic.LoadInt32(&tenants.lookups); n != 1 {
+		t.Errorf("tenant should be looked up once, but looked up %d times", n)
+	}
+	evaluators.Lock()
+	evaluators.missing["t1"] = time.Now().Add(-MissingTenantRecheckInterval)
+	evaluators.Unlock()
+	if kept, err := evaluators.Evaluator("t1"); err != nil || kept != evaluator {
+		t.Errorf("the last loaded policies of tenant should be kept, but got %v, err: %v", kept, err)
+	}
+	if n := atomic.LoadInt32(&tenants.lookups); n != 2 {
+		t.Errorf("tenant should be looked up again after the recheck interval, but looked up %d times", n)
+	}
+}
+
+// countingTenants counts the lookups of tenants
+type countingTenants struct {
+	pms.TenantManager
+	lookups int32
+}
+
+func (c *countingTenants) GetTenant(tenantName string) (*pms.Tenant, error) {
+	atomic.AddInt32(&c.lookups, 1)
+	return c.TenantManager.GetTenant(tenantName)
+}
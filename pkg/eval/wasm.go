@@ -0,0 +1,239 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	goruntime "runtime"
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	defaultWasmMaxMemoryMB = 16
+	defaultWasmCallTimeout = time.Second
+	wasmPageSize           = 64 * 1024
+	maxWasmMemoryPages     = 65536
+
+	wasmMemoryExport   = "memory"
+	wasmAllocExport    = "alloc"
+	wasmEvaluateExport = "evaluate"
+)
+
+// wasmFunction runs a custom function compiled to WebAssembly. Besides its memory, the module exports
+//
+//	alloc(size i32) i32              returns the address of size bytes for the request
+//	evaluate(addr i32, size i32) i64 evaluates the JSON CustomerFunctionRequest at addr, and returns the
+//	                                 address and size of the JSON CustomerFunctionResponse as addr<<32 | size
+//
+// WASI modules are supported, like the ones built by TinyGo or Rust, but they have no access to files or network.
+// Every call runs in a new instance of the module, so that calls are isolated from each other and run concurrently.
+// A call is bounded by its timeout and memory limit, and at most maxConcurrency calls run at the same time, so that
+// the calls of a function can't take all the CPUs. The calls beyond it wait for a slot within their timeout.
+type wasmFunction struct {
+	name    string
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	timeout time.Duration
+	// slots has a slot for each running call
+	slots chan struct{}
+
+	// calls is the number of calls in progress, the runtime is closed by the last one once the function is closed
+	sync.Mutex
+	calls  int
+	closed bool
+}
+
+// newWasmFunction compiles the module of function cf with its memory limit
+func newWasmFunction(cf *pms.Function) (*wasmFunction, error) {
+	maxMemoryMB := defaultWasmMaxMemoryMB
+	timeout := defaultWasmCallTimeout
+	maxConcurrency := goruntime.NumCPU()
+	if cf.Limits != nil {
+		if cf.Limits.MaxMemoryMB > 0 {
+			maxMemoryMB = cf.Limits.MaxMemoryMB
+		}
+		if cf.Limits.TimeoutMS > 0 {
+			timeout = time.Duration(cf.Limits.TimeoutMS) * time.Millisecond
+		}
+		if cf.Limits.MaxConcurrency > 0 {
+			maxConcurrency = cf.Limits.MaxConcurrency
+		}
+	}
+	pages := maxMemoryMB * (1024 * 1024 / wasmPageSize)
+	if pages > maxWasmMemoryPages {
+		pages = maxWasmMemoryPages
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(pages)).
+		// Calls running out of time are interrupted, including the ones in infinite loops
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, errors.Wrapf(err, errors.CustomerFuncError, "failed to initialize WASI of wasm function %q", cf.Name)
+	}
+	module, err := runtime.CompileModule(ctx, cf.Module)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, errors.Wrapf(err, errors.CustomerFuncError, "failed to compile wasm function %q", cf.Name)
+	}
+	if err := checkWasmExports(module); err != nil {
+		runtime.Close(ctx)
+		return nil, errors.Wrapf(err, errors.CustomerFuncError, "invalid wasm function %q", cf.Name)
+	}
+
+	f := &wasmFunction{
+		name:    cf.Name,
+		runtime: runtime,
+		module:  module,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrency),
+	}
+	// The function is closed when it's replaced or deleted, the finalizer releases the compiled module of the
+	// functions dropped with their evaluators
+	goruntime.SetFinalizer(f, func(f *wasmFunction) {
+		f.runtime.Close(context.Background())
+	})
+	return f, nil
+}
+
+// close releases the compiled module, the calls in progress complete before it's released
+func (f *wasmFunction) close() {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	if f.calls == 0 {
+		f.runtime.Close(context.Background())
+	}
+}
+
+func (f *wasmFunction) acquire() bool {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return false
+	}
+	f.calls++
+	return true
+}
+
+func (f *wasmFunction) release() {
+	f.Lock()
+	defer f.Unlock()
+	f.calls--
+	if f.closed && f.calls == 0 {
+		f.runtime.Close(context.Background())
+	}
+}
+
+func checkWasmExports(module wazero.CompiledModule) error {
+	if _, ok := module.ExportedMemories()[wasmMemoryExport]; !ok {
+		return errors.Errorf(errors.CustomerFuncError, "%q is not exported", wasmMemoryExport)
+	}
+	signatures := map[string][2][]api.ValueType{
+		wasmAllocExport:    {{api.ValueTypeI32}, {api.ValueTypeI32}},
+		wasmEvaluateExport: {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+	}
+	functions := module.ExportedFunctions()
+	for name, signature := range signatures {
+		def, ok := functions[name]
+		if !ok {
+			return errors.Errorf(errors.CustomerFuncError, "function %q is not exported", name)
+		}
+		if !sameValueTypes(def.ParamTypes(), signature[0]) || !sameValueTypes(def.ResultTypes(), signature[1]) {
+			return errors.Errorf(errors.CustomerFuncError, "unexpected signature of function %q", name)
+		}
+	}
+	return nil
+}
+
+func sameValueTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// call evaluates request in a new instance of the module, the timeout includes the time waiting for a slot
+func (f *wasmFunction) call(request *ext.CustomerFunctionRequest) (interface{}, error) {
+	if !f.acquire() {
+		return nil, errors.Errorf(errors.CustomerFuncError, "wasm function %q is unloaded", f.name)
+	}
+	defer f.release()
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	select {
+	case f.slots <- struct{}{}:
+		defer func() { <-f.slots }()
+	case <-ctx.Done():
+		log.Errorf("wasm function %s is timed out after %v waiting for %d concurrent calls\n", f.name, f.timeout, cap(f.slots))
+		return nil, errors.Errorf(errors.CustomerFuncError, "wasm function %q is timed out after %v waiting for %d concurrent calls",
+			f.name, f.timeout, cap(f.slots))
+	}
+
+	instance, err := f.runtime.InstantiateModule(ctx, f.module, wazero.NewModuleConfig().
+		// Instances are anonymous, so that the module can be instantiated concurrently
+		WithName("").
+		// Reactor modules are initialized by _initialize, it's skipped if not exported
+		WithStartFunctions("_initialize").
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader))
+	if err != nil {
+		return nil, f.callError(ctx, err, "failed to instantiate")
+	}
+	defer instance.Close(context.Background())
+
+	results, err := instance.ExportedFunction(wasmAllocExport).Call(ctx, uint64(len(buf)))
+	if err != nil {
+		return nil, f.callError(ctx, err, "failed to allocate request of")
+	}
+	addr := uint32(results[0])
+	if !instance.Memory().Write(addr, buf) {
+		return nil, errors.Errorf(errors.CustomerFuncError, "address %d allocated by wasm function %q is out of memory", addr, f.name)
+	}
+	results, err = instance.ExportedFunction(wasmEvaluateExport).Call(ctx, uint64(addr), uint64(len(buf)))
+	if err != nil {
+		return nil, f.callError(ctx, err, "failed to call")
+	}
+	body, ok := instance.Memory().Read(uint32(results[0]>>32), uint32(results[0]))
+	if !ok {
+		return nil, errors.Errorf(errors.CustomerFuncError, "response of wasm function %q is out of memory", f.name)
+	}
+	// body is a view of the memory of the instance, it's decoded before the instance is closed
+	return parseFunctionResponse(body, &pms.Function{Name: f.name})
+}
+
+func (f *wasmFunction) callError(ctx context.Context, err error, action string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Errorf("wasm function %s is timed out after %v\n", f.name, f.timeout)
+		return errors.Errorf(errors.CustomerFuncError, "wasm function %q is timed out after %v", f.name, f.timeout)
+	}
+	log.Errorf("error happens when calling wasm function %s, err is: %v\n", f.name, err)
+	return errors.Wrapf(err, errors.CustomerFuncError, "%s wasm function %q", action, f.name)
+}
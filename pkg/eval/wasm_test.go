@@ -0,0 +1,158 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package eval
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oracle/speedle/api/ext"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// wasmSection encodes a section of a WebAssembly binary, all the sections of the test modules are shorter than 128 bytes
+func wasmSection(id byte, content ...byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+// wasmTestModule assembles a module with memoryPages pages of memory, and the functions alloc and evaluate. alloc
+// is a bump allocator from address 1024, and evaluate is evaluateBody with one i32 local. The data {"result":}
+// is at address 0.
+func wasmTestModule(memoryPages byte, evaluateBody ...byte) []byte {
+	alloc := []byte{
+		0x01, 0x01, 0x7f, // one i32 local
+		0x23, 0x00, 0x21, 0x01, // local 1 = heap
+		0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, // heap += size
+		0x20, 0x01, 0x0b, // return local 1
+	}
+	evaluate := append([]byte{0x01, 0x01, 0x7f}, evaluateBody...)
+	code := []byte{0x02, byte(len(alloc))}
+	code = append(code, alloc...)
+	code = append(code, byte(len(evaluate)))
+	code = append(code, evaluate...)
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// (i32) -> i32, and (i32, i32) -> i64
+	module = append(module, wasmSection(1, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e)...)
+	module = append(module, wasmSection(3, 0x02, 0x00, 0x01)...)
+	module = append(module, wasmSection(5, 0x01, 0x00, memoryPages)...)
+	// heap: mutable i32 global initialized to 1024
+	module = append(module, wasmSection(6, 0x01, 0x7f, 0x01, 0x41, 0x80, 0x08, 0x0b)...)
+	module = append(module, wasmSection(7, 0x03,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+		0x08, 'e', 'v', 'a', 'l', 'u', 'a', 't', 'e', 0x00, 0x01)...)
+	module = append(module, wasmSection(10, code...)...)
+	data := append([]byte{0x01, 0x00, 0x41, 0x00, 0x0b, 11}, []byte(`{"result":}`)...)
+	module = append(module, wasmSection(11, data...)...)
+	return module
+}
+
+// wasmEchoBody returns {"result":<request>}, so the result is the request object
+var wasmEchoBody = []byte{
+	0x20, 0x01, 0x41, 0x0b, 0x6a, 0x10, 0x00, 0x21, 0x02, // local 2 = alloc(size + 11)
+	0x20, 0x02, 0x41, 0x00, 0x41, 0x0a, 0xfc, 0x0a, 0x00, 0x00, // copy {"result": to local 2
+	0x20, 0x02, 0x41, 0x0a, 0x6a, 0x20, 0x00, 0x20, 0x01, 0xfc, 0x0a, 0x00, 0x00, // copy the request after it
+	0x20, 0x02, 0x41, 0x0a, 0x6a, 0x20, 0x01, 0x6a, 0x41, 0x0a, 0x41, 0x01, 0xfc, 0x0a, 0x00, 0x00, // copy }
+	0x20, 0x02, 0xad, 0x42, 0x20, 0x86, // local 2 << 32
+	0x20, 0x01, 0x41, 0x0b, 0x6a, 0xad, 0x84, // | size + 11
+	0x0b,
+}
+
+// wasmLoopBody never returns
+var wasmLoopBody = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b}
+
+func TestWasmFunction(t *testing.T) {
+	f, err := newWasmFunction(&pms.Function{Name: "echo", Kind: pms.FunctionKindWasm, Module: wasmTestModule(1, wasmEchoBody...)})
+	if err != nil {
+		t.Fatal("failed to compile wasm function:", err)
+	}
+	result, err := f.call(&ext.CustomerFunctionRequest{Params: []interface{}{"alice", 3.0}})
+	want := map[string]interface{}{"params": []interface{}{"alice", 3.0}}
+	if err != nil || !reflect.DeepEqual(result, want) {
+		t.Errorf("expected result %v, got %v, %v", want, result, err)
+	}
+
+	loop, err := newWasmFunction(&pms.Function{Name: "loop", Kind: pms.FunctionKindWasm,
+		Module: wasmTestModule(1, wasmLoopBody...), Limits: &pms.FunctionLimits{TimeoutMS: 50}})
+	if err != nil {
+		t.Fatal("failed to compile wasm function:", err)
+	}
+	if _, err := loop.call(&ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError ||
+		!strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+
+	// The calls beyond the concurrency limit time out waiting for the running one
+	single, err := newWasmFunction(&pms.Function{Name: "single", Kind: pms.FunctionKindWasm,
+		Module: wasmTestModule(1, wasmEchoBody...), Limits: &pms.FunctionLimits{TimeoutMS: 50, MaxConcurrency: 1}})
+	if err != nil {
+		t.Fatal("failed to compile wasm function:", err)
+	}
+	single.slots <- struct{}{}
+	if _, err := single.call(&ext.CustomerFunctionRequest{}); errors.Code(err) != errors.CustomerFuncError ||
+		!strings.Contains(err.Error(), "waiting for 1 concurrent calls") {
+		t.Errorf("expected timeout error waiting for the running call, got %v", err)
+	}
+	<-single.slots
+	if _, err := single.call(&ext.CustomerFunctionRequest{}); err != nil {
+		t.Errorf("failed to call wasm function once the running call completes: %v", err)
+	}
+
+	invalidFunctions := map[string]*pms.Function{
+		"memory limit": {Name: "big", Module: wasmTestModule(32, wasmEchoBody...), Limits: &pms.FunctionLimits{MaxMemoryMB: 1}},
+		"no exports":   {Name: "empty", Module: []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}},
+		"garbage":      {Name: "garbage", Module: []byte("\x00asm garbage")},
+	}
+	for name, cf := range invalidFunctions {
+		if _, err := newWasmFunction(cf); errors.Code(err) != errors.CustomerFuncError {
+			t.Errorf("%s: expected customer function error, got %v", name, err)
+		}
+	}
+}
+
+func TestWasmFunctionInConditions(t *testing.T) {
+	echo := &pms.Function{Name: "echo", Kind: pms.FunctionKindWasm, Module: wasmTestModule(1, wasmEchoBody...), ResultCachable: true}
+	fs := NewRuntimePolicyStore()
+	functions, _ := convertFunctions([]*pms.Function{echo}, fs.FunctionResultCache, fs.FuncSvcEndpoint)
+	condition, err := compileCondition("JSONPath(echo(user, 3), '$.params[1]') == 3", functions)
+	if err != nil {
+		t.Fatal("failed to compile condition:", err)
+	}
+	for i := 0; i < 2; i++ {
+		result, err := condition.Evaluate(map[string]interface{}{"user": "alice"})
+		if err != nil || result != true {
+			t.Errorf("expected true, got %v, %v", result, err)
+		}
+	}
+	if stats := fs.FunctionResultCache.Stats(); stats.Entries != 1 || stats.Hits != 1 {
+		t.Errorf("result of wasm function should be cached, got stats %+v", stats)
+	}
+}
+
+func TestReplacedWasmFunctionIsClosed(t *testing.T) {
+	echo := &pms.Function{Name: "echo", Kind: pms.FunctionKindWasm, Module: wasmTestModule(1, wasmEchoBody...)}
+	fs := NewRuntimePolicyStore()
+	fs.init(&pms.PolicyStore{Functions: []*pms.Function{echo}}, "")
+	old := fs.Functions["echo"]
+	if _, err := old("alice"); err != nil {
+		t.Fatal("failed to call wasm function:", err)
+	}
+
+	fs.addFunction(&pms.Function{Name: "echo", Kind: pms.FunctionKindWasm, Module: wasmTestModule(2, wasmEchoBody...)})
+	if _, err := old("alice"); errors.Code(err) != errors.CustomerFuncError || !strings.Contains(err.Error(), "unloaded") {
+		t.Errorf("replaced wasm function should be closed, got %v", err)
+	}
+	if _, err := fs.Functions["echo"]("alice"); err != nil {
+		t.Errorf("failed to call the new wasm function: %v", err)
+	}
+
+	current := fs.Functions["echo"]
+	fs.deleteFunction("echo")
+	if _, err := current("alice"); errors.Code(err) != errors.CustomerFuncError {
+		t.Errorf("deleted wasm function should be closed, got %v", err)
+	}
+}
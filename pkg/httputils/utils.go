@@ -31,6 +31,10 @@ func errorCodeToHTTPStatus(err error) int {
 		return http.StatusBadRequest
 	case errors.ExceedLimit:
 		return http.StatusForbidden
+	case errors.Unauthorized:
+		return http.StatusUnauthorized
+	case errors.Forbidden:
+		return http.StatusForbidden
 	default:
 		// Unknown status
 		return http.StatusInternalServerError
@@ -0,0 +1,1085 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	"github.com/oracle/speedle/pkg/suid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRootBucket is the top level bucket holding the data of the default tenant
+	DefaultRootBucket = "speedle_ps"
+
+	ServicesBucket     = "services"
+	PoliciesBucket     = "policies"
+	RolePoliciesBucket = "role_policies"
+	FunctionsBucket    = "functions"
+	// ServiceKey is the key of service attributes like type and metadata in the bucket of each service
+	ServiceKey = "service"
+)
+
+// Store keeps policies in a bolt database, one key per policy, role policy and function, so each change only
+// writes the entities it touches. The layout of the root bucket is:
+//
+//	services/<service name>/service                     service attributes
+//	services/<service name>/policies/<policy id>        policy
+//	services/<service name>/role_policies/<policy id>   role policy
+//	functions/<function name>                           function
+//	discover_requests/<revision>                        discover request
+//	discover_index/<service name>/<revision>            discover request index by service
+//	changes/<revision>                                  change log read by watchers
+//
+// The sequence of the root bucket is the revision of the store, it's increased by every change.
+type Store struct {
+	db   *sharedDB
+	root []byte
+
+	watchLock sync.Mutex
+	watcher   *watcher
+	// watchRevision is the revision the store is watched from, -1 until the store is read or watched
+	watchRevision int64
+}
+
+func newStore(db *sharedDB, root string) (pms.PolicyStoreManager, error) {
+	s := &Store{db: db, root: []byte(root), watchRevision: -1}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		rootBucket, err := tx.CreateBucketIfNotExists(s.root)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{ServicesBucket, FunctionsBucket, DiscoverRequestsBucket, DiscoverIndexBucket, ChangesBucket} {
+			if _, err := rootBucket.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.release()
+		return nil, errors.Wrapf(err, errors.StoreError, "unable to initialize bucket %q in bolt database %q", root, db.path)
+	}
+	return s, nil
+}
+
+// Close stops watching and releases the bolt database
+func (s *Store) Close() error {
+	s.StopWatch()
+	return s.db.release()
+}
+
+func wrapStoreError(err error, format string, a ...interface{}) error {
+	if err == nil || errors.Code(err) != errors.UnknownError {
+		return err
+	}
+	return errors.Wrapf(err, errors.StoreError, format, a...)
+}
+
+// bucket returns the nested bucket of the root bucket at path, or nil if it doesn't exist
+func (s *Store) bucket(tx *bbolt.Tx, path ...string) *bbolt.Bucket {
+	b := tx.Bucket(s.root)
+	for _, name := range path {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket([]byte(name))
+	}
+	return b
+}
+
+func (s *Store) serviceBucket(tx *bbolt.Tx, serviceName string) (*bbolt.Bucket, error) {
+	b := s.bucket(tx, ServicesBucket, serviceName)
+	if b == nil {
+		return nil, errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
+	}
+	return b, nil
+}
+
+// nextEventID increases the revision of the store and returns it as the ID of the events of the transaction
+func (s *Store) nextEventID(tx *bbolt.Tx) (int64, error) {
+	rev, err := tx.Bucket(s.root).NextSequence()
+	return int64(rev), err
+}
+
+// checkRoot returns EntityNotFound if the root bucket is removed, e.g. the tenant of the store is deleted
+func (s *Store) checkRoot(tx *bbolt.Tx) error {
+	if tx.Bucket(s.root) == nil {
+		return errors.Errorf(errors.EntityNotFound, "bucket %q is not found in bolt database %q", s.root, s.db.path)
+	}
+	return nil
+}
+
+func (s *Store) view(fn func(tx *bbolt.Tx) error) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		if err := s.checkRoot(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func (s *Store) update(fn func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error)) error {
+	return s.db.update(string(s.root), func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		if err := s.checkRoot(tx); err != nil {
+			return nil, err
+		}
+		return fn(tx)
+	})
+}
+
+// Health returns EntityNotFound once the root bucket of the store is removed
+func (s *Store) Health() error {
+	return s.db.View(s.checkRoot)
+}
+
+// read policy store from bolt, the store is watched from the revision it's read at, unless it's already watched
+func (s *Store) ReadPolicyStore() (*pms.PolicyStore, error) {
+	var ps *pms.PolicyStore
+	var revision int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		var err error
+		ps, err = s.readPolicyStore(tx)
+		revision = s.revision(tx)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to read policy store")
+	}
+	atomic.CompareAndSwapInt64(&s.watchRevision, -1, revision)
+	return ps, nil
+}
+
+func (s *Store) readPolicyStore(tx *bbolt.Tx) (*pms.PolicyStore, error) {
+	var ps pms.PolicyStore
+	services, err := s.listServices(tx, true)
+	if err != nil {
+		return nil, err
+	}
+	ps.Services = services
+	if ps.Functions, err = s.listFunctions(tx, nil); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// write policy store to bolt, all existing services and functions are replaced
+func (s *Store) WritePolicyStore(ps *pms.PolicyStore) error {
+	for _, function := range ps.Functions {
+		if err := store.ValidateFunction(function); err != nil {
+			return err
+		}
+	}
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		rootBucket := tx.Bucket(s.root)
+		for _, name := range []string{ServicesBucket, FunctionsBucket} {
+			if err := rootBucket.DeleteBucket([]byte(name)); err != nil {
+				return nil, err
+			}
+			if _, err := rootBucket.CreateBucket([]byte(name)); err != nil {
+				return nil, err
+			}
+		}
+		for _, service := range ps.Services {
+			if err := s.putService(tx, service); err != nil {
+				return nil, err
+			}
+		}
+		for _, function := range ps.Functions {
+			if err := s.putFunction(tx, function); err != nil {
+				return nil, err
+			}
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.FULL_RELOAD, ID: id}}, nil
+	})
+	return wrapStoreError(err, "unable to write policy store")
+}
+
+// get the storage type of the store
+func (s *Store) Type() string {
+	return StoreType
+}
+
+func (s *Store) readService(b *bbolt.Bucket, serviceName string, withPolicies bool) (*pms.Service, error) {
+	var service pms.Service
+	if err := json.Unmarshal(b.Get([]byte(ServiceKey)), &service); err != nil {
+		return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal service %q", serviceName)
+	}
+	service.Name = serviceName
+	if !withPolicies {
+		return &service, nil
+	}
+	err := b.Bucket([]byte(PoliciesBucket)).ForEach(func(k, v []byte) error {
+		var policy pms.Policy
+		if err := json.Unmarshal(v, &policy); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal policy %q", v)
+		}
+		service.Policies = append(service.Policies, &policy)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = b.Bucket([]byte(RolePoliciesBucket)).ForEach(func(k, v []byte) error {
+		var rolePolicy pms.RolePolicy
+		if err := json.Unmarshal(v, &rolePolicy); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal role policy %q", v)
+		}
+		service.RolePolicies = append(service.RolePolicies, &rolePolicy)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+func (s *Store) listServices(tx *bbolt.Tx, withPolicies bool) ([]*pms.Service, error) {
+	var services []*pms.Service
+	servicesBucket := s.bucket(tx, ServicesBucket)
+	err := servicesBucket.ForEach(func(k, v []byte) error {
+		service, err := s.readService(servicesBucket.Bucket(k), string(k), withPolicies)
+		if err != nil {
+			return err
+		}
+		services = append(services, service)
+		return nil
+	})
+	return services, err
+}
+
+// putService creates the bucket of service, and puts the service together with its policies and role policies.
+// IDs are generated for the policies and role policies without one.
+func (s *Store) putService(tx *bbolt.Tx, service *pms.Service) error {
+	if len(service.Name) == 0 {
+		return errors.New(errors.InvalidRequest, "service name can not be empty")
+	}
+	b, err := s.bucket(tx, ServicesBucket).CreateBucket([]byte(service.Name))
+	if err == bbolt.ErrBucketExists {
+		return errors.Errorf(errors.EntityAlreadyExists, "service %q already exists", service.Name)
+	}
+	if err != nil {
+		return err
+	}
+	attributes := pms.Service{Name: service.Name, Type: service.Type, Metadata: service.Metadata}
+	value, err := json.Marshal(attributes)
+	if err != nil {
+		return errors.Wrapf(err, errors.SerializationError, "failed to marshal service %q", service.Name)
+	}
+	if err := b.Put([]byte(ServiceKey), value); err != nil {
+		return err
+	}
+	policies := make(map[string]interface{}, len(service.Policies))
+	for _, policy := range service.Policies {
+		if policy.ID == "" {
+			policy.ID = suid.New().String()
+		}
+		policies[policy.ID] = policy
+	}
+	if err := putEntities(b, PoliciesBucket, policies); err != nil {
+		return err
+	}
+	rolePolicies := make(map[string]interface{}, len(service.RolePolicies))
+	for _, rolePolicy := range service.RolePolicies {
+		if rolePolicy.ID == "" {
+			rolePolicy.ID = suid.New().String()
+		}
+		rolePolicies[rolePolicy.ID] = rolePolicy
+	}
+	return putEntities(b, RolePoliciesBucket, rolePolicies)
+}
+
+func putEntity(b *bbolt.Bucket, key string, entity interface{}) error {
+	value, err := json.Marshal(entity)
+	if err != nil {
+		return errors.Wrapf(err, errors.SerializationError, "failed to marshal %q", key)
+	}
+	return b.Put([]byte(key), value)
+}
+
+// putEntities creates bucket name in b, and puts entities into it in key order. bolt doesn't split nodes until
+// the transaction is committed, so putting lots of keys in random order in one transaction is very slow.
+func putEntities(b *bbolt.Bucket, name string, entities map[string]interface{}) error {
+	entitiesBucket, err := b.CreateBucket([]byte(name))
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(entities))
+	for key := range entities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := putEntity(entitiesBucket, key, entities[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetServiceNames() (serviceNames []string, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		return s.bucket(tx, ServicesBucket).ForEach(func(k, v []byte) error {
+			serviceNames = append(serviceNames, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get service names")
+	}
+	return serviceNames, nil
+}
+
+func (s *Store) GetPolicyAndRolePolicyCounts() (map[string]*pms.PolicyAndRolePolicyCount, error) {
+	countMap := make(map[string]*pms.PolicyAndRolePolicyCount)
+	err := s.view(func(tx *bbolt.Tx) error {
+		servicesBucket := s.bucket(tx, ServicesBucket)
+		return servicesBucket.ForEach(func(k, v []byte) error {
+			b := servicesBucket.Bucket(k)
+			countMap[string(k)] = &pms.PolicyAndRolePolicyCount{
+				PolicyCount:     keyCount(b.Bucket([]byte(PoliciesBucket))),
+				RolePolicyCount: keyCount(b.Bucket([]byte(RolePoliciesBucket))),
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get policy and role policy counts")
+	}
+	return countMap, nil
+}
+
+// keyCount returns the number of keys in bucket b, which must not have sub-buckets
+func keyCount(b *bbolt.Bucket) int64 {
+	return int64(b.Stats().KeyN)
+}
+
+func (s *Store) GetServiceCount() (int64, error) {
+	var count int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		//the stats of a bucket include its sub-buckets, so services are counted one by one
+		return s.bucket(tx, ServicesBucket).ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+	})
+	return count, wrapStoreError(err, "unable to get service count")
+}
+
+func (s *Store) ListAllServices() (services []*pms.Service, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		services, err = s.listServices(tx, true)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list services")
+	}
+	return services, nil
+}
+
+func (s *Store) GetService(serviceName string) (service *pms.Service, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		service, err = s.readService(b, serviceName, true)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get service %q", serviceName)
+	}
+	return service, nil
+}
+
+func (s *Store) CreateService(service *pms.Service) error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		if err := s.putService(tx, service); err != nil {
+			return nil, err
+		}
+		//the event carries a copy of the service, which is not shared with the caller
+		created, err := s.readService(s.bucket(tx, ServicesBucket, service.Name), service.Name, true)
+		if err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.SERVICE_ADD, ID: id, Content: created}}, nil
+	})
+	return wrapStoreError(err, "unable to create service %q", service.Name)
+}
+
+func (s *Store) DeleteService(serviceName string) error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		err := s.bucket(tx, ServicesBucket).DeleteBucket([]byte(serviceName))
+		if err == bbolt.ErrBucketNotFound {
+			return nil, errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.SERVICE_DELETE, ID: id, Content: []string{serviceName}}}, nil
+	})
+	return wrapStoreError(err, "unable to delete service %q", serviceName)
+}
+
+func (s *Store) DeleteServices() error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		var serviceNames []string
+		err := s.bucket(tx, ServicesBucket).ForEach(func(k, v []byte) error {
+			serviceNames = append(serviceNames, string(k))
+			return nil
+		})
+		if err != nil || len(serviceNames) == 0 {
+			return nil, err
+		}
+		rootBucket := tx.Bucket(s.root)
+		if err := rootBucket.DeleteBucket([]byte(ServicesBucket)); err != nil {
+			return nil, err
+		}
+		if _, err := rootBucket.CreateBucket([]byte(ServicesBucket)); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.SERVICE_DELETE, ID: id, Content: serviceNames}}, nil
+	})
+	return wrapStoreError(err, "unable to delete services")
+}
+
+func (s *Store) putFunction(tx *bbolt.Tx, function *pms.Function) error {
+	b := s.bucket(tx, FunctionsBucket)
+	if b.Get([]byte(function.Name)) != nil {
+		return errors.Errorf(errors.EntityAlreadyExists, "function %q already exists", function.Name)
+	}
+	return putEntity(b, function.Name, function)
+}
+
+func (s *Store) listFunctions(tx *bbolt.Tx, f *filter) ([]*pms.Function, error) {
+	var functions []*pms.Function
+	err := s.bucket(tx, FunctionsBucket).ForEach(func(k, v []byte) error {
+		var function pms.Function
+		if err := json.Unmarshal(v, &function); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal function %q", v)
+		}
+		if f == nil || nameFilter(function.Name, f) {
+			functions = append(functions, &function)
+		}
+		return nil
+	})
+	return functions, err
+}
+
+func (s *Store) CreateFunction(function *pms.Function) (*pms.Function, error) {
+	if err := store.ValidateFunction(function); err != nil {
+		return nil, err
+	}
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		if err := s.putFunction(tx, function); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		created := *function
+		return []pms.StoreChangeEvent{{Type: pms.FUNCTION_ADD, ID: id, Content: &created}}, nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create function %q", function.Name)
+	}
+	return function, nil
+}
+
+func (s *Store) DeleteFunction(funcName string) error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		b := s.bucket(tx, FunctionsBucket)
+		if b.Get([]byte(funcName)) == nil {
+			return nil, errors.Errorf(errors.EntityNotFound, "function %q is not found", funcName)
+		}
+		if err := b.Delete([]byte(funcName)); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.FUNCTION_DELETE, ID: id, Content: []string{funcName}}}, nil
+	})
+	return wrapStoreError(err, "unable to delete function %q", funcName)
+}
+
+func (s *Store) DeleteFunctions() error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		var funcNames []string
+		err := s.bucket(tx, FunctionsBucket).ForEach(func(k, v []byte) error {
+			funcNames = append(funcNames, string(k))
+			return nil
+		})
+		if err != nil || len(funcNames) == 0 {
+			return nil, err
+		}
+		rootBucket := tx.Bucket(s.root)
+		if err := rootBucket.DeleteBucket([]byte(FunctionsBucket)); err != nil {
+			return nil, err
+		}
+		if _, err := rootBucket.CreateBucket([]byte(FunctionsBucket)); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.FUNCTION_DELETE, ID: id, Content: funcNames}}, nil
+	})
+	return wrapStoreError(err, "unable to delete all functions")
+}
+
+func (s *Store) GetFunction(funcName string) (*pms.Function, error) {
+	var function pms.Function
+	err := s.view(func(tx *bbolt.Tx) error {
+		value := s.bucket(tx, FunctionsBucket).Get([]byte(funcName))
+		if value == nil {
+			return errors.Errorf(errors.EntityNotFound, "function %q is not found", funcName)
+		}
+		if err := json.Unmarshal(value, &function); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal function %q", value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get function %q", funcName)
+	}
+	return &function, nil
+}
+
+func (s *Store) ListAllFunctions(filter string) (functions []*pms.Function, err error) {
+	f := parseFilter(filter)
+	err = s.view(func(tx *bbolt.Tx) error {
+		functions, err = s.listFunctions(tx, f)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list functions")
+	}
+	return functions, nil
+}
+
+func (s *Store) GetFunctionCount() (int64, error) {
+	var count int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		count = keyCount(s.bucket(tx, FunctionsBucket))
+		return nil
+	})
+	return count, wrapStoreError(err, "unable to get function count")
+}
+
+// For policy manager
+func (s *Store) ListAllPolicies(serviceName string, filter string) ([]*pms.Policy, error) {
+	f := parseFilter(filter)
+	policies := []*pms.Policy{}
+	err := s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		return b.Bucket([]byte(PoliciesBucket)).ForEach(func(k, v []byte) error {
+			var policy pms.Policy
+			if err := json.Unmarshal(v, &policy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+			}
+			if f == nil || nameFilter(policy.Name, f) {
+				policies = append(policies, &policy)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list policies of service %q", serviceName)
+	}
+	return policies, nil
+}
+
+// countEntities counts the keys of bucket name in service serviceName, or in all services when serviceName is empty
+func (s *Store) countEntities(serviceName string, name string) (int64, error) {
+	var count int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		if len(serviceName) > 0 {
+			b, err := s.serviceBucket(tx, serviceName)
+			if err != nil {
+				return err
+			}
+			count = keyCount(b.Bucket([]byte(name)))
+			return nil
+		}
+		servicesBucket := s.bucket(tx, ServicesBucket)
+		return servicesBucket.ForEach(func(k, v []byte) error {
+			count += keyCount(servicesBucket.Bucket(k).Bucket([]byte(name)))
+			return nil
+		})
+	})
+	return count, err
+}
+
+func (s *Store) GetPolicyCount(serviceName string) (int64, error) {
+	count, err := s.countEntities(serviceName, PoliciesBucket)
+	return count, wrapStoreError(err, "unable to get policy count")
+}
+
+func (s *Store) GetPolicy(serviceName string, id string) (*pms.Policy, error) {
+	var policy pms.Policy
+	err := s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		value := b.Bucket([]byte(PoliciesBucket)).Get([]byte(id))
+		if value == nil {
+			return errors.Errorf(errors.EntityNotFound, "policy %q is not found in service %q", id, serviceName)
+		}
+		if err := json.Unmarshal(value, &policy); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal a policy")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get policy %q in service %q", id, serviceName)
+	}
+	return &policy, nil
+}
+
+// deleteEntities deletes the keys of bucket name in service serviceName. All the keys are deleted when id is empty.
+// The deleted values are returned.
+func (s *Store) deleteEntities(tx *bbolt.Tx, serviceName string, name string, id string) ([][]byte, error) {
+	b, err := s.serviceBucket(tx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	entities := b.Bucket([]byte(name))
+	var values [][]byte
+	if len(id) > 0 {
+		value := entities.Get([]byte(id))
+		if value == nil {
+			return nil, nil
+		}
+		values = append(values, append([]byte(nil), value...))
+		return values, entities.Delete([]byte(id))
+	}
+	err = entities.ForEach(func(k, v []byte) error {
+		values = append(values, append([]byte(nil), v...))
+		return nil
+	})
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	if err := b.DeleteBucket([]byte(name)); err != nil {
+		return nil, err
+	}
+	_, err = b.CreateBucket([]byte(name))
+	return values, err
+}
+
+func (s *Store) deletePolicies(serviceName string, id string) error {
+	return s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		values, err := s.deleteEntities(tx, serviceName, PoliciesBucket, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			if len(id) > 0 {
+				return nil, errors.Errorf(errors.EntityNotFound, "policy %q is not found in service %q", id, serviceName)
+			}
+			return nil, nil
+		}
+		var data []pms.StoreUpdateData
+		for _, value := range values {
+			var policy pms.Policy
+			if err := json.Unmarshal(value, &policy); err != nil {
+				return nil, errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+			}
+			data = append(data, pms.StoreUpdateData{ServiceName: serviceName, Data: &policy})
+		}
+		eventID, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.POLICY_DELETE, ID: eventID, Content: data}}, nil
+	})
+}
+
+func (s *Store) DeletePolicy(serviceName string, id string) error {
+	return wrapStoreError(s.deletePolicies(serviceName, id), "unable to delete policy %q in service %q", id, serviceName)
+}
+
+func (s *Store) DeletePolicies(serviceName string) error {
+	return wrapStoreError(s.deletePolicies(serviceName, ""), "unable to delete all policies in service %q", serviceName)
+}
+
+func (s *Store) CreatePolicy(serviceName string, policy *pms.Policy) (*pms.Policy, error) {
+	dupPolicy := *policy
+	if policy.ID == "" {
+		dupPolicy.ID = suid.New().String()
+	}
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		policies := b.Bucket([]byte(PoliciesBucket))
+		if policies.Get([]byte(dupPolicy.ID)) != nil {
+			return nil, errors.Errorf(errors.EntityAlreadyExists, "policy %q already exists in service %q", dupPolicy.ID, serviceName)
+		}
+		if err := putEntity(policies, dupPolicy.ID, &dupPolicy); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		created := dupPolicy
+		data := []pms.StoreUpdateData{{ServiceName: serviceName, Data: &created}}
+		return []pms.StoreChangeEvent{{Type: pms.POLICY_ADD, ID: id, Content: data}}, nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create a policy in service %q", serviceName)
+	}
+	return &dupPolicy, nil
+}
+
+// For role policy manager
+func (s *Store) ListAllRolePolicies(serviceName string, filter string) ([]*pms.RolePolicy, error) {
+	f := parseFilter(filter)
+	rolePolicies := []*pms.RolePolicy{}
+	err := s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		return b.Bucket([]byte(RolePoliciesBucket)).ForEach(func(k, v []byte) error {
+			var rolePolicy pms.RolePolicy
+			if err := json.Unmarshal(v, &rolePolicy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+			}
+			if f == nil || nameFilter(rolePolicy.Name, f) {
+				rolePolicies = append(rolePolicies, &rolePolicy)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list role policies of service %q", serviceName)
+	}
+	return rolePolicies, nil
+}
+
+func (s *Store) GetRolePolicyCount(serviceName string) (int64, error) {
+	count, err := s.countEntities(serviceName, RolePoliciesBucket)
+	return count, wrapStoreError(err, "unable to get role policy count")
+}
+
+func (s *Store) GetRolePolicy(serviceName string, id string) (*pms.RolePolicy, error) {
+	var rolePolicy pms.RolePolicy
+	err := s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		value := b.Bucket([]byte(RolePoliciesBucket)).Get([]byte(id))
+		if value == nil {
+			return errors.Errorf(errors.EntityNotFound, "role policy %q is not found in service %q", id, serviceName)
+		}
+		if err := json.Unmarshal(value, &rolePolicy); err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get role policy %q in service %q", id, serviceName)
+	}
+	return &rolePolicy, nil
+}
+
+func (s *Store) deleteRolePolicies(serviceName string, id string) error {
+	return s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		values, err := s.deleteEntities(tx, serviceName, RolePoliciesBucket, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			if len(id) > 0 {
+				return nil, errors.Errorf(errors.EntityNotFound, "role policy %q is not found in service %q", id, serviceName)
+			}
+			return nil, nil
+		}
+		var data []pms.StoreUpdateData
+		for _, value := range values {
+			var rolePolicy pms.RolePolicy
+			if err := json.Unmarshal(value, &rolePolicy); err != nil {
+				return nil, errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+			}
+			data = append(data, pms.StoreUpdateData{ServiceName: serviceName, Data: &rolePolicy})
+		}
+		eventID, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []pms.StoreChangeEvent{{Type: pms.ROLEPOLICY_DELETE, ID: eventID, Content: data}}, nil
+	})
+}
+
+func (s *Store) DeleteRolePolicy(serviceName string, id string) error {
+	return wrapStoreError(s.deleteRolePolicies(serviceName, id), "unable to delete role policy %q in service %q", id, serviceName)
+}
+
+func (s *Store) DeleteRolePolicies(serviceName string) error {
+	return wrapStoreError(s.deleteRolePolicies(serviceName, ""), "unable to delete all role policies in service %q", serviceName)
+}
+
+func (s *Store) CreateRolePolicy(serviceName string, rolePolicy *pms.RolePolicy) (*pms.RolePolicy, error) {
+	dupRolePolicy := *rolePolicy
+	if rolePolicy.ID == "" {
+		dupRolePolicy.ID = suid.New().String()
+	}
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		rolePolicies := b.Bucket([]byte(RolePoliciesBucket))
+		if rolePolicies.Get([]byte(dupRolePolicy.ID)) != nil {
+			return nil, errors.Errorf(errors.EntityAlreadyExists, "role policy %q already exists in service %q", dupRolePolicy.ID, serviceName)
+		}
+		if err := putEntity(rolePolicies, dupRolePolicy.ID, &dupRolePolicy); err != nil {
+			return nil, err
+		}
+		id, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		created := dupRolePolicy
+		data := []pms.StoreUpdateData{{ServiceName: serviceName, Data: &created}}
+		return []pms.StoreChangeEvent{{Type: pms.ROLEPOLICY_ADD, ID: id, Content: data}}, nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create role policy in service %q", serviceName)
+	}
+	return &dupRolePolicy, nil
+}
+
+type filter struct {
+	field    string
+	operator string
+	target   string
+}
+
+func (f filter) String() string {
+	return fmt.Sprint(f.field, f.operator, f.target)
+}
+
+func parseFilter(filterStr string) *filter {
+	if len(filterStr) == 0 {
+		return nil
+	}
+	values := strings.Split(filterStr, " ")
+	if len(values) >= 2 {
+		f := &filter{
+			field:    values[0],
+			operator: values[1],
+		}
+		if len(values) > 2 {
+			f.target = values[2]
+		}
+		return f
+	}
+	log.Error("invalid filter string:", filterStr)
+	return nil
+}
+
+func nameFilter(name string, f *filter) bool { //this filter function return true when the input filter is invalid.
+	if f.field != "name" {
+		log.Error("invalid name filter. filter is:", f)
+		return true
+	}
+	switch f.operator {
+	case "eq":
+		return name == f.target
+	case "co":
+		return strings.Contains(name, f.target)
+	case "sw":
+		return strings.HasPrefix(name, f.target)
+	case "pr":
+		return len(name) > 0
+	case "gt":
+		return name > f.target
+	case "ge":
+		return name >= f.target
+	case "lt":
+		return name < f.target
+	case "le":
+		return name <= f.target
+	default:
+		log.Error("invalid name filter:", f)
+		return true
+	}
+}
+
+// readPage calls fn with the values of up to limit keys of bucket name in service serviceName, which are greater
+// than afterID, ordered by key
+func (s *Store) readPage(serviceName string, name string, afterID string, limit int, fn func(v []byte) error) error {
+	return s.view(func(tx *bbolt.Tx) error {
+		b, err := s.serviceBucket(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		c := b.Bucket([]byte(name)).Cursor()
+		k, v := c.Seek([]byte(afterID))
+		if k != nil && string(k) == afterID {
+			k, v = c.Next()
+		}
+		for n := 0; k != nil && n < limit; k, v = c.Next() {
+			if err := fn(v); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+// ServicePage returns up to limit services whose names are greater than afterName, ordered by name, without their
+// policies and role policies
+func (s *Store) ServicePage(afterName string, limit int) ([]*pms.Service, error) {
+	services := []*pms.Service{}
+	err := s.view(func(tx *bbolt.Tx) error {
+		servicesBucket := s.bucket(tx, ServicesBucket)
+		c := servicesBucket.Cursor()
+		k, _ := c.Seek([]byte(afterName))
+		if k != nil && string(k) == afterName {
+			k, _ = c.Next()
+		}
+		for ; k != nil && len(services) < limit; k, _ = c.Next() {
+			service, err := s.readService(servicesBucket.Bucket(k), string(k), false)
+			if err != nil {
+				return err
+			}
+			services = append(services, service)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list services")
+	}
+	return services, nil
+}
+
+// PolicyPage returns up to limit policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) PolicyPage(serviceName string, afterID string, limit int) ([]*pms.Policy, error) {
+	policies := []*pms.Policy{}
+	err := s.readPage(serviceName, PoliciesBucket, afterID, limit, func(v []byte) error {
+		var policy pms.Policy
+		if err := json.Unmarshal(v, &policy); err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+		}
+		policies = append(policies, &policy)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list policies of service %q", serviceName)
+	}
+	return policies, nil
+}
+
+// RolePolicyPage returns up to limit role policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) RolePolicyPage(serviceName string, afterID string, limit int) ([]*pms.RolePolicy, error) {
+	rolePolicies := []*pms.RolePolicy{}
+	err := s.readPage(serviceName, RolePoliciesBucket, afterID, limit, func(v []byte) error {
+		var rolePolicy pms.RolePolicy
+		if err := json.Unmarshal(v, &rolePolicy); err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policies")
+		}
+		rolePolicies = append(rolePolicies, &rolePolicy)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list role policies of service %q", serviceName)
+	}
+	return rolePolicies, nil
+}
+
+// SearchPolicies searches the index of the store, which catches up with the changes committed by this process or
+// others from the change log before each search
+func (s *Store) SearchPolicies(query store.SearchQuery) ([]*store.SearchResult, error) {
+	index, err := s.searchIndex()
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to search policies")
+	}
+	return index.Search(query), nil
+}
+
+// searchIndex returns the search index of the store as of the current revision. It's built again if the changes it
+// misses are pruned from the change log, or can't be applied.
+func (s *Store) searchIndex() (*store.PolicyIndex, error) {
+	root := string(s.root)
+	s.db.indexLock.Lock()
+	defer s.db.indexLock.Unlock()
+	idx := s.db.indexes[root]
+	err := s.view(func(tx *bbolt.Tx) error {
+		revision := s.revision(tx)
+		if idx != nil && idx.revision <= revision {
+			if idx.revision == revision {
+				return nil
+			}
+			events, pruned, err := readChanges(s.bucket(tx, ChangesBucket), idx.revision, 0)
+			if err != nil {
+				return err
+			}
+			if !pruned && applyEvents(idx.index, events) {
+				idx.revision = revision
+				return nil
+			}
+		}
+		ps, err := s.readPolicyStore(tx)
+		if err != nil {
+			return err
+		}
+		idx = &rootIndex{index: store.NewPolicyIndex(ps), revision: revision}
+		s.db.indexes[root] = idx
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx.index, nil
+}
+
+func applyEvents(index *store.PolicyIndex, events []pms.StoreChangeEvent) bool {
+	for _, e := range events {
+		if !index.Apply(e) {
+			return false
+		}
+	}
+	return true
+}
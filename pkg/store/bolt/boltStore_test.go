@@ -0,0 +1,586 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "speedle-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err := store.NewStore(StoreType, map[string]interface{}{
+		BoltDBPathKey: filepath.Join(dir, "policies.db"),
+		BoltNoSyncKey: "true",
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal("fail to new bolt store:", err)
+	}
+	s := ps.(*Store)
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newPolicy(name string) *pms.Policy {
+	return &pms.Policy{
+		Name:       name,
+		Effect:     "grant",
+		Principals: [][]string{{"user:alice"}},
+		Permissions: []*pms.Permission{
+			{Resource: "/books", Actions: []string{"read"}},
+		},
+	}
+}
+
+func TestWriteReadPolicyStore(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	var ps pms.PolicyStore
+	for i := 0; i < 10; i++ {
+		service := pms.Service{Name: fmt.Sprintf("app%d", i), Type: pms.TypeApplication, Metadata: map[string]string{"owner": "team"}}
+		service.Policies = []*pms.Policy{newPolicy("p1"), newPolicy("p2")}
+		service.RolePolicies = []*pms.RolePolicy{{Name: "rp1", Effect: "grant", Roles: []string{"reader"}, Principals: []string{"user:bob"}}}
+		ps.Services = append(ps.Services, &service)
+	}
+	ps.Functions = []*pms.Function{{Name: "f1", FuncURL: "http://localhost:8000/f1"}}
+	if err := s.WritePolicyStore(&ps); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+
+	psr, err := s.ReadPolicyStore()
+	if err != nil {
+		t.Fatal("fail to read policy store:", err)
+	}
+	if len(psr.Services) != 10 || len(psr.Functions) != 1 {
+		t.Fatalf("expect 10 services and 1 function, got %d services and %d functions", len(psr.Services), len(psr.Functions))
+	}
+	for _, service := range psr.Services {
+		if len(service.Policies) != 2 || len(service.RolePolicies) != 1 {
+			t.Errorf("service %q should have 2 policies and 1 role policy", service.Name)
+		}
+		if service.Type != pms.TypeApplication || service.Metadata["owner"] != "team" {
+			t.Errorf("attributes of service %q are not kept: %+v", service.Name, service)
+		}
+		for _, policy := range service.Policies {
+			if len(policy.ID) == 0 {
+				t.Errorf("policy %q in service %q has no ID", policy.Name, service.Name)
+			}
+		}
+	}
+
+	//writing again replaces everything
+	if err := s.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{{Name: "only"}}}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	if count, _ := s.GetServiceCount(); count != 1 {
+		t.Errorf("expect 1 service, got %d", count)
+	}
+	if count, _ := s.GetFunctionCount(); count != 0 {
+		t.Errorf("expect 0 function, got %d", count)
+	}
+}
+
+func TestServicePolicyRolePolicy(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if err := s.CreateService(&pms.Service{Name: "app1", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "app1"}); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	if _, err := s.CreatePolicy("nonexist", newPolicy("p")); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		policy, err := s.CreatePolicy("app1", newPolicy(fmt.Sprintf("policy%d", i)))
+		if err != nil {
+			t.Fatal("fail to create policy:", err)
+		}
+		ids = append(ids, policy.ID)
+	}
+	withID := newPolicy("withID")
+	withID.ID = ids[0]
+	if _, err := s.CreatePolicy("app1", withID); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	rolePolicy, err := s.CreateRolePolicy("app1", &pms.RolePolicy{Name: "rp", Effect: "grant", Roles: []string{"reader"}, Principals: []string{"user:bob"}})
+	if err != nil {
+		t.Fatal("fail to create role policy:", err)
+	}
+
+	policy, err := s.GetPolicy("app1", ids[1])
+	if err != nil || policy.Name != "policy1" {
+		t.Errorf("fail to get policy: %v, %v", policy, err)
+	}
+	if _, err := s.GetRolePolicy("app1", rolePolicy.ID); err != nil {
+		t.Errorf("fail to get role policy: %v", err)
+	}
+	policies, err := s.ListAllPolicies("app1", "name sw policy")
+	if err != nil || len(policies) != 5 {
+		t.Errorf("expect 5 policies, got %d, %v", len(policies), err)
+	}
+	policies, err = s.ListAllPolicies("app1", "name eq policy3")
+	if err != nil || len(policies) != 1 || policies[0].ID != ids[3] {
+		t.Errorf("expect policy3, got %v, %v", policies, err)
+	}
+
+	counts, err := s.GetPolicyAndRolePolicyCounts()
+	if err != nil || counts["app1"].PolicyCount != 5 || counts["app1"].RolePolicyCount != 1 {
+		t.Errorf("unexpected counts %v, %v", counts, err)
+	}
+
+	if err := s.DeletePolicy("app1", ids[0]); err != nil {
+		t.Error("fail to delete policy:", err)
+	}
+	if err := s.DeletePolicy("app1", ids[0]); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if count, _ := s.GetPolicyCount(""); count != 4 {
+		t.Errorf("expect 4 policies, got %d", count)
+	}
+	if err := s.DeletePolicies("app1"); err != nil {
+		t.Error("fail to delete policies:", err)
+	}
+	if err := s.DeleteRolePolicies("app1"); err != nil {
+		t.Error("fail to delete role policies:", err)
+	}
+	service, err := s.GetService("app1")
+	if err != nil || len(service.Policies) != 0 || len(service.RolePolicies) != 0 || service.Type != pms.TypeApplication {
+		t.Errorf("unexpected service %+v, %v", service, err)
+	}
+
+	if err := s.DeleteService("app1"); err != nil {
+		t.Error("fail to delete service:", err)
+	}
+	if _, err := s.GetService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.DeleteService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if _, err := s.CreateFunction(&pms.Function{Name: "f"}); errors.Code(err) != errors.InvalidRequest {
+		t.Errorf("expect InvalidRequest, got %v", err)
+	}
+	for _, name := range []string{"f1", "f2", "g1"} {
+		if _, err := s.CreateFunction(&pms.Function{Name: name, FuncURL: "http://localhost/" + name}); err != nil {
+			t.Fatal("fail to create function:", err)
+		}
+	}
+	if _, err := s.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	functions, err := s.ListAllFunctions("name sw f")
+	if err != nil || len(functions) != 2 {
+		t.Errorf("expect 2 functions, got %v, %v", functions, err)
+	}
+	if f, err := s.GetFunction("g1"); err != nil || f.FuncURL != "http://localhost/g1" {
+		t.Errorf("unexpected function %v, %v", f, err)
+	}
+	if err := s.DeleteFunction("g1"); err != nil {
+		t.Error("fail to delete function:", err)
+	}
+	if _, err := s.GetFunction("g1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.DeleteFunctions(); err != nil {
+		t.Error("fail to delete functions:", err)
+	}
+	if count, _ := s.GetFunctionCount(); count != 0 {
+		t.Errorf("expect 0 function, got %d", count)
+	}
+}
+
+func receiveEvent(t *testing.T, ch pms.StorageChangeChannel) pms.StoreChangeEvent {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for store change event")
+	}
+	return pms.StoreChangeEvent{}
+}
+
+func TestWatch(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	ch, err := s.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := s.CreatePolicy("app1", newPolicy("p1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolePolicy, err := s.CreateRolePolicy("app1", &pms.RolePolicy{Name: "rp1", Roles: []string{"r"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeletePolicy("app1", policy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRolePolicy("app1", rolePolicy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteFunction("f1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteService("app1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePolicyStore(&pms.PolicyStore{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []pms.EventType{pms.SERVICE_ADD, pms.POLICY_ADD, pms.ROLEPOLICY_ADD, pms.FUNCTION_ADD,
+		pms.POLICY_DELETE, pms.ROLEPOLICY_DELETE, pms.FUNCTION_DELETE, pms.SERVICE_DELETE, pms.FULL_RELOAD}
+	var lastID int64
+	for _, eventType := range expected {
+		e := receiveEvent(t, ch)
+		if e.Type != eventType {
+			t.Fatalf("expect event %v, got %v", eventType, e.Type)
+		}
+		if e.ID <= lastID {
+			t.Errorf("event IDs should increase, got %d after %d", e.ID, lastID)
+		}
+		lastID = e.ID
+		switch e.Type {
+		case pms.POLICY_ADD, pms.POLICY_DELETE:
+			data := e.Content.([]pms.StoreUpdateData)
+			if len(data) != 1 || data[0].ServiceName != "app1" || data[0].Data.(*pms.Policy).ID != policy.ID {
+				t.Errorf("unexpected content %v of event %v", data, e.Type)
+			}
+		case pms.SERVICE_DELETE:
+			if names := e.Content.([]string); len(names) != 1 || names[0] != "app1" {
+				t.Errorf("unexpected content %v of event %v", names, e.Type)
+			}
+		}
+	}
+
+	s.StopWatch()
+	if _, ok := <-ch; ok {
+		t.Error("channel should be closed after StopWatch")
+	}
+}
+
+func TestSharedDatabase(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	//a second store of the same file shares the database, and sees the changes of the first one
+	ps, err := store.NewStore(StoreType, map[string]interface{}{BoltDBPathKey: s.db.path, BoltOpenTimeoutKey: "1s"})
+	if err != nil {
+		t.Fatal("fail to open the same database again:", err)
+	}
+	s2 := ps.(*Store)
+	ch, err := s2.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.SERVICE_ADD || e.Content.(*pms.Service).Name != "app1" {
+		t.Errorf("unexpected event %v", e)
+	}
+	if err := s2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	//the data is persisted after the database is closed
+	path := s.db.path
+	if err := s.db.release(); err != nil {
+		t.Fatal(err)
+	}
+	ps, err = store.NewStore(StoreType, map[string]interface{}{BoltDBPathKey: path})
+	if err != nil {
+		t.Fatal("fail to reopen database:", err)
+	}
+	s.db = ps.(*Store).db
+	if _, err := s.GetService("app1"); err != nil {
+		t.Error("service should be persisted:", err)
+	}
+}
+
+func TestSharedAcrossProcesses(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	//another process opens the same file with its own sharedDB, e.g. ADS reading the policies written by PMS
+	other := &Store{db: newSharedDB(s.db.path, time.Second, true, 50*time.Millisecond, time.Hour), root: s.root, watchRevision: -1}
+	defer other.Close()
+	if err := s.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := other.ReadPolicyStore()
+	if err != nil || len(ps.Services) != 1 {
+		t.Fatalf("service app1 should be read, got %v, err: %v", ps, err)
+	}
+
+	//the changes committed after the read and before the watch are still received
+	if _, err := s.CreatePolicy("app1", newPolicy("p1")); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := other.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.POLICY_ADD || e.Content.([]pms.StoreUpdateData)[0].Data.(*pms.Policy).Name != "p1" {
+		t.Errorf("unexpected event %v", e)
+	}
+	if err := s.DeleteService("app1"); err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.SERVICE_DELETE || e.Content.([]string)[0] != "app1" {
+		t.Errorf("unexpected event %v", e)
+	}
+	if results, err := other.SearchPolicies(store.SearchQuery{Principal: "user:alice", Resource: "/books"}); err != nil || len(results) != 0 {
+		t.Errorf("nothing should be found after the service is deleted, got %v, err: %v", results, err)
+	}
+
+	//a watcher missing the pruned changes reloads all policies
+	other.StopWatch()
+	if err := s.CreateService(&pms.Service{Name: "app2"}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		return pruneChanges(s.bucket(tx, ChangesBucket), time.Now().Add(time.Hour))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch, err = other.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.SYNC_RELOAD || len(e.Content.(*pms.PolicyStore).Services) != 1 {
+		t.Errorf("unexpected event %v", e)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if err := s.WriteTenant(&pms.Tenant{Name: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	tenants, err := s.ReadTenants()
+	if err != nil || len(tenants) != 1 || tenants[0].Name != "t1" {
+		t.Fatalf("unexpected tenants %v, %v", tenants, err)
+	}
+	ps, err := s.NewTenantStore("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.(*Store).Close()
+	if err := ps.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("the service of tenant should not be visible to default tenant, got %v", err)
+	}
+
+	if err := s.RemoveTenant("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsRemoved(ps) {
+		t.Error("tenant store should be reported as removed")
+	}
+	if _, err := ps.GetServiceNames(); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.RemoveTenant("t1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+}
+
+func TestManyPolicies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test with 100k policies in short mode.")
+	}
+	s, clean := newTestStore(t)
+	defer clean()
+
+	const policyNum = 100000
+	service := &pms.Service{Name: "big"}
+	for i := 0; i < policyNum; i++ {
+		service.Policies = append(service.Policies, newPolicy(fmt.Sprintf("policy%d", i)))
+	}
+	if err := s.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{service}}); err != nil {
+		t.Fatal(err)
+	}
+
+	//per-entity operations don't depend on the number of policies in the store
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		policy, err := s.CreatePolicy("big", newPolicy("extra"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetPolicy("big", policy.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeletePolicy("big", policy.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Logf("100 create/get/delete rounds with %d policies took %v", policyNum, time.Since(start))
+
+	if count, err := s.GetPolicyCount("big"); err != nil || count != policyNum {
+		t.Errorf("expect %d policies, got %d, %v", policyNum, count, err)
+	}
+	ps, err := s.ReadPolicyStore()
+	if err != nil || len(ps.Services[0].Policies) != policyNum {
+		t.Errorf("fail to read %d policies: %v", policyNum, err)
+	}
+}
+
+func TestPolicyPage(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	service := &pms.Service{Name: "s1", Type: pms.TypeApplication}
+	for i := 0; i < 5; i++ {
+		policy := newPolicy(fmt.Sprintf("p%d", i))
+		policy.ID = fmt.Sprintf("id%d", 4-i)
+		service.Policies = append(service.Policies, policy)
+		service.RolePolicies = append(service.RolePolicies, &pms.RolePolicy{ID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("rp%d", i), Effect: "grant", Roles: []string{"r"}})
+	}
+	if err := s.CreateService(service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+
+	policies, err := s.PolicyPage("s1", "", 2)
+	if err != nil || len(policies) != 2 || policies[0].ID != "id0" || policies[1].ID != "id1" {
+		t.Errorf("policies id0 and id1 should be read, got %v, error %v", policies, err)
+	}
+	policies, err = s.PolicyPage("s1", "id1", 10)
+	if err != nil || len(policies) != 3 || policies[0].ID != "id2" || policies[2].ID != "id4" {
+		t.Errorf("policies id2 to id4 should be read, got %v, error %v", policies, err)
+	}
+	rolePolicies, err := s.RolePolicyPage("s1", "id3", 10)
+	if err != nil || len(rolePolicies) != 1 || rolePolicies[0].ID != "id4" {
+		t.Errorf("role policy id4 should be read, got %v, error %v", rolePolicies, err)
+	}
+	if _, err := s.PolicyPage("nonexist", "", 10); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("error of nonexistent service should be EntityNotFound, got %v", err)
+	}
+
+	policies, token, err := store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "name eq p0 or name eq p1"})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id3" || len(token) == 0 {
+		t.Fatalf("policy id3 should be listed with token, got %v, token %q, error %v", policies, token, err)
+	}
+	policies, token, err = store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "name eq p0 or name eq p1", Continue: token})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id4" || len(token) != 0 {
+		t.Errorf("policy id4 should be listed in the last page, got %v, token %q, error %v", policies, token, err)
+	}
+}
+
+func TestServicePage(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	for _, name := range []string{"s3", "s1", "s2"} {
+		service := &pms.Service{Name: name, Type: pms.TypeApplication, Metadata: map[string]string{"owner": name},
+			Policies: []*pms.Policy{newPolicy("p1")}}
+		if err := s.CreateService(service); err != nil {
+			t.Fatal("fail to create service:", err)
+		}
+	}
+
+	services, err := s.ServicePage("s1", 1)
+	if err != nil || len(services) != 1 || services[0].Name != "s2" || services[0].Metadata["owner"] != "s2" || len(services[0].Policies) != 0 {
+		t.Errorf("service s2 should be read without policies, got %v, error %v", services, err)
+	}
+
+	services, token, err := store.ListServices(s, store.ListOptions{Limit: 1, Filter: "name ne s1"})
+	if err != nil || len(services) != 1 || services[0].Name != "s2" || len(services[0].Policies) != 1 || len(token) == 0 {
+		t.Fatalf("service s2 should be listed with its policies and token, got %v, token %q, error %v", services, token, err)
+	}
+	services, token, err = store.ListServices(s, store.ListOptions{Limit: 1, Filter: "name ne s1", Continue: token})
+	if err != nil || len(services) != 1 || services[0].Name != "s3" || len(token) != 0 {
+		t.Errorf("service s3 should be listed in the last page, got %v, token %q, error %v", services, token, err)
+	}
+}
+
+func TestSearchPolicies(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	search := func() int {
+		results, err := s.SearchPolicies(store.SearchQuery{Principal: "user:alice", Resource: "/books"})
+		if err != nil {
+			t.Fatal("fail to search policies:", err)
+		}
+		count := 0
+		for _, result := range results {
+			count += len(result.Policies) + len(result.RolePolicies)
+		}
+		return count
+	}
+
+	if err := s.CreateService(&pms.Service{Name: "s1", Type: pms.TypeApplication, Policies: []*pms.Policy{newPolicy("p1")}}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if count := search(); count != 1 {
+		t.Fatalf("1 policy should be found, got %d", count)
+	}
+	policy, err := s.CreatePolicy("s1", newPolicy("p2"))
+	if err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	if _, err := s.CreateRolePolicy("s1", &pms.RolePolicy{Name: "rp1", Effect: "grant", Roles: []string{"r"}, Principals: []string{"user:alice"}, Resources: []string{"/books"}}); err != nil {
+		t.Fatal("fail to create role policy:", err)
+	}
+	if count := search(); count != 3 {
+		t.Errorf("2 policies and 1 role policy should be found, got %d", count)
+	}
+	if err := s.DeletePolicy("s1", policy.ID); err != nil {
+		t.Fatal("fail to delete policy:", err)
+	}
+	if count := search(); count != 2 {
+		t.Errorf("1 policy and 1 role policy should be found, got %d", count)
+	}
+	if err := s.DeleteService("s1"); err != nil {
+		t.Fatal("fail to delete service:", err)
+	}
+	if count := search(); count != 0 {
+		t.Errorf("nothing should be found, got %d", count)
+	}
+	if err := s.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{{Name: "s2", Policies: []*pms.Policy{newPolicy("p3")}}}}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	if count := search(); count != 1 {
+		t.Errorf("1 policy should be found after the policy store is written, got %d", count)
+	}
+}
@@ -0,0 +1,193 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+var (
+	dbsLock sync.Mutex
+	dbs     = make(map[string]*sharedDB)
+)
+
+// sharedDB is a bolt database file shared by the stores of this process and by other processes, e.g. PMS writing
+// the policies and ADS reading them. bolt locks the file as long as it is open, exclusively for writes, so the file
+// is only opened for the duration of each transaction. The lock of the file is held per open file, so the stores
+// of the same file in a process, e.g. the stores of tenants, share one sharedDB ordering their transactions.
+type sharedDB struct {
+	path         string
+	timeout      time.Duration
+	noSync       bool
+	pollInterval time.Duration
+	retention    time.Duration
+	refs         int
+
+	// lock is held for reading by read transactions and for writing by write transactions
+	lock sync.RWMutex
+	// lastPrune is when the change logs are pruned by this process, guarded by lock
+	lastPrune time.Time
+
+	watchersLock sync.Mutex
+	watchers     map[*watcher]struct{}
+
+	indexLock sync.Mutex
+	// indexes are the search indexes of the root buckets
+	indexes map[string]*rootIndex
+}
+
+// rootIndex is the search index of a root bucket as of revision
+type rootIndex struct {
+	index    *store.PolicyIndex
+	revision int64
+}
+
+func openDB(path string, timeout time.Duration, noSync bool, pollInterval time.Duration, retention time.Duration) (*sharedDB, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "invalid bolt database path %q", path)
+	}
+
+	dbsLock.Lock()
+	defer dbsLock.Unlock()
+	if db, ok := dbs[absPath]; ok {
+		db.refs++
+		return db, nil
+	}
+	db := newSharedDB(absPath, timeout, noSync, pollInterval, retention)
+	dbs[absPath] = db
+	return db, nil
+}
+
+func newSharedDB(path string, timeout time.Duration, noSync bool, pollInterval time.Duration, retention time.Duration) *sharedDB {
+	return &sharedDB{
+		path:         path,
+		timeout:      timeout,
+		noSync:       noSync,
+		pollInterval: pollInterval,
+		retention:    retention,
+		refs:         1,
+		watchers:     make(map[*watcher]struct{}),
+		indexes:      make(map[string]*rootIndex),
+	}
+}
+
+func (db *sharedDB) acquire() {
+	dbsLock.Lock()
+	defer dbsLock.Unlock()
+	db.refs++
+}
+
+// release forgets the database when it's no longer used by any store
+func (db *sharedDB) release() error {
+	dbsLock.Lock()
+	defer dbsLock.Unlock()
+	db.refs--
+	if db.refs > 0 {
+		return nil
+	}
+	if dbs[db.path] == db {
+		delete(dbs, db.path)
+	}
+	return nil
+}
+
+// open opens the database file, waiting at most the open timeout for the lock of the file
+func (db *sharedDB) open(readOnly bool) (*bbolt.DB, error) {
+	boltDB, err := bbolt.Open(db.path, 0600, &bbolt.Options{Timeout: db.timeout, NoSync: db.noSync, ReadOnly: readOnly})
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.StoreError, "unable to open bolt database %q", db.path)
+	}
+	return boltDB, nil
+}
+
+// View runs fn in a read transaction, other processes can read the database meanwhile
+func (db *sharedDB) View(fn func(tx *bbolt.Tx) error) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	boltDB, err := db.open(true)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+	return boltDB.View(fn)
+}
+
+// Update runs fn in a write transaction, no other process can open the database meanwhile
+func (db *sharedDB) Update(fn func(tx *bbolt.Tx) error) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	boltDB, err := db.open(false)
+	if err != nil {
+		return err
+	}
+	defer boltDB.Close()
+	return boltDB.Update(fn)
+}
+
+// update runs fn in a write transaction, the events returned by fn are appended to the change log of the root
+// bucket in the same transaction, and the watchers of this process are notified once it is committed
+func (db *sharedDB) update(root string, fn func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error)) error {
+	var events []pms.StoreChangeEvent
+	err := db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		if events, err = fn(tx); err != nil || len(events) == 0 {
+			return err
+		}
+		changes := tx.Bucket([]byte(root)).Bucket([]byte(ChangesBucket))
+		now := time.Now()
+		for _, e := range events {
+			if err := logChange(changes, e, now); err != nil {
+				return err
+			}
+		}
+		if db.retention > 0 && now.Sub(db.lastPrune) >= db.retention/10 {
+			//the prune time is updated even if the transaction is rolled back later, the changes are pruned next time
+			db.lastPrune = now
+			return pruneChanges(changes, now.Add(-db.retention))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.notify(root)
+	return nil
+}
+
+// notify wakes up the watchers of root bucket in this process, so they read the committed changes immediately
+func (db *sharedDB) notify(root string) {
+	db.watchersLock.Lock()
+	defer db.watchersLock.Unlock()
+	for w := range db.watchers {
+		if string(w.store.root) == root {
+			w.wakeUp()
+		}
+	}
+}
+
+func (db *sharedDB) addWatcher(w *watcher) {
+	db.watchersLock.Lock()
+	defer db.watchersLock.Unlock()
+	db.watchers[w] = struct{}{}
+}
+
+func (db *sharedDB) removeWatcher(w *watcher) {
+	db.watchersLock.Lock()
+	defer db.watchersLock.Unlock()
+	delete(db.watchers, w)
+}
+
+func (db *sharedDB) removeIndex(root string) {
+	db.indexLock.Lock()
+	defer db.indexLock.Unlock()
+	delete(db.indexes, root)
+}
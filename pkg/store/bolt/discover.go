@@ -0,0 +1,202 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+const (
+	DiscoverRequestsBucket = "discover_requests"
+	DiscoverIndexBucket    = "discover_index"
+)
+
+// revisionKey encodes revision in big endian, so discover requests are sorted by revision
+func revisionKey(revision int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(revision))
+	return key
+}
+
+func (s *Store) revision(tx *bbolt.Tx) int64 {
+	return int64(tx.Bucket(s.root).Sequence())
+}
+
+func (s *Store) SaveDiscoverRequest(request *ads.RequestContext) error {
+	value, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal request")
+	}
+	err = s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		revision, err := s.nextEventID(tx)
+		if err != nil {
+			return nil, err
+		}
+		key := revisionKey(revision)
+		requests := s.bucket(tx, DiscoverRequestsBucket)
+		if err := requests.Put(key, value); err != nil {
+			return nil, err
+		}
+		if len(request.ServiceName) > 0 {
+			index, err := s.bucket(tx, DiscoverIndexBucket).CreateBucketIfNotExists([]byte(request.ServiceName))
+			if err != nil {
+				return nil, err
+			}
+			if err := index.Put(key, []byte{}); err != nil {
+				return nil, err
+			}
+		}
+		if keyCount(requests) >= store.MaxDiscoverRequestNum { //reach Max number of requests, remove the oldest ones.
+			return nil, s.deleteOldestRequests(tx, store.DeleteNumWhenReachMaxDiscoverRequest)
+		}
+		return nil, nil
+	})
+	return wrapStoreError(err, "unable to save discover request")
+}
+
+func (s *Store) deleteOldestRequests(tx *bbolt.Tx, num int64) error {
+	requests := s.bucket(tx, DiscoverRequestsBucket)
+	indexes := s.bucket(tx, DiscoverIndexBucket)
+	c := requests.Cursor()
+	for k, v := c.First(); k != nil && num > 0; k, v = c.First() {
+		var request ads.RequestContext
+		if err := json.Unmarshal(v, &request); err == nil && len(request.ServiceName) > 0 {
+			if index := indexes.Bucket([]byte(request.ServiceName)); index != nil {
+				if err := index.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		if err := c.Delete(); err != nil {
+			return err
+		}
+		num--
+	}
+	return nil
+}
+
+// readRequests reads the discover requests of service serviceName, or of all services when serviceName is empty,
+// whose revisions are greater than revision
+func (s *Store) readRequests(tx *bbolt.Tx, serviceName string, revision int64) ([]*ads.RequestContext, error) {
+	requests := []*ads.RequestContext{}
+	requestsBucket := s.bucket(tx, DiscoverRequestsBucket)
+	c := requestsBucket.Cursor()
+	if len(serviceName) > 0 {
+		index := s.bucket(tx, DiscoverIndexBucket, serviceName)
+		if index == nil {
+			return requests, nil
+		}
+		c = index.Cursor()
+	}
+	for k, v := c.Seek(revisionKey(revision + 1)); k != nil; k, v = c.Next() {
+		if len(serviceName) > 0 {
+			v = requestsBucket.Get(k)
+		}
+		var request ads.RequestContext
+		if err := json.Unmarshal(v, &request); err != nil {
+			return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal request context %q for service %q", v, serviceName)
+		}
+		requests = append(requests, &request)
+	}
+	return requests, nil
+}
+
+func (s *Store) GetLastDiscoverRequest(serviceName string) (*ads.RequestContext, int64, error) {
+	var request *ads.RequestContext
+	var revision int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		requestsBucket := s.bucket(tx, DiscoverRequestsBucket)
+		var value []byte
+		if len(serviceName) == 0 {
+			_, value = requestsBucket.Cursor().Last()
+		} else if index := s.bucket(tx, DiscoverIndexBucket, serviceName); index != nil {
+			if k, _ := index.Cursor().Last(); k != nil {
+				value = requestsBucket.Get(k)
+			}
+		}
+		if value == nil {
+			return errors.Errorf(errors.EntityNotFound, "no request found for service %q", serviceName)
+		}
+		request = &ads.RequestContext{}
+		if err := json.Unmarshal(value, request); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal request context %q for service %q", value, serviceName)
+		}
+		revision = s.revision(tx)
+		return nil
+	})
+	if err != nil {
+		return nil, -1, wrapStoreError(err, "unable to get the last discover request for service %q", serviceName)
+	}
+	return request, revision, nil
+}
+
+func (s *Store) GetDiscoverRequestsSinceRevision(serviceName string, revision int64) ([]*ads.RequestContext, int64, error) {
+	var requests []*ads.RequestContext
+	currentRevision := revision
+	err := s.view(func(tx *bbolt.Tx) error {
+		var err error
+		requests, err = s.readRequests(tx, serviceName, revision)
+		currentRevision = s.revision(tx)
+		return err
+	})
+	if err != nil {
+		return nil, -1, wrapStoreError(err, "unable to get discover request for service %q with revision %d", serviceName, revision)
+	}
+	return requests, currentRevision, nil
+}
+
+func (s *Store) GetDiscoverRequests(serviceName string) ([]*ads.RequestContext, int64, error) {
+	return s.GetDiscoverRequestsSinceRevision(serviceName, 0)
+}
+
+func (s *Store) ResetDiscoverRequests(serviceName string) error {
+	err := s.update(func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		rootBucket := tx.Bucket(s.root)
+		if len(serviceName) == 0 {
+			for _, name := range []string{DiscoverRequestsBucket, DiscoverIndexBucket} {
+				if err := rootBucket.DeleteBucket([]byte(name)); err != nil {
+					return nil, err
+				}
+				if _, err := rootBucket.CreateBucket([]byte(name)); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}
+		indexes := s.bucket(tx, DiscoverIndexBucket)
+		index := indexes.Bucket([]byte(serviceName))
+		if index == nil {
+			return nil, nil
+		}
+		requests := s.bucket(tx, DiscoverRequestsBucket)
+		err := index.ForEach(func(k, v []byte) error {
+			return requests.Delete(k)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, indexes.DeleteBucket([]byte(serviceName))
+	})
+	return wrapStoreError(err, "unable to reset discover requests from service %q", serviceName)
+}
+
+// This method is implemented as common method at evaluator part
+func (s *Store) GeneratePolicies(serviceName, principalType, principalName, principalIDD string) (map[string]*pms.Service, int64, error) {
+	requests, revision, err := s.GetDiscoverRequests(serviceName)
+	if err != nil {
+		return nil, -1, err
+	}
+	serviceMap, err := store.GeneratePoliciesFromDiscoverRequests(requests, principalType, principalName, principalIDD)
+	if err != nil {
+		return nil, -1, err
+	}
+	return serviceMap, revision, nil
+}
@@ -0,0 +1,139 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+func newRequest(i int, serviceName string) *ads.RequestContext {
+	user := ads.Principal{Type: "user", Name: "user" + strconv.Itoa(i%10)}
+	subj := ads.Subject{Principals: []*ads.Principal{&user}}
+	return &ads.RequestContext{Subject: &subj, ServiceName: serviceName, Resource: "/res" + strconv.Itoa(i), Action: "read", Attributes: map[string]interface{}{}}
+}
+
+func TestPutGetLastRequest(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	store.MaxDiscoverRequestNum = int64(100)
+	store.DeleteNumWhenReachMaxDiscoverRequest = int64(10)
+	defer func() {
+		store.MaxDiscoverRequestNum = store.DefaultMaxDiscoverRequestNum
+		store.DeleteNumWhenReachMaxDiscoverRequest = store.DefaultDeleteNumWhenReachMaxDiscoverRequest
+	}()
+
+	for i := 0; i < 300; i++ {
+		serviceName := "erp" + strconv.Itoa(i%10)
+		if err := s.SaveDiscoverRequest(newRequest(i, serviceName)); err != nil {
+			t.Fatal("fail to save request:", err)
+		}
+		req, _, err := s.GetLastDiscoverRequest(serviceName)
+		if err != nil || req.Resource != "/res"+strconv.Itoa(i) {
+			t.Fatalf("the last request for service is incorrect: %v, %v", req, err)
+		}
+	}
+
+	requests, _, err := s.GetDiscoverRequests("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(requests)) > store.MaxDiscoverRequestNum {
+		t.Errorf("should not exceed max number, got %d", len(requests))
+	}
+	requests, _, err = s.GetDiscoverRequests("erp0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range requests {
+		if req.ServiceName != "erp0" {
+			t.Errorf("unexpected request of service %q", req.ServiceName)
+		}
+	}
+	req, _, err := s.GetLastDiscoverRequest("")
+	if err != nil || req.Resource != "/res299" {
+		t.Errorf("the last request is incorrect: %v, %v", req, err)
+	}
+}
+
+func TestGetRequestsSinceRevision(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	for i := 0; i < 5; i++ {
+		if err := s.SaveDiscoverRequest(newRequest(i, "erp")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, revision, err := s.GetLastDiscoverRequest("erp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 5; i < 10; i++ {
+		if err := s.SaveDiscoverRequest(newRequest(i, "erp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveDiscoverRequest(newRequest(i, "crm")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requests, newRevision, err := s.GetDiscoverRequestsSinceRevision("erp", revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 5 {
+		t.Fatalf("requests number should be 5, got %d", len(requests))
+	}
+	for i, req := range requests {
+		if req.Resource != "/res"+strconv.Itoa(i+5) {
+			t.Errorf("requests sequence or content is incorrect: %v", req)
+		}
+	}
+	if requests, _, _ := s.GetDiscoverRequestsSinceRevision("erp", newRevision); len(requests) != 0 {
+		t.Errorf("no request since revision %d, got %d", newRevision, len(requests))
+	}
+}
+
+func TestResetDiscoverRequests(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	for i := 0; i < 100; i++ {
+		if err := s.SaveDiscoverRequest(newRequest(i, "erp"+strconv.Itoa(i%10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.ResetDiscoverRequests("erp1"); err != nil {
+		t.Fatal(err)
+	}
+	if requests, _, _ := s.GetDiscoverRequests("erp1"); len(requests) != 0 {
+		t.Errorf("requests of erp1 should be reset, got %d", len(requests))
+	}
+	if requests, _, _ := s.GetDiscoverRequests(""); len(requests) != 90 {
+		t.Errorf("expect 90 requests, got %d", len(requests))
+	}
+	if err := s.ResetDiscoverRequests(""); err != nil {
+		t.Fatal(err)
+	}
+	if requests, _, _ := s.GetDiscoverRequests(""); len(requests) != 0 {
+		t.Errorf("all requests should be reset, got %d", len(requests))
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := s.SaveDiscoverRequest(newRequest(i, "erp")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	services, _, err := s.GeneratePolicies("erp", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service, ok := services["erp"]; !ok || len(service.Policies) == 0 {
+		t.Errorf("policies should be generated for service erp, got %v", services)
+	}
+}
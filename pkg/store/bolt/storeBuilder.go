@@ -0,0 +1,150 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+const (
+	StoreType = "bolt"
+
+	//Following are keys of bolt store properties
+	BoltDBPathKey      = "BoltDBPath"
+	BoltOpenTimeoutKey = "BoltOpenTimeout"
+	BoltNoSyncKey      = "BoltNoSync"
+	// BoltPollIntervalKey is how often watchers read the change log for the changes committed by other processes
+	BoltPollIntervalKey = "BoltPollInterval"
+	// BoltChangeRetentionKey is how long changes are kept in the change log, watchers falling further behind reload
+	// all policies
+	BoltChangeRetentionKey = "BoltChangeRetention"
+
+	BoltDBPathFlagName          = "boltstore-path"
+	BoltOpenTimeoutFlagName     = "boltstore-openTimeout"
+	BoltNoSyncFlagName          = "boltstore-nosync"
+	BoltPollIntervalFlagName    = "boltstore-pollInterval"
+	BoltChangeRetentionFlagName = "boltstore-changeRetention"
+
+	//default property values
+	DefaultBoltStorePath            = "/tmp/speedle-bolt-store.db"
+	DefaultBoltStoreOpenTimeout     = 10 * time.Second
+	DefaultBoltStoreNoSync          = false
+	DefaultBoltStorePollInterval    = time.Second
+	DefaultBoltStoreChangeRetention = time.Hour
+)
+
+type BoltStoreBuilder struct{}
+
+func (bsb BoltStoreBuilder) NewStore(config map[string]interface{}) (pms.PolicyStoreManager, error) {
+	path, ok := config[BoltDBPathKey].(string)
+	if !ok || len(path) == 0 {
+		return nil, errors.New(errors.ConfigError, "configure item BoltDBPath is not found")
+	}
+
+	timeout := DefaultBoltStoreOpenTimeout
+	if val, ok := config[BoltOpenTimeoutKey]; ok {
+		var err error
+		if timeout, err = convertValueToDuration(val, BoltOpenTimeoutKey); err != nil {
+			return nil, err
+		}
+	}
+
+	noSync := DefaultBoltStoreNoSync
+	if val, ok := config[BoltNoSyncKey]; ok {
+		var err error
+		if noSync, err = convertValueToBool(val, BoltNoSyncKey); err != nil {
+			return nil, err
+		}
+	}
+
+	pollInterval := DefaultBoltStorePollInterval
+	if val, ok := config[BoltPollIntervalKey]; ok {
+		var err error
+		if pollInterval, err = convertValueToDuration(val, BoltPollIntervalKey); err != nil {
+			return nil, err
+		}
+		if pollInterval <= 0 {
+			return nil, errors.Errorf(errors.ConfigError, "configure item %s must be positive", BoltPollIntervalKey)
+		}
+	}
+
+	retention := DefaultBoltStoreChangeRetention
+	if val, ok := config[BoltChangeRetentionKey]; ok {
+		var err error
+		if retention, err = convertValueToDuration(val, BoltChangeRetentionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debugf("new bolt store: path = %q, openTimeout = %v, noSync = %v, pollInterval = %v, changeRetention = %v",
+		path, timeout, noSync, pollInterval, retention)
+	db, err := openDB(path, timeout, noSync, pollInterval, retention)
+	if err != nil {
+		return nil, err
+	}
+	return newStore(db, DefaultRootBucket)
+}
+
+func convertValueToBool(val interface{}, keyName string) (bool, error) {
+	switch x := val.(type) {
+	case bool:
+		return x, nil
+	case string:
+		boolValue, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, errors.Wrapf(err, errors.ConfigError, "failed to convert configure %q", keyName)
+		}
+		return boolValue, nil
+	default:
+		return false, errors.Errorf(errors.ConfigError, "unsupported data type %T for configuration item %q", x, keyName)
+	}
+}
+
+// convertValueToDuration accepts a duration string like "5s", or a number of seconds
+func convertValueToDuration(val interface{}, keyName string) (time.Duration, error) {
+	switch x := val.(type) {
+	case float64:
+		return time.Duration(x * float64(time.Second)), nil
+	case int:
+		return time.Duration(x) * time.Second, nil
+	case string:
+		if seconds, err := strconv.Atoi(x); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+		duration, err := time.ParseDuration(x)
+		if err != nil {
+			return 0, errors.Wrapf(err, errors.ConfigError, "failed to convert configure %q", keyName)
+		}
+		return duration, nil
+	default:
+		return 0, errors.Errorf(errors.ConfigError, "unsupported data type %T for configuration item %q", x, keyName)
+	}
+}
+
+func (bsb BoltStoreBuilder) GetStoreParams() map[string]string {
+	return map[string]string{
+		BoltDBPathFlagName:          BoltDBPathKey,
+		BoltOpenTimeoutFlagName:     BoltOpenTimeoutKey,
+		BoltNoSyncFlagName:          BoltNoSyncKey,
+		BoltPollIntervalFlagName:    BoltPollIntervalKey,
+		BoltChangeRetentionFlagName: BoltChangeRetentionKey,
+	}
+}
+
+func init() {
+	pflag.String(BoltDBPathFlagName, DefaultBoltStorePath, "Store config: database file of bolt store.")
+	pflag.String(BoltOpenTimeoutFlagName, DefaultBoltStoreOpenTimeout.String(), "Store config: how long to wait for the lock of the bolt database file, which is held by each transaction of PMS or ADS.")
+	pflag.Bool(BoltNoSyncFlagName, DefaultBoltStoreNoSync, "Store config: skip fsync after each bolt transaction, faster but may lose the latest changes on a crash.")
+	pflag.String(BoltPollIntervalFlagName, DefaultBoltStorePollInterval.String(), "Store config: how often bolt store watchers read the changes committed by other processes.")
+	pflag.String(BoltChangeRetentionFlagName, DefaultBoltStoreChangeRetention.String(), "Store config: how long changes are kept in the change log of bolt store, 0 keeps them forever.")
+
+	store.Register(StoreType, BoltStoreBuilder{})
+}
@@ -0,0 +1,90 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"encoding/json"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+const (
+	// TenantsBucket is the top level bucket under which tenants are stored, one key per tenant
+	TenantsBucket = "speedle_tenants"
+	// TenantRootBucketPrefix is the prefix of the top level bucket holding the data of each tenant
+	TenantRootBucketPrefix = "speedle_ps_tenant/"
+)
+
+func tenantRootBucket(tenantName string) string {
+	return TenantRootBucketPrefix + tenantName
+}
+
+// ReadTenants reads all the stored tenants
+func (s *Store) ReadTenants() ([]*pms.Tenant, error) {
+	tenants := []*pms.Tenant{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(TenantsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var tenant pms.Tenant
+			if err := json.Unmarshal(v, &tenant); err != nil {
+				return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal tenant %q", k)
+			}
+			tenants = append(tenants, &tenant)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to read tenants")
+	}
+	return tenants, nil
+}
+
+// WriteTenant creates or updates a tenant
+func (s *Store) WriteTenant(tenant *pms.Tenant) error {
+	value, err := json.Marshal(tenant)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal tenant")
+	}
+	err = s.db.update("", func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		b, err := tx.CreateBucketIfNotExists([]byte(TenantsBucket))
+		if err != nil {
+			return nil, err
+		}
+		return nil, b.Put([]byte(tenant.Name), value)
+	})
+	return wrapStoreError(err, "unable to write tenant %q", tenant.Name)
+}
+
+// RemoveTenant deletes a tenant together with all its policies and discover requests
+func (s *Store) RemoveTenant(tenantName string) error {
+	err := s.db.update("", func(tx *bbolt.Tx) ([]pms.StoreChangeEvent, error) {
+		b := tx.Bucket([]byte(TenantsBucket))
+		if b == nil || b.Get([]byte(tenantName)) == nil {
+			return nil, errors.Errorf(errors.EntityNotFound, "tenant %q is not found", tenantName)
+		}
+		if err := b.Delete([]byte(tenantName)); err != nil {
+			return nil, err
+		}
+		if err := tx.DeleteBucket([]byte(tenantRootBucket(tenantName))); err != nil && err != bbolt.ErrBucketNotFound {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return wrapStoreError(err, "unable to delete tenant %q", tenantName)
+	}
+	s.db.removeIndex(tenantRootBucket(tenantName))
+	return nil
+}
+
+// NewTenantStore creates the store of a tenant, which shares the bolt database but uses the tenant's own root bucket
+func (s *Store) NewTenantStore(tenantName string) (pms.PolicyStoreManager, error) {
+	s.db.acquire()
+	return newStore(s.db, tenantRootBucket(tenantName))
+}
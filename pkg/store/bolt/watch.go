@@ -0,0 +1,265 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	bbolt "github.com/coreos/bbolt"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ChangesBucket is the change log of a root bucket, the changes are keyed by their revisions. The sequence of
+	// the bucket is the last revision pruned from the log.
+	ChangesBucket = "changes"
+
+	// changesPageSize is the max number of changes read from the change log at once by watchers
+	changesPageSize = 500
+)
+
+// change is a change in the change log
+type change struct {
+	// Time is when the change is committed, in seconds since the Unix epoch
+	Time    int64           `json:"time"`
+	Type    pms.EventType   `json:"type"`
+	Content json.RawMessage `json:"content,omitempty"`
+}
+
+// logChange appends the change of event e to the change log
+func logChange(changes *bbolt.Bucket, e pms.StoreChangeEvent, now time.Time) error {
+	c := change{Time: now.Unix(), Type: e.Type}
+	if e.Content != nil {
+		content, err := json.Marshal(e.Content)
+		if err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to marshal change")
+		}
+		c.Content = content
+	}
+	value, err := json.Marshal(&c)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal change")
+	}
+	return changes.Put(revisionKey(e.ID), value)
+}
+
+// pruneChanges deletes the changes committed before, the last pruned revision is recorded, so watchers falling
+// behind it know they have missed changes
+func pruneChanges(changes *bbolt.Bucket, before time.Time) error {
+	pruned := changes.Sequence()
+	c := changes.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.First() {
+		var ch change
+		if err := json.Unmarshal(v, &ch); err == nil && ch.Time >= before.Unix() {
+			break
+		}
+		pruned = binary.BigEndian.Uint64(k)
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return changes.SetSequence(pruned)
+}
+
+// readChanges reads at most limit changes after revision, all of them if limit is not positive. pruned is true if
+// some of the changes are pruned from the change log.
+func readChanges(changes *bbolt.Bucket, revision int64, limit int) (events []pms.StoreChangeEvent, pruned bool, err error) {
+	if revision < int64(changes.Sequence()) {
+		return nil, true, nil
+	}
+	c := changes.Cursor()
+	for k, v := c.Seek(revisionKey(revision + 1)); k != nil && (limit <= 0 || len(events) < limit); k, v = c.Next() {
+		var ch change
+		if err := json.Unmarshal(v, &ch); err != nil {
+			return nil, false, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal change %q", v)
+		}
+		e := pms.StoreChangeEvent{Type: ch.Type, ID: int64(binary.BigEndian.Uint64(k))}
+		if e.Content, err = decodeChange(ch.Type, ch.Content); err != nil {
+			return nil, false, err
+		}
+		events = append(events, e)
+	}
+	return events, false, nil
+}
+
+// decodeChange decodes the content of an event from the content of a change
+func decodeChange(eventType pms.EventType, content json.RawMessage) (interface{}, error) {
+	var err error
+	switch eventType {
+	case pms.SERVICE_ADD:
+		var service pms.Service
+		if err = json.Unmarshal(content, &service); err == nil {
+			return &service, nil
+		}
+	case pms.SERVICE_DELETE, pms.FUNCTION_DELETE:
+		var names []string
+		if err = json.Unmarshal(content, &names); err == nil {
+			return names, nil
+		}
+	case pms.POLICY_ADD, pms.POLICY_DELETE:
+		var policies []struct {
+			ServiceName string
+			Data        *pms.Policy
+		}
+		if err = json.Unmarshal(content, &policies); err == nil {
+			data := make([]pms.StoreUpdateData, 0, len(policies))
+			for _, policy := range policies {
+				data = append(data, pms.StoreUpdateData{ServiceName: policy.ServiceName, Data: policy.Data})
+			}
+			return data, nil
+		}
+	case pms.ROLEPOLICY_ADD, pms.ROLEPOLICY_DELETE:
+		var rolePolicies []struct {
+			ServiceName string
+			Data        *pms.RolePolicy
+		}
+		if err = json.Unmarshal(content, &rolePolicies); err == nil {
+			data := make([]pms.StoreUpdateData, 0, len(rolePolicies))
+			for _, rolePolicy := range rolePolicies {
+				data = append(data, pms.StoreUpdateData{ServiceName: rolePolicy.ServiceName, Data: rolePolicy.Data})
+			}
+			return data, nil
+		}
+	case pms.FUNCTION_ADD:
+		var function pms.Function
+		if err = json.Unmarshal(content, &function); err == nil {
+			return &function, nil
+		}
+	default:
+		return nil, nil
+	}
+	return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal change %q", content)
+}
+
+// changesAfter returns at most changesPageSize changes after revision. If some of them are pruned from the change
+// log, the whole store is returned as a SYNC_RELOAD event instead.
+func (s *Store) changesAfter(revision int64) (events []pms.StoreChangeEvent, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		var pruned bool
+		if events, pruned, err = readChanges(s.bucket(tx, ChangesBucket), revision, changesPageSize); err != nil || !pruned {
+			return err
+		}
+		log.Warningf("Changes after revision %d are pruned, reloading all policies.", revision)
+		ps, err := s.readPolicyStore(tx)
+		if err != nil {
+			return err
+		}
+		events = []pms.StoreChangeEvent{{Type: pms.SYNC_RELOAD, ID: s.revision(tx), Content: ps}}
+		return nil
+	})
+	return events, err
+}
+
+// Watch returns a channel of the changes made to the store, including the changes made by other processes sharing
+// the database file. Changes are watched from the revision of the policy store read by ReadPolicyStore, or from the
+// current revision if it is not read, or from the last delivered one if the store is watched again. The change log
+// is polled every poll interval, and read at once when changes are committed by this process.
+func (s *Store) Watch() (pms.StorageChangeChannel, error) {
+	log.Info("Entering Watch...")
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	if s.watcher != nil {
+		s.stopWatcher()
+	}
+	revision := atomic.LoadInt64(&s.watchRevision)
+	if revision < 0 {
+		err := s.view(func(tx *bbolt.Tx) error {
+			revision = s.revision(tx)
+			return nil
+		})
+		if err != nil {
+			return nil, wrapStoreError(err, "unable to read the revision of the store")
+		}
+	}
+	s.watcher = &watcher{
+		store:    s,
+		revision: revision,
+		out:      make(chan pms.StoreChangeEvent),
+		notify:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.db.addWatcher(s.watcher)
+	go s.watcher.run()
+	return s.watcher.out, nil
+}
+
+func (s *Store) StopWatch() {
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	if s.watcher != nil {
+		log.Info("Exiting Watch...")
+		s.stopWatcher()
+	}
+}
+
+// stopWatcher stops the watcher, the store is watched from its last delivered revision next time
+func (s *Store) stopWatcher() {
+	s.db.removeWatcher(s.watcher)
+	close(s.watcher.stop)
+	<-s.watcher.done
+	atomic.StoreInt64(&s.watchRevision, s.watcher.revision)
+	s.watcher = nil
+}
+
+// watcher polls the change log of a store, and delivers the changes after revision to out
+type watcher struct {
+	store *Store
+	// revision is the revision of the last delivered change, it's only accessed by run until done is closed
+	revision int64
+	out      chan pms.StoreChangeEvent
+	notify   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// wakeUp makes the watcher read the change log at once
+func (w *watcher) wakeUp() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) run() {
+	defer func() {
+		close(w.out)
+		close(w.done)
+	}()
+	ticker := time.NewTicker(w.store.db.pollInterval)
+	defer ticker.Stop()
+	for {
+		events, err := w.store.changesAfter(w.revision)
+		if err != nil {
+			if errors.Code(err) == errors.EntityNotFound {
+				// the tenant of the store is deleted, until the watch is stopped
+				log.Debugf("Unable to read the changes after revision %d, err: %v.", w.revision, err)
+			} else {
+				log.Warningf("Unable to read the changes after revision %d, err: %v.", w.revision, err)
+			}
+		}
+		for _, e := range events {
+			select {
+			case w.out <- e:
+				w.revision = e.ID
+			case <-w.stop:
+				return
+			}
+		}
+		if len(events) >= changesPageSize {
+			continue
+		}
+		select {
+		case <-w.notify:
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
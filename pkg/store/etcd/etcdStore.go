@@ -6,10 +6,13 @@ package etcd
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
 	"github.com/oracle/speedle/pkg/suid"
 
 	"github.com/oracle/speedle/api/pms"
@@ -34,12 +37,16 @@ const (
 )
 
 type Store struct {
-	client       *clientv3.Client
-	Config       *clientv3.Config
-	KeyPrefix    string
-	stop         chan struct{}
-	embeddedInst *embed.Etcd
-	embeddedDir  string
+	client         *clientv3.Client
+	Config         *clientv3.Config
+	KeyPrefix      string
+	stop           chan struct{}
+	embeddedInst   *embed.Etcd
+	embeddedDir    string
+	discoverPrefix string
+	// revision is the etcd revision of the last change delivered by Watch, accessed atomically
+	revision int64
+	search   searchIndex
 }
 
 func (s *Store) destroy() error {
@@ -55,19 +62,36 @@ func (s *Store) destroy() error {
 
 //read policy store from etcd3
 func (s *Store) ReadPolicyStore() (*pms.PolicyStore, error) {
-	serviceNames, err := s.GetServiceNames()
+	revision, err := s.currentRevision()
+	if err != nil {
+		return nil, err
+	}
+	ps, err := s.readPolicyStoreAt(revision)
+	if err != nil {
+		return nil, err
+	}
+	// Watch starts from the revision of the policies read first, so that the changes made between the read and the
+	// start of the watch are delivered
+	atomic.CompareAndSwapInt64(&s.revision, 0, revision)
+	return ps, nil
+}
+
+// readPolicyStoreAt reads the policy store as of etcd revision, so that it is consistent with the changes watched
+// after the revision
+func (s *Store) readPolicyStoreAt(revision int64) (*pms.PolicyStore, error) {
+	serviceNames, err := s.getServiceNames(clientv3.WithRev(revision))
 	if err != nil {
 		return nil, err
 	}
 	var ps pms.PolicyStore
 	for _, serviceName := range serviceNames {
-		service, err := s.GetService(serviceName)
+		service, err := s.getService(serviceName, clientv3.WithRev(revision))
 		if err != nil {
 			return nil, err
 		}
 		ps.Services = append(ps.Services, service)
 	}
-	functions, err := s.ListAllFunctions("")
+	functions, err := s.listFunctions("", clientv3.WithRev(revision))
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +121,12 @@ func (s *Store) WritePolicyStore(ps *pms.PolicyStore) error {
 }
 
 func (s *Store) GetServiceNames() (serviceNames []string, err error) {
+	return s.getServiceNames()
+}
+
+func (s *Store) getServiceNames(opts ...clientv3.OpOption) (serviceNames []string, err error) {
 	serviceKeyPrefix := s.KeyPrefix + ServicesKey + KeySeparator
-	responses, err := s.prefixGet(serviceKeyPrefix, clientv3.WithKeysOnly())
+	responses, err := s.prefixGet(serviceKeyPrefix, append(opts, clientv3.WithKeysOnly())...)
 	if err != nil {
 		return serviceNames, err
 	}
@@ -238,9 +266,13 @@ func (s *Store) GetServiceItself(serviceName string) (*pms.Service, error) {
 }
 
 func (s *Store) GetService(serviceName string) (*pms.Service, error) {
+	return s.getService(serviceName)
+}
+
+func (s *Store) getService(serviceName string, opts ...clientv3.OpOption) (*pms.Service, error) {
 	var service pms.Service
 	serviceKey := s.KeyPrefix + ServicesKey + KeySeparator + serviceName + KeySeparator
-	responses, err := s.prefixGet(serviceKey)
+	responses, err := s.prefixGet(serviceKey, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -367,6 +399,7 @@ func (s *Store) CreateService(service *pms.Service) error {
 		if !txnResp.Succeeded {
 			return errors.Errorf(errors.EntityAlreadyExists, "service %q already exists", service.Name)
 		}
+		s.wrote(txnResp)
 		startIndex = endIndex
 	}
 	if fail { //clean all data inserted
@@ -397,18 +430,20 @@ func (s *Store) DeleteService(serviceName string) error {
 	if !txnResp.Succeeded {
 		return errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
 func (s *Store) DeleteServices() error {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
-	_, err := s.client.KV.Txn(ctx).Then(
+	txnResp, err := s.client.KV.Txn(ctx).Then(
 		clientv3.OpDelete(s.KeyPrefix+ServicesKey+KeySeparator, clientv3.WithPrefix()),
 	).Commit()
 	if err != nil {
 		return err
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
@@ -417,15 +452,8 @@ func (s *Store) Type() string {
 	return StoreType
 }
 
-func validateFunc(function *pms.Function) error {
-	if function.Name == "" || function.FuncURL == "" {
-		return errors.New(errors.InvalidRequest, "\"name\" and \"funcURL\" in function definition can not be empty")
-	}
-	return nil
-}
-
 func (s *Store) CreateFunction(function *pms.Function) (*pms.Function, error) {
-	if err := validateFunc(function); err != nil {
+	if err := store.ValidateFunction(function); err != nil {
 		return nil, err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
@@ -499,10 +527,14 @@ func (s *Store) GetFunction(funcName string) (*pms.Function, error) {
 }
 
 func (s *Store) ListAllFunctions(filter string) ([]*pms.Function, error) {
+	return s.listFunctions(filter)
+}
+
+func (s *Store) listFunctions(filter string, opts ...clientv3.OpOption) ([]*pms.Function, error) {
 	f := parseFilter(filter)
 
 	functionKeyPrefix := s.KeyPrefix + FunctionsKey + KeySeparator
-	responses, err := s.prefixGet(functionKeyPrefix)
+	responses, err := s.prefixGet(functionKeyPrefix, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -543,27 +575,39 @@ func (s *Store) GetFunctionCount() (int64, error) {
 	return getResp.Count, nil
 }
 
+// Watch returns a channel of the changes made to the store. Changes are watched from the revision of the policy
+// store read by ReadPolicyStore, or from the current revision if it is not read, or from the last delivered one if
+// the store is watched again. Watch restarted after failures resumes from the last
+// delivered revision, so no change is missed; if that revision is compacted meanwhile, the whole store is delivered
+// as a SYNC_RELOAD event instead.
 func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 	log.Info("Entering Watch...")
+	if atomic.LoadInt64(&s.revision) == 0 {
+		if revision, err := s.currentRevision(); err != nil {
+			log.Warningf("Unable to get the current revision due to error %v, watching from the revision of the watch.\n", err)
+		} else {
+			atomic.StoreInt64(&s.revision, revision)
+		}
+	}
 	evalChan := make(chan pms.StoreChangeEvent)
-	s.stop = make(chan struct{})
+	stop := make(chan struct{})
+	s.stop = stop
 	errChan := make(chan error)
 	stopChan := make(chan struct{})
 	go func() {
 		defer func() {
 			close(evalChan)
-			close(s.stop)
+			close(stop)
 			close(errChan)
 			close(stopChan)
 			log.Info("Exiting Watch...")
 		}()
 	loop:
 		for {
-			//TODO: reload policy store in case missing any changes during watch failure
-			go watch(evalChan, s, errChan, stopChan)
+			go watch(evalChan, s, stop, errChan, stopChan)
 			select {
 			case err := <-errChan:
-				log.Warningf("Error %v happens, restart watching...\n", err)
+				log.Warningf("Error %v happens, restart watching from revision %d...\n", err, atomic.LoadInt64(&s.revision))
 				continue
 			case <-stopChan:
 				log.Warning("Receiving stop signal, stop Watching...")
@@ -575,7 +619,7 @@ func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 	return evalChan, nil
 }
 
-func watch(evalChan chan pms.StoreChangeEvent, s *Store, errChan chan error, stopChan chan struct{}) {
+func watch(evalChan chan pms.StoreChangeEvent, s *Store, stop chan struct{}, errChan chan error, stopChan chan struct{}) {
 	watchID := time.Now().Unix()
 	log.Infof("Entering watch %v...", watchID)
 	cli, err := clientv3.New(*s.Config)
@@ -599,59 +643,50 @@ func watch(evalChan chan pms.StoreChangeEvent, s *Store, errChan chan error, sto
 		return
 	}
 
-	etcdChan := cli.Watch(context.Background(), s.KeyPrefix, clientv3.WithPrefix())
+	// progress notifications advance the revision while the store is not changed
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithProgressNotify()}
+	if revision := atomic.LoadInt64(&s.revision); revision > 0 {
+		opts = append(opts, clientv3.WithRev(revision+1))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	etcdChan := cli.Watch(ctx, s.KeyPrefix, opts...)
 
 	for {
 		select {
 		// receive watch response from etcd
-		case resp := <-etcdChan:
+		case resp, ok := <-etcdChan:
+			if !ok {
+				errChan <- errors.New(errors.StoreError, "watch channel is closed")
+				return
+			}
+			if resp.CompactRevision != 0 {
+				log.Warningf("Changes after revision %d are compacted, reloading all policies\n", atomic.LoadInt64(&s.revision))
+				if err := s.resync(evalChan); err != nil {
+					errChan <- err
+					return
+				}
+				// the watch is canceled by etcd, it's restarted from the revision of the reloaded policies
+				errChan <- errors.Wrap(resp.Err(), errors.StoreError, "watch revision is compacted")
+				return
+			}
 			if err := resp.Err(); err != nil {
 				log.Warningf("Error happens in watch response, %v\n", err)
 				err = errors.Wrap(err, errors.StoreError, "error found in watch response")
 				errChan <- err
 				return
 			}
-			for _, e := range resp.Events {
-				id := time.Now().Unix()
-				//Note: In each policy/rolePolicy creation/deletion, service node (s.KeyPrefix+serviceName+keySeparator) will be updated.
-				//so we could only check the event on service node.
-				if clientv3.EventTypeDelete == e.Type {
-					if strings.HasPrefix(string(e.Kv.Key), s.KeyPrefix+ServicesKey+KeySeparator) {
-						serviceName := strings.TrimPrefix(string(e.Kv.Key), s.KeyPrefix+ServicesKey+KeySeparator)
-						serviceName = strings.TrimSuffix(serviceName, KeySeparator)
-						if strings.Index(serviceName, KeySeparator) == -1 {
-							evalChan <- pms.StoreChangeEvent{Type: pms.SERVICE_DELETE, ID: id, Content: []string{serviceName}}
-						}
-					} else if strings.HasPrefix(string(e.Kv.Key), s.KeyPrefix+FunctionsKey+KeySeparator) {
-						functionName := strings.TrimPrefix(string(e.Kv.Key), s.KeyPrefix+FunctionsKey+KeySeparator)
-						evalChan <- pms.StoreChangeEvent{Type: pms.FUNCTION_DELETE, ID: id, Content: []string{functionName}}
-					}
-
-				} else if clientv3.EventTypePut == e.Type {
-					if strings.HasPrefix(string(e.Kv.Key), s.KeyPrefix+ServicesKey+KeySeparator) {
-						serviceName := strings.TrimPrefix(string(e.Kv.Key), s.KeyPrefix+ServicesKey+KeySeparator)
-						serviceName = strings.TrimSuffix(serviceName, KeySeparator)
-						if strings.Index(serviceName, KeySeparator) == -1 {
-							service, err := s.GetService(serviceName)
-							if err != nil {
-								log.Warningf("Unable get service due to error %v.\n", err)
-								continue
-							}
-							evalChan <- pms.StoreChangeEvent{Type: pms.SERVICE_ADD, ID: id, Content: service}
-						}
-					} else if strings.HasPrefix(string(e.Kv.Key), s.KeyPrefix+FunctionsKey+KeySeparator) {
-						functionName := strings.TrimPrefix(string(e.Kv.Key), s.KeyPrefix+FunctionsKey+KeySeparator)
-						function, err := s.GetFunction(functionName)
-						if err != nil {
-							log.Warningf("Unable to get function due to error %v.\n", err)
-						}
-						evalChan <- pms.StoreChangeEvent{Type: pms.FUNCTION_ADD, ID: id, Content: function}
-
-					}
-				}
+			for _, e := range s.decodeEvents(resp.Events) {
+				evalChan <- e
+				atomic.StoreInt64(&s.revision, e.ID)
+			}
+			if len(resp.Events) > 0 {
+				atomic.StoreInt64(&s.revision, resp.Events[len(resp.Events)-1].Kv.ModRevision)
+			} else if resp.IsProgressNotify() {
+				atomic.StoreInt64(&s.revision, resp.Header.Revision)
 			}
 			// receive the stop signal
-		case <-s.stop:
+		case <-stop:
 			log.Warning("Receiving stop signal")
 			stopChan <- struct{}{}
 			return
@@ -665,6 +700,40 @@ func watch(evalChan chan pms.StoreChangeEvent, s *Store, errChan chan error, sto
 
 }
 
+// resync delivers the whole store as a SYNC_RELOAD event. If the store can't be read, a FULL_RELOAD event is
+// delivered so the evaluator reloads the policies by itself.
+func (s *Store) resync(evalChan chan pms.StoreChangeEvent) error {
+	revision, err := s.currentRevision()
+	if err != nil {
+		return err
+	}
+	// The policies are read as of the revision, so that the watch resumes right after them
+	event := pms.StoreChangeEvent{Type: pms.SYNC_RELOAD, ID: revision}
+	if event.Content, err = s.readPolicyStoreAt(revision); err != nil {
+		log.Warningf("Unable to read policy store due to error %v, requesting full reload.\n", err)
+		event = pms.StoreChangeEvent{Type: pms.FULL_RELOAD, ID: revision}
+	}
+	evalChan <- event
+	atomic.StoreInt64(&s.revision, revision)
+	return nil
+}
+
+// currentRevision returns the current revision of the etcd cluster
+func (s *Store) currentRevision() (int64, error) {
+	resp, err := s.timeOutGet(s.KeyPrefix, clientv3.WithCountOnly())
+	if err != nil {
+		return 0, errors.Wrap(err, errors.StoreError, "failed to get the revision from etcd server")
+	}
+	return resp.Header.Revision, nil
+}
+
+// Revision returns the etcd revision of the last change delivered by Watch. Replicas watching the same etcd cluster
+// serve the same policies once they report the same revision.
+func (s *Store) Revision() (int64, bool) {
+	revision := atomic.LoadInt64(&s.revision)
+	return revision, revision > 0
+}
+
 func (s *Store) StopWatch() {
 	if s.stop != nil {
 		s.stop <- struct{}{}
@@ -812,13 +881,14 @@ func (s *Store) DeletePolicy(serviceName string, id string) error {
 	if !txnResp.Succeeded {
 		return errors.Errorf(errors.EntityNotFound, "policy %q is not found in service %q", id, serviceName)
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
 func (s *Store) DeletePolicies(serviceName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
-	_, err := s.client.KV.Txn(ctx).Then(
+	txnResp, err := s.client.KV.Txn(ctx).Then(
 		clientv3.OpDelete(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator+PoliciesKey, clientv3.WithPrefix()),
 		//make sure updating service key is the last operation, so watch could work correctly
 		clientv3.OpPut(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator, ""),
@@ -826,6 +896,7 @@ func (s *Store) DeletePolicies(serviceName string) error {
 	if err != nil {
 		return errors.Wrap(err, errors.StoreError, "failed to delete all policies from etcd server")
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
@@ -858,6 +929,7 @@ func (s *Store) CreatePolicy(serviceName string, policy *pms.Policy) (*pms.Polic
 	if !txnResp.Succeeded {
 		return nil, errors.Errorf(errors.EntityAlreadyExists, "policy %q already exists in service %q", policy.ID, serviceName)
 	}
+	s.wrote(txnResp)
 	return &dupPolicy, nil
 }
 
@@ -999,13 +1071,14 @@ func (s *Store) DeleteRolePolicy(serviceName string, id string) error {
 	if !txnResp.Succeeded {
 		return errors.Errorf(errors.EntityNotFound, "role policy %q is not found in service %q", id, serviceName)
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
 func (s *Store) DeleteRolePolicies(serviceName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
-	_, err := s.client.KV.Txn(ctx).Then(
+	txnResp, err := s.client.KV.Txn(ctx).Then(
 		clientv3.OpDelete(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator+RolePoliciesKey, clientv3.WithPrefix()),
 		//make sure updating service key is the last operation, so watch could work correctly
 		clientv3.OpPut(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator, ""),
@@ -1013,6 +1086,7 @@ func (s *Store) DeleteRolePolicies(serviceName string) error {
 	if err != nil {
 		return errors.Wrap(err, errors.StoreError, "failed to delete all policies from etcd server")
 	}
+	s.wrote(txnResp)
 	return nil
 }
 
@@ -1045,6 +1119,7 @@ func (s *Store) CreateRolePolicy(serviceName string, rolePolicy *pms.RolePolicy)
 	if !txnResp.Succeeded {
 		return nil, errors.Errorf(errors.EntityAlreadyExists, "role policy %q already exists in service %q", dupRolePolicy.ID, serviceName)
 	}
+	s.wrote(txnResp)
 	return &dupRolePolicy, nil
 }
 
@@ -1106,3 +1181,76 @@ func nameFilter(name string, f *filter) bool { //this filter function return tru
 		return true
 	}
 }
+
+// rangeAfter reads up to limit key values with prefix, whose keys are greater than prefix+after, ordered by key
+func (s *Store) rangeAfter(prefix string, after string, limit int) (*clientv3.GetResponse, error) {
+	start := prefix + after
+	if len(after) > 0 {
+		start += "\x00"
+	}
+	return s.timeOutGet(start, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)), clientv3.WithLimit(int64(limit)),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+}
+
+// ServicePage returns up to limit services whose names are greater than afterName, ordered by name, without their
+// policies and role policies. Only the keys are read to find the names of services.
+func (s *Store) ServicePage(afterName string, limit int) ([]*pms.Service, error) {
+	serviceNames, err := s.GetServiceNames()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.StoreError, "failed to read services")
+	}
+	sort.Strings(serviceNames)
+	start := sort.SearchStrings(serviceNames, afterName)
+	if start < len(serviceNames) && serviceNames[start] == afterName {
+		start++
+	}
+	services := []*pms.Service{}
+	for _, serviceName := range serviceNames[start:] {
+		if len(services) == limit {
+			break
+		}
+		service, err := s.GetServiceItself(serviceName)
+		if errors.Code(err) == errors.EntityNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, errors.StoreError, "failed to read services")
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// PolicyPage returns up to limit policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) PolicyPage(serviceName string, afterID string, limit int) ([]*pms.Policy, error) {
+	resp, err := s.rangeAfter(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator+PoliciesKey+KeySeparator, afterID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.StoreError, "failed to read policies")
+	}
+	policies := []*pms.Policy{}
+	for _, kv := range resp.Kvs {
+		var policy pms.Policy
+		if err := json.Unmarshal(kv.Value, &policy); err != nil {
+			return nil, errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+		}
+		policies = append(policies, &policy)
+	}
+	return policies, nil
+}
+
+// RolePolicyPage returns up to limit role policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) RolePolicyPage(serviceName string, afterID string, limit int) ([]*pms.RolePolicy, error) {
+	resp, err := s.rangeAfter(s.KeyPrefix+ServicesKey+KeySeparator+serviceName+KeySeparator+RolePoliciesKey+KeySeparator, afterID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.StoreError, "failed to read role policies")
+	}
+	rolePolicies := []*pms.RolePolicy{}
+	for _, kv := range resp.Kvs {
+		var rolePolicy pms.RolePolicy
+		if err := json.Unmarshal(kv.Value, &rolePolicy); err != nil {
+			return nil, errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+		}
+		rolePolicies = append(rolePolicies, &rolePolicy)
+	}
+	return rolePolicies, nil
+}
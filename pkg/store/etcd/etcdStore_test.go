@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/pkg/cfg"
 	"github.com/oracle/speedle/pkg/store"
+	"golang.org/x/net/context"
 )
 
 var storeConfig *cfg.StoreConfig
@@ -689,24 +691,359 @@ func TestWatch(t *testing.T) {
 		t.Fatal("fail to write application:", err)
 	}
 
+	receive := func(eventType pms.EventType) pms.StoreChangeEvent {
+		select {
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fail to receive event %d", eventType)
+		case e := <-ch:
+			if e.Type != eventType {
+				t.Fatalf("expected event type: %d, received event type :%d\n", eventType, e.Type)
+			}
+			return e
+		}
+		return pms.StoreChangeEvent{}
+	}
+	e := receive(pms.SERVICE_ADD)
+	if service := e.Content.(*pms.Service); len(service.RolePolicies) != 2 {
+		t.Errorf("service should be added with 2 role policies, got %v", service.RolePolicies)
+	}
+
+	//policies and role policies are reported one by one
+	policy, err := store.CreatePolicy("app1_new", &pms.Policy{Name: "p1", Effect: "grant", Principals: [][]string{{"user:Alice"}}})
+	if err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	e = receive(pms.POLICY_ADD)
+	if data := e.Content.([]pms.StoreUpdateData); len(data) != 1 || data[0].ServiceName != "app1_new" || data[0].Data.(*pms.Policy).Name != "p1" {
+		t.Errorf("unexpected content %v of policy add event", data)
+	}
+	if err := store.DeletePolicy("app1_new", policy.ID); err != nil {
+		t.Fatal("fail to delete policy:", err)
+	}
+	e = receive(pms.POLICY_DELETE)
+	if data := e.Content.([]pms.StoreUpdateData); len(data) != 1 || data[0].Data.(*pms.Policy).ID != policy.ID {
+		t.Errorf("unexpected content %v of policy delete event", data)
+	}
+	if _, err := store.CreateRolePolicy("app1_new", &pms.RolePolicy{Name: "rp3", Effect: "grant", Roles: []string{"role3"}}); err != nil {
+		t.Fatal("fail to create role policy:", err)
+	}
+	receive(pms.ROLEPOLICY_ADD)
+	if err := store.DeleteRolePolicies("app1_new"); err != nil {
+		t.Fatal("fail to delete role policies:", err)
+	}
+	e = receive(pms.ROLEPOLICY_DELETE)
+	if data := e.Content.([]pms.StoreUpdateData); len(data) != 3 {
+		t.Errorf("all the 3 role policies should be deleted in one event, got %v", data)
+	}
+	if _, err := store.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); err != nil {
+		t.Fatal("fail to create function:", err)
+	}
+	if e = receive(pms.FUNCTION_ADD); e.Content.(*pms.Function).Name != "f1" {
+		t.Errorf("unexpected content %v of function add event", e.Content)
+	}
+	if err := store.DeleteFunction("f1"); err != nil {
+		t.Fatal("fail to delete function:", err)
+	}
+	receive(pms.FUNCTION_DELETE)
+
+	//a service created by several transactions is added once
+	bigService := pms.Service{Name: "app_big", Type: pms.TypeApplication}
+	for i := 0; i < 300; i++ {
+		bigService.Policies = append(bigService.Policies, &pms.Policy{Name: fmt.Sprintf("p%d", i), Effect: "grant"})
+	}
+	if err := store.CreateService(&bigService); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if e = receive(pms.SERVICE_ADD); len(e.Content.(*pms.Service).Policies) != 300 {
+		t.Errorf("service should be added with 300 policies, got %d", len(e.Content.(*pms.Service).Policies))
+	}
+	store.DeleteService("app_big")
+	receive(pms.SERVICE_DELETE)
+
+	//delete app
+	store.DeleteService("app1_new")
+	if e = receive(pms.SERVICE_DELETE); len(e.Content.([]string)) != 1 || e.Content.([]string)[0] != "app1_new" {
+		t.Errorf("unexpected content %v of service delete event", e.Content)
+	}
+}
+
+func TestWatchResume(t *testing.T) {
+	s, err := store.NewStore(storeConfig.StoreType, storeConfig.StoreProps)
+	if err != nil {
+		t.Fatal("fail to new etcd3 store:", err)
+	}
+	etcdStore := s.(*Store)
+	defer etcdStore.destroy()
+	defer s.DeleteService("app_resume")
+	defer s.DeleteService("app_compact")
+
+	if _, ok := etcdStore.Revision(); ok {
+		t.Error("revision should be unknown before watching")
+	}
+	watch := func() pms.StorageChangeChannel {
+		ch, err := s.Watch()
+		if err != nil {
+			t.Fatal("fail to watch:", err)
+		}
+		return ch
+	}
+	stop := func(ch pms.StorageChangeChannel) {
+		s.StopWatch()
+		for range ch {
+		}
+	}
+	receive := func(ch pms.StorageChangeChannel, eventType pms.EventType) pms.StoreChangeEvent {
+		select {
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fail to receive event %d", eventType)
+		case e := <-ch:
+			if e.Type != eventType {
+				t.Fatalf("expected event type: %d, received event type :%d\n", eventType, e.Type)
+			}
+			return e
+		}
+		return pms.StoreChangeEvent{}
+	}
+
+	ch := watch()
+	if err := s.CreateService(&pms.Service{Name: "app_resume", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	e := receive(ch, pms.SERVICE_ADD)
+	stop(ch)
+	if revision, ok := etcdStore.Revision(); !ok || revision != e.ID {
+		t.Errorf("revision should be %d, got %d", e.ID, revision)
+	}
+
+	//changes made while not watching are delivered when watched again
+	if _, err := s.CreatePolicy("app_resume", &pms.Policy{Name: "p1", Effect: "grant"}); err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	ch = watch()
+	e = receive(ch, pms.POLICY_ADD)
+	if data := e.Content.([]pms.StoreUpdateData); len(data) != 1 || data[0].Data.(*pms.Policy).Name != "p1" {
+		t.Errorf("unexpected content %v of policy add event", data)
+	}
+	stop(ch)
+
+	//the whole store is delivered if the changes are compacted
+	if err := s.CreateService(&pms.Service{Name: "app_compact", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if _, err := s.CreatePolicy("app_compact", &pms.Policy{Name: "p1", Effect: "grant"}); err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	revision, err := etcdStore.currentRevision()
+	if err != nil {
+		t.Fatal("fail to get revision:", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err := etcdStore.client.Compact(ctx, revision); err != nil {
+		t.Fatal("fail to compact:", err)
+	}
+	ch = watch()
+	e = receive(ch, pms.SYNC_RELOAD)
+	found := false
+	for _, service := range e.Content.(*pms.PolicyStore).Services {
+		found = found || service.Name == "app_compact"
+	}
+	if !found {
+		t.Error("compacted service app_compact should be reloaded")
+	}
+	if e.ID < revision {
+		t.Errorf("reload revision %d should not be less than the compacted revision %d", e.ID, revision)
+	}
+	//watch is resumed after the reload
+	if err := s.DeleteService("app_compact"); err != nil {
+		t.Fatal("fail to delete service:", err)
+	}
+	receive(ch, pms.SERVICE_DELETE)
+	stop(ch)
+}
+
+func TestWatchAfterRead(t *testing.T) {
+	s, err := store.NewStore(storeConfig.StoreType, storeConfig.StoreProps)
+	if err != nil {
+		t.Fatal("fail to new etcd3 store:", err)
+	}
+	defer s.(*Store).destroy()
+	defer s.DeleteService("app_after_read")
+
+	if _, err := s.ReadPolicyStore(); err != nil {
+		t.Fatal("fail to read policy store:", err)
+	}
+	//changes made between the read and the watch are delivered
+	if err := s.CreateService(&pms.Service{Name: "app_after_read", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	ch, err := s.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	defer func() {
+		s.StopWatch()
+		for range ch {
+		}
+	}()
 	select {
 	case <-time.After(5 * time.Second):
-		t.Errorf("fail to receive policy update event")
+		t.Fatal("fail to receive the service created after the read")
 	case e := <-ch:
 		if e.Type != pms.SERVICE_ADD {
 			t.Errorf("expected event type: %d, received event type :%d\n", pms.SERVICE_ADD, e.Type)
 		}
 	}
+}
 
-	//delete app
-	store.DeleteService("app1_new")
+func TestTenantKeysOutsideDefaultStore(t *testing.T) {
+	s, err := store.NewStore(storeConfig.StoreType, storeConfig.StoreProps)
+	if err != nil {
+		t.Fatal("fail to new etcd3 store:", err)
+	}
+	etcdStore := s.(*Store)
+	defer etcdStore.destroy()
+	defer s.DeleteService("default_app")
+	defer etcdStore.RemoveTenant("tenant1")
+	if strings.HasPrefix(etcdStore.tenantsRoot(), etcdStore.KeyPrefix) {
+		t.Errorf("tenants root %q should not be under key prefix %q", etcdStore.tenantsRoot(), etcdStore.KeyPrefix)
+	}
+
+	ch, err := s.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	defer func() {
+		s.StopWatch()
+		for range ch {
+		}
+	}()
+	if err := etcdStore.WriteTenant(&pms.Tenant{Name: "tenant1"}); err != nil {
+		t.Fatal("fail to write tenant:", err)
+	}
+	ts, err := etcdStore.NewTenantStore("tenant1")
+	if err != nil {
+		t.Fatal("fail to new tenant store:", err)
+	}
+	if err := ts.CreateService(&pms.Service{Name: "tenant_app", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service of tenant:", err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "default_app", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+
+	//only the service of the default tenant is seen by the default store
 	select {
 	case <-time.After(5 * time.Second):
-		t.Errorf("fail to receive policy update event")
+		t.Fatal("fail to receive the service of the default tenant")
 	case e := <-ch:
-		if e.Type != pms.SERVICE_DELETE {
-			t.Errorf("expected event type: %d, received event type :%d\n", pms.SERVICE_DELETE, e.Type)
+		if service, ok := e.Content.(*pms.Service); e.Type != pms.SERVICE_ADD || !ok || service.Name != "default_app" {
+			t.Errorf("only the service of the default tenant should be received, got %v", e)
+		}
+	}
+}
+
+func TestPolicyPage(t *testing.T) {
+	s, err := store.NewStore(storeConfig.StoreType, storeConfig.StoreProps)
+	if err != nil {
+		t.Fatal("fail to new etcd3 store:", err)
+	}
+	etcdStore := s.(*Store)
+	defer etcdStore.destroy()
+	defer s.DeleteService("app_page")
+
+	service := pms.Service{Name: "app_page", Type: pms.TypeApplication}
+	for i := 0; i < 5; i++ {
+		service.Policies = append(service.Policies, &pms.Policy{ID: fmt.Sprintf("id%d", 4-i), Name: fmt.Sprintf("p%d", i), Effect: "grant"})
+		service.RolePolicies = append(service.RolePolicies, &pms.RolePolicy{ID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("rp%d", i), Effect: "grant"})
+	}
+	if err := s.CreateService(&service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	policies, err := etcdStore.PolicyPage("app_page", "", 2)
+	if err != nil || len(policies) != 2 || policies[0].ID != "id0" || policies[1].ID != "id1" {
+		t.Errorf("policies id0 and id1 should be read, got %v, error %v", policies, err)
+	}
+	policies, err = etcdStore.PolicyPage("app_page", "id1", 10)
+	if err != nil || len(policies) != 3 || policies[0].ID != "id2" || policies[2].ID != "id4" {
+		t.Errorf("policies id2 to id4 should be read, got %v, error %v", policies, err)
+	}
+	rolePolicies, err := etcdStore.RolePolicyPage("app_page", "id3", 10)
+	if err != nil || len(rolePolicies) != 1 || rolePolicies[0].ID != "id4" {
+		t.Errorf("role policy id4 should be read, got %v, error %v", rolePolicies, err)
+	}
+	services, err := etcdStore.ServicePage("app_pag", 1)
+	if err != nil || len(services) != 1 || services[0].Name != "app_page" || len(services[0].Policies) != 0 {
+		t.Errorf("service app_page should be read without policies, got %v, error %v", services, err)
+	}
+}
+
+func TestSearchPolicies(t *testing.T) {
+	s, err := store.NewStore(storeConfig.StoreType, storeConfig.StoreProps)
+	if err != nil {
+		t.Fatal("fail to new etcd3 store:", err)
+	}
+	etcdStore := s.(*Store)
+	defer etcdStore.destroy()
+	defer s.DeleteService("app_search")
+	//another server sharing the etcd cluster
+	other := &Store{client: etcdStore.client, Config: etcdStore.Config, KeyPrefix: etcdStore.KeyPrefix}
+
+	policy := func(name string) *pms.Policy {
+		return &pms.Policy{Name: name, Effect: "grant", Principals: [][]string{{"user:search_alice"}},
+			Permissions: []*pms.Permission{{Resource: "/books", Actions: []string{"read"}}}}
+	}
+	search := func() int {
+		results, err := etcdStore.SearchPolicies(store.SearchQuery{Principal: "user:search_alice"})
+		if err != nil {
+			t.Fatal("fail to search policies:", err)
 		}
+		count := 0
+		for _, result := range results {
+			count += len(result.Policies)
+		}
+		return count
+	}
+
+	if err := s.CreateService(&pms.Service{Name: "app_search", Policies: []*pms.Policy{policy("p1")}}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if count := search(); count != 1 {
+		t.Fatalf("1 policy should be found, got %d", count)
+	}
+	//the changes of this process are found right after they are made
+	p2, err := s.CreatePolicy("app_search", policy("p2"))
+	if err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	if count := search(); count != 2 {
+		t.Errorf("2 policies should be found, got %d", count)
+	}
+	if err := s.DeletePolicy("app_search", p2.ID); err != nil {
+		t.Fatal("fail to delete policy:", err)
+	}
+	if count := search(); count != 1 {
+		t.Errorf("1 policy should be found after deletion, got %d", count)
 	}
 
+	//the changes of other servers are found shortly
+	if _, err := other.CreatePolicy("app_search", policy("p3")); err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for search() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("policy created by another server is not found")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := other.DeleteService("app_search"); err != nil {
+		t.Fatal("fail to delete service:", err)
+	}
+	for search() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("service deleted by another server is still found")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
 }
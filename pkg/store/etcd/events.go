@@ -0,0 +1,186 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package etcd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/oracle/speedle/api/pms"
+	log "github.com/sirupsen/logrus"
+)
+
+// key kinds under the prefix of the store
+const (
+	otherKey = iota
+	serviceKey
+	serviceTypeKey
+	policyKey
+	rolePolicyKey
+	functionKey
+)
+
+// parsedKey is a key of the store split into its parts
+type parsedKey struct {
+	kind        int
+	serviceName string
+	name        string
+}
+
+func (s *Store) parseKey(key string) parsedKey {
+	if strings.HasPrefix(key, s.KeyPrefix+FunctionsKey+KeySeparator) {
+		return parsedKey{kind: functionKey, name: strings.TrimPrefix(key, s.KeyPrefix+FunctionsKey+KeySeparator)}
+	}
+	if !strings.HasPrefix(key, s.KeyPrefix+ServicesKey+KeySeparator) {
+		return parsedKey{kind: otherKey}
+	}
+	//services/<service name>/, services/<service name>/type, services/<service name>/policies/<id>
+	//or services/<service name>/role_policies/<id>
+	parts := strings.SplitN(strings.TrimPrefix(key, s.KeyPrefix+ServicesKey+KeySeparator), KeySeparator, 3)
+	switch {
+	case len(parts) == 2 && len(parts[1]) == 0:
+		return parsedKey{kind: serviceKey, serviceName: parts[0]}
+	case len(parts) == 2 && parts[1] == ServiceTypeKey:
+		return parsedKey{kind: serviceTypeKey, serviceName: parts[0]}
+	case len(parts) == 3 && parts[1] == PoliciesKey:
+		return parsedKey{kind: policyKey, serviceName: parts[0], name: parts[2]}
+	case len(parts) == 3 && parts[1] == RolePoliciesKey:
+		return parsedKey{kind: rolePolicyKey, serviceName: parts[0], name: parts[2]}
+	}
+	return parsedKey{kind: otherKey}
+}
+
+// decodeEvents translates the etcd events of a watch response into store change events. Events of the same
+// revision come from one transaction:
+//   - A transaction creating the service key creates the service, the service is reloaded as a whole. Big services
+//     are created by several transactions, only the last one creates the service key.
+//   - A transaction deleting the service key deletes the service together with its policies.
+//   - Every other policy or role policy change touches the service key in the same transaction, and is reported
+//     policy by policy.
+//
+// The revision is used as event ID.
+func (s *Store) decodeEvents(events []*clientv3.Event) []pms.StoreChangeEvent {
+	var changes []pms.StoreChangeEvent
+	for start := 0; start < len(events); {
+		end := start + 1
+		for end < len(events) && events[end].Kv.ModRevision == events[start].Kv.ModRevision {
+			end++
+		}
+		changes = append(changes, s.decodeTxnEvents(events[start:end])...)
+		start = end
+	}
+	return changes
+}
+
+// decodeTxnEvents decodes the events of one revision
+func (s *Store) decodeTxnEvents(events []*clientv3.Event) []pms.StoreChangeEvent {
+	revision := events[0].Kv.ModRevision
+
+	//how the services are changed by the transaction
+	created := make(map[string]bool)
+	deleted := make(map[string]bool)
+	touched := make(map[string]bool)
+	for _, e := range events {
+		key := s.parseKey(string(e.Kv.Key))
+		if key.kind != serviceKey {
+			continue
+		}
+		switch {
+		case e.Type == clientv3.EventTypeDelete:
+			deleted[key.serviceName] = true
+		case e.IsCreate():
+			created[key.serviceName] = true
+		default:
+			touched[key.serviceName] = true
+		}
+	}
+
+	var changes []pms.StoreChangeEvent
+	// index of the change of each event type and service in changes, policies of the same service are merged into
+	// one change
+	indexes := make(map[pms.EventType]map[string]int)
+	appendData := func(eventType pms.EventType, serviceName string, data interface{}) {
+		if indexes[eventType] == nil {
+			indexes[eventType] = make(map[string]int)
+		}
+		i, ok := indexes[eventType][serviceName]
+		if !ok {
+			i = len(changes)
+			indexes[eventType][serviceName] = i
+			changes = append(changes, pms.StoreChangeEvent{Type: eventType, ID: revision, Content: []pms.StoreUpdateData{}})
+		}
+		changes[i].Content = append(changes[i].Content.([]pms.StoreUpdateData), pms.StoreUpdateData{ServiceName: serviceName, Data: data})
+	}
+	appendName := func(eventType pms.EventType, name string) {
+		if indexes[eventType] == nil {
+			indexes[eventType] = make(map[string]int)
+		}
+		i, ok := indexes[eventType][""]
+		if !ok {
+			i = len(changes)
+			indexes[eventType][""] = i
+			changes = append(changes, pms.StoreChangeEvent{Type: eventType, ID: revision, Content: []string{}})
+		}
+		changes[i].Content = append(changes[i].Content.([]string), name)
+	}
+
+	for _, e := range events {
+		key := s.parseKey(string(e.Kv.Key))
+		isDelete := e.Type == clientv3.EventTypeDelete
+		switch key.kind {
+		case serviceKey:
+			if isDelete {
+				appendName(pms.SERVICE_DELETE, key.serviceName)
+			} else if created[key.serviceName] {
+				service, err := s.GetService(key.serviceName)
+				if err != nil {
+					log.Warningf("Unable get service due to error %v.\n", err)
+					continue
+				}
+				changes = append(changes, pms.StoreChangeEvent{Type: pms.SERVICE_ADD, ID: revision, Content: service})
+			}
+		case policyKey:
+			if !touched[key.serviceName] {
+				continue
+			}
+			if isDelete {
+				appendData(pms.POLICY_DELETE, key.serviceName, &pms.Policy{ID: key.name})
+				continue
+			}
+			var policy pms.Policy
+			if err := json.Unmarshal(e.Kv.Value, &policy); err != nil {
+				log.Warningf("Unable to unmarshal policy %q due to error %v.\n", e.Kv.Key, err)
+				continue
+			}
+			appendData(pms.POLICY_ADD, key.serviceName, &policy)
+		case rolePolicyKey:
+			if !touched[key.serviceName] {
+				continue
+			}
+			if isDelete {
+				appendData(pms.ROLEPOLICY_DELETE, key.serviceName, &pms.RolePolicy{ID: key.name})
+				continue
+			}
+			var rolePolicy pms.RolePolicy
+			if err := json.Unmarshal(e.Kv.Value, &rolePolicy); err != nil {
+				log.Warningf("Unable to unmarshal role policy %q due to error %v.\n", e.Kv.Key, err)
+				continue
+			}
+			appendData(pms.ROLEPOLICY_ADD, key.serviceName, &rolePolicy)
+		case functionKey:
+			if isDelete {
+				appendName(pms.FUNCTION_DELETE, key.name)
+				continue
+			}
+			var function pms.Function
+			if err := json.Unmarshal(e.Kv.Value, &function); err != nil {
+				log.Warningf("Unable to unmarshal function %q due to error %v.\n", e.Kv.Key, err)
+				continue
+			}
+			changes = append(changes, pms.StoreChangeEvent{Type: pms.FUNCTION_ADD, ID: revision, Content: &function})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,152 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package etcd
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	"golang.org/x/net/context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// searchWaitTimeout is how long a search waits for the index to catch up with the changes made by this process
+const searchWaitTimeout = 5 * time.Second
+
+// searchIndex is the search index of a store, it's kept up to date by watching the keys of the store
+type searchIndex struct {
+	lock  sync.Mutex
+	index *store.PolicyIndex
+	// revision is the etcd revision the index is up to date with, updated is closed when it's increased
+	revision int64
+	updated  chan struct{}
+	// written is the revision of the last change made by this process, accessed atomically
+	written int64
+}
+
+// setRevision must be called with the lock held
+func (x *searchIndex) setRevision(revision int64) {
+	x.revision = revision
+	if x.updated != nil {
+		close(x.updated)
+	}
+	x.updated = make(chan struct{})
+}
+
+// wrote records the revision of a transaction which changes policies, so searches afterwards find the change
+func (s *Store) wrote(resp *clientv3.TxnResponse) {
+	for {
+		written := atomic.LoadInt64(&s.search.written)
+		if resp.Header.Revision <= written || atomic.CompareAndSwapInt64(&s.search.written, written, resp.Header.Revision) {
+			return
+		}
+	}
+}
+
+// SearchPolicies searches the index updated by watching the store, the changes made by other servers are found
+// shortly after they are made, and the changes made by this process are found right after they are made.
+func (s *Store) SearchPolicies(query store.SearchQuery) ([]*store.SearchResult, error) {
+	x := &s.search
+	written := atomic.LoadInt64(&x.written)
+	timeout := time.After(searchWaitTimeout)
+
+	x.lock.Lock()
+	if x.index == nil {
+		if err := s.buildSearchIndex(); err != nil {
+			x.lock.Unlock()
+			return nil, err
+		}
+		go s.watchSearchIndex()
+	}
+	for x.revision < written {
+		updated := x.updated
+		x.lock.Unlock()
+		select {
+		case <-updated:
+		case <-timeout:
+			return nil, errors.Errorf(errors.StoreError, "search index is not updated to revision %d in %v", written, searchWaitTimeout)
+		}
+		x.lock.Lock()
+	}
+	index := x.index
+	x.lock.Unlock()
+	return index.Search(query), nil
+}
+
+// buildSearchIndex reads all the policies into the index, it must be called with the lock held
+func (s *Store) buildSearchIndex() error {
+	//the revision is read before the policies, changes made in between are applied again, which is harmless
+	revision, err := s.currentRevision()
+	if err != nil {
+		return err
+	}
+	ps, err := s.ReadPolicyStore()
+	if err != nil {
+		return errors.Wrap(err, errors.StoreError, "failed to read policies for search index")
+	}
+	if s.search.index == nil {
+		s.search.index = store.NewPolicyIndex(ps)
+	} else {
+		s.search.index.Reset(ps)
+	}
+	s.search.setRevision(revision)
+	return nil
+}
+
+// watchSearchIndex applies the changes of the store to the search index until the etcd client is closed. The
+// index is built again if the changes are compacted before being applied.
+func (s *Store) watchSearchIndex() {
+	x := &s.search
+	for s.client.Ctx().Err() == nil {
+		x.lock.Lock()
+		revision := x.revision
+		x.lock.Unlock()
+
+		ctx, cancel := context.WithCancel(s.client.Ctx())
+		for resp := range s.client.Watch(ctx, s.KeyPrefix, clientv3.WithPrefix(), clientv3.WithRev(revision+1)) {
+			if resp.CompactRevision != 0 {
+				log.Warningf("Changes after revision %d are compacted, building search index again\n", revision)
+				x.lock.Lock()
+				if err := s.buildSearchIndex(); err != nil {
+					log.Warningf("Unable to build search index due to error %v.\n", err)
+				}
+				x.lock.Unlock()
+				break
+			}
+			if err := resp.Err(); err != nil {
+				log.Warningf("Error happens in search index watch response, %v\n", err)
+				break
+			}
+			if len(resp.Events) == 0 {
+				continue
+			}
+			changes := s.decodeEvents(resp.Events)
+			x.lock.Lock()
+			for _, change := range changes {
+				if !x.index.Apply(change) {
+					if err := s.buildSearchIndex(); err != nil {
+						log.Warningf("Unable to build search index due to error %v.\n", err)
+					}
+					break
+				}
+			}
+			if last := resp.Events[len(resp.Events)-1].Kv.ModRevision; last > x.revision {
+				x.setRevision(last)
+			}
+			revision = x.revision
+			x.lock.Unlock()
+		}
+		cancel()
+
+		select {
+		case <-s.client.Ctx().Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package etcd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	// TenantsRootSuffix makes the root of the keys of tenants from the key prefix of the default tenant, e.g. the
+	// tenants of key prefix "/speedle_ps/" are stored under "/speedle_ps_tenants/"
+	TenantsRootSuffix = "_tenants"
+	// TenantsKey is the key under which tenants are stored, one key per tenant
+	TenantsKey = "tenants"
+	// TenantDataKey is the key under which the policies of each tenant are stored, with the tenant's own key prefix
+	TenantDataKey = "tenant_data"
+	// TenantDiscoverPrefix is the prefix of the discover requests of tenants
+	TenantDiscoverPrefix = "/isAllowedRequests_tenants/"
+)
+
+// tenantsRoot is the root of the keys of tenants. It's beside the key prefix of the default tenant rather than under
+// it, so the watch, revision and search index of the default store don't see the writes of tenants.
+func (s *Store) tenantsRoot() string {
+	return strings.TrimSuffix(s.KeyPrefix, KeySeparator) + TenantsRootSuffix + KeySeparator
+}
+
+func (s *Store) tenantKey(tenantName string) string {
+	return s.tenantsRoot() + TenantsKey + KeySeparator + tenantName
+}
+
+func (s *Store) tenantKeyPrefix(tenantName string) string {
+	return s.tenantsRoot() + TenantDataKey + KeySeparator + tenantName + KeySeparator
+}
+
+func tenantDiscoverPrefix(tenantName string) string {
+	return TenantDiscoverPrefix + tenantName + KeySeparator
+}
+
+// ReadTenants reads all the stored tenants
+func (s *Store) ReadTenants() ([]*pms.Tenant, error) {
+	responses, err := s.prefixGet(s.tenantsRoot() + TenantsKey + KeySeparator)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.StoreError, "unable to read tenants")
+	}
+	tenants := []*pms.Tenant{}
+	for _, resp := range responses {
+		for _, kv := range resp.Kvs {
+			var tenant pms.Tenant
+			if err := json.Unmarshal(kv.Value, &tenant); err != nil {
+				return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal tenant %q", kv.Key)
+			}
+			tenants = append(tenants, &tenant)
+		}
+	}
+	return tenants, nil
+}
+
+// WriteTenant creates or updates a tenant
+func (s *Store) WriteTenant(tenant *pms.Tenant) error {
+	value, err := json.Marshal(tenant)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal tenant")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err := s.client.Put(ctx, s.tenantKey(tenant.Name), string(value)); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to write tenant %q", tenant.Name)
+	}
+	return nil
+}
+
+// RemoveTenant deletes a tenant together with all its policies and discover requests
+func (s *Store) RemoveTenant(tenantName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	txnResp, err := s.client.KV.Txn(ctx).If(
+		clientv3.Compare(clientv3.Version(s.tenantKey(tenantName)), ">", 0), //key exist
+	).Then(
+		clientv3.OpDelete(s.tenantKey(tenantName)),
+		clientv3.OpDelete(s.tenantKeyPrefix(tenantName), clientv3.WithPrefix()),
+		clientv3.OpDelete(tenantDiscoverPrefix(tenantName), clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to delete tenant %q", tenantName)
+	}
+	if !txnResp.Succeeded {
+		return errors.Errorf(errors.EntityNotFound, "tenant %q is not found", tenantName)
+	}
+	return nil
+}
+
+// NewTenantStore creates the store of a tenant, which shares the etcd connection but uses the tenant's own key prefix
+func (s *Store) NewTenantStore(tenantName string) (pms.PolicyStoreManager, error) {
+	return &Store{
+		client:         s.client,
+		Config:         s.Config,
+		KeyPrefix:      s.tenantKeyPrefix(tenantName),
+		discoverPrefix: tenantDiscoverPrefix(tenantName),
+	}, nil
+}
@@ -21,6 +21,27 @@ import (
 type discoverRequestStore struct {
 	FileLocation string
 	rwLock       sync.RWMutex
+	fileLock     processLock
+}
+
+func (s *discoverRequestStore) lock() {
+	s.rwLock.Lock()
+	s.fileLock.lock(s.FileLocation, true)
+}
+
+func (s *discoverRequestStore) unlock() {
+	s.fileLock.unlock()
+	s.rwLock.Unlock()
+}
+
+func (s *discoverRequestStore) rLock() {
+	s.rwLock.RLock()
+	s.fileLock.lock(s.FileLocation, false)
+}
+
+func (s *discoverRequestStore) rUnlock() {
+	s.fileLock.unlock()
+	s.rwLock.RUnlock()
 }
 
 type RequestItem struct {
@@ -39,8 +60,8 @@ const (
 //read policy store from file
 func (s *discoverRequestStore) ReadDiscoverRequestStore() (*StoreContent, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.readDiscoverRequestStoreWithoutLock()
 }
@@ -59,39 +80,37 @@ func (s *discoverRequestStore) readDiscoverRequestStoreWithoutLock() (*StoreCont
 }
 
 func (s *discoverRequestStore) WriteDiscoverRequestStore(drs *StoreContent) error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	return s.writeDiscoverRequestStoreWithoutLock(drs)
 }
 
 func (s *discoverRequestStore) writeDiscoverRequestStoreWithoutLock(drs *StoreContent) error {
-	jsonFile, err := os.Create(s.FileLocation)
-	defer jsonFile.Close()
-	if err != nil {
-		return errors.Wrapf(err, errors.StoreError, "unable to create file %q", s.FileLocation)
-	}
 	drsB, err := json.MarshalIndent(*drs, "", "    ")
 	if err != nil {
 		log.Errorf("marshal indent filed becuase of %v", err)
 		return errors.Wrap(err, errors.SerializationError, "marshal indent failed")
 	}
-	if _, err := jsonFile.Write(drsB); err != nil {
+	if err := writeFileAtomically(s.FileLocation, drsB, 0644); err != nil {
 		log.Errorf("write to file failed becuase of %v", err)
-		return errors.Wrapf(err, errors.StoreError, "unable to write to file %q", s.FileLocation)
+		return err
 	}
 	return nil
 }
 
 func getDiscoverRequestStore(s *Store) (*discoverRequestStore, error) {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 	if s.discoverStore == nil {
 		dir, _ := filepath.Split(s.FileLocation)
 		discoverFileLocation := filepath.Join(dir, discoverStoreFileName)
 		if dir == "./" {
 			discoverFileLocation = dir + discoverStoreFileName
 		}
+		if s.discoverFileLocation != "" {
+			discoverFileLocation = s.discoverFileLocation
+		}
 		log.Infof("discover store file location:%s\n", discoverFileLocation)
 		if _, err := os.Stat(discoverFileLocation); os.IsNotExist(err) {
 			log.Infof("discover store file does not exist, create one...")
@@ -115,8 +134,8 @@ func (s *Store) SaveDiscoverRequest(discoverRequest *ads.RequestContext) error {
 	}
 }
 func (s *discoverRequestStore) saveDiscoverRequest(discoverRequest *ads.RequestContext) error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 	sContent, err := s.readDiscoverRequestStoreWithoutLock()
 	if err != nil {
 		return err
@@ -142,8 +161,8 @@ func (s *Store) GetLastDiscoverRequest(serviceName string) (*ads.RequestContext,
 	}
 }
 func (s *discoverRequestStore) getLastDiscoverRequest(serviceName string) (*ads.RequestContext, int64, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 	sContent, err := s.readDiscoverRequestStoreWithoutLock()
 	if err != nil {
 		return nil, -1, err
@@ -173,8 +192,8 @@ func (s *Store) GetDiscoverRequestsSinceRevision(serviceName string, revision in
 	}
 }
 func (s *discoverRequestStore) getDiscoverRequestsSinceRevision(serviceName string, revision int64) ([]*ads.RequestContext, int64, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 	sContent, err := s.readDiscoverRequestStoreWithoutLock()
 	if err != nil {
 		return nil, -1, err
@@ -203,8 +222,8 @@ func (s *Store) GetDiscoverRequests(serviceName string) ([]*ads.RequestContext,
 	}
 }
 func (s *discoverRequestStore) getDiscoverRequests(serviceName string) ([]*ads.RequestContext, int64, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 	sContent, err := s.readDiscoverRequestStoreWithoutLock()
 	if err != nil {
 		return nil, -1, err
@@ -235,8 +254,8 @@ func (s *Store) ResetDiscoverRequests(serviceName string) error {
 }
 
 func (s *discoverRequestStore) resetDiscoverRequests(serviceName string) error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 	sContent, err := s.readDiscoverRequestStoreWithoutLock()
 	if err != nil {
 		return err
@@ -8,10 +8,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
 	"github.com/oracle/speedle/pkg/suid"
 
 	"github.com/fsnotify/fsnotify"
@@ -20,17 +23,49 @@ import (
 )
 
 type Store struct {
-	FileLocation  string
-	stop          chan struct{}
+	FileLocation string
+	stop         chan struct{}
+	// rwLock serializes the goroutines of this process, and fileLock protects the file from the other processes
 	rwLock        sync.RWMutex
+	fileLock      processLock
 	discoverStore *discoverRequestStore
+	// discoverFileLocation overrides the default discover request file beside the policy file
+	discoverFileLocation string
+	// watchDebounce overrides defaultWatchDebounce
+	watchDebounce time.Duration
+	healthLock    sync.RWMutex
+	health        error
+	search        searchIndex
+}
+
+// defaultWatchDebounce is how long the watcher waits for the changes of the policy file to settle before reloading it
+const defaultWatchDebounce = 200 * time.Millisecond
+
+func (s *Store) lock() {
+	s.rwLock.Lock()
+	s.fileLock.lock(s.FileLocation, true)
+}
+
+func (s *Store) unlock() {
+	s.fileLock.unlock()
+	s.rwLock.Unlock()
+}
+
+func (s *Store) rLock() {
+	s.rwLock.RLock()
+	s.fileLock.lock(s.FileLocation, false)
+}
+
+func (s *Store) rUnlock() {
+	s.fileLock.unlock()
+	s.rwLock.RUnlock()
 }
 
 // ReadPolicyStore reads policy store from a file
 func (s *Store) ReadPolicyStore() (*pms.PolicyStore, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.readPolicyStoreWithoutLock()
 }
@@ -63,33 +98,25 @@ func (s *Store) readPolicyStoreWithoutLock() (*pms.PolicyStore, error) {
 
 // WritePolicyStore writes policies to a file
 func (s *Store) WritePolicyStore(ps *pms.PolicyStore) error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	return s.writePolicyStoreWithoutLock(ps)
 }
 
 func (s *Store) writePolicyStoreWithoutLock(ps *pms.PolicyStore) error {
-	jsonFile, err := os.Create(s.FileLocation)
-	defer jsonFile.Close()
-	if err != nil {
-		return errors.Wrapf(err, errors.StoreError, "unable to create file %q", s.FileLocation)
-	}
 	psB, err := json.MarshalIndent(ps, "", "    ")
 	if err != nil {
 		return errors.Wrap(err, errors.StoreError, "marshal indent failed")
 	}
-	if _, err := jsonFile.Write(psB); err != nil {
-		return errors.Wrapf(err, errors.StoreError, "unable to write to file %q", s.FileLocation)
-	}
-	return nil
+	return writeFileAtomically(s.FileLocation, psB, 0644)
 }
 
 // ListAllServices lists all the services
 func (s *Store) ListAllServices() ([]*pms.Service, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.getServicesWithoutLock()
 }
@@ -105,8 +132,8 @@ func (s *Store) getServicesWithoutLock() ([]*pms.Service, error) {
 // GetServiceNames reads all the service names
 func (s *Store) GetServiceNames() ([]string, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.getServiceNamesWithoutLock()
 }
@@ -130,8 +157,8 @@ func (s *Store) getServiceNamesWithoutLock() ([]string, error) {
 // GetPolicyAndRolePolicyCounts returns a map, in which the key is the service name, and the value is the count of both policies and role policies in the service.
 func (s *Store) GetPolicyAndRolePolicyCounts() (map[string]*pms.PolicyAndRolePolicyCount, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	serviceNames, err := s.getServiceNamesWithoutLock()
 	if err != nil {
@@ -164,8 +191,8 @@ func (s *Store) GetPolicyAndRolePolicyCounts() (map[string]*pms.PolicyAndRolePol
 // GetServiceCount gets the service count
 func (s *Store) GetServiceCount() (int64, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -182,8 +209,8 @@ func (s *Store) GetServiceCount() (int64, error) {
 // GetService gets the detailed info of a service
 func (s *Store) GetService(serviceName string) (*pms.Service, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.getServiceWithoutLock(serviceName)
 }
@@ -204,8 +231,8 @@ func (s *Store) getServiceWithoutLock(serviceName string) (*pms.Service, error)
 // CreateService creates a new service
 func (s *Store) CreateService(service *pms.Service) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -239,8 +266,8 @@ func generateID(service *pms.Service) (*pms.Service, error) {
 // WriteService writes a service into a file
 func (s *Store) WriteService(service *pms.Service) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	return s.writeServiceWithoutLock(service)
 }
@@ -267,8 +294,8 @@ func (s *Store) writeServiceWithoutLock(service *pms.Service) error {
 // DeleteService deletes a service named ${serviceName} from a file
 func (s *Store) DeleteService(serviceName string) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -292,8 +319,8 @@ func (s *Store) DeleteService(serviceName string) error {
 
 // DeleteServices deletes all services from a file
 func (s *Store) DeleteServices() error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -304,6 +331,9 @@ func (s *Store) DeleteServices() error {
 	return s.writePolicyStoreWithoutLock(ps)
 }
 
+// Watch watches the policy file, and the files included by it if it is in SPDL format. Events are debounced, and
+// FULL_RELOAD is emitted only if the changed policy file is valid. While the policy file is missing or invalid,
+// the policies loaded before are kept serving, and the problem is reported by Health.
 func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 	log.Info("Enter Watch...")
 	watcher, err := fsnotify.NewWatcher()
@@ -312,10 +342,22 @@ func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 		return nil, errors.Wrap(err, errors.StoreError, "fsnotify new watcher failed")
 	}
 
-	err = watcher.Add(s.FileLocation)
+	location, err := filepath.Abs(s.FileLocation)
 	if err != nil {
-		log.Errorf("Failed to add the file %q into the watch list, error: %v", s.FileLocation, err)
-		return nil, errors.Wrapf(err, errors.StoreError, "Failed to add the file %q into the watch list", s.FileLocation)
+		watcher.Close()
+		return nil, errors.Wrapf(err, errors.StoreError, "unable to resolve file %q", s.FileLocation)
+	}
+	files := newWatchedFiles(watcher)
+	if err := files.add(location); err != nil {
+		watcher.Close()
+		log.Errorf("Failed to add the directory of file %q into the watch list, error: %v", s.FileLocation, err)
+		return nil, errors.Wrapf(err, errors.StoreError, "Failed to add the directory of file %q into the watch list", s.FileLocation)
+	}
+	s.watchIncludedFiles(files)
+
+	debounce := s.watchDebounce
+	if debounce == 0 {
+		debounce = defaultWatchDebounce
 	}
 
 	var storeChangeChan pms.StorageChangeChannel
@@ -329,33 +371,30 @@ func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 			close(storeChangeChan)
 			close(s.stop)
 		}()
+		// reload fires when no more change is detected in the debounce interval
+		var reload <-chan time.Time
 		for {
 			select {
 			case event := <-watcher.Events:
-				switch {
-				case event.Op&fsnotify.Write == fsnotify.Write:
-					log.Info("Reloading the file store...")
-					reloadEvent := pms.StoreChangeEvent{Type: pms.FULL_RELOAD}
-					storeChangeChan <- reloadEvent
-				case event.Op&fsnotify.Rename == fsnotify.Rename:
-					if _, err := os.Lstat(s.FileLocation); os.IsNotExist(err) {
-						log.Fatalf("The policy file %q has already been renamed, please double check", s.FileLocation)
-					} else {
-						// This is just a workaround for the issue https://github.com/fsnotify/fsnotify/issues/282
-						log.Info("Reloading the file store....")
-						reloadEvent := pms.StoreChangeEvent{Type: pms.FULL_RELOAD}
-						storeChangeChan <- reloadEvent
-
-						err = watcher.Add(s.FileLocation)
-						if err != nil {
-							log.Fatalf("Failed to add the file %q into the watch list again, error: %v", s.FileLocation, err)
-						}
-					}
-				case event.Op&fsnotify.Remove == fsnotify.Remove:
-					log.Fatalf("The policy file %q has already been removed, please double check", s.FileLocation)
-				default:
-					log.Infof("Operation %q was detected on the policy file %q", event.Op, s.FileLocation)
+				if files.changed(event) {
+					log.Debugf("Operation %q on %q changes the policy file %q", event.Op, event.Name, s.FileLocation)
+					reload = time.After(debounce)
+				}
+			case <-reload:
+				reload = nil
+				err := s.checkPolicyFile()
+				s.setHealth(err)
+				if err != nil {
+					continue
 				}
+				log.Info("Reloading the file store...")
+				select {
+				case storeChangeChan <- pms.StoreChangeEvent{Type: pms.FULL_RELOAD}:
+				case <-s.stop:
+					log.Warning("Received stop signal")
+					return
+				}
+				s.watchIncludedFiles(files)
 			case err := <-watcher.Errors:
 				log.Warningf("Error happened when watching the policy file, error: %v", err)
 			case <-s.stop:
@@ -368,6 +407,56 @@ func (s *Store) Watch() (pms.StorageChangeChannel, error) {
 	return storeChangeChan, nil
 }
 
+// watchIncludedFiles watches the files included by a SPDL policy file, so that the changes of them are reloaded as well.
+// It is called on each reload to catch the files newly included.
+func (s *Store) watchIncludedFiles(files *watchedFiles) {
+	if !strings.HasSuffix(s.FileLocation, ".spdl") {
+		return
+	}
+	s.rLock()
+	_, included, err := parseSPDLFile(s.FileLocation)
+	s.rUnlock()
+	if err != nil {
+		log.Warnf("Unable to find the files included by %q, error: %v", s.FileLocation, err)
+		return
+	}
+	for _, file := range included[1:] {
+		if err := files.add(file); err != nil {
+			log.Warnf("Failed to add the directory of included file %q into the watch list, error: %v", file, err)
+		}
+	}
+}
+
+// checkPolicyFile checks whether the changed policy file can be reloaded
+func (s *Store) checkPolicyFile() error {
+	if _, err := os.Stat(s.FileLocation); os.IsNotExist(err) {
+		return errors.Errorf(errors.EntityNotFound, "policy file %q is missing", s.FileLocation)
+	}
+	if _, err := s.ReadPolicyStore(); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "policy file %q is invalid", s.FileLocation)
+	}
+	return nil
+}
+
+// setHealth records the result of reloading the policy file
+func (s *Store) setHealth(err error) {
+	s.healthLock.Lock()
+	defer s.healthLock.Unlock()
+	if err != nil {
+		log.Errorf("Policy file is not reloaded, the policies loaded before are served, error: %v", err)
+	} else if s.health != nil {
+		log.Infof("Policy file %q is recovered", s.FileLocation)
+	}
+	s.health = err
+}
+
+// Health returns the problem of reloading the policy file, it is nil if the policies served are up to date
+func (s *Store) Health() error {
+	s.healthLock.RLock()
+	defer s.healthLock.RUnlock()
+	return s.health
+}
+
 func (s *Store) StopWatch() {
 	if s.stop != nil {
 		s.stop <- struct{}{}
@@ -381,8 +470,8 @@ func (s *Store) Type() string {
 // For policy manager
 func (s *Store) ListAllPolicies(serviceName string, filter string) ([]*pms.Policy, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	f := parseFilter(filter)
 	service, err := s.getServiceWithoutLock(serviceName)
@@ -404,8 +493,8 @@ func (s *Store) ListAllPolicies(serviceName string, filter string) ([]*pms.Polic
 
 func (s *Store) GetPolicyCount(serviceName string) (int64, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.getPolicyCountWithoutLock(serviceName)
 }
@@ -448,8 +537,8 @@ func (s *Store) getPolicyCountImpl(serviceName string) (int64, error) {
 
 func (s *Store) GetPolicy(serviceName string, id string) (*pms.Policy, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -467,8 +556,8 @@ func (s *Store) GetPolicy(serviceName string, id string) (*pms.Policy, error) {
 
 func (s *Store) DeletePolicy(serviceName string, id string) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -487,8 +576,8 @@ func (s *Store) DeletePolicy(serviceName string, id string) error {
 
 func (s *Store) DeletePolicies(serviceName string) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -503,8 +592,8 @@ func (s *Store) DeletePolicies(serviceName string) error {
 
 func (s *Store) CreatePolicy(serviceName string, policy *pms.Policy) (*pms.Policy, error) {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -523,8 +612,8 @@ func (s *Store) CreatePolicy(serviceName string, policy *pms.Policy) (*pms.Polic
 // For role policy manager
 func (s *Store) ListAllRolePolicies(serviceName string, filter string) ([]*pms.RolePolicy, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	f := parseFilter(filter)
 	service, err := s.getServiceWithoutLock(serviceName)
@@ -546,8 +635,8 @@ func (s *Store) ListAllRolePolicies(serviceName string, filter string) ([]*pms.R
 
 func (s *Store) GetRolePolicyCount(serviceName string) (int64, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	return s.getRolePolicyCountWithoutLock(serviceName)
 }
@@ -590,8 +679,8 @@ func (s *Store) getRolePolicyCountImpl(serviceName string) (int64, error) {
 
 func (s *Store) GetRolePolicy(serviceName string, id string) (*pms.RolePolicy, error) {
 
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -609,8 +698,8 @@ func (s *Store) GetRolePolicy(serviceName string, id string) (*pms.RolePolicy, e
 
 func (s *Store) DeleteRolePolicy(serviceName string, id string) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -628,8 +717,8 @@ func (s *Store) DeleteRolePolicy(serviceName string, id string) error {
 
 func (s *Store) DeleteRolePolicies(serviceName string) error {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -642,8 +731,8 @@ func (s *Store) DeleteRolePolicies(serviceName string) error {
 
 func (s *Store) CreateRolePolicy(serviceName string, rolePolicy *pms.RolePolicy) (*pms.RolePolicy, error) {
 
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	service, err := s.getServiceWithoutLock(serviceName)
 	if err != nil {
@@ -659,19 +748,12 @@ func (s *Store) CreateRolePolicy(serviceName string, rolePolicy *pms.RolePolicy)
 	return &dupRolePolicy, nil
 }
 
-func validateFunc(function *pms.Function) error {
-	if function.Name == "" || function.FuncURL == "" {
-		return errors.New(errors.InvalidRequest, "\"name\" and \"funcURL\" in function definition can not be empty")
-	}
-	return nil
-}
-
 func (s *Store) CreateFunction(function *pms.Function) (*pms.Function, error) {
-	if err := validateFunc(function); err != nil {
+	if err := store.ValidateFunction(function); err != nil {
 		return nil, err
 	}
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -693,8 +775,8 @@ func (s *Store) CreateFunction(function *pms.Function) (*pms.Function, error) {
 }
 
 func (s *Store) DeleteFunction(funcName string) error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -711,8 +793,8 @@ func (s *Store) DeleteFunction(funcName string) error {
 }
 
 func (s *Store) DeleteFunctions() error {
-	s.rwLock.Lock()
-	defer s.rwLock.Unlock()
+	s.lock()
+	defer s.unlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -723,8 +805,8 @@ func (s *Store) DeleteFunctions() error {
 }
 
 func (s *Store) GetFunction(funcName string) (*pms.Function, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
@@ -739,8 +821,8 @@ func (s *Store) GetFunction(funcName string) (*pms.Function, error) {
 }
 
 func (s *Store) ListAllFunctions(filter string) ([]*pms.Function, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	f := parseFilter(filter)
 	ps, err := s.readPolicyStoreWithoutLock()
@@ -761,8 +843,8 @@ func (s *Store) ListAllFunctions(filter string) ([]*pms.Function, error) {
 }
 
 func (s *Store) GetFunctionCount() (int64, error) {
-	s.rwLock.RLock()
-	defer s.rwLock.RUnlock()
+	s.rLock()
+	defer s.rUnlock()
 
 	ps, err := s.readPolicyStoreWithoutLock()
 	if err != nil {
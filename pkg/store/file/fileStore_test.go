@@ -5,6 +5,7 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
@@ -480,6 +481,9 @@ func TestWatch(t *testing.T) {
 		}
 	}()
 
+	// Wait for the change to be reloaded, otherwise the changes are debounced into one reload
+	time.Sleep(time.Second)
+
 	//delete app
 	store.DeleteService("app1_new")
 
@@ -487,3 +491,38 @@ func TestWatch(t *testing.T) {
 	store.StopWatch()
 	wg.Wait()
 }
+
+func TestSearchPolicies(t *testing.T) {
+	f, err := ioutil.TempFile("", "search*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	s := &Store{FileLocation: f.Name()}
+	other := &Store{FileLocation: f.Name()}
+	if err := s.WritePolicyStore(&pms.PolicyStore{}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+
+	query := store.SearchQuery{Principal: "user:alice"}
+	if results, err := s.SearchPolicies(query); err != nil || len(results) != 0 {
+		t.Fatalf("unexpected search result %v, %v", results, err)
+	}
+	// The changes made by another store on the same file are found as well
+	service := pms.Service{Name: "app1", Policies: []*pms.Policy{{Name: "p1", Effect: "grant",
+		Principals: [][]string{{"user:alice"}}, Permissions: []*pms.Permission{{Resource: "/r1", Actions: []string{"get"}}}}}}
+	if err := other.CreateService(&service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	results, err := s.SearchPolicies(query)
+	if err != nil || len(results) != 1 || results[0].ServiceName != "app1" || len(results[0].Policies) != 1 {
+		t.Fatalf("unexpected search result %v, %v", results, err)
+	}
+	if err := other.DeleteService("app1"); err != nil {
+		t.Fatal("fail to delete service:", err)
+	}
+	if results, err := s.SearchPolicies(query); err != nil || len(results) != 0 {
+		t.Fatalf("unexpected search result %v, %v", results, err)
+	}
+}
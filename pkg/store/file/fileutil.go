@@ -0,0 +1,130 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// lockFileSuffix is appended to the name of a file to get the name of its lock file
+const lockFileSuffix = ".lock"
+
+// processLock is an advisory lock (flock) on a lock file, which protects a file from being accessed by the other
+// processes at the same time, e.g. PMS and a CLI tool working on the same policy file. The goroutines of this process
+// are expected to be serialized by a sync.RWMutex before taking the lock, so that there is either one writer, or
+// several readers sharing one lock.
+type processLock struct {
+	mu      sync.Mutex
+	readers int
+	f       *os.File
+	// warned is set once failing to lock is logged, to avoid flooding the log
+	warned bool
+}
+
+// lock takes the lock of file location, exclusively for a writer or shared by readers.
+// File is still accessed without the lock if the lock file can't be created, e.g. in a read only directory.
+func (l *processLock) lock(location string, exclusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !exclusive {
+		l.readers++
+		if l.readers > 1 {
+			return
+		}
+	}
+	lockLocation := location + lockFileSuffix
+	f, err := os.OpenFile(lockLocation, os.O_RDWR|os.O_CREATE, 0644)
+	if err == nil {
+		if err = flock(f, exclusive); err != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		if !l.warned {
+			log.Warnf("Unable to lock file %q, it is not protected from the other processes, error: %v", lockLocation, err)
+			l.warned = true
+		}
+		return
+	}
+	l.f = f
+}
+
+// unlock releases the lock taken by lock
+func (l *processLock) unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readers > 0 {
+		l.readers--
+		if l.readers > 0 {
+			return
+		}
+	}
+	if l.f != nil {
+		// Closing the file releases the lock
+		if err := l.f.Close(); err != nil {
+			log.Warnf("Unable to close lock file %q, error: %v", l.f.Name(), err)
+		}
+		l.f = nil
+	}
+}
+
+// writeFileAtomically writes data to a temporary file beside location, flushes it to disk and renames it to location.
+// Readers see either the old content or the new one, even if the process crashes in the middle of writing.
+func writeFileAtomically(location string, data []byte, perm os.FileMode) (err error) {
+	// Replace the target of a symbolic link instead of the link itself
+	if target, err := filepath.EvalSymlinks(location); err == nil {
+		location = target
+	}
+	if fi, err := os.Stat(location); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(location)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to create temporary file for %q", location)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to write to file %q", tmp.Name())
+	}
+	if err = tmp.Sync(); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to flush file %q", tmp.Name())
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to change mode of file %q", tmp.Name())
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to close file %q", tmp.Name())
+	}
+	if err = os.Rename(tmp.Name(), location); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to rename file %q to %q", tmp.Name(), location)
+	}
+
+	// Flush the rename to disk as well, it is not supported on some platforms, so the error is ignored
+	if d, err := os.Open(dir); err == nil {
+		if err := d.Sync(); err != nil {
+			log.Debugf("Unable to flush directory %q, error: %v", dir, err)
+		}
+		d.Close()
+	}
+	return nil
+}
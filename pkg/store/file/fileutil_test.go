@@ -0,0 +1,128 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+)
+
+func TestWriteFileAtomically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-atomic")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "ps.json")
+	if err := ioutil.WriteFile(location, []byte("old"), 0600); err != nil {
+		t.Fatal("fail to write file:", err)
+	}
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(location, link); err != nil {
+		t.Fatal("fail to create link:", err)
+	}
+
+	if err := writeFileAtomically(link, []byte("new"), 0644); err != nil {
+		t.Fatal("fail to write file atomically:", err)
+	}
+	if raw, err := ioutil.ReadFile(location); err != nil || string(raw) != "new" {
+		t.Errorf("unexpected content %q, error: %v", raw, err)
+	}
+	if fi, err := os.Stat(location); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("mode of file should be kept, error: %v", err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symbolic link should be kept, error: %v", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("temporary file should be removed, but got %d files", len(files))
+	}
+}
+
+func TestProcessLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-lock")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "ps.json")
+
+	// Locks with different lock files behave like the locks of different processes
+	var writer, reader1, reader2 processLock
+	reader1.lock(location, false)
+	reader2.lock(location, false)
+
+	locked := make(chan struct{})
+	go func() {
+		writer.lock(location, true)
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("writer should wait for the readers")
+	case <-time.After(100 * time.Millisecond):
+	}
+	reader1.unlock()
+	reader2.unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer should get the lock after readers unlock")
+	}
+	writer.unlock()
+}
+
+func TestWatchValidContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-watch")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &Store{FileLocation: filepath.Join(dir, "ps.json"), watchDebounce: 50 * time.Millisecond}
+	if err := store.WritePolicyStore(&pms.PolicyStore{}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	ch, err := store.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	defer store.StopWatch()
+
+	// A half written file is not reloaded
+	if err := ioutil.WriteFile(store.FileLocation, []byte(`{"services": [`), 0644); err != nil {
+		t.Fatal("fail to write file:", err)
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("invalid content should not be reloaded, but got event %d", e.Type)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// Several writes are reloaded once
+	for i := 0; i < 3; i++ {
+		if err := store.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{{Name: "app1"}}}); err != nil {
+			t.Fatal("fail to write policy store:", err)
+		}
+	}
+	select {
+	case e := <-ch:
+		if e.Type != pms.FULL_RELOAD {
+			t.Errorf("expect event %d, but got %d", pms.FULL_RELOAD, e.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change is not reloaded")
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("changes should be debounced, but got another event %d", e.Type)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
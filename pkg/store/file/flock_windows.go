@@ -0,0 +1,16 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+//go:build windows
+// +build windows
+
+package file
+
+import (
+	"os"
+)
+
+// flock does nothing on windows, files are only protected from the goroutines of this process
+func flock(f *os.File, exclusive bool) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+// searchIndex is the search index of a store, it's built again when the policy file or the files it includes change
+type searchIndex struct {
+	sync.Mutex
+	index *store.PolicyIndex
+	// files are the stats of the files the index is built from
+	files map[string]os.FileInfo
+}
+
+// SearchPolicies searches the policies and role policies by the index of the store. The policy file is only read
+// again when it's changed since the index was built, by this process or any other.
+func (s *Store) SearchPolicies(query store.SearchQuery) ([]*store.SearchResult, error) {
+	s.search.Lock()
+	defer s.search.Unlock()
+
+	if s.search.index == nil || s.search.changed() {
+		s.rLock()
+		ps, files, err := s.readPolicyFilesWithoutLock()
+		stats := statFiles(files)
+		s.rUnlock()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.StoreError, "failed to read policies for search index")
+		}
+		if s.search.index == nil {
+			s.search.index = store.NewPolicyIndex(ps)
+		} else {
+			s.search.index.Reset(ps)
+		}
+		s.search.files = stats
+	}
+	return s.search.index.Search(query), nil
+}
+
+// readPolicyFilesWithoutLock reads the policy store, and returns the files it's read from
+func (s *Store) readPolicyFilesWithoutLock() (*pms.PolicyStore, []string, error) {
+	if strings.HasSuffix(s.FileLocation, ".spdl") {
+		return parseSPDLFile(s.FileLocation)
+	}
+	ps, err := s.readPolicyStoreWithoutLock()
+	return ps, []string{s.FileLocation}, err
+}
+
+func statFiles(files []string) map[string]os.FileInfo {
+	stats := make(map[string]os.FileInfo, len(files))
+	for _, file := range files {
+		if stat, err := os.Stat(file); err == nil {
+			stats[file] = stat
+		}
+	}
+	return stats
+}
+
+// changed checks whether any file the index is built from is replaced or modified. The files are replaced
+// atomically on writes, so they are compared by identity as well as by modification time and size.
+func (x *searchIndex) changed() bool {
+	if len(x.files) == 0 {
+		return true
+	}
+	for file, old := range x.files {
+		stat, err := os.Stat(file)
+		if err != nil || !os.SameFile(old, stat) || !old.ModTime().Equal(stat.ModTime()) || old.Size() != stat.Size() {
+			return true
+		}
+	}
+	return false
+}
@@ -5,18 +5,47 @@ package file
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/cmd/spctl/pdl"
 	"github.com/oracle/speedle/pkg/errors"
-	"github.com/oracle/speedle/pkg/suid"
 	log "github.com/sirupsen/logrus"
 )
 
+// SPDL is an ini like format to define a policy store, e.g.
+//
+//	include common/*.spdl
+//
+//	[functions]
+//	isWorkday https://funcs.example.com/isWorkday cachable ttl=3600
+//
+//	[service.library]
+//	type = application
+//	metadata.owner = "library team"
+//
+//	[policy]
+//	@name readers can read books
+//	p1: grant role reader read /books if isWorkday() && \
+//	        request_time > '2019-01-01 00:00:00'
+//
+//	[rolepolicy]
+//	grant group staff role reader
+//
+// Comments begin with #, and a line ending with \ is continued by the next line.
+
 type phase int
 
 const (
@@ -24,11 +53,12 @@ const (
 	phaseService
 	phasePolicy
 	phaseRolepolicy
+	phaseFunctions
 	phaseUnknown
 )
 
 func (t phase) String() string {
-	name := []string{"phaseRoot", "phaseService", "phasePolicy", "phaseRolepolicy"}
+	name := []string{"phaseRoot", "phaseService", "phasePolicy", "phaseRolepolicy", "phaseFunctions", "phaseUnknown"}
 	i := int(t)
 	switch {
 	case i < int(phaseUnknown):
@@ -44,11 +74,13 @@ const (
 	lineEmpty lineType = iota
 	lineSection
 	linePolicyDef
+	lineInclude
+	lineAnnotation
 	lineUnknown
 )
 
 func (t lineType) String() string {
-	name := []string{"lineEmpty", "lineSection", "linePolicyDef", "lineUnknown"}
+	name := []string{"lineEmpty", "lineSection", "linePolicyDef", "lineInclude", "lineAnnotation", "lineUnknown"}
 	i := int(t)
 	switch {
 	case i < int(lineUnknown):
@@ -58,63 +90,152 @@ func (t lineType) String() string {
 	}
 }
 
+// segment is a physical line which is part of a logical line
+type segment struct {
+	no    int
+	start int // offset of the physical line in the logical line
+}
+
 type lineCtx struct {
-	no      int
-	origin  string
-	trimed  string
-	ltype   lineType
-	errLoc  int
-	section string
-	phs     phase
-	service *pms.Service
+	file     string
+	no       int
+	origin   string
+	segments []segment
+	trimed   string
+	offset   int // offset of trimed in origin
+	ltype    lineType
+	errLoc   int
+	section  string
+	phs      phase
+	service  *pms.Service
+	// ids records the IDs assigned in current service, to detect duplicated labels
+	// and to tell apart identical definitions
+	ids map[string]bool
+	// annotations apply to the next policy or role policy
+	annotations   map[string]string
+	annotationPos string
 }
 
+// policyLabelRegex matches policy definitions with an explicit ID, e.g. "p1: grant user alice read /books"
+var policyLabelRegex = regexp.MustCompile(`^([A-Za-z0-9_.-]+)\s*:\s*(.+)$`)
+
+// idHashLen is the length of the hex encoded hash used as generated policy ID
+const idHashLen = 20
+
+const metadataPrefix = "metadata."
+
 var emptyPS pms.PolicyStore
 
+// spdlReader reads a policy store from a SPDL file and the files included by it
+type spdlReader struct {
+	ps *pms.PolicyStore
+	// files are the absolute paths of the files read, in the order they are read
+	files []string
+	// reading are the files being read, to detect recursive includes
+	reading map[string]bool
+}
+
+// ParseSPDLFile reads the policy store defined in SPDL file fileName
+func ParseSPDLFile(fileName string) (*pms.PolicyStore, error) {
+	ps, _, err := parseSPDLFile(fileName)
+	return ps, err
+}
+
+// parseSPDLFile reads the policy store defined in SPDL file fileName, and returns the files it consists of as well
+func parseSPDLFile(fileName string) (*pms.PolicyStore, []string, error) {
+	sr := spdlReader{
+		ps:      &pms.PolicyStore{},
+		reading: make(map[string]bool),
+	}
+	if err := sr.readFile(fileName); err != nil {
+		return &emptyPS, nil, err
+	}
+	return sr.ps, sr.files, nil
+}
+
 func (s *Store) readSPDLWithoutLock() (*pms.PolicyStore, error) {
-	var ps pms.PolicyStore
+	ps, _, err := parseSPDLFile(s.FileLocation)
+	return ps, err
+}
 
-	f, err := os.Open(s.FileLocation)
+func (sr *spdlReader) readFile(fileName string) error {
+	path, err := filepath.Abs(fileName)
 	if err != nil {
-		return &emptyPS, errors.Wrapf(err, errors.StoreError, "unable to open file %q", s.FileLocation)
+		return errors.Wrapf(err, errors.StoreError, "unable to resolve file %q", fileName)
+	}
+	if sr.reading[path] {
+		return fmt.Errorf("file %s includes itself", fileName)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to open file %q", fileName)
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
-			log.Warnf("Unable to close file %s because of error %v", s.FileLocation, err)
+			log.Warnf("Unable to close file %s because of error %v", fileName, err)
 		}
 	}()
+	sr.reading[path] = true
+	defer delete(sr.reading, path)
+	sr.files = append(sr.files, path)
 
-	lc := lineCtx{}
+	lc := lineCtx{file: fileName}
 	r := bufio.NewReader(f)
 	for {
-		if err := readLine(r, &lc); err != nil {
+		if err := readLogicalLine(r, &lc); err != nil {
 			if err == io.EOF {
 				break
-			} else {
-				return &emptyPS, err
 			}
+			return err
 		}
 
 		if err := determineType(&lc); err != nil {
-			return &emptyPS, err
+			return err
+		}
+		if lc.ltype != lineEmpty && lc.ltype != lineAnnotation && lc.ltype != linePolicyDef {
+			if err := checkAnnotations(&lc); err != nil {
+				return err
+			}
 		}
 		switch lc.ltype {
 		case lineEmpty:
 			continue
 		case lineSection:
-			if err := processSection(&ps, &lc); err != nil {
-				return &emptyPS, err
-			}
+			err = processSection(sr.ps, &lc)
+		case lineInclude:
+			err = sr.processInclude(&lc)
+		case lineAnnotation:
+			err = processAnnotation(&lc)
 		case linePolicyDef:
-			if err := processPolicyDef(&ps, &lc); err != nil {
-				return &emptyPS, err
-			}
+			err = processDef(sr.ps, &lc)
 		default:
-			return &emptyPS, fmt.Errorf("Unknown line type %s", lc.ltype)
+			err = fmt.Errorf("Unknown line type %s", lc.ltype)
+		}
+		if err != nil {
+			return err
 		}
 	}
 
-	return &ps, nil
+	return checkAnnotations(&lc)
+}
+
+// position returns the line and column number of offset p in current logical line
+func (lc *lineCtx) position(p int) (int, int) {
+	no, start := lc.no, 0
+	for _, seg := range lc.segments {
+		if seg.start > p {
+			break
+		}
+		no, start = seg.no, seg.start
+	}
+	return no, p - start + 1
+}
+
+// errorf returns an error found at offset p of current logical line
+func (lc *lineCtx) errorf(p int, format string, a ...interface{}) error {
+	no, col := lc.position(p)
+	return fmt.Errorf("%s:%d:%d: %s", lc.file, no, col, fmt.Sprintf(format, a...))
 }
 
 func getServiceSection(ps *pms.PolicyStore, service string) *pms.Service {
@@ -128,8 +249,8 @@ func getServiceSection(ps *pms.PolicyStore, service string) *pms.Service {
 
 func processPolicySection(ps *pms.PolicyStore, lc *lineCtx) error {
 	// Check if in correct service
-	if lc.phs == phaseRoot {
-		return fmt.Errorf("Policy section %s is in wrong service section", lc.section)
+	if lc.service == nil {
+		return lc.errorf(lc.offset, "section [%s] is not in a service section", lc.section)
 	}
 	lc.phs = phasePolicy
 
@@ -138,31 +259,42 @@ func processPolicySection(ps *pms.PolicyStore, lc *lineCtx) error {
 
 func processRolePolicySection(ps *pms.PolicyStore, lc *lineCtx) error {
 	// Check if in correct service
-	if lc.phs == phaseRoot {
-		return fmt.Errorf("Policy section %s is in wrong service section", lc.section)
+	if lc.service == nil {
+		return lc.errorf(lc.offset, "section [%s] is not in a service section", lc.section)
 	}
 	lc.phs = phaseRolepolicy
 
 	return nil
 }
 
+func processFunctionsSection(ps *pms.PolicyStore, lc *lineCtx) error {
+	lc.service = nil
+	lc.phs = phaseFunctions
+	return nil
+}
+
 func processServiceSection(ps *pms.PolicyStore, lc *lineCtx) error {
 	serviceName := lc.section[len("service."):]
+	if len(serviceName) == 0 {
+		return lc.errorf(lc.offset, "service name is missing in section [%s]", lc.section)
+	}
+	lc.phs = phaseService
 	service := getServiceSection(ps, serviceName)
 	if service != nil {
 		lc.service = service
+		lc.ids = serviceIDs(service)
 		return nil
 	}
 	lc.service = &pms.Service{
 		Name: serviceName,
 	}
-	lc.phs = phaseService
+	lc.ids = make(map[string]bool)
 	ps.Services = append(ps.Services, lc.service)
 	return nil
 }
 
 func processSection(ps *pms.PolicyStore, lc *lineCtx) error {
-	// There are three kinds of sections, service, policy and rolepolicy
+	// There are four kinds of sections, service, policy, rolepolicy and functions
 	switch {
 	case strings.HasPrefix(lc.section, "service."):
 		return processServiceSection(ps, lc)
@@ -170,66 +302,341 @@ func processSection(ps *pms.PolicyStore, lc *lineCtx) error {
 		return processPolicySection(ps, lc)
 	case lc.section == "rolepolicy":
 		return processRolePolicySection(ps, lc)
+	case lc.section == "functions":
+		return processFunctionsSection(ps, lc)
+	default:
+		return lc.errorf(lc.offset, "unknown section [%s]", lc.section)
+	}
+}
+
+// processInclude reads the files included by directive "include FILE", FILE is relative to the directory of current file
+// and can be a glob pattern, the matched files are read in lexical order.
+func (sr *spdlReader) processInclude(lc *lineCtx) error {
+	fields, err := splitFields(lc, lc.trimed, lc.offset)
+	if err != nil {
+		return err
+	}
+	if len(fields) != 2 {
+		return lc.errorf(lc.offset, "include directive should be followed by one file name")
+	}
+	pattern := fields[1].text
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(lc.file), pattern)
+	}
+	fileNames, err := filepath.Glob(pattern)
+	if err != nil {
+		return lc.errorf(fields[1].pos, "invalid file pattern %s: %v", fields[1].text, err)
+	}
+	if len(fileNames) == 0 && !strings.ContainsAny(fields[1].text, "*?[") {
+		return lc.errorf(fields[1].pos, "included file %s does not exist", fields[1].text)
+	}
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		if err := sr.readFile(fileName); err != nil {
+			return lc.errorf(fields[1].pos, "unable to include %s: %v", fileName, err)
+		}
+		// The included file may add policies to current service, their IDs are taken
+		if lc.service != nil {
+			for id := range serviceIDs(lc.service) {
+				lc.ids[id] = true
+			}
+		}
+	}
+	return nil
+}
+
+// processAnnotation records an annotation of the next policy or role policy, e.g. "@name readers can read books".
+// Annotation name sets the name of the policy, the others are added to its metadata.
+func processAnnotation(lc *lineCtx) error {
+	if lc.phs != phasePolicy && lc.phs != phaseRolepolicy {
+		return lc.errorf(lc.offset, "annotation is only allowed in [policy] and [rolepolicy] sections")
+	}
+	key, value := lc.trimed[1:], ""
+	if idx := strings.IndexFunc(key, unicode.IsSpace); idx != -1 {
+		key, value = key[:idx], strings.TrimSpace(key[idx:])
+	}
+	if len(key) == 0 {
+		return lc.errorf(lc.offset, "annotation key is missing")
+	}
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return lc.errorf(lc.offset+strings.Index(lc.trimed, value), "invalid quoted string %s", value)
+		}
+		value = unquoted
+	}
+	if lc.annotations == nil {
+		no, col := lc.position(lc.offset)
+		lc.annotations = make(map[string]string)
+		lc.annotationPos = fmt.Sprintf("%s:%d:%d", lc.file, no, col)
+	}
+	if _, ok := lc.annotations[key]; ok {
+		return lc.errorf(lc.offset, "duplicated annotation %s", key)
+	}
+	lc.annotations[key] = value
+	return nil
+}
+
+// checkAnnotations reports annotations not followed by a policy or role policy
+func checkAnnotations(lc *lineCtx) error {
+	if lc.annotations != nil {
+		return fmt.Errorf("%s: annotation is not followed by a policy or role policy", lc.annotationPos)
+	}
+	return nil
+}
+
+// applyAnnotations applies the pending annotations to the name and metadata of a policy or role policy
+func applyAnnotations(lc *lineCtx, name *string, metadata *map[string]string) {
+	for key, value := range lc.annotations {
+		if key == "name" {
+			*name = value
+			continue
+		}
+		if *metadata == nil {
+			*metadata = make(map[string]string)
+		}
+		(*metadata)[key] = value
+	}
+	lc.annotations = nil
+}
+
+// processServiceAttribute processes an attribute of current service, e.g. "type = k8s" or "metadata.owner = alice"
+func processServiceAttribute(lc *lineCtx) error {
+	key, value, err := splitAttribute(lc)
+	if err != nil {
+		return err
+	}
+	switch {
+	case key == "type":
+		if len(lc.service.Type) != 0 && lc.service.Type != value {
+			return lc.errorf(lc.offset, "type of service %s is already %s", lc.service.Name, lc.service.Type)
+		}
+		lc.service.Type = value
+	case strings.HasPrefix(key, metadataPrefix) && len(key) > len(metadataPrefix):
+		if lc.service.Metadata == nil {
+			lc.service.Metadata = make(map[string]string)
+		}
+		lc.service.Metadata[key[len(metadataPrefix):]] = value
 	default:
-		return fmt.Errorf("Unknown section %s", lc.section)
+		return lc.errorf(lc.offset, "unknown service attribute %s", key)
+	}
+	return nil
+}
+
+// splitAttribute splits an attribute in format "key = value", value should be double quoted if it contains spaces
+func splitAttribute(lc *lineCtx) (string, string, error) {
+	idx := strings.Index(lc.trimed, "=")
+	if idx <= 0 {
+		return "", "", lc.errorf(lc.offset, "attribute should be in format key = value")
+	}
+	key := strings.TrimSpace(lc.trimed[:idx])
+	fields, err := splitFields(lc, lc.trimed[idx+1:], lc.offset+idx+1)
+	if err != nil {
+		return "", "", err
+	}
+	if len(fields) != 1 {
+		return "", "", lc.errorf(lc.offset+idx+1, "attribute %s should have one value, quote the value if it contains spaces", key)
+	}
+	return key, fields[0].text, nil
+}
+
+// processFunctionDef processes a function definition in format "NAME URL [OPTION]...", options are
+// cachable[=BOOL], ttl=SECONDS, local-url=URL, ca=PEM, description=TEXT and metadata.KEY=VALUE
+func processFunctionDef(ps *pms.PolicyStore, lc *lineCtx) error {
+	fields, err := splitFields(lc, lc.trimed, lc.offset)
+	if err != nil {
+		return err
+	}
+	if len(fields) < 2 {
+		return lc.errorf(lc.offset, "function definition should be in format NAME URL [OPTION]...")
+	}
+	for _, function := range ps.Functions {
+		if function.Name == fields[0].text {
+			return lc.errorf(fields[0].pos, "duplicated function %s", function.Name)
+		}
+	}
+
+	function := &pms.Function{
+		Name:    fields[0].text,
+		FuncURL: fields[1].text,
+	}
+	for _, option := range fields[2:] {
+		key, value, hasValue := option.text, "", false
+		if idx := strings.Index(option.text, "="); idx != -1 {
+			key, value, hasValue = option.text[:idx], option.text[idx+1:], true
+		}
+		switch {
+		case key == "cachable":
+			function.ResultCachable = true
+			if hasValue {
+				if function.ResultCachable, err = strconv.ParseBool(value); err != nil {
+					return lc.errorf(option.pos, "invalid value of option cachable: %s", value)
+				}
+			}
+		case key == "ttl":
+			if function.ResultTTL, err = strconv.ParseInt(value, 10, 64); err != nil || function.ResultTTL < 0 {
+				return lc.errorf(option.pos, "invalid value of option ttl: %s", value)
+			}
+		case key == "local-url":
+			function.LocalFuncURL = value
+		case key == "ca":
+			function.CA = value
+		case key == "description":
+			function.Description = value
+		case strings.HasPrefix(key, metadataPrefix) && len(key) > len(metadataPrefix) && hasValue:
+			if function.Metadata == nil {
+				function.Metadata = make(map[string]string)
+			}
+			function.Metadata[key[len(metadataPrefix):]] = value
+		default:
+			return lc.errorf(option.pos, "unknown function option %s", option.text)
+		}
+	}
+	ps.Functions = append(ps.Functions, function)
+	return nil
+}
+
+// splitPolicyLabel splits a policy definition into its explicit ID and the SPDL, the ID is empty if not labeled.
+// It returns the offset of the SPDL in def as well.
+func splitPolicyLabel(def string) (string, string, int) {
+	matches := policyLabelRegex.FindStringSubmatchIndex(def)
+	if matches == nil {
+		return "", def, 0
+	}
+	return def[matches[2]:matches[3]], def[matches[4]:matches[5]], matches[4]
+}
+
+// serviceIDs returns the IDs of the policies and role policies already defined in service
+func serviceIDs(service *pms.Service) map[string]bool {
+	ids := make(map[string]bool)
+	for _, policy := range service.Policies {
+		ids[policy.ID] = true
+	}
+	for _, rolePolicy := range service.RolePolicies {
+		ids[rolePolicy.ID] = true
+	}
+	return ids
+}
+
+// assignID sets the ID of a policy or role policy defined in current line. Explicit label is used as is,
+// otherwise the ID is derived from service, section and content of the policy, so that it is stable across reads.
+// Identical definitions in the same service are told apart by the number of their occurrence.
+func assignID(lc *lineCtx, label string, section string, content interface{}) (string, error) {
+	if len(label) != 0 {
+		if lc.ids[label] {
+			return "", lc.errorf(lc.offset, "duplicated policy ID %s", label)
+		}
+		lc.ids[label] = true
+		return label, nil
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", lc.errorf(lc.offset, "unable to generate ID for policy: %v", err)
+	}
+	for occurrence := 0; ; occurrence++ {
+		h := sha256.New()
+		fmt.Fprintf(h, "%s\n%s\n%d\n", lc.service.Name, section, occurrence)
+		h.Write(data)
+		id := hex.EncodeToString(h.Sum(nil))[:idHashLen]
+		if !lc.ids[id] {
+			lc.ids[id] = true
+			return id, nil
+		}
+	}
+}
+
+// pdlError converts an error of parsing the PDL at offset of current line to an error with its position
+func pdlError(lc *lineCtx, offset int, kind string, err error) error {
+	if se, ok := err.(*pdl.SyntaxError); ok {
+		return lc.errorf(offset+se.Pos, "invalid %s: %s", kind, se.Msg)
 	}
+	return lc.errorf(offset, "invalid %s: %v", kind, err)
 }
 
 func processPolicyPDL(ps *pms.PolicyStore, lc *lineCtx) error {
-	policy, _, err := pdl.ParsePolicy(lc.trimed, "")
+	label, def, defOffset := splitPolicyLabel(lc.trimed)
+	policy, _, err := pdl.ParsePolicy(def, label)
 	if err != nil {
+		return pdlError(lc, lc.offset+defOffset, "policy", err)
+	}
+	applyAnnotations(lc, &policy.Name, &policy.Metadata)
+	if policy.ID, err = assignID(lc, label, "policy", policy); err != nil {
 		return err
 	}
-	policy.ID = suid.New().String()
 	lc.service.Policies = append(lc.service.Policies, policy)
 	return nil
 }
 
 func processRolePolicyPDL(ps *pms.PolicyStore, lc *lineCtx) error {
-	rolePolicy, _, err := pdl.ParseRolePolicy(lc.trimed, "")
+	label, def, defOffset := splitPolicyLabel(lc.trimed)
+	rolePolicy, _, err := pdl.ParseRolePolicy(def, label)
 	if err != nil {
+		return pdlError(lc, lc.offset+defOffset, "role policy", err)
+	}
+	applyAnnotations(lc, &rolePolicy.Name, &rolePolicy.Metadata)
+	if rolePolicy.ID, err = assignID(lc, label, "rolepolicy", rolePolicy); err != nil {
 		return err
 	}
-	rolePolicy.ID = suid.New().String()
 	lc.service.RolePolicies = append(lc.service.RolePolicies, rolePolicy)
 	return nil
 }
 
-func processPolicyDef(ps *pms.PolicyStore, lc *lineCtx) error {
+func processDef(ps *pms.PolicyStore, lc *lineCtx) error {
 	switch lc.phs {
+	case phaseService:
+		return processServiceAttribute(lc)
+	case phaseFunctions:
+		return processFunctionDef(ps, lc)
 	case phasePolicy:
 		return processPolicyPDL(ps, lc)
 	case phaseRolepolicy:
 		return processRolePolicyPDL(ps, lc)
 	default:
-		return fmt.Errorf("Wrong policy definition at line %d", lc.no)
+		return lc.errorf(lc.offset, "definition is not in any section")
 	}
 }
 
+// commentIndex returns the index of # which begins a comment in line, # in double quoted strings doesn't begin a comment
+func commentIndex(line string) int {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quoted && line[i] == '\\':
+			i++
+		case line[i] == '"':
+			quoted = !quoted
+		case !quoted && line[i] == '#':
+			return i
+		}
+	}
+	return -1
+}
+
 func determineType(lc *lineCtx) error {
-	lc.trimed = lc.origin
+	line := lc.origin
 	// Trim comments
-	idx := strings.Index(lc.origin, "#")
-	if idx != -1 {
-		lc.trimed = lc.origin[0:idx]
+	if idx := commentIndex(line); idx != -1 {
+		line = line[0:idx]
 	}
 
 	// Trim spaces
-	lc.trimed = strings.TrimSpace(lc.trimed)
-	if len(lc.trimed) == 0 || lc.trimed[0] == '#' {
+	lc.trimed = strings.TrimSpace(line)
+	if len(lc.trimed) == 0 {
 		// blank line
 		lc.ltype = lineEmpty
 		return nil
 	}
+	lc.offset = len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
 
 	if lc.trimed[0] == ']' {
-		return fmt.Errorf("Syntax error near %s at line %d", lc.trimed, lc.no)
+		return lc.errorf(lc.offset, "syntax error near %s", lc.trimed)
 	}
 
 	if lc.trimed[0] == '[' {
 		if len(lc.trimed) == 2 || lc.trimed[len(lc.trimed)-1] != ']' {
 			// This is an error, begin with [, but don't end with ]
-			return fmt.Errorf("Syntax error near %s at line %d", lc.trimed, lc.no)
+			return lc.errorf(lc.offset, "syntax error near %s", lc.trimed)
 		}
 		// length > 2 and wrap with []
 		lc.section = lc.trimed[1:(len(lc.trimed) - 1)]
@@ -237,12 +644,104 @@ func determineType(lc *lineCtx) error {
 		return nil
 	}
 
+	if lc.trimed[0] == '@' {
+		lc.ltype = lineAnnotation
+		return nil
+	}
+
+	if fields := strings.Fields(lc.trimed); fields[0] == "include" {
+		lc.ltype = lineInclude
+		return nil
+	}
+
 	// Don't start with [, assume this is a line for SPDL
 	lc.ltype = linePolicyDef
 
 	return nil
 }
 
+// field is a field of a line, pos is its offset in the logical line
+type field struct {
+	text string
+	pos  int
+}
+
+// splitFields splits s, which is at offset of current logical line, into fields separated by spaces.
+// Double quoted strings are unquoted, and the spaces in them don't separate fields.
+func splitFields(lc *lineCtx, s string, offset int) ([]field, error) {
+	var fields []field
+	i := 0
+	for {
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i >= len(s) {
+			return fields, nil
+		}
+
+		f := field{pos: offset + i}
+		var buf bytes.Buffer
+		for i < len(s) && !unicode.IsSpace(rune(s[i])) {
+			if s[i] != '"' {
+				buf.WriteByte(s[i])
+				i++
+				continue
+			}
+			end := closingQuote(s, i)
+			if end == -1 {
+				return nil, lc.errorf(offset+i, "unterminated quoted string")
+			}
+			unquoted, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, lc.errorf(offset+i, "invalid quoted string %s", s[i:end+1])
+			}
+			buf.WriteString(unquoted)
+			i = end + 1
+		}
+		f.text = buf.String()
+		fields = append(fields, f)
+	}
+}
+
+// closingQuote returns the index of the double quote closing the one at start
+func closingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// readLogicalLine reads a logical line, a line ending with \ is continued by the next line
+func readLogicalLine(r *bufio.Reader, lc *lineCtx) error {
+	var buf bytes.Buffer
+	lc.segments = lc.segments[:0]
+	for {
+		if err := readLine(r, lc); err != nil {
+			if err == io.EOF && len(lc.segments) != 0 {
+				// The last line ends with \
+				break
+			}
+			return err
+		}
+		lc.segments = append(lc.segments, segment{no: lc.no, start: buf.Len()})
+		line := strings.TrimRightFunc(lc.origin, unicode.IsSpace)
+		if !strings.HasSuffix(line, `\`) {
+			buf.WriteString(lc.origin)
+			break
+		}
+		buf.WriteString(line[:len(line)-1])
+		buf.WriteByte(' ')
+	}
+	lc.origin = buf.String()
+
+	return nil
+}
+
 func readLine(r *bufio.Reader, lc *lineCtx) error {
 	lc.origin = ""
 	for {
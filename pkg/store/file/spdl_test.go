@@ -7,8 +7,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/oracle/speedle/api/pms"
 )
 
 func TestReadLine(t *testing.T) {
@@ -110,3 +115,220 @@ func TestReadSPDLWithoutLock(t *testing.T) {
 		}
 	}
 }
+
+func readSPDLString(t *testing.T, content string) (*pms.PolicyStore, error) {
+	f, err := ioutil.TempFile("", "speedle-*.spdl")
+	if err != nil {
+		t.Fatalf("Failed to create temp file due to error %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("Failed to write temp file due to error %v", err)
+	}
+	f.Close()
+	store := Store{FileLocation: f.Name()}
+	return store.readSPDLWithoutLock()
+}
+
+func TestSPDLPolicyIDs(t *testing.T) {
+	content := `[service.app]
+[policy]
+p1: grant user alice read /books
+grant user bob read /books
+grant user bob read /books
+[rolepolicy]
+rp1 : grant user bill role employee
+grant user carl role employee
+`
+	ps1, err := readSPDLString(t, content)
+	if err != nil {
+		t.Fatalf("Can't read PDL file due to error %v", err)
+	}
+	ps2, err := readSPDLString(t, content)
+	if err != nil {
+		t.Fatalf("Can't read PDL file due to error %v", err)
+	}
+
+	service := ps1.Services[0]
+	if len(service.Policies) != 3 || len(service.RolePolicies) != 2 {
+		t.Fatalf("Unexpected policy count %d and role policy count %d", len(service.Policies), len(service.RolePolicies))
+	}
+	if service.Policies[0].ID != "p1" || service.Policies[0].Name != "p1" {
+		t.Errorf("Explicit policy ID is not honored, got ID %s, name %s", service.Policies[0].ID, service.Policies[0].Name)
+	}
+	if service.RolePolicies[0].ID != "rp1" || service.RolePolicies[0].Name != "rp1" {
+		t.Errorf("Explicit role policy ID is not honored, got ID %s, name %s", service.RolePolicies[0].ID, service.RolePolicies[0].Name)
+	}
+	if service.Policies[1].ID == service.Policies[2].ID {
+		t.Errorf("Identical policies should have different IDs, got %s", service.Policies[1].ID)
+	}
+	for i, policy := range service.Policies {
+		if policy.ID != ps2.Services[0].Policies[i].ID {
+			t.Errorf("Policy ID is not stable across reads, %s vs %s", policy.ID, ps2.Services[0].Policies[i].ID)
+		}
+	}
+	for i, rolePolicy := range service.RolePolicies {
+		if rolePolicy.ID != ps2.Services[0].RolePolicies[i].ID {
+			t.Errorf("Role policy ID is not stable across reads, %s vs %s", rolePolicy.ID, ps2.Services[0].RolePolicies[i].ID)
+		}
+	}
+
+	// Adding a policy doesn't change the IDs of the others
+	ps3, err := readSPDLString(t, strings.Replace(content, "[rolepolicy]", "grant user dave read /books\n[rolepolicy]", 1))
+	if err != nil {
+		t.Fatalf("Can't read PDL file due to error %v", err)
+	}
+	for i, policy := range service.Policies {
+		if policy.ID != ps3.Services[0].Policies[i].ID {
+			t.Errorf("Policy ID is changed by adding another policy, %s vs %s", policy.ID, ps3.Services[0].Policies[i].ID)
+		}
+	}
+
+	if _, err := readSPDLString(t, "[service.app]\n[policy]\np1: grant user alice read /books\np1: grant user bob read /books\n"); err == nil {
+		t.Error("Duplicated policy ID should be rejected")
+	}
+}
+
+func TestSPDLExtendedSyntax(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-spdl")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir due to error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.spdl": `include "common/*.spdl"
+
+[functions]
+isWorkday https://funcs.example.com/isWorkday cachable ttl=3600 description="is it a workday" # comment
+
+[service.library]
+type = k8s
+metadata.owner = "library # team"
+
+[policy]
+@name readers can read books
+@reviewer bob
+p1: grant role reader read /books if isWorkday() && \
+        request_user != 'guest'
+`,
+		"common/roles.spdl": `[service.library]
+[rolepolicy]
+grant group staff role reader
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir due to error %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file due to error %v", err)
+		}
+	}
+
+	ps, included, err := parseSPDLFile(filepath.Join(dir, "main.spdl"))
+	if err != nil {
+		t.Fatalf("Can't read PDL file due to error %v", err)
+	}
+	if len(included) != 2 || !strings.HasSuffix(included[1], "roles.spdl") {
+		t.Errorf("Unexpected files %v", included)
+	}
+
+	if len(ps.Functions) != 1 {
+		t.Fatalf("Wrong function count %d", len(ps.Functions))
+	}
+	function := ps.Functions[0]
+	if function.Name != "isWorkday" || function.FuncURL != "https://funcs.example.com/isWorkday" ||
+		!function.ResultCachable || function.ResultTTL != 3600 || function.Description != "is it a workday" {
+		t.Errorf("Unexpected function %+v", function)
+	}
+
+	if len(ps.Services) != 1 {
+		t.Fatalf("Wrong service count %d", len(ps.Services))
+	}
+	service := ps.Services[0]
+	if service.Type != "k8s" || service.Metadata["owner"] != "library # team" {
+		t.Errorf("Unexpected service attributes, type %s, metadata %v", service.Type, service.Metadata)
+	}
+	if len(service.Policies) != 1 || len(service.RolePolicies) != 1 {
+		t.Fatalf("Unexpected policy count %d and role policy count %d", len(service.Policies), len(service.RolePolicies))
+	}
+	policy := service.Policies[0]
+	if policy.ID != "p1" || policy.Name != "readers can read books" || policy.Metadata["reviewer"] != "bob" {
+		t.Errorf("Unexpected policy ID %s, name %s, metadata %v", policy.ID, policy.Name, policy.Metadata)
+	}
+	if !strings.Contains(policy.Condition, "isWorkday()") || !strings.Contains(policy.Condition, "request_user != 'guest'") {
+		t.Errorf("Unexpected condition %s", policy.Condition)
+	}
+}
+
+func TestSPDLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		pos     string
+	}{
+		{"unknown section", "[service.app]\n  [foo]\n", ":2:3:"},
+		{"policy out of service", "[policy]\n", ":1:1:"},
+		{"bad policy", "[service.app]\n[policy]\np1: grant user alice\n", ":3:21:"},
+		{"bad continued policy", "[service.app]\n[policy]\ngrant user alice \\\n  read\n", ":4:7:"},
+		{"unknown service attribute", "[service.app]\ncolor = red\n", ":2:1:"},
+		{"bad function option", "[functions]\nf1 http://f1 ttl=-1\n", ":2:14:"},
+		{"duplicated function", "[functions]\nf1 http://f1\nf1 http://f2\n", ":3:1:"},
+		{"dangling annotation", "[service.app]\n[policy]\n@name p1\n[rolepolicy]\n", ":3:1:"},
+		{"unterminated quote", "[service.app]\nmetadata.owner = \"alice\n", ":2:18:"},
+		{"missing include", "include nothing.spdl\n", ":1:9:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readSPDLString(t, tt.content)
+			if err == nil {
+				t.Fatal("Error should be found")
+			}
+			if !strings.Contains(err.Error(), tt.pos) {
+				t.Errorf("Error %q should be found at %s", err, tt.pos)
+			}
+		})
+	}
+}
+
+func TestSPDLIncludedIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-spdl")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir due to error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	included := `[service.app]
+[policy]
+p2: grant user bob read /books
+grant user carol read /books
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "included.spdl"), []byte(included), 0644); err != nil {
+		t.Fatalf("Failed to write file due to error %v", err)
+	}
+	main := filepath.Join(dir, "main.spdl")
+
+	// Identical definitions in the including file get their own IDs
+	content := "[service.app]\n[policy]\ninclude included.spdl\ngrant user carol read /books\n"
+	if err := ioutil.WriteFile(main, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file due to error %v", err)
+	}
+	ps, _, err := parseSPDLFile(main)
+	if err != nil {
+		t.Fatalf("Can't read PDL file due to error %v", err)
+	}
+	if policies := ps.Services[0].Policies; len(policies) != 3 || policies[1].ID == policies[2].ID {
+		t.Errorf("Identical policies should get different IDs, got %v", policies)
+	}
+
+	// Labels used in the included file are detected
+	content = "[service.app]\n[policy]\ninclude included.spdl\np2: grant user alice read /books\n"
+	if err := ioutil.WriteFile(main, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file due to error %v", err)
+	}
+	if _, _, err := parseSPDLFile(main); err == nil || !strings.Contains(err.Error(), "duplicated policy ID p2") {
+		t.Errorf("Duplicated label should be found, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// tenantStoreFileName is the file listing the tenants, it is put beside the policy file of the default tenant
+	tenantStoreFileName = "speedle_tenants.json"
+	// tenantDirName is the directory holding the policy files of the tenants, one file per tenant
+	tenantDirName = "speedle_tenants"
+)
+
+type tenantStoreContent struct {
+	Tenants []*pms.Tenant `json:"tenants,omitempty"`
+}
+
+func (s *Store) tenantStoreFileLocation() string {
+	return filepath.Join(filepath.Dir(s.FileLocation), tenantStoreFileName)
+}
+
+func (s *Store) tenantFileLocation(tenantName string) string {
+	return filepath.Join(filepath.Dir(s.FileLocation), tenantDirName, tenantName+".json")
+}
+
+func (s *Store) tenantDiscoverFileLocation(tenantName string) string {
+	return filepath.Join(filepath.Dir(s.FileLocation), tenantDirName, tenantName+"_"+discoverStoreFileName)
+}
+
+func (s *Store) readTenantsWithoutLock() (*tenantStoreContent, error) {
+	var content tenantStoreContent
+	location := s.tenantStoreFileLocation()
+	raw, err := ioutil.ReadFile(location)
+	if os.IsNotExist(err) {
+		return &content, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.StoreError, "unable to read file %q", location)
+	}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, errors.Wrapf(err, errors.SerializationError, "unable to parse tenant file %q", location)
+	}
+	return &content, nil
+}
+
+func (s *Store) writeTenantsWithoutLock(content *tenantStoreContent) error {
+	location := s.tenantStoreFileLocation()
+	raw, err := json.MarshalIndent(content, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "marshal indent failed")
+	}
+	return writeFileAtomically(location, raw, 0644)
+}
+
+// ReadTenants reads all the stored tenants
+func (s *Store) ReadTenants() ([]*pms.Tenant, error) {
+	s.rLock()
+	defer s.rUnlock()
+
+	content, err := s.readTenantsWithoutLock()
+	if err != nil {
+		return nil, err
+	}
+	return content.Tenants, nil
+}
+
+// WriteTenant creates or updates a tenant, and creates the policy file of the tenant if it does not exist.
+// The policies of the default tenant are kept in the policy file of the store.
+func (s *Store) WriteTenant(tenant *pms.Tenant) error {
+	s.lock()
+	defer s.unlock()
+
+	if tenant.Name != pms.DefaultTenant {
+		tenantFile := s.tenantFileLocation(tenant.Name)
+		if err := os.MkdirAll(filepath.Dir(tenantFile), 0755); err != nil {
+			return errors.Wrapf(err, errors.StoreError, "unable to create directory %q", filepath.Dir(tenantFile))
+		}
+		if _, err := os.Stat(tenantFile); os.IsNotExist(err) {
+			if err := ioutil.WriteFile(tenantFile, []byte("{}"), 0644); err != nil {
+				return errors.Wrapf(err, errors.StoreError, "unable to create file %q", tenantFile)
+			}
+		}
+	}
+
+	content, err := s.readTenantsWithoutLock()
+	if err != nil {
+		return err
+	}
+	for index, value := range content.Tenants {
+		if value.Name == tenant.Name {
+			content.Tenants = append(content.Tenants[:index], content.Tenants[index+1:]...)
+			break
+		}
+	}
+	content.Tenants = append(content.Tenants, tenant)
+	return s.writeTenantsWithoutLock(content)
+}
+
+// RemoveTenant deletes a tenant together with its policy file and discover request file
+func (s *Store) RemoveTenant(tenantName string) error {
+	s.lock()
+	defer s.unlock()
+
+	content, err := s.readTenantsWithoutLock()
+	if err != nil {
+		return err
+	}
+	found := false
+	for index, value := range content.Tenants {
+		if value.Name == tenantName {
+			content.Tenants = append(content.Tenants[:index], content.Tenants[index+1:]...)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf(errors.EntityNotFound, "tenant %q is not found", tenantName)
+	}
+	if err := s.writeTenantsWithoutLock(content); err != nil {
+		return err
+	}
+
+	for _, location := range []string{s.tenantFileLocation(tenantName), s.tenantDiscoverFileLocation(tenantName),
+		s.tenantFileLocation(tenantName) + lockFileSuffix, s.tenantDiscoverFileLocation(tenantName) + lockFileSuffix} {
+		if err := os.Remove(location); err != nil && !os.IsNotExist(err) {
+			log.Warningf("Unable to remove file %q of deleted tenant %q, error: %v", location, tenantName, err)
+		}
+	}
+	return nil
+}
+
+// NewTenantStore creates the store of a tenant, which keeps policies in the tenant's own file
+func (s *Store) NewTenantStore(tenantName string) (pms.PolicyStoreManager, error) {
+	tenantFile := s.tenantFileLocation(tenantName)
+	if _, err := os.Stat(tenantFile); err != nil {
+		return nil, errors.Wrapf(err, errors.StoreError, "policy file %q of tenant %q is not available", tenantFile, tenantName)
+	}
+	return &Store{
+		FileLocation:         tenantFile,
+		discoverFileLocation: s.tenantDiscoverFileLocation(tenantName),
+	}, nil
+}
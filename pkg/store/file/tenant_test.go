@@ -0,0 +1,95 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+func TestTenants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-tenants")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ps, err := store.NewStore("file", map[string]interface{}{"FileLocation": filepath.Join(dir, "ps.json")})
+	if err != nil {
+		t.Fatal("fail to new file store:", err)
+	}
+	tenants, err := store.NewTenantManager(ps)
+	if err != nil {
+		t.Fatal("fail to new tenant manager:", err)
+	}
+
+	for _, name := range []string{"", "-t1", "t/1", "t 1", pms.DefaultTenant} {
+		if err := tenants.CreateTenant(&pms.Tenant{Name: name}); err == nil {
+			t.Errorf("tenant %q should not be created", name)
+		}
+	}
+
+	if err := tenants.CreateTenant(&pms.Tenant{Name: "t1", Quota: &pms.Quota{MaxServiceNum: 1}}); err != nil {
+		t.Fatal("fail to create tenant:", err)
+	}
+	if err := tenants.CreateTenant(&pms.Tenant{Name: "t1"}); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect %s error, but got %v", errors.EntityAlreadyExists, err)
+	}
+
+	tenantList, err := tenants.ListAllTenants()
+	if err != nil {
+		t.Fatal("fail to list tenants:", err)
+	}
+	if len(tenantList) != 2 || tenantList[0].Name != pms.DefaultTenant || tenantList[1].Name != "t1" {
+		t.Errorf("unexpected tenants %v", tenantList)
+	}
+	tenant, err := tenants.GetTenant("t1")
+	if err != nil {
+		t.Fatal("fail to get tenant:", err)
+	}
+	if tenant.Quota == nil || tenant.Quota.MaxServiceNum != 1 {
+		t.Errorf("quota of tenant is not persisted, got %v", tenant.Quota)
+	}
+
+	// policies of tenants are isolated
+	tenantStore, err := tenants.TenantStore("t1")
+	if err != nil {
+		t.Fatal("fail to get tenant store:", err)
+	}
+	if err := tenantStore.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal("fail to create service in tenant:", err)
+	}
+	if _, err := ps.GetService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("service of tenant should not be visible in default tenant, err: %v", err)
+	}
+	if _, err := tenantStore.GetService("app1"); err != nil {
+		t.Errorf("fail to get service of tenant: %v", err)
+	}
+	defaultStore, err := tenants.TenantStore("")
+	if err != nil || defaultStore != ps {
+		t.Errorf("expect the default store, but got %v, err: %v", defaultStore, err)
+	}
+
+	if err := tenants.DeleteTenant(pms.DefaultTenant); err == nil {
+		t.Error("default tenant should not be deleted")
+	}
+	if err := tenants.DeleteTenant("t1"); err != nil {
+		t.Fatal("fail to delete tenant:", err)
+	}
+	if _, err := tenants.GetTenant("t1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect %s error, but got %v", errors.EntityNotFound, err)
+	}
+	if _, err := tenants.TenantStore("t1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect %s error, but got %v", errors.EntityNotFound, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, tenantDirName, "t1.json")); !os.IsNotExist(err) {
+		t.Errorf("policy file of deleted tenant should be removed, err: %v", err)
+	}
+}
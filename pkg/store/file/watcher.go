@@ -0,0 +1,77 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchedFiles tracks the files watched for changes. The directories of the files are watched instead of the files,
+// since files are replaced when they are written, and may be removed and created again. Symbolic links are followed,
+// e.g. a file mounted from a Kubernetes ConfigMap links to ..data/FILE, and ..data is swapped to a new directory
+// when the ConfigMap is updated.
+type watchedFiles struct {
+	watcher *fsnotify.Watcher
+	// targets are the files which the watched files resolve to, keyed by the absolute paths of the watched files.
+	// Target is empty if the file doesn't exist.
+	targets map[string]string
+}
+
+func newWatchedFiles(watcher *fsnotify.Watcher) *watchedFiles {
+	return &watchedFiles{
+		watcher: watcher,
+		targets: make(map[string]string),
+	}
+}
+
+// add starts watching file, which is an absolute path
+func (w *watchedFiles) add(file string) error {
+	if _, ok := w.targets[file]; ok {
+		return nil
+	}
+	if err := w.watcher.Add(filepath.Dir(file)); err != nil {
+		return err
+	}
+	w.targets[file] = ""
+	w.resolve(file)
+	return nil
+}
+
+// resolve updates the target of file, and tells whether the target is changed
+func (w *watchedFiles) resolve(file string) bool {
+	target, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		target = ""
+	}
+	if target == w.targets[file] {
+		return false
+	}
+	w.targets[file] = target
+	if len(target) != 0 && filepath.Dir(target) != filepath.Dir(file) {
+		// Watch where the link points to as well, so that the changes of the target are noticed
+		if err := w.watcher.Add(filepath.Dir(target)); err != nil {
+			log.Warnf("Failed to add the directory of %q, which %q links to, into the watch list, error: %v", target, file, err)
+		}
+	}
+	return true
+}
+
+// changed tells whether event changes any of the watched files, either the file itself or where it links to
+func (w *watchedFiles) changed(event fsnotify.Event) bool {
+	name := filepath.Clean(event.Name)
+	changed := false
+	for file, target := range w.targets {
+		if (name == file || name == target) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+			changed = true
+		}
+		// Any change in the directory may swap a symbolic link
+		if w.resolve(file) {
+			changed = true
+		}
+	}
+	return changed
+}
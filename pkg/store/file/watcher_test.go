@@ -0,0 +1,113 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+func expectReload(t *testing.T, ch pms.StorageChangeChannel, reload bool) {
+	select {
+	case e := <-ch:
+		if !reload {
+			t.Fatalf("no event is expected, but got %d", e.Type)
+		}
+		if e.Type != pms.FULL_RELOAD {
+			t.Fatalf("expect event %d, but got %d", pms.FULL_RELOAD, e.Type)
+		}
+	case <-time.After(time.Second):
+		if reload {
+			t.Fatal("change is not reloaded")
+		}
+	}
+}
+
+func TestWatchMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-watch")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &Store{FileLocation: filepath.Join(dir, "ps.json"), watchDebounce: 50 * time.Millisecond}
+	if err := store.WritePolicyStore(&pms.PolicyStore{}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	ch, err := store.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	defer store.StopWatch()
+
+	if err := os.Remove(store.FileLocation); err != nil {
+		t.Fatal("fail to remove policy file:", err)
+	}
+	expectReload(t, ch, false)
+	if err := store.Health(); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect %s error, but got %v", errors.EntityNotFound, err)
+	}
+
+	if err := store.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{{Name: "app1"}}}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	expectReload(t, ch, true)
+	if err := store.Health(); err != nil {
+		t.Errorf("store should be recovered, but got %v", err)
+	}
+}
+
+func TestWatchConfigMapUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "speedle-watch")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Files of a ConfigMap volume are linked to ..data/FILE, and ..data links to a directory holding the current data
+	writeData := func(version string, content string) {
+		if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatal("fail to create data dir:", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, version, "ps.json"), []byte(content), 0644); err != nil {
+			t.Fatal("fail to write policy file:", err)
+		}
+		if err := os.Symlink(version, filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal("fail to create link:", err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal("fail to swap link:", err)
+		}
+	}
+	writeData("..v1", `{}`)
+	if err := os.Symlink(filepath.Join("..data", "ps.json"), filepath.Join(dir, "ps.json")); err != nil {
+		t.Fatal("fail to create link:", err)
+	}
+
+	store := &Store{FileLocation: filepath.Join(dir, "ps.json"), watchDebounce: 50 * time.Millisecond}
+	ch, err := store.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	defer store.StopWatch()
+
+	writeData("..v2", `{"services": [{"name": "app1"}]}`)
+	expectReload(t, ch, true)
+	ps, err := store.ReadPolicyStore()
+	if err != nil || len(ps.Services) != 1 {
+		t.Errorf("new policies are not read, error: %v", err)
+	}
+
+	writeData("..v3", `{"services": [`)
+	expectReload(t, ch, false)
+	if err := store.Health(); errors.Code(err) != errors.StoreError {
+		t.Errorf("expect %s error, but got %v", errors.StoreError, err)
+	}
+}
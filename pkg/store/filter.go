@@ -0,0 +1,376 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// Filter attributes. Multi-valued attributes, e.g. principal, match if any of their values matches.
+const (
+	FilterName      = "name"
+	FilterEffect    = "effect"
+	FilterPrincipal = "principal"
+	// FilterResource matches both resources and resource expressions
+	FilterResource = "resource"
+	// FilterRole matches the roles of role policies, and the role principals of policies
+	FilterRole = "role"
+	// FilterCreated is the creation time recorded in metadata CreateTimeKey, compared as RFC 3339 time
+	FilterCreated = "created"
+	// FilterMetadataPrefix is the prefix of metadata attributes, e.g. metadata.createby
+	FilterMetadataPrefix = "metadata."
+)
+
+// CreateTimeKey is the metadata key of the creation time of services, policies and role policies
+const CreateTimeKey = "createtime"
+
+const rolePrincipalPrefix = "role:"
+
+// comparison operators
+var filterOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "pr": true, "gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// Filter is a parsed SCIM-like filter expression, e.g.
+//
+//	name sw "read" and (principal eq "group:finance" or metadata.owner pr) and created ge "2019-01-01T00:00:00Z"
+//
+// Comparisons are attribute operator value, where the operator is one of eq, ne, co, sw, ew, pr, gt, ge, lt and le,
+// and pr takes no value. Values are quoted strings or bare words. Comparisons are combined with and, or, not and
+// parentheses, and binds tighter than or.
+type Filter struct {
+	// logical operator: and, or or not, empty for a comparison
+	logical  string
+	operands []*Filter
+
+	attribute string
+	operator  string
+	value     string
+	time      time.Time
+}
+
+// ParseFilter parses a filter expression, nil filter is returned for empty expression
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.Errorf(errors.InvalidRequest, "unexpected %q in filter %q", p.tokens[p.pos].text, expression)
+	}
+	return f, nil
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+		case c == '"':
+			var value strings.Builder
+			i++
+			for ; i < len(expression) && expression[i] != '"'; i++ {
+				if expression[i] == '\\' && i+1 < len(expression) {
+					i++
+				}
+				value.WriteByte(expression[i])
+			}
+			if i == len(expression) {
+				return nil, errors.Errorf(errors.InvalidRequest, "unterminated string in filter %q", expression)
+			}
+			tokens = append(tokens, filterToken{text: value.String(), quoted: true})
+			i++
+		default:
+			start := i
+			for ; i < len(expression) && !strings.ContainsRune(" \t\n()\"", rune(expression[i])); i++ {
+			}
+			tokens = append(tokens, filterToken{text: expression[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// keyword returns true and moves to the next token if the current one is keyword k
+func (p *filterParser) keyword(k string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (*Filter, error) {
+	return p.parseLogical("or", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*Filter, error) {
+	return p.parseLogical("and", p.parseNot)
+}
+
+func (p *filterParser) parseLogical(logical string, parseOperand func() (*Filter, error)) (*Filter, error) {
+	f, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(logical) {
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if f.logical != logical {
+			f = &Filter{logical: logical, operands: []*Filter{f}}
+		}
+		f.operands = append(f.operands, operand)
+	}
+	return f, nil
+}
+
+func (p *filterParser) parseNot() (*Filter, error) {
+	if p.keyword("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Filter{logical: "not", operands: []*Filter{operand}}, nil
+	}
+	if p.keyword("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, errors.New(errors.InvalidRequest, "missing ) in filter")
+		}
+		return f, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (*Filter, error) {
+	if p.pos+1 >= len(p.tokens) {
+		return nil, errors.New(errors.InvalidRequest, "incomplete comparison in filter")
+	}
+	f := &Filter{attribute: p.tokens[p.pos].text, operator: strings.ToLower(p.tokens[p.pos+1].text)}
+	p.pos += 2
+	switch attr := f.attribute; {
+	case attr == FilterName, attr == FilterEffect, attr == FilterPrincipal, attr == FilterResource,
+		attr == FilterRole, attr == FilterCreated:
+	case strings.HasPrefix(attr, FilterMetadataPrefix) && len(attr) > len(FilterMetadataPrefix):
+	default:
+		return nil, errors.Errorf(errors.InvalidRequest, "unsupported filter attribute %q", attr)
+	}
+	if !filterOperators[f.operator] {
+		return nil, errors.Errorf(errors.InvalidRequest, "unsupported filter operator %q", f.operator)
+	}
+	if f.operator == "pr" {
+		return f, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, errors.Errorf(errors.InvalidRequest, "missing value of %s %s in filter", f.attribute, f.operator)
+	}
+	f.value = p.tokens[p.pos].text
+	p.pos++
+	if f.attribute == FilterCreated {
+		t, err := parseFilterTime(f.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, errors.InvalidRequest, "invalid time %q in filter", f.value)
+		}
+		f.time = t
+	}
+	return f, nil
+}
+
+// Logical returns the logical operator combining the operands of the filter, and, or or not, it's empty if the
+// filter is a comparison
+func (f *Filter) Logical() string {
+	return f.logical
+}
+
+// Operands returns the filters combined by the logical operator
+func (f *Filter) Operands() []*Filter {
+	return f.operands
+}
+
+// Comparison returns the attribute, operator and value of a comparison, the value is empty for pr
+func (f *Filter) Comparison() (attribute string, operator string, value string) {
+	return f.attribute, f.operator, f.value
+}
+
+// parseFilterTime parses RFC 3339 time, or date
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// Match tells whether an entity, a service, policy, role policy, function or tenant, matches the filter. Nil
+// filter matches everything.
+func (f *Filter) Match(entity interface{}) bool {
+	if f == nil {
+		return true
+	}
+	switch f.logical {
+	case "and":
+		for _, operand := range f.operands {
+			if !operand.Match(entity) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, operand := range f.operands {
+			if operand.Match(entity) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !f.operands[0].Match(entity)
+	}
+
+	values := filterValues(entity, f.attribute)
+	if f.operator == "ne" {
+		return !f.compareAny(values, "eq")
+	}
+	return f.compareAny(values, f.operator)
+}
+
+func (f *Filter) compareAny(values []string, operator string) bool {
+	for _, value := range values {
+		if f.compare(value, operator) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) compare(value string, operator string) bool {
+	if operator == "pr" {
+		return len(value) > 0
+	}
+	if f.attribute == FilterCreated {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return false
+		}
+		switch operator {
+		case "eq":
+			return t.Equal(f.time)
+		case "gt":
+			return t.After(f.time)
+		case "ge":
+			return !t.Before(f.time)
+		case "lt":
+			return t.Before(f.time)
+		case "le":
+			return !t.After(f.time)
+		}
+	}
+	switch operator {
+	case "eq":
+		return value == f.value
+	case "co":
+		return strings.Contains(value, f.value)
+	case "sw":
+		return strings.HasPrefix(value, f.value)
+	case "ew":
+		return strings.HasSuffix(value, f.value)
+	case "gt":
+		return value > f.value
+	case "ge":
+		return value >= f.value
+	case "lt":
+		return value < f.value
+	case "le":
+		return value <= f.value
+	}
+	return false
+}
+
+// filterValues returns the values of an attribute of an entity
+func filterValues(entity interface{}, attribute string) []string {
+	var name, effect string
+	var metadata map[string]string
+	var principals, resources, roles []string
+	switch e := entity.(type) {
+	case *pms.Service:
+		name, metadata = e.Name, e.Metadata
+	case *pms.Policy:
+		name, effect, metadata = e.Name, e.Effect, e.Metadata
+		for _, and := range e.Principals {
+			for _, principal := range and {
+				principals = append(principals, principal)
+				if strings.HasPrefix(principal, rolePrincipalPrefix) {
+					roles = append(roles, strings.TrimPrefix(principal, rolePrincipalPrefix))
+				}
+			}
+		}
+		for _, permission := range e.Permissions {
+			if len(permission.Resource) > 0 {
+				resources = append(resources, permission.Resource)
+			}
+			if len(permission.ResourceExpression) > 0 {
+				resources = append(resources, permission.ResourceExpression)
+			}
+		}
+	case *pms.RolePolicy:
+		name, effect, metadata = e.Name, e.Effect, e.Metadata
+		principals, roles = e.Principals, e.Roles
+		resources = append(append(resources, e.Resources...), e.ResourceExpressions...)
+	case *pms.Function:
+		name, metadata = e.Name, e.Metadata
+	case *pms.Tenant:
+		name, metadata = e.Name, e.Metadata
+	}
+
+	switch attribute {
+	case FilterName:
+		return []string{name}
+	case FilterEffect:
+		return []string{effect}
+	case FilterPrincipal:
+		return principals
+	case FilterResource:
+		return resources
+	case FilterRole:
+		return roles
+	case FilterCreated:
+		if created, ok := metadata[CreateTimeKey]; ok {
+			return []string{created}
+		}
+		return nil
+	}
+	if value, ok := metadata[strings.TrimPrefix(attribute, FilterMetadataPrefix)]; ok {
+		return []string{value}
+	}
+	return nil
+}
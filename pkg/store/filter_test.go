@@ -0,0 +1,102 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+func TestFilterMatch(t *testing.T) {
+	policy := &pms.Policy{
+		ID:     "1",
+		Name:   "read-reports",
+		Effect: pms.Grant,
+		Permissions: []*pms.Permission{
+			{Resource: "/reports", Actions: []string{"get"}},
+			{ResourceExpression: "/reports/.*", Actions: []string{"get"}},
+		},
+		Principals: [][]string{{"group:finance"}, {"user:alice", "role:auditor"}},
+		Metadata:   map[string]string{"owner": "bob", CreateTimeKey: "2019-03-01T10:00:00Z"},
+	}
+	rolePolicy := &pms.RolePolicy{
+		ID:         "2",
+		Name:       "auditors",
+		Effect:     pms.Deny,
+		Roles:      []string{"auditor"},
+		Principals: []string{"user:carl"},
+		Resources:  []string{"/audit"},
+	}
+
+	tests := []struct {
+		filter     string
+		policy     bool
+		rolePolicy bool
+	}{
+		{"", true, true},
+		{"name eq read-reports", true, false},
+		{`name eq "read-reports"`, true, false},
+		{"name ne read-reports", false, true},
+		{"name co report", true, false},
+		{"name sw aud", false, true},
+		{"name ew reports", true, false},
+		{"name gt b", true, false},
+		{"name le b", false, true},
+		{"effect eq deny", false, true},
+		{"principal eq group:finance", true, false},
+		{"principal eq user:carl", false, true},
+		{"resource eq /reports/.*", true, false},
+		{"resource sw /audit", false, true},
+		{"role eq auditor", true, true},
+		{"metadata.owner eq bob", true, false},
+		{"metadata.owner pr", true, false},
+		{"not metadata.owner pr", false, true},
+		{"created gt 2019-01-01", true, false},
+		{`created lt "2019-03-01T11:00:00+01:00"`, false, false},
+		{`created eq "2019-03-01T11:00:00+01:00"`, true, false},
+		{"effect eq grant and principal eq user:carl", false, false},
+		{"effect eq grant or principal eq user:carl", true, true},
+		{"role eq auditor and (effect eq deny or name sw read)", true, true},
+		{"role eq auditor and not (effect eq deny or name sw read)", false, false},
+		{"name eq x or name eq auditors and effect eq deny", false, true},
+		{"NAME EQ x OR name EQ read-reports", false, false},
+	}
+	for _, test := range tests {
+		f, err := ParseFilter(test.filter)
+		if err != nil {
+			// attribute names are case sensitive
+			if test.policy || test.rolePolicy {
+				t.Errorf("failed to parse filter %q: %v", test.filter, err)
+			}
+			continue
+		}
+		if got := f.Match(policy); got != test.policy {
+			t.Errorf("filter %q should match policy: %v, got %v", test.filter, test.policy, got)
+		}
+		if got := f.Match(rolePolicy); got != test.rolePolicy {
+			t.Errorf("filter %q should match role policy: %v, got %v", test.filter, test.rolePolicy, got)
+		}
+	}
+}
+
+func TestParseInvalidFilter(t *testing.T) {
+	for _, filter := range []string{
+		"name",
+		"name eq",
+		"title eq x",
+		"name like x",
+		`name eq "x`,
+		"(name eq x",
+		"name eq x)",
+		"name eq x and",
+		"created gt yesterday",
+		"metadata. pr",
+	} {
+		if _, err := ParseFilter(filter); errors.Code(err) != errors.InvalidRequest {
+			t.Errorf("filter %q should be invalid, got error %v", filter, err)
+		}
+	}
+}
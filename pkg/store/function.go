@@ -0,0 +1,62 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"bytes"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// wasmMagic starts all the WebAssembly binaries
+var wasmMagic = []byte("\x00asm")
+
+// ValidateFunction checks the definition of a custom function, http functions are called at funcURL, and wasm
+// functions are run from their modules
+func ValidateFunction(function *pms.Function) error {
+	if function == nil || function.Name == "" {
+		return errors.New(errors.InvalidRequest, "\"name\" in function definition can not be empty")
+	}
+	switch function.Kind {
+	case "", pms.FunctionKindHTTP:
+		if function.FuncURL == "" {
+			return errors.New(errors.InvalidRequest, "\"name\" and \"funcURL\" in function definition can not be empty")
+		}
+		if len(function.Module) != 0 {
+			return errors.Errorf(errors.InvalidRequest, "module is only allowed in wasm function, function %q is an http function", function.Name)
+		}
+	case pms.FunctionKindWasm:
+		if !bytes.HasPrefix(function.Module, wasmMagic) {
+			return errors.Errorf(errors.InvalidRequest, "module of wasm function %q is not a WebAssembly binary", function.Name)
+		}
+		if function.Retry != nil || function.CircuitBreaker != nil {
+			return errors.Errorf(errors.InvalidRequest, "retry and circuit breaker are only allowed in http function, function %q is a wasm function", function.Name)
+		}
+	default:
+		return errors.Errorf(errors.InvalidRequest, "unknown kind %q of function %q, it should be %q or %q", function.Kind,
+			function.Name, pms.FunctionKindHTTP, pms.FunctionKindWasm)
+	}
+	if function.Limits != nil && (function.Limits.MaxMemoryMB < 0 || function.Limits.TimeoutMS < 0 || function.Limits.MaxConcurrency < 0) {
+		return errors.Errorf(errors.InvalidRequest, "limits of function %q can not be negative", function.Name)
+	}
+	if retry := function.Retry; retry != nil {
+		if retry.MaxRetries < 0 || retry.BackoffMS < 0 {
+			return errors.Errorf(errors.InvalidRequest, "retry policy of function %q can not be negative", function.Name)
+		}
+		if retry.MaxRetries > pms.MaxFunctionRetries || retry.BackoffMS > pms.MaxFunctionRetryBackoffMS {
+			return errors.Errorf(errors.InvalidRequest, "retry policy of function %q exceeds the limits, maxRetries is at most %d and backoffMS is at most %d",
+				function.Name, pms.MaxFunctionRetries, pms.MaxFunctionRetryBackoffMS)
+		}
+	}
+	if breaker := function.CircuitBreaker; breaker != nil {
+		if breaker.FailureThreshold < 0 || breaker.OpenSeconds < 0 {
+			return errors.Errorf(errors.InvalidRequest, "circuit breaker of function %q can not be negative", function.Name)
+		}
+		if breaker.FailOpen && breaker.FallbackResult == nil {
+			return errors.Errorf(errors.InvalidRequest, "fallback result of function %q is required to fail open", function.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+func TestValidateFunction(t *testing.T) {
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	valid := []*pms.Function{
+		{Name: "f1", FuncURL: "https://localhost:8443/f1"},
+		{Name: "f2", Kind: pms.FunctionKindHTTP, FuncURL: "http://localhost:8080/f2"},
+		{Name: "f3", Kind: pms.FunctionKindWasm, Module: module, Limits: &pms.FunctionLimits{MaxMemoryMB: 4, TimeoutMS: 100}},
+		{Name: "f4", FuncURL: "https://localhost:8443/f4",
+			Retry: &pms.RetryPolicy{MaxRetries: 2}, CircuitBreaker: &pms.CircuitBreaker{FailOpen: true, FallbackResult: false}},
+	}
+	for _, f := range valid {
+		if err := ValidateFunction(f); err != nil {
+			t.Errorf("function %s should be valid, got %v", f.Name, err)
+		}
+	}
+	invalid := []*pms.Function{
+		{FuncURL: "http://localhost:8080/f"},
+		{Name: "f"},
+		{Name: "f", FuncURL: "http://localhost:8080/f", Module: module},
+		{Name: "f", Kind: pms.FunctionKindWasm},
+		{Name: "f", Kind: pms.FunctionKindWasm, Module: []byte("#!/bin/sh")},
+		{Name: "f", Kind: pms.FunctionKindWasm, Module: module, Limits: &pms.FunctionLimits{TimeoutMS: -1}},
+		{Name: "f", Kind: pms.FunctionKindWasm, Module: module, Limits: &pms.FunctionLimits{MaxConcurrency: -1}},
+		{Name: "f", Kind: "plugin", FuncURL: "f.so"},
+		{Name: "f", Kind: pms.FunctionKindWasm, Module: module, Retry: &pms.RetryPolicy{MaxRetries: 1}},
+		{Name: "f", FuncURL: "http://localhost:8080/f", Retry: &pms.RetryPolicy{BackoffMS: -1}},
+		{Name: "f", FuncURL: "http://localhost:8080/f", Retry: &pms.RetryPolicy{MaxRetries: pms.MaxFunctionRetries + 1}},
+		{Name: "f", FuncURL: "http://localhost:8080/f", Retry: &pms.RetryPolicy{BackoffMS: pms.MaxFunctionRetryBackoffMS + 1}},
+		{Name: "f", FuncURL: "http://localhost:8080/f", CircuitBreaker: &pms.CircuitBreaker{FailureThreshold: -1}},
+		{Name: "f", FuncURL: "http://localhost:8080/f", CircuitBreaker: &pms.CircuitBreaker{FailOpen: true}},
+	}
+	for _, f := range invalid {
+		if err := ValidateFunction(f); errors.Code(err) != errors.InvalidRequest {
+			t.Errorf("function %+v should be invalid, got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// HealthReporter is implemented by the policy stores and evaluators which can tell whether the policies they serve
+// are up to date, e.g. the file store keeps serving the policies loaded before if the policy file becomes invalid.
+type HealthReporter interface {
+	// Health returns nil if the policies are up to date, otherwise the problem. Error with code EntityNotFound
+	// means the policies are removed from the backend, e.g. the policy file is deleted.
+	Health() error
+}
+
+// IsRemoved tells whether i, a policy store or an evaluator, reports that its policies are removed from the backend
+func IsRemoved(i interface{}) bool {
+	if reporter, ok := i.(HealthReporter); ok {
+		return errors.Code(reporter.Health()) == errors.EntityNotFound
+	}
+	return false
+}
+
+// RevisionReporter is implemented by the policy stores and evaluators which can tell the revision of the policies
+// they serve, so operators can confirm replicas watching the same backend have converged.
+type RevisionReporter interface {
+	// Revision returns the revision of the last change delivered by the store, ok is false if it's unknown.
+	Revision() (revision int64, ok bool)
+}
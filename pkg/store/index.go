@@ -0,0 +1,388 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// SearchQuery selects the policies and role policies to search, empty fields match everything but at least one
+// field must be set.
+type SearchQuery struct {
+	// Principal matches the principals of policies and role policies, e.g. group:finance
+	Principal string
+	// Resource matches the resources of policies and role policies, and their resource expressions matching it
+	Resource string
+	// Role matches the roles of role policies, and the role principals of policies
+	Role string
+	// Action matches the actions of policies, role policies are not matched if it's set. If Resource is set too,
+	// the action must be granted or denied on the resource by the same permission.
+	Action string
+}
+
+// SearchResult is the policies and role policies of a service matching a search
+type SearchResult struct {
+	ServiceName  string            `json:"serviceName"`
+	Policies     []*pms.Policy     `json:"policies,omitempty"`
+	RolePolicies []*pms.RolePolicy `json:"rolePolicies,omitempty"`
+}
+
+// PolicySearcher is implemented by the policy stores which maintain a PolicyIndex of their policies. The index is
+// built on first search, and kept up to date with the changes of the store afterwards.
+type PolicySearcher interface {
+	// SearchPolicies returns the matched policies and role policies grouped by service, ordered by service name
+	// and ID
+	SearchPolicies(query SearchQuery) ([]*SearchResult, error)
+}
+
+// Search finds the policies and role policies matching query in all services of ps. Stores not implementing
+// PolicySearcher are searched by an index built from all their policies.
+func Search(ps pms.PolicyStoreManager, query SearchQuery) ([]*SearchResult, error) {
+	if len(query.Principal) == 0 && len(query.Resource) == 0 && len(query.Role) == 0 && len(query.Action) == 0 {
+		return nil, errors.New(errors.InvalidRequest, "principal, resource, role or action should be specified")
+	}
+	if searcher, ok := ps.(PolicySearcher); ok {
+		return searcher.SearchPolicies(query)
+	}
+	policyStore, err := ps.ReadPolicyStore()
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicyIndex(policyStore).Search(query), nil
+}
+
+// index term kinds
+const (
+	principalTerm = "principal:"
+	resourceTerm  = "resource:"
+	roleTerm      = "role:"
+	actionTerm    = "action:"
+)
+
+// indexRef identifies a policy or role policy in the index
+type indexRef struct {
+	serviceName string
+	rolePolicy  bool
+	id          string
+}
+
+// indexedExpression is a resource expression and the policies using it
+type indexedExpression struct {
+	re   *regexp.Regexp
+	refs map[indexRef]bool
+}
+
+// PolicyIndex is an in-memory inverted index of policies and role policies by principal, resource, role and
+// action, so searches only read the matched policies. Policy stores keep it up to date by applying their change
+// events. It's safe for concurrent use.
+type PolicyIndex struct {
+	lock sync.RWMutex
+	// entities are *pms.Policy or *pms.RolePolicy
+	entities map[indexRef]interface{}
+	services map[string]map[indexRef]bool
+	terms    map[string]map[indexRef]bool
+	// resource expressions are matched against the searched resource
+	expressions map[string]*indexedExpression
+}
+
+// NewPolicyIndex creates the index of the policies in ps
+func NewPolicyIndex(ps *pms.PolicyStore) *PolicyIndex {
+	x := &PolicyIndex{}
+	x.Reset(ps)
+	return x
+}
+
+// Reset replaces the content of the index with the policies in ps
+func (x *PolicyIndex) Reset(ps *pms.PolicyStore) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.reset(ps)
+}
+
+func (x *PolicyIndex) reset(ps *pms.PolicyStore) {
+	x.entities = make(map[indexRef]interface{})
+	x.services = make(map[string]map[indexRef]bool)
+	x.terms = make(map[string]map[indexRef]bool)
+	x.expressions = make(map[string]*indexedExpression)
+	if ps == nil {
+		return
+	}
+	for _, service := range ps.Services {
+		x.putService(service)
+	}
+}
+
+// Apply updates the index with a store change event, false is returned if the index can't be updated by the event,
+// e.g. FULL_RELOAD, then it should be reset.
+func (x *PolicyIndex) Apply(e pms.StoreChangeEvent) bool {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	switch e.Type {
+	case pms.SERVICE_ADD:
+		service, ok := e.Content.(*pms.Service)
+		if !ok {
+			return false
+		}
+		x.removeService(service.Name)
+		x.putService(service)
+	case pms.SERVICE_DELETE:
+		serviceNames, ok := e.Content.([]string)
+		if !ok {
+			return false
+		}
+		for _, serviceName := range serviceNames {
+			x.removeService(serviceName)
+		}
+	case pms.POLICY_ADD, pms.POLICY_DELETE, pms.ROLEPOLICY_ADD, pms.ROLEPOLICY_DELETE:
+		data, ok := e.Content.([]pms.StoreUpdateData)
+		if !ok {
+			return false
+		}
+		for _, d := range data {
+			switch entity := d.Data.(type) {
+			case *pms.Policy:
+				ref := indexRef{serviceName: d.ServiceName, id: entity.ID}
+				x.remove(ref)
+				if e.Type == pms.POLICY_ADD {
+					x.put(ref, entity)
+				}
+			case *pms.RolePolicy:
+				ref := indexRef{serviceName: d.ServiceName, rolePolicy: true, id: entity.ID}
+				x.remove(ref)
+				if e.Type == pms.ROLEPOLICY_ADD {
+					x.put(ref, entity)
+				}
+			default:
+				return false
+			}
+		}
+	case pms.SYNC_RELOAD:
+		ps, ok := e.Content.(*pms.PolicyStore)
+		if !ok {
+			return false
+		}
+		x.reset(ps)
+	case pms.FUNCTION_ADD, pms.FUNCTION_DELETE:
+	default:
+		return false
+	}
+	return true
+}
+
+func (x *PolicyIndex) putService(service *pms.Service) {
+	x.services[service.Name] = make(map[indexRef]bool)
+	for _, policy := range service.Policies {
+		x.put(indexRef{serviceName: service.Name, id: policy.ID}, policy)
+	}
+	for _, rolePolicy := range service.RolePolicies {
+		x.put(indexRef{serviceName: service.Name, rolePolicy: true, id: rolePolicy.ID}, rolePolicy)
+	}
+}
+
+func (x *PolicyIndex) removeService(serviceName string) {
+	for ref := range x.services[serviceName] {
+		x.remove(ref)
+	}
+	delete(x.services, serviceName)
+}
+
+func (x *PolicyIndex) put(ref indexRef, entity interface{}) {
+	x.entities[ref] = entity
+	if x.services[ref.serviceName] == nil {
+		x.services[ref.serviceName] = make(map[indexRef]bool)
+	}
+	x.services[ref.serviceName][ref] = true
+	terms, expressions := indexTerms(entity)
+	for _, term := range terms {
+		if x.terms[term] == nil {
+			x.terms[term] = make(map[indexRef]bool)
+		}
+		x.terms[term][ref] = true
+	}
+	for _, expression := range expressions {
+		indexed, ok := x.expressions[expression]
+		if !ok {
+			re, err := regexp.Compile(expression)
+			if err != nil {
+				log.Warningf("Invalid resource expression %q in %q of service %q: %v", expression, ref.id, ref.serviceName, err)
+			}
+			indexed = &indexedExpression{re: re, refs: make(map[indexRef]bool)}
+			x.expressions[expression] = indexed
+		}
+		indexed.refs[ref] = true
+	}
+}
+
+func (x *PolicyIndex) remove(ref indexRef) {
+	entity, ok := x.entities[ref]
+	if !ok {
+		return
+	}
+	delete(x.entities, ref)
+	delete(x.services[ref.serviceName], ref)
+	terms, expressions := indexTerms(entity)
+	for _, term := range terms {
+		delete(x.terms[term], ref)
+		if len(x.terms[term]) == 0 {
+			delete(x.terms, term)
+		}
+	}
+	for _, expression := range expressions {
+		if indexed, ok := x.expressions[expression]; ok {
+			delete(indexed.refs, ref)
+			if len(indexed.refs) == 0 {
+				delete(x.expressions, expression)
+			}
+		}
+	}
+}
+
+// indexTerms returns the index terms and the resource expressions of a policy or role policy
+func indexTerms(entity interface{}) (terms []string, expressions []string) {
+	switch e := entity.(type) {
+	case *pms.Policy:
+		for _, and := range e.Principals {
+			for _, principal := range and {
+				terms = append(terms, principalTerm+principal)
+			}
+		}
+		for _, permission := range e.Permissions {
+			if len(permission.Resource) > 0 {
+				terms = append(terms, resourceTerm+permission.Resource)
+			}
+			if len(permission.ResourceExpression) > 0 {
+				expressions = append(expressions, permission.ResourceExpression)
+			}
+			for _, action := range permission.Actions {
+				terms = append(terms, actionTerm+action)
+			}
+		}
+		for _, role := range filterValues(e, FilterRole) {
+			terms = append(terms, roleTerm+role)
+		}
+	case *pms.RolePolicy:
+		for _, principal := range e.Principals {
+			terms = append(terms, principalTerm+principal)
+		}
+		for _, resource := range e.Resources {
+			terms = append(terms, resourceTerm+resource)
+		}
+		expressions = e.ResourceExpressions
+		for _, role := range e.Roles {
+			terms = append(terms, roleTerm+role)
+		}
+	}
+	return terms, expressions
+}
+
+// Search returns the policies and role policies matching query, an empty query matches nothing
+func (x *PolicyIndex) Search(query SearchQuery) []*SearchResult {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	var sets []map[indexRef]bool
+	if len(query.Principal) > 0 {
+		sets = append(sets, x.terms[principalTerm+query.Principal])
+	}
+	if len(query.Role) > 0 {
+		sets = append(sets, x.terms[roleTerm+query.Role])
+	}
+	if len(query.Action) > 0 {
+		sets = append(sets, x.terms[actionTerm+query.Action])
+	}
+	if len(query.Resource) > 0 {
+		sets = append(sets, x.resourceRefs(query.Resource))
+	}
+	if len(sets) == 0 {
+		return []*SearchResult{}
+	}
+
+	// intersect from the smallest set
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+	results := make(map[string]*SearchResult)
+	for ref := range sets[0] {
+		matched := true
+		for _, set := range sets[1:] {
+			if !set[ref] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		result, ok := results[ref.serviceName]
+		if !ok {
+			result = &SearchResult{ServiceName: ref.serviceName}
+			results[ref.serviceName] = result
+		}
+		switch entity := x.entities[ref].(type) {
+		case *pms.Policy:
+			if len(query.Resource) > 0 && len(query.Action) > 0 && !permitted(entity, query.Resource, query.Action) {
+				continue
+			}
+			result.Policies = append(result.Policies, entity)
+		case *pms.RolePolicy:
+			result.RolePolicies = append(result.RolePolicies, entity)
+		}
+	}
+
+	ret := make([]*SearchResult, 0, len(results))
+	for _, result := range results {
+		if len(result.Policies) == 0 && len(result.RolePolicies) == 0 {
+			continue
+		}
+		sort.Slice(result.Policies, func(i, j int) bool { return result.Policies[i].ID < result.Policies[j].ID })
+		sort.Slice(result.RolePolicies, func(i, j int) bool { return result.RolePolicies[i].ID < result.RolePolicies[j].ID })
+		ret = append(ret, result)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ServiceName < ret[j].ServiceName })
+	return ret
+}
+
+// resourceRefs returns the policies and role policies of a resource, or of resource expressions matching it
+func (x *PolicyIndex) resourceRefs(resource string) map[indexRef]bool {
+	refs := make(map[indexRef]bool)
+	for ref := range x.terms[resourceTerm+resource] {
+		refs[ref] = true
+	}
+	for expression, indexed := range x.expressions {
+		if expression == resource || (indexed.re != nil && indexed.re.MatchString(resource)) {
+			for ref := range indexed.refs {
+				refs[ref] = true
+			}
+		}
+	}
+	return refs
+}
+
+// permitted tells whether a permission of the policy has both the resource and the action
+func permitted(policy *pms.Policy, resource string, action string) bool {
+	for _, permission := range policy.Permissions {
+		if permission.Resource != resource && permission.ResourceExpression != resource &&
+			!matchExpression(permission.ResourceExpression, resource) {
+			continue
+		}
+		for _, a := range permission.Actions {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchExpression(expression string, resource string) bool {
+	if len(expression) == 0 {
+		return false
+	}
+	matched, err := regexp.MatchString(expression, resource)
+	return err == nil && matched
+}
@@ -0,0 +1,113 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+)
+
+func testIndexPolicyStore() *pms.PolicyStore {
+	return &pms.PolicyStore{Services: []*pms.Service{
+		{
+			Name: "app1",
+			Policies: []*pms.Policy{
+				{ID: "p1", Effect: "grant", Principals: [][]string{{"group:finance"}},
+					Permissions: []*pms.Permission{{Resource: "/books", Actions: []string{"read", "write"}}}},
+				{ID: "p2", Effect: "deny", Principals: [][]string{{"user:bill", "role:auditor"}},
+					Permissions: []*pms.Permission{{ResourceExpression: "/reports/.*", Actions: []string{"delete"}}}},
+			},
+			RolePolicies: []*pms.RolePolicy{
+				{ID: "rp1", Effect: "grant", Roles: []string{"auditor"}, Principals: []string{"group:finance"},
+					Resources: []string{"/books"}},
+			},
+		},
+		{
+			Name: "app2",
+			Policies: []*pms.Policy{
+				{ID: "p3", Effect: "grant", Principals: [][]string{{"group:finance"}},
+					Permissions: []*pms.Permission{{Resource: "/reports/2019", Actions: []string{"read"}}}},
+			},
+		},
+	}}
+}
+
+// searchIDs returns the IDs of the found policies and role policies, prefixed by their service names
+func searchIDs(results []*SearchResult) []string {
+	ids := []string{}
+	for _, result := range results {
+		for _, policy := range result.Policies {
+			ids = append(ids, result.ServiceName+"/"+policy.ID)
+		}
+		for _, rolePolicy := range result.RolePolicies {
+			ids = append(ids, result.ServiceName+"/"+rolePolicy.ID)
+		}
+	}
+	return ids
+}
+
+func TestPolicyIndexSearch(t *testing.T) {
+	x := NewPolicyIndex(testIndexPolicyStore())
+	tests := []struct {
+		query SearchQuery
+		want  []string
+	}{
+		{SearchQuery{Principal: "group:finance"}, []string{"app1/p1", "app1/rp1", "app2/p3"}},
+		{SearchQuery{Principal: "group:none"}, []string{}},
+		{SearchQuery{Role: "auditor"}, []string{"app1/p2", "app1/rp1"}},
+		{SearchQuery{Resource: "/books"}, []string{"app1/p1", "app1/rp1"}},
+		{SearchQuery{Resource: "/reports/2019"}, []string{"app1/p2", "app2/p3"}},
+		{SearchQuery{Resource: "/reports/.*"}, []string{"app1/p2"}},
+		{SearchQuery{Action: "read"}, []string{"app1/p1", "app2/p3"}},
+		{SearchQuery{Resource: "/reports/2019", Action: "read"}, []string{"app2/p3"}},
+		{SearchQuery{Resource: "/books", Action: "delete"}, []string{}},
+		{SearchQuery{Principal: "group:finance", Resource: "/books"}, []string{"app1/p1", "app1/rp1"}},
+		{SearchQuery{}, []string{}},
+	}
+	for _, test := range tests {
+		if got := searchIDs(x.Search(test.query)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("search %+v: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestPolicyIndexApply(t *testing.T) {
+	x := NewPolicyIndex(testIndexPolicyStore())
+	query := SearchQuery{Principal: "group:finance"}
+	events := []struct {
+		event pms.StoreChangeEvent
+		want  []string
+	}{
+		{pms.StoreChangeEvent{Type: pms.POLICY_DELETE, Content: []pms.StoreUpdateData{{ServiceName: "app1", Data: &pms.Policy{ID: "p1"}}}},
+			[]string{"app1/rp1", "app2/p3"}},
+		{pms.StoreChangeEvent{Type: pms.POLICY_ADD, Content: []pms.StoreUpdateData{{ServiceName: "app2", Data: &pms.Policy{ID: "p3", Principals: [][]string{{"group:hr"}}}}}},
+			[]string{"app1/rp1"}},
+		{pms.StoreChangeEvent{Type: pms.ROLEPOLICY_ADD, Content: []pms.StoreUpdateData{{ServiceName: "app3", Data: &pms.RolePolicy{ID: "rp2", Principals: []string{"group:finance"}}}}},
+			[]string{"app1/rp1", "app3/rp2"}},
+		{pms.StoreChangeEvent{Type: pms.SERVICE_DELETE, Content: []string{"app1"}},
+			[]string{"app3/rp2"}},
+		{pms.StoreChangeEvent{Type: pms.SERVICE_ADD, Content: &pms.Service{Name: "app3"}},
+			[]string{}},
+		{pms.StoreChangeEvent{Type: pms.SYNC_RELOAD, Content: testIndexPolicyStore()},
+			[]string{"app1/p1", "app1/rp1", "app2/p3"}},
+		{pms.StoreChangeEvent{Type: pms.FUNCTION_DELETE, Content: []string{"f1"}},
+			[]string{"app1/p1", "app1/rp1", "app2/p3"}},
+	}
+	for i, e := range events {
+		if !x.Apply(e.event) {
+			t.Fatalf("event %d should be applied", i)
+		}
+		if got := searchIDs(x.Search(query)); !reflect.DeepEqual(got, e.want) {
+			t.Errorf("after event %d: got %v, want %v", i, got, e.want)
+		}
+	}
+	if x.Apply(pms.StoreChangeEvent{Type: pms.FULL_RELOAD}) {
+		t.Error("FULL_RELOAD should not be applied")
+	}
+	if len(x.terms) == 0 || len(x.expressions) != 1 {
+		t.Errorf("unexpected index %v, %v", x.terms, x.expressions)
+	}
+}
@@ -0,0 +1,295 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"encoding/base64"
+	"sort"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// listBatchSize is the number of entities read at a time, unless a page without filter is read
+const listBatchSize = 500
+
+// ListOptions selects a page of entities to list
+type ListOptions struct {
+	// Filter is a filter expression, see Filter
+	Filter string
+	// Limit is the max number of entities in the page, zero means no limit
+	Limit int
+	// Continue is the token returned with the previous page, empty for the first page
+	Continue string
+}
+
+// PolicyPager is implemented by the policy stores which can read policies and role policies in ID order without
+// reading all of them. Policies of stores not implementing it are paged in memory.
+type PolicyPager interface {
+	// PolicyPage returns up to limit policies of a service whose IDs are greater than afterID, ordered by ID
+	PolicyPage(serviceName string, afterID string, limit int) ([]*pms.Policy, error)
+	// RolePolicyPage returns up to limit role policies of a service whose IDs are greater than afterID, ordered by ID
+	RolePolicyPage(serviceName string, afterID string, limit int) ([]*pms.RolePolicy, error)
+}
+
+// FilteredPolicyPager is implemented by the policy pagers which can apply filters while reading the pages. A store
+// may apply only the part of the filter it supports, it must return all the entities matching the filter, and the
+// entities not matching it are skipped later.
+type FilteredPolicyPager interface {
+	// FilteredPolicyPage returns up to limit policies of a service matching filter whose IDs are greater than
+	// afterID, ordered by ID
+	FilteredPolicyPage(serviceName string, afterID string, filter *Filter, limit int) ([]*pms.Policy, error)
+	// FilteredRolePolicyPage returns up to limit role policies of a service matching filter whose IDs are greater
+	// than afterID, ordered by ID
+	FilteredRolePolicyPage(serviceName string, afterID string, filter *Filter, limit int) ([]*pms.RolePolicy, error)
+}
+
+// ListPolicies lists a page of the policies of a service ordered by ID, the returned token is empty if it's the
+// last page
+func ListPolicies(ps pms.PolicyStoreManager, serviceName string, opts ListOptions) ([]*pms.Policy, string, error) {
+	read := func(afterID string, filter *Filter, limit int) ([]interface{}, error) {
+		var policies []*pms.Policy
+		var err error
+		if pager, ok := ps.(FilteredPolicyPager); ok {
+			policies, err = pager.FilteredPolicyPage(serviceName, afterID, filter, limit)
+		} else {
+			policies, err = ps.(PolicyPager).PolicyPage(serviceName, afterID, limit)
+		}
+		entities := make([]interface{}, 0, len(policies))
+		for _, policy := range policies {
+			entities = append(entities, policy)
+		}
+		return entities, err
+	}
+	if _, ok := ps.(PolicyPager); !ok {
+		policies, err := ps.ListAllPolicies(serviceName, "")
+		if err != nil {
+			return nil, "", err
+		}
+		entities := make([]interface{}, 0, len(policies))
+		for _, policy := range policies {
+			entities = append(entities, policy)
+		}
+		read = readSorted(entities, policyID)
+	}
+	entities, token, err := listPage(opts, read, policyID)
+	policies := make([]*pms.Policy, 0, len(entities))
+	for _, entity := range entities {
+		policies = append(policies, entity.(*pms.Policy))
+	}
+	return policies, token, err
+}
+
+// ListRolePolicies lists a page of the role policies of a service ordered by ID, the returned token is empty if
+// it's the last page
+func ListRolePolicies(ps pms.PolicyStoreManager, serviceName string, opts ListOptions) ([]*pms.RolePolicy, string, error) {
+	read := func(afterID string, filter *Filter, limit int) ([]interface{}, error) {
+		var rolePolicies []*pms.RolePolicy
+		var err error
+		if pager, ok := ps.(FilteredPolicyPager); ok {
+			rolePolicies, err = pager.FilteredRolePolicyPage(serviceName, afterID, filter, limit)
+		} else {
+			rolePolicies, err = ps.(PolicyPager).RolePolicyPage(serviceName, afterID, limit)
+		}
+		entities := make([]interface{}, 0, len(rolePolicies))
+		for _, rolePolicy := range rolePolicies {
+			entities = append(entities, rolePolicy)
+		}
+		return entities, err
+	}
+	if _, ok := ps.(PolicyPager); !ok {
+		rolePolicies, err := ps.ListAllRolePolicies(serviceName, "")
+		if err != nil {
+			return nil, "", err
+		}
+		entities := make([]interface{}, 0, len(rolePolicies))
+		for _, rolePolicy := range rolePolicies {
+			entities = append(entities, rolePolicy)
+		}
+		read = readSorted(entities, rolePolicyID)
+	}
+	entities, token, err := listPage(opts, read, rolePolicyID)
+	rolePolicies := make([]*pms.RolePolicy, 0, len(entities))
+	for _, entity := range entities {
+		rolePolicies = append(rolePolicies, entity.(*pms.RolePolicy))
+	}
+	return rolePolicies, token, err
+}
+
+// ServicePager is implemented by the policy stores which can read services in name order without reading all the
+// services and their policies. Services of stores not implementing it are paged in memory.
+type ServicePager interface {
+	// ServicePage returns up to limit services whose names are greater than afterName, ordered by name, without
+	// their policies and role policies
+	ServicePage(afterName string, limit int) ([]*pms.Service, error)
+}
+
+// FilteredServicePager is implemented by the service pagers which can apply filters while reading the pages, like
+// FilteredPolicyPager
+type FilteredServicePager interface {
+	// FilteredServicePage returns up to limit services matching filter whose names are greater than afterName,
+	// ordered by name, without their policies and role policies
+	FilteredServicePage(afterName string, filter *Filter, limit int) ([]*pms.Service, error)
+}
+
+// ListServices lists a page of services ordered by name, the returned token is empty if it's the last page
+func ListServices(ps pms.PolicyStoreManager, opts ListOptions) ([]*pms.Service, string, error) {
+	read := func(afterName string, filter *Filter, limit int) ([]interface{}, error) {
+		var services []*pms.Service
+		var err error
+		if pager, ok := ps.(FilteredServicePager); ok {
+			services, err = pager.FilteredServicePage(afterName, filter, limit)
+		} else {
+			services, err = ps.(ServicePager).ServicePage(afterName, limit)
+		}
+		entities := make([]interface{}, 0, len(services))
+		for _, service := range services {
+			entities = append(entities, service)
+		}
+		return entities, err
+	}
+	_, paged := ps.(ServicePager)
+	if !paged {
+		services, err := ps.ListAllServices()
+		if err != nil {
+			return nil, "", err
+		}
+		entities := make([]interface{}, 0, len(services))
+		for _, service := range services {
+			entities = append(entities, service)
+		}
+		read = readSorted(entities, serviceName)
+	}
+	entities, token, err := listPage(opts, read, serviceName)
+	if err != nil {
+		return nil, "", err
+	}
+	services := make([]*pms.Service, 0, len(entities))
+	for _, entity := range entities {
+		service := entity.(*pms.Service)
+		if paged {
+			//policies are only read for the services in the page, a service deleted meanwhile is skipped
+			if service, err = ps.GetService(service.Name); errors.Code(err) == errors.EntityNotFound {
+				continue
+			} else if err != nil {
+				return nil, "", err
+			}
+		}
+		services = append(services, service)
+	}
+	return services, token, nil
+}
+
+// ListFunctions lists a page of functions ordered by name, the returned token is empty if it's the last page
+func ListFunctions(ps pms.PolicyStoreManager, opts ListOptions) ([]*pms.Function, string, error) {
+	functions, err := ps.ListAllFunctions("")
+	if err != nil {
+		return nil, "", err
+	}
+	entities := make([]interface{}, 0, len(functions))
+	for _, function := range functions {
+		entities = append(entities, function)
+	}
+	entities, token, err := listPage(opts, readSorted(entities, functionName), functionName)
+	functions = make([]*pms.Function, 0, len(entities))
+	for _, entity := range entities {
+		functions = append(functions, entity.(*pms.Function))
+	}
+	return functions, token, err
+}
+
+// ListTenants lists a page of tenants ordered by name, the returned token is empty if it's the last page
+func ListTenants(tm pms.TenantManager, opts ListOptions) ([]*pms.Tenant, string, error) {
+	tenants, err := tm.ListAllTenants()
+	if err != nil {
+		return nil, "", err
+	}
+	entities := make([]interface{}, 0, len(tenants))
+	for _, tenant := range tenants {
+		entities = append(entities, tenant)
+	}
+	entities, token, err := listPage(opts, readSorted(entities, tenantName), tenantName)
+	tenants = make([]*pms.Tenant, 0, len(entities))
+	for _, entity := range entities {
+		tenants = append(tenants, entity.(*pms.Tenant))
+	}
+	return tenants, token, err
+}
+
+func policyID(entity interface{}) string     { return entity.(*pms.Policy).ID }
+func rolePolicyID(entity interface{}) string { return entity.(*pms.RolePolicy).ID }
+func serviceName(entity interface{}) string  { return entity.(*pms.Service).Name }
+func functionName(entity interface{}) string { return entity.(*pms.Function).Name }
+func tenantName(entity interface{}) string   { return entity.(*pms.Tenant).Name }
+
+// readSorted sorts the entities by key in memory, and returns the function reading them
+func readSorted(entities []interface{}, key func(interface{}) string) func(string, *Filter, int) ([]interface{}, error) {
+	sort.Slice(entities, func(i, j int) bool { return key(entities[i]) < key(entities[j]) })
+	return func(after string, filter *Filter, limit int) ([]interface{}, error) {
+		start := sort.Search(len(entities), func(i int) bool { return key(entities[i]) > after })
+		end := len(entities)
+		if limit > 0 && start+limit < end {
+			end = start + limit
+		}
+		return entities[start:end], nil
+	}
+}
+
+// listPage reads the entities after the continue token batch by batch, until a page of entities matching the filter
+// is found. read returns up to limit entities whose keys are greater than after, ordered by key, it may skip the
+// entities not matching filter.
+func listPage(opts ListOptions, read func(after string, filter *Filter, limit int) ([]interface{}, error), key func(interface{}) string) ([]interface{}, string, error) {
+	if opts.Limit < 0 {
+		return nil, "", errors.Errorf(errors.InvalidRequest, "invalid limit %d", opts.Limit)
+	}
+	filter, err := ParseFilter(opts.Filter)
+	if err != nil {
+		return nil, "", err
+	}
+	after, err := decodeContinue(opts.Continue)
+	if err != nil {
+		return nil, "", err
+	}
+
+	//one more entity is read to know whether there is next page
+	batchSize := listBatchSize
+	if opts.Limit > 0 {
+		batchSize = opts.Limit + 1
+		if filter != nil && batchSize < listBatchSize {
+			batchSize = listBatchSize
+		}
+	}
+	page := []interface{}{}
+	for {
+		batch, err := read(after, filter, batchSize)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, entity := range batch {
+			if !filter.Match(entity) {
+				continue
+			}
+			if opts.Limit > 0 && len(page) == opts.Limit {
+				return page, encodeContinue(key(page[len(page)-1])), nil
+			}
+			page = append(page, entity)
+		}
+		if len(batch) < batchSize {
+			return page, "", nil
+		}
+		after = key(batch[len(batch)-1])
+	}
+}
+
+func encodeContinue(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeContinue(token string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", errors.Wrapf(err, errors.InvalidRequest, "invalid continue token %q", token)
+	}
+	return string(key), nil
+}
@@ -0,0 +1,98 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	_ "github.com/oracle/speedle/pkg/store/file"
+)
+
+func TestListPages(t *testing.T) {
+	defer os.Remove("list_test.json")
+	ps, err := store.NewStore("file", map[string]interface{}{"FileLocation": "./list_test.json"})
+	if err != nil {
+		t.Fatal("fail to new file store:", err)
+	}
+	service := &pms.Service{Name: "s1", Type: pms.TypeApplication}
+	for i := 0; i < 25; i++ {
+		effect := pms.Grant
+		if i%2 == 1 {
+			effect = pms.Deny
+		}
+		service.Policies = append(service.Policies, &pms.Policy{Name: fmt.Sprintf("policy%d", i), Effect: effect})
+	}
+	if err := ps.CreateService(service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	all, err := ps.ListAllPolicies("s1", "")
+	if err != nil {
+		t.Fatal("fail to list policies:", err)
+	}
+	var sortedIDs []string
+	policy3 := ""
+	for _, policy := range all {
+		sortedIDs = append(sortedIDs, policy.ID)
+		if policy.Name == "policy3" {
+			policy3 = policy.ID
+		}
+	}
+	sort.Strings(sortedIDs)
+
+	list := func(opts store.ListOptions) []string {
+		var ids []string
+		for {
+			policies, token, err := store.ListPolicies(ps, "s1", opts)
+			if err != nil {
+				t.Fatal("fail to list policies:", err)
+			}
+			if opts.Limit > 0 && len(policies) > opts.Limit {
+				t.Fatalf("%d policies are returned, more than limit %d", len(policies), opts.Limit)
+			}
+			for _, policy := range policies {
+				ids = append(ids, policy.ID)
+			}
+			if len(token) == 0 {
+				return ids
+			}
+			opts.Continue = token
+		}
+	}
+	if ids := list(store.ListOptions{}); !reflect.DeepEqual(ids, sortedIDs) {
+		t.Errorf("all policies should be listed in ID order, got %v", ids)
+	}
+	if ids := list(store.ListOptions{Limit: 10}); !reflect.DeepEqual(ids, sortedIDs) {
+		t.Errorf("all policies should be listed in 3 pages, got %v", ids)
+	}
+	if ids := list(store.ListOptions{Limit: 5, Filter: "effect eq deny"}); len(ids) != 12 {
+		t.Errorf("12 denied policies should be listed, got %v", ids)
+	}
+	if ids := list(store.ListOptions{Limit: 5, Filter: "name eq policy3"}); len(ids) != 1 || ids[0] != policy3 {
+		t.Errorf("policy %s should be listed, got %v", policy3, ids)
+	}
+
+	//the last page has no token
+	policies, token, err := store.ListPolicies(ps, "s1", store.ListOptions{Limit: 25})
+	if err != nil || len(policies) != 25 || len(token) != 0 {
+		t.Errorf("one page should be listed without token, got %d policies, token %q, error %v", len(policies), token, err)
+	}
+
+	for _, opts := range []store.ListOptions{{Limit: -1}, {Continue: "!"}, {Filter: "name eq"}} {
+		if _, _, err := store.ListPolicies(ps, "s1", opts); errors.Code(err) != errors.InvalidRequest {
+			t.Errorf("options %v should be invalid, got error %v", opts, err)
+		}
+	}
+
+	services, token, err := store.ListServices(ps, store.ListOptions{Limit: 1, Filter: "name eq s1"})
+	if err != nil || len(services) != 1 || len(token) != 0 {
+		t.Errorf("service s1 should be listed, got %v, token %q, error %v", services, token, err)
+	}
+}
@@ -0,0 +1,108 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// dialect hides the differences between the supported SQL databases. Queries are written with '?' placeholders,
+// and rebound to the placeholders of the database.
+type dialect struct {
+	name string
+	// numbered placeholders like $1, $2 are used instead of '?'
+	numberedPlaceholders bool
+	// autoIncrementKey is the column definition of an auto increment primary key
+	autoIncrementKey string
+	// writeLock is executed at the beginning of every write transaction. Writes are serialized, so revisions
+	// are committed in order, and the watchers polling the change log never miss a revision.
+	writeLock string
+	// readIsolation is the isolation level of read transactions, which must see a consistent snapshot
+	readIsolation sql.IsolationLevel
+	// contains is the function returning the position of a substring, 0 if not found
+	contains string
+	// collation is appended to name comparisons and orderings, so names are compared byte by byte
+	collation string
+	// jsonValue is the text value at a path of the JSON column %s, the path formatted by jsonPath is bound to '?'
+	jsonValue string
+	jsonPath  func(keys []string) (string, bool)
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {
+		name:                 "postgres",
+		numberedPlaceholders: true,
+		autoIncrementKey:     "BIGSERIAL PRIMARY KEY",
+		writeLock:            "SELECT pg_advisory_xact_lock(7370656564)",
+		readIsolation:        sql.LevelRepeatableRead,
+		contains:             "strpos",
+		collation:            ` COLLATE "C"`,
+		jsonValue:            `(CAST(%s AS jsonb) #>> CAST(? AS text[]))`,
+		jsonPath:             postgresJSONPath,
+	},
+	"sqlite3": {
+		name:             "sqlite3",
+		autoIncrementKey: "INTEGER PRIMARY KEY AUTOINCREMENT",
+		readIsolation:    sql.LevelDefault,
+		contains:         "instr",
+		jsonValue:        `json_extract(%s, ?)`,
+		jsonPath:         sqliteJSONPath,
+	},
+}
+
+func getDialect(driver string) (*dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		var names []string
+		for name := range dialects {
+			names = append(names, name)
+		}
+		return nil, errors.Errorf(errors.ConfigError, "unsupported SQL driver %q, supported drivers are %v", driver, names)
+	}
+	return d, nil
+}
+
+// postgresJSONPath formats the keys as a text array, e.g. {"metadata","owner"}
+func postgresJSONPath(keys []string) (string, bool) {
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, `"`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key)+`"`)
+	}
+	return "{" + strings.Join(quoted, ",") + "}", true
+}
+
+// sqliteJSONPath formats the keys as a JSON path, e.g. $."metadata"."owner". Keys with '"' can't be quoted in a path.
+func sqliteJSONPath(keys []string) (string, bool) {
+	path := "$"
+	for _, key := range keys {
+		if strings.Contains(key, `"`) {
+			return "", false
+		}
+		path += `."` + key + `"`
+	}
+	return path, true
+}
+
+// rebind replaces the '?' placeholders in query with the placeholders of the database
+func (d *dialect) rebind(query string) string {
+	if !d.numberedPlaceholders {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+const DiscoverRequestsTable = "speedle_discover_requests"
+
+// discoverRevision returns the revision of the latest discover request of the tenant
+func (s *Store) discoverRevision(q queryer) (int64, error) {
+	return s.queryInt(q, `SELECT MAX(revision) FROM `+DiscoverRequestsTable+` WHERE tenant = ?`, s.tenant)
+}
+
+func (s *Store) SaveDiscoverRequest(request *ads.RequestContext) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal request")
+	}
+	err = s.update(func(tx *sql.Tx) error {
+		_, err := s.exec(tx, `INSERT INTO `+DiscoverRequestsTable+` (tenant, service, body) VALUES (?, ?, ?)`,
+			s.tenant, request.ServiceName, string(body))
+		if err != nil {
+			return err
+		}
+		count, err := s.queryInt(tx, `SELECT COUNT(*) FROM `+DiscoverRequestsTable+` WHERE tenant = ?`, s.tenant)
+		if err != nil {
+			return err
+		}
+		if count >= store.MaxDiscoverRequestNum { //reach Max number of requests, remove the oldest ones.
+			_, err = s.exec(tx, `DELETE FROM `+DiscoverRequestsTable+` WHERE revision IN (SELECT revision FROM `+
+				DiscoverRequestsTable+` WHERE tenant = ? ORDER BY revision LIMIT ?)`, s.tenant, store.DeleteNumWhenReachMaxDiscoverRequest)
+		}
+		return err
+	})
+	return wrapStoreError(err, "unable to save discover request")
+}
+
+// readRequests reads the discover requests of service serviceName, or of all services when serviceName is empty,
+// whose revisions are greater than revision
+func (s *Store) readRequests(q queryer, serviceName string, revision int64, last bool) ([]*ads.RequestContext, error) {
+	query := `SELECT body FROM ` + DiscoverRequestsTable + ` WHERE tenant = ? AND revision > ?`
+	args := []interface{}{s.tenant, revision}
+	if len(serviceName) > 0 {
+		query += ` AND service = ?`
+		args = append(args, serviceName)
+	}
+	if last {
+		query += ` ORDER BY revision DESC LIMIT 1`
+	} else {
+		query += ` ORDER BY revision`
+	}
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	requests := []*ads.RequestContext{}
+	for rows.Next() {
+		var body []byte
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		var request ads.RequestContext
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal request context %q for service %q", body, serviceName)
+		}
+		requests = append(requests, &request)
+	}
+	return requests, rows.Err()
+}
+
+func (s *Store) GetLastDiscoverRequest(serviceName string) (*ads.RequestContext, int64, error) {
+	var request *ads.RequestContext
+	var revision int64
+	err := s.view(func(tx *sql.Tx) error {
+		requests, err := s.readRequests(tx, serviceName, 0, true)
+		if err != nil {
+			return err
+		}
+		if len(requests) == 0 {
+			return errors.Errorf(errors.EntityNotFound, "no request found for service %q", serviceName)
+		}
+		request = requests[0]
+		revision, err = s.discoverRevision(tx)
+		return err
+	})
+	if err != nil {
+		return nil, -1, wrapStoreError(err, "unable to get the last discover request for service %q", serviceName)
+	}
+	return request, revision, nil
+}
+
+func (s *Store) GetDiscoverRequestsSinceRevision(serviceName string, revision int64) ([]*ads.RequestContext, int64, error) {
+	var requests []*ads.RequestContext
+	currentRevision := revision
+	err := s.view(func(tx *sql.Tx) error {
+		var err error
+		if requests, err = s.readRequests(tx, serviceName, revision, false); err != nil {
+			return err
+		}
+		if currentRevision, err = s.discoverRevision(tx); err != nil {
+			return err
+		}
+		if currentRevision < revision {
+			currentRevision = revision
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, -1, wrapStoreError(err, "unable to get discover request for service %q with revision %d", serviceName, revision)
+	}
+	return requests, currentRevision, nil
+}
+
+func (s *Store) GetDiscoverRequests(serviceName string) ([]*ads.RequestContext, int64, error) {
+	return s.GetDiscoverRequestsSinceRevision(serviceName, 0)
+}
+
+func (s *Store) ResetDiscoverRequests(serviceName string) error {
+	err := s.update(func(tx *sql.Tx) error {
+		query := `DELETE FROM ` + DiscoverRequestsTable + ` WHERE tenant = ?`
+		args := []interface{}{s.tenant}
+		if len(serviceName) > 0 {
+			query += ` AND service = ?`
+			args = append(args, serviceName)
+		}
+		_, err := s.exec(tx, query, args...)
+		return err
+	})
+	return wrapStoreError(err, "unable to reset discover requests from service %q", serviceName)
+}
+
+// This method is implemented as common method at evaluator part
+func (s *Store) GeneratePolicies(serviceName, principalType, principalName, principalIDD string) (map[string]*pms.Service, int64, error) {
+	requests, revision, err := s.GetDiscoverRequests(serviceName)
+	if err != nil {
+		return nil, -1, err
+	}
+	serviceMap, err := store.GeneratePoliciesFromDiscoverRequests(requests, principalType, principalName, principalIDD)
+	if err != nil {
+		return nil, -1, err
+	}
+	return serviceMap, revision, nil
+}
@@ -0,0 +1,109 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oracle/speedle/pkg/store"
+)
+
+// filterCondition translates the comparisons of filter on name, effect and metadata into a SQL condition on the rows
+// of table. The condition holds for every row matching filter, and the rows selected are matched against filter
+// again, so the comparisons which can't be translated, and the negations, are left out of the condition.
+func (s *Store) filterCondition(table string, filter *store.Filter) (string, []interface{}) {
+	cond, args, ok := s.translateFilter(table, filter)
+	if !ok {
+		return "", nil
+	}
+	return ` AND ` + cond, args
+}
+
+func (s *Store) translateFilter(table string, f *store.Filter) (string, []interface{}, bool) {
+	if f == nil {
+		return "", nil, false
+	}
+	switch logical := f.Logical(); logical {
+	case "and", "or":
+		var conds []string
+		var args []interface{}
+		for _, operand := range f.Operands() {
+			cond, condArgs, ok := s.translateFilter(table, operand)
+			if !ok {
+				if logical == "or" {
+					return "", nil, false
+				}
+				//the rows are still limited by the other operands
+				continue
+			}
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+		if len(conds) == 0 {
+			return "", nil, false
+		}
+		return `(` + strings.Join(conds, ` `+strings.ToUpper(logical)+` `) + `)`, args, true
+	case "not":
+		return "", nil, false
+	}
+
+	attribute, operator, value := f.Comparison()
+	column, columnArgs, ok := s.filterColumn(table, attribute)
+	if !ok {
+		return "", nil, false
+	}
+	args := append([]interface{}{}, columnArgs...)
+	switch operator {
+	case "eq":
+		return column + ` = ?`, append(args, value), true
+	case "ne":
+		//an attribute which is not set is not equal to any value
+		return `(` + column + ` IS NULL OR ` + column + ` <> ?)`, append(append(args, columnArgs...), value), true
+	case "co":
+		return s.dialect.contains + `(` + column + `, ?) > 0`, append(args, value), true
+	case "sw":
+		return `substr(` + column + `, 1, ?) = ?`, append(args, utf8.RuneCountInString(value), value), true
+	case "pr":
+		return column + ` <> ''`, args, true
+	case "gt":
+		return column + s.dialect.collation + ` > ?`, append(args, value), true
+	case "ge":
+		return column + s.dialect.collation + ` >= ?`, append(args, value), true
+	case "lt":
+		return column + s.dialect.collation + ` < ?`, append(args, value), true
+	case "le":
+		return column + s.dialect.collation + ` <= ?`, append(args, value), true
+	}
+	return "", nil, false
+}
+
+// filterColumn returns the SQL expression of a filter attribute of the rows of table, and its arguments
+func (s *Store) filterColumn(table string, attribute string) (string, []interface{}, bool) {
+	var column string
+	var keys []string
+	switch {
+	case attribute == store.FilterName:
+		return `name`, nil, true
+	case attribute == store.FilterEffect && table != ServicesTable:
+		column, keys = `body`, []string{"effect"}
+	case strings.HasPrefix(attribute, store.FilterMetadataPrefix) && table == ServicesTable:
+		column, keys = `metadata`, []string{strings.TrimPrefix(attribute, store.FilterMetadataPrefix)}
+	case strings.HasPrefix(attribute, store.FilterMetadataPrefix):
+		column, keys = `body`, []string{"metadata", strings.TrimPrefix(attribute, store.FilterMetadataPrefix)}
+	default:
+		return "", nil, false
+	}
+	path, ok := s.dialect.jsonPath(keys)
+	if !ok {
+		return "", nil, false
+	}
+	value := fmt.Sprintf(s.dialect.jsonValue, column)
+	if attribute == store.FilterEffect {
+		//effect is omitted from the body when it's empty
+		value = `COALESCE(` + value + `, '')`
+	}
+	return value, []interface{}{path}, true
+}
@@ -0,0 +1,140 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrationsTable records the schema versions applied to the database
+const MigrationsTable = "speedle_schema_migrations"
+
+// migration upgrades the schema to version. {{autoIncrementKey}} in statements is replaced by the dialect.
+type migration struct {
+	version    int
+	statements []string
+}
+
+// migrations are applied in order, and must never be changed once released. Add a new migration instead.
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE speedle_tenants (
+				name VARCHAR(255) NOT NULL PRIMARY KEY,
+				body TEXT NOT NULL
+			)`,
+			`CREATE TABLE speedle_services (
+				tenant VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				type VARCHAR(64) NOT NULL,
+				metadata TEXT NOT NULL,
+				PRIMARY KEY (tenant, name)
+			)`,
+			`CREATE TABLE speedle_policies (
+				tenant VARCHAR(255) NOT NULL,
+				service VARCHAR(255) NOT NULL,
+				id VARCHAR(64) NOT NULL,
+				name TEXT NOT NULL,
+				body TEXT NOT NULL,
+				PRIMARY KEY (tenant, service, id)
+			)`,
+			`CREATE INDEX speedle_policies_name ON speedle_policies (tenant, service, name)`,
+			`CREATE TABLE speedle_role_policies (
+				tenant VARCHAR(255) NOT NULL,
+				service VARCHAR(255) NOT NULL,
+				id VARCHAR(64) NOT NULL,
+				name TEXT NOT NULL,
+				body TEXT NOT NULL,
+				PRIMARY KEY (tenant, service, id)
+			)`,
+			`CREATE INDEX speedle_role_policies_name ON speedle_role_policies (tenant, service, name)`,
+			`CREATE TABLE speedle_functions (
+				tenant VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				body TEXT NOT NULL,
+				PRIMARY KEY (tenant, name)
+			)`,
+			`CREATE TABLE speedle_changes (
+				revision {{autoIncrementKey}},
+				tenant VARCHAR(255) NOT NULL,
+				type INTEGER NOT NULL,
+				service VARCHAR(255) NOT NULL,
+				body TEXT NOT NULL,
+				created_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX speedle_changes_tenant ON speedle_changes (tenant, revision)`,
+			`CREATE INDEX speedle_changes_created_at ON speedle_changes (created_at)`,
+			`CREATE TABLE speedle_change_watermarks (
+				tenant VARCHAR(255) NOT NULL PRIMARY KEY,
+				pruned_revision BIGINT NOT NULL
+			)`,
+			`CREATE TABLE speedle_discover_requests (
+				revision {{autoIncrementKey}},
+				tenant VARCHAR(255) NOT NULL,
+				service VARCHAR(255) NOT NULL,
+				body TEXT NOT NULL
+			)`,
+			`CREATE INDEX speedle_discover_requests_service ON speedle_discover_requests (tenant, service, revision)`,
+		},
+	},
+}
+
+// migrate brings the schema of db up to date. The migrations are applied in write transactions, so concurrent
+// servers starting against the same database apply each migration once.
+func migrate(db *sql.DB, d *dialect) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + MigrationsTable + ` (
+		version INTEGER NOT NULL PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, errors.StoreError, "unable to create schema migrations table")
+	}
+
+	for _, m := range migrations {
+		if err := applyMigration(db, d, m); err != nil {
+			return errors.Wrapf(err, errors.StoreError, "unable to migrate schema to version %d", m.version)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, d *dialect, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(d.writeLock) > 0 {
+		if _, err := tx.Exec(d.writeLock); err != nil {
+			return err
+		}
+	}
+	var count int
+	if err := tx.QueryRow(d.rebind(`SELECT COUNT(*) FROM `+MigrationsTable+` WHERE version = ?`), m.version).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	for _, statement := range m.statements {
+		if _, err := tx.Exec(strings.Replace(statement, "{{autoIncrementKey}}", d.autoIncrementKey, -1)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(d.rebind(`INSERT INTO `+MigrationsTable+` (version, applied_at) VALUES (?, ?)`), m.version, time.Now().Unix()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Infof("SQL store schema is migrated to version %d", m.version)
+	return nil
+}
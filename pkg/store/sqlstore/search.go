@@ -0,0 +1,71 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"sync"
+
+	"github.com/oracle/speedle/pkg/store"
+)
+
+// searchIndex is the search index of a store and the revision of the change log it's up to date with
+type searchIndex struct {
+	sync.Mutex
+	index    *store.PolicyIndex
+	revision int64
+}
+
+// SearchPolicies searches the index caught up with the change log, so the changes made by other servers sharing
+// the database are found too.
+func (s *Store) SearchPolicies(query store.SearchQuery) ([]*store.SearchResult, error) {
+	s.search.Lock()
+	defer s.search.Unlock()
+	if err := s.catchUpSearchIndex(); err != nil {
+		return nil, wrapStoreError(err, "unable to update the search index")
+	}
+	return s.search.index.Search(query), nil
+}
+
+// catchUpSearchIndex applies the changes logged since the last search to the index. The index is built again if
+// some changes are pruned before being applied, or can't be applied.
+func (s *Store) catchUpSearchIndex() error {
+	for {
+		if s.search.index == nil {
+			//the revision is read before the policies, changes made in between are applied again, which is harmless
+			revision, err := s.currentRevision(s.db)
+			if err != nil {
+				return err
+			}
+			ps, err := s.ReadPolicyStore()
+			if err != nil {
+				return err
+			}
+			s.search.index = store.NewPolicyIndex(ps)
+			s.search.revision = revision
+		}
+
+		events, err := s.readChanges(s.search.revision)
+		if err != nil {
+			return err
+		}
+		pruned, err := s.prunedRevision(s.db)
+		if err != nil {
+			return err
+		}
+		if pruned > s.search.revision {
+			s.search.index = nil
+			continue
+		}
+		for _, event := range events {
+			if !s.search.index.Apply(event) {
+				s.search.index = nil
+				break
+			}
+			s.search.revision = event.ID
+		}
+		if s.search.index != nil && len(events) < changesPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,1115 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	"github.com/oracle/speedle/pkg/suid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ServicesTable        = "speedle_services"
+	PoliciesTable        = "speedle_policies"
+	RolePoliciesTable    = "speedle_role_policies"
+	FunctionsTable       = "speedle_functions"
+	ChangesTable         = "speedle_changes"
+	ChangeWatermarkTable = "speedle_change_watermarks"
+	TenantsTable         = "speedle_tenants"
+
+	// DefaultTenant is the value of the tenant column of the default tenant
+	DefaultTenant = ""
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Store keeps policies in a SQL database, one row per service, policy, role policy and function, so each change
+// only writes the rows it touches. All the tables have a tenant column, the default tenant is the empty string.
+//
+// Every change is also appended to the change log table in the same transaction. Watchers poll the change log,
+// and get policies, role policies and functions one by one instead of reloading everything. The change log is
+// pruned after the retention, watchers falling behind the pruned revision reload all policies.
+type Store struct {
+	db      *sql.DB
+	dialect *dialect
+	tenant  string
+	// owner closes the database, the stores of tenants share the database of the default store
+	owner bool
+
+	pollInterval time.Duration
+	retention    time.Duration
+	pruneLock    sync.Mutex
+	lastPrune    time.Time
+
+	watchLock sync.Mutex
+	watcher   *watcher
+
+	search searchIndex
+}
+
+func openStore(driver, dataSource string, pollInterval, retention time.Duration) (*Store, error) {
+	d, err := getDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "unable to open %s database", driver)
+	}
+	if d.name == "sqlite3" {
+		//sqlite allows only one writer, and a connection doesn't see the uncommitted changes of the others
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, errors.StoreError, "unable to connect to %s database", driver)
+	}
+	if err := migrate(db, d); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Infof("%s database is opened", driver)
+	return &Store{
+		db:           db,
+		dialect:      d,
+		tenant:       DefaultTenant,
+		owner:        true,
+		pollInterval: pollInterval,
+		retention:    retention,
+	}, nil
+}
+
+// Close stops watching and closes the database
+func (s *Store) Close() error {
+	s.StopWatch()
+	if !s.owner {
+		return nil
+	}
+	if err := s.db.Close(); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to close %s database", s.dialect.name)
+	}
+	return nil
+}
+
+func wrapStoreError(err error, format string, a ...interface{}) error {
+	if err == nil || errors.Code(err) != errors.UnknownError {
+		return err
+	}
+	return errors.Wrapf(err, errors.StoreError, format, a...)
+}
+
+// checkTenant returns EntityNotFound if the tenant of the store is deleted
+func (s *Store) checkTenant(q queryer) error {
+	if s.tenant == DefaultTenant {
+		return nil
+	}
+	var count int
+	if err := q.QueryRow(s.dialect.rebind(`SELECT COUNT(*) FROM `+TenantsTable+` WHERE name = ?`), s.tenant).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.Errorf(errors.EntityNotFound, "tenant %q is not found", s.tenant)
+	}
+	return nil
+}
+
+// view runs fn in a read transaction
+func (s *Store) view(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: s.dialect.readIsolation})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.checkTenant(tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// update runs fn in a write transaction. Write transactions are serialized, so the revisions of the change log
+// are committed in order.
+func (s *Store) update(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if len(s.dialect.writeLock) > 0 {
+		if _, err := tx.Exec(s.dialect.writeLock); err != nil {
+			return err
+		}
+	}
+	if err := s.checkTenant(tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Health returns EntityNotFound once the tenant of the store is deleted, or the error connecting to the database
+func (s *Store) Health() error {
+	if err := s.db.Ping(); err != nil {
+		return errors.Wrapf(err, errors.StoreError, "unable to connect to %s database", s.dialect.name)
+	}
+	return wrapStoreError(s.checkTenant(s.db), "unable to check tenant %q", s.tenant)
+}
+
+// exec runs a query with '?' placeholders
+func (s *Store) exec(q queryer, query string, args ...interface{}) (sql.Result, error) {
+	return q.Exec(s.dialect.rebind(query), args...)
+}
+
+func (s *Store) queryInt(q queryer, query string, args ...interface{}) (int64, error) {
+	var n sql.NullInt64
+	err := q.QueryRow(s.dialect.rebind(query), args...).Scan(&n)
+	return n.Int64, err
+}
+
+// queryStrings returns the first column of the rows of query
+func (s *Store) queryStrings(q queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// read policy store from database
+func (s *Store) ReadPolicyStore() (*pms.PolicyStore, error) {
+	var ps pms.PolicyStore
+	err := s.view(func(tx *sql.Tx) error {
+		services, err := s.listServices(tx, "", true)
+		if err != nil {
+			return err
+		}
+		ps.Services = services
+		ps.Functions, err = s.listFunctions(tx, "")
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to read policy store")
+	}
+	return &ps, nil
+}
+
+// write policy store to database, all existing services and functions are replaced
+func (s *Store) WritePolicyStore(ps *pms.PolicyStore) error {
+	for _, function := range ps.Functions {
+		if err := store.ValidateFunction(function); err != nil {
+			return err
+		}
+	}
+	err := s.update(func(tx *sql.Tx) error {
+		for _, table := range []string{ServicesTable, PoliciesTable, RolePoliciesTable, FunctionsTable} {
+			if _, err := s.exec(tx, `DELETE FROM `+table+` WHERE tenant = ?`, s.tenant); err != nil {
+				return err
+			}
+		}
+		for _, service := range ps.Services {
+			if err := s.putService(tx, service); err != nil {
+				return err
+			}
+		}
+		for _, function := range ps.Functions {
+			if err := s.putFunction(tx, function); err != nil {
+				return err
+			}
+		}
+		return s.logChange(tx, pms.FULL_RELOAD, "", nil)
+	})
+	return wrapStoreError(err, "unable to write policy store")
+}
+
+// get the storage type of the store
+func (s *Store) Type() string {
+	return StoreType
+}
+
+// serviceExists returns EntityNotFound if service serviceName doesn't exist
+func (s *Store) serviceExists(q queryer, serviceName string) error {
+	count, err := s.queryInt(q, `SELECT COUNT(*) FROM `+ServicesTable+` WHERE tenant = ? AND name = ?`, s.tenant, serviceName)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
+	}
+	return nil
+}
+
+// queryServices reads the services selected by query, which selects their name, type and metadata
+func (s *Store) queryServices(q queryer, query string, args ...interface{}) ([]*pms.Service, error) {
+	rows, err := q.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var services []*pms.Service
+	for rows.Next() {
+		var service pms.Service
+		var metadata string
+		if err := rows.Scan(&service.Name, &service.Type, &metadata); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(metadata), &service.Metadata); err != nil {
+			return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal metadata of service %q", service.Name)
+		}
+		services = append(services, &service)
+	}
+	return services, rows.Err()
+}
+
+// listServices lists service serviceName, or all the services when serviceName is empty
+func (s *Store) listServices(q queryer, serviceName string, withPolicies bool) ([]*pms.Service, error) {
+	query := `SELECT name, type, metadata FROM ` + ServicesTable + ` WHERE tenant = ?`
+	args := []interface{}{s.tenant}
+	if len(serviceName) > 0 {
+		query += ` AND name = ?`
+		args = append(args, serviceName)
+	}
+	services, err := s.queryServices(q, query+` ORDER BY name`+s.dialect.collation, args...)
+	if err != nil || !withPolicies || len(services) == 0 {
+		return services, err
+	}
+	serviceMap := make(map[string]*pms.Service)
+	for _, service := range services {
+		serviceMap[service.Name] = service
+	}
+
+	err = s.selectEntities(q, PoliciesTable, serviceName, "", nil, func(service string, body []byte) error {
+		var policy pms.Policy
+		if err := json.Unmarshal(body, &policy); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal policy %q", body)
+		}
+		if s, ok := serviceMap[service]; ok {
+			s.Policies = append(s.Policies, &policy)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.selectEntities(q, RolePoliciesTable, serviceName, "", nil, func(service string, body []byte) error {
+		var rolePolicy pms.RolePolicy
+		if err := json.Unmarshal(body, &rolePolicy); err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to unmarshal role policy %q", body)
+		}
+		if s, ok := serviceMap[service]; ok {
+			s.RolePolicies = append(s.RolePolicies, &rolePolicy)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// selectEntities calls fn with the body of each row of table in service serviceName, or in all services when
+// serviceName is empty, and matching condition cond, ordered by service and id
+func (s *Store) selectEntities(q queryer, table string, serviceName string, cond string, condArgs []interface{}, fn func(service string, body []byte) error) error {
+	query := `SELECT service, body FROM ` + table + ` WHERE tenant = ?`
+	args := []interface{}{s.tenant}
+	if len(serviceName) > 0 {
+		query += ` AND service = ?`
+		args = append(args, serviceName)
+	}
+	query += cond + ` ORDER BY service` + s.dialect.collation + `, id`
+	rows, err := q.Query(s.dialect.rebind(query), append(args, condArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var service string
+		var body []byte
+		if err := rows.Scan(&service, &body); err != nil {
+			return err
+		}
+		if err := fn(service, body); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// putService inserts the service together with its policies and role policies. IDs are generated for the
+// policies and role policies without one.
+func (s *Store) putService(tx *sql.Tx, service *pms.Service) error {
+	if len(service.Name) == 0 {
+		return errors.New(errors.InvalidRequest, "service name can not be empty")
+	}
+	count, err := s.queryInt(tx, `SELECT COUNT(*) FROM `+ServicesTable+` WHERE tenant = ? AND name = ?`, s.tenant, service.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.Errorf(errors.EntityAlreadyExists, "service %q already exists", service.Name)
+	}
+	metadata, err := json.Marshal(service.Metadata)
+	if err != nil {
+		return errors.Wrapf(err, errors.SerializationError, "failed to marshal service %q", service.Name)
+	}
+	_, err = s.exec(tx, `INSERT INTO `+ServicesTable+` (tenant, name, type, metadata) VALUES (?, ?, ?, ?)`,
+		s.tenant, service.Name, service.Type, string(metadata))
+	if err != nil {
+		return err
+	}
+
+	policies := make([]entity, 0, len(service.Policies))
+	for _, policy := range service.Policies {
+		if policy.ID == "" {
+			policy.ID = suid.New().String()
+		}
+		policies = append(policies, entity{id: policy.ID, name: policy.Name, value: policy})
+	}
+	if err := s.insertEntities(tx, PoliciesTable, service.Name, policies); err != nil {
+		return err
+	}
+	rolePolicies := make([]entity, 0, len(service.RolePolicies))
+	for _, rolePolicy := range service.RolePolicies {
+		if rolePolicy.ID == "" {
+			rolePolicy.ID = suid.New().String()
+		}
+		rolePolicies = append(rolePolicies, entity{id: rolePolicy.ID, name: rolePolicy.Name, value: rolePolicy})
+	}
+	return s.insertEntities(tx, RolePoliciesTable, service.Name, rolePolicies)
+}
+
+// entity is a row of the policies or role policies table
+type entity struct {
+	id    string
+	name  string
+	value interface{}
+}
+
+func (s *Store) insertEntities(tx *sql.Tx, table string, serviceName string, entities []entity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(s.dialect.rebind(`INSERT INTO ` + table + ` (tenant, service, id, name, body) VALUES (?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range entities {
+		body, err := json.Marshal(e.value)
+		if err != nil {
+			return errors.Wrapf(err, errors.SerializationError, "failed to marshal %q", e.id)
+		}
+		if _, err := stmt.Exec(s.tenant, serviceName, e.id, e.name, string(body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetServiceNames() ([]string, error) {
+	serviceNames, err := s.queryStrings(s.db, `SELECT name FROM `+ServicesTable+` WHERE tenant = ? ORDER BY name`+s.dialect.collation, s.tenant)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get service names")
+	}
+	return serviceNames, nil
+}
+
+func (s *Store) GetPolicyAndRolePolicyCounts() (map[string]*pms.PolicyAndRolePolicyCount, error) {
+	countMap := make(map[string]*pms.PolicyAndRolePolicyCount)
+	err := s.view(func(tx *sql.Tx) error {
+		serviceNames, err := s.queryStrings(tx, `SELECT name FROM `+ServicesTable+` WHERE tenant = ?`, s.tenant)
+		if err != nil {
+			return err
+		}
+		for _, serviceName := range serviceNames {
+			countMap[serviceName] = &pms.PolicyAndRolePolicyCount{}
+		}
+		for _, table := range []string{PoliciesTable, RolePoliciesTable} {
+			rows, err := tx.Query(s.dialect.rebind(`SELECT service, COUNT(*) FROM `+table+` WHERE tenant = ? GROUP BY service`), s.tenant)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var serviceName string
+				var count int64
+				if err := rows.Scan(&serviceName, &count); err != nil {
+					rows.Close()
+					return err
+				}
+				if c, ok := countMap[serviceName]; ok {
+					if table == PoliciesTable {
+						c.PolicyCount = count
+					} else {
+						c.RolePolicyCount = count
+					}
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get policy and role policy counts")
+	}
+	return countMap, nil
+}
+
+func (s *Store) GetServiceCount() (int64, error) {
+	count, err := s.queryInt(s.db, `SELECT COUNT(*) FROM `+ServicesTable+` WHERE tenant = ?`, s.tenant)
+	return count, wrapStoreError(err, "unable to get service count")
+}
+
+func (s *Store) ListAllServices() (services []*pms.Service, err error) {
+	err = s.view(func(tx *sql.Tx) error {
+		services, err = s.listServices(tx, "", true)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list services")
+	}
+	return services, nil
+}
+
+func (s *Store) GetService(serviceName string) (*pms.Service, error) {
+	var services []*pms.Service
+	err := s.view(func(tx *sql.Tx) error {
+		var err error
+		if services, err = s.listServices(tx, serviceName, true); err != nil {
+			return err
+		}
+		if len(services) == 0 {
+			return errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get service %q", serviceName)
+	}
+	return services[0], nil
+}
+
+func (s *Store) CreateService(service *pms.Service) error {
+	err := s.update(func(tx *sql.Tx) error {
+		if err := s.putService(tx, service); err != nil {
+			return err
+		}
+		return s.logChange(tx, pms.SERVICE_ADD, service.Name, service)
+	})
+	return wrapStoreError(err, "unable to create service %q", service.Name)
+}
+
+// deleteServices deletes service serviceName, or all the services when serviceName is empty, and returns the
+// names of the deleted services
+func (s *Store) deleteServices(tx *sql.Tx, serviceName string) ([]string, error) {
+	cond, args := ``, []interface{}{s.tenant}
+	if len(serviceName) > 0 {
+		cond = ` AND name = ?`
+		args = append(args, serviceName)
+	}
+	serviceNames, err := s.queryStrings(tx, `SELECT name FROM `+ServicesTable+` WHERE tenant = ?`+cond, args...)
+	if err != nil || len(serviceNames) == 0 {
+		return nil, err
+	}
+	if _, err := s.exec(tx, `DELETE FROM `+ServicesTable+` WHERE tenant = ?`+cond, args...); err != nil {
+		return nil, err
+	}
+	if len(serviceName) > 0 {
+		cond = ` AND service = ?`
+	}
+	for _, table := range []string{PoliciesTable, RolePoliciesTable} {
+		if _, err := s.exec(tx, `DELETE FROM `+table+` WHERE tenant = ?`+cond, args...); err != nil {
+			return nil, err
+		}
+	}
+	return serviceNames, nil
+}
+
+func (s *Store) DeleteService(serviceName string) error {
+	err := s.update(func(tx *sql.Tx) error {
+		serviceNames, err := s.deleteServices(tx, serviceName)
+		if err != nil {
+			return err
+		}
+		if len(serviceNames) == 0 {
+			return errors.Errorf(errors.EntityNotFound, "service %q is not found", serviceName)
+		}
+		return s.logChange(tx, pms.SERVICE_DELETE, "", serviceNames)
+	})
+	return wrapStoreError(err, "unable to delete service %q", serviceName)
+}
+
+func (s *Store) DeleteServices() error {
+	err := s.update(func(tx *sql.Tx) error {
+		serviceNames, err := s.deleteServices(tx, "")
+		if err != nil || len(serviceNames) == 0 {
+			return err
+		}
+		return s.logChange(tx, pms.SERVICE_DELETE, "", serviceNames)
+	})
+	return wrapStoreError(err, "unable to delete services")
+}
+
+func (s *Store) putFunction(tx *sql.Tx, function *pms.Function) error {
+	count, err := s.queryInt(tx, `SELECT COUNT(*) FROM `+FunctionsTable+` WHERE tenant = ? AND name = ?`, s.tenant, function.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.Errorf(errors.EntityAlreadyExists, "function %q already exists", function.Name)
+	}
+	body, err := json.Marshal(function)
+	if err != nil {
+		return errors.Wrapf(err, errors.SerializationError, "failed to marshal function %q", function.Name)
+	}
+	_, err = s.exec(tx, `INSERT INTO `+FunctionsTable+` (tenant, name, body) VALUES (?, ?, ?)`, s.tenant, function.Name, string(body))
+	return err
+}
+
+// listFunctions lists the functions matching filter, which is pushed down to the database
+func (s *Store) listFunctions(q queryer, filter string) ([]*pms.Function, error) {
+	cond, condArgs := s.nameCondition(filter)
+	rows, err := q.Query(s.dialect.rebind(`SELECT body FROM `+FunctionsTable+` WHERE tenant = ?`+cond+` ORDER BY name`+s.dialect.collation),
+		append([]interface{}{s.tenant}, condArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var functions []*pms.Function
+	for rows.Next() {
+		var body []byte
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		var function pms.Function
+		if err := json.Unmarshal(body, &function); err != nil {
+			return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal function %q", body)
+		}
+		functions = append(functions, &function)
+	}
+	return functions, rows.Err()
+}
+
+func (s *Store) CreateFunction(function *pms.Function) (*pms.Function, error) {
+	if err := store.ValidateFunction(function); err != nil {
+		return nil, err
+	}
+	err := s.update(func(tx *sql.Tx) error {
+		if err := s.putFunction(tx, function); err != nil {
+			return err
+		}
+		return s.logChange(tx, pms.FUNCTION_ADD, "", function)
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create function %q", function.Name)
+	}
+	return function, nil
+}
+
+// deleteFunctions deletes function funcName, or all the functions when funcName is empty, and returns the
+// names of the deleted functions
+func (s *Store) deleteFunctions(tx *sql.Tx, funcName string) ([]string, error) {
+	cond, args := ``, []interface{}{s.tenant}
+	if len(funcName) > 0 {
+		cond = ` AND name = ?`
+		args = append(args, funcName)
+	}
+	funcNames, err := s.queryStrings(tx, `SELECT name FROM `+FunctionsTable+` WHERE tenant = ?`+cond, args...)
+	if err != nil || len(funcNames) == 0 {
+		return nil, err
+	}
+	_, err = s.exec(tx, `DELETE FROM `+FunctionsTable+` WHERE tenant = ?`+cond, args...)
+	return funcNames, err
+}
+
+func (s *Store) DeleteFunction(funcName string) error {
+	err := s.update(func(tx *sql.Tx) error {
+		funcNames, err := s.deleteFunctions(tx, funcName)
+		if err != nil {
+			return err
+		}
+		if len(funcNames) == 0 {
+			return errors.Errorf(errors.EntityNotFound, "function %q is not found", funcName)
+		}
+		return s.logChange(tx, pms.FUNCTION_DELETE, "", funcNames)
+	})
+	return wrapStoreError(err, "unable to delete function %q", funcName)
+}
+
+func (s *Store) DeleteFunctions() error {
+	err := s.update(func(tx *sql.Tx) error {
+		funcNames, err := s.deleteFunctions(tx, "")
+		if err != nil || len(funcNames) == 0 {
+			return err
+		}
+		return s.logChange(tx, pms.FUNCTION_DELETE, "", funcNames)
+	})
+	return wrapStoreError(err, "unable to delete all functions")
+}
+
+func (s *Store) GetFunction(funcName string) (*pms.Function, error) {
+	var body []byte
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT body FROM `+FunctionsTable+` WHERE tenant = ? AND name = ?`), s.tenant, funcName).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf(errors.EntityNotFound, "function %q is not found", funcName)
+	}
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get function %q", funcName)
+	}
+	var function pms.Function
+	if err := json.Unmarshal(body, &function); err != nil {
+		return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal function %q", body)
+	}
+	return &function, nil
+}
+
+func (s *Store) ListAllFunctions(filter string) ([]*pms.Function, error) {
+	functions, err := s.listFunctions(s.db, filter)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list functions")
+	}
+	return functions, nil
+}
+
+func (s *Store) GetFunctionCount() (int64, error) {
+	count, err := s.queryInt(s.db, `SELECT COUNT(*) FROM `+FunctionsTable+` WHERE tenant = ?`, s.tenant)
+	return count, wrapStoreError(err, "unable to get function count")
+}
+
+// For policy manager
+func (s *Store) ListAllPolicies(serviceName string, filter string) ([]*pms.Policy, error) {
+	cond, condArgs := s.nameCondition(filter)
+	policies := []*pms.Policy{}
+	err := s.view(func(tx *sql.Tx) error {
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		return s.selectEntities(tx, PoliciesTable, serviceName, cond, condArgs, func(service string, body []byte) error {
+			var policy pms.Policy
+			if err := json.Unmarshal(body, &policy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+			}
+			policies = append(policies, &policy)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list policies of service %q", serviceName)
+	}
+	return policies, nil
+}
+
+// countEntities counts the rows of table in service serviceName, or in all services when serviceName is empty
+func (s *Store) countEntities(serviceName string, table string) (int64, error) {
+	var count int64
+	err := s.view(func(tx *sql.Tx) error {
+		if len(serviceName) == 0 {
+			var err error
+			count, err = s.queryInt(tx, `SELECT COUNT(*) FROM `+table+` WHERE tenant = ?`, s.tenant)
+			return err
+		}
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		var err error
+		count, err = s.queryInt(tx, `SELECT COUNT(*) FROM `+table+` WHERE tenant = ? AND service = ?`, s.tenant, serviceName)
+		return err
+	})
+	return count, err
+}
+
+func (s *Store) GetPolicyCount(serviceName string) (int64, error) {
+	count, err := s.countEntities(serviceName, PoliciesTable)
+	return count, wrapStoreError(err, "unable to get policy count")
+}
+
+// getEntity reads the body of entity id in service serviceName, EntityNotFound is returned if the service or
+// the entity doesn't exist
+func (s *Store) getEntity(table string, kind string, serviceName string, id string) ([]byte, error) {
+	var body []byte
+	err := s.view(func(tx *sql.Tx) error {
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		err := tx.QueryRow(s.dialect.rebind(`SELECT body FROM `+table+` WHERE tenant = ? AND service = ? AND id = ?`), s.tenant, serviceName, id).Scan(&body)
+		if err == sql.ErrNoRows {
+			return errors.Errorf(errors.EntityNotFound, "%s %q is not found in service %q", kind, id, serviceName)
+		}
+		return err
+	})
+	return body, err
+}
+
+func (s *Store) GetPolicy(serviceName string, id string) (*pms.Policy, error) {
+	body, err := s.getEntity(PoliciesTable, "policy", serviceName, id)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get policy %q in service %q", id, serviceName)
+	}
+	var policy pms.Policy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal a policy")
+	}
+	return &policy, nil
+}
+
+// deleteEntities deletes entity id of table in service serviceName, or all the entities of the service when id
+// is empty. The bodies of the deleted entities are returned.
+func (s *Store) deleteEntities(tx *sql.Tx, table string, serviceName string, id string) ([][]byte, error) {
+	if err := s.serviceExists(tx, serviceName); err != nil {
+		return nil, err
+	}
+	cond, condArgs := ``, []interface{}{}
+	if len(id) > 0 {
+		cond = ` AND id = ?`
+		condArgs = append(condArgs, id)
+	}
+	var bodies [][]byte
+	err := s.selectEntities(tx, table, serviceName, cond, condArgs, func(service string, body []byte) error {
+		bodies = append(bodies, body)
+		return nil
+	})
+	if err != nil || len(bodies) == 0 {
+		return nil, err
+	}
+	_, err = s.exec(tx, `DELETE FROM `+table+` WHERE tenant = ? AND service = ?`+cond, append([]interface{}{s.tenant, serviceName}, condArgs...)...)
+	return bodies, err
+}
+
+func (s *Store) deletePolicies(serviceName string, id string) error {
+	return s.update(func(tx *sql.Tx) error {
+		bodies, err := s.deleteEntities(tx, PoliciesTable, serviceName, id)
+		if err != nil {
+			return err
+		}
+		if len(bodies) == 0 {
+			if len(id) > 0 {
+				return errors.Errorf(errors.EntityNotFound, "policy %q is not found in service %q", id, serviceName)
+			}
+			return nil
+		}
+		var policies []*pms.Policy
+		for _, body := range bodies {
+			var policy pms.Policy
+			if err := json.Unmarshal(body, &policy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+			}
+			policies = append(policies, &policy)
+		}
+		return s.logChange(tx, pms.POLICY_DELETE, serviceName, policies)
+	})
+}
+
+func (s *Store) DeletePolicy(serviceName string, id string) error {
+	return wrapStoreError(s.deletePolicies(serviceName, id), "unable to delete policy %q in service %q", id, serviceName)
+}
+
+func (s *Store) DeletePolicies(serviceName string) error {
+	return wrapStoreError(s.deletePolicies(serviceName, ""), "unable to delete all policies in service %q", serviceName)
+}
+
+// createEntity inserts entity e of table into service serviceName, and logs the change
+func (s *Store) createEntity(table string, kind string, serviceName string, e entity, eventType pms.EventType) error {
+	return s.update(func(tx *sql.Tx) error {
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		count, err := s.queryInt(tx, `SELECT COUNT(*) FROM `+table+` WHERE tenant = ? AND service = ? AND id = ?`, s.tenant, serviceName, e.id)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.Errorf(errors.EntityAlreadyExists, "%s %q already exists in service %q", kind, e.id, serviceName)
+		}
+		if err := s.insertEntities(tx, table, serviceName, []entity{e}); err != nil {
+			return err
+		}
+		return s.logChange(tx, eventType, serviceName, []interface{}{e.value})
+	})
+}
+
+func (s *Store) CreatePolicy(serviceName string, policy *pms.Policy) (*pms.Policy, error) {
+	dupPolicy := *policy
+	if policy.ID == "" {
+		dupPolicy.ID = suid.New().String()
+	}
+	err := s.createEntity(PoliciesTable, "policy", serviceName, entity{id: dupPolicy.ID, name: dupPolicy.Name, value: &dupPolicy}, pms.POLICY_ADD)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create a policy in service %q", serviceName)
+	}
+	return &dupPolicy, nil
+}
+
+// For role policy manager
+func (s *Store) ListAllRolePolicies(serviceName string, filter string) ([]*pms.RolePolicy, error) {
+	cond, condArgs := s.nameCondition(filter)
+	rolePolicies := []*pms.RolePolicy{}
+	err := s.view(func(tx *sql.Tx) error {
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		return s.selectEntities(tx, RolePoliciesTable, serviceName, cond, condArgs, func(service string, body []byte) error {
+			var rolePolicy pms.RolePolicy
+			if err := json.Unmarshal(body, &rolePolicy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+			}
+			rolePolicies = append(rolePolicies, &rolePolicy)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list role policies of service %q", serviceName)
+	}
+	return rolePolicies, nil
+}
+
+func (s *Store) GetRolePolicyCount(serviceName string) (int64, error) {
+	count, err := s.countEntities(serviceName, RolePoliciesTable)
+	return count, wrapStoreError(err, "unable to get role policy count")
+}
+
+func (s *Store) GetRolePolicy(serviceName string, id string) (*pms.RolePolicy, error) {
+	body, err := s.getEntity(RolePoliciesTable, "role policy", serviceName, id)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to get role policy %q in service %q", id, serviceName)
+	}
+	var rolePolicy pms.RolePolicy
+	if err := json.Unmarshal(body, &rolePolicy); err != nil {
+		return nil, errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+	}
+	return &rolePolicy, nil
+}
+
+func (s *Store) deleteRolePolicies(serviceName string, id string) error {
+	return s.update(func(tx *sql.Tx) error {
+		bodies, err := s.deleteEntities(tx, RolePoliciesTable, serviceName, id)
+		if err != nil {
+			return err
+		}
+		if len(bodies) == 0 {
+			if len(id) > 0 {
+				return errors.Errorf(errors.EntityNotFound, "role policy %q is not found in service %q", id, serviceName)
+			}
+			return nil
+		}
+		var rolePolicies []*pms.RolePolicy
+		for _, body := range bodies {
+			var rolePolicy pms.RolePolicy
+			if err := json.Unmarshal(body, &rolePolicy); err != nil {
+				return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policy")
+			}
+			rolePolicies = append(rolePolicies, &rolePolicy)
+		}
+		return s.logChange(tx, pms.ROLEPOLICY_DELETE, serviceName, rolePolicies)
+	})
+}
+
+func (s *Store) DeleteRolePolicy(serviceName string, id string) error {
+	return wrapStoreError(s.deleteRolePolicies(serviceName, id), "unable to delete role policy %q in service %q", id, serviceName)
+}
+
+func (s *Store) DeleteRolePolicies(serviceName string) error {
+	return wrapStoreError(s.deleteRolePolicies(serviceName, ""), "unable to delete all role policies in service %q", serviceName)
+}
+
+func (s *Store) CreateRolePolicy(serviceName string, rolePolicy *pms.RolePolicy) (*pms.RolePolicy, error) {
+	dupRolePolicy := *rolePolicy
+	if rolePolicy.ID == "" {
+		dupRolePolicy.ID = suid.New().String()
+	}
+	err := s.createEntity(RolePoliciesTable, "role policy", serviceName, entity{id: dupRolePolicy.ID, name: dupRolePolicy.Name, value: &dupRolePolicy}, pms.ROLEPOLICY_ADD)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to create role policy in service %q", serviceName)
+	}
+	return &dupRolePolicy, nil
+}
+
+type filter struct {
+	field    string
+	operator string
+	target   string
+}
+
+func (f filter) String() string {
+	return fmt.Sprint(f.field, f.operator, f.target)
+}
+
+func parseFilter(filterStr string) *filter {
+	if len(filterStr) == 0 {
+		return nil
+	}
+	values := strings.Split(filterStr, " ")
+	if len(values) >= 2 {
+		f := &filter{
+			field:    values[0],
+			operator: values[1],
+		}
+		if len(values) > 2 {
+			f.target = values[2]
+		}
+		return f
+	}
+	log.Error("invalid filter string:", filterStr)
+	return nil
+}
+
+// nameCondition translates a name filter into a SQL condition on the name column. Like the name filters of the
+// other stores, everything is matched when the filter is invalid.
+func (s *Store) nameCondition(filterStr string) (string, []interface{}) {
+	f := parseFilter(filterStr)
+	if f == nil {
+		return "", nil
+	}
+	if f.field != "name" {
+		log.Error("invalid name filter. filter is:", f)
+		return "", nil
+	}
+	name := ` AND name` + s.dialect.collation
+	switch f.operator {
+	case "eq":
+		return ` AND name = ?`, []interface{}{f.target}
+	case "co":
+		return ` AND ` + s.dialect.contains + `(name, ?) > 0`, []interface{}{f.target}
+	case "sw":
+		return ` AND substr(name, 1, ?) = ?`, []interface{}{utf8.RuneCountInString(f.target), f.target}
+	case "pr":
+		return ` AND name <> ''`, nil
+	case "gt":
+		return name + ` > ?`, []interface{}{f.target}
+	case "ge":
+		return name + ` >= ?`, []interface{}{f.target}
+	case "lt":
+		return name + ` < ?`, []interface{}{f.target}
+	case "le":
+		return name + ` <= ?`, []interface{}{f.target}
+	default:
+		log.Error("invalid name filter:", f)
+		return "", nil
+	}
+}
+
+// selectPage calls fn with the bodies of up to limit rows of table in service serviceName matching filter, whose IDs
+// are greater than afterID, ordered by ID
+func (s *Store) selectPage(table string, serviceName string, afterID string, filter *store.Filter, limit int, fn func(body []byte) error) error {
+	cond, condArgs := s.filterCondition(table, filter)
+	return s.view(func(tx *sql.Tx) error {
+		if err := s.serviceExists(tx, serviceName); err != nil {
+			return err
+		}
+		args := append([]interface{}{s.tenant, serviceName, afterID}, condArgs...)
+		rows, err := tx.Query(s.dialect.rebind(`SELECT body FROM `+table+` WHERE tenant = ? AND service = ? AND id`+
+			s.dialect.collation+` > ?`+cond+` ORDER BY id`+s.dialect.collation+` LIMIT ?`), append(args, limit)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var body []byte
+			if err := rows.Scan(&body); err != nil {
+				return err
+			}
+			if err := fn(body); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// ServicePage returns up to limit services whose names are greater than afterName, ordered by name, without their
+// policies and role policies
+func (s *Store) ServicePage(afterName string, limit int) ([]*pms.Service, error) {
+	return s.FilteredServicePage(afterName, nil, limit)
+}
+
+// FilteredServicePage is ServicePage returning the services matching filter only, the comparisons on name and
+// metadata are done by the database
+func (s *Store) FilteredServicePage(afterName string, filter *store.Filter, limit int) (services []*pms.Service, err error) {
+	cond, condArgs := s.filterCondition(ServicesTable, filter)
+	args := append([]interface{}{s.tenant, afterName}, condArgs...)
+	err = s.view(func(tx *sql.Tx) error {
+		services, err = s.queryServices(tx, `SELECT name, type, metadata FROM `+ServicesTable+` WHERE tenant = ? AND name`+
+			s.dialect.collation+` > ?`+cond+` ORDER BY name`+s.dialect.collation+` LIMIT ?`, append(args, limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list services")
+	}
+	return services, nil
+}
+
+// PolicyPage returns up to limit policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) PolicyPage(serviceName string, afterID string, limit int) ([]*pms.Policy, error) {
+	return s.FilteredPolicyPage(serviceName, afterID, nil, limit)
+}
+
+// FilteredPolicyPage is PolicyPage returning the policies matching filter only, the comparisons on name, effect and
+// metadata are done by the database
+func (s *Store) FilteredPolicyPage(serviceName string, afterID string, filter *store.Filter, limit int) ([]*pms.Policy, error) {
+	policies := []*pms.Policy{}
+	err := s.selectPage(PoliciesTable, serviceName, afterID, filter, limit, func(body []byte) error {
+		var policy pms.Policy
+		if err := json.Unmarshal(body, &policy); err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to unmarshal policies")
+		}
+		policies = append(policies, &policy)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list policies of service %q", serviceName)
+	}
+	return policies, nil
+}
+
+// RolePolicyPage returns up to limit role policies of a service whose IDs are greater than afterID, ordered by ID
+func (s *Store) RolePolicyPage(serviceName string, afterID string, limit int) ([]*pms.RolePolicy, error) {
+	return s.FilteredRolePolicyPage(serviceName, afterID, nil, limit)
+}
+
+// FilteredRolePolicyPage is RolePolicyPage returning the role policies matching filter only, the comparisons on
+// name, effect and metadata are done by the database
+func (s *Store) FilteredRolePolicyPage(serviceName string, afterID string, filter *store.Filter, limit int) ([]*pms.RolePolicy, error) {
+	rolePolicies := []*pms.RolePolicy{}
+	err := s.selectPage(RolePoliciesTable, serviceName, afterID, filter, limit, func(body []byte) error {
+		var rolePolicy pms.RolePolicy
+		if err := json.Unmarshal(body, &rolePolicy); err != nil {
+			return errors.Wrap(err, errors.SerializationError, "failed to unmarshal role policies")
+		}
+		rolePolicies = append(rolePolicies, &rolePolicy)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to list role policies of service %q", serviceName)
+	}
+	return rolePolicies, nil
+}
@@ -0,0 +1,729 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+// testDataSource returns the data source of a sqlite database in a temporary directory
+func testDataSource(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "speedle-sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "file:" + filepath.Join(dir, "policies.db") + "?_busy_timeout=5000&_journal_mode=WAL", func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func openTestStore(t *testing.T, dataSource string) *Store {
+	ps, err := store.NewStore(StoreType, map[string]interface{}{
+		SQLDriverKey:       "sqlite3",
+		SQLDataSourceKey:   dataSource,
+		SQLPollIntervalKey: "10ms",
+	})
+	if err != nil {
+		t.Fatal("fail to new SQL store:", err)
+	}
+	return ps.(*Store)
+}
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dataSource, clean := testDataSource(t)
+	s := openTestStore(t, dataSource)
+	return s, func() {
+		s.Close()
+		clean()
+	}
+}
+
+func newPolicy(name string) *pms.Policy {
+	return &pms.Policy{
+		Name:       name,
+		Effect:     "grant",
+		Principals: [][]string{{"user:alice"}},
+		Permissions: []*pms.Permission{
+			{Resource: "/books", Actions: []string{"read"}},
+		},
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	dataSource, clean := testDataSource(t)
+	defer clean()
+
+	s := openTestStore(t, dataSource)
+	defer s.Close()
+	//opening the migrated database again applies nothing
+	s2 := openTestStore(t, dataSource)
+	defer s2.Close()
+
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM ` + MigrationsTable).Scan(&count); err != nil || count != len(migrations) {
+		t.Errorf("expect %d migrations, got %d, %v", len(migrations), count, err)
+	}
+
+	if _, err := store.NewStore(StoreType, map[string]interface{}{SQLDriverKey: "oracle", SQLDataSourceKey: dataSource}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expect ConfigError for unsupported driver, got %v", err)
+	}
+	if _, err := store.NewStore(StoreType, map[string]interface{}{SQLDriverKey: "sqlite3"}); errors.Code(err) != errors.ConfigError {
+		t.Errorf("expect ConfigError without data source, got %v", err)
+	}
+}
+
+func TestWriteReadPolicyStore(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	var ps pms.PolicyStore
+	for i := 0; i < 10; i++ {
+		service := pms.Service{Name: fmt.Sprintf("app%d", i), Type: pms.TypeApplication, Metadata: map[string]string{"owner": "team"}}
+		service.Policies = []*pms.Policy{newPolicy("p1"), newPolicy("p2")}
+		service.RolePolicies = []*pms.RolePolicy{{Name: "rp1", Effect: "grant", Roles: []string{"reader"}, Principals: []string{"user:bob"}}}
+		ps.Services = append(ps.Services, &service)
+	}
+	ps.Functions = []*pms.Function{{Name: "f1", FuncURL: "http://localhost:8000/f1"}}
+	if err := s.WritePolicyStore(&ps); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+
+	psr, err := s.ReadPolicyStore()
+	if err != nil {
+		t.Fatal("fail to read policy store:", err)
+	}
+	if len(psr.Services) != 10 || len(psr.Functions) != 1 {
+		t.Fatalf("expect 10 services and 1 function, got %d services and %d functions", len(psr.Services), len(psr.Functions))
+	}
+	for _, service := range psr.Services {
+		if len(service.Policies) != 2 || len(service.RolePolicies) != 1 {
+			t.Errorf("service %q should have 2 policies and 1 role policy", service.Name)
+		}
+		if service.Type != pms.TypeApplication || service.Metadata["owner"] != "team" {
+			t.Errorf("attributes of service %q are not kept: %+v", service.Name, service)
+		}
+		for _, policy := range service.Policies {
+			if len(policy.ID) == 0 {
+				t.Errorf("policy %q in service %q has no ID", policy.Name, service.Name)
+			}
+		}
+	}
+
+	//writing again replaces everything
+	if err := s.WritePolicyStore(&pms.PolicyStore{Services: []*pms.Service{{Name: "only"}}}); err != nil {
+		t.Fatal("fail to write policy store:", err)
+	}
+	if count, _ := s.GetServiceCount(); count != 1 {
+		t.Errorf("expect 1 service, got %d", count)
+	}
+	if count, _ := s.GetFunctionCount(); count != 0 {
+		t.Errorf("expect 0 function, got %d", count)
+	}
+	if count, _ := s.GetPolicyCount(""); count != 0 {
+		t.Errorf("expect 0 policy, got %d", count)
+	}
+}
+
+func TestServicePolicyRolePolicy(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if err := s.CreateService(&pms.Service{Name: "app1", Type: pms.TypeApplication}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "app1"}); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	if _, err := s.CreatePolicy("nonexist", newPolicy("p")); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if _, err := s.ListAllPolicies("nonexist", ""); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		policy, err := s.CreatePolicy("app1", newPolicy(fmt.Sprintf("policy%d", i)))
+		if err != nil {
+			t.Fatal("fail to create policy:", err)
+		}
+		ids = append(ids, policy.ID)
+	}
+	withID := newPolicy("withID")
+	withID.ID = ids[0]
+	if _, err := s.CreatePolicy("app1", withID); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	rolePolicy, err := s.CreateRolePolicy("app1", &pms.RolePolicy{Name: "rp", Effect: "grant", Roles: []string{"reader"}, Principals: []string{"user:bob"}})
+	if err != nil {
+		t.Fatal("fail to create role policy:", err)
+	}
+
+	policy, err := s.GetPolicy("app1", ids[1])
+	if err != nil || policy.Name != "policy1" {
+		t.Errorf("fail to get policy: %v, %v", policy, err)
+	}
+	if _, err := s.GetPolicy("app1", "nonexist"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if _, err := s.GetRolePolicy("app1", rolePolicy.ID); err != nil {
+		t.Errorf("fail to get role policy: %v", err)
+	}
+	policies, err := s.ListAllPolicies("app1", "name eq policy3")
+	if err != nil || len(policies) != 1 || policies[0].ID != ids[3] {
+		t.Errorf("expect policy3, got %v, %v", policies, err)
+	}
+
+	counts, err := s.GetPolicyAndRolePolicyCounts()
+	if err != nil || counts["app1"].PolicyCount != 5 || counts["app1"].RolePolicyCount != 1 {
+		t.Errorf("unexpected counts %v, %v", counts, err)
+	}
+
+	if err := s.DeletePolicy("app1", ids[0]); err != nil {
+		t.Error("fail to delete policy:", err)
+	}
+	if err := s.DeletePolicy("app1", ids[0]); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if count, _ := s.GetPolicyCount(""); count != 4 {
+		t.Errorf("expect 4 policies, got %d", count)
+	}
+	if err := s.DeletePolicies("app1"); err != nil {
+		t.Error("fail to delete policies:", err)
+	}
+	if err := s.DeleteRolePolicies("app1"); err != nil {
+		t.Error("fail to delete role policies:", err)
+	}
+	service, err := s.GetService("app1")
+	if err != nil || len(service.Policies) != 0 || len(service.RolePolicies) != 0 || service.Type != pms.TypeApplication {
+		t.Errorf("unexpected service %+v, %v", service, err)
+	}
+
+	if err := s.DeleteService("app1"); err != nil {
+		t.Error("fail to delete service:", err)
+	}
+	if _, err := s.GetService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.DeleteService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+}
+
+func TestNameFilters(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	service := &pms.Service{Name: "app1"}
+	for _, name := range []string{"read_books", "write_books", "Read_all", "100%_books", "読む"} {
+		service.Policies = append(service.Policies, newPolicy(name))
+	}
+	if err := s.CreateService(service); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{"", 5},
+		{"name eq read_books", 1},
+		{"name co books", 3},
+		{"name co _", 4},
+		{"name co %", 1},
+		{"name sw read", 1},
+		{"name sw Read", 1},
+		{"name sw 読", 1},
+		{"name pr", 5},
+		{"name gt read_books", 2},
+		{"name ge read_books", 3},
+		{"name lt Read_all", 1},
+		{"name le Read_all", 2},
+		{"id eq x", 5},
+		{"name xx x", 5},
+	}
+	for _, test := range tests {
+		policies, err := s.ListAllPolicies("app1", test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(policies) != test.want {
+			var names []string
+			for _, policy := range policies {
+				names = append(names, policy.Name)
+			}
+			t.Errorf("filter %q: expect %d policies, got %v", test.filter, test.want, names)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if _, err := s.CreateFunction(&pms.Function{Name: "f"}); errors.Code(err) != errors.InvalidRequest {
+		t.Errorf("expect InvalidRequest, got %v", err)
+	}
+	for _, name := range []string{"f1", "f2", "g1"} {
+		if _, err := s.CreateFunction(&pms.Function{Name: name, FuncURL: "http://localhost/" + name}); err != nil {
+			t.Fatal("fail to create function:", err)
+		}
+	}
+	if _, err := s.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); errors.Code(err) != errors.EntityAlreadyExists {
+		t.Errorf("expect EntityAlreadyExists, got %v", err)
+	}
+	functions, err := s.ListAllFunctions("name sw f")
+	if err != nil || len(functions) != 2 {
+		t.Errorf("expect 2 functions, got %v, %v", functions, err)
+	}
+	if f, err := s.GetFunction("g1"); err != nil || f.FuncURL != "http://localhost/g1" {
+		t.Errorf("unexpected function %v, %v", f, err)
+	}
+	if err := s.DeleteFunction("g1"); err != nil {
+		t.Error("fail to delete function:", err)
+	}
+	if _, err := s.GetFunction("g1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.DeleteFunctions(); err != nil {
+		t.Error("fail to delete functions:", err)
+	}
+	if count, _ := s.GetFunctionCount(); count != 0 {
+		t.Errorf("expect 0 function, got %d", count)
+	}
+}
+
+func receiveEvent(t *testing.T, ch pms.StorageChangeChannel) pms.StoreChangeEvent {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for store change event")
+	}
+	return pms.StoreChangeEvent{}
+}
+
+func TestWatch(t *testing.T) {
+	dataSource, clean := testDataSource(t)
+	defer clean()
+	s := openTestStore(t, dataSource)
+	defer s.Close()
+	//the changes made by another server sharing the database are watched too
+	other := openTestStore(t, dataSource)
+	defer other.Close()
+
+	if err := s.CreateService(&pms.Service{Name: "before"}); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := s.Watch()
+	if err != nil {
+		t.Fatal("fail to watch:", err)
+	}
+	if err := other.CreateService(&pms.Service{Name: "app1", Policies: []*pms.Policy{newPolicy("p0")}}); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := other.CreatePolicy("app1", newPolicy("p1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolePolicy, err := s.CreateRolePolicy("app1", &pms.RolePolicy{Name: "rp1", Roles: []string{"r"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeletePolicy("app1", policy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.DeleteRolePolicy("app1", rolePolicy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteFunction("f1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.DeleteService("app1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePolicyStore(&pms.PolicyStore{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []pms.EventType{pms.SERVICE_ADD, pms.POLICY_ADD, pms.ROLEPOLICY_ADD, pms.FUNCTION_ADD,
+		pms.POLICY_DELETE, pms.ROLEPOLICY_DELETE, pms.FUNCTION_DELETE, pms.SERVICE_DELETE, pms.FULL_RELOAD}
+	var lastID int64
+	for _, eventType := range expected {
+		e := receiveEvent(t, ch)
+		if e.Type != eventType {
+			t.Fatalf("expect event %v, got %v", eventType, e.Type)
+		}
+		if e.ID <= lastID {
+			t.Errorf("event IDs should increase, got %d after %d", e.ID, lastID)
+		}
+		lastID = e.ID
+		switch e.Type {
+		case pms.SERVICE_ADD:
+			service := e.Content.(*pms.Service)
+			if service.Name != "app1" || len(service.Policies) != 1 || len(service.Policies[0].ID) == 0 {
+				t.Errorf("unexpected content %+v of event %v", service, e.Type)
+			}
+		case pms.POLICY_ADD, pms.POLICY_DELETE:
+			data := e.Content.([]pms.StoreUpdateData)
+			if len(data) != 1 || data[0].ServiceName != "app1" || data[0].Data.(*pms.Policy).ID != policy.ID {
+				t.Errorf("unexpected content %v of event %v", data, e.Type)
+			}
+		case pms.ROLEPOLICY_ADD, pms.ROLEPOLICY_DELETE:
+			data := e.Content.([]pms.StoreUpdateData)
+			if len(data) != 1 || data[0].ServiceName != "app1" || data[0].Data.(*pms.RolePolicy).ID != rolePolicy.ID {
+				t.Errorf("unexpected content %v of event %v", data, e.Type)
+			}
+		case pms.FUNCTION_ADD:
+			if function := e.Content.(*pms.Function); function.Name != "f1" {
+				t.Errorf("unexpected content %v of event %v", function, e.Type)
+			}
+		case pms.SERVICE_DELETE, pms.FUNCTION_DELETE:
+			if names := e.Content.([]string); len(names) != 1 {
+				t.Errorf("unexpected content %v of event %v", names, e.Type)
+			}
+		}
+	}
+
+	s.StopWatch()
+	if _, ok := <-ch; ok {
+		t.Error("channel should be closed after StopWatch")
+	}
+}
+
+func TestWatchPrunedChanges(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+	s.retention = time.Minute
+
+	revision, err := s.currentRevision(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &watcher{store: s, revision: revision, out: make(chan pms.StoreChangeEvent, 10), stop: make(chan struct{})}
+	if err := s.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	//prune the changes as if the retention has passed
+	err = s.update(func(tx *sql.Tx) error {
+		return s.pruneChanges(tx, time.Now().Add(2*time.Minute))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := s.queryInt(s.db, `SELECT COUNT(*) FROM `+ChangesTable); count != 0 {
+		t.Fatalf("changes should be pruned, got %d", count)
+	}
+
+	if err := w.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-w.out; e.Type != pms.FULL_RELOAD {
+		t.Errorf("expect full reload after changes are pruned, got %v", e.Type)
+	}
+
+	//the watcher goes on with the changes after the reload
+	if _, err := s.CreatePolicy("app1", newPolicy("p1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-w.out; e.Type != pms.POLICY_ADD {
+		t.Errorf("expect policy add, got %v", e.Type)
+	}
+	if len(w.out) != 0 {
+		t.Errorf("unexpected events %d", len(w.out))
+	}
+
+	//a new watcher doesn't reload
+	ch, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteService("app1"); err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.SERVICE_DELETE {
+		t.Errorf("expect service delete, got %v", e.Type)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if err := s.WriteTenant(&pms.Tenant{Name: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteTenant(&pms.Tenant{Name: "t1", Metadata: map[string]string{"k": "updated"}}); err != nil {
+		t.Fatal(err)
+	}
+	tenants, err := s.ReadTenants()
+	if err != nil || len(tenants) != 1 || tenants[0].Name != "t1" || tenants[0].Metadata["k"] != "updated" {
+		t.Fatalf("unexpected tenants %v, %v", tenants, err)
+	}
+	ps, err := s.NewTenantStore("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.(*Store).Close()
+	ch, err := ps.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "app0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.CreateService(&pms.Service{Name: "app1"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := receiveEvent(t, ch); e.Type != pms.SERVICE_ADD || e.Content.(*pms.Service).Name != "app1" {
+		t.Errorf("tenant store should only watch its own changes, got %v", e)
+	}
+	if _, err := s.GetService("app1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("the service of tenant should not be visible to default tenant, got %v", err)
+	}
+
+	if err := s.RemoveTenant("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsRemoved(ps) {
+		t.Error("tenant store should be reported as removed")
+	}
+	if _, err := ps.ListAllServices(); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if err := s.RemoveTenant("t1"); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("expect EntityNotFound, got %v", err)
+	}
+	if count, _ := s.GetServiceCount(); count != 1 {
+		t.Errorf("services of default tenant should be kept, got %d", count)
+	}
+}
+
+func TestPolicyPage(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	service := &pms.Service{Name: "s1", Type: pms.TypeApplication}
+	for i := 0; i < 5; i++ {
+		policy := newPolicy(fmt.Sprintf("p%d", i))
+		policy.ID = fmt.Sprintf("id%d", 4-i)
+		service.Policies = append(service.Policies, policy)
+		service.RolePolicies = append(service.RolePolicies, &pms.RolePolicy{ID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("rp%d", i), Effect: "grant", Roles: []string{"r"}})
+	}
+	if err := s.CreateService(service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+
+	policies, err := s.PolicyPage("s1", "", 2)
+	if err != nil || len(policies) != 2 || policies[0].ID != "id0" || policies[1].ID != "id1" {
+		t.Errorf("policies id0 and id1 should be read, got %v, error %v", policies, err)
+	}
+	policies, err = s.PolicyPage("s1", "id1", 10)
+	if err != nil || len(policies) != 3 || policies[0].ID != "id2" || policies[2].ID != "id4" {
+		t.Errorf("policies id2 to id4 should be read, got %v, error %v", policies, err)
+	}
+	rolePolicies, err := s.RolePolicyPage("s1", "id3", 10)
+	if err != nil || len(rolePolicies) != 1 || rolePolicies[0].ID != "id4" {
+		t.Errorf("role policy id4 should be read, got %v, error %v", rolePolicies, err)
+	}
+	if _, err := s.PolicyPage("nonexist", "", 10); errors.Code(err) != errors.EntityNotFound {
+		t.Errorf("error of nonexistent service should be EntityNotFound, got %v", err)
+	}
+
+	policies, token, err := store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "name eq p0 or name eq p1"})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id3" || len(token) == 0 {
+		t.Fatalf("policy id3 should be listed with token, got %v, token %q, error %v", policies, token, err)
+	}
+	policies, token, err = store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "name eq p0 or name eq p1", Continue: token})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id4" || len(token) != 0 {
+		t.Errorf("policy id4 should be listed in the last page, got %v, token %q, error %v", policies, token, err)
+	}
+}
+
+func TestServicePage(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	for _, name := range []string{"s3", "s1", "s2"} {
+		service := &pms.Service{Name: name, Type: pms.TypeApplication, Metadata: map[string]string{"owner": name},
+			Policies: []*pms.Policy{newPolicy("p1")}}
+		if err := s.CreateService(service); err != nil {
+			t.Fatal("fail to create service:", err)
+		}
+	}
+
+	services, err := s.ServicePage("s1", 1)
+	if err != nil || len(services) != 1 || services[0].Name != "s2" || services[0].Metadata["owner"] != "s2" || len(services[0].Policies) != 0 {
+		t.Errorf("service s2 should be read without policies, got %v, error %v", services, err)
+	}
+
+	services, token, err := store.ListServices(s, store.ListOptions{Limit: 1, Filter: "name ne s1"})
+	if err != nil || len(services) != 1 || services[0].Name != "s2" || len(services[0].Policies) != 1 || len(token) == 0 {
+		t.Fatalf("service s2 should be listed with its policies and token, got %v, token %q, error %v", services, token, err)
+	}
+	services, token, err = store.ListServices(s, store.ListOptions{Limit: 1, Filter: "name ne s1", Continue: token})
+	if err != nil || len(services) != 1 || services[0].Name != "s3" || len(token) != 0 {
+		t.Errorf("service s3 should be listed in the last page, got %v, token %q, error %v", services, token, err)
+	}
+}
+
+func TestFilteredPages(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	service := &pms.Service{Name: "s1", Type: pms.TypeApplication, Metadata: map[string]string{"owner": "bob"}}
+	for i := 0; i < 6; i++ {
+		policy := newPolicy(fmt.Sprintf("p%d", i))
+		policy.ID = fmt.Sprintf("id%d", i)
+		if i%2 == 1 {
+			policy.Effect = "deny"
+		}
+		if i < 4 {
+			policy.Metadata = map[string]string{"owner": fmt.Sprintf("user%d", i%3)}
+		}
+		service.Policies = append(service.Policies, policy)
+		service.RolePolicies = append(service.RolePolicies, &pms.RolePolicy{ID: policy.ID, Name: policy.Name, Effect: policy.Effect,
+			Roles: []string{"r"}, Metadata: policy.Metadata})
+	}
+	if err := s.CreateService(service); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if err := s.CreateService(&pms.Service{Name: "s2", Type: pms.TypeApplication, Metadata: map[string]string{"owner": "carol"}}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+
+	// The filters are done by the database, except the comparisons which can't be translated
+	filters := map[string][]string{
+		`effect eq deny`:                          {"id1", "id3", "id5"},
+		`effect eq deny and name ge p3`:           {"id3", "id5"},
+		`metadata.owner eq user1`:                 {"id1"},
+		`metadata.owner ne user1`:                 {"id0", "id2", "id3", "id4", "id5"},
+		`metadata.owner pr`:                       {"id0", "id1", "id2", "id3"},
+		`metadata.owner sw user and name co 2`:    {"id2"},
+		`name eq p0 or metadata.owner eq user2`:   {"id0", "id2"},
+		`effect eq grant and principal eq user:x`: {"id0", "id2", "id4"},
+		`not effect eq grant`:                     {"id0", "id1", "id2", "id3", "id4", "id5"},
+		`name eq p0 or principal eq user:x`:       {"id0", "id1", "id2", "id3", "id4", "id5"},
+	}
+	for expression, want := range filters {
+		filter, err := store.ParseFilter(expression)
+		if err != nil {
+			t.Fatalf("failed to parse filter %q: %v", expression, err)
+		}
+		policies, err := s.FilteredPolicyPage("s1", "", filter, 10)
+		var ids []string
+		for _, policy := range policies {
+			ids = append(ids, policy.ID)
+		}
+		if err != nil || !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: policies %v should be read, got %v, error %v", expression, want, ids, err)
+		}
+		rolePolicies, err := s.FilteredRolePolicyPage("s1", "id2", filter, 10)
+		ids = nil
+		for _, rolePolicy := range rolePolicies {
+			ids = append(ids, rolePolicy.ID)
+		}
+		if want = want[sort.SearchStrings(want, "id3"):]; len(want) == 0 {
+			want = nil
+		}
+		if err != nil || !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: role policies %v should be read, got %v, error %v", expression, want, ids, err)
+		}
+	}
+
+	policies, token, err := store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "effect eq deny and metadata.owner pr"})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id1" || len(token) == 0 {
+		t.Fatalf("policy id1 should be listed with token, got %v, token %q, error %v", policies, token, err)
+	}
+	policies, token, err = store.ListPolicies(s, "s1", store.ListOptions{Limit: 1, Filter: "effect eq deny and metadata.owner pr", Continue: token})
+	if err != nil || len(policies) != 1 || policies[0].ID != "id3" || len(token) != 0 {
+		t.Errorf("policy id3 should be listed in the last page, got %v, token %q, error %v", policies, token, err)
+	}
+
+	filter, _ := store.ParseFilter("metadata.owner eq carol")
+	services, err := s.FilteredServicePage("", filter, 10)
+	if err != nil || len(services) != 1 || services[0].Name != "s2" {
+		t.Errorf("service s2 should be read, got %v, error %v", services, err)
+	}
+}
+
+func TestSearchPolicies(t *testing.T) {
+	dataSource, clean := testDataSource(t)
+	defer clean()
+	s := openTestStore(t, dataSource)
+	defer s.Close()
+	//the changes made by another server sharing the database are found too
+	other := openTestStore(t, dataSource)
+	defer other.Close()
+	search := func() []string {
+		results, err := s.SearchPolicies(store.SearchQuery{Principal: "user:alice", Action: "read"})
+		if err != nil {
+			t.Fatal("fail to search policies:", err)
+		}
+		names := []string{}
+		for _, result := range results {
+			for _, policy := range result.Policies {
+				names = append(names, result.ServiceName+"/"+policy.Name)
+			}
+		}
+		return names
+	}
+
+	if err := s.CreateService(&pms.Service{Name: "app1", Policies: []*pms.Policy{newPolicy("p1")}}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if names := search(); !reflect.DeepEqual(names, []string{"app1/p1"}) {
+		t.Errorf("app1/p1 should be found, got %v", names)
+	}
+	policy, err := other.CreatePolicy("app1", newPolicy("p2"))
+	if err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	if err := other.CreateService(&pms.Service{Name: "app2", Policies: []*pms.Policy{newPolicy("p3")}}); err != nil {
+		t.Fatal("fail to create service:", err)
+	}
+	if names := search(); len(names) != 3 {
+		t.Errorf("3 policies should be found, got %v", names)
+	}
+	if err := other.DeletePolicy("app1", policy.ID); err != nil {
+		t.Fatal("fail to delete policy:", err)
+	}
+	if err := other.DeleteService("app2"); err != nil {
+		t.Fatal("fail to delete service:", err)
+	}
+	if names := search(); !reflect.DeepEqual(names, []string{"app1/p1"}) {
+		t.Errorf("only app1/p1 should be found, got %v", names)
+	}
+
+	//the index is built again if the changes are pruned before being applied
+	other.retention = time.Minute
+	if _, err := other.CreatePolicy("app1", newPolicy("p4")); err != nil {
+		t.Fatal("fail to create policy:", err)
+	}
+	err = other.update(func(tx *sql.Tx) error {
+		return other.pruneChanges(tx, time.Now().Add(2*time.Minute))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := search(); len(names) != 2 {
+		t.Errorf("2 policies should be found after the changes are pruned, got %v", names)
+	}
+}
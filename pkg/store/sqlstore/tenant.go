@@ -0,0 +1,84 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// ReadTenants reads all the stored tenants
+func (s *Store) ReadTenants() ([]*pms.Tenant, error) {
+	bodies, err := s.queryStrings(s.db, `SELECT body FROM `+TenantsTable+` ORDER BY name`)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to read tenants")
+	}
+	tenants := []*pms.Tenant{}
+	for _, body := range bodies {
+		var tenant pms.Tenant
+		if err := json.Unmarshal([]byte(body), &tenant); err != nil {
+			return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal tenant %q", body)
+		}
+		tenants = append(tenants, &tenant)
+	}
+	return tenants, nil
+}
+
+// WriteTenant creates or updates a tenant
+func (s *Store) WriteTenant(tenant *pms.Tenant) error {
+	body, err := json.Marshal(tenant)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal tenant")
+	}
+	err = s.update(func(tx *sql.Tx) error {
+		result, err := s.exec(tx, `UPDATE `+TenantsTable+` SET body = ? WHERE name = ?`, string(body), tenant.Name)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		_, err = s.exec(tx, `INSERT INTO `+TenantsTable+` (name, body) VALUES (?, ?)`, tenant.Name, string(body))
+		return err
+	})
+	return wrapStoreError(err, "unable to write tenant %q", tenant.Name)
+}
+
+// RemoveTenant deletes a tenant together with all its policies and discover requests
+func (s *Store) RemoveTenant(tenantName string) error {
+	err := s.update(func(tx *sql.Tx) error {
+		result, err := s.exec(tx, `DELETE FROM `+TenantsTable+` WHERE name = ?`, tenantName)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.Errorf(errors.EntityNotFound, "tenant %q is not found", tenantName)
+		}
+		for _, table := range []string{ServicesTable, PoliciesTable, RolePoliciesTable, FunctionsTable,
+			ChangesTable, ChangeWatermarkTable, DiscoverRequestsTable} {
+			if _, err := s.exec(tx, `DELETE FROM `+table+` WHERE tenant = ?`, tenantName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wrapStoreError(err, "unable to delete tenant %q", tenantName)
+}
+
+// NewTenantStore creates the store of a tenant, which shares the database but only reads and writes the rows of
+// the tenant
+func (s *Store) NewTenantStore(tenantName string) (pms.PolicyStoreManager, error) {
+	return &Store{
+		db:           s.db,
+		dialect:      s.dialect,
+		tenant:       tenantName,
+		pollInterval: s.pollInterval,
+		retention:    s.retention,
+	}, nil
+}
@@ -0,0 +1,276 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// changesPageSize is the max number of changes read from the change log by one query
+const changesPageSize = 500
+
+// logChange appends a change to the change log. content is the content of the event, for policy and role policy
+// events it's the list of the policies or role policies of service serviceName.
+func (s *Store) logChange(tx *sql.Tx, eventType pms.EventType, serviceName string, content interface{}) error {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return errors.Wrap(err, errors.SerializationError, "failed to marshal change")
+	}
+	now := time.Now()
+	_, err = s.exec(tx, `INSERT INTO `+ChangesTable+` (tenant, type, service, body, created_at) VALUES (?, ?, ?, ?, ?)`,
+		s.tenant, int(eventType), serviceName, string(body), now.Unix())
+	if err != nil {
+		return err
+	}
+	return s.pruneChanges(tx, now)
+}
+
+// pruneChanges deletes the changes older than the retention, at most once per tenth of the retention. The last
+// pruned revision is recorded, so watchers falling behind it know they have missed changes.
+func (s *Store) pruneChanges(tx *sql.Tx, now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+	if now.Sub(s.lastPrune) < s.retention/10 {
+		return nil
+	}
+	//the prune time is updated even if the transaction is rolled back later, the changes are pruned next time
+	s.lastPrune = now
+
+	pruned, err := s.queryInt(tx, `SELECT MAX(revision) FROM `+ChangesTable+` WHERE tenant = ? AND created_at < ?`,
+		s.tenant, now.Add(-s.retention).Unix())
+	if err != nil || pruned == 0 {
+		return err
+	}
+	if _, err := s.exec(tx, `DELETE FROM `+ChangesTable+` WHERE tenant = ? AND revision <= ?`, s.tenant, pruned); err != nil {
+		return err
+	}
+	result, err := s.exec(tx, `UPDATE `+ChangeWatermarkTable+` SET pruned_revision = ? WHERE tenant = ?`, pruned, s.tenant)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = s.exec(tx, `INSERT INTO `+ChangeWatermarkTable+` (tenant, pruned_revision) VALUES (?, ?)`, s.tenant, pruned)
+	return err
+}
+
+// prunedRevision returns the last revision pruned from the change log
+func (s *Store) prunedRevision(q queryer) (int64, error) {
+	return s.queryInt(q, `SELECT MAX(pruned_revision) FROM `+ChangeWatermarkTable+` WHERE tenant = ?`, s.tenant)
+}
+
+// currentRevision returns the latest revision of the change log. Revisions are shared by all tenants.
+func (s *Store) currentRevision(q queryer) (int64, error) {
+	revision, err := s.queryInt(q, `SELECT MAX(revision) FROM `+ChangesTable)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := s.prunedRevision(q)
+	if err != nil {
+		return 0, err
+	}
+	if pruned > revision {
+		revision = pruned
+	}
+	return revision, nil
+}
+
+// readChanges reads the events of the changes after revision
+func (s *Store) readChanges(revision int64) ([]pms.StoreChangeEvent, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`SELECT revision, type, service, body FROM `+ChangesTable+
+		` WHERE tenant = ? AND revision > ? ORDER BY revision LIMIT ?`), s.tenant, revision, changesPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []pms.StoreChangeEvent
+	for rows.Next() {
+		var eventType int
+		var serviceName string
+		var body []byte
+		event := pms.StoreChangeEvent{}
+		if err := rows.Scan(&event.ID, &eventType, &serviceName, &body); err != nil {
+			return nil, err
+		}
+		event.Type = pms.EventType(eventType)
+		if event.Content, err = decodeChange(event.Type, serviceName, body); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// decodeChange decodes the content of an event from the body of a change
+func decodeChange(eventType pms.EventType, serviceName string, body []byte) (interface{}, error) {
+	var err error
+	switch eventType {
+	case pms.SERVICE_ADD:
+		var service pms.Service
+		err = json.Unmarshal(body, &service)
+		if err == nil {
+			return &service, nil
+		}
+	case pms.SERVICE_DELETE, pms.FUNCTION_DELETE:
+		var names []string
+		err = json.Unmarshal(body, &names)
+		if err == nil {
+			return names, nil
+		}
+	case pms.POLICY_ADD, pms.POLICY_DELETE:
+		var policies []*pms.Policy
+		err = json.Unmarshal(body, &policies)
+		if err == nil {
+			data := make([]pms.StoreUpdateData, 0, len(policies))
+			for _, policy := range policies {
+				data = append(data, pms.StoreUpdateData{ServiceName: serviceName, Data: policy})
+			}
+			return data, nil
+		}
+	case pms.ROLEPOLICY_ADD, pms.ROLEPOLICY_DELETE:
+		var rolePolicies []*pms.RolePolicy
+		err = json.Unmarshal(body, &rolePolicies)
+		if err == nil {
+			data := make([]pms.StoreUpdateData, 0, len(rolePolicies))
+			for _, rolePolicy := range rolePolicies {
+				data = append(data, pms.StoreUpdateData{ServiceName: serviceName, Data: rolePolicy})
+			}
+			return data, nil
+		}
+	case pms.FUNCTION_ADD:
+		var function pms.Function
+		err = json.Unmarshal(body, &function)
+		if err == nil {
+			return &function, nil
+		}
+	default:
+		return nil, nil
+	}
+	return nil, errors.Wrapf(err, errors.SerializationError, "failed to unmarshal change %q", body)
+}
+
+// Watch returns a channel of the changes made to the store since Watch is called, including the changes made by
+// other servers sharing the database. The change log is polled every poll interval.
+func (s *Store) Watch() (pms.StorageChangeChannel, error) {
+	log.Info("Entering Watch...")
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	revision, err := s.currentRevision(s.db)
+	if err != nil {
+		return nil, wrapStoreError(err, "unable to read the revision of the change log")
+	}
+	if s.watcher != nil {
+		s.watcher.close()
+	}
+	s.watcher = &watcher{
+		store:    s,
+		revision: revision,
+		out:      make(chan pms.StoreChangeEvent),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.watcher.run()
+	return s.watcher.out, nil
+}
+
+func (s *Store) StopWatch() {
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	if s.watcher != nil {
+		log.Info("Exiting Watch...")
+		s.watcher.close()
+		s.watcher = nil
+	}
+}
+
+// watcher polls the change log, and delivers the changes after revision to out
+type watcher struct {
+	store    *Store
+	revision int64
+	out      chan pms.StoreChangeEvent
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (w *watcher) run() {
+	defer func() {
+		close(w.out)
+		close(w.done)
+	}()
+	ticker := time.NewTicker(w.store.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(); err != nil {
+			log.Errorf("failed to poll the change log of SQL store: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// poll delivers the changes logged since the last poll. A full reload is requested if some changes are pruned
+// before being delivered.
+func (w *watcher) poll() error {
+	for {
+		events, err := w.store.readChanges(w.revision)
+		if err != nil {
+			return err
+		}
+		//the watermark is read after the changes, so the changes pruned while reading are noticed
+		pruned, err := w.store.prunedRevision(w.store.db)
+		if err != nil {
+			return err
+		}
+		if pruned > w.revision {
+			log.Warningf("changes after revision %d are pruned, reloading all policies", w.revision)
+			revision, err := w.store.currentRevision(w.store.db)
+			if err != nil {
+				return err
+			}
+			if !w.send(pms.StoreChangeEvent{Type: pms.FULL_RELOAD, ID: revision}) {
+				return nil
+			}
+			w.revision = revision
+			continue
+		}
+
+		for _, event := range events {
+			if !w.send(event) {
+				return nil
+			}
+			w.revision = event.ID
+		}
+		if len(events) < changesPageSize {
+			return nil
+		}
+	}
+}
+
+// send returns false if the watcher is stopped
+func (w *watcher) send(event pms.StoreChangeEvent) bool {
+	select {
+	case w.out <- event:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+func (w *watcher) close() {
+	close(w.stop)
+	<-w.done
+}
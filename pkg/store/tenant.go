@@ -0,0 +1,183 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package store
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+var tenantNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// TenantBackend is implemented by the policy stores which support multi-tenancy.
+// It persists tenants, and creates the isolated policy store of each tenant.
+type TenantBackend interface {
+	// ReadTenants reads all the stored tenants, the default one is only stored once it is updated
+	ReadTenants() ([]*pms.Tenant, error)
+	// WriteTenant creates or updates a tenant
+	WriteTenant(tenant *pms.Tenant) error
+	// RemoveTenant deletes a tenant together with all its policies and discover requests
+	RemoveTenant(tenantName string) error
+	// NewTenantStore creates the policy store of a tenant
+	NewTenantStore(tenantName string) (pms.PolicyStoreManager, error)
+}
+
+type tenantManager struct {
+	sync.RWMutex
+	defaultStore pms.PolicyStoreManager
+	backend      TenantBackend
+	stores       map[string]pms.PolicyStoreManager
+}
+
+// NewTenantManager creates a tenant manager on top of policy store ps, which is the store of the default tenant
+func NewTenantManager(ps pms.PolicyStoreManager) (pms.TenantManager, error) {
+	backend, ok := ps.(TenantBackend)
+	if !ok {
+		return nil, errors.Errorf(errors.ConfigError, "%q policy store doesn't support multi-tenancy", ps.Type())
+	}
+	return &tenantManager{
+		defaultStore: ps,
+		backend:      backend,
+		stores:       make(map[string]pms.PolicyStoreManager),
+	}, nil
+}
+
+// ValidateTenantName checks whether name can be used as a tenant name, it must be usable in URL, file name and etcd key
+func ValidateTenantName(name string) error {
+	if !tenantNameRegexp.MatchString(name) {
+		return errors.Errorf(errors.InvalidRequest, "invalid tenant name %q, it should consist of at most 64 letters, digits, '_', '.' or '-', and start with a letter or digit", name)
+	}
+	return nil
+}
+
+func (m *tenantManager) CreateTenant(tenant *pms.Tenant) error {
+	if tenant == nil {
+		return errors.New(errors.InvalidRequest, "tenant is nil")
+	}
+	if err := ValidateTenantName(tenant.Name); err != nil {
+		return err
+	}
+	if tenant.Name == pms.DefaultTenant {
+		return errors.Errorf(errors.EntityAlreadyExists, "tenant %q already exists", tenant.Name)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if _, err := m.getTenantWithoutLock(tenant.Name); err == nil {
+		return errors.Errorf(errors.EntityAlreadyExists, "tenant %q already exists", tenant.Name)
+	}
+	return m.backend.WriteTenant(tenant)
+}
+
+func (m *tenantManager) DeleteTenant(tenantName string) error {
+	if tenantName == pms.DefaultTenant {
+		return errors.Errorf(errors.InvalidRequest, "tenant %q can not be deleted", tenantName)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if _, err := m.getTenantWithoutLock(tenantName); err != nil {
+		return err
+	}
+	if err := m.backend.RemoveTenant(tenantName); err != nil {
+		return err
+	}
+	delete(m.stores, tenantName)
+	return nil
+}
+
+// UpdateTenant updates a tenant, the quota and metadata of the default tenant are stored like the ones of
+// the other tenants, e.g. the global quota changed at runtime
+func (m *tenantManager) UpdateTenant(tenant *pms.Tenant) error {
+	if tenant == nil {
+		return errors.New(errors.InvalidRequest, "tenant is nil")
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if _, err := m.getTenantWithoutLock(tenant.Name); err != nil {
+		return err
+	}
+	return m.backend.WriteTenant(tenant)
+}
+
+func (m *tenantManager) GetTenant(tenantName string) (*pms.Tenant, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.getTenantWithoutLock(tenantName)
+}
+
+// getTenantWithoutLock reads a tenant from the backend, the default tenant always exists even if it's not stored
+func (m *tenantManager) getTenantWithoutLock(tenantName string) (*pms.Tenant, error) {
+	tenants, err := m.backend.ReadTenants()
+	if err != nil {
+		return nil, err
+	}
+	for _, tenant := range tenants {
+		if tenant.Name == tenantName {
+			return tenant, nil
+		}
+	}
+	if tenantName == pms.DefaultTenant {
+		return &pms.Tenant{Name: pms.DefaultTenant}, nil
+	}
+	return nil, errors.Errorf(errors.EntityNotFound, "tenant %q is not found", tenantName)
+}
+
+func (m *tenantManager) ListAllTenants() ([]*pms.Tenant, error) {
+	m.RLock()
+	defer m.RUnlock()
+	stored, err := m.backend.ReadTenants()
+	if err != nil {
+		return nil, err
+	}
+	defaultTenant := &pms.Tenant{Name: pms.DefaultTenant}
+	tenants := make([]*pms.Tenant, 0, len(stored))
+	for _, tenant := range stored {
+		if tenant.Name == pms.DefaultTenant {
+			defaultTenant = tenant
+		} else {
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Name < tenants[j].Name })
+	return append([]*pms.Tenant{defaultTenant}, tenants...), nil
+}
+
+func (m *tenantManager) TenantStore(tenantName string) (pms.PolicyStoreManager, error) {
+	if len(tenantName) == 0 || tenantName == pms.DefaultTenant {
+		return m.defaultStore, nil
+	}
+
+	m.RLock()
+	ps, ok := m.stores[tenantName]
+	m.RUnlock()
+	if ok && !IsRemoved(ps) {
+		return ps, nil
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if ps, ok := m.stores[tenantName]; ok {
+		if !IsRemoved(ps) {
+			return ps, nil
+		}
+		// The tenant may be deleted by another process sharing the same store
+		delete(m.stores, tenantName)
+	}
+	// The tenant may be created by another process sharing the same store, so always check the backend
+	if _, err := m.getTenantWithoutLock(tenantName); err != nil {
+		return nil, err
+	}
+	ps, err := m.backend.NewTenantStore(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	m.stores[tenantName] = ps
+	return ps, nil
+}
@@ -10,6 +10,8 @@ import (
 	adsPB "github.com/oracle/speedle/pkg/svcs/adsgrpc/pb"
 	pmsPB "github.com/oracle/speedle/pkg/svcs/pmsgrpc/pb"
 	"github.com/oracle/speedle/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var POLICY_RELOAD_TIME = 500 //ms
@@ -925,3 +927,35 @@ func TestMats_GRpc_GetAllGrantedPermission(t *testing.T) {
 	}
 	testutil.RunTestCases(t, data, nil)
 }
+
+func TestConvertObjectAttributes(t *testing.T) {
+	reqCtx, err := convertGRPCContextRequest(&adsPB.ContextRequest{
+		ServiceName:      "srv",
+		Attributes:       map[string]string{"env": "prod"},
+		ObjectAttributes: map[string]string{"resource": `{"labels": {"env": "prod"}}`},
+	})
+	if err != nil {
+		t.Fatal("failed to convert object attributes:", err)
+	}
+	resource, ok := reqCtx.Attributes["resource"].(map[string]interface{})
+	if !ok || resource["labels"].(map[string]interface{})["env"] != "prod" || reqCtx.Attributes["env"] != "prod" {
+		t.Errorf("unexpected attributes %v", reqCtx.Attributes)
+	}
+
+	for _, value := range []string{`["prod"]`, `null`, `{`} {
+		_, err := convertGRPCContextRequest(&adsPB.ContextRequest{
+			ObjectAttributes: map[string]string{"resource": value},
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s should not be an object attribute, got %v", value, err)
+		}
+	}
+
+	_, err = convertGRPCContextRequest(&adsPB.ContextRequest{
+		Attributes:       map[string]string{"resource": "/node1"},
+		ObjectAttributes: map[string]string{"resource": `{"labels": {"env": "prod"}}`},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("attribute resource should not be both a string and an object attribute, got %v", err)
+	}
+}
@@ -5,12 +5,15 @@ package adsgrpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	adsapi "github.com/oracle/speedle/api/ads"
 	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/pkg/eval"
 	"github.com/oracle/speedle/pkg/svcs/adsgrpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/oracle/speedle/pkg/logging"
 )
@@ -28,7 +31,18 @@ func NewGRPCService(evaluator eval.InternalEvaluator) (*GRPCService, error) {
 	}, nil
 }
 
-func convertGRPCContextRequest(context *pb.ContextRequest) *adsapi.RequestContext {
+// evaluatorOf returns the evaluator resolved by the tenant interceptor, calls out of any tenant scope
+// are evaluated by the evaluator of the default tenant
+func (impl *GRPCService) evaluatorOf(ctx context.Context) eval.InternalEvaluator {
+	if evaluator, ok := eval.EvaluatorFrom(ctx); ok {
+		return evaluator
+	}
+	return impl.evaluator
+}
+
+// convertGRPCContextRequest converts the request, object attributes must be JSON objects, and must not be named
+// like the string attributes
+func convertGRPCContextRequest(context *pb.ContextRequest) (*adsapi.RequestContext, error) {
 	ret := adsapi.RequestContext{
 		Subject:     convertGRPCSubject(context.Subject),
 		ServiceName: context.ServiceName,
@@ -36,14 +50,24 @@ func convertGRPCContextRequest(context *pb.ContextRequest) *adsapi.RequestContex
 		Action:      context.Action,
 	}
 
-	if context.Attributes == nil {
-		return &ret
+	if context.Attributes == nil && context.ObjectAttributes == nil {
+		return &ret, nil
 	}
 	ret.Attributes = make(map[string]interface{})
 	for k, v := range context.Attributes {
 		ret.Attributes[k] = v
 	}
-	return &ret
+	for k, v := range context.ObjectAttributes {
+		if _, ok := context.Attributes[k]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "attribute %q is both a string and an object attribute", k)
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &object); err != nil || object == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "object attribute %q is not a JSON object", k)
+		}
+		ret.Attributes[k] = object
+	}
+	return &ret, nil
 }
 
 func convertGRPCPrincipals(principals []*pb.Principal) []*adsapi.Principal {
@@ -75,12 +99,16 @@ func convertGRPCSubject(subject *pb.Subject) *adsapi.Subject {
 }
 
 func (impl *GRPCService) IsAllowed(ctx context.Context, in *pb.ContextRequest) (*pb.IsAllowedResponse, error) {
-	reqCtx := convertGRPCContextRequest(in)
+	reqCtx, err := convertGRPCContextRequest(in)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert token
-	impl.evaluator.AssertToken(reqCtx)
+	evaluator := impl.evaluatorOf(ctx)
+	evaluator.AssertToken(reqCtx)
 
-	allowed, reason, err := impl.evaluator.IsAllowed(*reqCtx)
+	allowed, reason, err := evaluator.IsAllowed(*reqCtx)
 	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]IsAllowed", reqCtx, err.Error())
@@ -99,12 +127,16 @@ func (impl *GRPCService) IsAllowed(ctx context.Context, in *pb.ContextRequest) (
 }
 
 func (impl *GRPCService) GetAllGrantedRoles(ctx context.Context, in *pb.ContextRequest) (*pb.AllRoleResponse, error) {
-	reqCtx := convertGRPCContextRequest(in)
+	reqCtx, err := convertGRPCContextRequest(in)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert token
-	impl.evaluator.AssertToken(reqCtx)
+	evaluator := impl.evaluatorOf(ctx)
+	evaluator.AssertToken(reqCtx)
 
-	roles, err := impl.evaluator.GetAllGrantedRoles(*reqCtx)
+	roles, err := evaluator.GetAllGrantedRoles(*reqCtx)
 	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]GetAllGrantedRoles", reqCtx, err.Error())
@@ -120,12 +152,16 @@ func (impl *GRPCService) GetAllGrantedRoles(ctx context.Context, in *pb.ContextR
 }
 
 func (impl *GRPCService) GetAllPermissions(ctx context.Context, in *pb.ContextRequest) (*pb.AllPermissionResponse, error) {
-	reqCtx := convertGRPCContextRequest(in)
+	reqCtx, err := convertGRPCContextRequest(in)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert token
-	impl.evaluator.AssertToken(reqCtx)
+	evaluator := impl.evaluatorOf(ctx)
+	evaluator.AssertToken(reqCtx)
 
-	perms, err := impl.evaluator.GetAllGrantedPermissions(*reqCtx)
+	perms, err := evaluator.GetAllGrantedPermissions(*reqCtx)
 	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]GetAllGrantedPermissions", reqCtx, err.Error())
@@ -149,12 +185,16 @@ func (impl *GRPCService) GetAllPermissions(ctx context.Context, in *pb.ContextRe
 }
 
 func (impl *GRPCService) Discover(ctx context.Context, in *pb.ContextRequest) (*pb.IsAllowedResponse, error) {
-	reqCtx := convertGRPCContextRequest(in)
+	reqCtx, err := convertGRPCContextRequest(in)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert token
-	impl.evaluator.AssertToken(reqCtx)
+	evaluator := impl.evaluatorOf(ctx)
+	evaluator.AssertToken(reqCtx)
 
-	allowed, reason, err := impl.evaluator.Discover(*reqCtx)
+	allowed, reason, err := evaluator.Discover(*reqCtx)
 	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]Discovery", reqCtx, err.Error())
@@ -324,12 +364,16 @@ func convertAPIRolePolicy2EvaluatedRolePolicyResponse(apiRolePolicy *adsapi.Eval
 }
 
 func (impl *GRPCService) Diagnose(ctx context.Context, in *pb.ContextRequest) (*pb.EvaluationDebugResponse, error) {
-	reqCtx := convertGRPCContextRequest(in)
+	reqCtx, err := convertGRPCContextRequest(in)
+	if err != nil {
+		return nil, err
+	}
 
 	// assert token
-	impl.evaluator.AssertToken(reqCtx)
+	evaluator := impl.evaluatorOf(ctx)
+	evaluator.AssertToken(reqCtx)
 
-	evaResult, err := impl.evaluator.Diagnose(*reqCtx)
+	evaResult, err := evaluator.Diagnose(*reqCtx)
 	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]Diagnose", reqCtx, err.Error())
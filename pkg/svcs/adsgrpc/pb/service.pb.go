@@ -114,6 +114,8 @@ type ContextRequest struct {
 	Resource    string            `protobuf:"bytes,3,opt,name=resource" json:"resource,omitempty"`
 	Action      string            `protobuf:"bytes,4,opt,name=action" json:"action,omitempty"`
 	Attributes  map[string]string `protobuf:"bytes,5,rep,name=attributes" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// objectAttributes are the object attributes in JSON like {"labels": {"env": "prod"}}
+	ObjectAttributes map[string]string `protobuf:"bytes,6,rep,name=objectAttributes" json:"objectAttributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *ContextRequest) Reset()                    { *m = ContextRequest{} }
@@ -156,6 +158,13 @@ func (m *ContextRequest) GetAttributes() map[string]string {
 	return nil
 }
 
+func (m *ContextRequest) GetObjectAttributes() map[string]string {
+	if m != nil {
+		return m.ObjectAttributes
+	}
+	return nil
+}
+
 type IsAllowedResponse struct {
 	Allowed bool   `protobuf:"varint,1,opt,name=allowed" json:"allowed,omitempty"`
 	Reason  int32  `protobuf:"varint,2,opt,name=reason" json:"reason,omitempty"`
@@ -904,62 +913,64 @@ var _Evaluator_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 904 bytes of a gzipped FileDescriptorProto
+	// 929 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x56, 0xdd, 0x8e, 0xdb, 0x44,
-	0x14, 0x4e, 0x9c, 0xc4, 0x89, 0x4f, 0xba, 0xbb, 0xdd, 0x49, 0xbb, 0x35, 0x01, 0x55, 0xab, 0x11,
-	0x88, 0x0a, 0x89, 0x14, 0x02, 0x52, 0xab, 0xa2, 0x0a, 0xd2, 0x26, 0x54, 0x7b, 0x01, 0x8a, 0x5c,
-	0x6e, 0xb9, 0x70, 0x92, 0x69, 0x64, 0xea, 0x7a, 0xcc, 0xcc, 0x78, 0x69, 0xde, 0x82, 0x5b, 0xae,
-	0x11, 0x2f, 0x82, 0x78, 0x1c, 0x6e, 0x78, 0x03, 0x34, 0x3f, 0xb6, 0xc7, 0xb1, 0xc3, 0xee, 0x4a,
-	0x20, 0xee, 0x7c, 0xce, 0x9c, 0x39, 0x7f, 0xdf, 0x77, 0xe6, 0x18, 0x8e, 0x38, 0x61, 0x97, 0xd1,
-	0x9a, 0x4c, 0x52, 0x46, 0x05, 0x45, 0x4e, 0xba, 0xc2, 0x0b, 0xf0, 0x96, 0x2c, 0x4a, 0xd6, 0x51,
-	0x1a, 0xc6, 0x08, 0x41, 0x57, 0xec, 0x52, 0xe2, 0xb7, 0xcf, 0xdb, 0x0f, 0xbc, 0x40, 0x7d, 0x4b,
-	0x5d, 0x12, 0xbe, 0x21, 0xbe, 0xa3, 0x75, 0xf2, 0x1b, 0xdd, 0x86, 0x4e, 0xb4, 0xd9, 0xf8, 0x1d,
-	0xa5, 0x92, 0x9f, 0x38, 0x86, 0xfe, 0xcb, 0x6c, 0xf5, 0x03, 0x59, 0x0b, 0xf4, 0x31, 0x40, 0x9a,
-	0x7b, 0xe4, 0x7e, 0xfb, 0xbc, 0xf3, 0x60, 0x38, 0x3d, 0x9a, 0xa4, 0xab, 0x49, 0x11, 0x27, 0xb0,
-	0x0c, 0xd0, 0x7b, 0xe0, 0x09, 0xfa, 0x9a, 0x24, 0xdf, 0xc9, 0xc0, 0x3a, 0x48, 0xa9, 0x40, 0x77,
-	0xa0, 0xa7, 0x04, 0x13, 0x4b, 0x0b, 0xf8, 0x67, 0x07, 0x8e, 0x9f, 0xd3, 0x44, 0x90, 0xb7, 0x22,
-	0x20, 0x3f, 0x66, 0x84, 0x0b, 0xf4, 0x01, 0xf4, 0xb9, 0x4e, 0x40, 0x65, 0x3f, 0x9c, 0x0e, 0x65,
-	0x48, 0x93, 0x53, 0x90, 0x9f, 0xa1, 0x73, 0x18, 0x9a, 0x1e, 0x7c, 0x5b, 0x16, 0x65, 0xab, 0xd0,
-	0x18, 0x06, 0x8c, 0x70, 0x9a, 0xb1, 0x35, 0x31, 0x41, 0x0b, 0x19, 0x9d, 0x81, 0x1b, 0xae, 0x45,
-	0x44, 0x13, 0xbf, 0xab, 0x4e, 0x8c, 0x84, 0x9e, 0x01, 0x84, 0x42, 0xb0, 0x68, 0x95, 0x09, 0xc2,
-	0xfd, 0x9e, 0x2a, 0x19, 0xcb, 0xf8, 0xd5, 0x24, 0x27, 0xb3, 0xc2, 0x68, 0x91, 0x08, 0xb6, 0x0b,
-	0xac, 0x5b, 0xe3, 0xa7, 0x70, 0xb2, 0x77, 0x2c, 0xdb, 0xfc, 0x9a, 0xec, 0x0c, 0x1a, 0xf2, 0x53,
-	0xb6, 0xe3, 0x32, 0x8c, 0xb3, 0x3c, 0x71, 0x2d, 0x3c, 0x71, 0x1e, 0xb7, 0xf1, 0xf7, 0x70, 0x7a,
-	0xc1, 0x67, 0x71, 0x4c, 0x7f, 0x22, 0x9b, 0x80, 0xf0, 0x94, 0x26, 0x9c, 0x20, 0x1f, 0xfa, 0xa1,
-	0x56, 0x29, 0x27, 0x83, 0x20, 0x17, 0x65, 0x25, 0x8c, 0x84, 0x9c, 0x26, 0xca, 0x53, 0x2f, 0x30,
-	0x92, 0xd4, 0x13, 0xc6, 0xbe, 0xe1, 0x5b, 0x53, 0xbb, 0x91, 0xf0, 0x43, 0x38, 0x9a, 0x25, 0x9b,
-	0x65, 0x09, 0xdb, 0xfd, 0x1a, 0xca, 0x9e, 0x0d, 0x2b, 0xfe, 0xb3, 0x0d, 0x10, 0xd0, 0x98, 0x2c,
-	0x69, 0x1c, 0xad, 0x77, 0xe8, 0x18, 0x9c, 0x8b, 0xb9, 0xa9, 0xc4, 0xb9, 0x98, 0x4b, 0x56, 0x59,
-	0x00, 0xa8, 0x6f, 0x19, 0x7b, 0xf1, 0xea, 0x95, 0x44, 0xd0, 0xc4, 0xd6, 0x92, 0x2c, 0x5a, 0x7a,
-	0xe2, 0x7e, 0x57, 0x45, 0xd1, 0x82, 0x4c, 0xa0, 0x4c, 0x47, 0xf5, 0xdc, 0x0b, 0x2c, 0x8d, 0xe4,
-	0x55, 0x60, 0x70, 0xe3, 0xbe, 0xab, 0x8e, 0x4b, 0x05, 0xfa, 0x04, 0x46, 0xb9, 0xb0, 0x78, 0x9b,
-	0x32, 0xc2, 0x79, 0x44, 0x13, 0xee, 0xf7, 0x95, 0x5d, 0xd3, 0x91, 0xf4, 0xf7, 0x9c, 0x26, 0x9b,
-	0x48, 0xc1, 0x3f, 0xd0, 0x3c, 0x2d, 0x14, 0xf8, 0x77, 0x07, 0xdc, 0x7f, 0xa1, 0xd4, 0x47, 0x30,
-	0x4c, 0x09, 0x7b, 0x13, 0x99, 0x74, 0xba, 0x8a, 0x49, 0x77, 0xd5, 0xf0, 0x28, 0xe7, 0x93, 0x65,
-	0x71, 0x1a, 0xd8, 0x96, 0xe8, 0xd3, 0x5a, 0x37, 0x86, 0xd3, 0x53, 0x79, 0xaf, 0x82, 0xda, 0x7e,
-	0x83, 0xca, 0x82, 0xdc, 0xbd, 0x82, 0xc6, 0x0c, 0xa0, 0x8c, 0x55, 0x19, 0x8a, 0xf6, 0xde, 0x50,
-	0x4c, 0x00, 0xb1, 0x5a, 0xbf, 0x4c, 0xb5, 0x0d, 0x27, 0x8a, 0x94, 0x6a, 0x6c, 0xb8, 0xdf, 0x51,
-	0xed, 0xce, 0x45, 0xcc, 0x00, 0x2d, 0x24, 0xa3, 0x43, 0x41, 0x36, 0x45, 0x26, 0x12, 0xaa, 0x42,
-	0xb0, 0x02, 0xe8, 0x34, 0x9a, 0x8e, 0xd0, 0x47, 0x70, 0xdb, 0xf8, 0x91, 0x7d, 0x22, 0x3c, 0x8b,
-	0x85, 0xc9, 0xa7, 0xa6, 0xc7, 0xbf, 0x39, 0x30, 0x2a, 0x82, 0x5a, 0x84, 0x3d, 0x03, 0xf7, 0xa5,
-	0x08, 0x45, 0xc6, 0x4d, 0x20, 0x23, 0x19, 0x74, 0x9d, 0x1a, 0xba, 0x9d, 0x46, 0x74, 0xbb, 0xcd,
-	0x44, 0xee, 0x1d, 0x26, 0xb2, 0xfb, 0xcf, 0x44, 0xee, 0x5f, 0x93, 0xc8, 0x83, 0xc3, 0x44, 0xfe,
-	0xdc, 0xc6, 0xdd, 0x53, 0x6f, 0xe5, 0x99, 0x64, 0x4a, 0xbd, 0xf5, 0x36, 0xc1, 0xff, 0x72, 0xe0,
-	0xa4, 0xb0, 0xf8, 0x0f, 0x7b, 0xf4, 0x55, 0x75, 0x02, 0x34, 0x93, 0xef, 0x57, 0xf2, 0xbb, 0x62,
-	0x14, 0xae, 0xea, 0x67, 0xa5, 0xfe, 0xfe, 0x35, 0xeb, 0xff, 0x5f, 0xe6, 0xe1, 0x17, 0x07, 0xee,
-	0x95, 0x84, 0x9d, 0x93, 0x55, 0xb6, 0xbd, 0xf1, 0xd3, 0xee, 0x15, 0x4f, 0xfb, 0x13, 0x38, 0x66,
-	0x7a, 0x0f, 0x99, 0xad, 0xa4, 0xf0, 0x18, 0x4e, 0x51, 0x7d, 0x51, 0x05, 0x7b, 0x96, 0x08, 0xc3,
-	0xad, 0x2d, 0x0b, 0x13, 0x33, 0x22, 0xf9, 0x4b, 0x5c, 0xd1, 0xa1, 0x2f, 0xe0, 0x16, 0xcb, 0xe7,
-	0x27, 0x2a, 0xd6, 0xe0, 0xbd, 0x4a, 0x6b, 0xcb, 0x01, 0x0b, 0x2a, 0xc6, 0xe8, 0x21, 0x0c, 0xd2,
-	0xfc, 0xa2, 0xab, 0x2e, 0x8e, 0x1a, 0x30, 0x0f, 0x0a, 0x23, 0xfc, 0x21, 0x9c, 0xcc, 0xe2, 0x58,
-	0xfa, 0x2b, 0x5a, 0x72, 0x07, 0x7a, 0x4c, 0x65, 0xa7, 0xb7, 0x91, 0x16, 0xf0, 0xaf, 0x6d, 0xb8,
-	0x3b, 0x8b, 0x63, 0x8b, 0x2d, 0xb9, 0xfd, 0xd7, 0x55, 0xaa, 0xe9, 0x3f, 0x95, 0xf7, 0xd5, 0xa3,
-	0xd9, 0x64, 0x7f, 0x88, 0x70, 0xe3, 0x67, 0xd7, 0xa6, 0x86, 0x05, 0xb5, 0x53, 0x81, 0x7a, 0xfa,
-	0x87, 0x03, 0x9e, 0x29, 0x96, 0x32, 0xf4, 0x18, 0xbc, 0x62, 0x99, 0xa3, 0x06, 0x7c, 0xc6, 0x6a,
-	0x25, 0xd4, 0xf6, 0x3d, 0x6e, 0xa1, 0x2f, 0x01, 0xbd, 0x20, 0x62, 0x16, 0xc7, 0x2f, 0x6c, 0x68,
-	0x9a, 0x5c, 0x8c, 0x4c, 0xa1, 0x76, 0x0b, 0x71, 0x0b, 0xcd, 0xe1, 0x54, 0x3b, 0x58, 0x5a, 0x23,
-	0xd5, 0x74, 0xff, 0x9d, 0x83, 0x8d, 0xc2, 0x2d, 0xf4, 0x08, 0x06, 0xf3, 0x88, 0xaf, 0xe9, 0x25,
-	0x61, 0x37, 0xcb, 0xff, 0xa9, 0xbc, 0x18, 0x6e, 0x13, 0xca, 0x49, 0xe3, 0xc5, 0x77, 0x2d, 0x56,
-	0xec, 0xcf, 0x04, 0x6e, 0xad, 0x5c, 0xf5, 0x63, 0xfb, 0xd9, 0xdf, 0x01, 0x00, 0x00, 0xff, 0xff,
-	0x50, 0x10, 0x21, 0x84, 0xe9, 0x0a, 0x00, 0x00,
+	0x14, 0xde, 0x38, 0xbf, 0x3e, 0xe9, 0xfe, 0xcd, 0x76, 0xb7, 0x26, 0xa0, 0x6a, 0x35, 0x02, 0xb1,
+	0x42, 0x22, 0x85, 0x80, 0xd4, 0xaa, 0xa8, 0x82, 0x74, 0x13, 0xaa, 0xbd, 0x00, 0xa2, 0x69, 0x6f,
+	0xb9, 0x70, 0x92, 0xe9, 0xca, 0xd4, 0xf5, 0x98, 0x99, 0xf1, 0xd2, 0x3c, 0x0a, 0xd7, 0x88, 0x2b,
+	0xde, 0x02, 0xf1, 0x38, 0xdc, 0xf0, 0x06, 0x68, 0x7e, 0x6c, 0x8f, 0x63, 0x2f, 0xdd, 0x95, 0x40,
+	0xdc, 0xf9, 0x9c, 0x39, 0x73, 0xfe, 0xbe, 0xef, 0xcc, 0x31, 0xec, 0x0a, 0xca, 0xaf, 0xa2, 0x15,
+	0x1d, 0xa7, 0x9c, 0x49, 0x86, 0xbc, 0x74, 0x89, 0xe7, 0xe0, 0x2f, 0x78, 0x94, 0xac, 0xa2, 0x34,
+	0x8c, 0x11, 0x82, 0x8e, 0xdc, 0xa4, 0x34, 0x68, 0x9d, 0xb6, 0xce, 0x7c, 0xa2, 0xbf, 0x95, 0x2e,
+	0x09, 0x5f, 0xd3, 0xc0, 0x33, 0x3a, 0xf5, 0x8d, 0x0e, 0xa0, 0x1d, 0xad, 0xd7, 0x41, 0x5b, 0xab,
+	0xd4, 0x27, 0x8e, 0xa1, 0xff, 0x3c, 0x5b, 0xfe, 0x40, 0x57, 0x12, 0x7d, 0x0c, 0x90, 0xe6, 0x1e,
+	0x45, 0xd0, 0x3a, 0x6d, 0x9f, 0x0d, 0x27, 0xbb, 0xe3, 0x74, 0x39, 0x2e, 0xe2, 0x10, 0xc7, 0x00,
+	0xbd, 0x07, 0xbe, 0x64, 0xaf, 0x68, 0xf2, 0x62, 0x93, 0xe6, 0x41, 0x4a, 0x05, 0xba, 0x0b, 0x5d,
+	0x2d, 0xd8, 0x58, 0x46, 0xc0, 0xbf, 0xb5, 0x61, 0xef, 0x9c, 0x25, 0x92, 0xbe, 0x91, 0x84, 0xfe,
+	0x98, 0x51, 0x21, 0xd1, 0x07, 0xd0, 0x17, 0x26, 0x01, 0x9d, 0xfd, 0x70, 0x32, 0x54, 0x21, 0x6d,
+	0x4e, 0x24, 0x3f, 0x43, 0xa7, 0x30, 0xb4, 0x3d, 0xf8, 0xb6, 0x2c, 0xca, 0x55, 0xa1, 0x11, 0x0c,
+	0x38, 0x15, 0x2c, 0xe3, 0x2b, 0x6a, 0x83, 0x16, 0x32, 0x3a, 0x81, 0x5e, 0xb8, 0x92, 0x11, 0x4b,
+	0x82, 0x8e, 0x3e, 0xb1, 0x12, 0x7a, 0x0a, 0x10, 0x4a, 0xc9, 0xa3, 0x65, 0x26, 0xa9, 0x08, 0xba,
+	0xba, 0x64, 0xac, 0xe2, 0x57, 0x93, 0x1c, 0x4f, 0x0b, 0xa3, 0x79, 0x22, 0xf9, 0x86, 0x38, 0xb7,
+	0xd0, 0x0b, 0x38, 0x60, 0x3a, 0xc7, 0xd2, 0x28, 0xe8, 0x69, 0x4f, 0x67, 0x0d, 0x9e, 0xbe, 0xdb,
+	0x32, 0x35, 0xfe, 0x6a, 0x1e, 0x46, 0x4f, 0x60, 0x7f, 0xcb, 0x48, 0x81, 0xf7, 0x8a, 0x6e, 0x2c,
+	0xc6, 0xea, 0x53, 0x35, 0xf9, 0x2a, 0x8c, 0xb3, 0xbc, 0x1d, 0x46, 0x78, 0xec, 0x3d, 0x6a, 0x8d,
+	0xce, 0xe1, 0xb8, 0x31, 0xd2, 0x6d, 0x9c, 0xe0, 0xef, 0xe1, 0xf0, 0x42, 0x4c, 0xe3, 0x98, 0xfd,
+	0x44, 0xd7, 0x84, 0x8a, 0x94, 0x25, 0x82, 0xa2, 0x00, 0xfa, 0xa1, 0x51, 0x69, 0x27, 0x03, 0x92,
+	0x8b, 0xaa, 0xc9, 0x9c, 0x86, 0x82, 0x25, 0xda, 0x53, 0x97, 0x58, 0x49, 0xe9, 0x29, 0xe7, 0xdf,
+	0x88, 0x4b, 0x0b, 0x8b, 0x95, 0xf0, 0x03, 0xd8, 0x9d, 0x26, 0xeb, 0x45, 0xc9, 0xa8, 0xfb, 0x35,
+	0x02, 0xfa, 0x2e, 0xe3, 0xf0, 0x9f, 0x2d, 0x00, 0xc2, 0x62, 0xba, 0x60, 0x71, 0xb4, 0xda, 0xa0,
+	0x3d, 0xf0, 0x2e, 0x66, 0xb6, 0x12, 0xef, 0x62, 0xa6, 0x08, 0xef, 0x70, 0x43, 0x7f, 0xab, 0xd8,
+	0xf3, 0x97, 0x2f, 0x15, 0xb9, 0x6c, 0x6c, 0x23, 0xa9, 0xa2, 0x95, 0x27, 0x11, 0x74, 0x74, 0x14,
+	0x23, 0xa8, 0x04, 0xca, 0x74, 0x34, 0x1d, 0x7c, 0x02, 0x8b, 0x0a, 0xe5, 0x89, 0xa5, 0x94, 0xc1,
+	0xd8, 0x27, 0xa5, 0x02, 0x7d, 0x02, 0x47, 0xb9, 0x30, 0x7f, 0x93, 0x72, 0x2a, 0x44, 0xc4, 0x12,
+	0x11, 0xf4, 0xb5, 0x5d, 0xd3, 0x91, 0xf2, 0x77, 0xce, 0x92, 0x75, 0xa4, 0x99, 0x39, 0x30, 0x23,
+	0x54, 0x28, 0xf0, 0xef, 0x1e, 0xf4, 0xfe, 0x85, 0x52, 0x1f, 0xc2, 0x30, 0xa5, 0xfc, 0x75, 0x64,
+	0xd3, 0xe9, 0x68, 0x6a, 0x1e, 0xeb, 0xb9, 0xd6, 0xce, 0xc7, 0x8b, 0xe2, 0x94, 0xb8, 0x96, 0xe8,
+	0xd3, 0x5a, 0x37, 0x86, 0x93, 0x43, 0x75, 0xaf, 0x82, 0xda, 0x76, 0x83, 0xca, 0x82, 0x7a, 0x5b,
+	0x05, 0x8d, 0x38, 0x40, 0x19, 0xab, 0x32, 0xaf, 0xad, 0xad, 0x79, 0x1d, 0x03, 0xe2, 0xb5, 0x7e,
+	0xd9, 0x6a, 0x1b, 0x4e, 0x34, 0x29, 0xf5, 0x44, 0x8b, 0xa0, 0xad, 0xdb, 0x9d, 0x8b, 0x98, 0x03,
+	0x9a, 0x2b, 0x46, 0x87, 0x92, 0xae, 0x8b, 0x4c, 0x14, 0x54, 0x85, 0xe0, 0x04, 0x30, 0x69, 0x34,
+	0x1d, 0xa1, 0x8f, 0xe0, 0xc0, 0xfa, 0x51, 0x7d, 0xa2, 0x22, 0x8b, 0xa5, 0xcd, 0xa7, 0xa6, 0xc7,
+	0xbf, 0x7a, 0x70, 0x54, 0x04, 0x75, 0x08, 0x7b, 0x02, 0xbd, 0xe7, 0x32, 0x94, 0x99, 0xb0, 0x81,
+	0xac, 0x64, 0xd1, 0xf5, 0x6a, 0xe8, 0xb6, 0x1b, 0xd1, 0xed, 0x34, 0x13, 0xb9, 0x7b, 0x3d, 0x91,
+	0x7b, 0xff, 0x4c, 0xe4, 0xfe, 0x0d, 0x89, 0x3c, 0xb8, 0x9e, 0xc8, 0x9f, 0xbb, 0xb8, 0xfb, 0xfa,
+	0x19, 0x3f, 0x51, 0x4c, 0xa9, 0xb7, 0xde, 0x25, 0xf8, 0x5f, 0x1e, 0xec, 0x17, 0x16, 0xff, 0x61,
+	0x8f, 0xbe, 0xaa, 0x4e, 0x80, 0x61, 0xf2, 0xfd, 0x4a, 0x7e, 0x6f, 0x19, 0x85, 0xb7, 0xf5, 0xb3,
+	0x52, 0x7f, 0xff, 0x86, 0xf5, 0xff, 0x2f, 0xf3, 0xf0, 0xb3, 0x07, 0xf7, 0x4a, 0xc2, 0xce, 0xe8,
+	0x32, 0xbb, 0xbc, 0xf5, 0xd3, 0xee, 0x17, 0x4f, 0xfb, 0x63, 0xd8, 0xe3, 0x66, 0xb1, 0xd9, 0x35,
+	0xa7, 0xf1, 0x18, 0x4e, 0x50, 0x7d, 0xf3, 0x91, 0x2d, 0x4b, 0x84, 0xe1, 0xce, 0x25, 0x0f, 0x13,
+	0x3b, 0x22, 0xf9, 0x4b, 0x5c, 0xd1, 0xa1, 0x2f, 0xe0, 0x0e, 0xcf, 0xe7, 0x27, 0x2a, 0x36, 0xf4,
+	0xbd, 0x4a, 0x6b, 0xcb, 0x01, 0x23, 0x15, 0x63, 0xf4, 0x00, 0x06, 0x69, 0x7e, 0xd1, 0x2c, 0xe4,
+	0xa3, 0x06, 0xcc, 0x49, 0x61, 0x84, 0x3f, 0x84, 0xfd, 0x69, 0x1c, 0x2b, 0x7f, 0x45, 0x4b, 0xee,
+	0x42, 0x97, 0xeb, 0xec, 0xcc, 0x36, 0x32, 0x02, 0xfe, 0xa5, 0x05, 0xc7, 0xd3, 0x38, 0x76, 0xd8,
+	0x92, 0xdb, 0x7f, 0x5d, 0xa5, 0x9a, 0xf9, 0x89, 0x7a, 0x5f, 0x3f, 0x9a, 0x4d, 0xf6, 0xd7, 0x11,
+	0x6e, 0xf4, 0xf4, 0xc6, 0xd4, 0x70, 0xa0, 0xf6, 0x2a, 0x50, 0x4f, 0xfe, 0xf0, 0xc0, 0xb7, 0xc5,
+	0x32, 0x8e, 0x1e, 0x81, 0x5f, 0x2c, 0x73, 0xd4, 0x80, 0xcf, 0x48, 0xaf, 0x84, 0xda, 0xbe, 0xc7,
+	0x3b, 0xe8, 0x4b, 0x40, 0xcf, 0xa8, 0x9c, 0xc6, 0xf1, 0x33, 0x17, 0x9a, 0x26, 0x17, 0x47, 0xb6,
+	0x50, 0xb7, 0x85, 0x78, 0x07, 0xcd, 0xe0, 0xd0, 0x38, 0x58, 0x38, 0x23, 0xd5, 0x74, 0xff, 0x9d,
+	0x6b, 0x1b, 0x85, 0x77, 0xd0, 0x43, 0x18, 0xcc, 0x22, 0xb1, 0x62, 0x57, 0x94, 0xdf, 0x2e, 0xff,
+	0x27, 0xea, 0x62, 0x78, 0x99, 0x30, 0x41, 0x1b, 0x2f, 0xbe, 0xeb, 0xb0, 0x62, 0x7b, 0x26, 0xf0,
+	0xce, 0xb2, 0xa7, 0xff, 0xb9, 0x3f, 0xfb, 0x7b, 0x00, 0x2b, 0x39, 0x5d, 0x04, 0x84, 0x0b, 0x00,
+	0x00,
 }
@@ -0,0 +1,38 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/svcs"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewTenantInterceptor creates a unary interceptor which evaluates each call with the evaluator of the tenant
+// in Speedle-Tenant metadata
+func NewTenantInterceptor(evaluators *eval.TenantEvaluators) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		tenantName := svcs.TenantOfGRPC(ctx)
+		evaluator, err := evaluators.Evaluator(tenantName)
+		if err != nil {
+			log.Errorf("Unable to resolve the evaluator of tenant %q, err: %v", tenantName, err)
+			code := codes.Internal
+			switch errors.Code(err) {
+			case errors.EntityNotFound:
+				code = codes.NotFound
+			case errors.InvalidRequest:
+				code = codes.InvalidArgument
+			}
+			return nil, status.Error(code, err.Error())
+		}
+		return handler(eval.WithEvaluator(ctx, evaluator), req)
+	}
+}
@@ -0,0 +1,43 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/httputils"
+)
+
+// FlushResult is the response of flushing the assertion cache
+type FlushResult struct {
+	// Flushed is the number of tokens removed from the cache
+	Flushed int `json:"flushed"`
+}
+
+// assertionCacheStatsHandler reports the hit and miss statistics of the assertion cache
+func assertionCacheStatsHandler(evaluators *eval.TenantEvaluators) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cache := evaluators.AssertionCache()
+		if cache == nil {
+			httputils.HandleError(w, errors.New(errors.EntityNotFound, "assertion cache is not enabled"))
+			return
+		}
+		stats := cache.Stats()
+		httputils.SendOKResponse(w, &stats)
+	}
+}
+
+// assertionCacheFlushHandler removes all the tokens from the assertion cache, so that they are asserted again
+func assertionCacheFlushHandler(evaluators *eval.TenantEvaluators) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cache := evaluators.AssertionCache()
+		if cache == nil {
+			httputils.HandleError(w, errors.New(errors.EntityNotFound, "assertion cache is not enabled"))
+			return
+		}
+		httputils.SendOKResponse(w, &FlushResult{Flushed: cache.Flush()})
+	}
+}
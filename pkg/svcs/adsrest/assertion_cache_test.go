@@ -0,0 +1,82 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/svcs"
+)
+
+func TestAssertionCacheEndpoints(t *testing.T) {
+	conf := GenerateServerConfig()
+	evaluator, err := eval.NewFromConfig(conf)
+	if err != nil {
+		t.Fatal("failed to create evaluator:", err)
+	}
+	evaluators := eval.NewTenantEvaluators(conf, evaluator, nil)
+	publicRouter, err := NewTenantRouter(evaluators)
+	if err != nil {
+		t.Fatal("failed to create router:", err)
+	}
+	router := NewAdminRouter(evaluators)
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, svcs.PolicyAtzPath+"assertion-cache", nil))
+		return w
+	}
+
+	if w := serve("GET"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without assertion cache, got %d", w.Code)
+	}
+
+	server := assertion.NewTestServer(t, nil)
+	defer server.Close()
+	asserter, err := assertion.NewAsserter(&assertion.AsserterConfig{Endpoint: server.URL + "/assert"}, nil)
+	if err != nil {
+		t.Fatal("failed to create asserter:", err)
+	}
+	cache, err := assertion.NewCachingAsserter(asserter, &assertion.CacheConfig{TTL: 60})
+	if err != nil {
+		t.Fatal("failed to create caching asserter:", err)
+	}
+	evaluators.SetAssertionCache(cache)
+	cache.AssertToken("token", "github", "", nil)
+	cache.AssertToken("token", "github", "", nil)
+
+	w := serve("GET")
+	var stats assertion.CacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("failed to get stats, status %d, err %v", w.Code, err)
+	}
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// The cache is not exposed on the endpoint serving authorization checks
+	for _, method := range []string{"GET", "DELETE"} {
+		w := httptest.NewRecorder()
+		publicRouter.ServeHTTP(w, httptest.NewRequest(method, svcs.PolicyAtzPath+"assertion-cache", nil))
+		if w.Code == http.StatusOK {
+			t.Errorf("%s assertion-cache should not be served by the authorization check router", method)
+		}
+	}
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("cache should not be flushed through the authorization check router, got %+v", stats)
+	}
+
+	w = serve("DELETE")
+	var result FlushResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK || result.Flushed != 1 {
+		t.Errorf("unexpected flush response, status %d, body %s", w.Code, w.Body.String())
+	}
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("cache should be empty after flushed, got %+v", stats)
+	}
+}
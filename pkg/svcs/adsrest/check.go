@@ -158,6 +158,8 @@ var dataTypeMap = map[string]string{
 	"numeric":  "float64",
 	"bool":     "bool",
 	"datetime": "string",
+	// object is a JSON object, whose values are accessed by dot paths like resource.labels.env in conditions
+	"object": "map[string]interface {}",
 }
 
 var supportDateTimeLayout = []string{
@@ -323,7 +325,7 @@ func (e *RESTService) IsAllowed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, reason, err := e.Evaluator.IsAllowed(*context)
+	result, reason, err := e.evaluatorOf(r).IsAllowed(*context)
 	response := IsAllowedResponse{
 		Allowed: result,
 		Reason:  int32(reason),
@@ -364,7 +366,7 @@ func (e *RESTService) GetAllGrantedRoles(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	roles, err := e.Evaluator.GetAllGrantedRoles(*context)
+	roles, err := e.evaluatorOf(r).GetAllGrantedRoles(*context)
 	if err != nil {
 		httputils.HandleError(w, err)
 		// Audit log
@@ -395,7 +397,7 @@ func (e *RESTService) GetAllGrantedPermissions(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	permissions, err := e.Evaluator.GetAllGrantedPermissions(*context)
+	permissions, err := e.evaluatorOf(r).GetAllGrantedPermissions(*context)
 	if err != nil {
 		httputils.HandleError(w, err)
 		// Audit log
@@ -489,7 +491,7 @@ func (e *RESTService) Diagnose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	evaResult, err := e.Evaluator.Diagnose(*context)
+	evaResult, err := e.evaluatorOf(r).Diagnose(*context)
 	if err != nil {
 		httputils.HandleError(w, err)
 		// Audit log
@@ -118,3 +118,28 @@ func newADSServerWithAsserter(assertserverendpoint string, t *testing.T) (*httpt
 	conf.AsserterWebhookConfig = asconfig
 	return NewTestServerWithConfig(conf)
 }
+
+func TestConvObjectValue(t *testing.T) {
+	var attrs []*JsonAttribute
+	if err := json.Unmarshal([]byte(`[
+		{"name": "resource", "type": "object", "value": {"labels": {"env": "prod"}, "size": 3}},
+		{"name": "owners", "type": "object", "value": [{"name": "alice"}, {"name": "bob"}]}
+	]`), &attrs); err != nil {
+		t.Fatal("failed to unmarshal attributes:", err)
+	}
+	values, err := DumpRequestAttributes(attrs)
+	if err != nil {
+		t.Fatal("failed to convert object attributes:", err)
+	}
+	resource, ok := values["resource"].(map[string]interface{})
+	if !ok || resource["labels"].(map[string]interface{})["env"] != "prod" || resource["size"] != 3.0 {
+		t.Errorf("unexpected resource %v", values["resource"])
+	}
+	if owners, ok := values["owners"].([]interface{}); !ok || len(owners) != 2 {
+		t.Errorf("unexpected owners %v", values["owners"])
+	}
+
+	if _, err := ConvValue("object", "prod"); err == nil {
+		t.Error("a string should not be an object")
+	}
+}
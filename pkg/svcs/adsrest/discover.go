@@ -24,9 +24,10 @@ func (e *RESTService) Discover(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// assert token
-	e.Evaluator.AssertToken(context)
+	evaluator := e.evaluatorOf(r)
+	evaluator.AssertToken(context)
 
-	result, reason, err := e.Evaluator.Discover(*context)
+	result, reason, err := evaluator.Discover(*context)
 	response := IsAllowedResponse{
 		Allowed: result,
 		Reason:  int32(reason),
@@ -0,0 +1,19 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/httputils"
+)
+
+// functionCacheStatsHandler reports the statistics of the function result caches keyed by tenant name
+func functionCacheStatsHandler(evaluators *eval.TenantEvaluators) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := evaluators.FunctionResultCacheStats()
+		httputils.SendOKResponse(w, &stats)
+	}
+}
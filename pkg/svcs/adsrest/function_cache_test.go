@@ -0,0 +1,33 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/svcs"
+)
+
+func TestFunctionCacheStatsEndpoint(t *testing.T) {
+	conf := GenerateServerConfig()
+	evaluator, err := eval.NewFromConfig(conf)
+	if err != nil {
+		t.Fatal("failed to create evaluator:", err)
+	}
+	router := NewAdminRouter(eval.NewTenantEvaluators(conf, evaluator, nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", svcs.PolicyAtzPath+"function-cache", nil))
+	var stats map[string]eval.FunctionResultCacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("failed to get stats, status %d, err %v", w.Code, err)
+	}
+	if _, ok := stats[pms.DefaultTenant]; !ok {
+		t.Errorf("expected stats of the default tenant, got %v", stats)
+	}
+}
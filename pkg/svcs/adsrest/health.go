@@ -0,0 +1,48 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/httputils"
+)
+
+const (
+	// HealthUp means all the policies being evaluated are up to date
+	HealthUp = "UP"
+	// HealthDegraded means some policies can't be reloaded from the policy store, the ones loaded before are evaluated
+	HealthDegraded = "DEGRADED"
+)
+
+// HealthStatus is the health of authorization decision service
+type HealthStatus struct {
+	Status string `json:"status"`
+	// Problems are keyed by the names of the tenants whose policies can't be reloaded
+	Problems map[string]string `json:"problems,omitempty"`
+	// Revisions are the revisions of the policies being evaluated keyed by tenant name, replicas reporting the same
+	// revisions evaluate the same policies. Only tenants whose policy stores tell revisions are included.
+	Revisions map[string]int64 `json:"revisions,omitempty"`
+}
+
+// healthHandler reports the health of the evaluators. Degraded service still evaluates requests, so it responds
+// with status OK as well.
+func healthHandler(evaluators *eval.TenantEvaluators) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := HealthStatus{Status: HealthUp}
+		problems := evaluators.Health()
+		if len(problems) != 0 {
+			status.Status = HealthDegraded
+			status.Problems = make(map[string]string, len(problems))
+			for tenantName, err := range problems {
+				status.Problems[tenantName] = err.Error()
+			}
+		}
+		if revisions := evaluators.Revisions(); len(revisions) != 0 {
+			status.Revisions = revisions
+		}
+		httputils.SendOKResponse(w, &status)
+	}
+}
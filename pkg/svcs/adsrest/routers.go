@@ -5,7 +5,10 @@ package adsrest
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/cfg"
 	"github.com/oracle/speedle/pkg/eval"
 	"github.com/oracle/speedle/pkg/svcs"
 
@@ -66,21 +69,45 @@ func initRouters(evaluator eval.InternalEvaluator) (*routes, error) {
 }
 
 func NewRouter(evaluator eval.InternalEvaluator) (*mux.Router, error) {
-	routes, err := initRouters(evaluator)
+	return NewTenantRouter(eval.NewTenantEvaluators(&cfg.Config{}, evaluator, nil))
+}
+
+// NewTenantRouter creates the router of authorization decision service, requests are evaluated by the
+// evaluators of the tenants they are scoped to. Endpoints are served both with and without the tenant
+// path prefix, the latter are scoped by the tenant header or to the default tenant.
+func NewTenantRouter(evaluators *eval.TenantEvaluators) (*mux.Router, error) {
+	defaultEvaluator, err := evaluators.Evaluator(pms.DefaultTenant)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := initRouters(defaultEvaluator)
 	if err != nil {
 		return nil, err
 	}
 	router := mux.NewRouter().StrictSlash(true)
 	for _, route := range *routes {
 		var handler http.Handler
-		handler = route.HandlerFunc
+		handler = tenantHandler(evaluators, route.HandlerFunc)
 
-		router.
-			Methods(route.Method).
-			Path(route.Pattern).
-			Name(route.Name).
-			Handler(handler)
+		for _, pattern := range []string{route.Pattern, svcs.PolicyAtzPath + svcs.TenantPathPrefix + strings.TrimPrefix(route.Pattern, svcs.PolicyAtzPath)} {
+			router.
+				Methods(route.Method).
+				Path(pattern).
+				Name(route.Name).
+				Handler(handler)
+		}
 	}
+	router.Methods("GET").Path(svcs.PolicyAtzPath + "health").Name("Health").Handler(healthHandler(evaluators))
 
 	return router, nil
 }
+
+// NewAdminRouter creates the router of the admin APIs of authorization decision service, they are served
+// on the admin endpoint which is not reachable by the callers of authorization checks
+func NewAdminRouter(evaluators *eval.TenantEvaluators) *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	router.Methods("GET").Path(svcs.PolicyAtzPath + "assertion-cache").Name("AssertionCacheStats").Handler(assertionCacheStatsHandler(evaluators))
+	router.Methods("DELETE").Path(svcs.PolicyAtzPath + "assertion-cache").Name("FlushAssertionCache").Handler(assertionCacheFlushHandler(evaluators))
+	router.Methods("GET").Path(svcs.PolicyAtzPath + "function-cache").Name("FunctionCacheStats").Handler(functionCacheStatsHandler(evaluators))
+	return router
+}
@@ -0,0 +1,39 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package adsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/svcs"
+	log "github.com/sirupsen/logrus"
+)
+
+// evaluatorOf returns the evaluator resolved by tenantHandler, requests out of any tenant scope
+// are evaluated by the evaluator of the default tenant
+func (e *RESTService) evaluatorOf(r *http.Request) eval.InternalEvaluator {
+	if evaluator, ok := eval.EvaluatorFrom(r.Context()); ok {
+		return evaluator
+	}
+	return e.Evaluator
+}
+
+// tenantHandler resolves the evaluator of the tenant a request is scoped to, and passes it to next handler in request context
+func tenantHandler(evaluators *eval.TenantEvaluators, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantName, err := svcs.TenantOfHTTP(r)
+		var evaluator eval.InternalEvaluator
+		if err == nil {
+			evaluator, err = evaluators.Evaluator(tenantName)
+		}
+		if err != nil {
+			log.Errorf("Unable to resolve the evaluator of tenant %q, err: %v", tenantName, err)
+			httputils.HandleError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(eval.WithEvaluator(r.Context(), evaluator)))
+	})
+}
@@ -10,4 +10,12 @@ const (
 	PolicyAtzPath = "/authz-check/v1/"
 	// Header to store asserted pincipals
 	PrincipalsHeader = "Speedle-Principals"
+	// ContinueHeader is the header carrying the token to list the next page, it's absent from the last page
+	ContinueHeader = "Speedle-Continue"
+	// TenantHeader is the header, and the gRPC metadata key, carrying the tenant a request is scoped to
+	TenantHeader = "Speedle-Tenant"
+	// TenantPathVar is the path variable of the tenant in tenant scoped REST endpoints
+	TenantPathVar = "tenantName"
+	// TenantPathPrefix is the prefix of tenant scoped REST endpoints, relative to service paths
+	TenantPathPrefix = "tenant/{" + TenantPathVar + "}/"
 )
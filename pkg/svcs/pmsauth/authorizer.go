@@ -0,0 +1,308 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+// Package pmsauth authenticates the callers of policy management service, and authorizes each
+// management operation by evaluating it against the policies in a reserved Speedle service.
+package pmsauth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"sync"
+
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/eval"
+	"github.com/oracle/speedle/pkg/subjectutils"
+	log "github.com/sirupsen/logrus"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// DefaultAdminService is the Speedle service governing policy management APIs if it is not configured
+	DefaultAdminService = "speedle-admin"
+
+	// APIKeyHeader is the header carrying static API key, in both HTTP requests and gRPC metadata
+	APIKeyHeader = "X-API-Key"
+	// AuthorizationHeader is the header carrying bearer token, in both HTTP requests and gRPC metadata
+	AuthorizationHeader = "Authorization"
+
+	bearerPrefix = "bearer "
+
+	ActionCreate = "create"
+	ActionRead   = "read"
+	ActionDelete = "delete"
+	ActionUpdate = "update"
+)
+
+// ServiceResource returns the resource of a service, or of all services if serviceName is empty
+func ServiceResource(serviceName string) string {
+	return joinResource("/service", serviceName)
+}
+
+// PolicyResource returns the resource of the policies in a service
+func PolicyResource(serviceName string) string {
+	return ServiceResource(serviceName) + "/policy"
+}
+
+// RolePolicyResource returns the resource of the role policies in a service
+func RolePolicyResource(serviceName string) string {
+	return ServiceResource(serviceName) + "/role-policy"
+}
+
+// FunctionResource returns the resource of a function, or of all functions if funcName is empty
+func FunctionResource(funcName string) string {
+	return joinResource("/function", funcName)
+}
+
+// DiscoverResource returns the resource of discover requests and policies of a service,
+// or of all services if serviceName is empty
+func DiscoverResource(serviceName string) string {
+	return joinResource("/discover", serviceName)
+}
+
+// TenantResource returns the resource of a tenant, or of all tenants if tenantName is empty
+func TenantResource(tenantName string) string {
+	return joinResource("/tenant", tenantName)
+}
+
+// QuotaResource returns the resource of the quota and its usage
+func QuotaResource() string {
+	return "/quota"
+}
+
+// SearchResource returns the resource of searching the policies and role policies of all services
+func SearchResource() string {
+	return "/search"
+}
+
+// ScopedResource returns resource in the scope of a tenant, resources of the default tenant are not prefixed
+func ScopedResource(tenantName string, resource string) string {
+	if len(tenantName) == 0 || tenantName == pms.DefaultTenant {
+		return resource
+	}
+	return TenantResource(tenantName) + resource
+}
+
+func joinResource(collection string, name string) string {
+	if len(name) == 0 {
+		return collection
+	}
+	return collection + "/" + name
+}
+
+// Authorizer authenticates and authorizes policy management requests
+type Authorizer struct {
+	sync.RWMutex
+	evaluator       adsapi.PolicyEvaluator
+	asserter        assertion.TokenAsserter
+	adminService    string
+	adminPrincipals map[string]bool
+	apiKeys         map[string]string
+	tokenType       string
+}
+
+// New creates an authorizer evaluating management operations against the policies in policy store ps
+func New(conf *cfg.Config, ps pms.PolicyStoreManager) (*Authorizer, error) {
+	evaluator, err := eval.NewWithStore(&cfg.Config{EnableWatch: true}, ps)
+	if err != nil {
+		return nil, err
+	}
+
+	asserter, err := newAsserter(conf)
+	if err != nil {
+		log.Warningf("Bearer token authentication is disabled, load asserter error: %v", err)
+	}
+
+	return NewWithEvaluator(conf.PMSAuthConfig, evaluator, asserter), nil
+}
+
+// newAsserter creates the token asserter of conf, nil is returned if no asserter is configured
+func newAsserter(conf *cfg.Config) (assertion.TokenAsserter, error) {
+	if conf.AsserterWebhookConfig == nil && conf.JWTAsserterConfig == nil {
+		return nil, nil
+	}
+	asserter, _, err := assertion.NewTokenAsserter(conf.AsserterType, conf.AsserterWebhookConfig, conf.JWTAsserterConfig)
+	if err != nil {
+		return nil, err
+	}
+	return asserter, nil
+}
+
+// NewWithEvaluator creates an authorizer with the given evaluator and token asserter, asserter could be nil
+func NewWithEvaluator(authConf *cfg.PMSAuthConfig, evaluator adsapi.PolicyEvaluator, asserter assertion.TokenAsserter) *Authorizer {
+	a := &Authorizer{
+		evaluator: evaluator,
+		asserter:  asserter,
+	}
+	a.SetConfig(authConf)
+	return a
+}
+
+// SetConfig applies the authentication and authorization configuration, it can be called at runtime
+func (a *Authorizer) SetConfig(authConf *cfg.PMSAuthConfig) {
+	if authConf == nil {
+		authConf = &cfg.PMSAuthConfig{}
+	}
+	adminPrincipals := make(map[string]bool)
+	for _, principal := range authConf.AdminPrincipals {
+		adminPrincipals[principal] = true
+	}
+	apiKeys := make(map[string]string)
+	for key, user := range authConf.APIKeys {
+		apiKeys[key] = user
+	}
+	adminService := authConf.AdminService
+	if len(adminService) == 0 {
+		adminService = DefaultAdminService
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	a.adminService = adminService
+	a.adminPrincipals = adminPrincipals
+	a.apiKeys = apiKeys
+	a.tokenType = authConf.TokenType
+}
+
+// SetAsserterConfig rebuilds the token asserter authenticating bearer tokens from the asserter configuration of conf,
+// it can be called at runtime. Bearer tokens are rejected if no asserter is configured, and the previous asserter is
+// kept if the new one fails to load.
+func (a *Authorizer) SetAsserterConfig(conf *cfg.Config) error {
+	asserter, err := newAsserter(conf)
+	if err != nil {
+		return err
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.asserter = asserter
+	return nil
+}
+
+// AdminService returns the name of the service governing policy management APIs
+func (a *Authorizer) AdminService() string {
+	a.RLock()
+	defer a.RUnlock()
+	return a.adminService
+}
+
+// AuthenticateHTTP authenticates the caller of a HTTP request
+func (a *Authorizer) AuthenticateHTTP(r *http.Request) (*adsapi.Subject, error) {
+	var certs []*x509.Certificate
+	if r.TLS != nil {
+		certs = r.TLS.PeerCertificates
+	}
+	return a.authenticate(r.Header.Get(APIKeyHeader), r.Header.Get(AuthorizationHeader), certs)
+}
+
+// AuthenticateGRPC authenticates the caller of a gRPC request
+func (a *Authorizer) AuthenticateGRPC(ctx context.Context) (*adsapi.Subject, error) {
+	var apiKey, authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// gRPC metadata keys are always lower case
+		if values := md[strings.ToLower(APIKeyHeader)]; len(values) != 0 {
+			apiKey = values[0]
+		}
+		if values := md[strings.ToLower(AuthorizationHeader)]; len(values) != 0 {
+			authorization = values[0]
+		}
+	}
+
+	var certs []*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			certs = tlsInfo.State.PeerCertificates
+		}
+	}
+	return a.authenticate(apiKey, authorization, certs)
+}
+
+// authenticate identifies the caller by API key, bearer token or client certificate, in this order
+func (a *Authorizer) authenticate(apiKey string, authorization string, certs []*x509.Certificate) (*adsapi.Subject, error) {
+	a.RLock()
+	apiKeys, tokenType, asserter := a.apiKeys, a.tokenType, a.asserter
+	a.RUnlock()
+
+	if len(apiKey) != 0 {
+		user, ok := apiKeys[apiKey]
+		if !ok {
+			return nil, errors.New(errors.Unauthorized, "invalid API key")
+		}
+		return &adsapi.Subject{
+			Principals: []*adsapi.Principal{{Type: adsapi.PRINCIPAL_TYPE_USER, Name: user}},
+			Asserted:   true,
+		}, nil
+	}
+
+	if len(authorization) > len(bearerPrefix) && strings.ToLower(authorization[:len(bearerPrefix)]) == bearerPrefix {
+		if asserter == nil {
+			return nil, errors.New(errors.Unauthorized, "bearer token is not supported because no asserter is configured")
+		}
+		token := strings.TrimSpace(authorization[len(bearerPrefix):])
+		resp, err := asserter.AssertToken(token, tokenType, "", nil)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.Unauthorized, "failed to assert bearer token")
+		}
+		return &adsapi.Subject{
+			Principals: resp.Principals,
+			Asserted:   true,
+		}, nil
+	}
+
+	if len(certs) != 0 && len(certs[0].Subject.CommonName) != 0 {
+		return &adsapi.Subject{
+			Principals: []*adsapi.Principal{{Type: adsapi.PRINCIPAL_TYPE_USER, Name: certs[0].Subject.CommonName}},
+			Asserted:   true,
+		}, nil
+	}
+
+	return nil, errors.New(errors.Unauthorized, "no credential is provided")
+}
+
+// Authorize checks whether subject is allowed to perform action on resource of policy management service
+func (a *Authorizer) Authorize(subject *adsapi.Subject, resource string, action string) error {
+	if subject == nil || len(subject.Principals) == 0 {
+		return errors.New(errors.Unauthorized, "the caller is not authenticated")
+	}
+
+	a.RLock()
+	adminService, adminPrincipals := a.adminService, a.adminPrincipals
+	a.RUnlock()
+
+	for _, principal := range subject.Principals {
+		if adminPrincipals[subjectutils.EncodePrincipal(principal)] {
+			return nil
+		}
+	}
+
+	allowed, reason, err := a.evaluator.IsAllowed(adsapi.RequestContext{
+		Subject:     subject,
+		ServiceName: adminService,
+		Resource:    resource,
+		Action:      action,
+	})
+	if err != nil {
+		log.Warningf("Failed to evaluate %s on %s in service %s, reason: %v, err: %v", action, resource, adminService, reason, err)
+	}
+	if !allowed {
+		return errors.Errorf(errors.Forbidden, "%s is not allowed to %s %s", EncodePrincipals(subject), action, resource)
+	}
+	return nil
+}
+
+// EncodePrincipals encodes the principals of subject into a comma separated string
+func EncodePrincipals(subject *adsapi.Subject) string {
+	encoded := make([]string, 0, len(subject.Principals))
+	for _, principal := range subject.Principals {
+		encoded = append(encoded, subjectutils.EncodePrincipal(principal))
+	}
+	return strings.Join(encoded, ",")
+}
@@ -0,0 +1,178 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/assertion"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// fakeEvaluator allows the requests listed in grants, keyed by principal name, resource and action
+type fakeEvaluator struct {
+	adsapi.PolicyEvaluator
+	serviceName string
+	grants      map[string]bool
+}
+
+func (e *fakeEvaluator) IsAllowed(c adsapi.RequestContext) (bool, adsapi.Reason, error) {
+	if c.ServiceName != e.serviceName {
+		return false, adsapi.SERVICE_NOT_FOUND, nil
+	}
+	for _, principal := range c.Subject.Principals {
+		if e.grants[principal.Name+":"+c.Resource+":"+c.Action] {
+			return true, adsapi.GRANT_POLICY_FOUND, nil
+		}
+	}
+	return false, adsapi.NO_APPLICABLE_POLICIES, nil
+}
+
+type fakeAsserter struct{}
+
+func (a *fakeAsserter) AssertToken(token string, idpType string, allowedIDD string, requestHeaders map[string]string) (*assertion.AssertResponse, error) {
+	if token != "good-token" {
+		return nil, errors.New(errors.Unauthorized, "bad token")
+	}
+	return &assertion.AssertResponse{
+		Principals: []*adsapi.Principal{{Type: adsapi.PRINCIPAL_TYPE_USER, Name: "carol"}},
+	}, nil
+}
+
+func newTestAuthorizer() *Authorizer {
+	evaluator := &fakeEvaluator{
+		serviceName: DefaultAdminService,
+		grants: map[string]bool{
+			"bob:" + PolicyResource("app1") + ":" + ActionRead:  true,
+			"carol:" + ServiceResource("") + ":" + ActionCreate: true,
+		},
+	}
+	return NewWithEvaluator(&cfg.PMSAuthConfig{
+		AdminPrincipals: []string{"user:alice"},
+		APIKeys:         map[string]string{"alice-key": "alice", "bob-key": "bob"},
+	}, evaluator, &fakeAsserter{})
+}
+
+func TestAuthorize(t *testing.T) {
+	authorizer := newTestAuthorizer()
+
+	testcases := []struct {
+		apiKey        string
+		authorization string
+		resource      string
+		action        string
+		code          errors.ErrorCode
+	}{
+		// admin principal is allowed to do anything
+		{"alice-key", "", ServiceResource(""), ActionDelete, ""},
+		{"bob-key", "", PolicyResource("app1"), ActionRead, ""},
+		{"bob-key", "", PolicyResource("app1"), ActionCreate, errors.Forbidden},
+		{"bob-key", "", PolicyResource("app2"), ActionRead, errors.Forbidden},
+		{"unknown-key", "", PolicyResource("app1"), ActionRead, errors.Unauthorized},
+		{"", "Bearer good-token", ServiceResource(""), ActionCreate, ""},
+		{"", "bearer good-token", ServiceResource(""), ActionDelete, errors.Forbidden},
+		{"", "Bearer bad-token", ServiceResource(""), ActionCreate, errors.Unauthorized},
+		{"", "", ServiceResource(""), ActionRead, errors.Unauthorized},
+	}
+
+	for i, tc := range testcases {
+		r := httptest.NewRequest("GET", "/policy-mgmt/v1/service", nil)
+		if tc.apiKey != "" {
+			r.Header.Set(APIKeyHeader, tc.apiKey)
+		}
+		if tc.authorization != "" {
+			r.Header.Set(AuthorizationHeader, tc.authorization)
+		}
+
+		subject, err := authorizer.AuthenticateHTTP(r)
+		if err == nil {
+			err = authorizer.Authorize(subject, tc.resource, tc.action)
+		}
+		if tc.code == "" {
+			if err != nil {
+				t.Errorf("case %d: expected allowed, but got error: %v", i, err)
+			}
+			continue
+		}
+		if errors.Code(err) != tc.code {
+			t.Errorf("case %d: expected error code %s, but got: %v", i, tc.code, err)
+		}
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	authorizer := newTestAuthorizer()
+	r := httptest.NewRequest("GET", "/policy-mgmt/v1/service", nil)
+	r.Header.Set(APIKeyHeader, "bob-key")
+	subject, err := authorizer.AuthenticateHTTP(r)
+	if err != nil {
+		t.Fatalf("failed to authenticate bob: %v", err)
+	}
+	if err := authorizer.Authorize(subject, ServiceResource(""), ActionDelete); errors.Code(err) != errors.Forbidden {
+		t.Fatalf("expected bob to be forbidden, but got: %v", err)
+	}
+
+	authorizer.SetConfig(&cfg.PMSAuthConfig{
+		AdminPrincipals: []string{"user:bob"},
+		APIKeys:         map[string]string{"bob-key": "bob"},
+	})
+	if err := authorizer.Authorize(subject, ServiceResource(""), ActionDelete); err != nil {
+		t.Errorf("expected bob to be allowed as admin, but got: %v", err)
+	}
+
+	// Switching to another admin service, bob's grants no longer apply
+	authorizer.SetConfig(&cfg.PMSAuthConfig{
+		AdminService: "another-admin",
+		APIKeys:      map[string]string{"bob-key": "bob"},
+	})
+	if authorizer.AdminService() != "another-admin" {
+		t.Errorf("expected admin service another-admin, but got %s", authorizer.AdminService())
+	}
+	if err := authorizer.Authorize(subject, PolicyResource("app1"), ActionRead); errors.Code(err) != errors.Forbidden {
+		t.Errorf("expected bob to be forbidden, but got: %v", err)
+	}
+}
+
+func TestSetAsserterConfig(t *testing.T) {
+	authorizer := newTestAuthorizer()
+	authenticate := func(token string) (*adsapi.Subject, error) {
+		r := httptest.NewRequest("GET", "/policy-mgmt/v1/service", nil)
+		r.Header.Set(AuthorizationHeader, "Bearer "+token)
+		return authorizer.AuthenticateHTTP(r)
+	}
+
+	// The webhook asserts every token as dave
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&assertion.AssertResponse{
+			Principals: []*adsapi.Principal{{Type: adsapi.PRINCIPAL_TYPE_USER, Name: "dave"}},
+		})
+	}))
+	defer webhook.Close()
+	if err := authorizer.SetAsserterConfig(&cfg.Config{AsserterWebhookConfig: &assertion.AsserterConfig{Endpoint: webhook.URL}}); err != nil {
+		t.Fatalf("failed to set asserter: %v", err)
+	}
+	if subject, err := authenticate("good-token"); err != nil || subject.Principals[0].Name != "dave" {
+		t.Errorf("expected token to be asserted by the new asserter as dave, but got %v, %v", subject, err)
+	}
+
+	// The previous asserter is kept if the new one fails to load
+	if err := authorizer.SetAsserterConfig(&cfg.Config{AsserterType: "unknown", AsserterWebhookConfig: &assertion.AsserterConfig{}}); err == nil {
+		t.Error("expected error of unknown asserter type")
+	}
+	if subject, err := authenticate("good-token"); err != nil || subject.Principals[0].Name != "dave" {
+		t.Errorf("expected token to be asserted by the previous asserter as dave, but got %v, %v", subject, err)
+	}
+
+	if err := authorizer.SetAsserterConfig(&cfg.Config{}); err != nil {
+		t.Fatalf("failed to remove asserter: %v", err)
+	}
+	if _, err := authenticate("good-token"); errors.Code(err) != errors.Unauthorized {
+		t.Errorf("expected bearer token to be rejected without asserter, but got %v", err)
+	}
+}
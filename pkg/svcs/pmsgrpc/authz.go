@@ -0,0 +1,117 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsgrpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/svcs"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
+)
+
+const methodPrefix = "/pb.PolicyManager/"
+
+type serviceNameGetter interface {
+	GetServiceName() string
+}
+
+type nameGetter interface {
+	GetName() string
+}
+
+// serviceNameOf returns the service a request targets, service requests carry it in name field
+func serviceNameOf(req interface{}) string {
+	if getter, ok := req.(serviceNameGetter); ok {
+		return getter.GetServiceName()
+	}
+	if getter, ok := req.(nameGetter); ok {
+		return getter.GetName()
+	}
+	return ""
+}
+
+func nameOf(req interface{}) string {
+	if getter, ok := req.(nameGetter); ok {
+		return getter.GetName()
+	}
+	return ""
+}
+
+// permission is the resource and action a method is authorized against
+type permission struct {
+	resource func(req interface{}) string
+	action   string
+}
+
+func servicePermission(action string) permission {
+	return permission{func(req interface{}) string { return pmsauth.ServiceResource(serviceNameOf(req)) }, action}
+}
+
+func policyPermission(action string) permission {
+	return permission{func(req interface{}) string { return pmsauth.PolicyResource(serviceNameOf(req)) }, action}
+}
+
+func rolePolicyPermission(action string) permission {
+	return permission{func(req interface{}) string { return pmsauth.RolePolicyResource(serviceNameOf(req)) }, action}
+}
+
+func functionPermission(action string) permission {
+	return permission{func(req interface{}) string { return pmsauth.FunctionResource(nameOf(req)) }, action}
+}
+
+func discoverPermission(action string) permission {
+	return permission{func(req interface{}) string { return pmsauth.DiscoverResource(serviceNameOf(req)) }, action}
+}
+
+// methodPermissions maps gRPC methods to the permissions required to call them
+var methodPermissions = map[string]permission{
+	methodPrefix + "CreateFunction":        functionPermission(pmsauth.ActionCreate),
+	methodPrefix + "QueryFunctions":        functionPermission(pmsauth.ActionRead),
+	methodPrefix + "DeleteFunctions":       functionPermission(pmsauth.ActionDelete),
+	methodPrefix + "CreateService":         {func(interface{}) string { return pmsauth.ServiceResource("") }, pmsauth.ActionCreate},
+	methodPrefix + "QueryServices":         servicePermission(pmsauth.ActionRead),
+	methodPrefix + "DeleteServices":        servicePermission(pmsauth.ActionDelete),
+	methodPrefix + "CreatePolicy":          policyPermission(pmsauth.ActionCreate),
+	methodPrefix + "QueryPolicies":         policyPermission(pmsauth.ActionRead),
+	methodPrefix + "DeletePolicies":        policyPermission(pmsauth.ActionDelete),
+	methodPrefix + "CreateRolePolicy":      rolePolicyPermission(pmsauth.ActionCreate),
+	methodPrefix + "QueryRolePolicies":     rolePolicyPermission(pmsauth.ActionRead),
+	methodPrefix + "DeleteRolePolicies":    rolePolicyPermission(pmsauth.ActionDelete),
+	methodPrefix + "ListPolicyCounts":      servicePermission(pmsauth.ActionRead),
+	methodPrefix + "GetDiscoverRequests":   discoverPermission(pmsauth.ActionRead),
+	methodPrefix + "ResetDiscoverRequests": discoverPermission(pmsauth.ActionDelete),
+	methodPrefix + "GetDiscoverPolicies":   discoverPermission(pmsauth.ActionRead),
+}
+
+// NewAuthzInterceptor creates a unary interceptor which authenticates the caller and authorizes
+// each policy management method with authorizer, resources are scoped to the tenant of the call
+func NewAuthzInterceptor(authorizer *pmsauth.Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, methodPrefix) {
+			// Methods out of policy management service, e.g. reflection, are not governed
+			return handler(ctx, req)
+		}
+
+		subject, err := authorizer.AuthenticateGRPC(ctx)
+		if err == nil {
+			// Policy management methods without permission are denied, so that no method is left unprotected
+			if perm, ok := methodPermissions[info.FullMethod]; ok {
+				resource := pmsauth.ScopedResource(svcs.TenantOfGRPC(ctx), perm.resource(req))
+				err = authorizer.Authorize(subject, resource, perm.action)
+			} else {
+				err = errors.Errorf(errors.Forbidden, "no permission is defined for %s", info.FullMethod)
+			}
+		}
+		if err != nil {
+			logging.WriteSimpleFailedAuditLog(info.FullMethod, req, err.Error())
+			return nil, toGRPCStatus(err)
+		}
+		return handler(ctx, req)
+	}
+}
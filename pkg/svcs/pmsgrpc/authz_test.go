@@ -0,0 +1,50 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsgrpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
+	"github.com/oracle/speedle/pkg/svcs/pmsgrpc/pb"
+)
+
+func TestAuthzInterceptor(t *testing.T) {
+	authorizer := pmsauth.NewWithEvaluator(&cfg.PMSAuthConfig{
+		AdminPrincipals: []string{"user:alice"},
+		APIKeys:         map[string]string{"alice-key": "alice"},
+	}, nil, nil)
+	interceptor := NewAuthzInterceptor(authorizer)
+	adminCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(pmsauth.APIKeyHeader, "alice-key"))
+
+	testcases := []struct {
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{adminCtx, methodPrefix + "QueryServices", codes.OK},
+		{context.Background(), methodPrefix + "QueryServices", codes.Unauthenticated},
+		// Policy management methods without permission are denied even for admin principals
+		{adminCtx, methodPrefix + "UnmappedMethod", codes.PermissionDenied},
+		{context.Background(), "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", codes.OK},
+	}
+	for _, tc := range testcases {
+		called := false
+		_, err := interceptor(tc.ctx, &pb.ServiceQueryRequest{}, &grpc.UnaryServerInfo{FullMethod: tc.method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			})
+		if status.Code(err) != tc.code || called != (tc.code == codes.OK) {
+			t.Errorf("%s: expected code %v, got %v, handler called: %v", tc.method, tc.code, err, called)
+		}
+	}
+}
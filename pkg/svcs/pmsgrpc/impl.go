@@ -19,20 +19,40 @@ import (
 	"github.com/oracle/speedle/api/ads"
 	"github.com/oracle/speedle/api/pms"
 
-	"strings"
-
 	"github.com/oracle/speedle/pkg/logging"
 )
 
 type serviceImpl struct {
 	policyStore pms.PolicyStoreManager
+	quotas      *pmsimpl.Quotas
 }
 
-// NewServiceImpl initializes a new PMS GRPC instance
+// NewServiceImpl initializes a new PMS GRPC instance, the default quota applies
 func NewServiceImpl(ps pms.PolicyStoreManager) *serviceImpl {
+	quotas, _ := pmsimpl.NewQuotas(nil)
+	return NewServiceImplWithQuotas(ps, quotas)
+}
+
+// NewServiceImplWithQuotas initializes a new PMS GRPC instance enforcing quotas, which may be shared with REST service
+func NewServiceImplWithQuotas(ps pms.PolicyStoreManager, quotas *pmsimpl.Quotas) *serviceImpl {
 	return &serviceImpl{
 		policyStore: ps,
+		quotas:      quotas,
+	}
+}
+
+// tenantScope returns the tenant scope resolved by the tenant interceptor, calls out of any tenant scope
+// are served by the default tenant
+func (impl *serviceImpl) tenantScope(ctx context.Context) *pmsimpl.TenantScope {
+	if scope, ok := pmsimpl.TenantScopeFrom(ctx); ok {
+		return scope
 	}
+	return &pmsimpl.TenantScope{Tenant: &pms.Tenant{Name: pms.DefaultTenant}, PolicyStore: impl.policyStore}
+}
+
+// storeOf returns the policy store of the tenant the call is scoped to
+func (impl *serviceImpl) storeOf(ctx context.Context) pms.PolicyStoreManager {
+	return impl.tenantScope(ctx).PolicyStore
 }
 
 func convertRPCFunction(rpcFunction *pb.Function) *pms.Function {
@@ -249,6 +269,10 @@ func toGRPCStatus(err error) error {
 		return status.Error(codes.ResourceExhausted, msg)
 	case errors.InvalidRequest:
 		return status.Error(codes.InvalidArgument, msg)
+	case errors.Unauthorized:
+		return status.Error(codes.Unauthenticated, msg)
+	case errors.Forbidden:
+		return status.Error(codes.PermissionDenied, msg)
 	default:
 		return status.Error(codes.Unknown, msg)
 	}
@@ -256,7 +280,11 @@ func toGRPCStatus(err error) error {
 
 func (impl *serviceImpl) CreateFunction(ctx context.Context, in *pb.Function) (*pb.Function, error) {
 	function := convertRPCFunction(in)
-	if function, err := impl.policyStore.CreateFunction(function); err != nil {
+	err := impl.quotas.CreateFunction(impl.tenantScope(ctx), function, func() error {
+		_, err := impl.storeOf(ctx).CreateFunction(function)
+		return err
+	})
+	if err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]CreateFunction", function, err.Error())
 		return nil, toGRPCStatus(err)
@@ -270,31 +298,22 @@ func (impl *serviceImpl) CreateFunction(ctx context.Context, in *pb.Function) (*
 
 func (impl *serviceImpl) QueryFunctions(ctx context.Context, in *pb.FunctionQueryRequest) (*pb.FunctionQueryResponse, error) {
 	var functions = []*pms.Function{}
+	var token string
 	// Audit contextual fields for request
 	ctxFields := map[string]interface{}{
 		"name":    in.Name,
 		"filters": in.Filters,
 	}
 	if len(in.Name) == 0 {
-		if len(in.Filters) != 0 && strings.HasPrefix(in.Filters, "name") { //Query by name
-			functionsMatched, err := impl.policyStore.ListAllFunctions(in.Filters)
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryFunctions", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			functions = functionsMatched
-		} else { // Query all functions
-			functionsMatched, err := impl.policyStore.ListAllFunctions("")
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryFunctions", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			functions = functionsMatched
+		var err error
+		opts := store.ListOptions{Filter: in.Filters, Limit: int(in.Limit), Continue: in.Continue}
+		if functions, token, err = store.ListFunctions(impl.storeOf(ctx), opts); err != nil {
+			// Audit log
+			logging.WriteFailedAuditLog("[gRPC]QueryFunctions", ctxFields, err.Error())
+			return nil, toGRPCStatus(err)
 		}
 	} else {
-		function, err := impl.policyStore.GetFunction(in.Name)
+		function, err := impl.storeOf(ctx).GetFunction(in.Name)
 		if err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]QueryFunctions", ctxFields, err.Error())
@@ -305,6 +324,7 @@ func (impl *serviceImpl) QueryFunctions(ctx context.Context, in *pb.FunctionQuer
 
 	retFunctions := pb.FunctionQueryResponse{
 		Functions: make([]*pb.Function, 0),
+		Continue:  token,
 	}
 	for _, f := range functions {
 		retFunctions.Functions = append(retFunctions.Functions, convertMetaFunction(f))
@@ -333,17 +353,19 @@ func (impl *serviceImpl) DeleteFunctions(ctx context.Context, in *pb.FunctionQue
 
 	//TODO: revisit the query related APIs, currently filter does not work for delete API.
 	if len(in.Name) == 0 {
-		if err := impl.policyStore.DeleteFunctions(); err != nil {
+		if err := impl.storeOf(ctx).DeleteFunctions(); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeleteFunctions", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.Invalidate(impl.storeOf(ctx))
 	} else {
-		if err := impl.policyStore.DeleteFunction(in.Name); err != nil {
+		if err := impl.storeOf(ctx).DeleteFunction(in.Name); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeleteFunctions", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.FunctionCountChanged(impl.storeOf(ctx), -1)
 	}
 
 	// Audit log
@@ -355,14 +377,10 @@ func (impl *serviceImpl) DeleteFunctions(ctx context.Context, in *pb.FunctionQue
 func (impl *serviceImpl) CreateService(ctx context.Context, in *pb.ServiceRequest) (*pb.Service, error) {
 	service := convertRPCServiceRequest(in)
 
-	err := pmsimpl.CheckService(service, impl.policyStore)
+	err := impl.quotas.CreateService(impl.tenantScope(ctx), service, func() error {
+		return impl.storeOf(ctx).CreateService(service)
+	})
 	if err != nil {
-		// Audit log
-		logging.WriteSimpleFailedAuditLog("[gRPC]CreateService", &service, err.Error())
-		return nil, toGRPCStatus(err)
-	}
-
-	if err := impl.policyStore.CreateService(service); err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]CreateService", service, err.Error())
 		return nil, toGRPCStatus(err)
@@ -376,16 +394,18 @@ func (impl *serviceImpl) CreateService(ctx context.Context, in *pb.ServiceReques
 
 func (impl *serviceImpl) QueryServices(ctx context.Context, in *pb.ServiceQueryRequest) (*pb.ServiceQueryResponse, error) {
 	var ss []*pms.Service
+	var token string
 	if len(in.Name) == 0 {
 		// Get all services
 		var err error
-		if ss, err = impl.policyStore.ListAllServices(); err != nil {
+		opts := store.ListOptions{Filter: in.Filters, Limit: int(in.Limit), Continue: in.Continue}
+		if ss, token, err = store.ListServices(impl.storeOf(ctx), opts); err != nil {
 			// Audit log
 			logging.WriteSimpleFailedAuditLog("[gRPC]QueryServices", in.Name, err.Error())
 			return nil, toGRPCStatus(err)
 		}
 	} else {
-		svc, err := impl.policyStore.GetService(in.Name)
+		svc, err := impl.storeOf(ctx).GetService(in.Name)
 		if err != nil {
 			// Audit log
 			logging.WriteSimpleFailedAuditLog("[gRPC]QueryServices", in.Name, err.Error())
@@ -395,6 +415,7 @@ func (impl *serviceImpl) QueryServices(ctx context.Context, in *pb.ServiceQueryR
 	}
 	ret := pb.ServiceQueryResponse{
 		Services: make([]*pb.Service, 0),
+		Continue: token,
 	}
 
 	for _, svc := range ss {
@@ -409,22 +430,24 @@ func (impl *serviceImpl) QueryServices(ctx context.Context, in *pb.ServiceQueryR
 
 func (impl *serviceImpl) DeleteServices(ctx context.Context, in *pb.ServiceQueryRequest) (*pb.Empty, error) {
 	if len(in.Name) == 0 {
-		if err := impl.policyStore.DeleteServices(); err != nil {
+		if err := impl.storeOf(ctx).DeleteServices(); err != nil {
 			// Audit log
 			logging.WriteSimpleFailedAuditLog("[gRPC]DeleteServices", in.Name, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.Invalidate(impl.storeOf(ctx))
 
 		// Audit log
 		logging.WriteSimpleSucceededAuditLog("[gRPC]DeleteServices", in.Name, nil)
 		return &pb.Empty{}, nil
 	}
 
-	if err := impl.policyStore.DeleteService(in.Name); err != nil {
+	if err := impl.storeOf(ctx).DeleteService(in.Name); err != nil {
 		// Audit log
 		logging.WriteSimpleFailedAuditLog("[gRPC]DeleteServices", in.Name, err.Error())
 		return nil, toGRPCStatus(err)
 	}
+	impl.quotas.ServiceDeleted(impl.storeOf(ctx), in.Name)
 
 	// Audit log for response
 	logging.WriteSimpleSucceededAuditLog("[gRPC]DeleteServices", in.Name, nil)
@@ -447,13 +470,11 @@ func (impl *serviceImpl) CreatePolicy(ctx context.Context, in *pb.PolicyRequest)
 
 	metaPolicy := convertRPCPolicy(in.Policy)
 
-	if err := pmsimpl.CheckPolicy(in.ServiceName, metaPolicy, impl.policyStore); err != nil {
-		// Audit log
-		logging.WriteSimpleFailedAuditLog("[gRPC]CreatePolicy", ctxFields, err.Error())
-		return nil, toGRPCStatus(err)
-	}
-
-	retPolicy, err := impl.policyStore.CreatePolicy(in.ServiceName, metaPolicy)
+	var retPolicy *pms.Policy
+	err := impl.quotas.CreatePolicy(impl.tenantScope(ctx), in.ServiceName, metaPolicy, func() (err error) {
+		retPolicy, err = impl.storeOf(ctx).CreatePolicy(in.ServiceName, metaPolicy)
+		return err
+	})
 	if err != nil {
 		// Audit log
 		logging.WriteFailedAuditLog("[gRPC]CreatePolicy", ctxFields, err.Error())
@@ -478,26 +499,17 @@ func (impl *serviceImpl) QueryPolicies(ctx context.Context, in *pb.PolicyQueryRe
 	}
 
 	var policies = []*pms.Policy{}
+	var token string
 	if len(in.PolicyID) == 0 {
-		if len(in.Filters) != 0 && strings.HasPrefix(in.Filters, "name") { //Query by name
-			policiesMatched, err := impl.policyStore.ListAllPolicies(in.ServiceName, in.Filters)
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryPolicies", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			policies = policiesMatched
-		} else { // Query all policies
-			service, err := impl.policyStore.GetService(in.ServiceName)
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryPolicies", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			policies = service.Policies
+		var err error
+		opts := store.ListOptions{Filter: in.Filters, Limit: int(in.Limit), Continue: in.Continue}
+		if policies, token, err = store.ListPolicies(impl.storeOf(ctx), in.ServiceName, opts); err != nil {
+			// Audit log
+			logging.WriteFailedAuditLog("[gRPC]QueryPolicies", ctxFields, err.Error())
+			return nil, toGRPCStatus(err)
 		}
 	} else {
-		policy, err := impl.policyStore.GetPolicy(in.ServiceName, in.PolicyID)
+		policy, err := impl.storeOf(ctx).GetPolicy(in.ServiceName, in.PolicyID)
 		if err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]QueryPolicies", ctxFields, err.Error())
@@ -508,6 +520,7 @@ func (impl *serviceImpl) QueryPolicies(ctx context.Context, in *pb.PolicyQueryRe
 
 	retPolicies := pb.PolicyQueryResponse{
 		Policies: make([]*pb.Policy, 0),
+		Continue: token,
 	}
 	for _, policy := range policies {
 		retPolicies.Policies = append(retPolicies.Policies, convertMetaPolicy(policy))
@@ -535,17 +548,19 @@ func (impl *serviceImpl) DeletePolicies(ctx context.Context, in *pb.PolicyQueryR
 	}
 
 	if len(in.PolicyID) == 0 {
-		if err := impl.policyStore.DeletePolicies(in.ServiceName); err != nil {
+		if err := impl.storeOf(ctx).DeletePolicies(in.ServiceName); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeletePolicies", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.Invalidate(impl.storeOf(ctx))
 	} else {
-		if err := impl.policyStore.DeletePolicy(in.ServiceName, in.PolicyID); err != nil {
+		if err := impl.storeOf(ctx).DeletePolicy(in.ServiceName, in.PolicyID); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeletePolicies", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.PolicyCountChanged(impl.storeOf(ctx), in.ServiceName, -1)
 	}
 
 	// Audit log
@@ -570,13 +585,11 @@ func (impl *serviceImpl) CreateRolePolicy(ctx context.Context, in *pb.RolePolicy
 
 	metaRolePolicy := convertRPCRolePolicy(in.RolePolicy)
 
-	if err := pmsimpl.CheckRolePolicy(in.ServiceName, metaRolePolicy, impl.policyStore); err != nil {
-		// Audit log
-		logging.WriteSimpleFailedAuditLog("[gRPC]CreateRolePolicy", ctxFields, err.Error())
-		return nil, toGRPCStatus(err)
-	}
-
-	retPolicy, err := impl.policyStore.CreateRolePolicy(in.ServiceName, metaRolePolicy)
+	var retPolicy *pms.RolePolicy
+	err := impl.quotas.CreateRolePolicy(impl.tenantScope(ctx), in.ServiceName, metaRolePolicy, func() (err error) {
+		retPolicy, err = impl.storeOf(ctx).CreateRolePolicy(in.ServiceName, metaRolePolicy)
+		return err
+	})
 	if err != nil {
 		// Audit log
 		logging.WriteFailedAuditLog("[gRPC]CreateRolePolicy", ctxFields, err.Error())
@@ -601,28 +614,19 @@ func (impl *serviceImpl) QueryRolePolicies(ctx context.Context, in *pb.RolePolic
 	}
 
 	var policies = []*pms.RolePolicy{}
+	var token string
 	if len(in.RolePolicyID) == 0 {
-		if len(in.Filters) != 0 && strings.HasPrefix(in.Filters, "name") { //Query by name
-			policiesMatched, err := impl.policyStore.ListAllRolePolicies(in.ServiceName, in.Filters)
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryRolePolicies", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			policies = policiesMatched
-		} else { // Query all policies
-			service, err := impl.policyStore.GetService(in.ServiceName)
-			if err != nil {
-				// Audit log
-				logging.WriteFailedAuditLog("[gRPC]QueryRolePolicies", ctxFields, err.Error())
-				return nil, toGRPCStatus(err)
-			}
-			policies = service.RolePolicies
+		var err error
+		opts := store.ListOptions{Filter: in.Filters, Limit: int(in.Limit), Continue: in.Continue}
+		if policies, token, err = store.ListRolePolicies(impl.storeOf(ctx), in.ServiceName, opts); err != nil {
+			// Audit log
+			logging.WriteFailedAuditLog("[gRPC]QueryRolePolicies", ctxFields, err.Error())
+			return nil, toGRPCStatus(err)
 		}
 		// Audit log
 		logging.WriteSucceededAuditLog("[gRPC]QueryRolePolicies", ctxFields, map[string]interface{}{"rolePolicyCount": len(policies)})
 	} else {
-		policy, err := impl.policyStore.GetRolePolicy(in.ServiceName, in.RolePolicyID)
+		policy, err := impl.storeOf(ctx).GetRolePolicy(in.ServiceName, in.RolePolicyID)
 		if err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]QueryRolePolicies", ctxFields, err.Error())
@@ -636,6 +640,7 @@ func (impl *serviceImpl) QueryRolePolicies(ctx context.Context, in *pb.RolePolic
 
 	retPolicies := pb.RolePolicyQueryResponse{
 		RolePolicies: make([]*pb.RolePolicy, 0),
+		Continue:     token,
 	}
 	for _, policy := range policies {
 		retPolicies.RolePolicies = append(retPolicies.RolePolicies, convertMetaRolePolicy(policy))
@@ -656,17 +661,19 @@ func (impl *serviceImpl) DeleteRolePolicies(ctx context.Context, in *pb.RolePoli
 	}
 
 	if len(in.RolePolicyID) == 0 {
-		if err := impl.policyStore.DeleteRolePolicies(in.ServiceName); err != nil {
+		if err := impl.storeOf(ctx).DeleteRolePolicies(in.ServiceName); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeleteRolePolicies", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.Invalidate(impl.storeOf(ctx))
 	} else {
-		if err := impl.policyStore.DeleteRolePolicy(in.ServiceName, in.RolePolicyID); err != nil {
+		if err := impl.storeOf(ctx).DeleteRolePolicy(in.ServiceName, in.RolePolicyID); err != nil {
 			// Audit log
 			logging.WriteFailedAuditLog("[gRPC]DeleteRolePolicies", ctxFields, err.Error())
 			return nil, toGRPCStatus(err)
 		}
+		impl.quotas.PolicyCountChanged(impl.storeOf(ctx), in.ServiceName, -1)
 	}
 
 	// Audit log
@@ -676,7 +683,7 @@ func (impl *serviceImpl) DeleteRolePolicies(ctx context.Context, in *pb.RolePoli
 }
 
 func (impl *serviceImpl) ListPolicyCounts(ctx context.Context, in *pb.Empty) (*pb.PolicyCountsMap, error) {
-	countsMap, err := impl.policyStore.GetPolicyAndRolePolicyCounts()
+	countsMap, err := impl.storeOf(ctx).GetPolicyAndRolePolicyCounts()
 	if err != nil {
 		// Audit log
 		logging.WriteFailedAuditLog("[gRPC]ListPolicyCounts", nil, err.Error())
@@ -698,7 +705,7 @@ func (impl *serviceImpl) ListPolicyCounts(ctx context.Context, in *pb.Empty) (*p
 }
 
 func (impl *serviceImpl) GetDiscoverRequests(ctx context.Context, in *pb.DiscoverRequestsRequest) (*pb.DiscoverRequestsResponse, error) {
-	discoverRequestMgr, _ := impl.policyStore.(store.DiscoverRequestManager)
+	discoverRequestMgr, _ := impl.storeOf(ctx).(store.DiscoverRequestManager)
 	last := in.Last
 	revision := in.Revision
 	serviceName := in.ServiceName
@@ -757,7 +764,7 @@ func (impl *serviceImpl) GetDiscoverRequests(ctx context.Context, in *pb.Discove
 
 }
 func (impl *serviceImpl) ResetDiscoverRequests(ctx context.Context, in *pb.ResetRequestsRequest) (*pb.ResetRequestsResponse, error) {
-	discoverRequestMgr, _ := impl.policyStore.(store.DiscoverRequestManager)
+	discoverRequestMgr, _ := impl.storeOf(ctx).(store.DiscoverRequestManager)
 	err := discoverRequestMgr.ResetDiscoverRequests(in.ServiceName)
 
 	// Audit log
@@ -771,7 +778,7 @@ func (impl *serviceImpl) ResetDiscoverRequests(ctx context.Context, in *pb.Reset
 }
 
 func (impl *serviceImpl) GetDiscoverPolicies(ctx context.Context, in *pb.DiscoverPoliciesRequest) (*pb.DiscoverPoliciesResponse, error) {
-	discoverRequestMgr, _ := impl.policyStore.(store.DiscoverRequestManager)
+	discoverRequestMgr, _ := impl.storeOf(ctx).(store.DiscoverRequestManager)
 	serviceMap, revision, err := discoverRequestMgr.GeneratePolicies(in.ServiceName, in.PrincipalType, in.PrincipalName, in.PrincipalIdd)
 
 	// Audit contextual fields for request
@@ -5,9 +5,11 @@
 Package pb is a generated protocol buffer package.
 
 It is generated from these files:
+
 	service.proto
 
 It has these top-level messages:
+
 	DiscoverRequestsRequest
 	Principal
 	Subject
@@ -424,6 +426,10 @@ func (m *Function) GetResultTTL() int64 {
 type FunctionQueryRequest struct {
 	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Filters string `protobuf:"bytes,2,opt,name=filters" json:"filters,omitempty"`
+	// max number of functions returned, 0 means no limit
+	Limit int32 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+	// token returned with the previous page
+	Continue string `protobuf:"bytes,4,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *FunctionQueryRequest) Reset()                    { *m = FunctionQueryRequest{} }
@@ -445,8 +451,24 @@ func (m *FunctionQueryRequest) GetFilters() string {
 	return ""
 }
 
+func (m *FunctionQueryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *FunctionQueryRequest) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type FunctionQueryResponse struct {
 	Functions []*Function `protobuf:"bytes,1,rep,name=functions" json:"functions,omitempty"`
+	// token to query the next page, empty for the last page
+	Continue string `protobuf:"bytes,2,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *FunctionQueryResponse) Reset()                    { *m = FunctionQueryResponse{} }
@@ -461,6 +483,13 @@ func (m *FunctionQueryResponse) GetFunctions() []*Function {
 	return nil
 }
 
+func (m *FunctionQueryResponse) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type AndPrincipals struct {
 	Principals []string `protobuf:"bytes,1,rep,name=principals" json:"principals,omitempty"`
 }
@@ -535,6 +564,8 @@ func (m *PolicyRequest) GetPolicy() *Policy {
 
 type ServiceQueryResponse struct {
 	Services []*Service `protobuf:"bytes,1,rep,name=services" json:"services,omitempty"`
+	// token to query the next page, empty for the last page
+	Continue string `protobuf:"bytes,2,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *ServiceQueryResponse) Reset()                    { *m = ServiceQueryResponse{} }
@@ -549,8 +580,20 @@ func (m *ServiceQueryResponse) GetServices() []*Service {
 	return nil
 }
 
+func (m *ServiceQueryResponse) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type ServiceQueryRequest struct {
-	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Filters string `protobuf:"bytes,2,opt,name=filters" json:"filters,omitempty"`
+	// max number of services returned, 0 means no limit
+	Limit int32 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+	// token returned with the previous page
+	Continue string `protobuf:"bytes,4,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *ServiceQueryRequest) Reset()                    { *m = ServiceQueryRequest{} }
@@ -565,10 +608,35 @@ func (m *ServiceQueryRequest) GetName() string {
 	return ""
 }
 
+func (m *ServiceQueryRequest) GetFilters() string {
+	if m != nil {
+		return m.Filters
+	}
+	return ""
+}
+
+func (m *ServiceQueryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ServiceQueryRequest) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type PolicyQueryRequest struct {
 	ServiceName string `protobuf:"bytes,1,opt,name=serviceName" json:"serviceName,omitempty"`
 	PolicyID    string `protobuf:"bytes,2,opt,name=policyID" json:"policyID,omitempty"`
 	Filters     string `protobuf:"bytes,3,opt,name=filters" json:"filters,omitempty"`
+	// max number of policies returned, 0 means no limit
+	Limit int32 `protobuf:"varint,4,opt,name=limit" json:"limit,omitempty"`
+	// token returned with the previous page
+	Continue string `protobuf:"bytes,5,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *PolicyQueryRequest) Reset()                    { *m = PolicyQueryRequest{} }
@@ -597,8 +665,24 @@ func (m *PolicyQueryRequest) GetFilters() string {
 	return ""
 }
 
+func (m *PolicyQueryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *PolicyQueryRequest) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type PolicyQueryResponse struct {
 	Policies []*Policy `protobuf:"bytes,1,rep,name=policies" json:"policies,omitempty"`
+	// token to query the next page, empty for the last page
+	Continue string `protobuf:"bytes,2,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *PolicyQueryResponse) Reset()                    { *m = PolicyQueryResponse{} }
@@ -613,6 +697,13 @@ func (m *PolicyQueryResponse) GetPolicies() []*Policy {
 	return nil
 }
 
+func (m *PolicyQueryResponse) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type Policy struct {
 	Id          string               `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Name        string               `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
@@ -729,6 +820,10 @@ type RolePolicyQueryRequest struct {
 	ServiceName  string `protobuf:"bytes,1,opt,name=serviceName" json:"serviceName,omitempty"`
 	RolePolicyID string `protobuf:"bytes,2,opt,name=rolePolicyID" json:"rolePolicyID,omitempty"`
 	Filters      string `protobuf:"bytes,3,opt,name=filters" json:"filters,omitempty"`
+	// max number of role policies returned, 0 means no limit
+	Limit int32 `protobuf:"varint,4,opt,name=limit" json:"limit,omitempty"`
+	// token returned with the previous page
+	Continue string `protobuf:"bytes,5,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *RolePolicyQueryRequest) Reset()                    { *m = RolePolicyQueryRequest{} }
@@ -757,8 +852,24 @@ func (m *RolePolicyQueryRequest) GetFilters() string {
 	return ""
 }
 
+func (m *RolePolicyQueryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *RolePolicyQueryRequest) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type RolePolicyQueryResponse struct {
 	RolePolicies []*RolePolicy `protobuf:"bytes,1,rep,name=rolePolicies" json:"rolePolicies,omitempty"`
+	// token to query the next page, empty for the last page
+	Continue string `protobuf:"bytes,2,opt,name=continue" json:"continue,omitempty"`
 }
 
 func (m *RolePolicyQueryResponse) Reset()                    { *m = RolePolicyQueryResponse{} }
@@ -773,6 +884,13 @@ func (m *RolePolicyQueryResponse) GetRolePolicies() []*RolePolicy {
 	return nil
 }
 
+func (m *RolePolicyQueryResponse) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
 type RolePolicy struct {
 	Id                  string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Name                string   `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
@@ -1529,93 +1647,95 @@ var _PolicyManager_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1400 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xdb, 0x6e, 0xdb, 0x46,
-	0x13, 0x16, 0x25, 0xeb, 0x34, 0xb2, 0x64, 0x79, 0x6d, 0xc7, 0x8c, 0xfe, 0xe4, 0x87, 0xbb, 0x6d,
-	0x53, 0x37, 0x40, 0x65, 0x44, 0xe9, 0xc1, 0x68, 0x61, 0x14, 0x8e, 0xac, 0x04, 0x46, 0x6d, 0xd7,
-	0xa5, 0x9d, 0x8b, 0xf6, 0xc6, 0xa0, 0xa8, 0x75, 0xca, 0x86, 0x21, 0x59, 0x2e, 0x65, 0x44, 0x6f,
-	0xd1, 0xeb, 0xde, 0xf4, 0x01, 0xda, 0x77, 0xe9, 0x4d, 0x5f, 0xa6, 0x77, 0xc5, 0x1e, 0xb8, 0xdc,
-	0xa5, 0x14, 0x1f, 0x8a, 0x5e, 0x89, 0x3b, 0x33, 0x3b, 0xf3, 0xcd, 0xec, 0x37, 0xb3, 0xa4, 0xa0,
-	0x4d, 0x49, 0x72, 0xe5, 0x7b, 0xa4, 0x1f, 0x27, 0x51, 0x1a, 0xa1, 0x72, 0x3c, 0xc6, 0xaf, 0x61,
-	0xf3, 0xc0, 0xa7, 0x5e, 0x74, 0x45, 0x12, 0x87, 0xfc, 0x3c, 0x25, 0x34, 0xa5, 0xf2, 0x17, 0x6d,
-	0x41, 0x4b, 0xda, 0x9f, 0xb8, 0x6f, 0x88, 0x6d, 0x6d, 0x59, 0xdb, 0x4d, 0x47, 0x17, 0x21, 0x04,
-	0x4b, 0x81, 0x4b, 0x53, 0xbb, 0xbc, 0x65, 0x6d, 0x37, 0x1c, 0xfe, 0x8c, 0x7a, 0xd0, 0x48, 0xc8,
-	0x95, 0x4f, 0xfd, 0x28, 0xb4, 0x2b, 0x5b, 0xd6, 0x76, 0xc5, 0x51, 0x6b, 0x3c, 0x82, 0xe6, 0x69,
-	0xe2, 0x87, 0x9e, 0x1f, 0xbb, 0x01, 0xdb, 0x9c, 0xce, 0xe2, 0xcc, 0x2f, 0x7f, 0x66, 0xb2, 0x90,
-	0xc5, 0x2a, 0x0b, 0x19, 0x7b, 0x46, 0x5d, 0xa8, 0xf8, 0x93, 0x09, 0xf7, 0xd5, 0x74, 0xd8, 0x23,
-	0x0e, 0xa0, 0x7e, 0x36, 0x1d, 0xff, 0x44, 0xbc, 0x14, 0x7d, 0x02, 0x10, 0x67, 0x1e, 0xa9, 0x6d,
-	0x6d, 0x55, 0xb6, 0x5b, 0x83, 0x76, 0x3f, 0x1e, 0xf7, 0x55, 0x1c, 0x47, 0x33, 0x40, 0x0f, 0xa0,
-	0x99, 0x46, 0xaf, 0x49, 0x78, 0xce, 0x02, 0x8b, 0x20, 0xb9, 0x00, 0xad, 0x43, 0x95, 0x2f, 0x64,
-	0x2c, 0xb1, 0xc0, 0xbf, 0x94, 0xa1, 0x33, 0x8c, 0xc2, 0x94, 0xbc, 0x4d, 0xb3, 0xca, 0x7c, 0x08,
-	0x75, 0x2a, 0x00, 0x70, 0xf4, 0xad, 0x41, 0x8b, 0x85, 0x94, 0x98, 0x9c, 0x4c, 0x57, 0x2c, 0x60,
-	0x79, 0xbe, 0x80, 0xbc, 0x58, 0x34, 0x9a, 0x26, 0x1e, 0x91, 0x41, 0xd5, 0x1a, 0xdd, 0x83, 0x9a,
-	0xeb, 0xa5, 0xac, 0x8c, 0x4b, 0x5c, 0x23, 0x57, 0xe8, 0x19, 0x80, 0x9b, 0xa6, 0x89, 0x3f, 0x9e,
-	0xa6, 0x84, 0xda, 0x55, 0x9e, 0x32, 0x66, 0xf1, 0x4d, 0x90, 0xfd, 0x7d, 0x65, 0x34, 0x0a, 0xd3,
-	0x64, 0xe6, 0x68, 0xbb, 0x7a, 0x7b, 0xb0, 0x52, 0x50, 0xb3, 0x32, 0xbf, 0x26, 0x33, 0x79, 0x1a,
-	0xec, 0x91, 0x95, 0xe3, 0xca, 0x0d, 0xa6, 0x19, 0x70, 0xb1, 0xf8, 0xb2, 0xbc, 0x6b, 0xe1, 0x4b,
-	0xb0, 0xe7, 0x49, 0x43, 0xe3, 0x28, 0xa4, 0x04, 0xf5, 0x59, 0x4a, 0x42, 0x26, 0xcf, 0x03, 0xcd,
-	0x83, 0x73, 0x94, 0x8d, 0xc1, 0x97, 0x72, 0x81, 0x2f, 0xbb, 0xb0, 0xee, 0x10, 0x4a, 0xd2, 0x3b,
-	0x33, 0x13, 0x6f, 0xc2, 0x46, 0x61, 0xa7, 0x80, 0x87, 0x7f, 0xb7, 0x72, 0xc2, 0x9f, 0x46, 0x81,
-	0xef, 0xf9, 0xe4, 0x0e, 0x84, 0xff, 0x00, 0xda, 0x8a, 0x4d, 0x1a, 0x87, 0x4c, 0xa1, 0x61, 0xc5,
-	0x3d, 0x55, 0x0a, 0x56, 0xdc, 0x17, 0x86, 0x65, 0x25, 0x38, 0x9c, 0x4c, 0xe4, 0x29, 0x1b, 0x32,
-	0x7c, 0x91, 0x17, 0x3a, 0x07, 0x2b, 0x0b, 0xfd, 0x11, 0x34, 0x24, 0xb4, 0xac, 0xd0, 0x82, 0x85,
-	0x42, 0xe6, 0x28, 0xe5, 0xb5, 0x15, 0xfe, 0xcb, 0x82, 0xc6, 0xf3, 0x69, 0x28, 0x98, 0x95, 0x75,
-	0x9f, 0xa5, 0x75, 0xdf, 0x16, 0xb4, 0x26, 0x84, 0x7a, 0x89, 0x1f, 0xa7, 0xd9, 0xfe, 0xa6, 0xa3,
-	0x8b, 0x90, 0x0d, 0xf5, 0xcb, 0x69, 0xe8, 0xbd, 0x4c, 0x02, 0x99, 0x67, 0xb6, 0x64, 0x19, 0x06,
-	0x91, 0xe7, 0x06, 0xcf, 0xa5, 0x5a, 0x66, 0xa8, 0xcb, 0x50, 0x07, 0xca, 0x9e, 0x6b, 0x57, 0xb9,
-	0xa6, 0xec, 0xb9, 0xe8, 0x11, 0x74, 0x12, 0x42, 0xa7, 0x41, 0x3a, 0x74, 0xbd, 0x1f, 0xdd, 0x71,
-	0x40, 0xec, 0x1a, 0x1f, 0x2e, 0x05, 0x29, 0xeb, 0x64, 0x21, 0x39, 0x3f, 0x3f, 0xb2, 0xeb, 0x3c,
-	0xab, 0x5c, 0x80, 0x0f, 0x60, 0x3d, 0xcb, 0xea, 0xbb, 0x29, 0x49, 0x66, 0xd9, 0x09, 0x2f, 0xca,
-	0x90, 0xe1, 0xf7, 0x83, 0x94, 0x24, 0x54, 0x66, 0x97, 0x2d, 0xf1, 0x10, 0x36, 0x0a, 0x5e, 0x64,
-	0xe9, 0x1f, 0x43, 0xf3, 0x52, 0x2a, 0xb2, 0xda, 0x2f, 0xb3, 0xda, 0x67, 0xd6, 0x4e, 0xae, 0xc6,
-	0x3b, 0xd0, 0xde, 0x0f, 0x27, 0xa7, 0xf9, 0x0c, 0xfa, 0xff, 0xdc, 0xc8, 0x6a, 0xea, 0x33, 0x0a,
-	0xd7, 0xa1, 0x3a, 0x7a, 0x13, 0xa7, 0x33, 0x7c, 0x08, 0x9d, 0xec, 0x30, 0xaf, 0x81, 0xff, 0xbe,
-	0x1c, 0xa3, 0x0c, 0x7b, 0x67, 0xb0, 0xa2, 0x51, 0x80, 0x71, 0x51, 0xcc, 0x55, 0xfc, 0x12, 0xda,
-	0x9c, 0x3f, 0xb3, 0xdb, 0x53, 0x1d, 0x43, 0x2d, 0xe6, 0x5b, 0xb8, 0xe7, 0xd6, 0x00, 0xf8, 0x54,
-	0x15, 0x4e, 0xa4, 0x06, 0x7f, 0x0d, 0xeb, 0x32, 0x96, 0x59, 0x9f, 0xdb, 0x52, 0x13, 0x7f, 0x0c,
-	0x6b, 0xa6, 0x83, 0x77, 0xe6, 0x89, 0x03, 0x40, 0x22, 0xba, 0x61, 0x79, 0x73, 0x1e, 0x3d, 0x68,
-	0x08, 0xb4, 0x87, 0x07, 0xf2, 0x7c, 0xd5, 0x5a, 0x3f, 0xfa, 0x8a, 0x79, 0xf4, 0x7b, 0xb0, 0x66,
-	0x44, 0x93, 0x89, 0x3d, 0x92, 0xce, 0x7c, 0x95, 0x98, 0x5e, 0x16, 0xa5, 0xc3, 0x7f, 0x96, 0xa1,
-	0x26, 0x84, 0x8c, 0xe0, 0xfe, 0x44, 0x02, 0x2b, 0xfb, 0x93, 0x85, 0x57, 0x1c, 0x86, 0x1a, 0xb9,
-	0xbc, 0x64, 0xd7, 0x49, 0x85, 0x9f, 0x22, 0x77, 0x3a, 0xe2, 0x12, 0x47, 0x6a, 0xd0, 0x17, 0xd0,
-	0x8a, 0x49, 0xf2, 0xc6, 0xa7, 0x94, 0xb3, 0x6e, 0x89, 0x47, 0xdf, 0xc8, 0xa3, 0xf7, 0x4f, 0x95,
-	0xd6, 0xd1, 0x2d, 0xd1, 0x13, 0x83, 0x6f, 0xe2, 0xbe, 0x58, 0x65, 0xfb, 0x0c, 0x5a, 0x16, 0xaf,
-	0x49, 0x2f, 0x0a, 0x27, 0x3e, 0x6f, 0xf9, 0x9a, 0xb8, 0x26, 0x95, 0xa0, 0x47, 0x01, 0xf2, 0x58,
-	0xc6, 0x15, 0x66, 0x15, 0xae, 0xb0, 0x1d, 0x58, 0xcb, 0x9e, 0x2f, 0xc8, 0xdb, 0x38, 0x21, 0x94,
-	0xe6, 0x43, 0x04, 0x65, 0xaa, 0x91, 0xd2, 0xb0, 0x03, 0x71, 0x65, 0x5b, 0x55, 0x78, 0x63, 0x64,
-	0x4b, 0x4c, 0x60, 0xd5, 0x89, 0x02, 0x72, 0x57, 0x16, 0xf7, 0x01, 0x12, 0xb5, 0x4d, 0x32, 0xb9,
-	0xc3, 0x92, 0xd7, 0x9c, 0x69, 0x16, 0xf8, 0x2d, 0xdc, 0xcb, 0x35, 0x77, 0x64, 0x1a, 0x86, 0xe5,
-	0xdc, 0x93, 0x62, 0x9b, 0x21, 0xbb, 0x86, 0x71, 0xc7, 0xb0, 0x39, 0x17, 0x59, 0xb2, 0x6e, 0xa0,
-	0x39, 0xce, 0x99, 0x57, 0x4c, 0xc3, 0xb0, 0xc1, 0x7f, 0x5b, 0x00, 0xb9, 0xf2, 0x3f, 0x63, 0xe1,
-	0x3a, 0x54, 0x59, 0x18, 0xc1, 0xbf, 0xa6, 0x23, 0x16, 0x85, 0x91, 0x56, 0x2d, 0x8e, 0x34, 0x39,
-	0xac, 0xf9, 0x61, 0x53, 0xbb, 0xc6, 0xd5, 0xb9, 0x00, 0x3d, 0x81, 0xf5, 0x05, 0x2c, 0xa1, 0x76,
-	0x9d, 0x1b, 0xae, 0xcd, 0xd3, 0xa4, 0x40, 0xd0, 0x46, 0x81, 0xa0, 0xf8, 0x37, 0x0b, 0xea, 0x72,
-	0xac, 0xfc, 0xeb, 0x91, 0x69, 0xb4, 0x7a, 0xe5, 0xdd, 0xad, 0x8e, 0x9e, 0x42, 0x9b, 0x15, 0xe1,
-	0x42, 0x19, 0x2f, 0xdd, 0xe2, 0x74, 0x5e, 0xc1, 0x7d, 0x21, 0xdf, 0x0f, 0x27, 0xb9, 0xd1, 0x30,
-	0x9a, 0x86, 0x29, 0x65, 0x4c, 0x8b, 0xf3, 0x35, 0x47, 0x5e, 0x71, 0x74, 0x11, 0xda, 0x86, 0x95,
-	0xc4, 0xdc, 0x25, 0x2f, 0xf6, 0xa2, 0x18, 0xff, 0x61, 0xc1, 0x8a, 0xee, 0xfc, 0xd8, 0x8d, 0xd1,
-	0x1e, 0x34, 0x3c, 0xb6, 0x38, 0x76, 0x63, 0x49, 0xa5, 0xf7, 0xf2, 0xcc, 0x94, 0x59, 0x7f, 0x28,
-	0x6d, 0xc4, 0xdb, 0xa3, 0xda, 0xd2, 0xfb, 0x01, 0xda, 0x86, 0x6a, 0xc1, 0x9b, 0xe3, 0x53, 0xfd,
-	0xcd, 0xb1, 0x35, 0x78, 0x98, 0xbb, 0x5f, 0x90, 0xaf, 0xf6, 0x62, 0xf9, 0xf8, 0x21, 0xd4, 0x04,
-	0xe1, 0x50, 0x13, 0xaa, 0x2f, 0x9c, 0xfd, 0x93, 0xf3, 0x6e, 0x09, 0x35, 0x60, 0xe9, 0x60, 0x74,
-	0xf2, 0x7d, 0xd7, 0x7a, 0xbc, 0x03, 0x2d, 0xed, 0xa0, 0xd0, 0x0a, 0xb4, 0xf6, 0x4f, 0x4f, 0x8f,
-	0x0e, 0x87, 0xfb, 0xe7, 0x87, 0xdf, 0x9e, 0x74, 0x4b, 0x4c, 0xf0, 0xcd, 0xee, 0xd9, 0xc5, 0xf0,
-	0xe8, 0xe5, 0xd9, 0xf9, 0xc8, 0xe9, 0x5a, 0x83, 0x5f, 0x1b, 0xd9, 0xc5, 0x77, 0xec, 0x86, 0xee,
-	0x2b, 0x92, 0xa0, 0x3e, 0x74, 0x86, 0x09, 0x71, 0x53, 0xa2, 0xde, 0x7a, 0x8c, 0x9b, 0xbb, 0x67,
-	0xac, 0x70, 0x09, 0xbd, 0x80, 0x0e, 0x6f, 0xc6, 0x4c, 0x44, 0x91, 0xad, 0x5b, 0xe8, 0x23, 0xa2,
-	0x77, 0x7f, 0x81, 0x46, 0xbe, 0x76, 0x96, 0xd0, 0x2e, 0xac, 0x1c, 0x90, 0x80, 0xe4, 0x81, 0xaf,
-	0xf3, 0xd4, 0xe4, 0xad, 0xc7, 0xdf, 0x02, 0x4a, 0x68, 0x00, 0x6d, 0x01, 0x59, 0x71, 0x5a, 0xbf,
-	0x4c, 0xe5, 0x0e, 0xfd, 0x82, 0xc5, 0x25, 0x74, 0x00, 0x6d, 0xee, 0xf0, 0x2c, 0x7b, 0x09, 0xdc,
-	0xd4, 0xf4, 0x46, 0x28, 0x7b, 0x5e, 0xa1, 0x30, 0x7f, 0x0e, 0x1d, 0x81, 0xf9, 0x66, 0x37, 0x06,
-	0xe2, 0x1d, 0x58, 0x16, 0x88, 0xe5, 0xf4, 0x59, 0xd5, 0x3a, 0x47, 0xda, 0x6b, 0xcd, 0x84, 0x4b,
-	0xe8, 0x99, 0x84, 0x9b, 0x35, 0x08, 0xba, 0x97, 0xab, 0x8d, 0x30, 0x9b, 0x73, 0x72, 0x05, 0xf6,
-	0xb3, 0x0c, 0xec, 0x8d, 0x4e, 0x0c, 0xac, 0x5f, 0x41, 0x57, 0x60, 0xd5, 0xa6, 0xe5, 0x46, 0xa1,
-	0x79, 0xe5, 0xbe, 0x42, 0x4f, 0xe3, 0x12, 0x3a, 0x81, 0x55, 0xe1, 0x59, 0x6b, 0x6e, 0xd4, 0x33,
-	0xcd, 0x8c, 0xd0, 0xff, 0x5b, 0xa8, 0x53, 0x39, 0xec, 0x01, 0x12, 0x39, 0xdc, 0xda, 0xa1, 0x91,
-	0xcb, 0xa7, 0xd0, 0x3d, 0xf2, 0x69, 0x6a, 0x4c, 0x93, 0xdc, 0xa0, 0xb7, 0xb6, 0xa0, 0xcd, 0x71,
-	0x09, 0x39, 0xb0, 0xf6, 0x82, 0xa4, 0xc5, 0x0f, 0x3a, 0xc4, 0xa1, 0xbe, 0xe3, 0xbf, 0x81, 0xde,
-	0x83, 0xc5, 0x4a, 0x95, 0xc8, 0x89, 0xfc, 0xfe, 0x9a, 0xf3, 0xca, 0xe9, 0xb6, 0xe8, 0xa3, 0x4e,
-	0x74, 0xcf, 0xe2, 0x8f, 0xb6, 0x22, 0x46, 0x55, 0x19, 0x03, 0x63, 0xe1, 0x73, 0xce, 0xc4, 0x58,
-	0xfc, 0x7c, 0xc2, 0xa5, 0x71, 0x8d, 0xff, 0x0b, 0xf2, 0xf4, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff,
-	0x77, 0xb6, 0x2a, 0x0a, 0x16, 0x11, 0x00, 0x00,
+	// 1438 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xcd, 0x72, 0x1b, 0x45,
+	0x10, 0xd6, 0x4a, 0xd6, 0x5f, 0xcb, 0x92, 0xe5, 0xb1, 0x1d, 0x6f, 0x44, 0x92, 0x32, 0x03, 0x04,
+	0x57, 0xaa, 0x90, 0x2b, 0x0a, 0x3f, 0x2e, 0xa8, 0x1c, 0x1c, 0x59, 0x49, 0xb9, 0x70, 0x8c, 0x59,
+	0x3b, 0x87, 0xc0, 0xc1, 0xb5, 0x5e, 0x8d, 0xc3, 0x92, 0xf5, 0xee, 0xb2, 0xbb, 0x72, 0x45, 0x6f,
+	0xc1, 0x99, 0x03, 0xdc, 0x81, 0x77, 0xe1, 0xc2, 0xcb, 0x70, 0xa3, 0xe6, 0x77, 0x67, 0x56, 0xb2,
+	0x1d, 0x53, 0x29, 0x4e, 0xde, 0xe9, 0xee, 0xe9, 0xfe, 0xba, 0xe7, 0xeb, 0x9e, 0x91, 0xa1, 0x9d,
+	0x92, 0xe4, 0xc2, 0xf7, 0x48, 0x3f, 0x4e, 0xa2, 0x2c, 0x42, 0xe5, 0xf8, 0x14, 0xbf, 0x86, 0xf5,
+	0x5d, 0x3f, 0xf5, 0xa2, 0x0b, 0x92, 0x38, 0xe4, 0xa7, 0x09, 0x49, 0xb3, 0x54, 0xfc, 0x45, 0x1b,
+	0xd0, 0x12, 0xf6, 0x07, 0xee, 0x39, 0xb1, 0xad, 0x0d, 0x6b, 0xb3, 0xe9, 0xe8, 0x22, 0x84, 0x60,
+	0x21, 0x70, 0xd3, 0xcc, 0x2e, 0x6f, 0x58, 0x9b, 0x0d, 0x87, 0x7d, 0xa3, 0x1e, 0x34, 0x12, 0x72,
+	0xe1, 0xa7, 0x7e, 0x14, 0xda, 0x95, 0x0d, 0x6b, 0xb3, 0xe2, 0xa8, 0x35, 0x1e, 0x41, 0xf3, 0x30,
+	0xf1, 0x43, 0xcf, 0x8f, 0xdd, 0x80, 0x6e, 0xce, 0xa6, 0xb1, 0xf4, 0xcb, 0xbe, 0xa9, 0x2c, 0xa4,
+	0xb1, 0xca, 0x5c, 0x46, 0xbf, 0x51, 0x17, 0x2a, 0xfe, 0x78, 0xcc, 0x7c, 0x35, 0x1d, 0xfa, 0x89,
+	0x03, 0xa8, 0x1f, 0x4d, 0x4e, 0x7f, 0x24, 0x5e, 0x86, 0x3e, 0x01, 0x88, 0xa5, 0xc7, 0xd4, 0xb6,
+	0x36, 0x2a, 0x9b, 0xad, 0x41, 0xbb, 0x1f, 0x9f, 0xf6, 0x55, 0x1c, 0x47, 0x33, 0x40, 0x77, 0xa0,
+	0x99, 0x45, 0xaf, 0x49, 0x78, 0x3c, 0x8d, 0x65, 0x90, 0x5c, 0x80, 0x56, 0xa1, 0xca, 0x16, 0x22,
+	0x16, 0x5f, 0xe0, 0x9f, 0xcb, 0xd0, 0x19, 0x46, 0x61, 0x46, 0xde, 0x64, 0xb2, 0x32, 0x1f, 0x41,
+	0x3d, 0xe5, 0x00, 0x18, 0xfa, 0xd6, 0xa0, 0x45, 0x43, 0x0a, 0x4c, 0x8e, 0xd4, 0x15, 0x0b, 0x58,
+	0x9e, 0x2d, 0x20, 0x2b, 0x56, 0x1a, 0x4d, 0x12, 0x8f, 0x88, 0xa0, 0x6a, 0x8d, 0x6e, 0x41, 0xcd,
+	0xf5, 0x32, 0x5a, 0xc6, 0x05, 0xa6, 0x11, 0x2b, 0xf4, 0x04, 0xc0, 0xcd, 0xb2, 0xc4, 0x3f, 0x9d,
+	0x64, 0x24, 0xb5, 0xab, 0x2c, 0x65, 0x4c, 0xe3, 0x9b, 0x20, 0xfb, 0x3b, 0xca, 0x68, 0x14, 0x66,
+	0xc9, 0xd4, 0xd1, 0x76, 0xf5, 0x1e, 0xc3, 0x52, 0x41, 0x4d, 0xcb, 0xfc, 0x9a, 0x4c, 0xc5, 0x69,
+	0xd0, 0x4f, 0x5a, 0x8e, 0x0b, 0x37, 0x98, 0x48, 0xe0, 0x7c, 0xf1, 0x65, 0x79, 0xdb, 0xc2, 0x67,
+	0x60, 0xcf, 0x92, 0x26, 0x8d, 0xa3, 0x30, 0x25, 0xa8, 0x4f, 0x53, 0xe2, 0x32, 0x71, 0x1e, 0x68,
+	0x16, 0x9c, 0xa3, 0x6c, 0x0c, 0xbe, 0x94, 0x0b, 0x7c, 0xd9, 0x86, 0x55, 0x87, 0xa4, 0x24, 0xbb,
+	0x31, 0x33, 0xf1, 0x3a, 0xac, 0x15, 0x76, 0x72, 0x78, 0xf8, 0x0f, 0x2b, 0x27, 0xfc, 0x61, 0x14,
+	0xf8, 0x9e, 0x4f, 0x6e, 0x40, 0xf8, 0x0f, 0xa1, 0xad, 0xd8, 0xa4, 0x71, 0xc8, 0x14, 0x1a, 0x56,
+	0xcc, 0x53, 0xa5, 0x60, 0xc5, 0x7c, 0x61, 0x58, 0x54, 0x82, 0xbd, 0xf1, 0x58, 0x9c, 0xb2, 0x21,
+	0xc3, 0x27, 0x60, 0xcf, 0x82, 0x15, 0x85, 0xfe, 0x18, 0x1a, 0x02, 0x9a, 0x2c, 0x34, 0x67, 0x21,
+	0x97, 0x39, 0x4a, 0x79, 0x65, 0x85, 0xff, 0xb6, 0xa0, 0xf1, 0x74, 0x12, 0x72, 0x66, 0xc9, 0xee,
+	0xb3, 0xb4, 0xee, 0xdb, 0x80, 0xd6, 0x98, 0xa4, 0x5e, 0xe2, 0xc7, 0x99, 0xdc, 0xdf, 0x74, 0x74,
+	0x11, 0xb2, 0xa1, 0x7e, 0x36, 0x09, 0xbd, 0x17, 0x49, 0x20, 0xf2, 0x94, 0x4b, 0x9a, 0x61, 0x10,
+	0x79, 0x6e, 0xf0, 0x54, 0xa8, 0x45, 0x86, 0xba, 0x0c, 0x75, 0xa0, 0xec, 0xb9, 0x76, 0x95, 0x69,
+	0xca, 0x9e, 0x8b, 0xee, 0x43, 0x27, 0x21, 0xe9, 0x24, 0xc8, 0x86, 0xae, 0xf7, 0x83, 0x7b, 0x1a,
+	0x10, 0xbb, 0xc6, 0x86, 0x4b, 0x41, 0x4a, 0x3b, 0x99, 0x4b, 0x8e, 0x8f, 0xf7, 0xed, 0x3a, 0xcb,
+	0x2a, 0x17, 0xe0, 0x0b, 0x58, 0x95, 0x59, 0x7d, 0x3b, 0x21, 0xc9, 0x54, 0x9e, 0xf0, 0xbc, 0x0c,
+	0x29, 0x7e, 0x3f, 0xc8, 0x48, 0x92, 0x8a, 0xec, 0xe4, 0x92, 0x36, 0x40, 0xe0, 0x9f, 0xfb, 0x19,
+	0xcb, 0xab, 0xea, 0xf0, 0x05, 0x2d, 0xa7, 0x17, 0x85, 0x99, 0x1f, 0x4e, 0x88, 0xc8, 0x48, 0xad,
+	0xf1, 0x09, 0xac, 0x15, 0xe2, 0x8a, 0xc3, 0x7a, 0x00, 0xcd, 0x33, 0xa1, 0x90, 0xa7, 0xb5, 0x48,
+	0x4f, 0x4b, 0x5a, 0x3b, 0xb9, 0xda, 0x08, 0x50, 0x2e, 0x04, 0xd8, 0x82, 0xf6, 0x4e, 0x38, 0x3e,
+	0xcc, 0x27, 0xda, 0xbd, 0x99, 0x01, 0xd8, 0xd4, 0x27, 0x1e, 0xae, 0x43, 0x75, 0x74, 0x1e, 0x67,
+	0x53, 0xbc, 0x07, 0x1d, 0x49, 0x8d, 0x2b, 0x8a, 0xf1, 0x81, 0x18, 0xca, 0x34, 0x6e, 0x67, 0xb0,
+	0xa4, 0x11, 0x8a, 0x32, 0x9b, 0x4f, 0x69, 0xfc, 0x02, 0xda, 0x8c, 0x8d, 0xd3, 0xb7, 0x6f, 0x1c,
+	0x0c, 0xb5, 0x98, 0x6d, 0x61, 0x9e, 0x5b, 0x03, 0x60, 0x33, 0x9a, 0x3b, 0x11, 0x1a, 0xfc, 0x3d,
+	0xac, 0x8a, 0x58, 0x66, 0xed, 0x6e, 0x42, 0xf4, 0x4b, 0x0b, 0x37, 0x81, 0x15, 0xd3, 0xf9, 0xff,
+	0x43, 0x88, 0x5f, 0x2d, 0x40, 0x3c, 0x4d, 0x23, 0xec, 0xf5, 0x05, 0xeb, 0x41, 0x83, 0x97, 0x65,
+	0x6f, 0x57, 0xe6, 0x22, 0xd7, 0x3a, 0xc0, 0xca, 0x25, 0x00, 0x17, 0x2e, 0x03, 0x58, 0x2d, 0x00,
+	0x7c, 0x09, 0x2b, 0x06, 0x3e, 0x51, 0xf3, 0xfb, 0x22, 0xbc, 0xaf, 0x6a, 0xae, 0x9f, 0x98, 0xd2,
+	0x5d, 0x59, 0xf2, 0xbf, 0xca, 0x50, 0xe3, 0x1b, 0x68, 0x97, 0xfb, 0x63, 0x91, 0x66, 0xd9, 0x1f,
+	0xcf, 0xbd, 0xe7, 0x31, 0xd4, 0xc8, 0xd9, 0x19, 0xbd, 0x53, 0x2b, 0x8c, 0x7c, 0x2c, 0xe0, 0x88,
+	0x49, 0x1c, 0xa1, 0x41, 0x5f, 0x40, 0x2b, 0x26, 0xc9, 0xb9, 0x9f, 0xa6, 0xac, 0x91, 0x16, 0x18,
+	0xb2, 0xb5, 0x1c, 0x59, 0xff, 0x50, 0x69, 0x1d, 0xdd, 0x12, 0x3d, 0x34, 0xda, 0x84, 0x5f, 0x9a,
+	0xcb, 0x74, 0x9f, 0xd1, 0x4d, 0xc5, 0xb7, 0x82, 0x17, 0x85, 0x63, 0x9f, 0xcd, 0xbd, 0x1a, 0x7f,
+	0x2b, 0x28, 0x41, 0x2f, 0x05, 0xc8, 0x63, 0x19, 0xf7, 0xb8, 0x55, 0xb8, 0xc7, 0xb7, 0x60, 0x45,
+	0x7e, 0x9f, 0x90, 0x37, 0x71, 0x42, 0xd2, 0x34, 0x9f, 0xa4, 0x48, 0xaa, 0x46, 0x4a, 0x43, 0x8f,
+	0xd7, 0x15, 0x93, 0xa2, 0xc2, 0xfa, 0x59, 0x2e, 0x31, 0x81, 0x65, 0x27, 0x0a, 0xc8, 0x4d, 0x9b,
+	0xaf, 0x0f, 0x90, 0xa8, 0x6d, 0xa2, 0x01, 0x3b, 0x34, 0x79, 0xcd, 0x99, 0x66, 0x81, 0x7f, 0xb7,
+	0xe0, 0x56, 0xae, 0xba, 0x21, 0x71, 0x31, 0x2c, 0xe6, 0xae, 0x14, 0x79, 0x0d, 0xd9, 0x3b, 0x25,
+	0xb0, 0x0f, 0xeb, 0x33, 0x58, 0x05, 0x89, 0x07, 0x1a, 0x94, 0x9c, 0xc8, 0xc5, 0xcc, 0x0d, 0x9b,
+	0x2b, 0x09, 0xfd, 0x8f, 0x05, 0x90, 0x6f, 0x7c, 0x67, 0xa4, 0x5e, 0x85, 0x2a, 0x85, 0xc0, 0xe9,
+	0xdc, 0x74, 0xf8, 0x02, 0xdd, 0x9b, 0x61, 0x6c, 0xb3, 0x48, 0x4f, 0xc9, 0x9d, 0xd4, 0xae, 0x31,
+	0x75, 0x2e, 0x40, 0x0f, 0x61, 0x75, 0x0e, 0xe9, 0x52, 0xbb, 0xce, 0x0c, 0x57, 0x66, 0x59, 0x57,
+	0xe0, 0x7b, 0xa3, 0xc0, 0x77, 0xfc, 0x9b, 0x05, 0x75, 0x31, 0x40, 0xff, 0xf3, 0xc5, 0x61, 0x4c,
+	0x95, 0xca, 0x15, 0x53, 0xe5, 0x11, 0xb4, 0x69, 0x11, 0x4e, 0x94, 0xf1, 0xc2, 0xf5, 0x27, 0x87,
+	0x5f, 0xc1, 0x6d, 0x2e, 0xdf, 0x09, 0xc7, 0xb9, 0xd1, 0x30, 0x9a, 0x84, 0x59, 0x4a, 0x79, 0x1b,
+	0xe7, 0x6b, 0x86, 0xbc, 0xe2, 0xe8, 0x22, 0xb4, 0x09, 0x4b, 0x89, 0xb9, 0x4b, 0x3c, 0x96, 0x8a,
+	0x62, 0xfc, 0xa7, 0x05, 0x4b, 0xba, 0xf3, 0xe7, 0x6e, 0x8c, 0x1e, 0x53, 0xda, 0x4c, 0xc2, 0xec,
+	0xb9, 0x1b, 0x0b, 0x9a, 0xbd, 0x9f, 0x67, 0xa6, 0xcc, 0xfa, 0x43, 0x61, 0xc3, 0x5f, 0xe4, 0x6a,
+	0x4b, 0xef, 0x3b, 0x68, 0x1b, 0xaa, 0x39, 0xaf, 0xf1, 0x47, 0xfa, 0x6b, 0xbc, 0x35, 0xb8, 0x9b,
+	0xbb, 0x9f, 0x93, 0xaf, 0xf6, 0x58, 0x7f, 0x70, 0x17, 0x6a, 0x9c, 0x70, 0xa8, 0x09, 0xd5, 0x67,
+	0xce, 0xce, 0xc1, 0x71, 0xb7, 0x84, 0x1a, 0xb0, 0xb0, 0x3b, 0x3a, 0x78, 0xd9, 0xb5, 0x1e, 0x6c,
+	0x41, 0x4b, 0x3b, 0x28, 0xb4, 0x04, 0xad, 0x9d, 0xc3, 0xc3, 0xfd, 0xbd, 0xe1, 0xce, 0xf1, 0xde,
+	0x37, 0x07, 0xdd, 0x12, 0x15, 0x7c, 0xbd, 0x7d, 0x74, 0x32, 0xdc, 0x7f, 0x71, 0x74, 0x3c, 0x72,
+	0xba, 0xd6, 0xe0, 0x97, 0x86, 0xbc, 0xfe, 0x9f, 0xbb, 0xa1, 0xfb, 0x8a, 0x24, 0xa8, 0x0f, 0x9d,
+	0x61, 0x42, 0xdc, 0x8c, 0xa8, 0x97, 0xa4, 0xf1, 0xb6, 0xe9, 0x19, 0x2b, 0x5c, 0x42, 0xcf, 0xa0,
+	0xc3, 0x1a, 0xf5, 0xa9, 0x7a, 0xf2, 0xd8, 0xba, 0x85, 0x3e, 0x70, 0x7a, 0xb7, 0xe7, 0x68, 0xc4,
+	0x53, 0xbe, 0x84, 0xb6, 0x61, 0x69, 0x97, 0x04, 0x24, 0x23, 0x6f, 0xe3, 0xa9, 0xc9, 0x5a, 0x8f,
+	0xbd, 0x85, 0x4a, 0x68, 0x00, 0x6d, 0x0e, 0x59, 0x71, 0x5a, 0x7f, 0x52, 0x88, 0x1d, 0xfa, 0x33,
+	0x03, 0x97, 0xd0, 0x2e, 0xb4, 0x99, 0xc3, 0x23, 0xf9, 0xde, 0x58, 0xd7, 0xf4, 0x46, 0x28, 0x7b,
+	0x56, 0xa1, 0x30, 0x7f, 0x0e, 0x1d, 0x8e, 0xf9, 0x7a, 0x37, 0x06, 0xe2, 0x2d, 0x58, 0xe4, 0x88,
+	0xc5, 0xf4, 0x59, 0xd6, 0x3a, 0x47, 0xd8, 0x6b, 0xcd, 0x84, 0x4b, 0xe8, 0x89, 0x80, 0xab, 0x46,
+	0xdb, 0xad, 0x5c, 0x6d, 0x84, 0x59, 0x9f, 0x91, 0x2b, 0xb0, 0x9f, 0x49, 0xb0, 0xd7, 0x3a, 0x31,
+	0xb0, 0x7e, 0x05, 0x5d, 0x8e, 0x55, 0x9b, 0x96, 0x6b, 0x85, 0xe6, 0x15, 0xfb, 0x0a, 0x3d, 0x8d,
+	0x4b, 0xe8, 0x00, 0x96, 0xb9, 0x67, 0x63, 0x2c, 0x9b, 0x66, 0x46, 0xe8, 0xf7, 0xe6, 0xea, 0x54,
+	0x0e, 0x8f, 0x01, 0xf1, 0x1c, 0xde, 0xda, 0xa1, 0x91, 0xcb, 0xa7, 0xd0, 0xdd, 0xf7, 0xd3, 0xcc,
+	0x98, 0x26, 0xb9, 0x41, 0x6f, 0x65, 0x4e, 0x9b, 0xe3, 0x12, 0x72, 0x60, 0xe5, 0x19, 0xc9, 0x8a,
+	0x3f, 0x92, 0x11, 0x83, 0x7a, 0xc9, 0xff, 0x5b, 0x7a, 0x77, 0xe6, 0x2b, 0x55, 0x22, 0x07, 0xe2,
+	0x37, 0xed, 0x8c, 0x57, 0x46, 0xb7, 0x79, 0x3f, 0x94, 0x7b, 0xb7, 0xe7, 0x68, 0x94, 0x3f, 0x13,
+	0xa3, 0xaa, 0x8c, 0x81, 0xb1, 0xf0, 0x13, 0xb9, 0x77, 0x67, 0xbe, 0x52, 0xfa, 0x3c, 0xad, 0xb1,
+	0xff, 0x2c, 0x3d, 0xfa, 0x77, 0x00, 0x1e, 0x10, 0x79, 0x79, 0x6a, 0x12, 0x00, 0x00,
 }
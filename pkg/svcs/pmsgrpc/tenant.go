@@ -0,0 +1,49 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsgrpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/svcs"
+	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
+)
+
+// NewTenantInterceptor creates a unary interceptor which scopes each policy management call to the tenant
+// in Speedle-Tenant metadata, ps is the policy store of the default tenant.
+// tenants is nil if the policy store doesn't support multi-tenancy.
+func NewTenantInterceptor(tenants pms.TenantManager, ps pms.PolicyStoreManager) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, methodPrefix) {
+			return handler(ctx, req)
+		}
+
+		tenantName := svcs.TenantOfGRPC(ctx)
+		scope, err := pmsimpl.ResolveTenantScope(tenants, ps, tenantName)
+		if err != nil {
+			logging.WriteSimpleFailedAuditLog(info.FullMethod, tenantName, err.Error())
+			return nil, toGRPCStatus(err)
+		}
+		return handler(pmsimpl.WithTenantScope(ctx, scope), req)
+	}
+}
+
+// ChainUnaryInterceptors chains unary interceptors into one, the first one is the outermost
+func ChainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
@@ -0,0 +1,345 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsimpl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// UsageRecountInterval is how often the usage of a policy store is recounted from the store, so that the
+// entities created or deleted by other servers or tools sharing the store are taken into account
+var UsageRecountInterval = time.Minute
+
+// Quotas holds the quota applied to all the tenants, which can be adjusted at runtime, and the usage of
+// each policy store. Usage is counted from the store on first use and every UsageRecountInterval, and
+// maintained incrementally in between as entities are created and deleted by this server. An entity is
+// checked against the limits and written to the store while the usage of the store is locked, so that
+// concurrent requests can't exceed the limits together.
+type Quotas struct {
+	sync.Mutex
+	// global is the quota of server configuration
+	global *pms.Quota
+	// tenants persists the global quota changed at runtime as the quota of the default tenant,
+	// it is nil if the policy store doesn't support multi-tenancy
+	tenants pms.TenantManager
+	usages  map[pms.PolicyStoreManager]*usage
+}
+
+type usage struct {
+	// Mutex is held from the check of a creation to its write in the store, and while the usage is counted
+	sync.Mutex
+	// counted is when the usage is counted from the store, it is zero if the usage must be counted again
+	counted     time.Time
+	serviceNum  int64
+	functionNum int64
+	// number of Policy + RolePolicy of each service
+	services map[string]int64
+}
+
+func (u *usage) policyNum() int64 {
+	var num int64
+	for _, count := range u.services {
+		num += count
+	}
+	return num
+}
+
+// NewQuotas creates the quotas whose global quota is global, the limits not set in global default to DefaultQuota
+func NewQuotas(global *pms.Quota) (*Quotas, error) {
+	return NewQuotasWithTenants(global, nil)
+}
+
+// NewQuotasWithTenants creates the quotas whose global quota is global, and which reads the global quota
+// changed at runtime from the default tenant in tenants, see SetStoredGlobalQuota
+func NewQuotasWithTenants(global *pms.Quota, tenants pms.TenantManager) (*Quotas, error) {
+	q := &Quotas{
+		tenants: tenants,
+		usages:  make(map[pms.PolicyStoreManager]*usage),
+	}
+	if err := q.SetGlobalQuota(global); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// ValidateQuota checks whether the quota is well formed
+func ValidateQuota(quota *pms.Quota) error {
+	if quota == nil {
+		return nil
+	}
+	for serviceName, serviceQuota := range quota.Services {
+		if len(serviceName) == 0 {
+			return errors.New(errors.InvalidRequest, "service name is empty in quota")
+		}
+		if serviceQuota == nil {
+			return errors.Errorf(errors.InvalidRequest, "quota of service %q is empty", serviceName)
+		}
+	}
+	return nil
+}
+
+// GlobalQuota returns the quota applied to all the tenants, the limits stored with the default tenant
+// override the ones of server configuration
+func (q *Quotas) GlobalQuota() (*pms.Quota, error) {
+	var stored *pms.Quota
+	if q.tenants != nil {
+		// The stored quota is read on each use, so that the changes made by other servers apply
+		tenant, err := q.tenants.GetTenant(pms.DefaultTenant)
+		if err != nil {
+			return nil, err
+		}
+		stored = tenant.Quota
+	}
+	q.Lock()
+	defer q.Unlock()
+	return mergeQuota(q.global, stored), nil
+}
+
+// SetGlobalQuota changes the quota of server configuration, the limits not set in quota default to DefaultQuota
+func (q *Quotas) SetGlobalQuota(quota *pms.Quota) error {
+	if err := ValidateQuota(quota); err != nil {
+		return err
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.global = mergeQuota(DefaultQuota(), quota)
+	return nil
+}
+
+// SetStoredGlobalQuota persists quota as the quota of the default tenant, so that it survives restarts and
+// applies to all the servers sharing the policy store
+func (q *Quotas) SetStoredGlobalQuota(quota *pms.Quota) error {
+	if err := ValidateQuota(quota); err != nil {
+		return err
+	}
+	if q.tenants == nil {
+		return errors.New(errors.InvalidRequest, "global quota can't be stored as the policy store doesn't support multi-tenancy")
+	}
+	tenant, err := q.tenants.GetTenant(pms.DefaultTenant)
+	if err != nil {
+		return err
+	}
+	updated := *tenant
+	updated.Quota = quota
+	return q.tenants.UpdateTenant(&updated)
+}
+
+// TenantQuota returns the limits applied to a tenant, the limits not set in the tenant's quota default to the global quota
+func (q *Quotas) TenantQuota(tenant *pms.Tenant) (*pms.Quota, error) {
+	global, err := q.GlobalQuota()
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil || tenant.Name == pms.DefaultTenant {
+		return global, nil
+	}
+	return mergeQuota(global, tenant.Quota), nil
+}
+
+// mergeQuota returns a copy of base whose limits are overridden by the limits set in override
+func mergeQuota(base *pms.Quota, override *pms.Quota) *pms.Quota {
+	quota := *base
+	quota.Services = make(map[string]*pms.ServiceQuota, len(base.Services))
+	for serviceName, serviceQuota := range base.Services {
+		copied := *serviceQuota
+		quota.Services[serviceName] = &copied
+	}
+	if override == nil {
+		return &quota
+	}
+
+	if override.MaxServiceNum != 0 {
+		quota.MaxServiceNum = override.MaxServiceNum
+	}
+	if override.MaxPolicyNum != 0 {
+		quota.MaxPolicyNum = override.MaxPolicyNum
+	}
+	if override.MaxFunctionNum != 0 {
+		quota.MaxFunctionNum = override.MaxFunctionNum
+	}
+	if override.MaxPolicySize != 0 {
+		quota.MaxPolicySize = override.MaxPolicySize
+	}
+	for serviceName, serviceQuota := range override.Services {
+		merged, ok := quota.Services[serviceName]
+		if !ok {
+			merged = &pms.ServiceQuota{}
+			quota.Services[serviceName] = merged
+		}
+		if serviceQuota.MaxPolicyNum != 0 {
+			merged.MaxPolicyNum = serviceQuota.MaxPolicyNum
+		}
+		if serviceQuota.MaxPolicySize != 0 {
+			merged.MaxPolicySize = serviceQuota.MaxPolicySize
+		}
+	}
+	return &quota
+}
+
+// serviceLimits returns the maximum number and size of the policies in a service
+func serviceLimits(quota *pms.Quota, serviceName string) (int64, int64) {
+	maxPolicyNum, maxPolicySize := int64(-1), quota.MaxPolicySize
+	if serviceQuota, ok := quota.Services[serviceName]; ok {
+		if serviceQuota.MaxPolicyNum != 0 {
+			maxPolicyNum = serviceQuota.MaxPolicyNum
+		}
+		if serviceQuota.MaxPolicySize != 0 {
+			maxPolicySize = serviceQuota.MaxPolicySize
+		}
+	}
+	return maxPolicyNum, maxPolicySize
+}
+
+// lockUsage locks and returns the usage of policy store ps, which is counted from the store if it is not counted
+// yet, or was counted UsageRecountInterval ago
+func (q *Quotas) lockUsage(ps pms.PolicyStoreManager) (*usage, error) {
+	q.Lock()
+	u, ok := q.usages[ps]
+	if !ok {
+		u = &usage{}
+		q.usages[ps] = u
+	}
+	q.Unlock()
+
+	u.Lock()
+	if u.counted.IsZero() || time.Since(u.counted) >= UsageRecountInterval {
+		if err := u.count(ps); err != nil {
+			u.Unlock()
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// count counts the usage from policy store ps
+func (u *usage) count(ps pms.PolicyStoreManager) error {
+	serviceNum, err := ps.GetServiceCount()
+	if err != nil {
+		return err
+	}
+	functionNum, err := ps.GetFunctionCount()
+	if err != nil {
+		return err
+	}
+	counts, err := ps.GetPolicyAndRolePolicyCounts()
+	if err != nil {
+		return err
+	}
+	u.serviceNum = serviceNum
+	u.functionNum = functionNum
+	u.services = make(map[string]int64, len(counts))
+	for serviceName, count := range counts {
+		u.services[serviceName] = count.PolicyCount + count.RolePolicyCount
+	}
+	u.counted = time.Now()
+	return nil
+}
+
+// enforce writes entities to the policy store of the tenant in scope with create, if check passes against the quota
+// of the tenant and the usage of the store, and then records the written entities with created. The usage is locked
+// from the check to the write.
+func (q *Quotas) enforce(scope *TenantScope, check func(quota *pms.Quota, u *usage) error, create func() error, created func(u *usage)) error {
+	quota, err := q.TenantQuota(scope.Tenant)
+	if err != nil {
+		return err
+	}
+
+	u, err := q.lockUsage(scope.PolicyStore)
+	if err != nil {
+		return err
+	}
+	defer u.Unlock()
+	if err := check(quota, u); err != nil {
+		return err
+	}
+	if err := create(); err != nil {
+		// The write may be partially done, so the usage is counted again
+		u.counted = time.Time{}
+		return err
+	}
+	created(u)
+	return nil
+}
+
+// update applies f to the usage of policy store ps if it is counted, uncounted usage is counted from the store on next use
+func (q *Quotas) update(ps pms.PolicyStoreManager, f func(u *usage)) {
+	q.Lock()
+	u, ok := q.usages[ps]
+	q.Unlock()
+	if !ok {
+		return
+	}
+	u.Lock()
+	defer u.Unlock()
+	if !u.counted.IsZero() {
+		f(u)
+	}
+}
+
+// ServiceDeleted records that service serviceName is deleted from policy store ps
+func (q *Quotas) ServiceDeleted(ps pms.PolicyStoreManager, serviceName string) {
+	q.update(ps, func(u *usage) {
+		u.serviceNum--
+		delete(u.services, serviceName)
+	})
+}
+
+// PolicyCountChanged records that delta Policies or RolePolicies are added to service serviceName in policy store ps,
+// negative delta means the policies are deleted
+func (q *Quotas) PolicyCountChanged(ps pms.PolicyStoreManager, serviceName string, delta int64) {
+	q.update(ps, func(u *usage) {
+		u.services[serviceName] += delta
+	})
+}
+
+// FunctionCountChanged records that delta functions are added to policy store ps, negative delta means the functions are deleted
+func (q *Quotas) FunctionCountChanged(ps pms.PolicyStoreManager, delta int64) {
+	q.update(ps, func(u *usage) {
+		u.functionNum += delta
+	})
+}
+
+// Invalidate makes the usage of policy store ps counted from the store again on next use, e.g. after bulk deletions
+func (q *Quotas) Invalidate(ps pms.PolicyStoreManager) {
+	q.update(ps, func(u *usage) {
+		u.counted = time.Time{}
+	})
+}
+
+// Remove drops the usage of policy store ps when the store is no longer used, e.g. when its tenant is deleted
+func (q *Quotas) Remove(ps pms.PolicyStoreManager) {
+	q.Lock()
+	defer q.Unlock()
+	delete(q.usages, ps)
+}
+
+// Usage reports the usage of the tenant in scope against its quota
+func (q *Quotas) Usage(scope *TenantScope) (*pms.QuotaUsage, error) {
+	quota, err := q.TenantQuota(scope.Tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := q.lockUsage(scope.PolicyStore)
+	if err != nil {
+		return nil, err
+	}
+	defer u.Unlock()
+	services := make(map[string]int64, len(u.services))
+	for serviceName, count := range u.services {
+		services[serviceName] = count
+	}
+	return &pms.QuotaUsage{
+		Tenant:      scope.Tenant.Name,
+		Quota:       quota,
+		ServiceNum:  u.serviceNum,
+		PolicyNum:   u.policyNum(),
+		FunctionNum: u.functionNum,
+		Services:    services,
+	}, nil
+}
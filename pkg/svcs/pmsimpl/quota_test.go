@@ -0,0 +1,259 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsimpl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/store"
+	_ "github.com/oracle/speedle/pkg/store/file"
+)
+
+func newTestScope(t *testing.T, tenant *pms.Tenant) (*TenantScope, func()) {
+	dir, err := ioutil.TempDir("", "speedle-quota")
+	if err != nil {
+		t.Fatal("fail to create temp dir:", err)
+	}
+	ps, err := store.NewStore("file", map[string]interface{}{"FileLocation": filepath.Join(dir, "ps.json")})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal("fail to new file store:", err)
+	}
+	return &TenantScope{Tenant: tenant, PolicyStore: ps}, func() { os.RemoveAll(dir) }
+}
+
+func TestTenantQuota(t *testing.T) {
+	quotas, err := NewQuotas(&pms.Quota{
+		MaxServiceNum: 10,
+		Services:      map[string]*pms.ServiceQuota{"app1": {MaxPolicyNum: 5}},
+	})
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+
+	tests := []struct {
+		name   string
+		tenant *pms.Tenant
+		want   pms.Quota
+	}{
+		{
+			name:   "global",
+			tenant: &pms.Tenant{Name: pms.DefaultTenant},
+			want:   pms.Quota{MaxServiceNum: 10, MaxPolicyNum: -1, MaxFunctionNum: -1, MaxPolicySize: -1},
+		},
+		{
+			name: "override",
+			tenant: &pms.Tenant{Name: "t1", Quota: &pms.Quota{
+				MaxServiceNum: -1,
+				MaxPolicySize: 100,
+				Services:      map[string]*pms.ServiceQuota{"app1": {MaxPolicySize: 50}},
+			}},
+			want: pms.Quota{MaxServiceNum: -1, MaxPolicyNum: -1, MaxFunctionNum: -1, MaxPolicySize: 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quotas.TenantQuota(tt.tenant)
+			if err != nil {
+				t.Fatal("fail to get tenant quota:", err)
+			}
+			if got.MaxServiceNum != tt.want.MaxServiceNum || got.MaxPolicyNum != tt.want.MaxPolicyNum ||
+				got.MaxFunctionNum != tt.want.MaxFunctionNum || got.MaxPolicySize != tt.want.MaxPolicySize {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if got.Services["app1"].MaxPolicyNum != 5 {
+				t.Errorf("per-service limit of global quota should be inherited, got %+v", got.Services["app1"])
+			}
+		})
+	}
+
+	// Tenant quota should not leak into global quota
+	if quota, _ := quotas.GlobalQuota(); quota.Services["app1"].MaxPolicySize != 0 {
+		t.Errorf("global quota is changed, got %+v", quota.Services["app1"])
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	scope, cleanup := newTestScope(t, &pms.Tenant{Name: pms.DefaultTenant})
+	defer cleanup()
+	quotas, err := NewQuotas(&pms.Quota{
+		MaxServiceNum:  1,
+		MaxPolicyNum:   3,
+		MaxFunctionNum: 1,
+		Services:       map[string]*pms.ServiceQuota{"app1": {MaxPolicyNum: 2, MaxPolicySize: 1000}},
+	})
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+	ps := scope.PolicyStore
+
+	service := &pms.Service{Name: "app1", Policies: []*pms.Policy{{Name: "p1", Effect: "grant"}}}
+	if err := quotas.CreateService(scope, service, func() error { return ps.CreateService(service) }); err != nil {
+		t.Fatal("service should be created:", err)
+	}
+	notCreated := func() error {
+		t.Error("entity exceeding the limits should not be created")
+		return nil
+	}
+	if err := quotas.CreateService(scope, &pms.Service{Name: "app2"}, notCreated); errors.Code(err) != errors.ExceedLimit {
+		t.Errorf("expect %s error for exceeding service number, but got %v", errors.ExceedLimit, err)
+	}
+
+	policy := &pms.Policy{Name: "p2", Effect: "grant"}
+	err = quotas.CreatePolicy(scope, "app1", policy, func() error {
+		_, err := ps.CreatePolicy("app1", policy)
+		return err
+	})
+	if err != nil {
+		t.Fatal("policy should be created:", err)
+	}
+	if err := quotas.CreateRolePolicy(scope, "app1", &pms.RolePolicy{Effect: "grant"}, notCreated); errors.Code(err) != errors.ExceedLimit {
+		t.Errorf("expect %s error for exceeding per-service policy number, but got %v", errors.ExceedLimit, err)
+	}
+	large := &pms.Policy{Name: string(make([]byte, 1000)), Effect: "grant"}
+	quotas.PolicyCountChanged(ps, "app1", -1)
+	if err := quotas.CreatePolicy(scope, "app1", large, notCreated); errors.Code(err) != errors.ExceedLimit {
+		t.Errorf("expect %s error for exceeding per-service policy size, but got %v", errors.ExceedLimit, err)
+	}
+	quotas.PolicyCountChanged(ps, "app1", 1)
+
+	// The function fails to be created in the store, the usage is recounted
+	if err := quotas.CreateFunction(scope, &pms.Function{Name: "f1"}, func() error { return errors.New(errors.StoreError, "failed") }); err == nil {
+		t.Fatal("the error of creating function should be returned")
+	}
+	if err := quotas.CreateFunction(scope, &pms.Function{Name: "f1"}, func() error { return nil }); err != nil {
+		t.Fatal("function should be allowed:", err)
+	}
+	if err := quotas.CreateFunction(scope, &pms.Function{Name: "f2"}, notCreated); errors.Code(err) != errors.ExceedLimit {
+		t.Errorf("expect %s error for exceeding function number, but got %v", errors.ExceedLimit, err)
+	}
+
+	usage, err := quotas.Usage(scope)
+	if err != nil {
+		t.Fatal("fail to get usage:", err)
+	}
+	if usage.ServiceNum != 1 || usage.PolicyNum != 2 || usage.FunctionNum != 1 || usage.Services["app1"] != 2 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	// Usage is recounted from the store after being invalidated, the function is never created in the store
+	quotas.Invalidate(ps)
+	usage, err = quotas.Usage(scope)
+	if err != nil {
+		t.Fatal("fail to get usage:", err)
+	}
+	if usage.ServiceNum != 1 || usage.PolicyNum != 2 || usage.FunctionNum != 0 {
+		t.Errorf("unexpected usage after recount %+v", usage)
+	}
+}
+
+func TestUsageRecounted(t *testing.T) {
+	scope, cleanup := newTestScope(t, &pms.Tenant{Name: pms.DefaultTenant})
+	defer cleanup()
+	quotas, err := NewQuotas(&pms.Quota{MaxFunctionNum: 1})
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+	if _, err := quotas.Usage(scope); err != nil {
+		t.Fatal("fail to get usage:", err)
+	}
+
+	// Another server creates a function in the shared store
+	if _, err := scope.PolicyStore.CreateFunction(&pms.Function{Name: "f1", FuncURL: "http://localhost/f1"}); err != nil {
+		t.Fatal("fail to create function:", err)
+	}
+	defer func(interval time.Duration) { UsageRecountInterval = interval }(UsageRecountInterval)
+	UsageRecountInterval = 0
+	if err := quotas.CreateFunction(scope, &pms.Function{Name: "f2"}, func() error { return nil }); errors.Code(err) != errors.ExceedLimit {
+		t.Errorf("expect %s error for the function created by another server, but got %v", errors.ExceedLimit, err)
+	}
+}
+
+func TestConcurrentCreations(t *testing.T) {
+	scope, cleanup := newTestScope(t, &pms.Tenant{Name: pms.DefaultTenant})
+	defer cleanup()
+	quotas, err := NewQuotas(&pms.Quota{MaxServiceNum: 3})
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+
+	var wg sync.WaitGroup
+	var created int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			service := &pms.Service{Name: fmt.Sprintf("app%d", i)}
+			err := quotas.CreateService(scope, service, func() error { return scope.PolicyStore.CreateService(service) })
+			if err == nil {
+				atomic.AddInt64(&created, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if count, _ := scope.PolicyStore.GetServiceCount(); created != 3 || count != 3 {
+		t.Errorf("expect 3 services created, got %d, %d in the store", created, count)
+	}
+}
+
+func TestSetGlobalQuota(t *testing.T) {
+	quotas, err := NewQuotas(nil)
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+	if err := quotas.SetGlobalQuota(&pms.Quota{Services: map[string]*pms.ServiceQuota{"": {}}}); errors.Code(err) != errors.InvalidRequest {
+		t.Errorf("expect %s error, but got %v", errors.InvalidRequest, err)
+	}
+	if err := quotas.SetGlobalQuota(&pms.Quota{MaxPolicyNum: 7}); err != nil {
+		t.Fatal("fail to set global quota:", err)
+	}
+	if quota, _ := quotas.GlobalQuota(); quota.MaxPolicyNum != 7 || quota.MaxServiceNum != MaxServiceNum {
+		t.Errorf("unexpected global quota %+v", quota)
+	}
+	if err := quotas.SetStoredGlobalQuota(&pms.Quota{MaxPolicyNum: 8}); errors.Code(err) != errors.InvalidRequest {
+		t.Errorf("expect %s error without tenants, but got %v", errors.InvalidRequest, err)
+	}
+}
+
+func TestStoredGlobalQuota(t *testing.T) {
+	scope, cleanup := newTestScope(t, &pms.Tenant{Name: pms.DefaultTenant})
+	defer cleanup()
+	tenants, err := store.NewTenantManager(scope.PolicyStore)
+	if err != nil {
+		t.Fatal("fail to new tenant manager:", err)
+	}
+	quotas, err := NewQuotasWithTenants(&pms.Quota{MaxServiceNum: 10, MaxPolicyNum: 10}, tenants)
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+	if err := quotas.SetStoredGlobalQuota(&pms.Quota{MaxPolicyNum: 7}); err != nil {
+		t.Fatal("fail to store global quota:", err)
+	}
+
+	// Another server sharing the store, or this server after restart, applies the stored global quota
+	other, err := NewQuotasWithTenants(&pms.Quota{MaxServiceNum: 10, MaxPolicyNum: 10}, tenants)
+	if err != nil {
+		t.Fatal("fail to create quotas:", err)
+	}
+	quota, err := other.TenantQuota(&pms.Tenant{Name: "t1", Quota: &pms.Quota{MaxFunctionNum: 3}})
+	if err != nil {
+		t.Fatal("fail to get tenant quota:", err)
+	}
+	if quota.MaxServiceNum != 10 || quota.MaxPolicyNum != 7 || quota.MaxFunctionNum != 3 {
+		t.Errorf("unexpected tenant quota %+v", quota)
+	}
+	tenantList, err := tenants.ListAllTenants()
+	if err != nil || len(tenantList) != 1 || tenantList[0].Name != pms.DefaultTenant || tenantList[0].Quota.MaxPolicyNum != 7 {
+		t.Errorf("only the default tenant with the stored quota should be listed, got %v, err: %v", tenantList, err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 )
 
 var (
+	// Default limits applied when they are not set in configuration
 	//The value <= 0 mean don't check the max number/size
 	MaxServiceNum  = int64(-1) // Maximum number of service for a tenant
 	MaxPolicyNum   = int64(-1) // Maximum number of Policy + RolePolicy per tenant
@@ -18,62 +19,68 @@ var (
 	MaxPolicySize  = int64(-1) // Maximum size in bytes for a Policy or RolePolicy
 )
 
+// DefaultQuota returns the limits applied when they are not set in configuration
+func DefaultQuota() *pms.Quota {
+	return &pms.Quota{
+		MaxServiceNum:  MaxServiceNum,
+		MaxPolicyNum:   MaxPolicyNum,
+		MaxFunctionNum: MaxFunctionNum,
+		MaxPolicySize:  MaxPolicySize,
+	}
+}
+
 /*
-Check the following items:
+CreateService creates service with create if it doesn't exceed the quota, checking the following items:
 	1. The maximum number of service;
-	2. The maximum number of Policy + RolePolicy;
+	2. The maximum number of Policy + RolePolicy, in the tenant and in the service;
 	3. The size of each Policy and RolePolicy;
 */
-func CheckService(service *pms.Service, policyStore pms.PolicyStoreManager) error {
-	// Check the number of the service
-	srvCount, err := policyStore.GetServiceCount()
-	if nil != err {
-		return err
-	}
-	if MaxServiceNum > 0 && srvCount+1 > MaxServiceNum {
-		return errors.Errorf(errors.ExceedLimit, "reached the maximum number of service, existingCount: %d", srvCount)
-	}
+func (q *Quotas) CreateService(scope *TenantScope, service *pms.Service, create func() error) error {
+	creatingCount := int64(len(service.Policies) + len(service.RolePolicies))
+	check := func(quota *pms.Quota, u *usage) error {
+		// Check the number of the service
+		if quota.MaxServiceNum > 0 && u.serviceNum+1 > quota.MaxServiceNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of service, existingCount: %d", u.serviceNum)
+		}
 
-	// Check the number of policy and rolePolicy
-	existingCount, err := getPolicyAndRolePolicyCount("", policyStore)
-	if nil != err {
-		return err
-	}
-	creatingCount := 0
-	if nil != service.Policies {
-		creatingCount += len(service.Policies)
-	}
-	if nil != service.RolePolicies {
-		creatingCount += len(service.RolePolicies)
-	}
-	if MaxPolicyNum > 0 && int64(existingCount)+int64(creatingCount) > MaxPolicyNum {
-		return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy, existingCount: %d, creatingCount: %d", existingCount, creatingCount)
-	}
+		// Check the number of policy and rolePolicy
+		existingCount := u.policyNum()
+		if quota.MaxPolicyNum > 0 && existingCount+creatingCount > quota.MaxPolicyNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy, existingCount: %d, creatingCount: %d", existingCount, creatingCount)
+		}
+		maxServicePolicyNum, maxPolicySize := serviceLimits(quota, service.Name)
+		if maxServicePolicyNum > 0 && creatingCount > maxServicePolicyNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy in service %q, creatingCount: %d", service.Name, creatingCount)
+		}
 
-	// Check the size of each policy and RolePolicy
-	for _, policy := range service.Policies {
-		sizeValid, err := checkMaxSize(*policy, MaxPolicySize)
-		if !sizeValid {
-			return err
+		// Check the size of each policy and RolePolicy
+		for _, policy := range service.Policies {
+			sizeValid, err := checkMaxSize(*policy, maxPolicySize)
+			if !sizeValid {
+				return err
+			}
 		}
-	}
-	for _, rolePolicy := range service.RolePolicies {
-		sizeValid, err := checkMaxSize(*rolePolicy, MaxPolicySize)
-		if !sizeValid {
-			return err
+		for _, rolePolicy := range service.RolePolicies {
+			sizeValid, err := checkMaxSize(*rolePolicy, maxPolicySize)
+			if !sizeValid {
+				return err
+			}
 		}
+		return nil
 	}
-
-	return nil
+	return q.enforce(scope, check, create, func(u *usage) {
+		u.serviceNum++
+		u.services[service.Name] = creatingCount
+	})
 }
 
 /*
-Check the following items:
-	1. The maximum number of Policy + RolePolicy;
+CreatePolicy creates policy with create if it doesn't exceed the quota, checking the following items:
+	1. The maximum number of Policy + RolePolicy, in the tenant and in the service;
 	2. The size of the Policy;
     3. If the effect field of policy is empty;
 */
-func CheckPolicy(serviceName string, policy *pms.Policy, policyStore pms.PolicyStoreManager) error {
+func (q *Quotas) CreatePolicy(scope *TenantScope, serviceName string, policy *pms.Policy, create func() error) error {
 	// Check global service
 	if serviceName == pms.GlobalService {
 		return errors.New(errors.InvalidRequest, "global policy doesn't support authorization policies")
@@ -83,66 +90,46 @@ func CheckPolicy(serviceName string, policy *pms.Policy, policyStore pms.PolicyS
 		return errors.New(errors.InvalidRequest, "no effect provided in policy.")
 	}
 
-	// Check the number of Policy + RolePolicy
-	existingCount, err := getPolicyAndRolePolicyCount("", policyStore)
-	if nil != err {
-		return err
-	}
-	if MaxPolicyNum > 0 && existingCount+1 > MaxPolicyNum {
-		return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy: %d", existingCount)
-	}
-
-	// Check the size of the Policy
-	sizeValid, err := checkMaxSize(*policy, MaxPolicySize)
-	if !sizeValid {
-		return err
-	}
-
-	return nil
+	return q.createPolicy(scope, serviceName, *policy, create)
 }
 
 /*
-Check the following items:
-	1. The maximum number of Policy + RolePolicy;
+CreateRolePolicy creates rolePolicy with create if it doesn't exceed the quota, checking the following items:
+	1. The maximum number of Policy + RolePolicy, in the tenant and in the service;
 	2. The size of the RolePolicy;
     3. If the effect field of RolePolicy is empty;
 */
-func CheckRolePolicy(serviceName string, rolePolicy *pms.RolePolicy, policyStore pms.PolicyStoreManager) error {
+func (q *Quotas) CreateRolePolicy(scope *TenantScope, serviceName string, rolePolicy *pms.RolePolicy, create func() error) error {
 	if len(rolePolicy.Effect) <= 0 {
 		return errors.New(errors.InvalidRequest, "no effect provided in role policy.")
 	}
 
-	// Check the number of Policy + RolePolicy
-	existingCount, err := getPolicyAndRolePolicyCount("", policyStore)
-	if nil != err {
-		return err
-	}
-	if MaxPolicyNum > 0 && existingCount+1 > MaxPolicyNum {
-		return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy: %d", existingCount)
-	}
-
-	// Check the size of the RolePolicy
-	sizeValid, err := checkMaxSize(*rolePolicy, MaxPolicySize)
-	if !sizeValid {
-		return err
-	}
-
-	return nil
+	return q.createPolicy(scope, serviceName, *rolePolicy, create)
 }
 
-// get the existing number of policy + rolePolicy
-func getPolicyAndRolePolicyCount(serviceName string, policyStore pms.PolicyStoreManager) (int64, error) {
-	policyCount, err := policyStore.GetPolicyCount(serviceName)
-	if nil != err {
-		return 0, err
-	}
+// createPolicy checks the number of Policy + RolePolicy and the size of the creating one, and creates it with create
+func (q *Quotas) createPolicy(scope *TenantScope, serviceName string, policy interface{}, create func() error) error {
+	check := func(quota *pms.Quota, u *usage) error {
+		// Check the number of Policy + RolePolicy
+		existingCount := u.policyNum()
+		if quota.MaxPolicyNum > 0 && existingCount+1 > quota.MaxPolicyNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy: %d", existingCount)
+		}
+		maxServicePolicyNum, maxPolicySize := serviceLimits(quota, serviceName)
+		if maxServicePolicyNum > 0 && u.services[serviceName]+1 > maxServicePolicyNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of policy and rolePolicy in service %q: %d", serviceName, u.services[serviceName])
+		}
 
-	rolePolicyCount, err := policyStore.GetRolePolicyCount(serviceName)
-	if nil != err {
-		return 0, err
+		// Check the size of the Policy or RolePolicy
+		sizeValid, err := checkMaxSize(policy, maxPolicySize)
+		if !sizeValid {
+			return err
+		}
+		return nil
 	}
-
-	return (policyCount + rolePolicyCount), nil
+	return q.enforce(scope, check, create, func(u *usage) {
+		u.services[serviceName]++
+	})
 }
 
 // check the size of policy or rolePolicy
@@ -159,17 +146,17 @@ func checkMaxSize(val interface{}, maxSize int64) (bool, error) {
 }
 
 /*
-Check the following items:
+CreateFunction creates function with create if it doesn't exceed the quota, checking the following items:
 	1. The maximum number of function;
 */
-func CheckFunction(function *pms.Function, policyStore pms.PolicyStoreManager) error {
-	// Check the number of Policy + RolePolicy
-	existingCount, err := policyStore.GetFunctionCount()
-	if nil != err {
-		return err
-	}
-	if MaxFunctionNum > 0 && existingCount >= MaxFunctionNum {
-		return errors.Errorf(errors.ExceedLimit, "reached the maximum number of function: %d", existingCount)
+func (q *Quotas) CreateFunction(scope *TenantScope, function *pms.Function, create func() error) error {
+	check := func(quota *pms.Quota, u *usage) error {
+		if quota.MaxFunctionNum > 0 && u.functionNum >= quota.MaxFunctionNum {
+			return errors.Errorf(errors.ExceedLimit, "reached the maximum number of function: %d", u.functionNum)
+		}
+		return nil
 	}
-	return nil
+	return q.enforce(scope, check, create, func(u *usage) {
+		u.functionNum++
+	})
 }
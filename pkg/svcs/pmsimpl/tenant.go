@@ -0,0 +1,50 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsimpl
+
+import (
+	"context"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+)
+
+// TenantScope is the tenant a policy management request is scoped to, and the policy store of the tenant
+type TenantScope struct {
+	Tenant      *pms.Tenant
+	PolicyStore pms.PolicyStoreManager
+}
+
+type tenantScopeKey struct{}
+
+// WithTenantScope returns a copy of ctx carrying the tenant scope
+func WithTenantScope(ctx context.Context, scope *TenantScope) context.Context {
+	return context.WithValue(ctx, tenantScopeKey{}, scope)
+}
+
+// TenantScopeFrom returns the tenant scope carried by ctx
+func TenantScopeFrom(ctx context.Context) (*TenantScope, bool) {
+	scope, ok := ctx.Value(tenantScopeKey{}).(*TenantScope)
+	return scope, ok
+}
+
+// ResolveTenantScope returns the scope of tenant tenantName. The default tenant is used if tenantName is empty,
+// whose policies are kept in defaultStore. tenants is nil if the policy store doesn't support multi-tenancy.
+func ResolveTenantScope(tenants pms.TenantManager, defaultStore pms.PolicyStoreManager, tenantName string) (*TenantScope, error) {
+	if len(tenantName) == 0 || tenantName == pms.DefaultTenant {
+		return &TenantScope{Tenant: &pms.Tenant{Name: pms.DefaultTenant}, PolicyStore: defaultStore}, nil
+	}
+	if tenants == nil {
+		return nil, errors.Errorf(errors.InvalidRequest, "%q policy store doesn't support multi-tenancy", defaultStore.Type())
+	}
+	tenant, err := tenants.GetTenant(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := tenants.TenantStore(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	return &TenantScope{Tenant: tenant, PolicyStore: ps}, nil
+}
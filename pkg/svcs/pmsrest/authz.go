@@ -0,0 +1,136 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsrest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/svcs"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
+)
+
+// permission is the resource and action a route is authorized against
+type permission struct {
+	resource func(vars map[string]string) string
+	action   string
+	// global permissions are not scoped to the tenant of the request
+	global bool
+}
+
+func servicePermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.ServiceResource(vars["serviceName"]) }, action, false}
+}
+
+func policyPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.PolicyResource(vars["serviceName"]) }, action, false}
+}
+
+func rolePolicyPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.RolePolicyResource(vars["serviceName"]) }, action, false}
+}
+
+func functionPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.FunctionResource(vars["functionName"]) }, action, false}
+}
+
+func discoverPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.DiscoverResource(vars["serviceName"]) }, action, false}
+}
+
+func quotaPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.QuotaResource() }, action, false}
+}
+
+// searchPermission is required to search policies, because the results span all the services
+func searchPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.SearchResource() }, action, false}
+}
+
+func tenantPermission(action string) permission {
+	return permission{func(vars map[string]string) string { return pmsauth.TenantResource(vars[svcs.TenantPathVar]) }, action, true}
+}
+
+// routePermissions maps route names to the permissions required to call them
+var routePermissions = map[string]permission{
+	"CreatePolicy":       policyPermission(pmsauth.ActionCreate),
+	"DeletePolicies":     policyPermission(pmsauth.ActionDelete),
+	"DeletePolicy":       policyPermission(pmsauth.ActionDelete),
+	"GetPolicy":          policyPermission(pmsauth.ActionRead),
+	"ListPolicies":       policyPermission(pmsauth.ActionRead),
+	"CreateRolePolicy":   rolePolicyPermission(pmsauth.ActionCreate),
+	"DeleteRolePolicies": rolePolicyPermission(pmsauth.ActionDelete),
+	"DeleteRolePolicy":   rolePolicyPermission(pmsauth.ActionDelete),
+	"GetRolePolicy":      rolePolicyPermission(pmsauth.ActionRead),
+	"ListRolePolicies":   rolePolicyPermission(pmsauth.ActionRead),
+	"SearchPolicies":     searchPermission(pmsauth.ActionRead),
+
+	"CreateService":    servicePermission(pmsauth.ActionCreate),
+	"DeleteService":    servicePermission(pmsauth.ActionDelete),
+	"DeleteServices":   servicePermission(pmsauth.ActionDelete),
+	"GetService":       servicePermission(pmsauth.ActionRead),
+	"ListServices":     servicePermission(pmsauth.ActionRead),
+	"ListPolicyCounts": servicePermission(pmsauth.ActionRead),
+
+	"CreateFunction":  functionPermission(pmsauth.ActionCreate),
+	"DeleteFunction":  functionPermission(pmsauth.ActionDelete),
+	"DeleteFunctions": functionPermission(pmsauth.ActionDelete),
+	"GetFunction":     functionPermission(pmsauth.ActionRead),
+	"ListFunctions":   functionPermission(pmsauth.ActionRead),
+
+	"GetAllDiscoverRequests":   discoverPermission(pmsauth.ActionRead),
+	"GetDiscoverRequests":      discoverPermission(pmsauth.ActionRead),
+	"ResetDiscoverRequests":    discoverPermission(pmsauth.ActionDelete),
+	"ResetAllDiscoverRequests": discoverPermission(pmsauth.ActionDelete),
+	"GetDiscoverPolicies":      discoverPermission(pmsauth.ActionRead),
+	"GetAllDiscoverPolicies":   discoverPermission(pmsauth.ActionRead),
+
+	"GetQuota":    quotaPermission(pmsauth.ActionRead),
+	"UpdateQuota": quotaPermission(pmsauth.ActionUpdate),
+
+	"CreateTenant": tenantPermission(pmsauth.ActionCreate),
+	"DeleteTenant": tenantPermission(pmsauth.ActionDelete),
+	"GetTenant":    tenantPermission(pmsauth.ActionRead),
+	"ListTenants":  tenantPermission(pmsauth.ActionRead),
+}
+
+// authorizeHandler authenticates the caller and authorizes the route before calling next handler.
+// Resources of tenant scoped routes are prefixed with the tenant, see pmsauth.ScopedResource.
+// The authenticated principals are passed to next handler in Speedle-Principals header.
+func authorizeHandler(authorizer *pmsauth.Authorizer, routeName string, next http.Handler) http.Handler {
+	perm, ok := routePermissions[routeName]
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never trust the principals header from callers
+		r.Header.Del(svcs.PrincipalsHeader)
+
+		subject, err := authorizer.AuthenticateHTTP(r)
+		if err == nil {
+			if ok {
+				resource := perm.resource(mux.Vars(r))
+				if !perm.global {
+					var tenantName string
+					if tenantName, err = svcs.TenantOfHTTP(r); err == nil {
+						resource = pmsauth.ScopedResource(tenantName, resource)
+					}
+				}
+				if err == nil {
+					err = authorizer.Authorize(subject, resource, perm.action)
+				}
+			} else {
+				err = errors.Errorf(errors.Forbidden, "no permission is defined for %s", routeName)
+			}
+		}
+		if err != nil {
+			httputils.HandleError(w, err)
+			logging.WriteSimpleFailedAuditLog(routeName, r.URL.Path, err.Error())
+			return
+		}
+
+		r.Header.Set(svcs.PrincipalsHeader, pmsauth.EncodePrincipals(subject))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsrest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adsapi "github.com/oracle/speedle/api/ads"
+	"github.com/oracle/speedle/pkg/cfg"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
+)
+
+// fakeEvaluator allows the requests listed in grants, keyed by principal name, resource and action
+type fakeEvaluator struct {
+	adsapi.PolicyEvaluator
+	grants map[string]bool
+}
+
+func (e *fakeEvaluator) IsAllowed(c adsapi.RequestContext) (bool, adsapi.Reason, error) {
+	for _, principal := range c.Subject.Principals {
+		if e.grants[principal.Name+":"+c.Resource+":"+c.Action] {
+			return true, adsapi.GRANT_POLICY_FOUND, nil
+		}
+	}
+	return false, adsapi.NO_APPLICABLE_POLICIES, nil
+}
+
+func TestSearchPermission(t *testing.T) {
+	authorizer := pmsauth.NewWithEvaluator(&cfg.PMSAuthConfig{
+		APIKeys: map[string]string{"bob-key": "bob", "carol-key": "carol"},
+	}, &fakeEvaluator{grants: map[string]bool{
+		// the resource of the policies of service "policy" is the one of the policies of no service
+		"bob:" + pmsauth.PolicyResource("policy") + ":" + pmsauth.ActionRead:  true,
+		"bob:" + pmsauth.ServiceResource("policy") + ":" + pmsauth.ActionRead: true,
+		"carol:" + pmsauth.SearchResource() + ":" + pmsauth.ActionRead:        true,
+	}}, nil)
+	handler := authorizeHandler(authorizer, "SearchPolicies", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for apiKey, status := range map[string]int{"bob-key": http.StatusForbidden, "carol-key": http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/policy-mgmt/v1/search?principal=user:alan", nil)
+		r.Header.Set(pmsauth.APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", apiKey, status, w.Code)
+		}
+	}
+}
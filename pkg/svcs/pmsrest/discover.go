@@ -29,7 +29,7 @@ type GetDiscoverPoliciesResponse struct {
 }
 
 func (e *RESTService) GetAllDiscoverRequests(w http.ResponseWriter, r *http.Request) {
-	if !e.checkPolicyForDiscover(w) {
+	if !e.checkPolicyForDiscover(w, r) {
 		return
 	}
 
@@ -43,7 +43,7 @@ func (e *RESTService) GetAllDiscoverRequests(w http.ResponseWriter, r *http.Requ
 	}
 
 	if strings.EqualFold("true", last) { //get last discover request
-		request, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetLastDiscoverRequest("")
+		request, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetLastDiscoverRequest("")
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -72,7 +72,7 @@ func (e *RESTService) GetAllDiscoverRequests(w http.ResponseWriter, r *http.Requ
 			logging.WriteFailedAuditLog("GetAllDiscoverRequests", ctxFields, err.Error())
 			return
 		}
-		requests, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetDiscoverRequestsSinceRevision("", revision)
+		requests, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetDiscoverRequestsSinceRevision("", revision)
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -87,7 +87,7 @@ func (e *RESTService) GetAllDiscoverRequests(w http.ResponseWriter, r *http.Requ
 		logging.WriteSucceededAuditLog("GetAllDiscoverRequests", ctxFields, map[string]interface{}{"requestCount": len(requests)})
 	} else {
 		//get all service requests
-		requests, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetDiscoverRequests("")
+		requests, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetDiscoverRequests("")
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -103,7 +103,7 @@ func (e *RESTService) GetAllDiscoverRequests(w http.ResponseWriter, r *http.Requ
 }
 
 func (e *RESTService) GetDiscoverRequests(w http.ResponseWriter, r *http.Request) {
-	if !e.checkPolicyForDiscover(w) {
+	if !e.checkPolicyForDiscover(w, r) {
 		return
 	}
 
@@ -123,7 +123,7 @@ func (e *RESTService) GetDiscoverRequests(w http.ResponseWriter, r *http.Request
 	}
 
 	if strings.EqualFold("true", last) { //get last discover request
-		request, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetLastDiscoverRequest(serviceName)
+		request, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetLastDiscoverRequest(serviceName)
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -151,7 +151,7 @@ func (e *RESTService) GetDiscoverRequests(w http.ResponseWriter, r *http.Request
 			logging.WriteFailedAuditLog("GetDiscoverRequests", ctxFields, err.Error())
 			return
 		}
-		requests, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetDiscoverRequestsSinceRevision(serviceName, revision)
+		requests, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetDiscoverRequestsSinceRevision(serviceName, revision)
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -167,7 +167,7 @@ func (e *RESTService) GetDiscoverRequests(w http.ResponseWriter, r *http.Request
 		logging.WriteSucceededAuditLog("GetDiscoverRequests", ctxFields, map[string]interface{}{"requestCount": len(requests)})
 	} else {
 		//get all service requests
-		requests, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GetDiscoverRequests(serviceName)
+		requests, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GetDiscoverRequests(serviceName)
 		if err != nil {
 			log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 			httputils.HandleError(w, err)
@@ -183,7 +183,7 @@ func (e *RESTService) GetDiscoverRequests(w http.ResponseWriter, r *http.Request
 }
 
 func (e *RESTService) ResetDiscoverRequests(w http.ResponseWriter, r *http.Request) {
-	if !e.checkPolicyForDiscover(w) {
+	if !e.checkPolicyForDiscover(w, r) {
 		return
 	}
 
@@ -192,7 +192,7 @@ func (e *RESTService) ResetDiscoverRequests(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := e.PolicyStore.(store.DiscoverRequestManager).ResetDiscoverRequests(serviceName); err != nil {
+	if err := e.policyStore(r).(store.DiscoverRequestManager).ResetDiscoverRequests(serviceName); err != nil {
 		log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 		httputils.HandleError(w, err)
 
@@ -208,11 +208,11 @@ func (e *RESTService) ResetDiscoverRequests(w http.ResponseWriter, r *http.Reque
 }
 
 func (e *RESTService) ResetAllDiscoverRequests(w http.ResponseWriter, r *http.Request) {
-	if !e.checkPolicyForDiscover(w) {
+	if !e.checkPolicyForDiscover(w, r) {
 		return
 	}
 
-	err := e.PolicyStore.(store.DiscoverRequestManager).ResetDiscoverRequests("")
+	err := e.policyStore(r).(store.DiscoverRequestManager).ResetDiscoverRequests("")
 	if err != nil {
 		log.Errorf("%v, Cause: %v", err, errors.Cause(err))
 		httputils.HandleError(w, err)
@@ -226,7 +226,7 @@ func (e *RESTService) ResetAllDiscoverRequests(w http.ResponseWriter, r *http.Re
 }
 
 func (e *RESTService) GetDiscoverPolicies(w http.ResponseWriter, r *http.Request) {
-	if !e.checkPolicyForDiscover(w) {
+	if !e.checkPolicyForDiscover(w, r) {
 		return
 	}
 
@@ -243,7 +243,7 @@ func (e *RESTService) GetDiscoverPolicies(w http.ResponseWriter, r *http.Request
 		"principalIDD":  principalIDD,
 	}
 
-	serviceMap, revision, err := e.PolicyStore.(store.DiscoverRequestManager).GeneratePolicies(serviceName, principalType, principalName, principalIDD)
+	serviceMap, revision, err := e.policyStore(r).(store.DiscoverRequestManager).GeneratePolicies(serviceName, principalType, principalName, principalIDD)
 	if err != nil {
 		log.Error(err)
 		httputils.HandleError(w, err)
@@ -271,9 +271,9 @@ func (e *RESTService) GetDiscoverPolicies(w http.ResponseWriter, r *http.Request
 
 }
 
-func (e *RESTService) checkPolicyForDiscover(w http.ResponseWriter) bool {
-	if _, ok := e.PolicyStore.(store.DiscoverRequestManager); !ok {
-		err := errors.Errorf(errors.InvalidRequest, "%q policy store doesn't support discover request management", e.PolicyStore.Type())
+func (e *RESTService) checkPolicyForDiscover(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := e.policyStore(r).(store.DiscoverRequestManager); !ok {
+		err := errors.Errorf(errors.InvalidRequest, "%q policy store doesn't support discover request management", e.policyStore(r).Type())
 		log.Error(err)
 		httputils.HandleError(w, err)
 		return false
@@ -6,11 +6,13 @@ package pmsrest
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/oracle/speedle/pkg/errors"
 	"github.com/oracle/speedle/pkg/httputils"
 	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/store"
 	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
 
 	"github.com/gorilla/mux"
@@ -25,6 +27,9 @@ import (
 
 type RESTService struct {
 	PolicyStore pms.PolicyStoreManager
+	// Tenants is nil if the policy store doesn't support multi-tenancy
+	Tenants pms.TenantManager
+	Quotas  *pmsimpl.Quotas
 }
 
 type serviceRequestBody struct {
@@ -33,7 +38,25 @@ type serviceRequestBody struct {
 }
 
 func NewRestService(s pms.PolicyStoreManager) (*RESTService, error) {
-	return &RESTService{PolicyStore: s}, nil
+	quotas, err := pmsimpl.NewQuotas(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RESTService{PolicyStore: s, Quotas: quotas}, nil
+}
+
+// tenantScope returns the tenant scope resolved by tenantHandler, requests out of any tenant scope
+// are served by the default tenant
+func (mgr *RESTService) tenantScope(r *http.Request) *pmsimpl.TenantScope {
+	if scope, ok := pmsimpl.TenantScopeFrom(r.Context()); ok {
+		return scope
+	}
+	return &pmsimpl.TenantScope{Tenant: &pms.Tenant{Name: pms.DefaultTenant}, PolicyStore: mgr.PolicyStore}
+}
+
+// policyStore returns the policy store of the tenant the request is scoped to
+func (mgr *RESTService) policyStore(r *http.Request) pms.PolicyStoreManager {
+	return mgr.tenantScope(r).PolicyStore
 }
 
 // returns:
@@ -41,6 +64,10 @@ func NewRestService(s pms.PolicyStoreManager) (*RESTService, error) {
 //     2. policy/role-policy ID
 func ParseRequestURI(r *http.Request) (string, string) {
 	segs := strings.Split(r.URL.Path, "/")
+	// Skip the tenant prefix, e.g. /policy-mgmt/v1/tenant/{tenantName}/service/...
+	if len(segs) > 5 && segs[3] == "tenant" {
+		segs = append(segs[:3], segs[5:]...)
+	}
 	segLength := len(segs)
 	if segLength > 4 {
 		if segLength > 6 {
@@ -62,6 +89,31 @@ func ParseForFilters(r *http.Request) string {
 	return filterStr
 }
 
+// parseListOptions parses the filter, limit and continue query parameters of list requests
+func parseListOptions(r *http.Request) (store.ListOptions, error) {
+	query := r.URL.Query()
+	opts := store.ListOptions{Filter: ParseForFilters(r), Continue: query.Get("continue")}
+	if limit := query.Get("limit"); len(limit) > 0 {
+		var err error
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit < 0 {
+			return opts, errors.Errorf(errors.InvalidRequest, "invalid limit %q", limit)
+		}
+	}
+	return opts, nil
+}
+
+// sendListResponse sends a page of entities, the token to list the next page is sent in header ContinueHeader
+func sendListResponse(w http.ResponseWriter, token string, count int, list interface{}) {
+	if len(token) > 0 {
+		w.Header().Set(svcs.ContinueHeader, token)
+	}
+	if count == 0 {
+		httputils.SendEmptyListResponse(w)
+		return
+	}
+	httputils.SendOKResponse(w, list)
+}
+
 func decodeServiceRequest(r *http.Request) (*serviceRequestBody, error) {
 	decoder := json.NewDecoder(r.Body)
 	var request serviceRequestBody
@@ -105,14 +157,7 @@ func (mgr *RESTService) CreateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = pmsimpl.CheckService(&service, mgr.PolicyStore)
-	if err != nil {
-		httputils.HandleError(w, err)
-		logging.WriteSimpleFailedAuditLog("CreateService", &service, err.Error())
-		return
-	}
-
-	if _, err := mgr.PolicyStore.GetService(service.Name); err == nil {
+	if _, err := mgr.policyStore(r).GetService(service.Name); err == nil {
 		// servcie already exists.
 		httputils.SendBadRequestResponse(w, &httputils.ErrorResponse{
 			Error: "Service already exists.",
@@ -131,7 +176,10 @@ func (mgr *RESTService) CreateService(w http.ResponseWriter, r *http.Request) {
 		rolepolicy.Metadata = metaData
 	}
 
-	if err := mgr.PolicyStore.CreateService(&service); err != nil {
+	err = mgr.Quotas.CreateService(mgr.tenantScope(r), &service, func() error {
+		return mgr.policyStore(r).CreateService(&service)
+	})
+	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("CreateService", &service, err.Error())
 		return
@@ -150,22 +198,24 @@ func (mgr *RESTService) DeleteService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := mgr.PolicyStore.DeleteService(serviceName); err != nil {
+	if err := mgr.policyStore(r).DeleteService(serviceName); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeleteService", serviceName, err.Error())
 		return
 	}
+	mgr.Quotas.ServiceDeleted(mgr.policyStore(r), serviceName)
 
 	logging.WriteSimpleSucceededAuditLog("DeleteService", serviceName, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (mgr *RESTService) DeleteServices(w http.ResponseWriter, r *http.Request) {
-	if err := mgr.PolicyStore.DeleteServices(); err != nil {
+	if err := mgr.policyStore(r).DeleteServices(); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeleteServices", nil, err.Error())
 		return
 	}
+	mgr.Quotas.Invalidate(mgr.policyStore(r))
 
 	logging.WriteSimpleSucceededAuditLog("DeleteServices", nil, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -180,7 +230,7 @@ func (mgr *RESTService) GetService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	service, err := mgr.PolicyStore.GetService(serviceName)
+	service, err := mgr.policyStore(r).GetService(serviceName)
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("GetService", serviceName, err.Error())
@@ -192,7 +242,12 @@ func (mgr *RESTService) GetService(w http.ResponseWriter, r *http.Request) {
 }
 
 func (mgr *RESTService) ListServices(w http.ResponseWriter, r *http.Request) {
-	services, err := mgr.PolicyStore.ListAllServices()
+	opts, err := parseListOptions(r)
+	var services []*pms.Service
+	var token string
+	if err == nil {
+		services, token, err = store.ListServices(mgr.policyStore(r), opts)
+	}
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("ListServices", nil, err.Error())
@@ -200,16 +255,11 @@ func (mgr *RESTService) ListServices(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logging.WriteSimpleSucceededAuditLog("ListServices", nil, len(services))
-
-	if len(services) == 0 {
-		httputils.SendEmptyListResponse(w)
-		return
-	}
-	httputils.SendOKResponse(w, &services)
+	sendListResponse(w, token, len(services), &services)
 }
 
 func (mgr *RESTService) ListPolicyAndRolePolicyCounts(w http.ResponseWriter, r *http.Request) {
-	countMap, err := mgr.PolicyStore.GetPolicyAndRolePolicyCounts()
+	countMap, err := mgr.policyStore(r).GetPolicyAndRolePolicyCounts()
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("ListPolicyCounts", nil, err.Error())
@@ -242,15 +292,12 @@ func (mgr *RESTService) CreatePolicy(w http.ResponseWriter, r *http.Request) {
 		"policy":      &policy,
 	}
 
-	err := pmsimpl.CheckPolicy(serviceName, &policy, mgr.PolicyStore)
-	if err != nil {
-		httputils.HandleError(w, err)
-		logging.WriteSimpleFailedAuditLog("CreatePolicy", ctxFields, err.Error())
-		return
-	}
-
 	policy.Metadata = getCreateMetaData(r)
-	ret, err := mgr.PolicyStore.CreatePolicy(serviceName, &policy)
+	var ret *pms.Policy
+	err := mgr.Quotas.CreatePolicy(mgr.tenantScope(r), serviceName, &policy, func() (err error) {
+		ret, err = mgr.policyStore(r).CreatePolicy(serviceName, &policy)
+		return err
+	})
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("CreatePolicy", ctxFields, err.Error())
@@ -270,11 +317,12 @@ func (mgr *RESTService) DeletePolicies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := mgr.PolicyStore.DeletePolicies(serviceName); err != nil {
+	if err := mgr.policyStore(r).DeletePolicies(serviceName); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeletePolicies", serviceName, err.Error())
 		return
 	}
+	mgr.Quotas.Invalidate(mgr.policyStore(r))
 
 	logging.WriteSimpleSucceededAuditLog("DeletePolicies", serviceName, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -295,11 +343,12 @@ func (mgr *RESTService) DeletePolicy(w http.ResponseWriter, r *http.Request) {
 		"policyId":    policyIDStr,
 	}
 
-	if err := mgr.PolicyStore.DeletePolicy(serviceName, policyIDStr); err != nil {
+	if err := mgr.policyStore(r).DeletePolicy(serviceName, policyIDStr); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("DeletePolicy", ctxFields, err.Error())
 		return
 	}
+	mgr.Quotas.PolicyCountChanged(mgr.policyStore(r), serviceName, -1)
 
 	logging.WriteSucceededAuditLog("DeletePolicy", ctxFields, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -320,7 +369,7 @@ func (mgr *RESTService) GetPolicy(w http.ResponseWriter, r *http.Request) {
 		"policyId":    policyIDStr,
 	}
 
-	policy, err := mgr.PolicyStore.GetPolicy(serviceName, policyIDStr)
+	policy, err := mgr.policyStore(r).GetPolicy(serviceName, policyIDStr)
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("GetPolicy", ctxFields, err.Error())
@@ -339,8 +388,12 @@ func (mgr *RESTService) ListPolicies(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	filters := ParseForFilters(r)
-	policies, err := mgr.PolicyStore.ListAllPolicies(serviceName, filters)
+	opts, err := parseListOptions(r)
+	var policies []*pms.Policy
+	var token string
+	if err == nil {
+		policies, token, err = store.ListPolicies(mgr.policyStore(r), serviceName, opts)
+	}
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("ListPolicies", serviceName, err.Error())
@@ -348,11 +401,7 @@ func (mgr *RESTService) ListPolicies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logging.WriteSimpleSucceededAuditLog("ListPolicies", serviceName, len(policies))
-	if len(policies) == 0 {
-		httputils.SendEmptyListResponse(w)
-		return
-	}
-	httputils.SendOKResponse(w, policies)
+	sendListResponse(w, token, len(policies), policies)
 }
 
 // Role policy management
@@ -376,15 +425,12 @@ func (mgr *RESTService) CreateRolePolicy(w http.ResponseWriter, r *http.Request)
 		"rolePolicy":  &rolePolicy,
 	}
 
-	err := pmsimpl.CheckRolePolicy(serviceName, &rolePolicy, mgr.PolicyStore)
-	if err != nil {
-		httputils.HandleError(w, err)
-		logging.WriteSimpleFailedAuditLog("CreateRolePolicy", ctxFields, err.Error())
-		return
-	}
-
 	rolePolicy.Metadata = getCreateMetaData(r)
-	ret, err := mgr.PolicyStore.CreateRolePolicy(serviceName, &rolePolicy)
+	var ret *pms.RolePolicy
+	err := mgr.Quotas.CreateRolePolicy(mgr.tenantScope(r), serviceName, &rolePolicy, func() (err error) {
+		ret, err = mgr.policyStore(r).CreateRolePolicy(serviceName, &rolePolicy)
+		return err
+	})
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("CreateRolePolicy", ctxFields, err.Error())
@@ -404,11 +450,12 @@ func (mgr *RESTService) DeleteRolePolicies(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := mgr.PolicyStore.DeleteRolePolicies(serviceName); err != nil {
+	if err := mgr.policyStore(r).DeleteRolePolicies(serviceName); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeleteRolePolicies", serviceName, err.Error())
 		return
 	}
+	mgr.Quotas.Invalidate(mgr.policyStore(r))
 
 	logging.WriteSimpleSucceededAuditLog("DeleteRolePolicies", serviceName, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -429,11 +476,12 @@ func (mgr *RESTService) DeleteRolePolicy(w http.ResponseWriter, r *http.Request)
 		"rolePolicyId": rolePolicyIDStr,
 	}
 
-	if err := mgr.PolicyStore.DeleteRolePolicy(serviceName, rolePolicyIDStr); err != nil {
+	if err := mgr.policyStore(r).DeleteRolePolicy(serviceName, rolePolicyIDStr); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("DeleteRolePolicy", ctxFields, err.Error())
 		return
 	}
+	mgr.Quotas.PolicyCountChanged(mgr.policyStore(r), serviceName, -1)
 
 	logging.WriteSucceededAuditLog("DeleteRolePolicy", ctxFields, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -454,7 +502,7 @@ func (mgr *RESTService) GetRolePolicy(w http.ResponseWriter, r *http.Request) {
 		"rolePolicyId": rolePolicyIDStr,
 	}
 
-	rolePolicy, err := mgr.PolicyStore.GetRolePolicy(serviceName, rolePolicyIDStr)
+	rolePolicy, err := mgr.policyStore(r).GetRolePolicy(serviceName, rolePolicyIDStr)
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteFailedAuditLog("GetRolePolicy", ctxFields, err.Error())
@@ -473,8 +521,12 @@ func (mgr *RESTService) ListRolePolicies(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
-	filters := ParseForFilters(r)
-	rolePolicies, err := mgr.PolicyStore.ListAllRolePolicies(serviceName, filters)
+	opts, err := parseListOptions(r)
+	var rolePolicies []*pms.RolePolicy
+	var token string
+	if err == nil {
+		rolePolicies, token, err = store.ListRolePolicies(mgr.policyStore(r), serviceName, opts)
+	}
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("ListRolePolicies", serviceName, err.Error())
@@ -482,13 +534,7 @@ func (mgr *RESTService) ListRolePolicies(w http.ResponseWriter, r *http.Request)
 	}
 
 	logging.WriteSimpleSucceededAuditLog("ListRolePolicies", serviceName, len(rolePolicies))
-
-	if len(rolePolicies) == 0 {
-		httputils.SendEmptyListResponse(w)
-		return
-	}
-
-	httputils.SendOKResponse(w, &rolePolicies)
+	sendListResponse(w, token, len(rolePolicies), &rolePolicies)
 }
 
 func (mgr *RESTService) CreateFunction(w http.ResponseWriter, r *http.Request) {
@@ -500,14 +546,12 @@ func (mgr *RESTService) CreateFunction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = pmsimpl.CheckFunction(&cf, mgr.PolicyStore)
-	if err != nil {
-		httputils.HandleError(w, err)
-		logging.WriteSimpleFailedAuditLog("CreateFunction", &cf, err.Error())
-		return
-	}
 	cf.Metadata = getCreateMetaData(r)
-	ret, err := mgr.PolicyStore.CreateFunction(&cf)
+	var ret *pms.Function
+	err = mgr.Quotas.CreateFunction(mgr.tenantScope(r), &cf, func() (err error) {
+		ret, err = mgr.policyStore(r).CreateFunction(&cf)
+		return err
+	})
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("CreateFunction", &cf, err.Error())
@@ -530,11 +574,12 @@ func (mgr *RESTService) DeleteFunction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := mgr.PolicyStore.DeleteFunction(funcName); err != nil {
+	if err := mgr.policyStore(r).DeleteFunction(funcName); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeleteFunction", funcName, err.Error())
 		return
 	}
+	mgr.Quotas.FunctionCountChanged(mgr.policyStore(r), -1)
 
 	logging.WriteSimpleSucceededAuditLog("DeleteFunction", funcName, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -542,11 +587,12 @@ func (mgr *RESTService) DeleteFunction(w http.ResponseWriter, r *http.Request) {
 }
 
 func (mgr *RESTService) DeleteFunctions(w http.ResponseWriter, r *http.Request) {
-	if err := mgr.PolicyStore.DeleteFunctions(); err != nil {
+	if err := mgr.policyStore(r).DeleteFunctions(); err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("DeleteFunctions", nil, err.Error())
 		return
 	}
+	mgr.Quotas.Invalidate(mgr.policyStore(r))
 
 	logging.WriteSimpleSucceededAuditLog("DeleteFunctions", nil, nil)
 	w.WriteHeader(http.StatusNoContent)
@@ -564,7 +610,7 @@ func (mgr *RESTService) GetFunction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cf, err := mgr.PolicyStore.GetFunction(funcName)
+	cf, err := mgr.policyStore(r).GetFunction(funcName)
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("GetFunction", funcName, err.Error())
@@ -576,7 +622,12 @@ func (mgr *RESTService) GetFunction(w http.ResponseWriter, r *http.Request) {
 }
 
 func (mgr *RESTService) ListFunctions(w http.ResponseWriter, r *http.Request) {
-	functions, err := mgr.PolicyStore.ListAllFunctions("")
+	opts, err := parseListOptions(r)
+	var functions []*pms.Function
+	var token string
+	if err == nil {
+		functions, token, err = store.ListFunctions(mgr.policyStore(r), opts)
+	}
 	if err != nil {
 		httputils.HandleError(w, err)
 		logging.WriteSimpleFailedAuditLog("ListFunctions", nil, err.Error())
@@ -584,9 +635,5 @@ func (mgr *RESTService) ListFunctions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logging.WriteSimpleSucceededAuditLog("ListFunctions", nil, len(functions))
-	if len(functions) == 0 {
-		httputils.SendEmptyListResponse(w)
-		return
-	}
-	httputils.SendOKResponse(w, functions)
+	sendListResponse(w, token, len(functions), functions)
 }
@@ -4,8 +4,10 @@ package pmsrest
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 
 	"os"
 	"testing"
@@ -264,3 +266,99 @@ func addPrincipalHeader(req *http.Request) {
 	data, _ := json.Marshal(principals)*/
 	req.Header.Add(svcs.PrincipalsHeader, creator)
 }
+
+func TestListPoliciesPages(t *testing.T) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	service := pmsapi.Service{Name: "pageservice", Type: pmsapi.TypeApplication}
+	for i := 0; i < 5; i++ {
+		service.Policies = append(service.Policies, &pmsapi.Policy{Name: fmt.Sprintf("p%d", i), Effect: "grant"})
+	}
+	serviceData, _ := json.Marshal(service)
+	resp, err := client.Post(testserver.URL+svcs.PolicyMgmtPath+"service", "application/json", bytes.NewBuffer(serviceData))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatal("failed to create service", err)
+	}
+
+	list := func(query url.Values) ([]*pmsapi.Policy, string, int) {
+		resp, err := client.Get(testserver.URL + svcs.PolicyMgmtPath + "service/pageservice/policy?" + query.Encode())
+		if err != nil {
+			t.Fatal("failed get response")
+		}
+		defer resp.Body.Close()
+		var policies []*pmsapi.Policy
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&policies); err != nil {
+				t.Fatal("failed to unmarsh response.")
+			}
+		}
+		return policies, resp.Header.Get(svcs.ContinueHeader), resp.StatusCode
+	}
+
+	query := url.Values{"limit": {"2"}, "filter": {"name ne p0"}}
+	names := []string{}
+	for pages := 0; ; pages++ {
+		policies, token, status := list(query)
+		if status != http.StatusOK || len(policies) > 2 || pages > 2 {
+			t.Fatalf("unexpected page %v, status %d", policies, status)
+		}
+		for _, policy := range policies {
+			names = append(names, policy.Name)
+		}
+		if len(token) == 0 {
+			break
+		}
+		query.Set("continue", token)
+	}
+	if len(names) != 4 {
+		t.Errorf("4 policies should be listed, got %v", names)
+	}
+
+	for _, query := range []url.Values{{"limit": {"x"}}, {"continue": {"!"}}, {"filter": {"name like x"}}} {
+		if _, _, status := list(query); status != http.StatusBadRequest {
+			t.Errorf("query %v should be bad request, got status %d", query, status)
+		}
+	}
+}
+
+func TestSearchPolicies(t *testing.T) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	service := pmsapi.Service{Name: "searchservice", Type: pmsapi.TypeApplication,
+		Policies: []*pmsapi.Policy{{Name: "p1", Effect: "grant", Principals: [][]string{{"user:searcher"}},
+			Permissions: []*pmsapi.Permission{{Resource: "/search/r1", Actions: []string{"get"}}}}},
+		RolePolicies: []*pmsapi.RolePolicy{{Name: "rp1", Effect: "grant", Roles: []string{"searchrole"},
+			Principals: []string{"user:searcher"}}},
+	}
+	serviceData, _ := json.Marshal(service)
+	resp, err := client.Post(testserver.URL+svcs.PolicyMgmtPath+"service", "application/json", bytes.NewBuffer(serviceData))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatal("failed to create service", err)
+	}
+
+	search := func(query url.Values) ([]*store.SearchResult, int) {
+		resp, err := client.Get(testserver.URL + svcs.PolicyMgmtPath + "search?" + query.Encode())
+		if err != nil {
+			t.Fatal("failed get response")
+		}
+		defer resp.Body.Close()
+		var results []*store.SearchResult
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+				t.Fatal("failed to unmarsh response.")
+			}
+		}
+		return results, resp.StatusCode
+	}
+
+	results, status := search(url.Values{"principal": {"user:searcher"}})
+	if status != http.StatusOK || len(results) != 1 || results[0].ServiceName != "searchservice" ||
+		len(results[0].Policies) != 1 || len(results[0].RolePolicies) != 1 {
+		t.Errorf("unexpected search result %v, status %d", results, status)
+	}
+	results, status = search(url.Values{"resource": {"/search/r1"}, "action": {"delete"}})
+	if status != http.StatusOK || len(results) != 0 {
+		t.Errorf("unexpected search result %v, status %d", results, status)
+	}
+	if _, status := search(url.Values{}); status != http.StatusBadRequest {
+		t.Errorf("search without query should be bad request, got status %d", status)
+	}
+}
@@ -0,0 +1,63 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
+)
+
+// Quota management
+func (mgr *RESTService) GetQuota(w http.ResponseWriter, r *http.Request) {
+	scope := mgr.tenantScope(r)
+	if r.URL.Query().Get("recount") == "true" {
+		// Usage is recounted every pmsimpl.UsageRecountInterval, recount it now if policies are changed by
+		// others sharing the store
+		mgr.Quotas.Invalidate(scope.PolicyStore)
+	}
+	usage, err := mgr.Quotas.Usage(scope)
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("GetQuota", scope.Tenant.Name, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("GetQuota", scope.Tenant.Name, nil)
+	httputils.SendOKResponse(w, usage)
+}
+
+// UpdateQuota changes the quota of the tenant the request is scoped to. The quota of the default tenant
+// is the global quota, which applies to all the tenants unless they override it with their own. It is
+// stored with the default tenant, and overrides the quota of server configuration.
+func (mgr *RESTService) UpdateQuota(w http.ResponseWriter, r *http.Request) {
+	var quota pms.Quota
+	if err := decodeRequestBody(r, &quota); err != nil {
+		httputils.HandleError(w, err)
+		return
+	}
+
+	scope := mgr.tenantScope(r)
+	err := pmsimpl.ValidateQuota(&quota)
+	if err == nil {
+		if scope.Tenant.Name == pms.DefaultTenant {
+			err = mgr.Quotas.SetStoredGlobalQuota(&quota)
+		} else {
+			tenant := *scope.Tenant
+			tenant.Quota = &quota
+			err = mgr.Tenants.UpdateTenant(&tenant)
+		}
+	}
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("UpdateQuota", &quota, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("UpdateQuota", &quota, nil)
+	httputils.SendOKResponse(w, &quota)
+}
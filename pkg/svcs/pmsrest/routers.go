@@ -5,10 +5,13 @@ package pmsrest
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/oracle/speedle/api/pms"
 	"github.com/oracle/speedle/pkg/svcs"
+	"github.com/oracle/speedle/pkg/svcs/pmsauth"
+	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
 )
 
 type route struct {
@@ -18,13 +21,8 @@ type route struct {
 	HandlerFunc http.HandlerFunc
 }
 
-func initRouters(ps pms.PolicyStoreManager) (*[]route, error) {
-
-	manager, err := NewRestService(ps)
-	if err != nil {
-		return nil, err
-	}
-
+// initRouters returns the routes scoped to tenants
+func initRouters(manager *RESTService) (*[]route, error) {
 	svcRoutes := []route{}
 
 	policyManagerRoutes := []route{
@@ -97,6 +95,13 @@ func initRouters(ps pms.PolicyStoreManager) (*[]route, error) {
 			svcs.PolicyMgmtPath + "service/{serviceName}/role-policy",
 			manager.ListRolePolicies,
 		},
+
+		{
+			"SearchPolicies",
+			"GET",
+			svcs.PolicyMgmtPath + "search",
+			manager.SearchPolicies,
+		},
 	}
 	svcRoutes = append(svcRoutes, policyManagerRoutes...)
 
@@ -228,26 +233,112 @@ func initRouters(ps pms.PolicyStoreManager) (*[]route, error) {
 	}
 	svcRoutes = append(svcRoutes, discoverRequestManageRoutes...)
 
+	quotaManageRoutes := []route{
+		{
+			"GetQuota",
+			"GET",
+			svcs.PolicyMgmtPath + "quota",
+			manager.GetQuota,
+		},
+
+		{
+			"UpdateQuota",
+			"PUT",
+			svcs.PolicyMgmtPath + "quota",
+			manager.UpdateQuota,
+		},
+	}
+	svcRoutes = append(svcRoutes, quotaManageRoutes...)
+
 	return &svcRoutes, nil
 
 }
 
+// initTenantRouters returns the routes managing tenants
+func initTenantRouters(manager *RESTService) *[]route {
+	return &[]route{
+		{
+			"CreateTenant",
+			"POST",
+			svcs.PolicyMgmtPath + "tenant",
+			manager.CreateTenant,
+		},
+
+		{
+			"DeleteTenant",
+			"DELETE",
+			svcs.PolicyMgmtPath + "tenant/{" + svcs.TenantPathVar + "}",
+			manager.DeleteTenant,
+		},
+
+		{
+			"GetTenant",
+			"GET",
+			svcs.PolicyMgmtPath + "tenant/{" + svcs.TenantPathVar + "}",
+			manager.GetTenant,
+		},
+
+		{
+			"ListTenants",
+			"GET",
+			svcs.PolicyMgmtPath + "tenant",
+			manager.ListTenants,
+		},
+	}
+}
+
 func NewRouter(ps pms.PolicyStoreManager) (*mux.Router, error) {
-	routes, err := initRouters(ps)
+	return NewTenantRouter(ps, nil, nil, nil)
+}
+
+// NewRouterWithAuthorizer creates the router of policy management service, every request is
+// authenticated and authorized by authorizer if it is not nil
+func NewRouterWithAuthorizer(ps pms.PolicyStoreManager, authorizer *pmsauth.Authorizer) (*mux.Router, error) {
+	return NewTenantRouter(ps, nil, nil, authorizer)
+}
+
+// NewTenantRouter creates the router of policy management service serving the tenants managed by tenants,
+// ps is the policy store of the default tenant. Tenant scoped endpoints are served both with and without
+// the tenant path prefix, the latter are scoped by the tenant header or to the default tenant.
+// tenants is nil if the policy store doesn't support multi-tenancy, and quotas is nil if the default quota applies.
+func NewTenantRouter(ps pms.PolicyStoreManager, tenants pms.TenantManager, quotas *pmsimpl.Quotas, authorizer *pmsauth.Authorizer) (*mux.Router, error) {
+	manager, err := NewRestService(ps)
+	if err != nil {
+		return nil, err
+	}
+	manager.Tenants = tenants
+	if quotas == nil {
+		if quotas, err = pmsimpl.NewQuotasWithTenants(nil, tenants); err != nil {
+			return nil, err
+		}
+	}
+	manager.Quotas = quotas
+
+	routes, err := initRouters(manager)
 	if err != nil {
 		return nil, err
 	}
 	router := mux.NewRouter().StrictSlash(true)
 
-	for _, route := range *routes {
-		var handler http.Handler
-		handler = route.HandlerFunc
+	addRoute := func(route route, pattern string, handler http.Handler) {
+		if authorizer != nil {
+			handler = authorizeHandler(authorizer, route.Name, handler)
+		}
 		router.
 			Methods(route.Method).
-			Path(route.Pattern).
+			Path(pattern).
 			Name(route.Name).
 			Handler(handler)
 	}
 
+	for _, route := range *initTenantRouters(manager) {
+		addRoute(route, route.Pattern, route.HandlerFunc)
+	}
+	for _, route := range *routes {
+		handler := manager.tenantHandler(route.Name, route.HandlerFunc)
+		addRoute(route, route.Pattern, handler)
+		addRoute(route, svcs.PolicyMgmtPath+svcs.TenantPathPrefix+strings.TrimPrefix(route.Pattern, svcs.PolicyMgmtPath), handler)
+	}
+
 	return router, nil
 }
@@ -0,0 +1,33 @@
+//Copyright (c) 2019, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsrest
+
+import (
+	"net/http"
+
+	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/store"
+)
+
+// SearchPolicies finds the policies and role policies of all the services matching the principal, resource,
+// role and action query parameters, all the specified parameters must match.
+func (mgr *RESTService) SearchPolicies(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := store.SearchQuery{
+		Principal: params.Get("principal"),
+		Resource:  params.Get("resource"),
+		Role:      params.Get("role"),
+		Action:    params.Get("action"),
+	}
+	results, err := store.Search(mgr.policyStore(r), query)
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("SearchPolicies", query, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("SearchPolicies", query, len(results))
+	sendListResponse(w, "", len(results), results)
+}
@@ -0,0 +1,125 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package pmsrest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/oracle/speedle/api/pms"
+	"github.com/oracle/speedle/pkg/errors"
+	"github.com/oracle/speedle/pkg/httputils"
+	"github.com/oracle/speedle/pkg/logging"
+	"github.com/oracle/speedle/pkg/store"
+	"github.com/oracle/speedle/pkg/svcs"
+	"github.com/oracle/speedle/pkg/svcs/pmsimpl"
+)
+
+// tenantHandler resolves the tenant a request is scoped to, and passes the tenant scope to next handler in request context
+func (mgr *RESTService) tenantHandler(routeName string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantName, err := svcs.TenantOfHTTP(r)
+		var scope *pmsimpl.TenantScope
+		if err == nil {
+			scope, err = pmsimpl.ResolveTenantScope(mgr.Tenants, mgr.PolicyStore, tenantName)
+		}
+		if err != nil {
+			httputils.HandleError(w, err)
+			logging.WriteSimpleFailedAuditLog(routeName, tenantName, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(pmsimpl.WithTenantScope(r.Context(), scope)))
+	})
+}
+
+func (mgr *RESTService) tenantManager() (pms.TenantManager, error) {
+	if mgr.Tenants == nil {
+		return nil, errors.Errorf(errors.InvalidRequest, "%q policy store doesn't support multi-tenancy", mgr.PolicyStore.Type())
+	}
+	return mgr.Tenants, nil
+}
+
+// Tenant management
+func (mgr *RESTService) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var tenant pms.Tenant
+	if err := decodeRequestBody(r, &tenant); err != nil {
+		httputils.HandleError(w, err)
+		return
+	}
+
+	tenants, err := mgr.tenantManager()
+	if err == nil {
+		tenant.Metadata = getCreateMetaData(r)
+		err = tenants.CreateTenant(&tenant)
+	}
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("CreateTenant", &tenant, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("CreateTenant", &tenant, nil)
+	httputils.SendCreatedResponse(w, &tenant)
+}
+
+func (mgr *RESTService) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	tenantName := mux.Vars(r)[svcs.TenantPathVar]
+	tenants, err := mgr.tenantManager()
+	var ps pms.PolicyStoreManager
+	if err == nil {
+		ps, err = tenants.TenantStore(tenantName)
+	}
+	if err == nil {
+		err = tenants.DeleteTenant(tenantName)
+	}
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("DeleteTenant", tenantName, err.Error())
+		return
+	}
+	mgr.Quotas.Remove(ps)
+
+	logging.WriteSimpleSucceededAuditLog("DeleteTenant", tenantName, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (mgr *RESTService) GetTenant(w http.ResponseWriter, r *http.Request) {
+	tenantName := mux.Vars(r)[svcs.TenantPathVar]
+	tenants, err := mgr.tenantManager()
+	var tenant *pms.Tenant
+	if err == nil {
+		tenant, err = tenants.GetTenant(tenantName)
+	}
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("GetTenant", tenantName, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("GetTenant", tenantName, nil)
+	httputils.SendOKResponse(w, tenant)
+}
+
+func (mgr *RESTService) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := mgr.tenantManager()
+	var tenantList []*pms.Tenant
+	var token string
+	if err == nil {
+		var opts store.ListOptions
+		if opts, err = parseListOptions(r); err == nil {
+			tenantList, token, err = store.ListTenants(tenants, opts)
+		}
+	}
+	if err != nil {
+		httputils.HandleError(w, err)
+		logging.WriteSimpleFailedAuditLog("ListTenants", nil, err.Error())
+		return
+	}
+
+	logging.WriteSimpleSucceededAuditLog("ListTenants", nil, len(tenantList))
+	if len(token) > 0 {
+		w.Header().Set(svcs.ContinueHeader, token)
+	}
+	httputils.SendOKResponse(w, &tenantList)
+}
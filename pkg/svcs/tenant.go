@@ -0,0 +1,42 @@
+//Copyright (c) 2018, Oracle and/or its affiliates. All rights reserved.
+//Licensed under the Universal Permissive License (UPL) Version 1.0 as shown at http://oss.oracle.com/licenses/upl.
+
+package svcs
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/oracle/speedle/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// TenantOfHTTP returns the tenant a REST request is scoped to, from the path prefix or the tenant header.
+// Empty string is returned for requests scoped to the default tenant.
+func TenantOfHTTP(r *http.Request) (string, error) {
+	pathTenant := mux.Vars(r)[TenantPathVar]
+	headerTenant := r.Header.Get(TenantHeader)
+	if len(pathTenant) != 0 && len(headerTenant) != 0 && pathTenant != headerTenant {
+		return "", errors.Errorf(errors.InvalidRequest, "tenant %q in path conflicts with tenant %q in %s header", pathTenant, headerTenant, TenantHeader)
+	}
+	if len(pathTenant) != 0 {
+		return pathTenant, nil
+	}
+	return headerTenant, nil
+}
+
+// TenantOfGRPC returns the tenant a gRPC request is scoped to, from the request metadata.
+// Empty string is returned for requests scoped to the default tenant.
+func TenantOfGRPC(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	// gRPC metadata keys are always lower case
+	if values := md[strings.ToLower(TenantHeader)]; len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}